@@ -0,0 +1,81 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IpamReservationSpec defines the desired state of IpamReservation.
+type IpamReservationSpec struct {
+	// ReservedSubnets is the set of networks that must not be used for remote clusters,
+	// in addition to the ones reserved when the network manager is started.
+	// +optional
+	ReservedSubnets []string `json:"reservedSubnets,omitempty"`
+	// ClusterRemappings is the set of networks pinned for the remapping of the remote clusters networks.
+	// +optional
+	ClusterRemappings []ClusterRemapping `json:"clusterRemappings,omitempty"`
+}
+
+// ClusterRemapping contains the networks the PodCIDR and the ExternalCIDR of a remote cluster have to be
+// remapped on, in case they conflict with the networks already in use. The pinned networks must have the
+// same mask length of the remote ones, and they are not reserved until the remote cluster is configured.
+type ClusterRemapping struct {
+	// ClusterID is the ID of the remote cluster.
+	ClusterID     string `json:"clusterID"`
+	PinnedSubnets `json:",inline"`
+}
+
+// IpamReservationStatus defines the observed state of IpamReservation.
+type IpamReservationStatus struct {
+	// ReservedSubnets is the set of networks currently reserved on behalf of this resource.
+	// +optional
+	ReservedSubnets []string `json:"reservedSubnets,omitempty"`
+	// PinnedClusters is the set of remote clusters whose remapped networks are currently pinned by this resource.
+	// +optional
+	PinnedClusters []string `json:"pinnedClusters,omitempty"`
+	// Message describes the last error occurred while enforcing the resource, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Reserved",type=string,JSONPath=`.status.reservedSubnets`
+//+kubebuilder:printcolumn:name="Pinned Clusters",type=string,JSONPath=`.status.pinnedClusters`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// IpamReservation is the Schema for the ipamreservations API.
+type IpamReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IpamReservationSpec   `json:"spec,omitempty"`
+	Status IpamReservationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IpamReservationList contains a list of IpamReservation.
+type IpamReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IpamReservation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IpamReservation{}, &IpamReservationList{})
+}
@@ -31,6 +31,14 @@ type Subnets struct {
 	LocalNATExternalCIDR string `json:"localNATExternalCIDR"`
 	// Network used in local cluster for remote service endpoints.
 	RemoteExternalCIDR string `json:"remoteExternalCIDR"`
+	// IPv6 network used in the remote cluster for local Pods, in case of dual-stack clusters.
+	LocalNATPodCIDRv6 string `json:"localNATPodCIDRv6,omitempty"`
+	// IPv6 network used for Pods in the remote cluster, in case of dual-stack clusters.
+	RemotePodCIDRv6 string `json:"remotePodCIDRv6,omitempty"`
+	// IPv6 network used in remote cluster for local service endpoints, in case of dual-stack clusters.
+	LocalNATExternalCIDRv6 string `json:"localNATExternalCIDRv6,omitempty"`
+	// IPv6 network used in local cluster for remote service endpoints, in case of dual-stack clusters.
+	RemoteExternalCIDRv6 string `json:"remoteExternalCIDRv6,omitempty"`
 }
 
 // ClusterMapping is an empty struct.
@@ -72,6 +80,43 @@ type IpamSpec struct {
 	PodCIDR string `json:"podCIDR"`
 	// ServiceCIDR
 	ServiceCIDR string `json:"serviceCIDR"`
+	// Cluster IPv6 ExternalCIDR, in case of dual-stack clusters.
+	ExternalCIDRv6 string `json:"externalCIDRv6,omitempty"`
+	// Cluster IPv6 PodCIDR, in case of dual-stack clusters.
+	PodCIDRv6 string `json:"podCIDRv6,omitempty"`
+	// IPv6 ServiceCIDR, in case of dual-stack clusters.
+	ServiceCIDRv6 string `json:"serviceCIDRv6,omitempty"`
+	// Networks pinned by the user for the remapping of the remote clusters networks.
+	// Key is the remote cluster ID, value is the set of pinned networks.
+	PinnedSubnets map[string]PinnedSubnets `json:"pinnedSubnets,omitempty"`
+	// Owners of the networks pinned by the user.
+	// Key is the remote cluster ID, value is the name of the IpamReservation pinning its networks.
+	PinnedSubnetsOwners map[string]string `json:"pinnedSubnetsOwners,omitempty"`
+	// Networks used to remap the networks of the clusters reached through a transit cluster.
+	// Key is the ID of the transit cluster, value is a map whose key is the ID of the cluster reached through it.
+	TransitSubnets map[string]map[string]TransitSubnets `json:"transitSubnets,omitempty"`
+}
+
+// TransitSubnets contains the networks assigned to the IPv4 PodCIDR and ExternalCIDR of a cluster reached through
+// a transit cluster, i.e. the original ones or the ones they have been remapped on.
+type TransitSubnets struct {
+	// Network assigned to the PodCIDR of the cluster.
+	PodCIDR string `json:"podCIDR"`
+	// Network assigned to the ExternalCIDR of the cluster.
+	ExternalCIDR string `json:"externalCIDR"`
+}
+
+// PinnedSubnets contains the networks the PodCIDR and the ExternalCIDR of a remote cluster have to be
+// remapped on, in case of conflicts. An empty value means the network is chosen from the network pools.
+type PinnedSubnets struct {
+	// Network the remote PodCIDR is remapped on.
+	PodCIDR string `json:"podCIDR,omitempty"`
+	// Network the remote ExternalCIDR is remapped on.
+	ExternalCIDR string `json:"externalCIDR,omitempty"`
+	// Network the remote IPv6 PodCIDR is remapped on, in case of dual-stack clusters.
+	PodCIDRv6 string `json:"podCIDRv6,omitempty"`
+	// Network the remote IPv6 ExternalCIDR is remapped on, in case of dual-stack clusters.
+	ExternalCIDRv6 string `json:"externalCIDRv6,omitempty"`
 }
 
 // +kubebuilder:object:root=true
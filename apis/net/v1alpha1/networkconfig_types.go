@@ -32,12 +32,32 @@ type NetworkConfigSpec struct {
 	PodCIDR string `json:"podCIDR"`
 	// Network used for local service endpoints.
 	ExternalCIDR string `json:"externalCIDR"`
+	// IPv6 network used in the local cluster for the pod IPs, set only in case of dual-stack clusters.
+	PodCIDRv6 string `json:"podCIDRv6,omitempty"`
+	// IPv6 network used for local service endpoints, set only in case of dual-stack clusters.
+	ExternalCIDRv6 string `json:"externalCIDRv6,omitempty"`
 	// Public IP of the node where the VPN tunnel is created.
 	EndpointIP string `json:"endpointIP"`
 	// Vpn technology used to interconnect two clusters.
 	BackendType string `json:"backendType"`
 	// Connection parameters
 	BackendConfig map[string]string `json:"backend_config"`
+	// Networks of the clusters reachable through the local cluster, advertised to let the remote cluster reach them
+	// without a direct peering. Populated only if transit routing is enabled.
+	TransitNetworks []TransitNetwork `json:"transitNetworks,omitempty"`
+}
+
+// TransitNetwork describes the IPv4 networks of a cluster reachable through the cluster advertising them.
+type TransitNetwork struct {
+	// The ID of the cluster the networks belong to.
+	ClusterID string `json:"clusterID"`
+	// Network used for the pod IPs of the cluster, as seen by the advertising cluster.
+	PodCIDR string `json:"podCIDR"`
+	// Network used for the service endpoints of the cluster, as seen by the advertising cluster.
+	ExternalCIDR string `json:"externalCIDR"`
+	// The IDs of the clusters traversed starting from the advertising one (excluded) to reach the networks.
+	// The last element is always the ID of the cluster the networks belong to.
+	Path []string `json:"path"`
 }
 
 // NetworkConfigStatus defines the observed state of NetworkConfig.
@@ -54,6 +74,26 @@ type NetworkConfigStatus struct {
 	// The new subnet used to NAT the externalCIDR of the remote cluster. The original ExternalCIDR may have been mapped
 	// to this network by the remote cluster.
 	ExternalCIDRNAT string `json:"externalCIDRNAT,omitempty"`
+	// The new subnet used to NAT the IPv6 podCidr of the remote cluster, in case of dual-stack clusters.
+	PodCIDRv6NAT string `json:"podCIDRv6NAT,omitempty"`
+	// The new subnet used to NAT the IPv6 externalCIDR of the remote cluster, in case of dual-stack clusters.
+	ExternalCIDRv6NAT string `json:"externalCIDRv6NAT,omitempty"`
+	// The subnets used by the remote cluster to NAT the transit networks it accepted, i.e. the ones
+	// it reaches through the local cluster.
+	TransitNetworksNAT []TransitNetworkNAT `json:"transitNetworksNAT,omitempty"`
+	// The next wireguard public key advertised in the BackendConfig during a key rotation, set once the remote cluster
+	// configured it as an additional peer of the tunnel. The key is switched only after this confirmation.
+	InstalledNextPublicKey string `json:"installedNextPublicKey,omitempty"`
+}
+
+// TransitNetworkNAT describes the subnets used by the remote cluster to NAT the networks of a transit cluster.
+type TransitNetworkNAT struct {
+	// The ID of the cluster the networks belong to.
+	ClusterID string `json:"clusterID"`
+	// The new subnet used to NAT the podCIDR of the transit cluster, or "None" if not remapped.
+	PodCIDRNAT string `json:"podCIDRNAT"`
+	// The new subnet used to NAT the externalCIDR of the transit cluster, or "None" if not remapped.
+	ExternalCIDRNAT string `json:"externalCIDRNAT"`
 }
 
 // +kubebuilder:object:root=true
@@ -52,6 +52,29 @@ type TunnelEndpointSpec struct {
 	// +kubebuilder:validation:Optional
 	RemoteNATExternalCIDR string `json:"remoteNATExternalCIDR"`
 
+	// IPv6 PodCIDR of local cluster, set only in case both clusters are dual-stack.
+	LocalPodCIDRv6 string `json:"localPodCIDRv6,omitempty"`
+	// Network used in the remote cluster to map the local IPv6 PodCIDR, in case of conflicts (in the remote cluster).
+	LocalNATPodCIDRv6 string `json:"localNATPodCIDRv6,omitempty"`
+	// IPv6 ExternalCIDR of local cluster, set only in case both clusters are dual-stack.
+	LocalExternalCIDRv6 string `json:"localExternalCIDRv6,omitempty"`
+	// Network used in the remote cluster to map the local IPv6 ExternalCIDR, in case of conflicts (in the remote cluster).
+	LocalNATExternalCIDRv6 string `json:"localNATExternalCIDRv6,omitempty"`
+
+	// IPv6 PodCIDR of remote cluster, set only in case both clusters are dual-stack.
+	RemotePodCIDRv6 string `json:"remotePodCIDRv6,omitempty"`
+	// Network used in the local cluster to map the remote cluster IPv6 PodCIDR, in case of conflicts with RemotePodCIDRv6.
+	RemoteNATPodCIDRv6 string `json:"remoteNATPodCIDRv6,omitempty"`
+	// IPv6 ExternalCIDR of remote cluster, set only in case both clusters are dual-stack.
+	RemoteExternalCIDRv6 string `json:"remoteExternalCIDRv6,omitempty"`
+	// Network used in the local cluster to map the remote cluster IPv6 ExternalCIDR, in case of conflicts with RemoteExternalCIDRv6.
+	RemoteNATExternalCIDRv6 string `json:"remoteNATExternalCIDRv6,omitempty"`
+
+	// Networks of the clusters reached through the remote cluster, acting as transit hop.
+	RemoteTransitNetworks []TransitEndpoint `json:"remoteTransitNetworks,omitempty"`
+	// Networks of the clusters reached by the remote cluster through the local one, acting as transit hop.
+	LocalTransitNetworks []TransitEndpoint `json:"localTransitNetworks,omitempty"`
+
 	// Public IP of the node where the VPN tunnel is created.
 	EndpointIP string `json:"endpointIP"`
 	// Vpn technology used to interconnect two clusters.
@@ -60,6 +83,26 @@ type TunnelEndpointSpec struct {
 	BackendConfig map[string]string `json:"backend_config"`
 }
 
+// TransitEndpoint describes the IPv4 networks of a cluster reached through a transit hop, rather than a direct tunnel.
+type TransitEndpoint struct {
+	// The ID of the cluster the networks belong to.
+	ClusterID string `json:"clusterID"`
+	// PodCIDR of the cluster, as seen by the transit hop.
+	PodCIDR string `json:"podCIDR"`
+	// Network used on the side of the tunnel opposite to the transit hop to map PodCIDR, in case of conflicts.
+	// +kubebuilder:default="None"
+	// +kubebuilder:validation:Optional
+	NATPodCIDR string `json:"natPodCIDR"`
+	// ExternalCIDR of the cluster, as seen by the transit hop.
+	ExternalCIDR string `json:"externalCIDR"`
+	// Network used on the side of the tunnel opposite to the transit hop to map ExternalCIDR, in case of conflicts.
+	// +kubebuilder:default="None"
+	// +kubebuilder:validation:Optional
+	NATExternalCIDR string `json:"natExternalCIDR"`
+	// The IDs of the clusters traversed starting from the transit hop (excluded) to reach the networks.
+	Path []string `json:"path,omitempty"`
+}
+
 // TunnelEndpointStatus defines the observed state of TunnelEndpoint.
 type TunnelEndpointStatus struct {
 	TunnelIFaceIndex int        `json:"tunnelIFaceIndex,omitempty"`
@@ -68,6 +111,43 @@ type TunnelEndpointStatus struct {
 	VethIFaceName    string     `json:"vethIFaceName,omitempty"`
 	GatewayIP        string     `json:"gatewayIP,omitempty"`
 	Connection       Connection `json:"connection,omitempty"`
+	// GatewayIPv6 is the IPv6 address of the gateway replica handling the tunnel, in case of dual-stack clusters.
+	GatewayIPv6 string `json:"gatewayIPv6,omitempty"`
+	// GatewayPod is the name of the gateway replica handling the tunnel.
+	GatewayPod string `json:"gatewayPod,omitempty"`
+	// KeyRotation holds the status of the rotation of the keys used by the tunnel, if supported by the backend.
+	KeyRotation *KeyRotationStatus `json:"keyRotation,omitempty"`
+	// Statistics holds the health and traffic statistics of the tunnel, measured by the gateway.
+	Statistics *TunnelStatistics `json:"statistics,omitempty"`
+	// MTU is the effective MTU of the tunnel, i.e. the path MTU towards the remote endpoint minus the encapsulation overhead.
+	// It is applied to the routes towards the remote networks, both in the gateway and on the nodes.
+	MTU int `json:"mtu,omitempty"`
+}
+
+// TunnelStatistics describes the health and the traffic statistics of the vpn tunnel connecting to a remote cluster.
+type TunnelStatistics struct {
+	// RTT is the average round trip time towards the remote gateway, measured by the last probes.
+	RTT *metav1.Duration `json:"rtt,omitempty"`
+	// PacketLoss is the percentage of the last probes towards the remote gateway which did not get a reply.
+	PacketLoss int `json:"packetLoss"`
+	// LastProbeTime is the time the outcome of the last probe has been recorded.
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// LastHandshakeTime is the time of the last handshake with the remote peer, if supported by the backend.
+	LastHandshakeTime *metav1.Time `json:"lastHandshakeTime,omitempty"`
+	// ReceivedBytes is the number of bytes received from the remote peer, if supported by the backend.
+	ReceivedBytes int64 `json:"receivedBytes,omitempty"`
+	// TransmittedBytes is the number of bytes transmitted to the remote peer, if supported by the backend.
+	TransmittedBytes int64 `json:"transmittedBytes,omitempty"`
+}
+
+// KeyRotationStatus describes the status of the periodic rotation of the keys used by the vpn tunnel.
+type KeyRotationStatus struct {
+	// Interval is the interval between two subsequent key rotations. A zero value means that rotation is disabled.
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// LastRotationTime is the time the local key was last rotated.
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// NextRotationTime is the time the next key rotation is expected to start.
+	NextRotationTime *metav1.Time `json:"nextRotationTime,omitempty"`
 }
 
 // Connection holds the configuration and status of a vpn tunnel connecting to remote cluster.
@@ -97,6 +177,7 @@ const (
 // +kubebuilder:printcolumn:name="Endpoint IP",type=string,JSONPath=`.spec.endpointIP`,priority=1
 // +kubebuilder:printcolumn:name="Backend type",type=string,JSONPath=`.spec.backendType`
 // +kubebuilder:printcolumn:name="Connection status",type=string,JSONPath=`.status.connection.status`
+// +kubebuilder:printcolumn:name="Gateway",type=string,JSONPath=`.status.gatewayPod`,priority=1
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type TunnelEndpoint struct {
 	metav1.TypeMeta   `json:",inline"`
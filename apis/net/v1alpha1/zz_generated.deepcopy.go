@@ -19,6 +19,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -37,6 +38,22 @@ func (in *ClusterMapping) DeepCopy() *ClusterMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRemapping) DeepCopyInto(out *ClusterRemapping) {
+	*out = *in
+	out.PinnedSubnets = in.PinnedSubnets
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRemapping.
+func (in *ClusterRemapping) DeepCopy() *ClusterRemapping {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRemapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfiguredCluster) DeepCopyInto(out *ConfiguredCluster) {
 	*out = *in
@@ -96,6 +113,115 @@ func (in *EndpointMapping) DeepCopy() *EndpointMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpamReservation) DeepCopyInto(out *IpamReservation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpamReservation.
+func (in *IpamReservation) DeepCopy() *IpamReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IpamReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IpamReservation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpamReservationList) DeepCopyInto(out *IpamReservationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IpamReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpamReservationList.
+func (in *IpamReservationList) DeepCopy() *IpamReservationList {
+	if in == nil {
+		return nil
+	}
+	out := new(IpamReservationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IpamReservationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpamReservationSpec) DeepCopyInto(out *IpamReservationSpec) {
+	*out = *in
+	if in.ReservedSubnets != nil {
+		in, out := &in.ReservedSubnets, &out.ReservedSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterRemappings != nil {
+		in, out := &in.ClusterRemappings, &out.ClusterRemappings
+		*out = make([]ClusterRemapping, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpamReservationSpec.
+func (in *IpamReservationSpec) DeepCopy() *IpamReservationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IpamReservationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpamReservationStatus) DeepCopyInto(out *IpamReservationStatus) {
+	*out = *in
+	if in.ReservedSubnets != nil {
+		in, out := &in.ReservedSubnets, &out.ReservedSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PinnedClusters != nil {
+		in, out := &in.PinnedClusters, &out.PinnedClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpamReservationStatus.
+func (in *IpamReservationStatus) DeepCopy() *IpamReservationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IpamReservationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IpamSpec) DeepCopyInto(out *IpamSpec) {
 	*out = *in
@@ -140,6 +266,37 @@ func (in *IpamSpec) DeepCopyInto(out *IpamSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.PinnedSubnets != nil {
+		in, out := &in.PinnedSubnets, &out.PinnedSubnets
+		*out = make(map[string]PinnedSubnets, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PinnedSubnetsOwners != nil {
+		in, out := &in.PinnedSubnetsOwners, &out.PinnedSubnetsOwners
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TransitSubnets != nil {
+		in, out := &in.TransitSubnets, &out.TransitSubnets
+		*out = make(map[string]map[string]TransitSubnets, len(*in))
+		for key, val := range *in {
+			var outVal map[string]TransitSubnets
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]TransitSubnets, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpamSpec.
@@ -210,6 +367,30 @@ func (in *IpamStorageList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotationStatus) DeepCopyInto(out *KeyRotationStatus) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRotationTime != nil {
+		in, out := &in.NextRotationTime, &out.NextRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotationStatus.
+func (in *KeyRotationStatus) DeepCopy() *KeyRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in Mappings) DeepCopyInto(out *Mappings) {
 	{
@@ -333,7 +514,7 @@ func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
@@ -396,6 +577,13 @@ func (in *NetworkConfigSpec) DeepCopyInto(out *NetworkConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.TransitNetworks != nil {
+		in, out := &in.TransitNetworks, &out.TransitNetworks
+		*out = make([]TransitNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfigSpec.
@@ -411,6 +599,11 @@ func (in *NetworkConfigSpec) DeepCopy() *NetworkConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkConfigStatus) DeepCopyInto(out *NetworkConfigStatus) {
 	*out = *in
+	if in.TransitNetworksNAT != nil {
+		in, out := &in.TransitNetworksNAT, &out.TransitNetworksNAT
+		*out = make([]TransitNetworkNAT, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfigStatus.
@@ -423,6 +616,21 @@ func (in *NetworkConfigStatus) DeepCopy() *NetworkConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedSubnets) DeepCopyInto(out *PinnedSubnets) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedSubnets.
+func (in *PinnedSubnets) DeepCopy() *PinnedSubnets {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedSubnets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Subnets) DeepCopyInto(out *Subnets) {
 	*out = *in
@@ -438,6 +646,76 @@ func (in *Subnets) DeepCopy() *Subnets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransitEndpoint) DeepCopyInto(out *TransitEndpoint) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransitEndpoint.
+func (in *TransitEndpoint) DeepCopy() *TransitEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(TransitEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransitNetwork) DeepCopyInto(out *TransitNetwork) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransitNetwork.
+func (in *TransitNetwork) DeepCopy() *TransitNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(TransitNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransitNetworkNAT) DeepCopyInto(out *TransitNetworkNAT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransitNetworkNAT.
+func (in *TransitNetworkNAT) DeepCopy() *TransitNetworkNAT {
+	if in == nil {
+		return nil
+	}
+	out := new(TransitNetworkNAT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransitSubnets) DeepCopyInto(out *TransitSubnets) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransitSubnets.
+func (in *TransitSubnets) DeepCopy() *TransitSubnets {
+	if in == nil {
+		return nil
+	}
+	out := new(TransitSubnets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelEndpoint) DeepCopyInto(out *TunnelEndpoint) {
 	*out = *in
@@ -500,6 +778,20 @@ func (in *TunnelEndpointList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelEndpointSpec) DeepCopyInto(out *TunnelEndpointSpec) {
 	*out = *in
+	if in.RemoteTransitNetworks != nil {
+		in, out := &in.RemoteTransitNetworks, &out.RemoteTransitNetworks
+		*out = make([]TransitEndpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LocalTransitNetworks != nil {
+		in, out := &in.LocalTransitNetworks, &out.LocalTransitNetworks
+		*out = make([]TransitEndpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.BackendConfig != nil {
 		in, out := &in.BackendConfig, &out.BackendConfig
 		*out = make(map[string]string, len(*in))
@@ -523,6 +815,16 @@ func (in *TunnelEndpointSpec) DeepCopy() *TunnelEndpointSpec {
 func (in *TunnelEndpointStatus) DeepCopyInto(out *TunnelEndpointStatus) {
 	*out = *in
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.KeyRotation != nil {
+		in, out := &in.KeyRotation, &out.KeyRotation
+		*out = new(KeyRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Statistics != nil {
+		in, out := &in.Statistics, &out.Statistics
+		*out = new(TunnelStatistics)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelEndpointStatus.
@@ -534,3 +836,31 @@ func (in *TunnelEndpointStatus) DeepCopy() *TunnelEndpointStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelStatistics) DeepCopyInto(out *TunnelStatistics) {
+	*out = *in
+	if in.RTT != nil {
+		in, out := &in.RTT, &out.RTT
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastHandshakeTime != nil {
+		in, out := &in.LastHandshakeTime, &out.LastHandshakeTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelStatistics.
+func (in *TunnelStatistics) DeepCopy() *TunnelStatistics {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelStatistics)
+	in.DeepCopyInto(out)
+	return out
+}
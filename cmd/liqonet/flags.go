@@ -29,6 +29,6 @@ type liqonetCommonFlags struct {
 func addCommonFlags(liqonet *liqonetCommonFlags) {
 	flag.StringVar(&liqonet.metricsAddr, "metrics-bind-addr", ":0", "The address the metric endpoint binds to.")
 	flag.StringVar(&liqonet.runAs, "run-as", liqoconst.LiqoGatewayOperatorName,
-		fmt.Sprintf("The accepted values are: %q, %q, %q.",
-			liqoconst.LiqoGatewayOperatorName, liqoconst.LiqoRouteOperatorName, liqoconst.LiqoNetworkManagerName))
+		fmt.Sprintf("The accepted values are: %q, %q, %q, %q.", liqoconst.LiqoGatewayOperatorName,
+			liqoconst.LiqoRouteOperatorName, liqoconst.LiqoNetworkManagerName, liqoconst.LiqoNATReflectorName))
 }
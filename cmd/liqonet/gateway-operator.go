@@ -16,6 +16,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"sync"
 	"time"
@@ -29,7 +30,13 @@ import (
 
 	tunneloperator "github.com/liqotech/liqo/internal/liqonet/tunnel-operator"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqoebpf "github.com/liqotech/liqo/pkg/liqonet/ebpf"
+	"github.com/liqotech/liqo/pkg/liqonet/iptables"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
 	liqonetns "github.com/liqotech/liqo/pkg/liqonet/netns"
+	tunnelipsec "github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/probe"
 	tunnelwg "github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
 	"github.com/liqotech/liqo/pkg/mapperUtils"
@@ -38,9 +45,27 @@ import (
 
 type gatewayOperatorFlags struct {
 	enableLeaderElection bool
+	activeActive         bool
 	leaseDuration        time.Duration
 	renewDeadline        time.Duration
 	retryPeriod          time.Duration
+
+	wireguardImplementation      string
+	wireguardKeyRotationInterval time.Duration
+	ipsecRekeyInterval           time.Duration
+
+	probeInterval         time.Duration
+	probeFailureThreshold int
+
+	netfilterBackend string
+	ebpfNAT          bool
+
+	tunnelMTU        int
+	pathMTUDiscovery bool
+
+	natReflector       string
+	natRelay           bool
+	natRelaySecretFile string
 }
 
 func addGatewayOperatorFlags(liqonet *gatewayOperatorFlags) {
@@ -52,26 +77,120 @@ func addGatewayOperatorFlags(liqonet *gatewayOperatorFlags) {
 		"renew-deadline is the duration that the acting control plane will retry refreshing leadership before giving up")
 	flag.DurationVar(&liqonet.retryPeriod, "gateway.retry-period", 2*time.Second,
 		"retry-period is the duration the LeaderElector clients should wait between tries of actions")
+	flag.BoolVar(&liqonet.activeActive, "gateway.active-active", false,
+		"active-active shards the tunnels towards the remote clusters among all the replicas, instead of electing a leader handling all of them. "+
+			"It requires the replicas to be directly reachable by the remote clusters at the address of the respective nodes")
+	flag.StringVar(&liqonet.wireguardImplementation, "gateway.wireguard-implementation", string(tunnelwg.ImplementationAuto),
+		fmt.Sprintf("The implementation of wireguard to be used. The accepted values are: %q (the kernel one if available, "+
+			"the userspace one otherwise), %q, %q.", tunnelwg.ImplementationAuto, tunnelwg.ImplementationKernel, tunnelwg.ImplementationUserspace))
+	flag.DurationVar(&liqonet.wireguardKeyRotationInterval, "gateway.wireguard-key-rotation-interval", 0,
+		"The interval between two subsequent rotations of the wireguard keys. Zero disables the key rotation")
+	flag.DurationVar(&liqonet.ipsecRekeyInterval, "gateway.ipsec-rekey-interval", tunnelipsec.DefaultRekeyInterval,
+		"The interval between two subsequent regenerations of the nonce mixed into the keys of the IPsec security associations")
+	flag.DurationVar(&liqonet.probeInterval, "gateway.probe-interval", probe.DefaultInterval,
+		"The interval between two subsequent probes towards each remote gateway. Zero disables the probing")
+	flag.IntVar(&liqonet.probeFailureThreshold, "gateway.probe-failure-threshold", probe.DefaultFailureThreshold,
+		"The number of consecutive lost probes after which the connection with a remote gateway is flagged as erroneous")
+	flag.StringVar(&liqonet.netfilterBackend, "gateway.netfilter-backend", string(iptables.BackendIPTables),
+		fmt.Sprintf("The backend used to configure the NAT rules. The accepted values are: %q, %q (it requires Linux 5.8 or later).",
+			iptables.BackendIPTables, iptables.BackendNFTables))
+	flag.BoolVar(&liqonet.ebpfNAT, "gateway.ebpf-nat", false,
+		"ebpf-nat translates the IPv4 addresses of the NatMappings through eBPF programs attached to the tunnel interfaces, "+
+			"instead of configuring a DNAT rule for each mapping "+
+			"(the translation is stateless, hence it applies also to the connections initiated by the mapped endpoints)")
+	flag.IntVar(&liqonet.tunnelMTU, "gateway.tunnel-mtu", tunnelwg.MTU,
+		"The MTU of the tunnels towards the remote clusters, lowered by the additional encapsulation overhead of the drivers other than WireGuard")
+	flag.BoolVar(&liqonet.pathMTUDiscovery, "gateway.path-mtu-discovery", false,
+		"path-mtu-discovery derives the MTU of the tunnels from the path MTU discovered towards each remote endpoint, instead of the configured one")
+	flag.StringVar(&liqonet.natReflector, "gateway.nat-reflector-address", "",
+		"The address (host:port) of the reflector used to discover the public mapping of the tunnel port, if the gateway is behind a NAT. "+
+			"Empty disables the NAT traversal")
+	flag.BoolVar(&liqonet.natRelay, "gateway.nat-relay", false,
+		"nat-relay relays the tunnel traffic through the reflector, for the NATs preventing the direct communication between the gateways")
+	flag.StringVar(&liqonet.natRelaySecretFile, "gateway.nat-relay-secret-file", "",
+		fmt.Sprintf("The file containing the secret authenticating the allocation requests sent to the relays, either the local one "+
+			"or the one requested by the remote clusters. If empty, the secret is read from the %s environment variable",
+			nattraversal.RelaySecretEnvVar))
 }
 
 func runGatewayOperator(commonFlags *liqonetCommonFlags, gatewayFlags *gatewayOperatorFlags) {
 	metricsAddr := commonFlags.metricsAddr
-	enableLeaderElection := gatewayFlags.enableLeaderElection
+	enableLeaderElection := gatewayFlags.enableLeaderElection && !gatewayFlags.activeActive
 	leaseDuration := gatewayFlags.leaseDuration
 	renewDeadLine := gatewayFlags.renewDeadline
 	retryPeriod := gatewayFlags.retryPeriod
 
+	if err := tunnelwg.SetImplementation(tunnelwg.Implementation(gatewayFlags.wireguardImplementation)); err != nil {
+		klog.Errorf("invalid value for the wireguard implementation: %v", err)
+		os.Exit(1)
+	}
+	if err := tunnelwg.SetKeyRotationInterval(gatewayFlags.wireguardKeyRotationInterval); err != nil {
+		klog.Errorf("invalid value for the wireguard key rotation interval: %v", err)
+		os.Exit(1)
+	}
+	if err := tunnelipsec.SetRekeyInterval(gatewayFlags.ipsecRekeyInterval); err != nil {
+		klog.Errorf("invalid value for the ipsec rekey interval: %v", err)
+		os.Exit(1)
+	}
+	if err := iptables.SetBackend(iptables.Backend(gatewayFlags.netfilterBackend)); err != nil {
+		klog.Errorf("invalid value for the netfilter backend: %v", err)
+		os.Exit(1)
+	}
+	if gatewayFlags.activeActive && gatewayFlags.wireguardKeyRotationInterval > 0 {
+		klog.Errorf("the rotation of the wireguard keys is not supported in active/active mode")
+		os.Exit(1)
+	}
+	if gatewayFlags.natReflector != "" && gatewayFlags.activeActive {
+		klog.Errorf("the NAT traversal is not supported in active/active mode")
+		os.Exit(1)
+	}
+	if gatewayFlags.natRelay && gatewayFlags.natReflector == "" {
+		klog.Errorf("the relay of the tunnel traffic requires the address of the NAT reflector")
+		os.Exit(1)
+	}
+	natRelaySecret, err := nattraversal.LoadRelaySecret(gatewayFlags.natRelaySecretFile)
+	if err != nil {
+		klog.Errorf("unable to load the relay secret: %v", err)
+		os.Exit(1)
+	}
+	if gatewayFlags.natRelay && len(natRelaySecret) == 0 {
+		klog.Errorf("the relay of the tunnel traffic requires the secret to authenticate the allocation requests")
+		os.Exit(1)
+	}
+	if gatewayFlags.natRelay && gatewayFlags.wireguardKeyRotationInterval > 0 {
+		klog.Errorf("the rotation of the wireguard keys is not supported when the tunnel traffic is relayed")
+		os.Exit(1)
+	}
+	if gatewayFlags.tunnelMTU < mtu.Minimum {
+		klog.Errorf("invalid tunnel MTU: it must be at least %d", mtu.Minimum)
+		os.Exit(1)
+	}
+	if gatewayFlags.probeInterval < 0 || gatewayFlags.probeFailureThreshold <= 0 {
+		klog.Errorf("invalid probe configuration: the interval must not be negative and the failure threshold must be positive")
+		os.Exit(1)
+	}
+
 	// Get the pod ip and parse to net.IP.
 	podIP, err := utils.GetPodIP()
 	if err != nil {
 		klog.Errorf("unable to get podIP: %v", err)
 		os.Exit(1)
 	}
+	// The IPv6 address is available only in dual-stack clusters.
+	var podIPv6 string
+	if ip := utils.GetPodIPv6(); ip != nil {
+		podIPv6 = ip.String()
+	}
 	podNamespace, err := utils.GetPodNamespace()
 	if err != nil {
 		klog.Errorf("unable to get pod namespace: %v", err)
 		os.Exit(1)
 	}
+	podName, err := utils.GetPodName()
+	if err != nil {
+		klog.Errorf("unable to get pod name: %v", err)
+		os.Exit(1)
+	}
 	main, err := ctrl.NewManager(restcfg.SetRateLimiter(ctrl.GetConfigOrDie()), ctrl.Options{
 		MapperProvider:                mapperUtils.LiqoMapperProvider(scheme),
 		Scheme:                        scheme,
@@ -115,13 +234,14 @@ func runGatewayOperator(commonFlags *liqonetCommonFlags, gatewayFlags *gatewayOp
 	}
 	klog.Infof("created custom network namespace {%s}", liqoconst.GatewayNetnsName)
 
-	labelController := tunneloperator.NewLabelerController(podIP.String(), main.GetClient())
+	labelController := tunneloperator.NewLabelerController(podIP.String(), gatewayFlags.activeActive, main.GetClient())
 	if err = labelController.SetupWithManager(main); err != nil {
 		klog.Errorf("unable to setup labeler controller: %s", err)
 		os.Exit(1)
 	}
-	tunnelController, err := tunneloperator.NewTunnelController(podIP.String(), podNamespace, eventRecorder,
-		clientset, main.GetClient(), &readyClustersMutex, readyClusters, gatewayNetns, hostNetns)
+	tunnelController, err := tunneloperator.NewTunnelController(podName, podIP.String(), podIPv6, podNamespace, eventRecorder,
+		clientset, main.GetClient(), &readyClustersMutex, readyClusters, gatewayNetns, hostNetns,
+		probe.Options{Interval: gatewayFlags.probeInterval, FailureThreshold: gatewayFlags.probeFailureThreshold}, gatewayFlags.activeActive)
 	// If something goes wrong while creating and configuring the tunnel controller
 	// then make sure that we remove all the resources created during the create process.
 	if err != nil {
@@ -130,12 +250,20 @@ func runGatewayOperator(commonFlags *liqonetCommonFlags, gatewayFlags *gatewayOp
 		if err := liqonetns.DeleteNetns(liqoconst.GatewayNetnsName); err != nil {
 			klog.Errorf("an error occurred while deleting netns {%s}: %v", liqoconst.GatewayNetnsName, err)
 		}
-		klog.Info("cleaning up wireguard tunnel interface")
+		klog.Info("cleaning up tunnel interfaces")
 		if err := utils.DeleteIFaceByName(tunnelwg.DeviceName); err != nil {
 			klog.Errorf("an error occurred while deleting iface {%s}: %v", tunnelwg.DriverName, err)
 		}
+		if err := utils.DeleteIFaceByName(tunnelipsec.DeviceName); err != nil {
+			klog.Errorf("an error occurred while deleting iface {%s}: %v", tunnelipsec.DriverName, err)
+		}
 		os.Exit(1)
 	}
+	tunnelController.MTU = gatewayFlags.tunnelMTU
+	tunnelController.PathMTUDiscovery = gatewayFlags.pathMTUDiscovery
+	tunnelController.NATReflector = gatewayFlags.natReflector
+	tunnelController.NATRelay = gatewayFlags.natRelay
+	tunnelController.NATRelaySecret = natRelaySecret
 	if err = tunnelController.SetupWithManager(main); err != nil {
 		klog.Errorf("unable to setup tunnel controller: %s", err)
 		os.Exit(1)
@@ -146,6 +274,25 @@ func runGatewayOperator(commonFlags *liqonetCommonFlags, gatewayFlags *gatewayOp
 		klog.Errorf("an error occurred while creating the natmapping controller: %v", err)
 		os.Exit(1)
 	}
+	if gatewayFlags.ebpfNAT {
+		// The programs are attached only to the interfaces of the initialized drivers, and attached again
+		// by the handler of the tunnel controller in case a driver recreates its interface.
+		devices := tunnelController.TunnelDevices()
+		linkNames := make([]string, 0, len(devices))
+		for _, name := range devices {
+			linkNames = append(linkNames, name)
+		}
+		var datapath *liqoebpf.Datapath
+		if err := gatewayNetns.Do(func(netNamespace ns.NetNS) error {
+			datapath, err = liqoebpf.NewDatapath(linkNames...)
+			return err
+		}); err != nil {
+			klog.Errorf("an error occurred while configuring the eBPF NAT datapath: %v", err)
+			os.Exit(1)
+		}
+		tunnelController.Handler = liqoebpf.NewHandler(tunnelController.Handler, datapath, devices)
+		natMappingController.Handler = liqoebpf.NewHandler(natMappingController.Handler, datapath, devices)
+	}
 	if err = natMappingController.SetupWithManager(main); err != nil {
 		klog.Errorf("unable to setup natmapping controller: %s", err)
 		os.Exit(1)
@@ -56,11 +56,13 @@ func main() {
 	routeFlags := &routeOperatorFlags{}
 	gatewayFlags := &gatewayOperatorFlags{}
 	managerFlags := &networkManagerFlags{}
+	reflectorFlags := &natReflectorFlags{}
 
 	addCommonFlags(commonFlags)
 	addGatewayOperatorFlags(gatewayFlags)
 	addRouteOperatorFlags(routeFlags)
 	addNetworkManagerFlags(managerFlags)
+	addNATReflectorFlags(reflectorFlags)
 
 	flag.Parse()
 
@@ -71,5 +73,7 @@ func main() {
 		runGatewayOperator(commonFlags, gatewayFlags)
 	case liqoconst.LiqoNetworkManagerName:
 		runNetworkManager(commonFlags, managerFlags)
+	case liqoconst.LiqoNATReflectorName:
+		runNATReflector(reflectorFlags)
 	}
 }
@@ -0,0 +1,74 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+)
+
+type natReflectorFlags struct {
+	address                   string
+	relay                     bool
+	relaySecretFile           string
+	relayMaxSessions          int
+	relayMaxSessionsPerSource int
+}
+
+func addNATReflectorFlags(liqonet *natReflectorFlags) {
+	flag.StringVar(&liqonet.address, "reflector.address", fmt.Sprintf(":%d", nattraversal.DefaultReflectorPort),
+		"The address the NAT reflector listens on")
+	flag.BoolVar(&liqonet.relay, "reflector.relay", false,
+		"relay enables the relaying of the tunnel traffic between the gateways unable to communicate directly")
+	flag.StringVar(&liqonet.relaySecretFile, "reflector.relay-secret-file", "",
+		fmt.Sprintf("The file containing the secret shared with the gateways to authenticate the allocation requests, "+
+			"required if the relay is enabled. If empty, the secret is read from the %s environment variable", nattraversal.RelaySecretEnvVar))
+	flag.IntVar(&liqonet.relayMaxSessions, "reflector.relay-max-sessions", nattraversal.DefaultRelayMaxSessions,
+		"The maximum number of sessions handled by the relay")
+	flag.IntVar(&liqonet.relayMaxSessionsPerSource, "reflector.relay-max-sessions-per-source", nattraversal.DefaultRelayMaxSessionsPerSource,
+		"The maximum number of sessions created from the same source address")
+}
+
+func runNATReflector(reflectorFlags *natReflectorFlags) {
+	var relayConfig *nattraversal.RelayConfig
+	if reflectorFlags.relay {
+		secret, err := nattraversal.LoadRelaySecret(reflectorFlags.relaySecretFile)
+		if err != nil {
+			klog.Errorf("unable to load the relay secret: %v", err)
+			os.Exit(1)
+		}
+		relayConfig = &nattraversal.RelayConfig{
+			Secret:               secret,
+			MaxSessions:          reflectorFlags.relayMaxSessions,
+			MaxSessionsPerSource: reflectorFlags.relayMaxSessionsPerSource,
+		}
+	}
+	reflector, err := nattraversal.NewReflector(reflectorFlags.address, relayConfig)
+	if err != nil {
+		klog.Errorf("unable to create the NAT reflector: %v", err)
+		os.Exit(1)
+	}
+	klog.Infof("Starting the NAT reflector on %s (relay enabled: %t)", reflector.Addr(), reflectorFlags.relay)
+	if err := reflector.Start(ctrl.SetupSignalHandler()); err != nil {
+		klog.Errorf("an error occurred while running the NAT reflector: %v", err)
+		os.Exit(1)
+	}
+}
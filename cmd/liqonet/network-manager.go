@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -27,6 +28,8 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 
+	"github.com/liqotech/liqo/internal/liqonet/network-manager/ipamchecker"
+	"github.com/liqotech/liqo/internal/liqonet/network-manager/ipamreservation"
 	"github.com/liqotech/liqo/internal/liqonet/network-manager/netcfgcreator"
 	"github.com/liqotech/liqo/internal/liqonet/network-manager/tunnelendpointcreator"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
@@ -41,17 +44,38 @@ type networkManagerFlags struct {
 	podCIDR     string
 	serviceCIDR string
 
+	podCIDRv6     string
+	serviceCIDRv6 string
+
 	additionalPools args.StringList
 	reservedPools   args.StringList
+
+	gatewayActiveActive bool
+	transitRouting      bool
+
+	ipamCheckPeriod time.Duration
+	ipamRepair      bool
 }
 
 func addNetworkManagerFlags(managerFlags *networkManagerFlags) {
 	flag.StringVar(&managerFlags.podCIDR, "manager.pod-cidr", "", "The subnet used by the cluster for the pods, in CIDR notation")
 	flag.StringVar(&managerFlags.serviceCIDR, "manager.service-cidr", "", "The subnet used by the cluster for the pods, in services notation")
+	flag.StringVar(&managerFlags.podCIDRv6, "manager.pod-cidr-v6", "",
+		"The IPv6 subnet used by the cluster for the pods, in CIDR notation (dual-stack clusters only)")
+	flag.StringVar(&managerFlags.serviceCIDRv6, "manager.service-cidr-v6", "",
+		"The IPv6 subnet used by the cluster for the services, in CIDR notation (dual-stack clusters only)")
 	flag.Var(&managerFlags.reservedPools, "manager.reserved-pools",
 		"Private CIDRs slices used by the Kubernetes infrastructure, in addition to the pod and service CIDR (e.g., the node subnet).")
 	flag.Var(&managerFlags.additionalPools, "manager.additional-pools",
 		"Network pools used to map a cluster network into another one in order to prevent conflicts, in addition to standard private CIDRs.")
+	flag.BoolVar(&managerFlags.gatewayActiveActive, "manager.gateway-active-active", false,
+		"Whether the gateway replicas run in active/active mode, each one handling the tunnels towards a subset of the remote clusters")
+	flag.BoolVar(&managerFlags.transitRouting, "manager.transit-routing", false,
+		"Whether the networks of the peered clusters are advertised to the other ones, to let them communicate through the local cluster")
+	flag.DurationVar(&managerFlags.ipamCheckPeriod, "manager.ipam-check-period", 10*time.Minute,
+		"The period between two consistency checks of the IPAM configuration against the peered clusters (0 to disable)")
+	flag.BoolVar(&managerFlags.ipamRepair, "manager.ipam-repair", false,
+		"Whether the drifts detected by the IPAM consistency checks have to be repaired, rather than only reported")
 }
 
 func validateNetworkManagerFlags(managerFlags *networkManagerFlags) error {
@@ -65,14 +89,23 @@ func validateNetworkManagerFlags(managerFlags *networkManagerFlags) error {
 		return fmt.Errorf("service CIDR is empty or invalid (%q)", managerFlags.serviceCIDR)
 	}
 
+	if managerFlags.podCIDRv6 != "" || managerFlags.serviceCIDRv6 != "" {
+		if !utils.IsIPv6Network(managerFlags.podCIDRv6) {
+			return fmt.Errorf("IPv6 pod CIDR is empty or invalid (%q)", managerFlags.podCIDRv6)
+		}
+		if !utils.IsIPv6Network(managerFlags.serviceCIDRv6) {
+			return fmt.Errorf("IPv6 service CIDR is empty or invalid (%q)", managerFlags.serviceCIDRv6)
+		}
+	}
+
 	for _, pool := range managerFlags.reservedPools.StringList {
-		if !cidrRegex.MatchString(pool) {
+		if !cidrRegex.MatchString(pool) && !utils.IsIPv6Network(pool) {
 			return fmt.Errorf("reserved pool entry empty or invalid (%q)", pool)
 		}
 	}
 
 	for _, pool := range managerFlags.additionalPools.StringList {
-		if !cidrRegex.MatchString(pool) {
+		if !cidrRegex.MatchString(pool) && !utils.IsIPv6Network(pool) {
 			return fmt.Errorf("additional pool entry empty or invalid (%q)", pool)
 		}
 	}
@@ -100,6 +133,7 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 			SelectorsByObject: cache.SelectorsByObject{
 				&corev1.Secret{}:  {Field: fields.OneTermEqualSelector("metadata.namespace", podNamespace)},
 				&corev1.Service{}: {Field: fields.OneTermEqualSelector("metadata.namespace", podNamespace)},
+				&corev1.Pod{}:     {Field: fields.OneTermEqualSelector("metadata.namespace", podNamespace)},
 			},
 		}),
 	})
@@ -121,18 +155,34 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 		os.Exit(1)
 	}
 
+	var externalCIDRv6 string
+	if managerFlags.podCIDRv6 != "" {
+		if externalCIDRv6, err = ipam.GetExternalCIDRv6(utils.GetMask(managerFlags.podCIDRv6)); err != nil {
+			klog.Errorf("Failed to initialize the IPv6 external CIDR: %v", err)
+			os.Exit(1)
+		}
+	}
+
 	tec := &tunnelendpointcreator.TunnelEndpointCreator{
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		IPManager: ipam,
+		DualStack: managerFlags.podCIDRv6 != "",
+
+		TransitRouting: managerFlags.transitRouting,
 	}
 
 	ncc := &netcfgcreator.NetworkConfigCreator{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 
-		PodCIDR:      managerFlags.podCIDR,
-		ExternalCIDR: externalCIDR,
+		PodCIDR:        managerFlags.podCIDR,
+		ExternalCIDR:   externalCIDR,
+		PodCIDRv6:      managerFlags.podCIDRv6,
+		ExternalCIDRv6: externalCIDRv6,
+
+		ActiveActiveGateway: managerFlags.gatewayActiveActive,
+		TransitRouting:      managerFlags.transitRouting,
 	}
 
 	if err = tec.SetupWithManager(mgr); err != nil {
@@ -145,6 +195,29 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 		os.Exit(1)
 	}
 
+	irr := &ipamreservation.IpamReservationReconciler{
+		Client:    mgr.GetClient(),
+		IPManager: ipam,
+	}
+	if err = irr.SetupWithManager(mgr); err != nil {
+		klog.Errorf("unable to create controller IpamReservationReconciler: %s", err)
+		os.Exit(1)
+	}
+
+	if managerFlags.ipamCheckPeriod > 0 {
+		checker := &ipamchecker.IpamChecker{
+			Reader:    mgr.GetAPIReader(),
+			IPManager: ipam,
+			Recorder:  mgr.GetEventRecorderFor(liqoconst.LiqoNetworkManagerName),
+			Period:    managerFlags.ipamCheckPeriod,
+			Repair:    managerFlags.ipamRepair,
+		}
+		if err = mgr.Add(checker); err != nil {
+			klog.Errorf("unable to add the IPAM consistency checker: %s", err)
+			os.Exit(1)
+		}
+	}
+
 	klog.Info("starting manager as liqo-network-manager")
 	if err := mgr.Start(tec.SetupSignalHandlerForTunEndCreator()); err != nil {
 		klog.Errorf("an error occurred while starting manager: %s", err)
@@ -155,7 +228,11 @@ func runNetworkManager(commonFlags *liqonetCommonFlags, managerFlags *networkMan
 func initializeIPAM(client dynamic.Interface, managerFlags *networkManagerFlags) (*liqonetIpam.IPAM, error) {
 	ipam := liqonetIpam.NewIPAM()
 
-	if err := ipam.Init(liqonetIpam.Pools, client, liqoconst.NetworkManagerIpamPort); err != nil {
+	pools := liqonetIpam.Pools
+	if managerFlags.podCIDRv6 != "" {
+		pools = append(append([]string{}, liqonetIpam.Pools...), liqonetIpam.PoolsV6...)
+	}
+	if err := ipam.Init(pools, client, liqoconst.NetworkManagerIpamPort); err != nil {
 		return nil, err
 	}
 
@@ -165,6 +242,14 @@ func initializeIPAM(client dynamic.Interface, managerFlags *networkManagerFlags)
 	if err := ipam.SetServiceCIDR(managerFlags.serviceCIDR); err != nil {
 		return nil, err
 	}
+	if managerFlags.podCIDRv6 != "" {
+		if err := ipam.SetPodCIDR(managerFlags.podCIDRv6); err != nil {
+			return nil, err
+		}
+		if err := ipam.SetServiceCIDR(managerFlags.serviceCIDRv6); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, pool := range managerFlags.additionalPools.StringList {
 		if err := ipam.AddNetworkPool(pool); err != nil {
@@ -16,6 +16,7 @@ package main
 
 import (
 	"flag"
+	"net"
 	"os"
 	"strings"
 	"sync"
@@ -30,6 +31,7 @@ import (
 
 	routeoperator "github.com/liqotech/liqo/internal/liqonet/route-operator"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
 	"github.com/liqotech/liqo/pkg/liqonet/overlay"
 	liqorouting "github.com/liqotech/liqo/pkg/liqonet/routing"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
@@ -45,7 +47,8 @@ type routeOperatorFlags struct {
 
 func addRouteOperatorFlags(liqonet *routeOperatorFlags) {
 	flag.IntVar(&liqonet.vni, "route.vxlan-vni", 18952, "VXLAN Virtual Network Identifier (VNI) for the Liqonet intra-cluster overlay network")
-	flag.IntVar(&liqonet.mtu, "route.vxlan-mtu", 1420, "VXLAN Max Transmit Unit (MTU) for the Liqonet intra-cluster overlay network")
+	flag.IntVar(&liqonet.mtu, "route.vxlan-mtu", 1420, "VXLAN Max Transmit Unit (MTU) for the Liqonet intra-cluster overlay network. "+
+		"Zero derives it from the MTU of the node interface, minus the VXLAN overhead")
 	flag.IntVar(&liqonet.vtepPort, "route.vxlan-vtep-port", 4879,
 		"VXLAN Virtual Tunnel Endpoints (VTEP) port for the Liqonet intra-cluster overlay network")
 }
@@ -135,6 +138,9 @@ func runRouteOperator(commonFlags *liqonetCommonFlags, routeFlags *routeOperator
 		os.Exit(1)
 	}
 	vxlanConfig.VtepAddr = podIP
+	if vxlanConfig.MTU == 0 {
+		vxlanConfig.MTU = overlayMTU(podIP)
+	}
 	vxlanDevice, err := overlay.NewVxlanDevice(vxlanConfig)
 	if err != nil {
 		klog.Errorf("an error occurred while creating vxlan device : %v", err)
@@ -147,7 +153,9 @@ func runRouteOperator(commonFlags *liqonetCommonFlags, routeFlags *routeOperator
 		os.Exit(1)
 	}
 	eventRecorder := mainMgr.GetEventRecorderFor(liqoconst.LiqoRouteOperatorName + "." + podIP.String())
-	routeController := routeoperator.NewRouteController(podIP.String(), vxlanDevice, vxlanRoutingManager, eventRecorder, mainMgr.GetClient())
+	// The routes towards the clusters reached through the remote ones are configured along with the ones of the latter.
+	routeController := routeoperator.NewRouteController(podIP.String(), vxlanDevice,
+		liqorouting.NewTransitRoutingManager(vxlanRoutingManager), eventRecorder, mainMgr.GetClient())
 	if err = routeController.SetupWithManager(mainMgr); err != nil {
 		klog.Errorf("unable to setup controller: %s", err)
 		os.Exit(1)
@@ -180,3 +188,15 @@ func runRouteOperator(commonFlags *liqonetCommonFlags, routeFlags *routeOperator
 		os.Exit(1)
 	}
 }
+
+// overlayMTU returns the MTU of the vxlan device, derived from the one of the interface the given address is assigned to.
+func overlayMTU(podIP net.IP) int {
+	linkMTU, err := mtu.LinkMTU(podIP)
+	if err != nil {
+		klog.Warningf("unable to retrieve the MTU of the interface with address %s, falling back to %d: %v", podIP, mtu.DefaultPathMTU, err)
+		linkMTU = mtu.DefaultPathMTU
+	}
+	vxlanMTU := linkMTU - mtu.VxlanOverhead
+	klog.Infof("MTU of the VXLAN overlay network set to %d", vxlanMTU)
+	return vxlanMTU
+}
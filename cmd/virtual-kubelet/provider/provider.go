@@ -17,6 +17,7 @@ package provider
 import (
 	"context"
 	"io"
+	"net/http"
 
 	module "github.com/virtual-kubelet/virtual-kubelet/node"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
@@ -50,3 +51,13 @@ type Provider interface {
 type PodMetricsProvider interface {
 	GetStatsSummary(context.Context) (*stats.Summary, error)
 }
+
+// PodStreamingProvider is an optional interface that providers can implement to support
+// attaching to running containers and forwarding ports to pods.
+type PodStreamingProvider interface {
+	// AttachToContainer attaches to a running container of the pod, serving the given upgrade request.
+	AttachToContainer(w http.ResponseWriter, req *http.Request, namespace, podName, containerName string)
+
+	// PortForward forwards the ports requested by the given upgrade request to the pod.
+	PortForward(w http.ResponseWriter, req *http.Request, namespace, podName string)
+}
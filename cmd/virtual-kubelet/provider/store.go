@@ -89,6 +89,12 @@ type InitConfig struct {
 	RemoteClusterID      string
 	LiqoIpamServer       string
 	InformerResyncPeriod time.Duration
+	StorageClassMapping  map[string]string
+	IngressClassMapping  map[string]string
+	HomeAPIServerHost    string
+	HomeAPIServerPort    string
+	DeniedPodSpecFields  []string
+	ReflectedVolumeTypes []string
 }
 
 // InitFunc defines the signature of the function creating a Provider instance based on the corresponding configuration.
@@ -33,6 +33,12 @@ func registerKubernetes(ctx context.Context, s *provider.Store) error {
 			cfg.HomeKubeConfig,
 			cfg.InformerResyncPeriod,
 			cfg.LiqoIpamServer,
+			cfg.StorageClassMapping,
+			cfg.IngressClassMapping,
+			cfg.HomeAPIServerHost,
+			cfg.HomeAPIServerPort,
+			cfg.DeniedPodSpecFields,
+			cfg.ReflectedVolumeTypes,
 		)
 	})
 }
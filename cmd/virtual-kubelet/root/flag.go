@@ -46,6 +46,18 @@ func InstallFlags(flags *pflag.FlagSet, c *Opts) {
 
 	flags.Var(&c.NodeExtraAnnotations, "node-extra-annotations", "Extra annotations to add to the Virtual Node")
 	flags.Var(&c.NodeExtraLabels, "node-extra-labels", "Extra labels to add to the Virtual Node")
+	flags.Var(&c.StorageClassMapping, "storage-class-mapping",
+		"Mapping between home and foreign storage classes for the reflected PersistentVolumeClaims (e.g. standard=gp2,fast=io1)")
+	flags.Var(&c.IngressClassMapping, "ingress-class-mapping",
+		"Mapping between home and foreign ingress classes for the reflected Ingresses, optionally restricted to a remote cluster "+
+			"(e.g. nginx=traefik,<cluster-id>/nginx=haproxy)")
+	flags.StringVar(&c.HomeAPIServerHost, "home-api-server-host", c.HomeAPIServerHost,
+		"Home API server address reachable from the offloaded pods, which authenticate with the home ServiceAccount tokens if set")
+	flags.StringSliceVar(&c.DeniedPodSpecFields, "denied-pod-spec-fields", c.DeniedPodSpecFields,
+		"Pod spec fields (JSON names, prefixed by 'containers.' for the container ones) which are not reflected to the foreign cluster")
+	flags.StringSliceVar(&c.ReflectedVolumeTypes, "reflected-volume-types", c.ReflectedVolumeTypes,
+		"Volume types (JSON names of the volume sources) which are reflected to the foreign cluster, while the pods mounting other ones are rejected")
+	flags.StringVar(&c.HomeAPIServerPort, "home-api-server-port", c.HomeAPIServerPort, "Home API server port reachable from the offloaded pods")
 
 	flagset := flag.NewFlagSet("klog", flag.PanicOnError)
 	klog.InitFlags(flagset)
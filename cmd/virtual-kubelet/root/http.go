@@ -24,11 +24,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
 	"k8s.io/klog/v2"
 
 	"github.com/liqotech/liqo/cmd/virtual-kubelet/provider"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/metrics"
 )
 
 // AcceptedCiphers is the list of accepted TLS ciphers, with known weak ciphers elided
@@ -102,6 +104,9 @@ func setupHTTPServer(ctx context.Context, p provider.Provider, cfg *apiServerCon
 			GetStatsSummary: summaryHandlerFunc,
 		}
 		api.AttachPodMetricsRoutes(podMetricsRoutes, mux)
+		if summaryHandlerFunc != nil {
+			mux.Handle(metrics.ResourceMetricsPath, metrics.NewResourceMetricsHandler(metrics.StatsSummaryFunc(summaryHandlerFunc)))
+		}
 		s := &http.Server{
 			Handler: mux,
 		}
@@ -126,7 +131,7 @@ func startPodHandlerServer(ctx context.Context, p provider.Provider,
 		return nil, errors.Wrap(err, "error setting up listener for pod http server")
 	}
 
-	mux := http.NewServeMux()
+	serveMux := http.NewServeMux()
 
 	podRoutes := api.PodHandlerConfig{
 		RunInContainer:        p.RunInContainer,
@@ -136,16 +141,38 @@ func startPodHandlerServer(ctx context.Context, p provider.Provider,
 		GetPods:               p.GetPods,
 	}
 
-	api.AttachPodRoutes(podRoutes, mux, true)
+	api.AttachPodRoutes(podRoutes, serveMux, true)
+	serveMux.Handle(metrics.ResourceMetricsPath, metrics.NewResourceMetricsHandler(p.GetStatsSummary))
+	if sp, ok := p.(provider.PodStreamingProvider); ok {
+		attachPodStreamingRoutes(sp, serveMux)
+	}
 
 	s := &http.Server{
-		Handler:   mux,
+		Handler:   serveMux,
 		TLSConfig: tlsCfg,
 	}
 	go serveHTTP(ctx, s, l, "pods")
 	return s, nil
 }
 
+// attachPodStreamingRoutes adds the http routes to attach to containers and forward ports to pods,
+// which are not provided by the virtual-kubelet library.
+func attachPodStreamingRoutes(p provider.PodStreamingProvider, serveMux api.ServeMux) {
+	r := mux.NewRouter()
+	r.HandleFunc("/attach/{namespace}/{pod}/{container}", func(w http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		p.AttachToContainer(w, req, vars["namespace"], vars["pod"], vars["container"])
+	}).Methods(http.MethodPost, http.MethodGet)
+	r.HandleFunc("/portForward/{namespace}/{pod}", func(w http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		p.PortForward(w, req, vars["namespace"], vars["pod"])
+	}).Methods(http.MethodPost, http.MethodGet)
+	r.NotFoundHandler = http.HandlerFunc(api.NotFound)
+
+	serveMux.Handle("/attach/", api.InstrumentHandler(r))
+	serveMux.Handle("/portForward/", api.InstrumentHandler(r))
+}
+
 func serveHTTP(ctx context.Context, s *http.Server, l net.Listener, name string) {
 	if err := s.Serve(l); err != nil {
 		select {
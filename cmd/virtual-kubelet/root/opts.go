@@ -23,6 +23,7 @@ import (
 
 	"github.com/liqotech/liqo/pkg/consts"
 	argsutils "github.com/liqotech/liqo/pkg/utils/args"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
 )
 
 // Defaults for root command options.
@@ -38,6 +39,8 @@ const (
 	DefaultKubeletNamespace = "default"
 	DefaultHomeClusterID    = "cluster1"
 	DefaultLiqoIpamServer   = consts.NetworkManagerServiceName
+
+	DefaultHomeAPIServerPort = "443"
 )
 
 // Opts stores all the options for configuring the root virtual-kubelet command.
@@ -86,6 +89,22 @@ type Opts struct {
 
 	NodeExtraAnnotations argsutils.StringMap
 	NodeExtraLabels      argsutils.StringMap
+
+	// Mapping between the home and the foreign storage classes, used when reflecting PersistentVolumeClaims
+	StorageClassMapping argsutils.StringMap
+	// Mapping between the home and the foreign ingress classes, used when reflecting Ingresses.
+	// The entries prefixed by a cluster ID apply only when offloading to that cluster
+	IngressClassMapping argsutils.StringMap
+
+	// Address of the home API server, as reachable from the offloaded pods.
+	// If set, the offloaded pods authenticate against the home API server with the tokens of the home ServiceAccounts
+	HomeAPIServerHost string
+	HomeAPIServerPort string
+
+	// Fields of the pod spec (and of its containers) which are not reflected to the foreign cluster
+	DeniedPodSpecFields []string
+	// Types of the volumes which are reflected to the foreign cluster, while the pods mounting other ones are rejected
+	ReflectedVolumeTypes []string
 }
 
 // SetDefaultOpts sets default options for unset values on the passed in option struct.
@@ -137,5 +156,17 @@ func SetDefaultOpts(c *Opts) error {
 		c.LiqoIpamServer = DefaultLiqoIpamServer
 	}
 
+	if c.HomeAPIServerPort == "" {
+		c.HomeAPIServerPort = DefaultHomeAPIServerPort
+	}
+
+	if c.DeniedPodSpecFields == nil {
+		c.DeniedPodSpecFields = forge.DefaultDeniedPodSpecFields
+	}
+
+	if c.ReflectedVolumeTypes == nil {
+		c.ReflectedVolumeTypes = forge.DefaultReflectedVolumeTypes
+	}
+
 	return nil
 }
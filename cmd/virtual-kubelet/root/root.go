@@ -122,6 +122,12 @@ func runRootCommand(ctx context.Context, s *provider.Store, c *Opts) error {
 		HomeClusterID:        c.HomeClusterID,
 		InformerResyncPeriod: c.InformerResyncPeriod,
 		LiqoIpamServer:       c.LiqoIpamServer,
+		StorageClassMapping:  c.StorageClassMapping.StringMap,
+		IngressClassMapping:  c.IngressClassMapping.StringMap,
+		HomeAPIServerHost:    c.HomeAPIServerHost,
+		HomeAPIServerPort:    c.HomeAPIServerPort,
+		DeniedPodSpecFields:  c.DeniedPodSpecFields,
+		ReflectedVolumeTypes: c.ReflectedVolumeTypes,
 	}
 
 	pInit := s.Get(c.Provider)
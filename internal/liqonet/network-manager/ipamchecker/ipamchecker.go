@@ -0,0 +1,153 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipamchecker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// IpamChecker cross-checks the IPAM configuration against the ForeignClusters, NetworkConfigs and
+// TunnelEndpoints at startup and then periodically, reporting the drifts as events on the IpamStorage resource.
+type IpamChecker struct {
+	// Reader is used to retrieve the peering resources. A non-caching reader is
+	// preferred, to prevent the configuration of new peerings from being considered orphaned.
+	Reader    client.Reader
+	IPManager liqonetIpam.Ipam
+	Recorder  record.EventRecorder
+
+	// Period is the interval between two consecutive checks.
+	Period time.Duration
+	// Repair is true if the detected drifts have to be fixed.
+	Repair bool
+}
+
+// +kubebuilder:rbac:groups=discovery.liqo.io,resources=foreignclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=net.liqo.io,resources=networkconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints,verbs=get;list;watch
+// +kubebuilder:rbac:groups=net.liqo.io,resources=ipamstorages,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Start runs the consistency check once, and then periodically until the context is canceled.
+// It implements the manager.Runnable interface.
+func (ic *IpamChecker) Start(ctx context.Context) error {
+	klog.Infof("Starting the IPAM consistency checker (period: %v, repair: %t)", ic.Period, ic.Repair)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := ic.check(ctx); err != nil {
+			klog.Errorf("IPAM consistency check failed: %v", err)
+		}
+	}, ic.Period)
+	return nil
+}
+
+// check performs a consistency check and reports the detected drifts.
+func (ic *IpamChecker) check(ctx context.Context) error {
+	var peered map[string]bool
+	// The peered clusters are retrieved once the IPAM has been locked by CheckConsistency,
+	// so that no cluster can be configured in the meanwhile.
+	isPeered := func(clusterID string) (bool, error) {
+		if peered == nil {
+			var err error
+			if peered, err = ic.peeredClusters(ctx); err != nil {
+				return false, err
+			}
+		}
+		return peered[clusterID], nil
+	}
+
+	drifts, err := ic.IPManager.CheckConsistency(isPeered, ic.Repair)
+	ic.report(ctx, drifts)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		klog.V(4).Info("IPAM configuration is consistent")
+	}
+	return nil
+}
+
+// peeredClusters returns the set of remote clusters which are (being) peered or unpeered,
+// i.e. the ones with a ForeignCluster, a NetworkConfig or a TunnelEndpoint.
+func (ic *IpamChecker) peeredClusters(ctx context.Context) (map[string]bool, error) {
+	peered := make(map[string]bool)
+
+	var foreignClusters discoveryv1alpha1.ForeignClusterList
+	if err := ic.Reader.List(ctx, &foreignClusters); err != nil {
+		return nil, fmt.Errorf("unable to list ForeignClusters: %w", err)
+	}
+	for i := range foreignClusters.Items {
+		peered[foreignClusters.Items[i].Spec.ClusterIdentity.ClusterID] = true
+	}
+
+	var networkConfigs netv1alpha1.NetworkConfigList
+	if err := ic.Reader.List(ctx, &networkConfigs); err != nil {
+		return nil, fmt.Errorf("unable to list NetworkConfigs: %w", err)
+	}
+	for i := range networkConfigs.Items {
+		// The spec of a remote NetworkConfig carries the local cluster ID, while the sender is stored in the labels.
+		if clusterID, ok := networkConfigs.Items[i].GetLabels()[liqoconst.ReplicationOriginLabel]; ok {
+			peered[clusterID] = true
+		} else {
+			peered[networkConfigs.Items[i].Spec.ClusterID] = true
+		}
+	}
+
+	var tunnelEndpoints netv1alpha1.TunnelEndpointList
+	if err := ic.Reader.List(ctx, &tunnelEndpoints); err != nil {
+		return nil, fmt.Errorf("unable to list TunnelEndpoints: %w", err)
+	}
+	for i := range tunnelEndpoints.Items {
+		peered[tunnelEndpoints.Items[i].Spec.ClusterID] = true
+	}
+	return peered, nil
+}
+
+// report logs the drifts and records them as events on the IpamStorage resource.
+func (ic *IpamChecker) report(ctx context.Context, drifts []liqonetIpam.Drift) {
+	if len(drifts) == 0 {
+		return
+	}
+
+	var ipamStorages netv1alpha1.IpamStorageList
+	if err := ic.Reader.List(ctx, &ipamStorages, client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(labels.Set{
+		liqoconst.IpamStorageResourceLabelKey: liqoconst.IpamStorageResourceLabelValue,
+	})}); err != nil {
+		klog.Errorf("unable to retrieve the IpamStorage resource: %v", err)
+	}
+
+	for i := range drifts {
+		message := drifts[i].Message
+		if drifts[i].Repaired {
+			message = "Repaired: " + message
+		}
+		klog.Warningf("IPAM drift detected (%s): %s", drifts[i].Type, message)
+		if len(ipamStorages.Items) == 1 {
+			ic.Recorder.Event(&ipamStorages.Items[0], corev1.EventTypeWarning, string(drifts[i].Type), message)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipamchecker
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// fakeIPAM implements the CheckConsistency method of the Ipam interface, querying the
+// peering checker for the configured clusters and returning a drift for each non-peered one.
+type fakeIPAM struct {
+	liqonetIpam.Ipam
+	configuredClusters []string
+	repair             bool
+}
+
+func (ipam *fakeIPAM) CheckConsistency(isPeered liqonetIpam.PeeringChecker, repair bool) ([]liqonetIpam.Drift, error) {
+	ipam.repair = repair
+	var drifts []liqonetIpam.Drift
+	for _, clusterID := range ipam.configuredClusters {
+		peered, err := isPeered(clusterID)
+		if err != nil {
+			return drifts, err
+		}
+		if !peered {
+			drifts = append(drifts, liqonetIpam.Drift{
+				Type: liqonetIpam.OrphanedClusterDrift, ClusterID: clusterID, Message: "orphaned " + clusterID, Repaired: repair,
+			})
+		}
+	}
+	return drifts, nil
+}
+
+var _ = Describe("IpamChecker", func() {
+	var (
+		checker  *IpamChecker
+		ipam     *fakeIPAM
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(discoveryv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		objects := []runtime.Object{
+			&discoveryv1alpha1.ForeignCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foreign-cluster"},
+				Spec: discoveryv1alpha1.ForeignClusterSpec{
+					ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: "cluster-fc"},
+				},
+			},
+			&netv1alpha1.NetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "local-netcfg", Namespace: "tenant"},
+				Spec:       netv1alpha1.NetworkConfigSpec{ClusterID: "cluster-local-netcfg"},
+			},
+			&netv1alpha1.NetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "remote-netcfg", Namespace: "tenant",
+					Labels: map[string]string{liqoconst.ReplicationOriginLabel: "cluster-remote-netcfg"}},
+				Spec: netv1alpha1.NetworkConfigSpec{ClusterID: "local-cluster"},
+			},
+			&netv1alpha1.TunnelEndpoint{
+				ObjectMeta: metav1.ObjectMeta{Name: "tep", Namespace: "tenant"},
+				Spec:       netv1alpha1.TunnelEndpointSpec{ClusterID: "cluster-tep"},
+			},
+			&netv1alpha1.IpamStorage{
+				ObjectMeta: metav1.ObjectMeta{Name: "ipamstorage",
+					Labels: map[string]string{liqoconst.IpamStorageResourceLabelKey: liqoconst.IpamStorageResourceLabelValue}},
+			},
+		}
+
+		ipam = &fakeIPAM{}
+		recorder = record.NewFakeRecorder(10)
+		checker = &IpamChecker{
+			Reader:    fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+			IPManager: ipam,
+			Recorder:  recorder,
+			Repair:    true,
+		}
+	})
+
+	Context("When all the configured clusters are peered", func() {
+		It("should not record any event", func() {
+			ipam.configuredClusters = []string{"cluster-fc", "cluster-local-netcfg", "cluster-remote-netcfg", "cluster-tep"}
+			Expect(checker.check(context.Background())).To(Succeed())
+			Expect(ipam.repair).To(BeTrue())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+	})
+
+	Context("When a configured cluster is no longer peered", func() {
+		It("should record an event on the IpamStorage resource", func() {
+			ipam.configuredClusters = []string{"cluster-fc", "local-cluster", "cluster-orphaned"}
+			Expect(checker.check(context.Background())).To(Succeed())
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(<-recorder.Events).To(Equal("Warning OrphanedCluster Repaired: orphaned local-cluster"))
+			Expect(<-recorder.Events).To(Equal("Warning OrphanedCluster Repaired: orphaned cluster-orphaned"))
+		})
+	})
+})
@@ -0,0 +1,155 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipamreservation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// IpamReservationReconciler reserves the networks and pins the remappings declared by the IpamReservation resources.
+type IpamReservationReconciler struct {
+	client.Client
+	IPManager liqonetIpam.Ipam
+}
+
+var ipamReservationFinalizer = strings.Join([]string{"ipamreservation", liqoconst.FinalizersSuffix}, ".")
+
+// +kubebuilder:rbac:groups=net.liqo.io,resources=ipamreservations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=net.liqo.io,resources=ipamreservations/status;ipamreservations/finalizers,verbs=get;update;patch
+
+// Reconcile reserves the networks and pins the remappings declared by an IpamReservation,
+// releasing the ones no longer declared, and reports the enforced configuration in its status.
+func (r *IpamReservationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	klog.V(4).Infof("Reconciling IpamReservation %q", req.Name)
+
+	var reservation netv1alpha1.IpamReservation
+	if err := r.Get(ctx, req.NamespacedName, &reservation); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed retrieving IpamReservation %q: %v", req.Name, err)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !reservation.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(&reservation, ipamReservationFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.enforce(ctx, &reservation, nil, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&reservation, ipamReservationFinalizer)
+		if err := r.Update(ctx, &reservation); err != nil {
+			klog.Errorf("Failed removing finalizer from IpamReservation %q: %v", req.Name, err)
+			return ctrl.Result{}, err
+		}
+		klog.Infof("Networks reserved by IpamReservation %q have been released", req.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&reservation, ipamReservationFinalizer) {
+		controllerutil.AddFinalizer(&reservation, ipamReservationFinalizer)
+		if err := r.Update(ctx, &reservation); err != nil {
+			klog.Errorf("Failed adding finalizer to IpamReservation %q: %v", req.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	remappings := make(map[string]netv1alpha1.PinnedSubnets, len(reservation.Spec.ClusterRemappings))
+	for i := range reservation.Spec.ClusterRemappings {
+		remapping := &reservation.Spec.ClusterRemappings[i]
+		remappings[remapping.ClusterID] = remapping.PinnedSubnets
+	}
+	return ctrl.Result{}, r.enforce(ctx, &reservation, sets.NewString(reservation.Spec.ReservedSubnets...), remappings)
+}
+
+// enforce aligns the reserved networks and the pinned remappings to the desired ones, and updates the status
+// accordingly. The errors occurred are reported in the status as well, and returned to trigger a retry.
+func (r *IpamReservationReconciler) enforce(ctx context.Context, reservation *netv1alpha1.IpamReservation,
+	reserved sets.String, remappings map[string]netv1alpha1.PinnedSubnets) error {
+	var errs []string
+	status := &reservation.Status
+	currentReserved := sets.NewString(status.ReservedSubnets...)
+	currentPinned := sets.NewString(status.PinnedClusters...)
+
+	// Release the networks no longer reserved, and reserve the new ones.
+	for _, network := range currentReserved.Difference(reserved).List() {
+		if err := r.IPManager.FreeReservedSubnet(network); err != nil {
+			errs = append(errs, fmt.Sprintf("cannot release network %s: %v", network, err))
+			continue
+		}
+		currentReserved.Delete(network)
+	}
+	for _, network := range reserved.Difference(currentReserved).List() {
+		if err := r.IPManager.AcquireReservedSubnet(network); err != nil {
+			errs = append(errs, fmt.Sprintf("cannot reserve network %s: %v", network, err))
+			continue
+		}
+		currentReserved.Insert(network)
+	}
+
+	// Remove the pins of the clusters no longer declared, and configure the other ones.
+	for _, clusterID := range currentPinned.List() {
+		if _, found := remappings[clusterID]; found {
+			continue
+		}
+		if err := r.IPManager.RemovePinnedSubnets(clusterID, reservation.GetName()); err != nil {
+			errs = append(errs, fmt.Sprintf("cannot remove the networks pinned for cluster %s: %v", clusterID, err))
+			continue
+		}
+		currentPinned.Delete(clusterID)
+	}
+	for _, clusterID := range sets.StringKeySet(remappings).List() {
+		if err := r.IPManager.SetPinnedSubnets(clusterID, reservation.GetName(), remappings[clusterID]); err != nil {
+			errs = append(errs, fmt.Sprintf("cannot pin the networks for cluster %s: %v", clusterID, err))
+			continue
+		}
+		currentPinned.Insert(clusterID)
+	}
+
+	status.ReservedSubnets = currentReserved.List()
+	status.PinnedClusters = currentPinned.List()
+	status.Message = strings.Join(errs, "; ")
+	if err := r.Status().Update(ctx, reservation); err != nil {
+		klog.Errorf("Failed updating the status of IpamReservation %q: %v", reservation.GetName(), err)
+		return err
+	}
+
+	if len(errs) > 0 {
+		klog.Errorf("Failed enforcing IpamReservation %q: %s", reservation.GetName(), status.Message)
+		return fmt.Errorf("failed enforcing IpamReservation %q: %s", reservation.GetName(), status.Message)
+	}
+	klog.V(4).Infof("IpamReservation %q correctly enforced", reservation.GetName())
+	return nil
+}
+
+// SetupWithManager registers the IpamReservationReconciler to the manager.
+func (r *IpamReservationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1alpha1.IpamReservation{}).
+		Complete(r)
+}
@@ -0,0 +1,216 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipamreservation
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// fakeIPAM implements the reservation related methods of the Ipam interface, keeping track of
+// the reserved networks and of the pinned ones, along with their owners. The networks in unavailable cannot be reserved.
+type fakeIPAM struct {
+	liqonetIpam.Ipam
+	reserved    sets.String
+	pinned      map[string]netv1alpha1.PinnedSubnets
+	owners      map[string]string
+	unavailable sets.String
+}
+
+func (ipam *fakeIPAM) AcquireReservedSubnet(network string) error {
+	if ipam.reserved.Has(network) || ipam.unavailable.Has(network) {
+		return fmt.Errorf("network %s is not available", network)
+	}
+	ipam.reserved.Insert(network)
+	return nil
+}
+
+func (ipam *fakeIPAM) FreeReservedSubnet(network string) error {
+	ipam.reserved.Delete(network)
+	return nil
+}
+
+func (ipam *fakeIPAM) SetPinnedSubnets(clusterID, owner string, pinned netv1alpha1.PinnedSubnets) error {
+	if current, found := ipam.owners[clusterID]; found && current != owner {
+		return fmt.Errorf("networks of cluster %s are already pinned by %s", clusterID, current)
+	}
+	ipam.pinned[clusterID] = pinned
+	ipam.owners[clusterID] = owner
+	return nil
+}
+
+func (ipam *fakeIPAM) RemovePinnedSubnets(clusterID, owner string) error {
+	if ipam.owners[clusterID] != owner {
+		return nil
+	}
+	delete(ipam.pinned, clusterID)
+	delete(ipam.owners, clusterID)
+	return nil
+}
+
+var _ = Describe("IpamReservationReconciler", func() {
+	const name = "reservation"
+
+	var (
+		ctx         context.Context
+		cl          client.Client
+		ipam        *fakeIPAM
+		reconciler  *IpamReservationReconciler
+		reservation *netv1alpha1.IpamReservation
+		pinned      netv1alpha1.PinnedSubnets
+		reconcile   func() error
+		get         func() *netv1alpha1.IpamReservation
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(netv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		pinned = netv1alpha1.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"}
+		reservation = &netv1alpha1.IpamReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: netv1alpha1.IpamReservationSpec{
+				ReservedSubnets:   []string{"10.10.0.0/16", "192.168.10.0/24"},
+				ClusterRemappings: []netv1alpha1.ClusterRemapping{{ClusterID: "cluster-id", PinnedSubnets: pinned}},
+			},
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(reservation).Build()
+		ipam = &fakeIPAM{reserved: sets.NewString(), pinned: map[string]netv1alpha1.PinnedSubnets{},
+			owners: map[string]string{}, unavailable: sets.NewString()}
+		reconciler = &IpamReservationReconciler{Client: cl, IPManager: ipam}
+
+		reconcile = func() error {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+			return err
+		}
+		get = func() *netv1alpha1.IpamReservation {
+			var current netv1alpha1.IpamReservation
+			Expect(cl.Get(ctx, types.NamespacedName{Name: name}, &current)).To(Succeed())
+			return &current
+		}
+	})
+
+	Context("When a new IpamReservation is created", func() {
+		It("should reserve the networks, pin the remappings and update the status", func() {
+			Expect(reconcile()).To(Succeed())
+			Expect(ipam.reserved.List()).To(ConsistOf("10.10.0.0/16", "192.168.10.0/24"))
+			Expect(ipam.pinned).To(HaveKeyWithValue("cluster-id", pinned))
+
+			current := get()
+			Expect(current.GetFinalizers()).To(ContainElement(ipamReservationFinalizer))
+			Expect(current.Status.ReservedSubnets).To(ConsistOf("10.10.0.0/16", "192.168.10.0/24"))
+			Expect(current.Status.PinnedClusters).To(ConsistOf("cluster-id"))
+			Expect(current.Status.Message).To(BeEmpty())
+		})
+
+		It("should be idempotent", func() {
+			Expect(reconcile()).To(Succeed())
+			Expect(reconcile()).To(Succeed())
+			Expect(ipam.reserved.List()).To(ConsistOf("10.10.0.0/16", "192.168.10.0/24"))
+		})
+	})
+
+	Context("When a network cannot be reserved", func() {
+		It("should reserve the other ones and report the error in the status", func() {
+			ipam.unavailable.Insert("10.10.0.0/16")
+			Expect(reconcile()).ToNot(Succeed())
+			Expect(ipam.reserved.List()).To(ConsistOf("192.168.10.0/24"))
+
+			current := get()
+			Expect(current.Status.ReservedSubnets).To(ConsistOf("192.168.10.0/24"))
+			Expect(current.Status.Message).To(ContainSubstring("10.10.0.0/16"))
+
+			// The network is reserved as soon as it becomes available.
+			ipam.unavailable.Delete("10.10.0.0/16")
+			Expect(reconcile()).To(Succeed())
+			Expect(get().Status.ReservedSubnets).To(ConsistOf("10.10.0.0/16", "192.168.10.0/24"))
+			Expect(get().Status.Message).To(BeEmpty())
+		})
+	})
+
+	Context("When the networks of the cluster are already pinned by another IpamReservation", func() {
+		otherPinned := netv1alpha1.PinnedSubnets{PodCIDR: "10.200.0.0/16"}
+
+		BeforeEach(func() {
+			ipam.pinned["cluster-id"] = otherPinned
+			ipam.owners["cluster-id"] = "other"
+		})
+
+		It("should keep the other pin and report the error in the status", func() {
+			Expect(reconcile()).ToNot(Succeed())
+			Expect(ipam.pinned).To(HaveKeyWithValue("cluster-id", otherPinned))
+
+			current := get()
+			Expect(current.Status.PinnedClusters).To(BeEmpty())
+			Expect(current.Status.Message).To(ContainSubstring("other"))
+		})
+
+		It("should not remove the other pin when deleted", func() {
+			Expect(reconcile()).ToNot(Succeed())
+			Expect(cl.Delete(ctx, get())).To(Succeed())
+
+			Expect(reconcile()).To(Succeed())
+			Expect(ipam.pinned).To(HaveKeyWithValue("cluster-id", otherPinned))
+			Expect(ipam.owners).To(HaveKeyWithValue("cluster-id", "other"))
+		})
+	})
+
+	Context("When the IpamReservation is updated", func() {
+		It("should release the networks and the pins no longer declared", func() {
+			Expect(reconcile()).To(Succeed())
+
+			current := get()
+			current.Spec.ReservedSubnets = []string{"192.168.10.0/24", "10.20.0.0/16"}
+			current.Spec.ClusterRemappings = nil
+			Expect(cl.Update(ctx, current)).To(Succeed())
+
+			Expect(reconcile()).To(Succeed())
+			Expect(ipam.reserved.List()).To(ConsistOf("10.20.0.0/16", "192.168.10.0/24"))
+			Expect(ipam.pinned).To(BeEmpty())
+			Expect(get().Status.ReservedSubnets).To(ConsistOf("10.20.0.0/16", "192.168.10.0/24"))
+			Expect(get().Status.PinnedClusters).To(BeEmpty())
+		})
+	})
+
+	Context("When the IpamReservation is deleted", func() {
+		It("should release all the networks and the pins, and remove the finalizer", func() {
+			Expect(reconcile()).To(Succeed())
+			Expect(cl.Delete(ctx, get())).To(Succeed())
+
+			Expect(reconcile()).To(Succeed())
+			Expect(ipam.reserved).To(BeEmpty())
+			Expect(ipam.pinned).To(BeEmpty())
+
+			var current netv1alpha1.IpamReservation
+			err := cl.Get(ctx, types.NamespacedName{Name: name}, &current)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
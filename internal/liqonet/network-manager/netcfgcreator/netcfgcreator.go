@@ -47,22 +47,35 @@ type NetworkConfigCreator struct {
 	foreignClusters *syncset.SyncSet
 	secretWatcher   *SecretWatcher
 	serviceWatcher  *ServiceWatcher
+	replicaWatcher  *ReplicaWatcher
+	transitWatcher  *TransitWatcher
 
 	PodCIDR      string
 	ExternalCIDR string
+	// PodCIDRv6 and ExternalCIDRv6 are the IPv6 networks of the cluster, set only in case of dual-stack clusters.
+	PodCIDRv6      string
+	ExternalCIDRv6 string
+	// ActiveActiveGateway is true if the tunnels are sharded among the replicas of the gateway,
+	// hence each remote cluster is given the endpoint of the replica handling its tunnel.
+	ActiveActiveGateway bool
+	// TransitRouting is true if the networks of the peered clusters are advertised to the other ones,
+	// to let them communicate through the local cluster without a direct peering.
+	TransitRouting bool
 }
 
 // cluster-roles
 // +kubebuilder:rbac:groups=discovery.liqo.io,resources=foreignclusters,verbs=get;list;watch
 // +kubebuilder:rbac:groups=net.liqo.io,resources=networkconfigs,verbs=get;list;watch;create;update;patch;delete;deletecollection
+// +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints,verbs=get;list;watch
 // roles
 // +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=pods,verbs=get;list;watch
 
 // Reconcile reconciles the state of ForeignCluster resources to enforce the respective NetworkConfigs.
 func (ncc *NetworkConfigCreator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	// Wait, in case the configuration has not completed yet.
-	if !ncc.secretWatcher.WaitForConfigured(ctx) || !ncc.serviceWatcher.WaitForConfigured(ctx) {
+	if !ncc.secretWatcher.WaitForConfigured(ctx) || !ncc.waitForEndpointConfigured(ctx) {
 		return ctrl.Result{}, errors.New("context expired before initialization completed")
 	}
 
@@ -111,15 +124,32 @@ func (ncc *NetworkConfigCreator) SetupWithManager(mgr ctrl.Manager) error {
 	ncc.foreignClusters = syncset.New()
 	ncc.secretWatcher = NewSecretWatcher(enqueuefn)
 	ncc.serviceWatcher = NewServiceWatcher(enqueuefn)
+	ncc.replicaWatcher = NewReplicaWatcher(enqueuefn)
+	ncc.transitWatcher = NewTransitWatcher(enqueuefn)
 
 	localNetcfg, err := predicate.LabelSelectorPredicate(reflection.LocalResourcesLabelSelector())
 	utilruntime.Must(err)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	controller := ctrl.NewControllerManagedBy(mgr).
 		For(&discoveryv1alpha1.ForeignCluster{}).
 		Owns(&netv1alpha1.NetworkConfig{}, builder.WithPredicates(
 			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}), localNetcfg)).
 		Watches(&source.Kind{Type: &corev1.Secret{}}, ncc.secretWatcher.Handlers(), builder.WithPredicates(ncc.secretWatcher.Predicates())).
-		Watches(&source.Kind{Type: &corev1.Service{}}, ncc.serviceWatcher.Handlers(), builder.WithPredicates(ncc.serviceWatcher.Predicates())).
-		Complete(ncc)
+		Watches(&source.Kind{Type: &corev1.Service{}}, ncc.serviceWatcher.Handlers(), builder.WithPredicates(ncc.serviceWatcher.Predicates()))
+	if ncc.ActiveActiveGateway {
+		controller = controller.Watches(&source.Kind{Type: &corev1.Pod{}}, ncc.replicaWatcher.Handlers(),
+			builder.WithPredicates(ncc.replicaWatcher.Predicates()))
+	}
+	if ncc.TransitRouting {
+		controller = controller.Watches(&source.Kind{Type: &netv1alpha1.TunnelEndpoint{}}, ncc.transitWatcher.Handlers())
+	}
+	return controller.Complete(ncc)
+}
+
+// waitForEndpointConfigured waits until the endpoint of the local gateway is retrieved for the first time.
+func (ncc *NetworkConfigCreator) waitForEndpointConfigured(ctx context.Context) bool {
+	if ncc.ActiveActiveGateway {
+		return ncc.replicaWatcher.WaitForConfigured(ctx)
+	}
+	return ncc.serviceWatcher.WaitForConfigured(ctx)
 }
@@ -23,7 +23,6 @@ import (
 
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -31,6 +30,9 @@ import (
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/probe"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
@@ -131,7 +133,10 @@ func (ncc *NetworkConfigCreator) createNetworkConfig(ctx context.Context, fc *di
 			Namespace:    fc.Status.TenantNamespace.Local,
 		},
 	}
-	utilruntime.Must(ncc.populateNetworkConfig(&netcfg, fc))
+	if err := ncc.populateNetworkConfig(&netcfg, fc); err != nil {
+		klog.Errorf("An error occurred while creating NetworkConfig: %v", err)
+		return err
+	}
 
 	if err := ncc.Create(ctx, &netcfg); err != nil {
 		klog.Errorf("An error occurred while creating NetworkConfig: %v", err)
@@ -175,12 +180,26 @@ func (ncc *NetworkConfigCreator) populateNetworkConfig(netcfg *netv1alpha1.Netwo
 	netcfg.Labels[consts.ReplicationDestinationLabel] = clusterID
 
 	wgEndpointIP, wgEndpointPort := ncc.serviceWatcher.WiregardEndpoint()
+	_, ipsecEndpointPort := ncc.serviceWatcher.IPsecEndpoint()
+	if ncc.ActiveActiveGateway {
+		wgEndpointIP, wgEndpointPort, ipsecEndpointPort = ncc.replicaWatcher.Endpoints(clusterID)
+		if wgEndpointIP == "" {
+			return fmt.Errorf("no gateway replica available to handle the tunnel towards cluster %s", clusterID)
+		}
+	}
+	ipsecPublicKey := ncc.secretWatcher.IPsecPublicKey()
 
 	netcfg.Spec.ClusterID = clusterID
 	netcfg.Spec.PodCIDR = ncc.PodCIDR
 	netcfg.Spec.ExternalCIDR = ncc.ExternalCIDR
+	netcfg.Spec.PodCIDRv6 = ncc.PodCIDRv6
+	netcfg.Spec.ExternalCIDRv6 = ncc.ExternalCIDRv6
 	netcfg.Spec.EndpointIP = wgEndpointIP
-	netcfg.Spec.BackendType = wireguard.DriverName
+	netcfg.Spec.BackendType = backendType(fc)
+	netcfg.Spec.TransitNetworks = nil
+	if ncc.TransitRouting {
+		netcfg.Spec.TransitNetworks = ncc.transitWatcher.Networks(clusterID)
+	}
 
 	if netcfg.Spec.BackendConfig == nil {
 		netcfg.Spec.BackendConfig = map[string]string{}
@@ -188,9 +207,58 @@ func (ncc *NetworkConfigCreator) populateNetworkConfig(netcfg *netv1alpha1.Netwo
 	netcfg.Spec.BackendConfig[wireguard.PublicKey] = ncc.secretWatcher.WiregardPublicKey()
 	netcfg.Spec.BackendConfig[wireguard.ListeningPort] = wgEndpointPort
 
+	// The next Wireguard key is advertised during a key rotation, to let the remote cluster accept it in advance.
+	if nextPublicKey := ncc.secretWatcher.WiregardNextPublicKey(); nextPublicKey != "" {
+		netcfg.Spec.BackendConfig[wireguard.NextPublicKey] = nextPublicKey
+	} else {
+		delete(netcfg.Spec.BackendConfig, wireguard.NextPublicKey)
+	}
+
+	// The NAT traversal parameters are advertised only if the local gateway is located behind a NAT,
+	// to let the remote cluster keep the mappings alive and, possibly, relay the traffic.
+	natTraversal, relayAddress := ncc.serviceWatcher.NATTraversal()
+	if natTraversal != nattraversal.ModeNone && !ncc.ActiveActiveGateway {
+		netcfg.Spec.BackendConfig[wireguard.NATTraversal] = string(natTraversal)
+	} else {
+		delete(netcfg.Spec.BackendConfig, wireguard.NATTraversal)
+	}
+	if natTraversal == nattraversal.ModeRelay && !ncc.ActiveActiveGateway {
+		netcfg.Spec.BackendConfig[wireguard.RelayAddress] = relayAddress
+	} else {
+		delete(netcfg.Spec.BackendConfig, wireguard.RelayAddress)
+	}
+
+	// The local gateway always answers the probes, regardless of whether it is probing the remote ones.
+	netcfg.Spec.BackendConfig[probe.ResponderKey] = strconv.FormatBool(true)
+
+	// The IPsec parameters are advertised only if the local IPsec driver is available,
+	// to let the remote cluster establish the tunnel in case it is requested.
+	if ipsecPublicKey != "" && ipsecEndpointPort != "" {
+		netcfg.Spec.BackendConfig[ipsec.PublicKey] = ipsecPublicKey
+		netcfg.Spec.BackendConfig[ipsec.ListeningPort] = ipsecEndpointPort
+	} else {
+		delete(netcfg.Spec.BackendConfig, ipsec.PublicKey)
+		delete(netcfg.Spec.BackendConfig, ipsec.ListeningPort)
+	}
+	// The IPsec nonce is mixed into the keys of the security associations, hence the remote cluster installs them
+	// again every time it changes (e.g., because the local gateway restarted).
+	if ipsecNonce := ncc.secretWatcher.IPsecNonce(); ipsecNonce != "" && ipsecPublicKey != "" && ipsecEndpointPort != "" {
+		netcfg.Spec.BackendConfig[ipsec.Nonce] = ipsecNonce
+	} else {
+		delete(netcfg.Spec.BackendConfig, ipsec.Nonce)
+	}
+
 	return controllerutil.SetControllerReference(fc, netcfg, ncc.Scheme)
 }
 
+// backendType returns the tunnel backend requested for the given ForeignCluster, defaulting to wireguard.
+func backendType(fc *discoveryv1alpha1.ForeignCluster) string {
+	if backend, found := fc.GetAnnotations()[consts.TunnelBackendAnnotationKey]; found && backend != "" {
+		return backend
+	}
+	return wireguard.DriverName
+}
+
 // EnforceNetworkConfigAbsence ensures the absence of local NetworkConfigs associated with the given ForeignCluster.
 func (ncc *NetworkConfigCreator) EnforceNetworkConfigAbsence(ctx context.Context, fc *discoveryv1alpha1.ForeignCluster) error {
 	clusterID := fc.Spec.ClusterIdentity.ClusterID
@@ -20,15 +20,20 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/probe"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
@@ -42,11 +47,25 @@ var _ = Describe("Network config functions", func() {
 		ctx           context.Context
 		clientBuilder fake.ClientBuilder
 		fcw           *NetworkConfigCreator
+
+		ipsecPublicKey, ipsecPort string
+		ipsecNonce                string
+		wgNextPublicKey           string
+		natTraversal              nattraversal.Mode
+		relayAddress              string
+		replicaWatcher            *ReplicaWatcher
+		transitWatcher            *TransitWatcher
 	)
 
 	BeforeEach(func() {
 		ctx = context.Background()
 		clientBuilder = *fake.NewClientBuilder().WithScheme(scheme.Scheme)
+		ipsecPublicKey, ipsecPort = "", ""
+		ipsecNonce = ""
+		wgNextPublicKey = ""
+		natTraversal, relayAddress = nattraversal.ModeNone, ""
+		replicaWatcher = nil
+		transitWatcher = nil
 	})
 
 	JustBeforeEach(func() {
@@ -57,8 +76,15 @@ var _ = Describe("Network config functions", func() {
 			PodCIDR:      "192.168.0.0/24",
 			ExternalCIDR: "192.168.1.0/24",
 
-			secretWatcher:  &SecretWatcher{wiregardPublicKey: "public-key"},
-			serviceWatcher: &ServiceWatcher{endpointIP: "1.1.1.1", endpointPort: "9999"},
+			secretWatcher: &SecretWatcher{wiregardPublicKey: "public-key", wiregardNextPublicKey: wgNextPublicKey,
+				ipsecPublicKey: ipsecPublicKey, ipsecNonce: ipsecNonce},
+			serviceWatcher: &ServiceWatcher{endpointIP: "1.1.1.1", endpointPort: "9999", ipsecEndpointPort: ipsecPort,
+				natTraversal: natTraversal, relayAddress: relayAddress},
+			replicaWatcher: replicaWatcher,
+			transitWatcher: transitWatcher,
+
+			ActiveActiveGateway: replicaWatcher != nil,
+			TransitRouting:      transitWatcher != nil,
 		}
 	})
 
@@ -235,6 +261,7 @@ var _ = Describe("Network config functions", func() {
 				Expect(netcfg.Spec.BackendType).To(BeIdenticalTo(wireguard.DriverName))
 				Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.PublicKey, "public-key"))
 				Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.ListeningPort, "9999"))
+				Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(probe.ResponderKey, "true"))
 			}
 
 			When("the network config associated with the given foreign cluster does not exist", func() {
@@ -281,6 +308,122 @@ var _ = Describe("Network config functions", func() {
 					AssertNetworkConfigSpec(netcfg)
 				})
 			})
+
+			When("the local IPsec driver is not available", func() {
+				It("should not advertise the IPsec parameters", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.BackendConfig).ToNot(HaveKey(ipsec.PublicKey))
+					Expect(netcfg.Spec.BackendConfig).ToNot(HaveKey(ipsec.ListeningPort))
+					Expect(netcfg.Spec.BackendConfig).ToNot(HaveKey(ipsec.Nonce))
+				})
+				It("should not advertise the next wireguard key", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.BackendConfig).ToNot(HaveKey(wireguard.NextPublicKey))
+				})
+			})
+
+			When("the gateway is in active/active mode", func() {
+				BeforeEach(func() { replicaWatcher = NewReplicaWatcher(func(rli workqueue.RateLimitingInterface) {}) })
+
+				When("no gateway replica is available", func() {
+					It("should fail with an error", func() { Expect(err).To(HaveOccurred()) })
+				})
+
+				When("a gateway replica is available", func() {
+					BeforeEach(func() {
+						replicaWatcher.handle(&corev1.Pod{
+							ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: namespace},
+							Status:     corev1.PodStatus{PodIP: "2.2.2.2"},
+						}, true, nil)
+					})
+
+					It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+					It("the network config should advertise the endpoint of the replica", func() {
+						netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(netcfg.Spec.EndpointIP).To(BeIdenticalTo("2.2.2.2"))
+						Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.ListeningPort, "5871"))
+					})
+				})
+			})
+
+			When("transit routing is enabled", func() {
+				BeforeEach(func() {
+					transitWatcher = NewTransitWatcher(func(rli workqueue.RateLimitingInterface) {})
+					transitWatcher.handle(&netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+						ClusterID: "remote", RemotePodCIDR: "10.0.0.0/16", RemoteNATPodCIDR: "10.200.0.0/16",
+						RemoteExternalCIDR: "10.1.0.0/16", RemoteNATExternalCIDR: consts.DefaultCIDRValue,
+					}}, []netv1alpha1.TransitNetwork{{
+						ClusterID: "remote", PodCIDR: "10.200.0.0/16", ExternalCIDR: "10.1.0.0/16", Path: []string{"remote"},
+					}, {
+						ClusterID: clusterID, PodCIDR: "10.2.0.0/16", ExternalCIDR: "10.3.0.0/16", Path: []string{"remote", clusterID},
+					}}, nil)
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should advertise the networks reachable through the local cluster", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.TransitNetworks).To(ConsistOf(netv1alpha1.TransitNetwork{
+						ClusterID: "remote", PodCIDR: "10.200.0.0/16", ExternalCIDR: "10.1.0.0/16", Path: []string{"remote"},
+					}))
+				})
+			})
+
+			When("the gateway is behind a NAT", func() {
+				BeforeEach(func() { natTraversal = nattraversal.ModeDirect })
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should advertise the NAT traversal mode", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.NATTraversal, "direct"))
+					Expect(netcfg.Spec.BackendConfig).ToNot(HaveKey(wireguard.RelayAddress))
+				})
+
+				When("the traffic is relayed", func() {
+					BeforeEach(func() { natTraversal, relayAddress = nattraversal.ModeRelay, "relay.example.com:3478" })
+
+					It("the network config should advertise the relay address", func() {
+						netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.NATTraversal, "relay"))
+						Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.RelayAddress, "relay.example.com:3478"))
+					})
+				})
+			})
+
+			When("a wireguard key rotation is in progress", func() {
+				BeforeEach(func() { wgNextPublicKey = "next-public-key" })
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should advertise both the current and the next wireguard keys", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.PublicKey, "public-key"))
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.NextPublicKey, "next-public-key"))
+				})
+			})
+
+			When("the ipsec backend is requested and the local IPsec driver is available", func() {
+				BeforeEach(func() {
+					ipsecPublicKey, ipsecPort, ipsecNonce = "ipsec-public-key", "4500", "ipsec-nonce"
+					fc.SetAnnotations(map[string]string{consts.TunnelBackendAnnotationKey: ipsec.DriverName})
+				})
+
+				It("should succeed", func() { Expect(err).ToNot(HaveOccurred()) })
+				It("the network config should select the ipsec backend and advertise its parameters", func() {
+					netcfg, err := GetLocalNetworkConfig(ctx, fcw.Client, clusterID, namespace)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netcfg.Spec.BackendType).To(BeIdenticalTo(ipsec.DriverName))
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(ipsec.PublicKey, "ipsec-public-key"))
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(ipsec.ListeningPort, "4500"))
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(ipsec.Nonce, "ipsec-nonce"))
+					Expect(netcfg.Spec.BackendConfig).To(HaveKeyWithValue(wireguard.PublicKey, "public-key"))
+				})
+			})
 		})
 
 		Describe("The EnforceNetworkConfigAbsence function", func() {
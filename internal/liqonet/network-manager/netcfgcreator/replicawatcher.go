@@ -0,0 +1,167 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/liqotech/liqo/pkg/liqonet/sharding"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
+)
+
+// These labels are the ones set during the deployment of liqo using the helm chart.
+// Any change to those labels on the helm chart has also to be reflected here.
+var (
+	gatewayComponentLabelKey   = "app.kubernetes.io/component"
+	gatewayComponentLabelValue = "networking"
+	gatewayNameLabelKey        = "app.kubernetes.io/name"
+	gatewayNameLabelValue      = "gateway"
+)
+
+// ReplicaWatcher reconciles the gateway Pod objects to retrieve the replicas eligible to handle the tunnels,
+// when the gateway is configured in active/active mode.
+type ReplicaWatcher struct {
+	sync.RWMutex
+	replicas map[string]corev1.Pod
+
+	configured bool
+	wait       chan struct{}
+
+	enqueuefn func(workqueue.RateLimitingInterface)
+}
+
+// NewReplicaWatcher returns a new initialized ReplicaWatcher instance.
+func NewReplicaWatcher(enqueuefn func(workqueue.RateLimitingInterface)) *ReplicaWatcher {
+	return &ReplicaWatcher{
+		replicas:   map[string]corev1.Pod{},
+		configured: false,
+		wait:       make(chan struct{}),
+
+		enqueuefn: enqueuefn,
+	}
+}
+
+// Endpoints returns the Wireguard and IPsec endpoints of the gateway replica handling the tunnel towards
+// the given cluster. The replicas run in host network, hence they are reached at the address of the
+// respective nodes, on the ports their drivers are listening on. The IP is empty if no replica is available.
+func (rw *ReplicaWatcher) Endpoints(clusterID string) (ip, wireguardPort, ipsecPort string) {
+	rw.RLock()
+	defer rw.RUnlock()
+
+	replicas := make([]corev1.Pod, 0, len(rw.replicas))
+	for name := range rw.replicas {
+		replicas = append(replicas, rw.replicas[name])
+	}
+	owner := sharding.Owner(clusterID, replicas)
+	if owner == nil {
+		return "", "", ""
+	}
+	return owner.Status.PodIP, strconv.Itoa(wireguard.DefaultPort), strconv.Itoa(ipsec.DefaultPort)
+}
+
+// WaitForConfigured waits until an eligible replica is retrieved for the first time.
+func (rw *ReplicaWatcher) WaitForConfigured(ctx context.Context) bool {
+	rw.RLock()
+
+	if !rw.configured {
+		rw.RUnlock()
+		klog.Info("Waiting for the configuration of the replica watcher")
+
+		select {
+		case <-rw.wait:
+			klog.Info("Replica watcher correctly configured")
+			return true
+		case <-ctx.Done():
+			klog.Warning("Context expired before configuring the replica watcher")
+			return false
+		}
+	}
+
+	rw.RUnlock()
+	return true
+}
+
+// Handlers returns the set of handlers used for the Watch configuration.
+func (rw *ReplicaWatcher) Handlers() handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(ce event.CreateEvent, rli workqueue.RateLimitingInterface) {
+			pod := ce.Object.(*corev1.Pod)
+			rw.handle(pod, sharding.IsEligible(pod), rli)
+		},
+		UpdateFunc: func(ue event.UpdateEvent, rli workqueue.RateLimitingInterface) {
+			pod := ue.ObjectNew.(*corev1.Pod)
+			rw.handle(pod, sharding.IsEligible(pod), rli)
+		},
+		DeleteFunc: func(de event.DeleteEvent, rli workqueue.RateLimitingInterface) {
+			pod := de.Object.(*corev1.Pod)
+			rw.handle(pod, false, rli)
+		},
+	}
+}
+
+// Predicates returns the set of predicates used for the Watch configuration.
+func (rw *ReplicaWatcher) Predicates() predicate.Predicate {
+	replicasPredicate, err := predicate.LabelSelectorPredicate(metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			gatewayComponentLabelKey: gatewayComponentLabelValue,
+			gatewayNameLabelKey:      gatewayNameLabelValue,
+		},
+	})
+	utilruntime.Must(err)
+
+	return replicasPredicate
+}
+
+// handle processes the events concerning a gateway replica, given whether it is eligible to handle tunnels.
+func (rw *ReplicaWatcher) handle(pod *corev1.Pod, eligible bool, rli workqueue.RateLimitingInterface) {
+	klog.V(4).Infof("Handling gateway replica %q", klog.KObj(pod))
+
+	rw.Lock()
+	defer rw.Unlock()
+
+	current, found := rw.replicas[pod.GetName()]
+	// The set of eligible replicas did not change, nothing to do
+	if eligible == found && (!found || current.Status.PodIP == pod.Status.PodIP) {
+		return
+	}
+
+	if eligible {
+		klog.Infof("Gateway replica %q at address %s is eligible to handle the tunnels", klog.KObj(pod), pod.Status.PodIP)
+		rw.replicas[pod.GetName()] = *pod.DeepCopy()
+	} else {
+		klog.Infof("Gateway replica %q is no longer eligible to handle the tunnels", klog.KObj(pod))
+		delete(rw.replicas, pod.GetName())
+	}
+
+	if !rw.configured && len(rw.replicas) > 0 {
+		close(rw.wait)
+		rw.configured = true
+	}
+
+	// Enqueue all foreign clusters for update (which in turn update the respective network configs)
+	rw.enqueuefn(rli)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Replica Watcher functions", func() {
+	var (
+		handled int
+
+		rw  *ReplicaWatcher
+		pod corev1.Pod
+	)
+
+	BeforeEach(func() {
+		handled = 0
+		rw = NewReplicaWatcher(func(rli workqueue.RateLimitingInterface) { handled++ })
+		pod = corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+			Status:     corev1.PodStatus{PodIP: "1.1.1.1"},
+		}
+	})
+
+	Describe("The handle function", func() {
+		When("an eligible replica is added", func() {
+			BeforeEach(func() { rw.handle(&pod, true, nil) })
+
+			It("should retrieve the correct endpoints", func() {
+				ip, wgPort, ipsecPort := rw.Endpoints("cluster-id")
+				Expect(ip).To(BeIdenticalTo("1.1.1.1"))
+				Expect(wgPort).To(BeIdenticalTo("5871"))
+				Expect(ipsecPort).To(BeIdenticalTo("4500"))
+			})
+			It("should execute the handle function", func() { Expect(handled).To(Equal(1)) })
+			It("should be initialized", func() { Expect(rw.configured).To(BeTrue()) })
+
+			It("should not execute the handle function if nothing changes", func() {
+				rw.handle(&pod, true, nil)
+				Expect(handled).To(Equal(1))
+			})
+
+			When("the replica is no longer eligible", func() {
+				BeforeEach(func() { rw.handle(&pod, false, nil) })
+
+				It("should not return any endpoint", func() {
+					ip, _, _ := rw.Endpoints("cluster-id")
+					Expect(ip).To(BeEmpty())
+				})
+				It("should execute the handle function", func() { Expect(handled).To(Equal(2)) })
+			})
+
+			When("the address of the replica changes", func() {
+				BeforeEach(func() {
+					pod.Status.PodIP = "2.2.2.2"
+					rw.handle(&pod, true, nil)
+				})
+
+				It("should retrieve the new endpoint", func() {
+					ip, _, _ := rw.Endpoints("cluster-id")
+					Expect(ip).To(BeIdenticalTo("2.2.2.2"))
+				})
+				It("should execute the handle function", func() { Expect(handled).To(Equal(2)) })
+			})
+		})
+
+		When("a replica which is not eligible is added", func() {
+			BeforeEach(func() { rw.handle(&pod, false, nil) })
+
+			It("should not execute the handle function", func() { Expect(handled).To(BeZero()) })
+			It("should not be initialized", func() { Expect(rw.configured).To(BeFalse()) })
+		})
+	})
+
+	Describe("The WaitForConfigured function", func() {
+		It("should return false if the context expires before an eligible replica is found", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			Expect(rw.WaitForConfigured(ctx)).To(BeFalse())
+		})
+
+		It("should return true once an eligible replica is found", func() {
+			rw.handle(&pod, true, nil)
+			Expect(rw.WaitForConfigured(context.Background())).To(BeTrue())
+		})
+	})
+})
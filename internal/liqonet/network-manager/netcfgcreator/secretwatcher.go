@@ -28,13 +28,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
-// SecretWatcher reconciles Secret objects to retrieve the Wireguard public key.
+// SecretWatcher reconciles Secret objects to retrieve the Wireguard and IPsec public keys.
 type SecretWatcher struct {
 	sync.RWMutex
-	wiregardPublicKey string
+	wiregardPublicKey     string
+	wiregardNextPublicKey string
+	ipsecPublicKey        string
+	ipsecNonce            string
 
 	configured bool
 	wait       chan struct{}
@@ -60,6 +64,31 @@ func (sw *SecretWatcher) WiregardPublicKey() string {
 	return sw.wiregardPublicKey
 }
 
+// WiregardNextPublicKey returns the Wireguard public key which is going to replace the current one,
+// or an empty string if no key rotation is in progress.
+func (sw *SecretWatcher) WiregardNextPublicKey() string {
+	sw.RLock()
+	defer sw.RUnlock()
+
+	return sw.wiregardNextPublicKey
+}
+
+// IPsecPublicKey returns the retrieved IPsec public key, or an empty string if the IPsec driver is not available.
+func (sw *SecretWatcher) IPsecPublicKey() string {
+	sw.RLock()
+	defer sw.RUnlock()
+
+	return sw.ipsecPublicKey
+}
+
+// IPsecNonce returns the retrieved IPsec nonce, or an empty string if not yet generated by the IPsec driver.
+func (sw *SecretWatcher) IPsecNonce() string {
+	sw.RLock()
+	defer sw.RUnlock()
+
+	return sw.ipsecNonce
+}
+
 // WaitForConfigured waits until a valid key is retrieved for the first time.
 func (sw *SecretWatcher) WaitForConfigured(ctx context.Context) bool {
 	sw.RLock()
@@ -102,7 +131,7 @@ func (sw *SecretWatcher) Predicates() predicate.Predicate {
 		MatchExpressions: []metav1.LabelSelectorRequirement{{
 			Key:      wireguard.KeysLabel,
 			Operator: metav1.LabelSelectorOpIn,
-			Values:   []string{wireguard.DriverName},
+			Values:   []string{wireguard.DriverName, ipsec.DriverName},
 		}},
 	})
 	utilruntime.Must(err)
@@ -117,6 +146,11 @@ func (sw *SecretWatcher) handle(secret *corev1.Secret, rli workqueue.RateLimitin
 	sw.Lock()
 	defer sw.Unlock()
 
+	if secret.GetLabels()[ipsec.KeysLabel] == ipsec.DriverName {
+		sw.handleIPsec(secret, rli)
+		return
+	}
+
 	// Extract the public key from the secret
 	pubKeyByte, found := secret.Data[wireguard.PublicKey]
 	if !found {
@@ -129,14 +163,26 @@ func (sw *SecretWatcher) handle(secret *corev1.Secret, rli workqueue.RateLimitin
 		return
 	}
 
-	// The key did not change, nothing to do
-	if pubKey.String() == sw.wiregardPublicKey {
+	// Extract the next public key from the secret, which is present only during a key rotation
+	var nextPubKey string
+	if nextPubKeyByte, found := secret.Data[wireguard.NextPublicKey]; found {
+		key, err := wgtypes.ParseKey(string(nextPubKeyByte))
+		if err != nil {
+			klog.Errorf("Secret %q: invalid next public key: %v", klog.KObj(secret), err)
+			return
+		}
+		nextPubKey = key.String()
+	}
+
+	// The keys did not change, nothing to do
+	if pubKey.String() == sw.wiregardPublicKey && nextPubKey == sw.wiregardNextPublicKey {
 		return
 	}
 
-	// Configure the new key, and set as configured if not yet done
+	// Configure the new keys, and set as configured if not yet done
 	klog.Infof("Wiregard public key correctly retrieved")
 	sw.wiregardPublicKey = pubKey.String()
+	sw.wiregardNextPublicKey = nextPubKey
 	if !sw.configured {
 		close(sw.wait)
 		sw.configured = true
@@ -145,3 +191,34 @@ func (sw *SecretWatcher) handle(secret *corev1.Secret, rli workqueue.RateLimitin
 	// Enqueue all foreign clusters for update (which in turn update the respective network configs)
 	sw.enqueuefn(rli)
 }
+
+// handleIPsec processes creation and update events of the Secret object containing the IPsec keys.
+// The IPsec key is optional, hence it does not contribute to set the watcher as configured.
+func (sw *SecretWatcher) handleIPsec(secret *corev1.Secret, rli workqueue.RateLimitingInterface) {
+	// Extract the public key from the secret
+	pubKeyByte, found := secret.Data[ipsec.PublicKey]
+	if !found {
+		klog.Errorf("No data with key %s found in secret %q", ipsec.PublicKey, klog.KObj(secret))
+		return
+	}
+	pubKey, err := ipsec.ParseKey(string(pubKeyByte))
+	if err != nil {
+		klog.Errorf("Secret %q: invalid public key: %v", klog.KObj(secret), err)
+		return
+	}
+
+	// The nonce is generated once the gateway starts handling the tunnels, and regenerated periodically
+	nonce := string(secret.Data[ipsec.Nonce])
+
+	// The key and the nonce did not change, nothing to do
+	if pubKey.String() == sw.ipsecPublicKey && nonce == sw.ipsecNonce {
+		return
+	}
+
+	klog.Infof("IPsec public key and nonce correctly retrieved")
+	sw.ipsecPublicKey = pubKey.String()
+	sw.ipsecNonce = nonce
+
+	// Enqueue all foreign clusters for update (which in turn update the respective network configs)
+	sw.enqueuefn(rli)
+}
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
 
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
@@ -69,6 +70,32 @@ var _ = Describe("Secret Watcher functions", func() {
 			})
 		})
 
+		When("given a valid secret with the next key", func() {
+			const nextKey = "bmV4dC1wdWJsaWMta2V5LW9mLWNvcnJlY3QtbGVuZ3Q="
+
+			BeforeEach(func() {
+				secret.Data = map[string][]byte{wireguard.PublicKey: []byte(key), wireguard.NextPublicKey: []byte(nextKey)}
+			})
+
+			When("the current key is unchanged", func() {
+				BeforeEach(func() {
+					sw.wiregardPublicKey = key
+					sw.configured = true
+				})
+
+				It("should retrieve the correct public key", func() { Expect(sw.WiregardPublicKey()).To(BeIdenticalTo(key)) })
+				It("should retrieve the correct next public key", func() { Expect(sw.WiregardNextPublicKey()).To(BeIdenticalTo(nextKey)) })
+				It("should execute the handle function", func() { Expect(handled).To(BeClosed()) })
+			})
+
+			When("the next key is invalid", func() {
+				BeforeEach(func() { secret.Data[wireguard.NextPublicKey] = []byte("invalid") })
+
+				It("should leave the public key unmodified", func() { Expect(sw.WiregardPublicKey()).To(BeIdenticalTo("")) })
+				It("should not execute the handle function", func() { Expect(handled).ToNot(BeClosed()) })
+			})
+		})
+
 		When("given an invalid secret", func() {
 			BeforeEach(func() {
 				secret.Data = map[string][]byte{"incorrect-key": []byte(key)}
@@ -78,6 +105,19 @@ var _ = Describe("Secret Watcher functions", func() {
 			It("should not execute the handle function", func() { Expect(handled).ToNot(BeClosed()) })
 			It("should not be initialized", func() { Expect(sw.configured).To(BeFalse()) })
 		})
+
+		When("given a valid ipsec secret", func() {
+			BeforeEach(func() {
+				secret.Labels = map[string]string{ipsec.KeysLabel: ipsec.DriverName}
+				secret.Data = map[string][]byte{ipsec.PublicKey: []byte(key), ipsec.Nonce: []byte("nonce")}
+			})
+
+			It("should retrieve the correct public key", func() { Expect(sw.IPsecPublicKey()).To(BeIdenticalTo(key)) })
+			It("should retrieve the correct nonce", func() { Expect(sw.IPsecNonce()).To(BeIdenticalTo("nonce")) })
+			It("should leave the wireguard public key unmodified", func() { Expect(sw.WiregardPublicKey()).To(BeIdenticalTo("")) })
+			It("should execute the handle function", func() { Expect(handled).To(BeClosed()) })
+			It("should not be initialized", func() { Expect(sw.configured).To(BeFalse()) })
+		})
 	})
 
 	Describe("The WaitForConfigured function", func() {
@@ -16,6 +16,7 @@ package netcfgcreator
 
 import (
 	"context"
+	"net"
 	"strconv"
 	"sync"
 
@@ -28,6 +29,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
@@ -37,13 +40,19 @@ var (
 	serviceLabelKey      = "net.liqo.io/gateway"
 	serviceLabelValue    = "true"
 	serviceAnnotationKey = "net.liqo.io/gatewayNodeIP"
+	// These annotations are set by the gateway when the NAT traversal is enabled.
+	publicEndpointAnnotationKey = "net.liqo.io/gatewayPublicEndpoint"
+	relayAnnotationKey          = "net.liqo.io/gatewayRelay"
 )
 
-// ServiceWatcher reconciles Service objects to retrieve the Wireguard endpoint.
+// ServiceWatcher reconciles Service objects to retrieve the Wireguard and IPsec endpoints.
 type ServiceWatcher struct {
 	sync.RWMutex
-	endpointIP   string
-	endpointPort string
+	endpointIP        string
+	endpointPort      string
+	ipsecEndpointPort string
+	natTraversal      nattraversal.Mode
+	relayAddress      string
 
 	configured bool
 	wait       chan struct{}
@@ -69,6 +78,24 @@ func (sw *ServiceWatcher) WiregardEndpoint() (ip, port string) {
 	return sw.endpointIP, sw.endpointPort
 }
 
+// IPsecEndpoint returns the retrieved IPsec endpoint information (IP/port).
+// The port is empty in case it is not exposed by the service.
+func (sw *ServiceWatcher) IPsecEndpoint() (ip, port string) {
+	sw.RLock()
+	defer sw.RUnlock()
+
+	return sw.endpointIP, sw.ipsecEndpointPort
+}
+
+// NATTraversal returns the way the Wireguard endpoint traverses the NAT the gateway is located behind,
+// together with the address of the relay, if the traffic is relayed.
+func (sw *ServiceWatcher) NATTraversal() (mode nattraversal.Mode, relayAddress string) {
+	sw.RLock()
+	defer sw.RUnlock()
+
+	return sw.natTraversal, sw.relayAddress
+}
+
 // WaitForConfigured waits until a valid key is retrieved for the first time.
 func (sw *ServiceWatcher) WaitForConfigured(ctx context.Context) bool {
 	sw.RLock()
@@ -140,15 +167,37 @@ func (sw *ServiceWatcher) handle(service *corev1.Service, rli workqueue.RateLimi
 		return
 	}
 
+	// The IPsec port is optional, and it is advertised only if exposed by the service
+	ipsecPort := sw.retrieveIPsecPort(service)
+
+	// The public mapping discovered by the gateway behind a NAT takes precedence over the service
+	natTraversal, relayAddress := nattraversal.ModeNone, ""
+	if publicIP, publicPort, found := sw.retrievePublicEndpoint(service); found {
+		ip, port, retrieved, natTraversal = publicIP, publicPort, true, nattraversal.ModeDirect
+	}
+	if relay, found := service.GetAnnotations()[relayAnnotationKey]; found && relay != "" && natTraversal != nattraversal.ModeNone {
+		natTraversal, relayAddress = nattraversal.ModeRelay, relay
+	}
+
 	// The endpoint did not change, nothing to do
-	if !retrieved || (ip == sw.endpointIP && port == sw.endpointPort) {
+	if !retrieved || (ip == sw.endpointIP && port == sw.endpointPort && ipsecPort == sw.ipsecEndpointPort &&
+		natTraversal == sw.natTraversal && relayAddress == sw.relayAddress) {
 		return
 	}
 
 	// Configure the new key, and set as configured if not yet done
 	klog.Infof("Wiregard endpoint correctly retrieved: %s:%s", ip, port)
+	if ipsecPort != "" {
+		klog.Infof("IPsec endpoint correctly retrieved: %s:%s", ip, ipsecPort)
+	}
+	if natTraversal != nattraversal.ModeNone {
+		klog.Infof("NAT traversal enabled for the Wiregard endpoint (mode: %s)", natTraversal)
+	}
 	sw.endpointIP = ip
 	sw.endpointPort = port
+	sw.ipsecEndpointPort = ipsecPort
+	sw.natTraversal = natTraversal
+	sw.relayAddress = relayAddress
 	if !sw.configured {
 		close(sw.wait)
 		sw.configured = true
@@ -185,10 +234,12 @@ func (sw *ServiceWatcher) retrieveFromNodePort(service *corev1.Service) (endpoin
 
 // retrieveFromLoadBalancer retrieves the Wireguard endpoint from a LoadBalancer service.
 func (sw *ServiceWatcher) retrieveFromLoadBalancer(service *corev1.Service) (endpointIP, endpointPort string, retrieved bool) {
-	// Check if the ingress IP has been set
+	// Check if the ingress IP has been set, otherwise fall back to the node port, which is allocated
+	// also for the services of type LoadBalancer (e.g., if no load balancer implementation is available).
 	if len(service.Status.LoadBalancer.Ingress) == 0 {
-		klog.Warningf("The ingress IP has not been set for service %q of type %s", klog.KObj(service), service.Spec.Type)
-		return endpointIP, endpointPort, false
+		klog.Warningf("The ingress IP has not been set for service %q of type %s, falling back to the node port",
+			klog.KObj(service), service.Spec.Type)
+		return sw.retrieveFromNodePort(service)
 	}
 
 	// Retrieve the endpoint address
@@ -209,3 +260,34 @@ func (sw *ServiceWatcher) retrieveFromLoadBalancer(service *corev1.Service) (end
 	klog.Warningf("Port %s not found in service %q", wireguard.DriverName, klog.KObj(service))
 	return endpointIP, endpointPort, false
 }
+
+// retrievePublicEndpoint retrieves the public mapping of the Wireguard endpoint, as discovered by the gateway.
+func (sw *ServiceWatcher) retrievePublicEndpoint(service *corev1.Service) (endpointIP, endpointPort string, retrieved bool) {
+	endpoint, found := service.GetAnnotations()[publicEndpointAnnotationKey]
+	if !found || endpoint == "" {
+		return "", "", false
+	}
+	endpointIP, endpointPort, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		klog.Warningf("Invalid public endpoint %q for service %q: %v", endpoint, klog.KObj(service), err)
+		return "", "", false
+	}
+	return endpointIP, endpointPort, true
+}
+
+// retrieveIPsecPort retrieves the IPsec endpoint port, returning an empty string if it is not available.
+func (sw *ServiceWatcher) retrieveIPsecPort(service *corev1.Service) string {
+	for _, port := range service.Spec.Ports {
+		if port.Name != ipsec.DriverName {
+			continue
+		}
+		if service.Spec.Type == corev1.ServiceTypeNodePort {
+			if port.NodePort == 0 {
+				return ""
+			}
+			return strconv.FormatInt(int64(port.NodePort), 10)
+		}
+		return strconv.FormatInt(int64(port.Port), 10)
+	}
+	return ""
+}
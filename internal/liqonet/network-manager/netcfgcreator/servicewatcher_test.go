@@ -23,6 +23,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
+
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
 )
 
 var _ = Describe("Service Watcher functions", func() {
@@ -57,6 +59,26 @@ var _ = Describe("Service Watcher functions", func() {
 				})
 				It("should execute the handle function", func() { Expect(handled).To(BeClosed()) })
 				It("should be initialized", func() { Expect(sw.configured).To(BeTrue()) })
+				It("should not retrieve the ipsec port", func() {
+					_, port := sw.IPsecEndpoint()
+					Expect(port).To(BeEmpty())
+				})
+			})
+
+			When("given a valid service exposing also the ipsec port", func() {
+				BeforeEach(func() {
+					service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{Name: "ipsec", NodePort: 9998})
+				})
+
+				It("should retrieve the correct endpoints", func() {
+					ip, port := sw.WiregardEndpoint()
+					Expect(ip).To(BeIdenticalTo("1.1.1.1"))
+					Expect(port).To(BeIdenticalTo("9999"))
+					ip, port = sw.IPsecEndpoint()
+					Expect(ip).To(BeIdenticalTo("1.1.1.1"))
+					Expect(port).To(BeIdenticalTo("9998"))
+				})
+				It("should execute the handle function", func() { Expect(handled).To(BeClosed()) })
 			})
 
 			When("given an invalid service (missing the annotation)", func() {
@@ -114,6 +136,20 @@ var _ = Describe("Service Watcher functions", func() {
 				It("should not be initialized", func() { Expect(sw.configured).To(BeFalse()) })
 			})
 
+			When("given a service missing the load balancer ingress, but exposing the node port", func() {
+				BeforeEach(func() {
+					service.Annotations = map[string]string{"net.liqo.io/gatewayNodeIP": "2.2.2.2"}
+					service.Spec.Ports[0].NodePort = 30000
+					service.Status.LoadBalancer.Ingress = nil
+				})
+				It("should fall back to the node port endpoint", func() {
+					ip, port := sw.WiregardEndpoint()
+					Expect(ip).To(BeIdenticalTo("2.2.2.2"))
+					Expect(port).To(BeIdenticalTo("30000"))
+				})
+				It("should execute the handle function", func() { Expect(handled).To(BeClosed()) })
+			})
+
 			When("given an invalid service (missing the port)", func() {
 				BeforeEach(func() { service.Spec.Ports = nil })
 				It("should not execute the handle function", func() { Expect(handled).ToNot(BeClosed()) })
@@ -121,6 +157,52 @@ var _ = Describe("Service Watcher functions", func() {
 			})
 		})
 
+		Context("service of a gateway behind a NAT", func() {
+			BeforeEach(func() {
+				service.Annotations = map[string]string{
+					"net.liqo.io/gatewayNodeIP":         "1.1.1.1",
+					"net.liqo.io/gatewayPublicEndpoint": "3.3.3.3:40000",
+				}
+				service.Spec.Type = corev1.ServiceTypeNodePort
+				service.Spec.Ports = []corev1.ServicePort{{Name: "wireguard", NodePort: 9999}}
+			})
+
+			When("the traffic is not relayed", func() {
+				It("should retrieve the public endpoint", func() {
+					ip, port := sw.WiregardEndpoint()
+					Expect(ip).To(BeIdenticalTo("3.3.3.3"))
+					Expect(port).To(BeIdenticalTo("40000"))
+				})
+				It("should enable the direct NAT traversal", func() {
+					mode, relay := sw.NATTraversal()
+					Expect(mode).To(Equal(nattraversal.ModeDirect))
+					Expect(relay).To(BeEmpty())
+				})
+			})
+
+			When("the traffic is relayed", func() {
+				BeforeEach(func() { service.Annotations["net.liqo.io/gatewayRelay"] = "relay.example.com:3478" })
+				It("should enable the relayed NAT traversal", func() {
+					mode, relay := sw.NATTraversal()
+					Expect(mode).To(Equal(nattraversal.ModeRelay))
+					Expect(relay).To(Equal("relay.example.com:3478"))
+				})
+			})
+
+			When("the public endpoint is malformed", func() {
+				BeforeEach(func() { service.Annotations["net.liqo.io/gatewayPublicEndpoint"] = "3.3.3.3" })
+				It("should retrieve the node port endpoint", func() {
+					ip, port := sw.WiregardEndpoint()
+					Expect(ip).To(BeIdenticalTo("1.1.1.1"))
+					Expect(port).To(BeIdenticalTo("9999"))
+				})
+				It("should not enable the NAT traversal", func() {
+					mode, _ := sw.NATTraversal()
+					Expect(mode).To(Equal(nattraversal.ModeNone))
+				})
+			})
+		})
+
 		Context("cluster IP service", func() {
 			BeforeEach(func() { service.Spec.Type = corev1.ServiceTypeClusterIP })
 			It("should not execute the handle function", func() { Expect(handled).ToNot(BeClosed()) })
@@ -0,0 +1,156 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetutils "github.com/liqotech/liqo/pkg/liqonet/utils"
+	"github.com/liqotech/liqo/pkg/utils/slice"
+)
+
+// TransitWatcher reconciles the TunnelEndpoint objects to retrieve the networks reachable through each
+// peered cluster, which are advertised to the other ones when transit routing is enabled.
+type TransitWatcher struct {
+	sync.RWMutex
+	// networks is keyed by the ID of the peered cluster, and contains the networks reachable through it,
+	// as seen by the local cluster (i.e. the ones of the peered cluster itself and the ones it advertised).
+	networks map[string][]netv1alpha1.TransitNetwork
+
+	enqueuefn func(workqueue.RateLimitingInterface)
+}
+
+// NewTransitWatcher returns a new initialized TransitWatcher instance.
+func NewTransitWatcher(enqueuefn func(workqueue.RateLimitingInterface)) *TransitWatcher {
+	return &TransitWatcher{
+		networks:  map[string][]netv1alpha1.TransitNetwork{},
+		enqueuefn: enqueuefn,
+	}
+}
+
+// Networks returns the networks to be advertised to the given cluster, i.e. the ones reachable through the other
+// peered clusters. The networks whose path traverses the given cluster, or exceeds the maximum number of hops,
+// are excluded to prevent routing loops.
+func (tw *TransitWatcher) Networks(clusterID string) []netv1alpha1.TransitNetwork {
+	tw.RLock()
+	defer tw.RUnlock()
+
+	var networks []netv1alpha1.TransitNetwork
+	for peer := range tw.networks {
+		if peer == clusterID {
+			continue
+		}
+		for i := range tw.networks[peer] {
+			network := &tw.networks[peer][i]
+			if len(network.Path) > liqoconst.TransitMaxHops || slice.ContainsString(network.Path, clusterID) {
+				continue
+			}
+			networks = append(networks, *network.DeepCopy())
+		}
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		if networks[i].ClusterID != networks[j].ClusterID {
+			return networks[i].ClusterID < networks[j].ClusterID
+		}
+		return strings.Join(networks[i].Path, ",") < strings.Join(networks[j].Path, ",")
+	})
+	return networks
+}
+
+// Handlers returns the set of handlers used for the Watch configuration.
+func (tw *TransitWatcher) Handlers() handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(ce event.CreateEvent, rli workqueue.RateLimitingInterface) {
+			tep := ce.Object.(*netv1alpha1.TunnelEndpoint)
+			tw.handle(tep, reachableNetworks(tep), rli)
+		},
+		UpdateFunc: func(ue event.UpdateEvent, rli workqueue.RateLimitingInterface) {
+			tep := ue.ObjectNew.(*netv1alpha1.TunnelEndpoint)
+			tw.handle(tep, reachableNetworks(tep), rli)
+		},
+		DeleteFunc: func(de event.DeleteEvent, rli workqueue.RateLimitingInterface) {
+			tep := de.Object.(*netv1alpha1.TunnelEndpoint)
+			tw.handle(tep, nil, rli)
+		},
+	}
+}
+
+// handle processes the events concerning a TunnelEndpoint, given the networks reachable through the remote cluster.
+func (tw *TransitWatcher) handle(tep *netv1alpha1.TunnelEndpoint, networks []netv1alpha1.TransitNetwork,
+	rli workqueue.RateLimitingInterface) {
+	clusterID := tep.Spec.ClusterID
+	if clusterID == "" {
+		return
+	}
+	klog.V(4).Infof("Handling TunnelEndpoint %q for transit networks", klog.KObj(tep))
+
+	tw.Lock()
+	defer tw.Unlock()
+
+	current, found := tw.networks[clusterID]
+	// The set of reachable networks did not change, nothing to do
+	if (len(networks) == 0 && !found) || (found && reflect.DeepEqual(current, networks)) {
+		return
+	}
+
+	if len(networks) > 0 {
+		klog.Infof("Networks of %d cluster(s) reachable through cluster %v", len(networks), clusterID)
+		tw.networks[clusterID] = networks
+	} else {
+		klog.Infof("No networks reachable through cluster %v", clusterID)
+		delete(tw.networks, clusterID)
+	}
+
+	// Enqueue all foreign clusters for update (which in turn update the respective network configs)
+	tw.enqueuefn(rli)
+}
+
+// reachableNetworks returns the networks reachable through the remote cluster of the given TunnelEndpoint,
+// as seen by the local cluster: the ones of the remote cluster itself, followed by the ones it is a transit hop for.
+func reachableNetworks(tep *netv1alpha1.TunnelEndpoint) []netv1alpha1.TransitNetwork {
+	clusterID := tep.Spec.ClusterID
+	if clusterID == "" || !tep.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+
+	_, podCIDR := liqonetutils.GetPodCIDRS(tep)
+	_, externalCIDR := liqonetutils.GetExternalCIDRS(tep)
+	networks := []netv1alpha1.TransitNetwork{{
+		ClusterID: clusterID, PodCIDR: podCIDR, ExternalCIDR: externalCIDR, Path: []string{clusterID},
+	}}
+
+	transits := liqonetutils.TransitTunnelEndpoints(tep)
+	for i := range transits {
+		transit := &tep.Spec.RemoteTransitNetworks[i]
+		_, podCIDR := liqonetutils.GetPodCIDRS(transits[i])
+		_, externalCIDR := liqonetutils.GetExternalCIDRS(transits[i])
+		networks = append(networks, netv1alpha1.TransitNetwork{
+			ClusterID: transit.ClusterID, PodCIDR: podCIDR, ExternalCIDR: externalCIDR,
+			Path: append([]string{clusterID}, transit.Path...),
+		})
+	}
+	return networks
+}
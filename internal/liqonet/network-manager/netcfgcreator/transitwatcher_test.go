@@ -0,0 +1,110 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netcfgcreator
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+)
+
+func clusterIDs(networks []netv1alpha1.TransitNetwork) []string {
+	ids := make([]string, 0, len(networks))
+	for i := range networks {
+		ids = append(ids, networks[i].ClusterID)
+	}
+	return ids
+}
+
+var _ = Describe("Transit Watcher functions", func() {
+	var (
+		handled int
+
+		tw  *TransitWatcher
+		tep netv1alpha1.TunnelEndpoint
+	)
+
+	BeforeEach(func() {
+		handled = 0
+		tw = NewTransitWatcher(func(rli workqueue.RateLimitingInterface) { handled++ })
+		tep = netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterID:     "cluster-b",
+			RemotePodCIDR: "10.0.0.0/16", RemoteNATPodCIDR: "10.200.0.0/16",
+			RemoteExternalCIDR: "10.1.0.0/16", RemoteNATExternalCIDR: consts.DefaultCIDRValue,
+			RemoteTransitNetworks: []netv1alpha1.TransitEndpoint{{
+				ClusterID: "cluster-c", PodCIDR: "10.2.0.0/16", NATPodCIDR: consts.DefaultCIDRValue,
+				ExternalCIDR: "10.3.0.0/16", NATExternalCIDR: "10.203.0.0/16", Path: []string{"cluster-c"},
+			}},
+		}}
+	})
+
+	Describe("The reachableNetworks function", func() {
+		It("should return the networks of the remote cluster and of the ones reached through it", func() {
+			Expect(reachableNetworks(&tep)).To(Equal([]netv1alpha1.TransitNetwork{{
+				ClusterID: "cluster-b", PodCIDR: "10.200.0.0/16", ExternalCIDR: "10.1.0.0/16", Path: []string{"cluster-b"},
+			}, {
+				ClusterID: "cluster-c", PodCIDR: "10.2.0.0/16", ExternalCIDR: "10.203.0.0/16", Path: []string{"cluster-b", "cluster-c"},
+			}}))
+		})
+
+		It("should return no networks if the cluster ID is not set", func() {
+			tep.Spec.ClusterID = ""
+			Expect(reachableNetworks(&tep)).To(BeEmpty())
+		})
+	})
+
+	Describe("The handle function", func() {
+		When("a TunnelEndpoint is added", func() {
+			BeforeEach(func() { tw.handle(&tep, reachableNetworks(&tep), nil) })
+
+			It("should execute the handle function", func() { Expect(handled).To(Equal(1)) })
+			It("should not execute the handle function if nothing changes", func() {
+				tw.handle(&tep, reachableNetworks(&tep), nil)
+				Expect(handled).To(Equal(1))
+			})
+
+			It("should advertise the networks to the other clusters", func() {
+				Expect(tw.Networks("cluster-a")).To(HaveLen(2))
+			})
+			It("should not advertise the networks back to the cluster they are reached through", func() {
+				Expect(tw.Networks("cluster-b")).To(BeEmpty())
+			})
+			It("should not advertise the networks whose path traverses the destination cluster", func() {
+				Expect(clusterIDs(tw.Networks("cluster-c"))).To(ConsistOf("cluster-b"))
+			})
+
+			When("the TunnelEndpoint is removed", func() {
+				BeforeEach(func() { tw.handle(&tep, nil, nil) })
+
+				It("should execute the handle function", func() { Expect(handled).To(Equal(2)) })
+				It("should no longer advertise the networks", func() { Expect(tw.Networks("cluster-a")).To(BeEmpty()) })
+			})
+		})
+
+		When("the path towards a cluster exceeds the maximum number of hops", func() {
+			BeforeEach(func() {
+				tep.Spec.RemoteTransitNetworks[0].Path = []string{"cluster-d", "cluster-e", "cluster-c"}
+				tw.handle(&tep, reachableNetworks(&tep), nil)
+			})
+
+			It("should not advertise its networks", func() {
+				Expect(clusterIDs(tw.Networks("cluster-a"))).To(ConsistOf("cluster-b"))
+			})
+		})
+	})
+})
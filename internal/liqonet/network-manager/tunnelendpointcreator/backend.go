@@ -0,0 +1,83 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
+)
+
+// defaultBackendType is the backend type used when the two clusters do not agree on a different one.
+const defaultBackendType = wireguard.DriverName
+
+// backendAvailabilityKeys maps the optional backend types to the BackendConfig entry
+// advertised by a cluster when the corresponding driver is available.
+var backendAvailabilityKeys = map[string]string{
+	ipsec.DriverName: ipsec.PublicKey,
+}
+
+// negotiateBackendType returns the backend type to be used for the tunnel between the local and the remote cluster.
+// The same result is obtained by both clusters, since the negotiation is symmetric with respect to the two NetworkConfigs:
+// a backend type different from the default one is selected if requested by at least one of the clusters (and not
+// conflicting with the other one), and the corresponding driver is available on both sides.
+func negotiateBackendType(local, remote *netv1alpha1.NetworkConfig) string {
+	localType, remoteType := local.Spec.BackendType, remote.Spec.BackendType
+
+	requested := localType
+	switch {
+	case localType == remoteType:
+	case localType == defaultBackendType || localType == "":
+		requested = remoteType
+	case remoteType != defaultBackendType && remoteType != "":
+		// The two clusters requested different optional backends.
+		return defaultBackendType
+	}
+
+	if requested == defaultBackendType || requested == "" {
+		return defaultBackendType
+	}
+
+	key, found := backendAvailabilityKeys[requested]
+	if !found || local.Spec.BackendConfig[key] == "" || remote.Spec.BackendConfig[key] == "" {
+		return defaultBackendType
+	}
+	return requested
+}
+
+// backendConfig returns the BackendConfig of the TunnelEndpoint towards the remote cluster, i.e. the one advertised
+// by the remote cluster, along with the next local wireguard key it confirmed to have configured, if any.
+func backendConfig(local, remote *netv1alpha1.NetworkConfig) map[string]string {
+	config := make(map[string]string, len(remote.Spec.BackendConfig)+1)
+	for key, value := range remote.Spec.BackendConfig {
+		config[key] = value
+	}
+	if local.Status.InstalledNextPublicKey != "" &&
+		local.Status.InstalledNextPublicKey == local.Spec.BackendConfig[wireguard.NextPublicKey] {
+		config[wireguard.InstalledNextPublicKey] = local.Status.InstalledNextPublicKey
+	}
+	return config
+}
+
+// installedNextPublicKey returns the next wireguard key advertised by the remote cluster through the given NetworkConfig,
+// if the local gateway already configured it as a peer of the given TunnelEndpoint.
+func installedNextPublicKey(netcfg *netv1alpha1.NetworkConfig, tep *netv1alpha1.TunnelEndpoint) string {
+	next := netcfg.Spec.BackendConfig[wireguard.NextPublicKey]
+	if next == "" || tep == nil || tep.Status.Connection.Status != netv1alpha1.Connected ||
+		tep.Status.Connection.PeerConfiguration[wireguard.NextPublicKey] != next {
+		return ""
+	}
+	return next
+}
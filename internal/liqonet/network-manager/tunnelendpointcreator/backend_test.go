@@ -0,0 +1,99 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
+)
+
+func netcfg(backendType string, ipsecAvailable bool) *netv1alpha1.NetworkConfig {
+	config := map[string]string{wireguard.PublicKey: "wg-key"}
+	if ipsecAvailable {
+		config[ipsec.PublicKey] = "ipsec-key"
+	}
+	return &netv1alpha1.NetworkConfig{Spec: netv1alpha1.NetworkConfigSpec{BackendType: backendType, BackendConfig: config}}
+}
+
+func TestNegotiateBackendType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		local    *netv1alpha1.NetworkConfig
+		remote   *netv1alpha1.NetworkConfig
+		expected string
+	}{
+		{"both default", netcfg(wireguard.DriverName, true), netcfg(wireguard.DriverName, true), wireguard.DriverName},
+		{"both ipsec", netcfg(ipsec.DriverName, true), netcfg(ipsec.DriverName, true), ipsec.DriverName},
+		{"ipsec requested locally", netcfg(ipsec.DriverName, true), netcfg(wireguard.DriverName, true), ipsec.DriverName},
+		{"ipsec requested remotely", netcfg(wireguard.DriverName, true), netcfg(ipsec.DriverName, true), ipsec.DriverName},
+		{"ipsec unavailable locally", netcfg(wireguard.DriverName, false), netcfg(ipsec.DriverName, true), wireguard.DriverName},
+		{"ipsec unavailable remotely", netcfg(ipsec.DriverName, true), netcfg(ipsec.DriverName, false), wireguard.DriverName},
+		{"unknown backend", netcfg("foo", true), netcfg(wireguard.DriverName, true), wireguard.DriverName},
+		{"conflicting backends", netcfg("foo", true), netcfg(ipsec.DriverName, true), wireguard.DriverName},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, negotiateBackendType(tc.local, tc.remote), tc.expected)
+			// The negotiation must lead to the same result on both clusters.
+			assert.Equal(t, negotiateBackendType(tc.remote, tc.local), tc.expected)
+		})
+	}
+}
+
+func TestBackendConfig(t *testing.T) {
+	local, remote := netcfg(wireguard.DriverName, false), netcfg(wireguard.DriverName, false)
+	remote.Spec.BackendConfig[wireguard.PublicKey] = "remote-key"
+
+	config := backendConfig(local, remote)
+	assert.DeepEqual(t, config, map[string]string{wireguard.PublicKey: "remote-key"})
+	// The BackendConfig of the remote NetworkConfig must not be modified.
+	config[wireguard.InstalledNextPublicKey] = "foo"
+	assert.Equal(t, len(remote.Spec.BackendConfig), 1)
+
+	// A stale confirmation, referring to a previous rotation, is ignored.
+	local.Status.InstalledNextPublicKey = "previous-key"
+	local.Spec.BackendConfig[wireguard.NextPublicKey] = "next-key"
+	assert.DeepEqual(t, backendConfig(local, remote), map[string]string{wireguard.PublicKey: "remote-key"})
+
+	local.Status.InstalledNextPublicKey = "next-key"
+	assert.DeepEqual(t, backendConfig(local, remote),
+		map[string]string{wireguard.PublicKey: "remote-key", wireguard.InstalledNextPublicKey: "next-key"})
+}
+
+func TestInstalledNextPublicKey(t *testing.T) {
+	remote := netcfg(wireguard.DriverName, false)
+	tep := &netv1alpha1.TunnelEndpoint{Status: netv1alpha1.TunnelEndpointStatus{Connection: netv1alpha1.Connection{
+		Status: netv1alpha1.Connected, PeerConfiguration: map[string]string{wireguard.NextPublicKey: "next-key"}}}}
+
+	assert.Equal(t, installedNextPublicKey(remote, tep), "", "no rotation in progress")
+
+	remote.Spec.BackendConfig[wireguard.NextPublicKey] = "next-key"
+	assert.Equal(t, installedNextPublicKey(remote, nil), "", "no tunnelendpoint")
+	assert.Equal(t, installedNextPublicKey(remote, tep), "next-key")
+
+	tep.Status.Connection.Status = netv1alpha1.ConnectionError
+	assert.Equal(t, installedNextPublicKey(remote, tep), "", "connection failed")
+
+	tep.Status.Connection.Status = netv1alpha1.Connected
+	remote.Spec.BackendConfig[wireguard.NextPublicKey] = "another-key"
+	assert.Equal(t, installedNextPublicKey(remote, tep), "", "key not yet configured")
+}
@@ -0,0 +1,188 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/utils/slice"
+)
+
+// acceptedTransitNetworks returns the transit networks advertised by the given remote NetworkConfig which are accepted
+// by the local cluster, given the remote NetworkConfigs of all the peered clusters. An advertisement is discarded if
+// it is malformed, if its path is too long or traverses the local cluster (to prevent loops), if the cluster it refers
+// to is directly peered, or if the same cluster is reachable through a shorter path (or an equal one through a
+// transit cluster with a lower ID, to ensure a deterministic choice).
+func acceptedTransitNetworks(remote *netv1alpha1.NetworkConfig, netcfgs []netv1alpha1.NetworkConfig) []netv1alpha1.TransitNetwork {
+	localClusterID := remote.Spec.ClusterID
+	viaClusterID := remote.GetLabels()[liqoconst.ReplicationOriginLabel]
+
+	peered := map[string]bool{localClusterID: true}
+	for i := range netcfgs {
+		peered[netcfgs[i].GetLabels()[liqoconst.ReplicationOriginLabel]] = true
+	}
+
+	var accepted []netv1alpha1.TransitNetwork
+	for i := range remote.Spec.TransitNetworks {
+		network := &remote.Spec.TransitNetworks[i]
+		if !validTransitNetwork(network, localClusterID) || peered[network.ClusterID] {
+			continue
+		}
+		if betterTransitNetworkExists(network, viaClusterID, localClusterID, netcfgs) {
+			klog.V(4).Infof("Cluster %v is reachable through a better path than the one through cluster %v", network.ClusterID, viaClusterID)
+			continue
+		}
+		accepted = append(accepted, *network.DeepCopy())
+	}
+
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].ClusterID < accepted[j].ClusterID })
+	return accepted
+}
+
+// validTransitNetwork returns whether the given transit network is well-formed and does not lead to routing loops.
+func validTransitNetwork(network *netv1alpha1.TransitNetwork, localClusterID string) bool {
+	return network.ClusterID != "" && network.PodCIDR != "" && network.ExternalCIDR != "" &&
+		len(network.Path) > 0 && len(network.Path) <= liqoconst.TransitMaxHops &&
+		network.Path[len(network.Path)-1] == network.ClusterID &&
+		!slice.ContainsString(network.Path, localClusterID)
+}
+
+// betterTransitNetworkExists returns whether the cluster the given transit network refers to is advertised by
+// a different peered cluster through a better path.
+func betterTransitNetworkExists(network *netv1alpha1.TransitNetwork, viaClusterID, localClusterID string,
+	netcfgs []netv1alpha1.NetworkConfig) bool {
+	for i := range netcfgs {
+		otherViaClusterID := netcfgs[i].GetLabels()[liqoconst.ReplicationOriginLabel]
+		if otherViaClusterID == viaClusterID {
+			continue
+		}
+		for j := range netcfgs[i].Spec.TransitNetworks {
+			other := &netcfgs[i].Spec.TransitNetworks[j]
+			if other.ClusterID != network.ClusterID || !validTransitNetwork(other, localClusterID) {
+				continue
+			}
+			if len(other.Path) < len(network.Path) || (len(other.Path) == len(network.Path) && otherViaClusterID < viaClusterID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transitEndpoints returns the transit networks advertised in the spec of the given NetworkConfig,
+// and accepted by the remote cluster, together with the corresponding remappings.
+func transitEndpoints(netcfg *netv1alpha1.NetworkConfig) []netv1alpha1.TransitEndpoint {
+	var endpoints []netv1alpha1.TransitEndpoint
+	for i := range netcfg.Status.TransitNetworksNAT {
+		nat := &netcfg.Status.TransitNetworksNAT[i]
+		for j := range netcfg.Spec.TransitNetworks {
+			network := &netcfg.Spec.TransitNetworks[j]
+			if network.ClusterID != nat.ClusterID {
+				continue
+			}
+			endpoints = append(endpoints, netv1alpha1.TransitEndpoint{
+				ClusterID:       network.ClusterID,
+				PodCIDR:         network.PodCIDR,
+				NATPodCIDR:      nat.PodCIDRNAT,
+				ExternalCIDR:    network.ExternalCIDR,
+				NATExternalCIDR: nat.ExternalCIDRNAT,
+				Path:            append([]string(nil), network.Path...),
+			})
+			break
+		}
+	}
+	return endpoints
+}
+
+// enforceTransitNetworksNAT allocates the networks of the clusters reached through the cluster the given remote NetworkConfig
+// belongs to, and returns the corresponding remappings. The networks no longer reached through that cluster are released.
+func (tec *TunnelEndpointCreator) enforceTransitNetworksNAT(ctx context.Context, netcfg *netv1alpha1.NetworkConfig) (
+	[]netv1alpha1.TransitNetworkNAT, error) {
+	viaClusterID := netcfg.Labels[liqoconst.ReplicationOriginLabel]
+
+	var accepted []netv1alpha1.TransitNetwork
+	if tec.TransitRouting {
+		var netcfgs netv1alpha1.NetworkConfigList
+		if err := tec.List(ctx, &netcfgs, client.HasLabels{liqoconst.ReplicationOriginLabel}); err != nil {
+			klog.Errorf("An error occurred while listing remote NetworkConfigs: %v", err)
+			return nil, err
+		}
+		accepted = acceptedTransitNetworks(netcfg, netcfgs.Items)
+	}
+
+	var nats []netv1alpha1.TransitNetworkNAT
+	acceptedIDs := map[string]bool{}
+	for i := range accepted {
+		network := &accepted[i]
+		podCIDR, externalCIDR, err := tec.IPManager.GetTransitSubnets(viaClusterID, network.ClusterID, network.PodCIDR, network.ExternalCIDR)
+		if err != nil {
+			klog.Errorf("An error occurred while getting the subnets of cluster %v reached through cluster %v: %v",
+				network.ClusterID, viaClusterID, err)
+			return nil, err
+		}
+
+		// Set the default values in case the CIDRs have not been remapped
+		if podCIDR == network.PodCIDR {
+			podCIDR = liqoconst.DefaultCIDRValue
+		}
+		if externalCIDR == network.ExternalCIDR {
+			externalCIDR = liqoconst.DefaultCIDRValue
+		}
+		nats = append(nats, netv1alpha1.TransitNetworkNAT{ClusterID: network.ClusterID, PodCIDRNAT: podCIDR, ExternalCIDRNAT: externalCIDR})
+		acceptedIDs[network.ClusterID] = true
+	}
+
+	for i := range netcfg.Status.TransitNetworksNAT {
+		clusterID := netcfg.Status.TransitNetworksNAT[i].ClusterID
+		if acceptedIDs[clusterID] {
+			continue
+		}
+		if err := tec.IPManager.RemoveTransitSubnets(viaClusterID, clusterID); err != nil {
+			klog.Errorf("An error occurred while releasing the subnets of cluster %v reached through cluster %v: %v",
+				clusterID, viaClusterID, err)
+			return nil, err
+		}
+	}
+
+	return nats, nil
+}
+
+// transitNetworkConfigs returns the requests for the remote NetworkConfigs other than the given one, as the transit networks
+// they advertise are accepted depending on the ones advertised by the other peered clusters.
+func (tec *TunnelEndpointCreator) transitNetworkConfigs(obj client.Object) []reconcile.Request {
+	var netcfgs netv1alpha1.NetworkConfigList
+	if err := tec.List(context.Background(), &netcfgs, client.HasLabels{liqoconst.ReplicationOriginLabel}); err != nil {
+		klog.Errorf("An error occurred while listing remote NetworkConfigs: %v", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range netcfgs.Items {
+		if netcfgs.Items[i].GetUID() == obj.GetUID() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Name: netcfgs.Items[i].GetName(), Namespace: netcfgs.Items[i].GetNamespace()}})
+	}
+	return requests
+}
@@ -0,0 +1,85 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelendpointcreator
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+)
+
+func remoteNetcfg(viaClusterID string, networks ...netv1alpha1.TransitNetwork) netv1alpha1.NetworkConfig {
+	return netv1alpha1.NetworkConfig{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{liqoconst.ReplicationOriginLabel: viaClusterID}},
+		Spec:       netv1alpha1.NetworkConfigSpec{ClusterID: "local", TransitNetworks: networks},
+	}
+}
+
+func transitNetwork(clusterID string, path ...string) netv1alpha1.TransitNetwork {
+	return netv1alpha1.TransitNetwork{ClusterID: clusterID, PodCIDR: "10.0.0.0/16", ExternalCIDR: "10.1.0.0/16", Path: path}
+}
+
+func TestAcceptedTransitNetworks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		remote   netv1alpha1.NetworkConfig
+		others   []netv1alpha1.NetworkConfig
+		expected []string
+	}{
+		{"single advertisement", remoteNetcfg("hub", transitNetwork("edge", "edge")), nil, []string{"edge"}},
+		{"malformed path", remoteNetcfg("hub", transitNetwork("edge", "other")), nil, nil},
+		{"empty path", remoteNetcfg("hub", transitNetwork("edge")), nil, nil},
+		{"path traversing the local cluster", remoteNetcfg("hub", transitNetwork("edge", "local", "edge")), nil, nil},
+		{"path too long", remoteNetcfg("hub", transitNetwork("edge", "a", "b", "c", "edge")), nil, nil},
+		{"directly peered cluster", remoteNetcfg("hub", transitNetwork("edge", "edge")),
+			[]netv1alpha1.NetworkConfig{remoteNetcfg("edge")}, nil},
+		{"shorter path through another cluster", remoteNetcfg("hub", transitNetwork("edge", "a", "edge")),
+			[]netv1alpha1.NetworkConfig{remoteNetcfg("other", transitNetwork("edge", "edge"))}, nil},
+		{"longer path through another cluster", remoteNetcfg("hub", transitNetwork("edge", "edge")),
+			[]netv1alpha1.NetworkConfig{remoteNetcfg("other", transitNetwork("edge", "a", "edge"))}, []string{"edge"}},
+		{"equal path through a cluster with lower ID", remoteNetcfg("hub", transitNetwork("edge", "edge")),
+			[]netv1alpha1.NetworkConfig{remoteNetcfg("aaa", transitNetwork("edge", "edge"))}, nil},
+		{"equal path through a cluster with higher ID", remoteNetcfg("hub", transitNetwork("edge", "edge")),
+			[]netv1alpha1.NetworkConfig{remoteNetcfg("zzz", transitNetwork("edge", "edge"))}, []string{"edge"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			netcfgs := append([]netv1alpha1.NetworkConfig{tc.remote}, tc.others...)
+			var accepted []string
+			for _, network := range acceptedTransitNetworks(&tc.remote, netcfgs) {
+				accepted = append(accepted, network.ClusterID)
+			}
+			assert.DeepEqual(t, accepted, tc.expected)
+		})
+	}
+}
+
+func TestTransitEndpoints(t *testing.T) {
+	netcfg := remoteNetcfg("hub", transitNetwork("edge", "edge"), transitNetwork("rejected", "rejected"))
+	netcfg.Status.TransitNetworksNAT = []netv1alpha1.TransitNetworkNAT{
+		{ClusterID: "edge", PodCIDRNAT: "10.200.0.0/16", ExternalCIDRNAT: liqoconst.DefaultCIDRValue},
+	}
+
+	assert.DeepEqual(t, transitEndpoints(&netcfg), []netv1alpha1.TransitEndpoint{{
+		ClusterID: "edge", PodCIDR: "10.0.0.0/16", NATPodCIDR: "10.200.0.0/16",
+		ExternalCIDR: "10.1.0.0/16", NATExternalCIDR: liqoconst.DefaultCIDRValue, Path: []string{"edge"},
+	}})
+}
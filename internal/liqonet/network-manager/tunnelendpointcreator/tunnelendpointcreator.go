@@ -25,13 +25,20 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/trace"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/internal/liqonet/network-manager/netcfgcreator"
@@ -61,6 +68,20 @@ type networkParam struct {
 	localNatExternalCIDR  string
 	backendType           string
 	backendConfig         map[string]string
+
+	// The IPv6 networks, set only in case both clusters are dual-stack.
+	remotePodCIDRv6         string
+	remoteNatPodCIDRv6      string
+	remoteExternalCIDRv6    string
+	remoteNatExternalCIDRv6 string
+	localNatPodCIDRv6       string
+	localPodCIDRv6          string
+	localExternalCIDRv6     string
+	localNatExternalCIDRv6  string
+
+	// The IPv4 networks of the clusters reached through a transit hop, set only if transit routing is enabled.
+	remoteTransitNetworks []netv1alpha1.TransitEndpoint
+	localTransitNetworks  []netv1alpha1.TransitEndpoint
 }
 
 // TunnelEndpointCreator manages the most of liqo networking.
@@ -68,6 +89,12 @@ type TunnelEndpointCreator struct {
 	client.Client
 	Scheme    *runtime.Scheme
 	IPManager liqonetIpam.Ipam
+	// DualStack is true if the local cluster is a dual-stack one, hence the IPv6 networks
+	// of the remote dual-stack clusters have to be allocated as well.
+	DualStack bool
+	// TransitRouting is true if the networks of the clusters reached through a peered one, acting as transit hop,
+	// have to be accepted and allocated as well.
+	TransitRouting bool
 }
 
 // rbac for the net.liqo.io api
@@ -166,9 +193,40 @@ func (tec *TunnelEndpointCreator) Reconcile(ctx context.Context, req ctrl.Reques
 
 // SetupWithManager informs the manager that the tunnelEndpointCreator will deal with networkconfigs.
 func (tec *TunnelEndpointCreator) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&netv1alpha1.NetworkConfig{}).
-		Complete(tec)
+	controller := ctrl.NewControllerManagedBy(mgr).For(&netv1alpha1.NetworkConfig{}).
+		Watches(&source.Kind{Type: &netv1alpha1.TunnelEndpoint{}}, handler.EnqueueRequestsFromMapFunc(tec.tunnelEndpointNetworkConfigs),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: connectionChanged}))
+	if tec.TransitRouting {
+		controller = controller.Watches(&source.Kind{Type: &netv1alpha1.NetworkConfig{}},
+			handler.EnqueueRequestsFromMapFunc(tec.transitNetworkConfigs),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	return controller.Complete(tec)
+}
+
+// tunnelEndpointNetworkConfigs returns the requests for the remote NetworkConfig associated with the given TunnelEndpoint,
+// to confirm the next key of the remote cluster once configured by the local gateway.
+func (tec *TunnelEndpointCreator) tunnelEndpointNetworkConfigs(obj client.Object) []reconcile.Request {
+	tep, ok := obj.(*netv1alpha1.TunnelEndpoint)
+	if !ok {
+		return nil
+	}
+
+	remote, err := netcfgcreator.GetRemoteNetworkConfig(context.Background(), tec.Client, tep.Spec.ClusterID, tep.GetNamespace())
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to retrieve remote NetworkConfig for cluster %v: %v", tep.Spec.ClusterID, err)
+		}
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: remote.GetName(), Namespace: remote.GetNamespace()}}}
+}
+
+// connectionChanged filters the updates of the TunnelEndpoints, selecting only the ones modifying the connection status.
+func connectionChanged(ev event.UpdateEvent) bool {
+	oldTep, okOld := ev.ObjectOld.(*netv1alpha1.TunnelEndpoint)
+	newTep, okNew := ev.ObjectNew.(*netv1alpha1.TunnelEndpoint)
+	return !okOld || !okNew || !reflect.DeepEqual(oldTep.Status.Connection, newTep.Status.Connection)
 }
 
 // SetupSignalHandlerForTunEndCreator registers for SIGTERM, SIGINT, SIGKILL. A stop channel is returned
@@ -242,6 +300,12 @@ func (tec *TunnelEndpointCreator) processNetworkConfig(ctx context.Context, clus
 		klog.Errorf("Failed to add local subnets to IPAM for cluster %v: %v", local.Spec.ClusterID, err)
 		return err
 	}
+	if dualStack(local, remote) {
+		if err := tec.IPManager.AddLocalSubnetsPerClusterV6(local.Status.PodCIDRv6NAT, local.Status.ExternalCIDRv6NAT, clusterID); err != nil {
+			klog.Errorf("Failed to add local IPv6 subnets to IPAM for cluster %v: %v", local.Spec.ClusterID, err)
+			return err
+		}
+	}
 	tracer.Step("IPAM configuration")
 
 	// If we reached this point, then it is possible to enforce the TunnelEndpoint resource
@@ -290,11 +354,46 @@ func (tec *TunnelEndpointCreator) enforceRemoteNetConfigStatus(ctx context.Conte
 		podCIDR = liqoconst.DefaultCIDRValue
 	}
 
+	// Get the IPv6 CIDR remappings, in case both clusters are dual-stack
+	var podCIDRv6, externalCIDRv6 string
+	if tec.DualStack && netcfg.Spec.PodCIDRv6 != "" {
+		podCIDRv6, externalCIDRv6, err = tec.IPManager.GetSubnetsPerClusterV6(netcfg.Spec.PodCIDRv6, netcfg.Spec.ExternalCIDRv6, clusterID)
+		if err != nil {
+			klog.Errorf("An error occurred while getting a new IPv6 subnet for resource %q: %v", klog.KObj(netcfg), err)
+			return err
+		}
+		if podCIDRv6 == netcfg.Spec.PodCIDRv6 {
+			podCIDRv6 = liqoconst.DefaultCIDRValue
+		}
+		if externalCIDRv6 == netcfg.Spec.ExternalCIDRv6 {
+			externalCIDRv6 = liqoconst.DefaultCIDRValue
+		}
+		tracer.Step("IPv6 CIDR remappings retrieval")
+	}
+
+	// Get the remappings of the networks reached through the remote cluster
+	transitNetworksNAT, err := tec.enforceTransitNetworksNAT(ctx, netcfg)
+	if err != nil {
+		return err
+	}
+	tracer.Step("Transit networks remappings retrieval")
+
+	// Confirm the next wireguard key of the remote cluster, once the local gateway configured it as a peer
+	tep, _, err := tec.GetTunnelEndpoint(ctx, clusterID, netcfg.GetNamespace())
+	if err != nil {
+		return err
+	}
+	tracer.Step("TunnelEndpoint retrieval")
+
 	// Update the status fields
 	original := netcfg.Status.DeepCopy()
 	netcfg.Status.Processed = true
 	netcfg.Status.PodCIDRNAT = podCIDR
 	netcfg.Status.ExternalCIDRNAT = externalCIDR
+	netcfg.Status.PodCIDRv6NAT = podCIDRv6
+	netcfg.Status.ExternalCIDRv6NAT = externalCIDRv6
+	netcfg.Status.TransitNetworksNAT = transitNetworksNAT
+	netcfg.Status.InstalledNextPublicKey = installedNextPublicKey(netcfg, tep)
 
 	// Avoid performing updates in case it is not necessary
 	if !reflect.DeepEqual(original, netcfg.Status) {
@@ -323,8 +422,22 @@ func (tec *TunnelEndpointCreator) enforceTunnelEndpoint(ctx context.Context, loc
 		localPodCIDR:          local.Spec.PodCIDR,
 		localExternalCIDR:     local.Spec.ExternalCIDR,
 		localNatExternalCIDR:  local.Status.ExternalCIDRNAT,
-		backendType:           remote.Spec.BackendType,
-		backendConfig:         remote.Spec.BackendConfig,
+		backendType:           negotiateBackendType(local, remote),
+		backendConfig:         backendConfig(local, remote),
+	}
+	if dualStack(local, remote) {
+		param.remotePodCIDRv6 = remote.Spec.PodCIDRv6
+		param.remoteNatPodCIDRv6 = remote.Status.PodCIDRv6NAT
+		param.remoteExternalCIDRv6 = remote.Spec.ExternalCIDRv6
+		param.remoteNatExternalCIDRv6 = remote.Status.ExternalCIDRv6NAT
+		param.localNatPodCIDRv6 = local.Status.PodCIDRv6NAT
+		param.localPodCIDRv6 = local.Spec.PodCIDRv6
+		param.localExternalCIDRv6 = local.Spec.ExternalCIDRv6
+		param.localNatExternalCIDRv6 = local.Status.ExternalCIDRv6NAT
+	}
+	if tec.TransitRouting {
+		param.remoteTransitNetworks = transitEndpoints(remote)
+		param.localTransitNetworks = transitEndpoints(local)
 	}
 
 	// Try to get the tunnelEndpoint, which may not exist
@@ -415,11 +528,28 @@ func (tec *TunnelEndpointCreator) fillTunnelEndpointSpec(tep *netv1alpha1.Tunnel
 	tep.Spec.RemoteNATPodCIDR = param.remoteNatPodCIDR
 	tep.Spec.RemoteExternalCIDR = param.remoteExternalCIDR
 	tep.Spec.RemoteNATExternalCIDR = param.remoteNatExternalCIDR
+	tep.Spec.LocalPodCIDRv6 = param.localPodCIDRv6
+	tep.Spec.LocalExternalCIDRv6 = param.localExternalCIDRv6
+	tep.Spec.LocalNATPodCIDRv6 = param.localNatPodCIDRv6
+	tep.Spec.LocalNATExternalCIDRv6 = param.localNatExternalCIDRv6
+	tep.Spec.RemotePodCIDRv6 = param.remotePodCIDRv6
+	tep.Spec.RemoteNATPodCIDRv6 = param.remoteNatPodCIDRv6
+	tep.Spec.RemoteExternalCIDRv6 = param.remoteExternalCIDRv6
+	tep.Spec.RemoteNATExternalCIDRv6 = param.remoteNatExternalCIDRv6
+	tep.Spec.RemoteTransitNetworks = param.remoteTransitNetworks
+	tep.Spec.LocalTransitNetworks = param.localTransitNetworks
 	tep.Spec.EndpointIP = param.remoteEndpointIP
 	tep.Spec.BackendType = param.backendType
 	tep.Spec.BackendConfig = param.backendConfig
 }
 
+// dualStack returns whether the IPv6 networks have been negotiated by both clusters, i.e. each one
+// has processed the IPv6 CIDRs advertised by the other.
+func dualStack(local, remote *netv1alpha1.NetworkConfig) bool {
+	return local.Spec.PodCIDRv6 != "" && local.Status.PodCIDRv6NAT != "" &&
+		remote.Spec.PodCIDRv6 != "" && remote.Status.PodCIDRv6NAT != ""
+}
+
 // GetTunnelEndpoint retrieves the tunnelEndpoint resource related to a cluster.
 func (tec *TunnelEndpointCreator) GetTunnelEndpoint(ctx context.Context, destinationClusterID, namespace string) (
 	*netv1alpha1.TunnelEndpoint,
@@ -0,0 +1,313 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunneloperator
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vishvananda/netlink"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/probe"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+var (
+	tunnelRTTDesc = prometheus.NewDesc("liqo_gateway_tunnel_rtt_seconds",
+		"Average round trip time towards the remote gateway, measured by the last probes", []string{"cluster_id"}, nil)
+	tunnelPacketLossDesc = prometheus.NewDesc("liqo_gateway_tunnel_packet_loss_ratio",
+		"Ratio of the last probes towards the remote gateway which did not get a reply", []string{"cluster_id"}, nil)
+	tunnelReachableDesc = prometheus.NewDesc("liqo_gateway_tunnel_reachable",
+		"1 if the remote gateway is reachable through the tunnel, 0 otherwise", []string{"cluster_id"}, nil)
+	tunnelLastHandshakeDesc = prometheus.NewDesc("liqo_gateway_tunnel_last_handshake_timestamp_seconds",
+		"Time of the last handshake with the remote peer since unix epoch in seconds", []string{"cluster_id"}, nil)
+	tunnelReceivedBytesDesc = prometheus.NewDesc("liqo_gateway_tunnel_received_bytes_total",
+		"Number of bytes received from the remote peer", []string{"cluster_id"}, nil)
+	tunnelTransmittedBytesDesc = prometheus.NewDesc("liqo_gateway_tunnel_transmitted_bytes_total",
+		"Number of bytes transmitted to the remote peer", []string{"cluster_id"}, nil)
+)
+
+// probedTunnel holds the information concerning a tunnel monitored by the prober.
+type probedTunnel struct {
+	// tep is a reference to the TunnelEndpoint describing the tunnel, used to trigger its reconciliation.
+	tep *netv1alpha1.TunnelEndpoint
+	// backend is the type of the tunnel driver.
+	backend string
+	// responder is whether the remote gateway advertises to answer the probes.
+	responder bool
+	// statistics are the last reported statistics, nil if not yet available.
+	statistics *netv1alpha1.TunnelStatistics
+	// probe holds the outcome of the last probes at the time the statistics have been reported.
+	probe probe.Statistics
+}
+
+// tunnelHealth keeps track of the health of the tunnels, measured by the prober.
+type tunnelHealth struct {
+	sync.Mutex
+	prober  *probe.Prober
+	tunnels map[string]*probedTunnel
+}
+
+// SetUpProber opens the socket used to probe the remote gateways in the gateway network namespace,
+// and initializes the prober. A zero interval disables the probing.
+func (tc *TunnelController) SetUpProber(opts probe.Options) error {
+	tc.health.tunnels = make(map[string]*probedTunnel)
+	if opts.Interval == 0 {
+		klog.Info("probing of the remote gateways disabled")
+		return nil
+	}
+
+	var conn probe.PacketConn
+	err := tc.gatewayNetns.Do(func(netNamespace ns.NetNS) (err error) {
+		conn, err = probe.Listen()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	tc.health.prober = probe.New(conn, opts, tc.onProbeReport)
+	return nil
+}
+
+// EnsureProbePerCluster configures the address the local gateway answers the probes at, and starts
+// probing the remote gateway described by the given tep. It has to be called in the gateway network namespace.
+// The gateways are reached at the first address of the external CIDRs as seen by the peer, which is never
+// assigned to endpoints. The local address is configured even if the probing is disabled, since the remote
+// gateway may still probe the local one. The local addresses are not removed when a peering is torn down,
+// since they may be shared by different peers and they are not reachable from outside the tunnels.
+func (tc *TunnelController) EnsureProbePerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	localExternalCIDR, remoteExternalCIDR := utils.GetExternalCIDRS(tep)
+	localAddress, err := probeAddress(localExternalCIDR)
+	if err != nil {
+		return err
+	}
+	if err := tc.ensureLocalProbeAddress(localAddress); err != nil {
+		return err
+	}
+	if tc.health.prober == nil {
+		return nil
+	}
+	remoteAddress, err := probeAddress(remoteExternalCIDR)
+	if err != nil {
+		return err
+	}
+
+	tc.health.Lock()
+	defer tc.health.Unlock()
+	tc.health.prober.SetTarget(tep.Spec.ClusterID, remoteAddress)
+	responder, _ := strconv.ParseBool(tep.Spec.BackendConfig[probe.ResponderKey])
+	if tunnel, found := tc.health.tunnels[tep.Spec.ClusterID]; found {
+		tunnel.backend = tep.Spec.BackendType
+		tunnel.responder = responder
+		return nil
+	}
+	tc.health.tunnels[tep.Spec.ClusterID] = &probedTunnel{
+		tep: &netv1alpha1.TunnelEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: tep.Name, Namespace: tep.Namespace},
+		},
+		backend:   tep.Spec.BackendType,
+		responder: responder,
+	}
+	return nil
+}
+
+// RemoveProbePerCluster stops probing the remote gateway of the given cluster.
+func (tc *TunnelController) RemoveProbePerCluster(clusterID string) {
+	if tc.health.prober == nil {
+		return
+	}
+	tc.health.Lock()
+	defer tc.health.Unlock()
+	tc.health.prober.RemoveTarget(clusterID)
+	delete(tc.health.tunnels, clusterID)
+}
+
+// ensureLocalProbeAddress configures the given address on the gateway veth, to answer the probes of the remote gateways.
+func (tc *TunnelController) ensureLocalProbeAddress(address net.IP) error {
+	link, err := netlink.LinkByName(liqoconst.GatewayVethName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the gateway veth: %w", err)
+	}
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: address, Mask: net.CIDRMask(32, 32)}}
+	if err := netlink.AddrReplace(link, addr); err != nil {
+		return fmt.Errorf("failed to configure the probe address %s on the gateway veth: %w", address, err)
+	}
+	return nil
+}
+
+// onProbeReport stores the statistics reported by the prober, and triggers the reconciliation of the corresponding tep.
+func (tc *TunnelController) onProbeReport(clusterID string, stats probe.Statistics) {
+	tc.health.Lock()
+	tunnel, found := tc.health.tunnels[clusterID]
+	if !found {
+		tc.health.Unlock()
+		return
+	}
+	tunnel.probe = stats
+	tunnel.statistics = tc.tunnelStatistics(clusterID, tunnel.backend, tunnel.responder, &stats)
+	tep := tunnel.tep
+	tc.health.Unlock()
+
+	tc.events <- event.GenericEvent{Object: tep}
+}
+
+// tunnelStatistics builds the statistics of the tunnel towards the given cluster, including those reported by the driver.
+// The outcome of the probes is omitted if the remote gateway does not answer them.
+func (tc *TunnelController) tunnelStatistics(clusterID, backend string, responder bool,
+	stats *probe.Statistics) *netv1alpha1.TunnelStatistics {
+	statistics := &netv1alpha1.TunnelStatistics{}
+	if responder {
+		statistics.PacketLoss = stats.PacketLoss
+		if stats.RTT > 0 {
+			statistics.RTT = &metav1.Duration{Duration: stats.RTT}
+		}
+		if !stats.LastProbeTime.IsZero() {
+			last := metav1.NewTime(stats.LastProbeTime.Truncate(time.Second))
+			statistics.LastProbeTime = &last
+		}
+	}
+
+	peer, err := tc.peerStatistics(clusterID, backend)
+	if err != nil {
+		klog.Warningf("%s -> unable to retrieve the peer statistics: %v", clusterID, err)
+		return statistics
+	}
+	if peer != nil {
+		if !peer.LastHandshakeTime.IsZero() {
+			handshake := metav1.NewTime(peer.LastHandshakeTime.Truncate(time.Second))
+			statistics.LastHandshakeTime = &handshake
+		}
+		statistics.ReceivedBytes = peer.ReceivedBytes
+		statistics.TransmittedBytes = peer.TransmittedBytes
+	}
+	return statistics
+}
+
+// peerStatistics returns the traffic statistics reported by the given driver, or nil if not supported.
+func (tc *TunnelController) peerStatistics(clusterID, backend string) (*tunnel.PeerStatistics, error) {
+	reporter, ok := tc.drivers[backend].(tunnel.StatisticsReporter)
+	if !ok {
+		return nil, nil
+	}
+	return reporter.PeerStatistics(clusterID)
+}
+
+// reportedStatistics returns the last statistics reported for the tunnel towards the given cluster, if any.
+func (tc *TunnelController) reportedStatistics(clusterID string) *netv1alpha1.TunnelStatistics {
+	tc.health.Lock()
+	defer tc.health.Unlock()
+
+	tunnel, found := tc.health.tunnels[clusterID]
+	if !found || tunnel.statistics == nil {
+		return nil
+	}
+	return tunnel.statistics.DeepCopy()
+}
+
+// checkTunnelHealth returns the given connection flagged as erroneous if the remote gateway answers the probes
+// and it has been reported as unreachable by the prober, and the connection as is otherwise.
+func (tc *TunnelController) checkTunnelHealth(clusterID string, con *netv1alpha1.Connection) *netv1alpha1.Connection {
+	tc.health.Lock()
+	defer tc.health.Unlock()
+
+	tunnel, found := tc.health.tunnels[clusterID]
+	if !found || !tunnel.responder || tunnel.statistics == nil || tunnel.probe.Reachable || con.Status != netv1alpha1.Connected {
+		return con
+	}
+	unreachable := con.DeepCopy()
+	unreachable.Status = netv1alpha1.ConnectionError
+	unreachable.StatusMessage = fmt.Sprintf("remote gateway unreachable: %d consecutive probes lost", tunnel.probe.ConsecutiveFailures)
+	return unreachable
+}
+
+// probeAddress returns the address the gateway of the cluster owning the given external CIDR answers the probes at.
+func probeAddress(externalCIDR string) (net.IP, error) {
+	ip, _, err := net.ParseCIDR(externalCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse external CIDR %s: %w", externalCIDR, err)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("external CIDR %s is not an IPv4 network", externalCIDR)
+	}
+	return ip.To4(), nil
+}
+
+// tunnelMetricsCollector is a prometheus.Collector exposing the health and traffic statistics of the tunnels.
+type tunnelMetricsCollector struct {
+	tc *TunnelController
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *tunnelMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tunnelRTTDesc
+	ch <- tunnelPacketLossDesc
+	ch <- tunnelReachableDesc
+	ch <- tunnelLastHandshakeDesc
+	ch <- tunnelReceivedBytesDesc
+	ch <- tunnelTransmittedBytesDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *tunnelMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.tc.health.Lock()
+	backends := make(map[string]string, len(c.tc.health.tunnels))
+	responders := make(map[string]bool, len(c.tc.health.tunnels))
+	for clusterID, tunnel := range c.tc.health.tunnels {
+		backends[clusterID] = tunnel.backend
+		responders[clusterID] = tunnel.responder
+	}
+	c.tc.health.Unlock()
+
+	for clusterID, backend := range backends {
+		if stats, found := c.tc.health.prober.Statistics(clusterID); found && responders[clusterID] && stats.Probes > 0 {
+			ch <- prometheus.MustNewConstMetric(tunnelRTTDesc, prometheus.GaugeValue, stats.RTT.Seconds(), clusterID)
+			ch <- prometheus.MustNewConstMetric(tunnelPacketLossDesc, prometheus.GaugeValue, float64(stats.PacketLoss)/100, clusterID)
+			ch <- prometheus.MustNewConstMetric(tunnelReachableDesc, prometheus.GaugeValue, boolToFloat(stats.Reachable), clusterID)
+		}
+
+		peer, err := c.tc.peerStatistics(clusterID, backend)
+		if err != nil {
+			klog.Warningf("%s -> unable to retrieve the peer statistics: %v", clusterID, err)
+			continue
+		}
+		if peer == nil {
+			continue
+		}
+		if !peer.LastHandshakeTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(tunnelLastHandshakeDesc, prometheus.GaugeValue,
+				float64(peer.LastHandshakeTime.Unix()), clusterID)
+		}
+		ch <- prometheus.MustNewConstMetric(tunnelReceivedBytesDesc, prometheus.CounterValue, float64(peer.ReceivedBytes), clusterID)
+		ch <- prometheus.MustNewConstMetric(tunnelTransmittedBytesDesc, prometheus.CounterValue, float64(peer.TransmittedBytes), clusterID)
+	}
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -61,13 +61,16 @@ var (
 type LabelerController struct {
 	client.Client
 	PodIP string
+	// ActiveActive is true if all the replicas of the gateway are active, each one handling a subset of the tunnels.
+	ActiveActive bool
 }
 
 // NewLabelerController  returns a new controller ready to be setup and started with the controller manager.
-func NewLabelerController(podIP string, cl client.Client) *LabelerController {
+func NewLabelerController(podIP string, activeActive bool, cl client.Client) *LabelerController {
 	return &LabelerController{
-		Client: cl,
-		PodIP:  podIP,
+		Client:       cl,
+		PodIP:        podIP,
+		ActiveActive: activeActive,
 	}
 }
 
@@ -75,6 +78,8 @@ func NewLabelerController(podIP string, cl client.Client) *LabelerController {
 // meaning the pod where this code is running. If it is our pod, it checks that it is labels as the
 // active replica of the gateway. It ensures that the label "net.liqo.io/gateway=active" is present.
 // If the pod is not the current one, we make sure that the pod has the label "net.liqo.io/gateway=standby".
+// In active/active mode, each replica labels itself as active and leaves the other ones untouched, and the
+// gateway service is not annotated, since the peers are given the endpoint of the replica handling their tunnel.
 func (lbc *LabelerController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	pod := new(corev1.Pod)
 	err := lbc.Get(ctx, req.NamespacedName, pod)
@@ -93,6 +98,9 @@ func (lbc *LabelerController) Reconcile(ctx context.Context, req ctrl.Request) (
 			klog.Infof("successfully updated label {%s: %s} for pod {%s}",
 				gatewayLabelKey, gatewayStatusActive, req.String())
 		}
+		if lbc.ActiveActive {
+			return ctrl.Result{}, nil
+		}
 		if err := lbc.annotateGatewayService(ctx); err != nil {
 			// Do not log here, already done in annotateGatewayService.
 			return ctrl.Result{}, err
@@ -100,6 +108,9 @@ func (lbc *LabelerController) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 	// Make sure that the other replicas has the label set to "standby".
+	if lbc.ActiveActive {
+		return ctrl.Result{}, nil
+	}
 	if val := liqoutils.GetLabelValueFromObj(pod, gatewayLabelKey); val == gatewayStatusActive {
 		if liqoutils.AddLabelToObj(pod, gatewayLabelKey, gatewayStatusStandby) {
 			if err := lbc.Update(ctx, pod); err != nil {
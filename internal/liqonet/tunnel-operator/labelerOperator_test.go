@@ -137,7 +137,7 @@ var _ = Describe("LabelerOperator", func() {
 	Describe("testing NewOverlayOperator function", func() {
 		Context("when input parameters are correct", func() {
 			It("should return labeler controller ", func() {
-				lbc1 := NewLabelerController(labelerCurrentPodIP, k8sClient)
+				lbc1 := NewLabelerController(labelerCurrentPodIP, false, k8sClient)
 				Expect(lbc1).ShouldNot(BeNil())
 			})
 		})
@@ -312,6 +312,22 @@ var _ = Describe("LabelerOperator", func() {
 					return nil
 				}).Should(BeNil())
 			})
+
+			It("label is set to {active} in active/active mode, should leave it unchanged", func() {
+				lbc.ActiveActive = true
+				labelerTestPod.SetLabels(map[string]string{
+					gatewayLabelKey: gatewayStatusActive,
+				})
+				Eventually(func() error { return k8sClient.Create(context.TODO(), labelerTestPod) }).Should(BeNil())
+				newPod := &corev1.Pod{}
+				Eventually(func() error { return k8sClient.Get(context.TODO(), labelerReq.NamespacedName, newPod) }).Should(BeNil())
+				newPod.Status.PodIP = labelerOtherPodIP
+				// Set IP address of the newly created pod.
+				Eventually(func() error { return k8sClient.Status().Update(context.TODO(), newPod) }).Should(BeNil())
+				Eventually(func() error { _, err := lbc.Reconcile(context.TODO(), labelerReq); return err }).Should(BeNil())
+				Expect(k8sClient.Get(context.TODO(), labelerReq.NamespacedName, newPod)).To(Succeed())
+				Expect(newPod.GetLabels()).To(HaveKeyWithValue(gatewayLabelKey, gatewayStatusActive))
+			})
 		})
 
 		Context("pod does not exist", func() {
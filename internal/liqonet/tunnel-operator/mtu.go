@@ -0,0 +1,145 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunneloperator
+
+import (
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
+	tunnelwg "github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
+)
+
+// wireguardOverhead is the encapsulation overhead of the WireGuard driver, the configured MTU refers to.
+const wireguardOverhead = mtu.DefaultPathMTU - tunnelwg.MTU
+
+// discoveredPathMTU holds the path MTU discovered towards the endpoint of a remote cluster.
+type discoveredPathMTU struct {
+	endpoint string
+	// value is zero while the discovery is in progress.
+	value int
+}
+
+// tunnelMTU returns the effective MTU of the tunnel towards the given remote cluster. By default, it is the configured
+// MTU, lowered by the additional encapsulation overhead of the driver with respect to the WireGuard one. If the path MTU
+// discovery is enabled, it is the path MTU towards the remote endpoint (as seen by the peer tep) minus the overhead of
+// the driver. The discovery is performed in background, and the configured MTU is returned until it completes.
+func (tc *TunnelController) tunnelMTU(tep, peer *netv1alpha1.TunnelEndpoint) int {
+	overhead := wireguardOverhead
+	if reporter, ok := tc.drivers[peer.Spec.BackendType].(tunnel.OverheadReporter); ok {
+		overhead = reporter.Overhead()
+	}
+	configured := mtu.Encapsulated(tc.MTU+wireguardOverhead, overhead)
+	if !tc.PathMTUDiscovery {
+		return configured
+	}
+
+	clusterID := peer.Spec.ClusterID
+	tc.pathMTUsMutex.Lock()
+	defer tc.pathMTUsMutex.Unlock()
+	if discovered, ok := tc.pathMTUs[clusterID]; ok && discovered.endpoint == peer.Spec.EndpointIP {
+		if discovered.value == 0 {
+			return configured
+		}
+		return mtu.Encapsulated(discovered.value, overhead)
+	}
+
+	tc.pathMTUs[clusterID] = discoveredPathMTU{endpoint: peer.Spec.EndpointIP}
+	ref := &netv1alpha1.TunnelEndpoint{ObjectMeta: metav1.ObjectMeta{Name: tep.Name, Namespace: tep.Namespace}}
+	go tc.discoverPathMTU(ref, clusterID, peer.Spec.EndpointIP)
+	return configured
+}
+
+// discoverPathMTU discovers the path MTU towards the given endpoint, and triggers the reconciliation of the given tep
+// to apply it. The outcome is discarded if the endpoint of the remote cluster changed in the meanwhile.
+func (tc *TunnelController) discoverPathMTU(tep *netv1alpha1.TunnelEndpoint, clusterID, endpoint string) {
+	pathMTU := tc.MTU + wireguardOverhead
+	var discover = func(netNamespace ns.NetNS) error {
+		value, err := mtu.Discover(endpoint, mtu.DefaultWait)
+		if err != nil {
+			return err
+		}
+		pathMTU = value
+		return nil
+	}
+	// The packets of the tunnel leave from the host network namespace, hence the discovery is performed there.
+	if err := tc.hostNetns.Do(discover); err != nil {
+		klog.Warningf("%s -> unable to discover the path MTU towards %s, falling back to %d: %v", clusterID, endpoint, pathMTU, err)
+	} else {
+		klog.Infof("%s -> discovered path MTU towards %s: %d", clusterID, endpoint, pathMTU)
+	}
+
+	tc.pathMTUsMutex.Lock()
+	discovered, ok := tc.pathMTUs[clusterID]
+	if !ok || discovered.endpoint != endpoint {
+		tc.pathMTUsMutex.Unlock()
+		return
+	}
+	tc.pathMTUs[clusterID] = discoveredPathMTU{endpoint: endpoint, value: pathMTU}
+	tc.pathMTUsMutex.Unlock()
+
+	tc.events <- event.GenericEvent{Object: tep}
+}
+
+// forgetPathMTU discards the path MTU discovered towards the given remote cluster.
+func (tc *TunnelController) forgetPathMTU(clusterID string) {
+	tc.pathMTUsMutex.Lock()
+	defer tc.pathMTUsMutex.Unlock()
+	delete(tc.pathMTUs, clusterID)
+}
+
+// ensureGatewayLinksMTU raises the MTU of the tunnel device and of the gateway veth, if lower than the given one.
+// The MTU is never lowered, since the devices are shared among all the remote clusters: the MTU of the single
+// tunnels is enforced through the one of the corresponding routes. It must be executed in the gateway network namespace.
+func (tc *TunnelController) ensureGatewayLinksMTU(tep *netv1alpha1.TunnelEndpoint) error {
+	driver, ok := tc.drivers[tep.Spec.BackendType]
+	if !ok {
+		return fmt.Errorf("no tunnel driver found for backend type %s", tep.Spec.BackendType)
+	}
+	if err := raiseLinkMTU(driver.GetLink().Attrs().Name, tep.Status.MTU); err != nil {
+		return err
+	}
+	return raiseLinkMTU(liqoconst.GatewayVethName, tep.Status.MTU)
+}
+
+// ensureHostLinksMTU raises the MTU of the host veth, if lower than the given one.
+// It must be executed in the host network namespace.
+func (tc *TunnelController) ensureHostLinksMTU(tep *netv1alpha1.TunnelEndpoint) error {
+	return raiseLinkMTU(liqoconst.HostVethName, tep.Status.MTU)
+}
+
+// raiseLinkMTU sets the MTU of the given device to the given value, if currently lower.
+func raiseLinkMTU(name string, value int) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve device %s: %w", name, err)
+	}
+	if value <= link.Attrs().MTU {
+		return nil
+	}
+	if err := netlink.LinkSetMTU(link, value); err != nil {
+		return fmt.Errorf("unable to set the MTU of device %s to %d: %w", name, value, err)
+	}
+	klog.Infof("MTU of device {%s} raised to %d", name, value)
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunneloperator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+	tunnelwg "github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
+)
+
+const (
+	// These annotations are the ones read by the network manager to advertise the gateway behind a NAT.
+	// Any change to those annotations has also to be reflected there.
+	publicEndpointAnnotationKey = "net.liqo.io/gatewayPublicEndpoint"
+	relayAnnotationKey          = "net.liqo.io/gatewayRelay"
+
+	// natDiscoveryInterval is the interval between two subsequent discoveries of the public mapping of the tunnel port.
+	natDiscoveryInterval = time.Minute
+	// relayAllocationValidity is the time after which the relay endpoints are allocated again,
+	// to recover from the restarts of the relay.
+	relayAllocationValidity = time.Minute
+)
+
+// relayAllocation holds the relay endpoint allocated for the tunnel towards a remote cluster.
+type relayAllocation struct {
+	relay     string
+	session   string
+	endpoint  *net.UDPAddr
+	timestamp time.Time
+}
+
+// natTraversalMode returns the way the tunnel towards a remote cluster traverses the NATs, given the configuration of
+// the local gateway and the one advertised by the remote cluster, together with the address of the relay, if any.
+// The traffic is relayed if at least one of the gateways requires it: in case both do, the relay with the lower address
+// is selected, so that both gateways agree on it. Otherwise, the tunnel is established directly with the public mapping
+// of the gateways behind a NAT, relying on the keepalives to punch the holes.
+func natTraversalMode(localDiscovery bool, localRelay, remoteMode, remoteRelay string) (mode nattraversal.Mode, relay string) {
+	if nattraversal.Mode(remoteMode) != nattraversal.ModeRelay {
+		remoteRelay = ""
+	}
+	switch {
+	case localRelay != "" && remoteRelay != "":
+		relay = localRelay
+		if remoteRelay < localRelay {
+			relay = remoteRelay
+		}
+		return nattraversal.ModeRelay, relay
+	case localRelay != "":
+		return nattraversal.ModeRelay, localRelay
+	case remoteRelay != "":
+		return nattraversal.ModeRelay, remoteRelay
+	case localDiscovery || remoteMode != "":
+		return nattraversal.ModeDirect, ""
+	default:
+		return nattraversal.ModeNone, ""
+	}
+}
+
+// natTraversalEndpoint returns the tunnelendpoint the tunnel towards the remote cluster has to be configured with,
+// accounting for the traversal of the NATs: the mode is set in the backend configuration and, if the traffic is
+// relayed, the endpoint is replaced with the one allocated on the relay. The given tunnelendpoint is not modified.
+func (tc *TunnelController) natTraversalEndpoint(tep *netv1alpha1.TunnelEndpoint) (*netv1alpha1.TunnelEndpoint, error) {
+	if tep.Spec.BackendType != tunnelwg.DriverName {
+		return tep, nil
+	}
+	var localRelay string
+	if tc.NATRelay {
+		localRelay = tc.NATReflector
+	}
+	mode, relay := natTraversalMode(tc.NATReflector != "", localRelay,
+		tep.Spec.BackendConfig[tunnelwg.NATTraversal], tep.Spec.BackendConfig[tunnelwg.RelayAddress])
+	if mode == nattraversal.ModeNone {
+		return tep, nil
+	}
+
+	peer := tep.DeepCopy()
+	if peer.Spec.BackendConfig == nil {
+		peer.Spec.BackendConfig = map[string]string{}
+	}
+	peer.Spec.BackendConfig[tunnelwg.NATTraversal] = string(mode)
+	if mode != nattraversal.ModeRelay {
+		return peer, nil
+	}
+
+	endpoint, err := tc.relayEndpoint(tep, relay)
+	if err != nil {
+		return nil, err
+	}
+	peer.Spec.EndpointIP = endpoint.IP.String()
+	peer.Spec.BackendConfig[tunnelwg.ListeningPort] = strconv.Itoa(endpoint.Port)
+	return peer, nil
+}
+
+// relayEndpoint returns the endpoint allocated on the given relay for the tunnel towards the remote cluster.
+// The session is identified by the public keys of the two gateways, which are known by both sides.
+func (tc *TunnelController) relayEndpoint(tep *netv1alpha1.TunnelEndpoint, relay string) (*net.UDPAddr, error) {
+	clusterID := tep.Spec.ClusterID
+	wg, ok := tc.drivers[tunnelwg.DriverName].(*tunnelwg.Wireguard)
+	if !ok {
+		return nil, fmt.Errorf("the %s driver is not available", tunnelwg.DriverName)
+	}
+	session, side := nattraversal.Session(wg.LocalPublicKey(), tep.Spec.BackendConfig[tunnelwg.PublicKey])
+	if allocation, found := tc.relayAllocations[clusterID]; found && allocation.relay == relay &&
+		allocation.session == session && time.Since(allocation.timestamp) < relayAllocationValidity {
+		return allocation.endpoint, nil
+	}
+
+	var endpoint *net.UDPAddr
+	var allocate = func(netNamespace ns.NetNS) error {
+		var err error
+		endpoint, err = nattraversal.Allocate(relay, session, side, tc.NATRelaySecret, nattraversal.DefaultTimeout)
+		return err
+	}
+	// The tunnel traffic leaves from the host network namespace, hence the relay is contacted from there.
+	if err := tc.hostNetns.Do(allocate); err != nil {
+		klog.Errorf("%s -> unable to allocate the relay endpoint on %s: %v", clusterID, relay, err)
+		return nil, err
+	}
+	if previous, found := tc.relayAllocations[clusterID]; !found || previous.endpoint.String() != endpoint.String() {
+		klog.Infof("%s -> tunnel traffic relayed through %s", clusterID, endpoint)
+	}
+	tc.relayAllocations[clusterID] = relayAllocation{relay: relay, session: session, endpoint: endpoint, timestamp: time.Now()}
+	return endpoint, nil
+}
+
+// discoverPublicEndpoint periodically discovers the public mapping of the port of the wireguard tunnel,
+// and annotates the gateway service to let the network manager advertise it, until the context is canceled.
+func (tc *TunnelController) discoverPublicEndpoint(ctx context.Context) error {
+	wg, ok := tc.drivers[tunnelwg.DriverName].(*tunnelwg.Wireguard)
+	if !ok {
+		return fmt.Errorf("the %s driver is not available", tunnelwg.DriverName)
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		var mapping *net.UDPAddr
+		var discover = func(netNamespace ns.NetNS) error {
+			var err error
+			mapping, err = nattraversal.Discover(wg.ListeningPort(), tc.NATReflector, nattraversal.DefaultTimeout)
+			return err
+		}
+		if err := tc.hostNetns.Do(discover); err != nil {
+			klog.Warningf("unable to discover the public mapping of the tunnel port through %s: %v", tc.NATReflector, err)
+			return
+		}
+		klog.V(4).Infof("public mapping of the tunnel port discovered: %s", mapping)
+		if err := tc.annotateGatewayService(ctx, mapping.String()); err != nil {
+			klog.Errorf("unable to annotate the gateway service with the public mapping of the tunnel port: %v", err)
+		}
+	}, natDiscoveryInterval)
+	return nil
+}
+
+// annotateGatewayService sets the annotations concerning the NAT traversal on the gateway service.
+func (tc *TunnelController) annotateGatewayService(ctx context.Context, publicEndpoint string) error {
+	var services corev1.ServiceList
+	if err := tc.List(ctx, &services, client.InNamespace(tc.namespace), client.MatchingLabels{
+		podComponentLabelKey: podComponentLabelValue,
+		podNameLabelKey:      podNameLabelValue,
+	}); err != nil {
+		return err
+	}
+	if len(services.Items) != 1 {
+		return fmt.Errorf("expected number of services for the gateway is {1}, instead we found {%d}", len(services.Items))
+	}
+
+	svc := &services.Items[0]
+	original := svc.DeepCopy()
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	svc.Annotations[publicEndpointAnnotationKey] = publicEndpoint
+	if tc.NATRelay {
+		svc.Annotations[relayAnnotationKey] = tc.NATReflector
+	} else {
+		delete(svc.Annotations, relayAnnotationKey)
+	}
+	if original.Annotations[publicEndpointAnnotationKey] == publicEndpoint &&
+		original.Annotations[relayAnnotationKey] == svc.Annotations[relayAnnotationKey] {
+		return nil
+	}
+	if err := tc.Update(ctx, svc); err != nil {
+		return err
+	}
+	klog.Infof("gateway service {%s/%s} annotated with the public endpoint %s", svc.Namespace, svc.Name, publicEndpoint)
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunneloperator
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/liqotech/liqo/pkg/liqonet/nattraversal"
+)
+
+var _ = Describe("NAT traversal", func() {
+	type natTraversalModeCase struct {
+		localDiscovery          bool
+		localRelay              string
+		remoteMode, remoteRelay string
+		expectedMode            nattraversal.Mode
+		expectedRelay           string
+	}
+
+	DescribeTable("the natTraversalMode function",
+		func(c natTraversalModeCase) {
+			mode, relay := natTraversalMode(c.localDiscovery, c.localRelay, c.remoteMode, c.remoteRelay)
+			Expect(mode).To(Equal(c.expectedMode))
+			Expect(relay).To(Equal(c.expectedRelay))
+		},
+		Entry("neither gateway is behind a NAT", natTraversalModeCase{
+			expectedMode: nattraversal.ModeNone,
+		}),
+		Entry("the local gateway is behind a NAT", natTraversalModeCase{
+			localDiscovery: true, expectedMode: nattraversal.ModeDirect,
+		}),
+		Entry("the remote gateway is behind a NAT", natTraversalModeCase{
+			remoteMode: "direct", expectedMode: nattraversal.ModeDirect,
+		}),
+		Entry("the local gateway requires the relay", natTraversalModeCase{
+			localDiscovery: true, localRelay: "relay-a:3478", remoteMode: "direct",
+			expectedMode: nattraversal.ModeRelay, expectedRelay: "relay-a:3478",
+		}),
+		Entry("the remote gateway requires the relay", natTraversalModeCase{
+			remoteMode: "relay", remoteRelay: "relay-b:3478",
+			expectedMode: nattraversal.ModeRelay, expectedRelay: "relay-b:3478",
+		}),
+		Entry("both gateways require a relay", natTraversalModeCase{
+			localDiscovery: true, localRelay: "relay-b:3478", remoteMode: "relay", remoteRelay: "relay-a:3478",
+			expectedMode: nattraversal.ModeRelay, expectedRelay: "relay-a:3478",
+		}),
+		Entry("the remote relay is advertised without the relay mode", natTraversalModeCase{
+			remoteMode: "direct", remoteRelay: "relay-b:3478", expectedMode: nattraversal.ModeDirect,
+		}),
+	)
+})
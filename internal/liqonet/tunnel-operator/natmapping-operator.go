@@ -31,7 +31,7 @@ import (
 // NatMappingController reconciles a NatMapping object.
 type NatMappingController struct {
 	client.Client
-	iptables.IPTHandler
+	iptables.Handler
 	readyClustersMutex *sync.Mutex
 	readyClusters      map[string]struct{}
 	gatewayNetns       ns.NetNS
@@ -60,7 +60,7 @@ func (npc *NatMappingController) Reconcile(ctx context.Context, req ctrl.Request
 		if _, ready := npc.readyClusters[nm.Spec.ClusterID]; !ready {
 			return fmt.Errorf("tunnel for cluster {%s} is not ready", nm.Spec.ClusterID)
 		}
-		if err := npc.IPTHandler.EnsurePreroutingRulesPerNatMapping(&nm); err != nil {
+		if err := npc.Handler.EnsurePreroutingRulesPerNatMapping(&nm); err != nil {
 			klog.Errorf("unable to ensure prerouting rules for cluster {%s}: %s",
 				nm.Spec.ClusterID, err.Error())
 			return err
@@ -82,13 +82,13 @@ func (npc *NatMappingController) SetupWithManager(mgr ctrl.Manager) error {
 // NewNatMappingController returns a NAT mapping controller istance.
 func NewNatMappingController(cl client.Client, readyClustersMutex *sync.Mutex,
 	readyClusters map[string]struct{}, gatewayNetns ns.NetNS) (*NatMappingController, error) {
-	iptablesHandler, err := iptables.NewIPTHandler()
+	iptablesHandler, err := iptables.NewHandler()
 	if err != nil {
 		return nil, err
 	}
 	return &NatMappingController{
 		Client:             cl,
-		IPTHandler:         iptablesHandler,
+		Handler:            iptablesHandler,
 		readyClustersMutex: readyClustersMutex,
 		readyClusters:      readyClusters,
 		gatewayNetns:       gatewayNetns,
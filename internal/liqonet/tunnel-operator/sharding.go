@@ -0,0 +1,103 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunneloperator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/sharding"
+)
+
+// eligibilityChangedPredicate filters the events concerning the replicas of the gateway,
+// selecting the ones which change the set of replicas eligible to handle the tunnels.
+var eligibilityChangedPredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool { return sharding.IsEligible(e.Object.(*corev1.Pod)) },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return sharding.IsEligible(e.ObjectOld.(*corev1.Pod)) != sharding.IsEligible(e.ObjectNew.(*corev1.Pod))
+	},
+	DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+	GenericFunc: func(e event.GenericEvent) bool { return false },
+}
+
+// ownsTunnel returns whether the tunnel towards the given cluster is handled by the current replica.
+// In active/passive mode, the leader handles all the tunnels; in active/active mode, they are sharded
+// among the eligible replicas of the gateway.
+func (tc *TunnelController) ownsTunnel(ctx context.Context, clusterID string) (bool, error) {
+	if !tc.activeActive {
+		return true, nil
+	}
+	replicas, err := tc.gatewayReplicas(ctx)
+	if err != nil {
+		return false, err
+	}
+	owner := sharding.Owner(clusterID, replicas)
+	if owner == nil {
+		klog.Warningf("%s -> no gateway replica is currently eligible to handle the tunnel", clusterID)
+		return false, nil
+	}
+	return owner.Status.PodIP == tc.podIP, nil
+}
+
+// gatewayReplicas returns the replicas of the gateway which can be assigned tunnels.
+func (tc *TunnelController) gatewayReplicas(ctx context.Context) ([]corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := tc.List(ctx, &pods, client.InNamespace(tc.namespace), client.MatchingLabels{
+		podComponentLabelKey: podComponentLabelValue,
+		podNameLabelKey:      podNameLabelValue,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list the gateway replicas: %w", err)
+	}
+	return sharding.EligibleReplicas(pods.Items), nil
+}
+
+// isTunnelConfigured returns whether the tunnel towards the given cluster has been configured by the current replica.
+func (tc *TunnelController) isTunnelConfigured(clusterID string) bool {
+	tc.readyClustersMutex.Lock()
+	defer tc.readyClustersMutex.Unlock()
+	_, configured := tc.readyClusters[clusterID]
+	return configured
+}
+
+// forgetTunnel marks the tunnel towards the given cluster as no longer configured by the current replica.
+func (tc *TunnelController) forgetTunnel(clusterID string) {
+	tc.readyClustersMutex.Lock()
+	defer tc.readyClustersMutex.Unlock()
+	delete(tc.readyClusters, clusterID)
+}
+
+// enqueueTunnelEndpoints returns a reconcile request for each tunnelendpoint, to rebalance
+// the tunnels among the replicas of the gateway when the set of eligible ones changes.
+func (tc *TunnelController) enqueueTunnelEndpoints(_ client.Object) []reconcile.Request {
+	var teps netv1alpha1.TunnelEndpointList
+	if err := tc.List(context.TODO(), &teps); err != nil {
+		klog.Errorf("unable to list the tunnelendpoints to rebalance the tunnels: %v", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, len(teps.Items))
+	for i := range teps.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: teps.Items[i].Name, Namespace: teps.Items[i].Namespace}}
+	}
+	return requests
+}
@@ -28,16 +28,23 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8sApiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
@@ -45,8 +52,12 @@ import (
 	liqonetns "github.com/liqotech/liqo/pkg/liqonet/netns"
 	liqorouting "github.com/liqotech/liqo/pkg/liqonet/routing"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
+	// Register the ipsec tunnel driver.
+	_ "github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel/probe"
 	tunnelwg "github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
+	"github.com/liqotech/liqo/pkg/utils/slice"
 )
 
 var (
@@ -62,12 +73,14 @@ type TunnelController struct {
 	client.Client
 	record.EventRecorder
 	tunnel.Driver
-	liqorouting.Routing
-	iptables.IPTHandler
+	iptables.Handler
 	k8sClient          k8s.Interface
+	podName            string
 	drivers            map[string]tunnel.Driver
+	routingManagers    map[string]liqorouting.Routing
 	namespace          string
 	podIP              string
+	podIPv6            string
 	finalizer          string
 	hostNetns          ns.NetNS
 	gatewayNetns       ns.NetNS
@@ -75,6 +88,32 @@ type TunnelController struct {
 	gatewayVeth        netlink.Link
 	readyClustersMutex *sync.Mutex
 	readyClusters      map[string]struct{}
+	health             tunnelHealth
+	// activeActive is true if the tunnels are sharded among all the replicas of the gateway,
+	// instead of being handled by the leader only.
+	activeActive bool
+	// events triggers the reconciliation of the tunnelendpoints when changes not concerning the resources occur.
+	events chan event.GenericEvent
+	// hostRemoteCIDRs contains, for each remote cluster, the networks whose policy routing rules have been configured
+	// in the host network namespace. It allows to remove the rules of the transit networks no longer advertised.
+	hostRemoteCIDRs map[string][]string
+	// MTU is the MTU of the tunnels established through the WireGuard driver, lowered by the additional
+	// overhead of the other drivers. It is used as is unless the path MTU discovery is enabled.
+	MTU int
+	// PathMTUDiscovery is true if the MTU of the tunnels is derived from the path MTU discovered towards each remote endpoint.
+	PathMTUDiscovery bool
+	// pathMTUs contains, for each remote cluster, the path MTU discovered towards the corresponding endpoint.
+	pathMTUs      map[string]discoveredPathMTU
+	pathMTUsMutex sync.Mutex
+	// NATReflector is the address of the reflector used to discover the public mapping of the tunnel port,
+	// if the gateway is located behind a NAT. The NAT traversal is disabled if empty.
+	NATReflector string
+	// NATRelay is true if the tunnel traffic is relayed through the reflector.
+	NATRelay bool
+	// NATRelaySecret is the secret authenticating the allocation requests sent to the relays.
+	NATRelaySecret []byte
+	// relayAllocations contains, for each remote cluster, the endpoint allocated on the relay.
+	relayAllocations map[string]relayAllocation
 }
 
 // cluster-role
@@ -86,50 +125,58 @@ type TunnelController struct {
 // +kubebuilder:rbac:groups=core,namespace="do-not-care",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 // NewTunnelController instantiates and initializes the tunnel controller.
-func NewTunnelController(podIP, namespace string, er record.EventRecorder, k8sClient k8s.Interface, cl client.Client,
-	readyClustersMutex *sync.Mutex, readyClusters map[string]struct{}, gatewayNetns, hostNetns ns.NetNS) (*TunnelController, error) {
+func NewTunnelController(podName, podIP, podIPv6, namespace string, er record.EventRecorder, k8sClient k8s.Interface, cl client.Client,
+	readyClustersMutex *sync.Mutex, readyClusters map[string]struct{}, gatewayNetns, hostNetns ns.NetNS,
+	probeOpts probe.Options, activeActive bool) (*TunnelController, error) {
 	tunnelEndpointFinalizer := strings.Join([]string{liqoconst.LiqoGatewayOperatorName, liqoconst.FinalizersSuffix}, ".")
 	tc := &TunnelController{
 		Client:             cl,
 		EventRecorder:      er,
 		k8sClient:          k8sClient,
+		podName:            podName,
 		podIP:              podIP,
+		podIPv6:            podIPv6,
 		namespace:          namespace,
 		finalizer:          tunnelEndpointFinalizer,
 		readyClustersMutex: readyClustersMutex,
 		readyClusters:      readyClusters,
 		gatewayNetns:       gatewayNetns,
 		hostNetns:          hostNetns,
+		events:             make(chan event.GenericEvent),
+		activeActive:       activeActive,
+		hostRemoteCIDRs:    make(map[string][]string),
+		MTU:                tunnelwg.MTU,
+		pathMTUs:           make(map[string]discoveredPathMTU),
+		relayAllocations:   make(map[string]relayAllocation),
 	}
 
 	err := tc.SetUpTunnelDrivers()
 	if err != nil {
 		return nil, err
 	}
-	link, err := netlink.LinkByName(tunnelwg.DeviceName)
-	if err != nil {
-		return nil, err
-	}
 	err = tc.setUpGWNetns(liqoconst.GatewayNetnsName, liqoconst.HostVethName,
 		liqoconst.GatewayVethName, liqoconst.GatewayVethIPAddr, tunnelwg.MTU)
 	if err != nil {
 		return nil, err
 	}
-	// Move wireguard interface in the gateway network namespace.
-	if err = netlink.LinkSetNsFd(link, int(tc.gatewayNetns.Fd())); err != nil {
-		return nil, fmt.Errorf("failed to move wireguard interfacte to gateway netns: %w", err)
+	// Move the tunnel interfaces in the gateway network namespace.
+	for driverType, driver := range tc.drivers {
+		if err = netlink.LinkSetNsFd(driver.GetLink(), int(tc.gatewayNetns.Fd())); err != nil {
+			return nil, fmt.Errorf("failed to move %s interface to gateway netns: %w", driverType, err)
+		}
 	}
-	// After the wireguard device has been moved to the new netns we need to:
-	// 1) set it up;
+	// After the tunnel devices have been moved to the new netns we need to:
+	// 1) set them up;
 	// 2) replace the wgctl.Client with a new client spawned in the new netns.
-	var configureWg = func(netnsNamespace ns.NetNS) error {
-		link, err = netlink.LinkByName(tunnelwg.DeviceName)
-		if err != nil {
-			return err
-		}
-		err = netlink.LinkSetUp(link)
-		if err != nil {
-			return fmt.Errorf("failed to set wireguard iface up in gateway netns: %w", err)
+	var configureTunnels = func(netnsNamespace ns.NetNS) error {
+		for driverType, driver := range tc.drivers {
+			link, err := netlink.LinkByName(driver.GetLink().Attrs().Name)
+			if err != nil {
+				return err
+			}
+			if err = netlink.LinkSetUp(link); err != nil {
+				return fmt.Errorf("failed to set %s iface up in gateway netns: %w", driverType, err)
+			}
 		}
 		w := tc.drivers[tunnelwg.DriverName]
 		wg := w.(*tunnelwg.Wireguard)
@@ -138,7 +185,7 @@ func NewTunnelController(podIP, namespace string, er record.EventRecorder, k8sCl
 		}
 		return nil
 	}
-	if err := tc.gatewayNetns.Do(configureWg); err != nil {
+	if err := tc.gatewayNetns.Do(configureTunnels); err != nil {
 		return nil, err
 	}
 	err = tc.SetUpIPTablesHandler()
@@ -148,6 +195,9 @@ func NewTunnelController(podIP, namespace string, er record.EventRecorder, k8sCl
 	if err := tc.SetUpRouteManager(); err != nil {
 		return nil, err
 	}
+	if err := tc.SetUpProber(probeOpts); err != nil {
+		return nil, err
+	}
 	if err := tc.gatewayNetns.Set(); err != nil {
 		return nil, err
 	}
@@ -158,17 +208,23 @@ func NewTunnelController(podIP, namespace string, er record.EventRecorder, k8sCl
 func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var tep = new(netv1alpha1.TunnelEndpoint)
 	var err error
-	var clusterID, remotePodCIDR, remoteExternalCIDR string
+	var clusterID, remotePodCIDR string
 	var con *netv1alpha1.Connection
+	// peer is the tunnelendpoint the tunnel is configured with, accounting for the traversal of the NATs.
+	var peer *netv1alpha1.TunnelEndpoint
 
 	var configGWNetns = func(netNamespace ns.NetNS) error {
-		con, err = tc.connectToPeer(tep)
+		con, err = tc.connectToPeer(peer)
 		if err != nil {
 			return err
 		}
 		if err = tc.EnsureIPTablesRulesPerCluster(tep); err != nil {
 			return err
 		}
+		if err = tc.ensureGatewayLinksMTU(tep); err != nil {
+			klog.Errorf("%s -> unable to configure the MTU of the tunnel: %v", clusterID, err)
+			return err
+		}
 		// Set cluster tunnel as ready
 		tc.readyClustersMutex.Lock()
 		defer tc.readyClustersMutex.Unlock()
@@ -183,10 +239,16 @@ func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			tc.Event(tep, "Normal", "Processing", "route configured")
 			klog.Infof("%s -> route for destination {%s} correctly configured", clusterID, remotePodCIDR)
 		}
+		if err := tc.EnsureProbePerCluster(tep); err != nil {
+			klog.Errorf("%s -> unable to configure the probing of the remote gateway: %v", clusterID, err)
+			tc.Eventf(tep, "Warning", "Processing", "unable to configure the probing of the remote gateway: %v", err)
+			return err
+		}
 		return nil
 	}
 	var unconfigGWNetns = func(netNamespace ns.NetNS) error {
-		if err := tc.IPTHandler.RemoveIPTablesConfigurationPerCluster(tep); err != nil {
+		tc.RemoveProbePerCluster(tep.Spec.ClusterID)
+		if err := tc.Handler.RemoveIPTablesConfigurationPerCluster(tep); err != nil {
 			klog.Errorf("%s -> unable to remove iptables configuration: %s",
 				tep.Spec.ClusterID, err.Error())
 			return err
@@ -204,44 +266,61 @@ func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			tc.Event(tep, "Normal", "Processing", "route correctly removed")
 			klog.Infof("%s -> route for destination '%s' correctly removed", clusterID, remotePodCIDR)
 		}
+		tc.forgetPathMTU(tep.Spec.ClusterID)
+		delete(tc.relayAllocations, tep.Spec.ClusterID)
 		return nil
 	}
 	var configHNetns = func(netNamespace ns.NetNS) error {
-		added, err := liqorouting.AddPolicyRoutingRule(remotePodCIDR, anyNetwork, liqoconst.RoutingTableID)
-		if err != nil {
-			klog.Errorf("%s -> unable to configure policy routing rule for subnet {%s}: %s", clusterID, remotePodCIDR, err)
-			return err
-		}
-		if added {
-			klog.Infof("%s -> policy routing rule for subnet {%s} correctly configured", clusterID, remotePodCIDR)
+		remoteCIDRs := utils.GetRemoteCIDRs(tep)
+		// Remove the rules of the networks no longer reached through the tunnel, i.e. the transit ones no longer advertised.
+		for _, configuredCIDR := range tc.hostRemoteCIDRs[clusterID] {
+			if slice.ContainsString(remoteCIDRs, configuredCIDR) {
+				continue
+			}
+			deleted, err := liqorouting.DelPolicyRoutingRule(configuredCIDR, anyNetwork, liqoconst.RoutingTableID)
+			if err != nil {
+				klog.Errorf("%s -> unable to remove policy routing rule for subnet {%s}: %s", clusterID, configuredCIDR, err)
+				return err
+			}
+			if deleted {
+				klog.Infof("%s -> policy routing rule for subnet {%s} correctly removed", clusterID, configuredCIDR)
+			}
 		}
-		added, err = liqorouting.AddPolicyRoutingRule(remoteExternalCIDR, anyNetwork, liqoconst.RoutingTableID)
-		if err != nil {
-			klog.Errorf("%s -> unable to configure policy routing rule for subnet {%s}: %s", clusterID, remoteExternalCIDR, err)
+		tc.hostRemoteCIDRs[clusterID] = remoteCIDRs
+		if err := tc.ensureHostLinksMTU(tep); err != nil {
+			klog.Errorf("%s -> unable to configure the MTU of the host veth: %v", clusterID, err)
 			return err
 		}
-		if added {
-			klog.Infof("%s -> policy routing rule for subnet {%s} correctly configured", clusterID, remoteExternalCIDR)
+		for _, remoteCIDR := range remoteCIDRs {
+			added, err := liqorouting.AddPolicyRoutingRule(remoteCIDR, anyNetwork, liqoconst.RoutingTableID)
+			if err != nil {
+				klog.Errorf("%s -> unable to configure policy routing rule for subnet {%s}: %s", clusterID, remoteCIDR, err)
+				return err
+			}
+			if added {
+				klog.Infof("%s -> policy routing rule for subnet {%s} correctly configured", clusterID, remoteCIDR)
+			}
 		}
 		return nil
 	}
 	var unconfigHNetns = func(netNamespace ns.NetNS) error {
-		deleted, err := liqorouting.DelPolicyRoutingRule(remotePodCIDR, anyNetwork, liqoconst.RoutingTableID)
-		if err != nil {
-			klog.Errorf("%s -> unable to remove policy routing rule for subnet {%s}: %s", clusterID, remotePodCIDR, err)
-			return err
-		}
-		if deleted {
-			klog.Infof("%s -> policy routing rule for subnet {%s} correctly removed", clusterID, remotePodCIDR)
-		}
-		deleted, err = liqorouting.DelPolicyRoutingRule(remoteExternalCIDR, anyNetwork, liqoconst.RoutingTableID)
-		if err != nil {
-			klog.Errorf("%s -> unable to remove policy routing rule for subnet {%s}: %s", clusterID, remoteExternalCIDR, err)
-			return err
+		remoteCIDRs := utils.GetRemoteCIDRs(tep)
+		for _, configuredCIDR := range tc.hostRemoteCIDRs[clusterID] {
+			if !slice.ContainsString(remoteCIDRs, configuredCIDR) {
+				remoteCIDRs = append(remoteCIDRs, configuredCIDR)
+			}
 		}
-		if deleted {
-			klog.Infof("%s -> policy routing rule for subnet {%s} correctly removed", clusterID, remoteExternalCIDR)
+		for _, remoteCIDR := range remoteCIDRs {
+			deleted, err := liqorouting.DelPolicyRoutingRule(remoteCIDR, anyNetwork, liqoconst.RoutingTableID)
+			if err != nil {
+				klog.Errorf("%s -> unable to remove policy routing rule for subnet {%s}: %s", clusterID, remoteCIDR, err)
+				return err
+			}
+			if deleted {
+				klog.Infof("%s -> policy routing rule for subnet {%s} correctly removed", clusterID, remoteCIDR)
+			}
 		}
+		delete(tc.hostRemoteCIDRs, clusterID)
 		return nil
 	}
 	// Name of our finalizer.
@@ -256,7 +335,26 @@ func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	clusterID = tep.Spec.ClusterID
 
 	_, remotePodCIDR = utils.GetPodCIDRS(tep)
-	_, remoteExternalCIDR = utils.GetExternalCIDRS(tep)
+	owned, err := tc.ownsTunnel(ctx, clusterID)
+	if err != nil {
+		klog.Errorf("%s -> unable to determine the gateway replica handling the tunnel: %v", clusterID, err)
+		return result, err
+	}
+	if !owned {
+		// The tunnel is handled by a different replica, hence make sure it is not configured locally.
+		if !tc.isTunnelConfigured(clusterID) {
+			return result, nil
+		}
+		klog.Infof("%s -> the tunnel has been assigned to a different gateway replica, releasing it", clusterID)
+		if err = tc.gatewayNetns.Do(unconfigGWNetns); err != nil {
+			return result, err
+		}
+		if err = tc.hostNetns.Do(unconfigHNetns); err != nil {
+			return result, err
+		}
+		tc.forgetTunnel(clusterID)
+		return result, nil
+	}
 	// Examine DeletionTimestamp to determine if object is under deletion.
 	if tep.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(tep, tc.finalizer) {
@@ -282,6 +380,7 @@ func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			if err = tc.hostNetns.Do(unconfigHNetns); err != nil {
 				return result, err
 			}
+			tc.forgetTunnel(clusterID)
 			// Remove the finalizer from the list and update it.
 			controllerutil.RemoveFinalizer(tep, tc.finalizer)
 			if err := tc.Update(ctx, tep); err != nil {
@@ -296,16 +395,31 @@ func (tc *TunnelController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		// If object is being deleted and does not have a finalizer we just return.
 		return result, nil
 	}
+	if peer, err = tc.natTraversalEndpoint(tep); err != nil {
+		tc.Eventf(tep, "Warning", "Processing", "unable to configure the NAT traversal: %v", err)
+		return result, err
+	}
+	// The MTU is set in advance, as it is enforced on the routes towards the remote cluster.
+	previousMTU := tep.Status.MTU
+	tep.Status.MTU = tc.tunnelMTU(tep, peer)
 	if err := tc.gatewayNetns.Do(configGWNetns); err != nil {
 		return result, err
 	}
-	if reflect.DeepEqual(*con, tep.Status.Connection) && tep.Status.GatewayIP == tc.podIP &&
-		tep.Status.VethIFaceIndex == tc.hostVeth.Attrs().Index {
+	keyRotation := tc.keyRotationStatus(tep)
+	statistics := tc.reportedStatistics(clusterID)
+	if reflect.DeepEqual(*con, tep.Status.Connection) && tep.Status.GatewayIP == tc.podIP && tep.Status.GatewayIPv6 == tc.podIPv6 &&
+		tep.Status.GatewayPod == tc.podName &&
+		tep.Status.VethIFaceIndex == tc.hostVeth.Attrs().Index && equality.Semantic.DeepEqual(keyRotation, tep.Status.KeyRotation) &&
+		equality.Semantic.DeepEqual(statistics, tep.Status.Statistics) && previousMTU == tep.Status.MTU {
 		return result, nil
 	}
 	tep.Status.Connection = *con
 	tep.Status.GatewayIP = tc.podIP
+	tep.Status.GatewayIPv6 = tc.podIPv6
+	tep.Status.GatewayPod = tc.podName
 	tep.Status.VethIFaceIndex = tc.hostVeth.Attrs().Index
+	tep.Status.KeyRotation = keyRotation
+	tep.Status.Statistics = statistics
 	if err = tc.Status().Update(context.Background(), tep); err != nil {
 		if k8sApiErrors.IsConflict(err) {
 			klog.V(4).Infof("%s -> unable to add finalizers to resource %s: %s", clusterID, req.String(), err)
@@ -334,14 +448,30 @@ func (tc *TunnelController) connectToPeer(ep *netv1alpha1.TunnelEndpoint) (*netv
 		klog.Errorf("%s -> an error occurred while establishing vpn connection: %v", clusterID, err)
 		return nil, err
 	}
+	con = tc.checkTunnelHealth(clusterID, con)
 	if reflect.DeepEqual(*con, ep.Status.Connection) {
 		return con, nil
 	}
+	if con.Status == netv1alpha1.ConnectionError {
+		tc.Event(ep, "Warning", "Processing", con.StatusMessage)
+		klog.Warningf("%s -> %s", clusterID, con.StatusMessage)
+		return con, nil
+	}
 	tc.Event(ep, "Normal", "Processing", "connection established")
 	klog.Infof("%s -> vpn connection correctly established", clusterID)
 	return con, nil
 }
 
+// keyRotationStatus returns the status of the key rotation of the driver used for the given tep,
+// or nil if the driver does not support key rotation.
+func (tc *TunnelController) keyRotationStatus(ep *netv1alpha1.TunnelEndpoint) *netv1alpha1.KeyRotationStatus {
+	rotator, ok := tc.drivers[ep.Spec.BackendType].(tunnel.KeyRotator)
+	if !ok {
+		return nil
+	}
+	return rotator.KeyRotationStatus()
+}
+
 func (tc *TunnelController) disconnectFromPeer(ep *netv1alpha1.TunnelEndpoint) error {
 	clusterID := ep.Spec.ClusterID
 	// retrieve driver based on backend type
@@ -428,24 +558,111 @@ func (tc *TunnelController) SetupWithManager(mgr ctrl.Manager) error {
 			return false
 		},
 	}
-	return ctrl.NewControllerManagedBy(mgr).
+	// Discover the public mapping of the tunnel port, to let the remote clusters reach the gateway behind a NAT.
+	if tc.NATReflector != "" {
+		if err := mgr.Add(manager.RunnableFunc(tc.discoverPublicEndpoint)); err != nil {
+			return err
+		}
+	}
+	// Trigger the reconciliation of the tunnelendpoints when the keys of the corresponding driver are rotated,
+	// to keep their status up to date.
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return tc.notifyKeyRotations(ctx, tc.events)
+	})); err != nil {
+		return err
+	}
+	// Probe the remote gateways, triggering the reconciliation of the tunnelendpoints when new statistics are available.
+	if tc.health.prober != nil {
+		if err := mgr.Add(tc.health.prober); err != nil {
+			return err
+		}
+		if err := metrics.Registry.Register(&tunnelMetricsCollector{tc: tc}); err != nil {
+			return err
+		}
+	}
+	controller := ctrl.NewControllerManagedBy(mgr).
 		For(&netv1alpha1.TunnelEndpoint{}).WithEventFilter(resourceToBeProccesedPredicate).
-		Complete(tc)
+		Watches(&source.Channel{Source: tc.events}, &handler.EnqueueRequestForObject{})
+	if tc.activeActive {
+		// Rebalance the tunnels when the set of replicas of the gateway eligible to handle them changes.
+		controller = controller.Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(tc.enqueueTunnelEndpoints),
+			builder.WithPredicates(eligibilityChangedPredicate))
+	}
+	return controller.Complete(tc)
+}
+
+// notifyKeyRotations generates an event for each tunnelendpoint whose driver rotated its keys, until the context is canceled.
+func (tc *TunnelController) notifyKeyRotations(ctx context.Context, events chan<- event.GenericEvent) error {
+	// Merge the notifications of the drivers supporting key rotation, tagging them with the driver type.
+	rotations := make(chan string)
+	for driverType, driver := range tc.drivers {
+		rotator, ok := driver.(tunnel.KeyRotator)
+		if !ok {
+			continue
+		}
+		go func(driverType string, rotated <-chan struct{}) {
+			for {
+				select {
+				case <-rotated:
+					select {
+					case rotations <- driverType:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(driverType, rotator.KeyRotated())
+	}
+
+	for {
+		var driverType string
+		select {
+		case driverType = <-rotations:
+		case <-ctx.Done():
+			return nil
+		}
+		var teps netv1alpha1.TunnelEndpointList
+		if err := tc.List(ctx, &teps); err != nil {
+			klog.Errorf("unable to list the tunnelendpoints after the rotation of the %s keys: %v", driverType, err)
+			continue
+		}
+		for i := range teps.Items {
+			if teps.Items[i].Spec.BackendType != driverType {
+				continue
+			}
+			select {
+			case events <- event.GenericEvent{Object: &teps.Items[i]}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
 }
 
 // SetUpTunnelDrivers for each registered tunnel implementation it creates and initializes the driver.
+// The failure of a driver different from the default one is not fatal: the driver is skipped, and the
+// peerings requiring it can not be established until the problem is fixed.
 func (tc *TunnelController) SetUpTunnelDrivers() error {
 	tc.drivers = make(map[string]tunnel.Driver)
 	for tunnelType, createDriverFunc := range tunnel.Drivers {
 		klog.V(3).Infof("Creating driver for tunnel of type %s", tunnelType)
 		d, err := createDriverFunc(tc.k8sClient, tc.namespace)
-		if err != nil {
-			return err
+		if err == nil {
+			klog.V(3).Infof("Initializing driver for %s tunnel", tunnelType)
+			if err = d.Init(); err != nil {
+				if e := d.Close(); e != nil {
+					klog.Errorf("unable to delete tunnel network interface of type %s: %s", tunnelType, e)
+				}
+			}
 		}
-		klog.V(3).Infof("Initializing driver for %s tunnel", tunnelType)
-		err = d.Init()
 		if err != nil {
-			return err
+			if tunnelType == tunnelwg.DriverName {
+				return err
+			}
+			klog.Warningf("skipping driver for %s tunnel, as an error occurred during its setup: %v", tunnelType, err)
+			continue
 		}
 		klog.V(3).Infof("Driver for %s tunnel created and initialized", tunnelType)
 		tc.drivers[tunnelType] = d
@@ -453,9 +670,19 @@ func (tc *TunnelController) SetUpTunnelDrivers() error {
 	return nil
 }
 
-// SetUpIPTablesHandler initializes the IPTables handler of TunnelController.
+// TunnelDevices returns the names of the interfaces of the initialized tunnel drivers, indexed by backend type.
+func (tc *TunnelController) TunnelDevices() map[string]string {
+	devices := make(map[string]string, len(tc.drivers))
+	for driverType, driver := range tc.drivers {
+		devices[driverType] = driver.GetLink().Attrs().Name
+	}
+	return devices
+}
+
+// SetUpIPTablesHandler initializes the handler of TunnelController configuring the NAT rules,
+// according to the configured netfilter backend.
 func (tc *TunnelController) SetUpIPTablesHandler() error {
-	iptHandler, err := iptables.NewIPTHandler()
+	iptHandler, err := iptables.NewHandler()
 	if err != nil {
 		return err
 	}
@@ -469,22 +696,43 @@ func (tc *TunnelController) SetUpIPTablesHandler() error {
 	if err := tc.gatewayNetns.Do(init); err != nil {
 		return err
 	}
-	tc.IPTHandler = iptHandler
+	tc.Handler = iptHandler
 	return nil
 }
 
-// SetUpRouteManager initializes the Route manager of TunnelController.
+// SetUpRouteManager initializes the Route managers of TunnelController, one for each tunnel driver.
 func (tc *TunnelController) SetUpRouteManager() error {
-	// Todo make the gateway routing manager to support more than one vpn technology at the same time.
-	// Todo it should use the right tunnel based on the backend type set inside the tep.
-	grm, err := liqorouting.NewGatewayRoutingManager(unix.RT_TABLE_MAIN, tc.drivers[tunnelwg.DriverName].GetLink())
-	if err != nil {
-		return err
+	tc.routingManagers = make(map[string]liqorouting.Routing)
+	for driverType, driver := range tc.drivers {
+		grm, err := liqorouting.NewGatewayRoutingManager(unix.RT_TABLE_MAIN, driver.GetLink())
+		if err != nil {
+			return err
+		}
+		tc.routingManagers[driverType] = liqorouting.NewTransitRoutingManager(grm)
 	}
-	tc.Routing = grm
 	return nil
 }
 
+// EnsureRoutesPerCluster configures the routes for the given remote cluster through the tunnel
+// of the backend type set inside the tep.
+func (tc *TunnelController) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	grm, ok := tc.routingManagers[tep.Spec.BackendType]
+	if !ok {
+		return false, fmt.Errorf("no routing manager found for backend type %s", tep.Spec.BackendType)
+	}
+	return grm.EnsureRoutesPerCluster(tep)
+}
+
+// RemoveRoutesPerCluster removes the routes for the given remote cluster configured
+// through the tunnel of the backend type set inside the tep.
+func (tc *TunnelController) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	grm, ok := tc.routingManagers[tep.Spec.BackendType]
+	if !ok {
+		return false, fmt.Errorf("no routing manager found for backend type %s", tep.Spec.BackendType)
+	}
+	return grm.RemoveRoutesPerCluster(tep)
+}
+
 func (tc *TunnelController) setUpGWNetns(netnsName, hostVethName, gatewayVethName, gatewayVethIPAddr string, vethMtu int) error {
 	var err error
 	// Create veth pair to connect the two namespaces.
@@ -514,6 +762,9 @@ func (tc *TunnelController) setUpGWNetns(netnsName, hostVethName, gatewayVethNam
 			return err
 		}
 		klog.Infof("added route for destination {%s} on device {%s}", gatewayVethIPAddr, hostVethName)
+		if tc.podIPv6 != "" {
+			return addLinkLocalAddress(veth, liqoconst.HostVethIPv6Addr)
+		}
 		return nil
 	}
 	if err = tc.hostNetns.Do(configureHostNetns); err != nil {
@@ -542,7 +793,7 @@ func (tc *TunnelController) CleanUpConfiguration(netnsName, hostVethName string)
 	}
 	var deletePRRFromHostNS = func(netNamespace ns.NetNS) error {
 		// First we list all the policy routing rules.
-		rules, err := netlink.RuleList(netlink.FAMILY_V4)
+		rules, err := netlink.RuleList(netlink.FAMILY_ALL)
 		if err != nil {
 			return err
 		}
@@ -586,7 +837,7 @@ func (tc *TunnelController) CleanUpConfiguration(netnsName, hostVethName string)
 }
 
 func (tc *TunnelController) configureGatewayNetns(ifaceName, ipAddress string, gatewayNs ns.NetNS) error {
-	var defaultCIDR = "0.0.0.0/0"
+	var defaultCIDR, defaultCIDRv6 = "0.0.0.0/0", "::/0"
 	configuration := func(netNamespace ns.NetNS) error {
 		// Get veth interface.
 		veth, err := netlink.LinkByName(ifaceName)
@@ -622,7 +873,42 @@ func (tc *TunnelController) configureGatewayNetns(ifaceName, ipAddress string, g
 			return err
 		}
 		klog.Infof("enabled ipv4 forwarding in namespace {%s}", liqoconst.GatewayNetnsName)
+		if tc.podIPv6 == "" {
+			return nil
+		}
+		// In dual-stack clusters the IPv6 traffic leaving the namespace is routed through the link local
+		// address of the host veth, since proxy arp has no IPv6 counterpart.
+		if err := addLinkLocalAddress(veth, liqoconst.GatewayVethIPv6Addr); err != nil {
+			return err
+		}
+		hostVethIPv6, _, err := net.ParseCIDR(liqoconst.HostVethIPv6Addr)
+		if err != nil {
+			return err
+		}
+		if _, err := liqorouting.AddRoute(defaultCIDRv6, hostVethIPv6.String(), veth.Attrs().Index, 0); err != nil {
+			return err
+		}
+		klog.Infof("added route for destination {%s} on device {%s} in network namespace {%s}",
+			defaultCIDRv6, ifaceName, liqoconst.GatewayNetnsName)
+		if err := liqorouting.EnableIPv6Forwarding(); err != nil {
+			return err
+		}
+		klog.Infof("enabled ipv6 forwarding in namespace {%s}", liqoconst.GatewayNetnsName)
 		return nil
 	}
 	return gatewayNs.Do(configuration)
 }
+
+// addLinkLocalAddress configures the given link local IPv6 address on the link.
+func addLinkLocalAddress(link netlink.Link, address string) error {
+	ip, ipNet, err := net.ParseCIDR(address)
+	if err != nil {
+		return err
+	}
+	ipNet.IP = ip
+	if err := netlink.AddrReplace(link, &netlink.Addr{IPNet: ipNet}); err != nil {
+		return fmt.Errorf("unable to configure address %s on device %s: %w", address, link.Attrs().Name, err)
+	}
+	klog.Infof("configured IP address {%s} on device {%s}", address, link.Attrs().Name)
+	return nil
+}
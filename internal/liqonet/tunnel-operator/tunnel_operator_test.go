@@ -22,7 +22,12 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/vishvananda/netlink"
 
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
+	tunnelipsec "github.com/liqotech/liqo/pkg/liqonet/tunnel/ipsec"
+	tunnelwg "github.com/liqotech/liqo/pkg/liqonet/tunnel/wireguard"
 )
 
 var _ = Describe("TunnelOperator", func() {
@@ -79,4 +84,43 @@ var _ = Describe("TunnelOperator", func() {
 			})
 		})
 	})
+	Describe("tunnel MTU", func() {
+		var (
+			controller *TunnelController
+			tep        *netv1alpha1.TunnelEndpoint
+		)
+
+		BeforeEach(func() {
+			controller = &TunnelController{MTU: tunnelwg.MTU, pathMTUs: map[string]discoveredPathMTU{}}
+			tep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterID: "cluster-id", EndpointIP: "192.168.1.1", BackendType: tunnelwg.DriverName}}
+		})
+
+		Context("the path MTU discovery is disabled", func() {
+			It("should return the configured value", func() {
+				controller.MTU = 1300
+				Expect(controller.tunnelMTU(tep, tep)).To(Equal(1300))
+			})
+
+			It("should lower the configured value by the additional overhead of the driver", func() {
+				controller.drivers = map[string]tunnel.Driver{tunnelipsec.DriverName: &tunnelipsec.IPsec{}}
+				tep.Spec.BackendType = tunnelipsec.DriverName
+				Expect(controller.tunnelMTU(tep, tep)).To(Equal(tunnelipsec.MTU))
+			})
+		})
+
+		Context("the path MTU discovery is enabled", func() {
+			BeforeEach(func() { controller.PathMTUDiscovery = true })
+
+			It("should return the configured value while the discovery is in progress", func() {
+				controller.pathMTUs["cluster-id"] = discoveredPathMTU{endpoint: "192.168.1.1"}
+				Expect(controller.tunnelMTU(tep, tep)).To(Equal(tunnelwg.MTU))
+			})
+
+			It("should subtract the encapsulation overhead from the discovered value", func() {
+				controller.pathMTUs["cluster-id"] = discoveredPathMTU{endpoint: "192.168.1.1", value: 9000}
+				Expect(controller.tunnelMTU(tep, tep)).To(Equal(9000 - (mtu.DefaultPathMTU - tunnelwg.MTU)))
+			})
+		})
+	})
 })
@@ -51,6 +51,8 @@ const (
 	LiqoGatewayOperatorName = "liqo-gateway"
 	// LiqoNetworkManagerName name of the operator.
 	LiqoNetworkManagerName = "liqo-network-manager"
+	// LiqoNATReflectorName holds the name of the NAT reflector.
+	LiqoNATReflectorName = "liqo-nat-reflector"
 	// GatewayLeaderElectionID used as name for the lease.coordination.k8s.io resource.
 	GatewayLeaderElectionID = "1d5hml1.gateway.net.liqo.io"
 	// GatewayNetnsName name of the custom network namespace used by liqo-gateway.
@@ -65,6 +67,12 @@ const (
 	// IP address. No traffic leaving the custom network namespace has as source IP this
 	// address.
 	GatewayVethIPAddr = "169.254.100.1/32"
+	// GatewayVethIPv6Addr link local IPv6 address configured on gateway veth device, used as next hop
+	// for the IPv6 traffic towards the remote clusters.
+	GatewayVethIPv6Addr = "fe80::1/64"
+	// HostVethIPv6Addr link local IPv6 address configured on the host veth device, used as next hop
+	// for the IPv6 traffic leaving the custom network namespace.
+	HostVethIPv6Addr = "fe80::2/64"
 	// VxlanDeviceName name used for the vxlan devices created on each node by the instances
 	// of liqo-route.
 	VxlanDeviceName = "liqo.vxlan"
@@ -72,6 +80,11 @@ const (
 	OverlayNetworkPrefix = "240"
 	// OverlayNetworkMask size of the overlay network.
 	OverlayNetworkMask = "/8"
+	// OverlayNetworkPrefixV6 prefix used for the IPv6 overlay network. The last four bytes of the
+	// IPv6 addresses are the ones of the corresponding IPv4 overlay addresses.
+	OverlayNetworkPrefixV6 = "fd24::"
+	// OverlayNetworkMaskV6 size of the IPv6 overlay network.
+	OverlayNetworkMaskV6 = "/96"
 	// PodCIDR is a field of the TunnelEndpoint resource.
 	PodCIDR = "PodCIDR"
 	// ExternalCIDR is a field of the TunnelEndpoint resource.
@@ -88,6 +101,16 @@ const (
 	RemoteNATPodCIDR = "RemoteNATPodCIDR"
 	// RemoteNATExternalCIDR is a field of the TunnelEndpoint resource.
 	RemoteNATExternalCIDR = "RemoteNATExternalCIDR"
+	// PodCIDRv6 is a field of the TunnelEndpoint resource.
+	PodCIDRv6 = "PodCIDRv6"
+	// ExternalCIDRv6 is a field of the TunnelEndpoint resource.
+	ExternalCIDRv6 = "ExternalCIDRv6"
 	// FinalizersSuffix suffix used by the network operators to create the finalizers added to k8s resources.
 	FinalizersSuffix = "net.liqo.io"
+	// TunnelBackendAnnotationKey is the annotation set on the ForeignCluster resources to select the tunnel
+	// backend to be used towards the given remote cluster. When not set, the wireguard backend is used.
+	TunnelBackendAnnotationKey = "net.liqo.io/tunnel-backend"
+	// TransitMaxHops is the maximum number of clusters traversed to reach the networks of a cluster through
+	// transit routing, the destination one included. Longer paths are neither advertised nor accepted.
+	TransitMaxHops = 3
 )
@@ -0,0 +1,121 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+
+	liqoipam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+type fakeIpamClient struct {
+	liqoipam.IpamClient
+	requestedClusterIDs []string
+	dualStack           bool
+}
+
+func (c *fakeIpamClient) ListClusterSubnets(ctx context.Context, in *liqoipam.ListClusterSubnetsRequest,
+	opts ...grpc.CallOption) (*liqoipam.ListClusterSubnetsResponse, error) {
+	c.requestedClusterIDs = append(c.requestedClusterIDs, in.GetClusterID())
+	subnets := &liqoipam.ClusterSubnets{
+		ClusterID:          "cluster-1",
+		RemotePodCIDR:      "10.50.0.0/16",
+		RemoteExternalCIDR: "10.60.0.0/16",
+		LocalNATPodCIDR:    "10.0.1.0/24",
+	}
+	if c.dualStack {
+		subnets.RemotePodCIDRv6 = "fd00:50::/64"
+		subnets.RemoteExternalCIDRv6 = "fd00:60::/64"
+	}
+	return &liqoipam.ListClusterSubnetsResponse{ClusterSubnets: []*liqoipam.ClusterSubnets{subnets}}, nil
+}
+
+func (c *fakeIpamClient) ListPools(ctx context.Context, in *liqoipam.ListPoolsRequest,
+	opts ...grpc.CallOption) (*liqoipam.ListPoolsResponse, error) {
+	response := &liqoipam.ListPoolsResponse{
+		PodCIDR: "10.0.0.0/24",
+		Pools: []*liqoipam.Pool{
+			{Network: "10.0.0.0/8", AllocatedNetworks: []string{"10.0.0.0/24", "10.50.0.0/16"}},
+			{Network: "192.168.0.0/16"},
+		},
+	}
+	if c.dualStack {
+		response.ServiceCIDRv6 = "fd00:10::/112"
+	}
+	return response, nil
+}
+
+func (c *fakeIpamClient) ListEndpointMappings(ctx context.Context, in *liqoipam.ListEndpointMappingsRequest,
+	opts ...grpc.CallOption) (*liqoipam.ListEndpointMappingsResponse, error) {
+	c.requestedClusterIDs = append(c.requestedClusterIDs, in.GetClusterID())
+	return &liqoipam.ListEndpointMappingsResponse{EndpointMappings: []*liqoipam.EndpointMapping{
+		{Ip: "10.0.50.6", ExternalCIDRIP: "10.200.0.1", ClusterIDs: []string{"cluster-1", "cluster-2"}},
+	}}, nil
+}
+
+var _ = Describe("Collect", func() {
+	var (
+		client *fakeIpamClient
+		out    bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		client = &fakeIpamClient{}
+		out.Reset()
+	})
+
+	Context("Without a cluster ID", func() {
+		It("should render all the sections", func() {
+			Expect(collect(context.Background(), client, "", &out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("Cluster subnets"))
+			Expect(out.String()).To(ContainSubstring("Network pools"))
+			Expect(out.String()).To(ContainSubstring("Endpoint mappings"))
+			Expect(out.String()).To(MatchRegexp(`cluster-1\s+10.50.0.0/16\s+10.60.0.0/16\s+10.0.1.0/24\s+None`))
+			Expect(out.String()).To(MatchRegexp(`10.0.0.0/8\s+10.0.0.0/24,10.50.0.0/16`))
+			Expect(out.String()).To(MatchRegexp(`192.168.0.0/16\s+None`))
+			Expect(out.String()).To(MatchRegexp(`10.0.50.6\s+10.200.0.1\s+cluster-1,cluster-2`))
+		})
+	})
+
+	Context("With dual-stack clusters", func() {
+		It("should render the IPv6 networks", func() {
+			client.dualStack = true
+			Expect(collect(context.Background(), client, "", &out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("REMOTE POD CIDR (IPv6)"))
+			Expect(out.String()).To(MatchRegexp(`cluster-1\s+10.50.0.0/16\s+10.60.0.0/16\s+10.0.1.0/24\s+None\s+fd00:50::/64\s+fd00:60::/64\s+None\s+None`))
+			Expect(out.String()).To(MatchRegexp(`Pod CIDR \(IPv6\):\s+None`))
+			Expect(out.String()).To(MatchRegexp(`Service CIDR \(IPv6\):\s+fd00:10::/112`))
+		})
+
+		It("should omit the IPv6 columns otherwise", func() {
+			Expect(collect(context.Background(), client, "", &out)).To(Succeed())
+			Expect(out.String()).NotTo(ContainSubstring("IPv6"))
+		})
+	})
+
+	Context("With a cluster ID", func() {
+		It("should filter by cluster and omit the pools", func() {
+			Expect(collect(context.Background(), client, "cluster-1", &out)).To(Succeed())
+			Expect(client.requestedClusterIDs).To(ConsistOf("cluster-1", "cluster-1"))
+			Expect(out.String()).NotTo(ContainSubstring("Network pools"))
+			Expect(out.String()).To(ContainSubstring("Endpoint mappings"))
+		})
+	})
+})
@@ -0,0 +1,41 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+const (
+	// ShortHelp contains the short help string for liqoctl ipam command.
+	ShortHelp = "Show the network allocations of the Liqo IPAM"
+	// LongHelp contains the Long help string for liqoctl ipam command.
+	LongHelp = `
+Show the network allocations of the Liqo IPAM.
+
+The command queries the IPAM of the network manager and shows the networks
+assigned to the remote clusters, the network pools along with the networks
+allocated from them, and the endpoint IPs mapped in the local ExternalCIDR.
+The output can be restricted to a single remote cluster.
+
+$ liqoctl ipam --namespace ns-where-Liqo-is-running --cluster-id remote-cluster-id
+`
+	// UseCommand contains the name of the command.
+	UseCommand = "ipam"
+
+	// Namespace contains the name of namespace flag.
+	Namespace = "namespace"
+	// ClusterID contains the name of the cluster-id flag.
+	ClusterID = "cluster-id"
+
+	networkManagerDeployment = "liqo-network-manager"
+	noneValue                = "None"
+)
@@ -0,0 +1,150 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqoctl/common"
+	liqoipam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+// Args flags of the ipam command.
+type Args struct {
+	Namespace string
+	ClusterID string
+}
+
+// Handler implements the logic of the ipam command.
+func (a *Args) Handler(ctx context.Context) error {
+	restConfig := common.GetLiqoctlRestConfOrDie()
+
+	clientSet, err := k8s.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	pod, err := getNetworkManagerPod(ctx, clientSet, a.Namespace)
+	if err != nil {
+		return err
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	localPort, err := forwardIpamPort(restConfig, clientSet, pod, stopChan)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("localhost:%d", localPort), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return fmt.Errorf("unable to connect to the IPAM server: %w", err)
+	}
+	defer conn.Close()
+
+	return collect(ctx, liqoipam.NewIpamClient(conn), a.ClusterID, os.Stdout)
+}
+
+// collect retrieves the IPAM allocations through the given client and renders them on out.
+func collect(ctx context.Context, client liqoipam.IpamClient, clusterID string, out io.Writer) error {
+	subnets, err := client.ListClusterSubnets(ctx, &liqoipam.ListClusterSubnetsRequest{ClusterID: clusterID})
+	if err != nil {
+		return fmt.Errorf("unable to list the cluster subnets: %w", err)
+	}
+	pools, err := client.ListPools(ctx, &liqoipam.ListPoolsRequest{})
+	if err != nil {
+		return fmt.Errorf("unable to list the network pools: %w", err)
+	}
+	mappings, err := client.ListEndpointMappings(ctx, &liqoipam.ListEndpointMappingsRequest{ClusterID: clusterID})
+	if err != nil {
+		return fmt.Errorf("unable to list the endpoint mappings: %w", err)
+	}
+
+	renderClusterSubnets(out, subnets)
+	// The pools are shared among all the remote clusters, hence they are shown only in the overall view.
+	if clusterID == "" {
+		renderPools(out, pools)
+	}
+	renderEndpointMappings(out, mappings)
+	return nil
+}
+
+// getNetworkManagerPod returns a running pod of the network manager deployment.
+func getNetworkManagerPod(ctx context.Context, clientSet k8s.Interface, namespace string) (*corev1.Pod, error) {
+	deployment, err := clientSet.AppsV1().Deployments(namespace).Get(ctx, networkManagerDeployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the %s deployment: %w", networkManagerDeployment, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of the %s deployment: %w", networkManagerDeployment, err)
+	}
+	pods, err := clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the %s pods: %w", networkManagerDeployment, err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod found for the %s deployment in namespace %s", networkManagerDeployment, namespace)
+}
+
+// forwardIpamPort forwards a local port to the IPAM gRPC port of the given pod, and returns the local port.
+// The forwarding is stopped when stopChan is closed.
+func forwardIpamPort(restConfig *rest.Config, clientSet k8s.Interface, pod *corev1.Pod, stopChan chan struct{}) (uint16, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(restConfig)
+	if err != nil {
+		return 0, err
+	}
+	url := clientSet.CoreV1().RESTClient().Post().Resource("pods").
+		Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	readyChan := make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", consts.NetworkManagerIpamPort)},
+		stopChan, readyChan, io.Discard, os.Stderr)
+	if err != nil {
+		return 0, fmt.Errorf("unable to forward the IPAM port: %w", err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- forwarder.ForwardPorts() }()
+	select {
+	case <-readyChan:
+	case err := <-errChan:
+		return 0, fmt.Errorf("unable to forward the IPAM port: %w", err)
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		return 0, fmt.Errorf("unable to retrieve the forwarded port: %w", err)
+	}
+	return ports[0].Local, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	liqoipam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+)
+
+func newTabWriter(sectionName string) (*tabwriter.Writer, *bytes.Buffer) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 4, ' ', 0)
+
+	separator := strings.Repeat("-", len(sectionName))
+	fmt.Fprintf(w, "%s\n", sectionName)
+	fmt.Fprintf(w, "%s\n", separator)
+	return w, &buf
+}
+
+// flush writes the content of the tabwriter on out, followed by an empty line.
+func flush(out io.Writer, w *tabwriter.Writer, buf *bytes.Buffer) {
+	w.Flush()
+	fmt.Fprintf(out, "%s\n", buf.String())
+}
+
+// orNone returns the given value, or "None" if it is empty.
+func orNone(value string) string {
+	if value == "" {
+		return noneValue
+	}
+	return value
+}
+
+// hasIPv6Subnets returns whether at least one of the given clusters has been assigned IPv6 subnets.
+func hasIPv6Subnets(clusterSubnets []*liqoipam.ClusterSubnets) bool {
+	for _, subnets := range clusterSubnets {
+		if subnets.GetRemotePodCIDRv6() != "" || subnets.GetRemoteExternalCIDRv6() != "" ||
+			subnets.GetLocalNATPodCIDRv6() != "" || subnets.GetLocalNATExternalCIDRv6() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func renderClusterSubnets(out io.Writer, response *liqoipam.ListClusterSubnetsResponse) {
+	// the IPv6 columns are rendered only in case of dual-stack (or IPv6) clusters, to keep the output compact otherwise.
+	ipv6 := hasIPv6Subnets(response.GetClusterSubnets())
+
+	w, buf := newTabWriter("Cluster subnets")
+	header := "CLUSTER ID\tREMOTE POD CIDR\tREMOTE EXTERNAL CIDR\tLOCAL NAT POD CIDR\tLOCAL NAT EXTERNAL CIDR"
+	if ipv6 {
+		header += "\tREMOTE POD CIDR (IPv6)\tREMOTE EXTERNAL CIDR (IPv6)\tLOCAL NAT POD CIDR (IPv6)\tLOCAL NAT EXTERNAL CIDR (IPv6)"
+	}
+	fmt.Fprintln(w, header)
+	for _, subnets := range response.GetClusterSubnets() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", subnets.GetClusterID(),
+			orNone(subnets.GetRemotePodCIDR()), orNone(subnets.GetRemoteExternalCIDR()),
+			orNone(subnets.GetLocalNATPodCIDR()), orNone(subnets.GetLocalNATExternalCIDR()))
+		if ipv6 {
+			fmt.Fprintf(w, "\t%s\t%s\t%s\t%s",
+				orNone(subnets.GetRemotePodCIDRv6()), orNone(subnets.GetRemoteExternalCIDRv6()),
+				orNone(subnets.GetLocalNATPodCIDRv6()), orNone(subnets.GetLocalNATExternalCIDRv6()))
+		}
+		fmt.Fprintln(w)
+	}
+	flush(out, w, buf)
+}
+
+func renderPools(out io.Writer, response *liqoipam.ListPoolsResponse) {
+	w, buf := newTabWriter("Local networks")
+	fmt.Fprintf(w, "Pod CIDR:\t%s\n", orNone(response.GetPodCIDR()))
+	fmt.Fprintf(w, "Service CIDR:\t%s\n", orNone(response.GetServiceCIDR()))
+	fmt.Fprintf(w, "External CIDR:\t%s\n", orNone(response.GetExternalCIDR()))
+	if response.GetPodCIDRv6() != "" || response.GetServiceCIDRv6() != "" || response.GetExternalCIDRv6() != "" {
+		fmt.Fprintf(w, "Pod CIDR (IPv6):\t%s\n", orNone(response.GetPodCIDRv6()))
+		fmt.Fprintf(w, "Service CIDR (IPv6):\t%s\n", orNone(response.GetServiceCIDRv6()))
+		fmt.Fprintf(w, "External CIDR (IPv6):\t%s\n", orNone(response.GetExternalCIDRv6()))
+	}
+	flush(out, w, buf)
+
+	w, buf = newTabWriter("Network pools")
+	fmt.Fprintln(w, "POOL\tALLOCATED NETWORKS")
+	for _, pool := range response.GetPools() {
+		fmt.Fprintf(w, "%s\t%s\n", pool.GetNetwork(), orNone(strings.Join(pool.GetAllocatedNetworks(), ",")))
+	}
+	flush(out, w, buf)
+}
+
+func renderEndpointMappings(out io.Writer, response *liqoipam.ListEndpointMappingsResponse) {
+	w, buf := newTabWriter("Endpoint mappings")
+	fmt.Fprintln(w, "ENDPOINT IP\tEXTERNAL CIDR IP\tCLUSTER IDS")
+	for _, mapping := range response.GetEndpointMappings() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", mapping.GetIp(), mapping.GetExternalCIDRIP(), strings.Join(mapping.GetClusterIDs(), ","))
+	}
+	flush(out, w, buf)
+}
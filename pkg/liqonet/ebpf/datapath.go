@@ -0,0 +1,330 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+const (
+	// maxClusters is the maximum number of remote clusters handled by the datapath.
+	maxClusters = 1024
+	// maxMappings is the maximum number of mappings handled by the datapath, for each direction.
+	maxMappings = 65536
+	// filterPriority is the priority of the tc filters attaching the programs.
+	filterPriority = 1
+)
+
+// Datapath is the eBPF datapath translating the addresses of the ExternalCIDR according to the NatMapping resources.
+// The programs attached to the ingress of the tunnel interfaces replace the addresses of the ExternalCIDR with the
+// original ones, while the programs attached to the egress perform the reverse translation on the replies.
+// Differently from the DNAT rules, the translation is stateless: the egress programs rewrite the source address of
+// every packet sent by a mapped endpoint to the remote cluster, including the ones of the connections it initiates,
+// which hence appear as originated from the corresponding address of the ExternalCIDR.
+type Datapath struct {
+	mutex sync.Mutex
+	// clusters maps the PodCIDR of the remote clusters, as seen by the local one, to the index of the clusters.
+	clusters *ebpf.Map
+	// dnat maps the pairs (cluster index, address of the ExternalCIDR) to the original addresses.
+	dnat *ebpf.Map
+	// snat maps the pairs (cluster index, original address) to the addresses of the ExternalCIDR.
+	snat *ebpf.Map
+	// configured holds the configuration of each remote cluster currently loaded in the maps.
+	configured map[string]*clusterConfig
+	// attached maps the names of the interfaces the programs are attached to, to the corresponding indexes.
+	attached map[string]int
+}
+
+// clusterConfig is the configuration of a remote cluster loaded in the maps.
+type clusterConfig struct {
+	index   uint32
+	podCIDR *net.IPNet
+	// mappings maps the original IPv4 addresses to the ones of the ExternalCIDR.
+	mappings map[string]string
+}
+
+// NewDatapath creates the BPF maps and attaches the programs to the given interfaces, which
+// are looked up in the current network namespace.
+func NewDatapath(linkNames ...string) (*Datapath, error) {
+	d := &Datapath{configured: make(map[string]*clusterConfig), attached: make(map[string]int)}
+	specs := mapSpecs()
+	for name, m := range map[string]**ebpf.Map{clustersMapName: &d.clusters, dnatMapName: &d.dnat, snatMapName: &d.snat} {
+		var err error
+		if *m, err = ebpf.NewMap(specs[name]); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("unable to create BPF map %s: %w", name, err)
+		}
+	}
+	for _, name := range linkNames {
+		if err := d.EnsureAttached(name); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// EnsureAttached makes sure that the programs are attached to the given interface, which is looked up in the current
+// network namespace. They are attached again if the interface has been recreated in the meanwhile (i.e. its index changed).
+func (d *Datapath) EnsureAttached(linkName string) error {
+	link, err := netlink.LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("unable to get interface %s: %w", linkName, err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if index, found := d.attached[linkName]; found && index == link.Attrs().Index {
+		return nil
+	}
+	if err := d.attach(link); err != nil {
+		return fmt.Errorf("unable to attach the eBPF programs to interface %s: %w", linkName, err)
+	}
+	d.attached[linkName] = link.Attrs().Index
+	klog.Infof("attached the eBPF NAT programs to interface %s", linkName)
+	return nil
+}
+
+// attach loads the programs and attaches them to the ingress and egress of the given link.
+func (d *Datapath) attach(link netlink.Link) error {
+	var l2Len int32
+	if link.Attrs().EncapType == "ether" {
+		l2Len = ethHeaderLen
+	}
+	qdisc := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+	if err := netlink.QdiscReplace(qdisc); err != nil {
+		return fmt.Errorf("unable to configure the clsact qdisc: %w", err)
+	}
+	for parent, dir := range map[uint32]direction{netlink.HANDLE_MIN_INGRESS: ingress, netlink.HANDLE_MIN_EGRESS: egress} {
+		program, err := d.loadProgram(dir, l2Len)
+		if err != nil {
+			return err
+		}
+		filter := &netlink.BpfFilter{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    parent,
+				Handle:    netlink.MakeHandle(0, 1),
+				Protocol:  unix.ETH_P_ALL,
+				Priority:  filterPriority,
+			},
+			Fd:           program.FD(),
+			Name:         dir.name,
+			DirectAction: true,
+		}
+		// The filter holds a reference to the program, hence the file descriptor can be closed.
+		err = netlink.FilterReplace(filter)
+		_ = program.Close()
+		if err != nil {
+			return fmt.Errorf("unable to attach program %s: %w", dir.name, err)
+		}
+	}
+	return nil
+}
+
+// loadProgram loads the program translating the addresses in the given direction, referencing the maps of the datapath.
+func (d *Datapath) loadProgram(dir direction, l2Len int32) (*ebpf.Program, error) {
+	spec := programSpec(dir, l2Len)
+	maps := map[string]*ebpf.Map{clustersMapName: d.clusters, dnatMapName: d.dnat, snatMapName: d.snat}
+	for _, name := range []string{clustersMapName, dir.natMap} {
+		if err := spec.Instructions.AssociateMap(name, maps[name]); err != nil {
+			return nil, fmt.Errorf("unable to associate map %s to program %s: %w", name, dir.name, err)
+		}
+	}
+	program, err := ebpf.NewProgram(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load BPF program %s: %w", dir.name, err)
+	}
+	return program, nil
+}
+
+// EnsureNatMapping makes sure that the IPv4 mappings of the given NatMapping are loaded in the maps,
+// removing the outdated ones. The IPv6 mappings are ignored.
+func (d *Datapath) EnsureNatMapping(nm *netv1alpha1.NatMapping) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	clusterID := nm.Spec.ClusterID
+	_, podCIDR, err := net.ParseCIDR(nm.Spec.PodCIDR)
+	if err != nil || podCIDR.IP.To4() == nil {
+		return fmt.Errorf("invalid PodCIDR %q for cluster %s", nm.Spec.PodCIDR, clusterID)
+	}
+	config, ok := d.configured[clusterID]
+	if !ok {
+		index, err := d.freeIndex()
+		if err != nil {
+			return err
+		}
+		config = &clusterConfig{index: index, mappings: make(map[string]string)}
+		d.configured[clusterID] = config
+	}
+	desired := make(map[string]string)
+	for oldIP, newIP := range nm.Spec.ClusterMappings {
+		if net.ParseIP(oldIP).To4() != nil && net.ParseIP(newIP).To4() != nil {
+			desired[oldIP] = newIP
+		}
+	}
+	// The outdated mappings are removed first, since their addresses may be reused by the new ones.
+	for oldIP, newIP := range config.mappings {
+		if desired[oldIP] == newIP {
+			continue
+		}
+		if err := d.removeMapping(config, oldIP, newIP); err != nil {
+			return fmt.Errorf("unable to remove mapping %s -> %s for cluster %s: %w", oldIP, newIP, clusterID, err)
+		}
+	}
+	for oldIP, newIP := range desired {
+		if config.mappings[oldIP] == newIP {
+			continue
+		}
+		if err := d.addMapping(config, oldIP, newIP); err != nil {
+			return fmt.Errorf("unable to load mapping %s -> %s for cluster %s: %w", oldIP, newIP, clusterID, err)
+		}
+	}
+	if config.podCIDR == nil || config.podCIDR.String() != podCIDR.String() {
+		if err := d.clusters.Put(lpmKey(podCIDR), indexValue(config.index)); err != nil {
+			return fmt.Errorf("unable to load PodCIDR %s for cluster %s: %w", podCIDR, clusterID, err)
+		}
+		if config.podCIDR != nil {
+			if err := deleteEntry(d.clusters, lpmKey(config.podCIDR)); err != nil {
+				return fmt.Errorf("unable to remove PodCIDR %s for cluster %s: %w", config.podCIDR, clusterID, err)
+			}
+		}
+		config.podCIDR = podCIDR
+	}
+	klog.V(4).Infof("loaded %d eBPF NAT mappings for cluster %s", len(config.mappings), clusterID)
+	return nil
+}
+
+// RemoveCluster removes all the entries of the given cluster from the maps.
+func (d *Datapath) RemoveCluster(clusterID string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	config, ok := d.configured[clusterID]
+	if !ok {
+		return nil
+	}
+	if config.podCIDR != nil {
+		if err := deleteEntry(d.clusters, lpmKey(config.podCIDR)); err != nil {
+			return fmt.Errorf("unable to remove PodCIDR %s for cluster %s: %w", config.podCIDR, clusterID, err)
+		}
+		config.podCIDR = nil
+	}
+	for oldIP, newIP := range config.mappings {
+		if err := d.removeMapping(config, oldIP, newIP); err != nil {
+			return fmt.Errorf("unable to remove mapping %s -> %s for cluster %s: %w", oldIP, newIP, clusterID, err)
+		}
+	}
+	delete(d.configured, clusterID)
+	klog.Infof("removed the eBPF NAT configuration of cluster %s", clusterID)
+	return nil
+}
+
+// Close releases the maps. The programs keep working with the entries loaded so far, as long as they are attached.
+func (d *Datapath) Close() {
+	for _, m := range []*ebpf.Map{d.clusters, d.dnat, d.snat} {
+		if m != nil {
+			_ = m.Close()
+		}
+	}
+}
+
+func (d *Datapath) addMapping(config *clusterConfig, oldIP, newIP string) error {
+	if err := d.dnat.Put(natKey(config.index, newIP), addrValue(oldIP)); err != nil {
+		return err
+	}
+	if err := d.snat.Put(natKey(config.index, oldIP), addrValue(newIP)); err != nil {
+		return err
+	}
+	config.mappings[oldIP] = newIP
+	return nil
+}
+
+func (d *Datapath) removeMapping(config *clusterConfig, oldIP, newIP string) error {
+	if err := deleteEntry(d.dnat, natKey(config.index, newIP)); err != nil {
+		return err
+	}
+	if err := deleteEntry(d.snat, natKey(config.index, oldIP)); err != nil {
+		return err
+	}
+	delete(config.mappings, oldIP)
+	return nil
+}
+
+// deleteEntry removes the given entry from the map. Missing entries are not considered an error.
+func deleteEntry(m *ebpf.Map, key []byte) error {
+	if err := m.Delete(key); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return err
+	}
+	return nil
+}
+
+// freeIndex returns the lowest index not assigned to any cluster.
+func (d *Datapath) freeIndex() (uint32, error) {
+	used := make(map[uint32]struct{}, len(d.configured))
+	for _, config := range d.configured {
+		used[config.index] = struct{}{}
+	}
+	for index := uint32(0); index < maxClusters; index++ {
+		if _, ok := used[index]; !ok {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("the maximum number of clusters (%d) handled by the eBPF datapath has been reached", maxClusters)
+}
+
+// lpmKey returns the key of the clusters map for the given network.
+func lpmKey(network *net.IPNet) []byte {
+	ones, _ := network.Mask.Size()
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint32(key, uint32(ones))
+	copy(key[4:], network.IP.To4())
+	return key
+}
+
+// natKey returns the key of the nat maps for the given cluster index and address.
+func natKey(index uint32, ip string) []byte {
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint32(key, index)
+	copy(key[4:], net.ParseIP(ip).To4())
+	return key
+}
+
+func indexValue(index uint32) []byte {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, index)
+	return value
+}
+
+func addrValue(ip string) []byte {
+	return net.ParseIP(ip).To4()
+}
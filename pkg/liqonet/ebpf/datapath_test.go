@@ -0,0 +1,461 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/containernetworking/plugins/pkg/ns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/iptables"
+	liqonetns "github.com/liqotech/liqo/pkg/liqonet/netns"
+)
+
+const (
+	clusterID1  = "cluster1"
+	linkName    = "liqo-ebpf"
+	remotePodIP = "10.60.0.5"
+	oldIP1      = "10.0.0.2"
+	newIP1      = "192.168.4.2"
+	oldIP2      = "12.0.0.4"
+	newIP2      = "192.168.4.1"
+	// Configuration of the veth pair connecting the current network namespace to the one of the remote pod.
+	netnsName  = "liqo-ebpf-test"
+	hostVeth   = "liqo-ebpf-host"
+	peerVeth   = "liqo-ebpf-peer"
+	gatewayIP  = "10.60.0.1"
+	remotePort = 40000
+	localPort  = 8080
+)
+
+// checksum computes the internet checksum of the given data.
+func checksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// l4Checksum computes the checksum of the given transport segment, including the pseudo header.
+func l4Checksum(src, dst net.IP, protocol byte, segment []byte) uint16 {
+	pseudo := make([]byte, 0, 12+len(segment))
+	pseudo = append(pseudo, src.To4()...)
+	pseudo = append(pseudo, dst.To4()...)
+	pseudo = append(pseudo, 0, protocol, byte(len(segment)>>8), byte(len(segment)))
+	return checksum(append(pseudo, segment...))
+}
+
+// packet returns an ethernet frame carrying a TCP or UDP segment with valid checksums.
+func packet(src, dst string, protocol byte) []byte {
+	l4Len := 20
+	if protocol == protocolUDP {
+		l4Len = 8
+	}
+	payload := []byte("liqo")
+	frame := make([]byte, ethHeaderLen+20+l4Len+len(payload))
+	binary.BigEndian.PutUint16(frame[12:], unix.ETH_P_IP)
+	ip := frame[ethHeaderLen:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(frame)-ethHeaderLen))
+	ip[8], ip[9] = 64, protocol
+	copy(ip[ipSaddrOff:], net.ParseIP(src).To4())
+	copy(ip[ipDaddrOff:], net.ParseIP(dst).To4())
+	binary.BigEndian.PutUint16(ip[ipChecksumOff:], checksum(ip[:20]))
+	l4 := ip[20:]
+	binary.BigEndian.PutUint16(l4[0:], 40000)
+	binary.BigEndian.PutUint16(l4[2:], 8080)
+	copy(l4[l4Len:], payload)
+	if protocol == protocolTCP {
+		l4[12] = 5 << 4
+		binary.BigEndian.PutUint16(l4[tcpCsumOff:], l4Checksum(ip[ipSaddrOff:ipSaddrOff+4], ip[ipDaddrOff:ipDaddrOff+4], protocol, l4))
+	} else {
+		binary.BigEndian.PutUint16(l4[4:], uint16(len(l4)))
+	}
+	return frame
+}
+
+// addresses returns the source and destination addresses of the given frame.
+func addresses(frame []byte) (src, dst string) {
+	ip := frame[ethHeaderLen:]
+	return net.IP(ip[ipSaddrOff : ipSaddrOff+4]).String(), net.IP(ip[ipDaddrOff : ipDaddrOff+4]).String()
+}
+
+// expectValidChecksums checks the IP and transport checksums of the given frame.
+func expectValidChecksums(frame []byte) {
+	ip := frame[ethHeaderLen:]
+	ExpectWithOffset(1, checksum(ip[:20])).To(BeZero())
+	if ip[9] == protocolTCP {
+		ExpectWithOffset(1, l4Checksum(ip[ipSaddrOff:ipSaddrOff+4], ip[ipDaddrOff:ipDaddrOff+4], ip[9], ip[20:])).To(BeZero())
+	}
+}
+
+// fakeHandler records the NatMappings received by the wrapped handler.
+type fakeHandler struct {
+	iptables.Handler
+	natMappings []*netv1alpha1.NatMapping
+	teps        []string
+	removed     []string
+}
+
+func (f *fakeHandler) EnsurePreroutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) error {
+	f.teps = append(f.teps, tep.Spec.ClusterID)
+	return nil
+}
+
+func (f *fakeHandler) EnsurePreroutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) error {
+	f.natMappings = append(f.natMappings, nm)
+	return nil
+}
+
+func (f *fakeHandler) RemoveIPTablesConfigurationPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	f.removed = append(f.removed, tep.Spec.ClusterID)
+	return nil
+}
+
+var _ = Describe("Datapath", func() {
+	var (
+		d              *Datapath
+		nm             *netv1alpha1.NatMapping
+		ingressProgram *ebpf.Program
+		egressProgram  *ebpf.Program
+		err            error
+		run            func(program *ebpf.Program, frame []byte) []byte
+	)
+
+	run = func(program *ebpf.Program, frame []byte) []byte {
+		retval, out, err := program.Test(frame)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, retval).To(BeNumerically("==", actOK))
+		return out
+	}
+
+	BeforeEach(func() {
+		d, err = NewDatapath()
+		Expect(err).ToNot(HaveOccurred())
+		// The programs are loaded through the verifier, which rejects them in case of invalid accesses.
+		ingressProgram, err = d.loadProgram(ingress, ethHeaderLen)
+		Expect(err).ToNot(HaveOccurred())
+		egressProgram, err = d.loadProgram(egress, ethHeaderLen)
+		Expect(err).ToNot(HaveOccurred())
+		nm = &netv1alpha1.NatMapping{Spec: netv1alpha1.NatMappingSpec{
+			ClusterID:       clusterID1,
+			PodCIDR:         "10.60.0.0/24",
+			ExternalCIDR:    "192.168.4.0/24",
+			ClusterMappings: netv1alpha1.Mappings{oldIP1: newIP1, oldIP2: newIP2, "fd00::1": "fd01::1"},
+		}}
+		Expect(d.EnsureNatMapping(nm)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(ingressProgram.Close()).To(Succeed())
+		Expect(egressProgram.Close()).To(Succeed())
+		d.Close()
+	})
+
+	Describe("ingress program", func() {
+		It("should translate the destination address of the TCP packets coming from the remote cluster", func() {
+			out := run(ingressProgram, packet(remotePodIP, newIP1, protocolTCP))
+			src, dst := addresses(out)
+			Expect(src).To(Equal(remotePodIP))
+			Expect(dst).To(Equal(oldIP1))
+			expectValidChecksums(out)
+		})
+
+		It("should translate the destination address of the UDP packets without checksum", func() {
+			out := run(ingressProgram, packet(remotePodIP, newIP2, protocolUDP))
+			_, dst := addresses(out)
+			Expect(dst).To(Equal(oldIP2))
+			expectValidChecksums(out)
+			Expect(binary.BigEndian.Uint16(out[ethHeaderLen+20+udpCsumOff:])).To(BeZero())
+		})
+
+		It("should not modify the packets coming from unknown clusters", func() {
+			frame := packet("10.70.0.5", newIP1, protocolTCP)
+			Expect(run(ingressProgram, frame)).To(Equal(frame))
+		})
+
+		It("should not modify the packets directed to addresses not mapped", func() {
+			frame := packet(remotePodIP, "192.168.4.100", protocolTCP)
+			Expect(run(ingressProgram, frame)).To(Equal(frame))
+		})
+	})
+
+	Describe("egress program", func() {
+		It("should translate the source address of the replies directed to the remote cluster", func() {
+			out := run(egressProgram, packet(oldIP1, remotePodIP, protocolTCP))
+			src, dst := addresses(out)
+			Expect(src).To(Equal(newIP1))
+			Expect(dst).To(Equal(remotePodIP))
+			expectValidChecksums(out)
+		})
+
+		It("should not modify the packets directed to other destinations", func() {
+			frame := packet(oldIP1, "10.70.0.5", protocolTCP)
+			Expect(run(egressProgram, frame)).To(Equal(frame))
+		})
+	})
+
+	Describe("EnsureNatMapping", func() {
+		It("should remove the outdated mappings and load the new ones", func() {
+			nm.Spec.ClusterMappings = netv1alpha1.Mappings{oldIP1: newIP2}
+			Expect(d.EnsureNatMapping(nm)).To(Succeed())
+			frame := packet(remotePodIP, newIP1, protocolTCP)
+			Expect(run(ingressProgram, frame)).To(Equal(frame))
+			_, dst := addresses(run(ingressProgram, packet(remotePodIP, newIP2, protocolTCP)))
+			Expect(dst).To(Equal(oldIP1))
+			Expect(d.configured[clusterID1].mappings).To(Equal(map[string]string{oldIP1: newIP2}))
+		})
+
+		It("should follow the changes of the PodCIDR of the remote cluster", func() {
+			nm.Spec.PodCIDR = "10.70.0.0/24"
+			Expect(d.EnsureNatMapping(nm)).To(Succeed())
+			frame := packet(remotePodIP, newIP1, protocolTCP)
+			Expect(run(ingressProgram, frame)).To(Equal(frame))
+			_, dst := addresses(run(ingressProgram, packet("10.70.0.5", newIP1, protocolTCP)))
+			Expect(dst).To(Equal(oldIP1))
+		})
+
+		It("should assign a different index to each cluster", func() {
+			Expect(d.EnsureNatMapping(&netv1alpha1.NatMapping{Spec: netv1alpha1.NatMappingSpec{
+				ClusterID: "cluster2", PodCIDR: "10.70.0.0/24", ClusterMappings: netv1alpha1.Mappings{oldIP1: newIP2},
+			}})).To(Succeed())
+			Expect(d.configured["cluster2"].index).ToNot(Equal(d.configured[clusterID1].index))
+			_, dst := addresses(run(ingressProgram, packet(remotePodIP, newIP1, protocolTCP)))
+			Expect(dst).To(Equal(oldIP1))
+			_, dst = addresses(run(ingressProgram, packet("10.70.0.5", newIP2, protocolTCP)))
+			Expect(dst).To(Equal(oldIP1))
+		})
+
+		It("should fail if the PodCIDR is not a valid IPv4 network", func() {
+			nm.Spec.PodCIDR = "fd00::/64"
+			Expect(d.EnsureNatMapping(nm)).ToNot(Succeed())
+		})
+	})
+
+	Describe("RemoveCluster", func() {
+		It("should remove all the entries of the cluster", func() {
+			Expect(d.RemoveCluster(clusterID1)).To(Succeed())
+			frame := packet(remotePodIP, newIP1, protocolTCP)
+			Expect(run(ingressProgram, frame)).To(Equal(frame))
+			frame = packet(oldIP1, remotePodIP, protocolTCP)
+			Expect(run(egressProgram, frame)).To(Equal(frame))
+			Expect(d.configured).To(BeEmpty())
+		})
+
+		It("should not fail if the cluster is not configured", func() {
+			Expect(d.RemoveCluster("unknown")).To(Succeed())
+		})
+	})
+
+	Describe("Handler", func() {
+		It("should delegate only the IPv6 mappings to the wrapped handler", func() {
+			fake := &fakeHandler{}
+			h := NewHandler(fake, d, nil)
+			Expect(h.EnsurePreroutingRulesPerNatMapping(nm)).To(Succeed())
+			Expect(fake.natMappings).To(HaveLen(1))
+			Expect(fake.natMappings[0].Spec.ClusterMappings).To(Equal(netv1alpha1.Mappings{"fd00::1": "fd01::1"}))
+			Expect(nm.Spec.ClusterMappings).To(HaveLen(3))
+
+			Expect(h.RemoveIPTablesConfigurationPerCluster(&netv1alpha1.TunnelEndpoint{
+				Spec: netv1alpha1.TunnelEndpointSpec{ClusterID: clusterID1}})).To(Succeed())
+			Expect(fake.removed).To(ConsistOf(clusterID1))
+			Expect(d.configured).To(BeEmpty())
+		})
+
+		Context("the tunnel interface is recreated", func() {
+			addLink := func() netlink.Link {
+				Expect(netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: linkName}, PeerName: linkName + "-peer"})).To(Succeed())
+				link, err := netlink.LinkByName(linkName)
+				Expect(err).ToNot(HaveOccurred())
+				return link
+			}
+
+			BeforeEach(func() { addLink() })
+			AfterEach(func() {
+				if link, err := netlink.LinkByName(linkName); err == nil {
+					Expect(netlink.LinkDel(link)).To(Succeed())
+				}
+			})
+
+			It("should attach the programs again to the interface of the backend of the tunnel", func() {
+				fake := &fakeHandler{}
+				h := NewHandler(fake, d, map[string]string{"wireguard": linkName})
+				tep := &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{ClusterID: clusterID1, BackendType: "wireguard"}}
+				Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+				Expect(fake.teps).To(ConsistOf(clusterID1))
+
+				link, err := netlink.LinkByName(linkName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(netlink.LinkDel(link)).To(Succeed())
+				link = addLink()
+				Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+				Expect(d.attached).To(HaveKeyWithValue(linkName, link.Attrs().Index))
+				filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_EGRESS)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(filters).To(HaveLen(1))
+			})
+
+			It("should not attach the programs to the interfaces of other backends", func() {
+				h := NewHandler(&fakeHandler{}, d, map[string]string{"wireguard": linkName})
+				tep := &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{ClusterID: clusterID1, BackendType: "ipsec"}}
+				Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+				Expect(d.attached).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("loadProgram", func() {
+		It("should load the programs for the interfaces without link layer header", func() {
+			for _, dir := range []direction{ingress, egress} {
+				program, err := d.loadProgram(dir, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(program.Close()).To(Succeed())
+			}
+		})
+	})
+
+	// The remote pod lives in a separate network namespace, connected through a veth pair to the current one,
+	// which hosts the mapped endpoint. The programs are attached to the veth in the current network namespace.
+	Describe("attached to a veth pair", func() {
+		var peerNetns ns.NetNS
+
+		BeforeEach(func() {
+			hostNetns, err := ns.GetCurrentNS()
+			Expect(err).ToNot(HaveOccurred())
+			peerNetns, err = liqonetns.CreateNetns(netnsName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(liqonetns.CreateVethPair(hostVeth, peerVeth, hostNetns, peerNetns, 1500)).To(Succeed())
+
+			link, err := netlink.LinkByName(hostVeth)
+			Expect(err).ToNot(HaveOccurred())
+			for _, address := range []string{gatewayIP + "/24", oldIP1 + "/32"} {
+				addr, err := netlink.ParseAddr(address)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(netlink.AddrAdd(link, addr)).To(Succeed())
+			}
+			Expect(peerNetns.Do(func(ns.NetNS) error {
+				link, err := netlink.LinkByName(peerVeth)
+				if err != nil {
+					return err
+				}
+				addr, err := netlink.ParseAddr(remotePodIP + "/24")
+				if err != nil {
+					return err
+				}
+				if err := netlink.AddrAdd(link, addr); err != nil {
+					return err
+				}
+				_, externalCIDR, err := net.ParseCIDR(nm.Spec.ExternalCIDR)
+				if err != nil {
+					return err
+				}
+				return netlink.RouteAdd(&netlink.Route{LinkIndex: link.Attrs().Index, Dst: externalCIDR, Gw: net.ParseIP(gatewayIP)})
+			})).To(Succeed())
+
+			Expect(d.EnsureAttached(hostVeth)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if link, err := netlink.LinkByName(hostVeth); err == nil {
+				Expect(netlink.LinkDel(link)).To(Succeed())
+			}
+			Expect(peerNetns.Close()).To(Succeed())
+			Expect(liqonetns.DeleteNetns(netnsName)).To(Succeed())
+		})
+
+		It("should translate the addresses of the UDP datagrams exchanged with the remote pod", func() {
+			server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP(oldIP1), Port: localPort})
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+			var client *net.UDPConn
+			Expect(peerNetns.Do(func(ns.NetNS) error {
+				client, err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP(remotePodIP), Port: remotePort})
+				return err
+			})).To(Succeed())
+			defer client.Close()
+			buffer := make([]byte, 16)
+
+			// The datagram sent to the address of the ExternalCIDR reaches the mapped endpoint.
+			_, err = client.WriteToUDP([]byte("request"), &net.UDPAddr{IP: net.ParseIP(newIP1), Port: localPort})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+			n, from, err := server.ReadFromUDP(buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buffer[:n])).To(Equal("request"))
+			Expect(from.IP.String()).To(Equal(remotePodIP))
+
+			// The reply appears as originated from the address of the ExternalCIDR.
+			_, err = server.WriteToUDP([]byte("reply"), from)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+			n, from, err = client.ReadFromUDP(buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buffer[:n])).To(Equal("reply"))
+			Expect(from.IP.String()).To(Equal(newIP1))
+			Expect(from.Port).To(Equal(localPort))
+		})
+
+		It("should translate the addresses of the TCP connections initiated by the remote pod", func() {
+			listener, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP(oldIP1), Port: localPort})
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+			accepted := make(chan net.Conn, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := listener.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				accepted <- conn
+			}()
+
+			var client net.Conn
+			Expect(peerNetns.Do(func(ns.NetNS) error {
+				dialer := net.Dialer{Timeout: 5 * time.Second, LocalAddr: &net.TCPAddr{IP: net.ParseIP(remotePodIP)}}
+				client, err = dialer.Dial("tcp4", net.JoinHostPort(newIP1, strconv.Itoa(localPort)))
+				return err
+			})).To(Succeed())
+			defer client.Close()
+			Expect(client.RemoteAddr().(*net.TCPAddr).IP.String()).To(Equal(newIP1))
+
+			var server net.Conn
+			Eventually(accepted, 5*time.Second).Should(Receive(&server))
+			defer server.Close()
+			Expect(server.RemoteAddr().(*net.TCPAddr).IP.String()).To(Equal(remotePodIP))
+
+			_, err = client.Write([]byte("request"))
+			Expect(err).ToNot(HaveOccurred())
+			buffer := make([]byte, 16)
+			Expect(server.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+			n, err := server.Read(buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buffer[:n])).To(Equal("request"))
+		})
+	})
+})
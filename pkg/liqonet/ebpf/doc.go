@@ -0,0 +1,20 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ebpf contains an optional eBPF datapath performing the NAT of the addresses belonging
+// to the ExternalCIDR, as an alternative to the DNAT rules generated from the NatMapping resources.
+// The programs are attached to the tunnel interfaces through the tc hooks and look up the mappings in
+// BPF maps, which are kept in sync with the NatMapping resources. The programs and the maps are built
+// and loaded through the cilium/ebpf library, which also reports the log of the verifier in case of errors.
+package ebpf
@@ -0,0 +1,72 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/iptables"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// Handler wraps a handler configuring the NAT rules, offloading the translation of the IPv4 addresses
+// of the NatMapping resources to the eBPF datapath. The remaining rules, as well as the IPv6 mappings,
+// are still configured by the wrapped handler.
+type Handler struct {
+	iptables.Handler
+	datapath *Datapath
+	// links maps the backend types to the names of the tunnel interfaces the programs are attached to.
+	links map[string]string
+}
+
+// NewHandler returns a handler offloading the IPv4 NatMappings to the given datapath. The links map the backend types
+// of the initialized tunnel drivers to the names of the corresponding interfaces.
+func NewHandler(h iptables.Handler, datapath *Datapath, links map[string]string) *Handler {
+	return &Handler{Handler: h, datapath: datapath, links: links}
+}
+
+// EnsurePreroutingRulesPerTunnelEndpoint makes sure that the programs are attached to the interface of the tunnel
+// described by the given tep, which may have been recreated by the driver, and delegates to the wrapped handler.
+func (h *Handler) EnsurePreroutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) error {
+	if linkName, found := h.links[tep.Spec.BackendType]; found {
+		if err := h.datapath.EnsureAttached(linkName); err != nil {
+			return err
+		}
+	}
+	return h.Handler.EnsurePreroutingRulesPerTunnelEndpoint(tep)
+}
+
+// EnsurePreroutingRulesPerNatMapping loads the IPv4 mappings in the eBPF datapath, and configures
+// the IPv6 ones through the wrapped handler, which also removes any IPv4 rule configured previously.
+func (h *Handler) EnsurePreroutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) error {
+	if err := h.datapath.EnsureNatMapping(nm); err != nil {
+		return err
+	}
+	nmv6 := nm.DeepCopy()
+	for oldIP := range nmv6.Spec.ClusterMappings {
+		if !utils.IsIPv6(oldIP) {
+			delete(nmv6.Spec.ClusterMappings, oldIP)
+		}
+	}
+	return h.Handler.EnsurePreroutingRulesPerNatMapping(nmv6)
+}
+
+// RemoveIPTablesConfigurationPerCluster removes the configuration of the given cluster from both
+// the wrapped handler and the eBPF datapath.
+func (h *Handler) RemoveIPTablesConfigurationPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	if err := h.Handler.RemoveIPTablesConfigurationPerCluster(tep); err != nil {
+		return err
+	}
+	return h.datapath.RemoveCluster(tep.Spec.ClusterID)
+}
@@ -0,0 +1,188 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"golang.org/x/sys/unix"
+)
+
+// Flags of the checksum helpers.
+const (
+	csumPseudoHeader = 0x10
+	csumMangled0     = 0x20
+)
+
+const (
+	// actOK lets the packet continue its processing.
+	actOK = 0
+	// ethHeaderLen is the length of the ethernet header, which is not present on the tunnel interfaces.
+	ethHeaderLen = 14
+	protocolTCP  = 6
+	protocolUDP  = 17
+	// Offsets of the IPv4 header fields.
+	ipChecksumOff = 10
+	ipSaddrOff    = 12
+	ipDaddrOff    = 16
+	tcpCsumOff    = 16
+	udpCsumOff    = 6
+)
+
+// Offsets, with respect to the frame pointer, of the data stored in the stack.
+const (
+	stackIPHeader = -24
+	stackLPMKey   = -32
+	stackNatKey   = -40
+	stackNewAddr  = -44
+	stackEthType  = -48
+)
+
+// Names of the maps, which are referenced by the programs.
+const (
+	clustersMapName = "liqo_clusters"
+	dnatMapName     = "liqo_dnat"
+	snatMapName     = "liqo_snat"
+)
+
+// Labels of the programs.
+const (
+	labelPass = "pass"
+	labelUDP  = "udp"
+	labelL4   = "l4"
+	labelL3   = "l3"
+)
+
+// direction identifies the program translating the addresses in a given direction.
+type direction struct {
+	name string
+	// natMap is the name of the map holding the translated addresses.
+	natMap string
+	// lookupOff is the offset of the address identifying the remote cluster.
+	lookupOff int16
+	// rewriteOff is the offset of the address to be translated.
+	rewriteOff int16
+}
+
+var (
+	// ingress translates the destination addresses of the packets coming from the remote clusters,
+	// replacing the addresses of the ExternalCIDR with the original ones.
+	ingress = direction{name: "liqo_nat_ingress", natMap: dnatMapName, lookupOff: ipSaddrOff, rewriteOff: ipDaddrOff}
+	// egress translates the source addresses of the packets directed to the remote clusters, replacing the original
+	// addresses with the ones of the ExternalCIDR. Being stateless, it applies to the replies as well as to the
+	// packets of the connections initiated by the mapped endpoints.
+	egress = direction{name: "liqo_nat_egress", natMap: snatMapName, lookupOff: ipDaddrOff, rewriteOff: ipSaddrOff}
+)
+
+// mapSpecs returns the specifications of the maps shared by the programs.
+func mapSpecs() map[string]*ebpf.MapSpec {
+	return map[string]*ebpf.MapSpec{
+		// The keys of the clusters map are composed of the prefix length and the IPv4 network, the values are the indexes.
+		clustersMapName: {Name: clustersMapName, Type: ebpf.LPMTrie, KeySize: 8, ValueSize: 4,
+			MaxEntries: maxClusters, Flags: unix.BPF_F_NO_PREALLOC},
+		// The keys of the nat maps are composed of the cluster index and the address, the values are the translated ones.
+		dnatMapName: {Name: dnatMapName, Type: ebpf.Hash, KeySize: 8, ValueSize: 4, MaxEntries: maxMappings},
+		snatMapName: {Name: snatMapName, Type: ebpf.Hash, KeySize: 8, ValueSize: 4, MaxEntries: maxMappings},
+	}
+}
+
+// programSpec returns the program translating the addresses in the given direction. The remote cluster the packet
+// refers to is looked up in the clusters map, and the translated address in the nat one of the direction, which are
+// referenced by name. The l2Len is the length of the link layer header preceding the IP one on the interface the
+// program is attached to.
+func programSpec(dir direction, l2Len int32) *ebpf.ProgramSpec {
+	var insns asm.Instructions
+	emit := func(i ...asm.Instruction) { insns = append(insns, i...) }
+
+	emit(asm.Mov.Reg(asm.R6, asm.R1))
+	if l2Len == ethHeaderLen {
+		// Skip the non IPv4 frames.
+		emit(
+			asm.Mov.Reg(asm.R1, asm.R6), asm.Mov.Imm(asm.R2, 12),
+			asm.Mov.Reg(asm.R3, asm.RFP), asm.Add.Imm(asm.R3, stackEthType), asm.Mov.Imm(asm.R4, 2),
+			asm.FnSkbLoadBytes.Call(),
+			asm.JNE.Imm(asm.R0, 0, labelPass),
+			asm.LoadMem(asm.R1, asm.RFP, stackEthType, asm.Byte), asm.JNE.Imm(asm.R1, 0x08, labelPass),
+			asm.LoadMem(asm.R1, asm.RFP, stackEthType+1, asm.Byte), asm.JNE.Imm(asm.R1, 0x00, labelPass),
+		)
+	}
+	// Copy the IP header in the stack, and skip the non IPv4 packets.
+	emit(
+		asm.Mov.Reg(asm.R1, asm.R6), asm.Mov.Imm(asm.R2, l2Len),
+		asm.Mov.Reg(asm.R3, asm.RFP), asm.Add.Imm(asm.R3, stackIPHeader), asm.Mov.Imm(asm.R4, 20),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, labelPass),
+		asm.LoadMem(asm.R1, asm.RFP, stackIPHeader, asm.Byte),
+		asm.Mov.Reg(asm.R2, asm.R1), asm.RSh.Imm(asm.R2, 4), asm.JNE.Imm(asm.R2, 4, labelPass),
+		// R9 holds the length of the IP header, R8 the transport protocol.
+		asm.And.Imm(asm.R1, 0x0f), asm.LSh.Imm(asm.R1, 2), asm.Mov.Reg(asm.R9, asm.R1),
+		asm.LoadMem(asm.R8, asm.RFP, stackIPHeader+9, asm.Byte),
+	)
+	// Look up the remote cluster.
+	emit(
+		asm.StoreImm(asm.RFP, stackLPMKey, 32, asm.Word),
+		asm.LoadMem(asm.R1, asm.RFP, stackIPHeader+dir.lookupOff, asm.Word),
+		asm.StoreMem(asm.RFP, stackLPMKey+4, asm.R1, asm.Word),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(clustersMapName),
+		asm.Mov.Reg(asm.R2, asm.RFP), asm.Add.Imm(asm.R2, stackLPMKey),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, labelPass),
+	)
+	// Look up the translated address, which is kept in R7.
+	emit(
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.Word), asm.StoreMem(asm.RFP, stackNatKey, asm.R1, asm.Word),
+		asm.LoadMem(asm.R1, asm.RFP, stackIPHeader+dir.rewriteOff, asm.Word),
+		asm.StoreMem(asm.RFP, stackNatKey+4, asm.R1, asm.Word),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(dir.natMap),
+		asm.Mov.Reg(asm.R2, asm.RFP), asm.Add.Imm(asm.R2, stackNatKey),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, labelPass),
+		asm.LoadMem(asm.R7, asm.R0, 0, asm.Word), asm.StoreMem(asm.RFP, stackNewAddr, asm.R7, asm.Word),
+	)
+	// Update the transport checksum, unless the packet is a non-first fragment.
+	emit(
+		asm.LoadMem(asm.R1, asm.RFP, stackIPHeader+6, asm.Byte), asm.And.Imm(asm.R1, 0x1f),
+		asm.LoadMem(asm.R2, asm.RFP, stackIPHeader+7, asm.Byte), asm.Or.Reg(asm.R1, asm.R2),
+		asm.JNE.Imm(asm.R1, 0, labelL3),
+		asm.JNE.Imm(asm.R8, protocolTCP, labelUDP),
+		asm.Mov.Reg(asm.R2, asm.R9), asm.Add.Imm(asm.R2, l2Len+tcpCsumOff), asm.Mov.Imm(asm.R5, csumPseudoHeader|4),
+		asm.Ja.Label(labelL4),
+		asm.JNE.Imm(asm.R8, protocolUDP, labelL3).WithSymbol(labelUDP),
+		asm.Mov.Reg(asm.R2, asm.R9), asm.Add.Imm(asm.R2, l2Len+udpCsumOff),
+		asm.Mov.Imm(asm.R5, csumPseudoHeader|csumMangled0|4),
+		asm.Mov.Reg(asm.R1, asm.R6).WithSymbol(labelL4),
+		asm.LoadMem(asm.R3, asm.RFP, stackNatKey+4, asm.Word), asm.Mov.Reg(asm.R4, asm.R7),
+		asm.FnL4CsumReplace.Call(),
+		asm.JNE.Imm(asm.R0, 0, labelPass),
+	)
+	// Update the IP checksum and the address.
+	emit(
+		asm.Mov.Reg(asm.R1, asm.R6).WithSymbol(labelL3), asm.Mov.Imm(asm.R2, l2Len+ipChecksumOff),
+		asm.LoadMem(asm.R3, asm.RFP, stackNatKey+4, asm.Word), asm.Mov.Reg(asm.R4, asm.R7), asm.Mov.Imm(asm.R5, 4),
+		asm.FnL3CsumReplace.Call(),
+		asm.JNE.Imm(asm.R0, 0, labelPass),
+		asm.Mov.Reg(asm.R1, asm.R6), asm.Mov.Imm(asm.R2, l2Len+int32(dir.rewriteOff)),
+		asm.Mov.Reg(asm.R3, asm.RFP), asm.Add.Imm(asm.R3, stackNewAddr), asm.Mov.Imm(asm.R4, 4), asm.Mov.Imm(asm.R5, 0),
+		asm.FnSkbStoreBytes.Call(),
+	)
+	emit(asm.Mov.Imm(asm.R0, actOK).WithSymbol(labelPass), asm.Return())
+
+	return &ebpf.ProgramSpec{
+		Name:         dir.name,
+		Type:         ebpf.SchedCLS,
+		License:      "Apache-2.0",
+		Instructions: insns,
+	}
+}
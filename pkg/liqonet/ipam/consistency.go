@@ -0,0 +1,298 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	goipam "github.com/metal-stack/go-ipam"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// DriftType identifies the kind of inconsistency detected in the IPAM configuration.
+type DriftType string
+
+const (
+	// OrphanedClusterDrift means that networks, endpoint mappings or NAT mappings are still held
+	// for a remote cluster which is no longer peered.
+	OrphanedClusterDrift DriftType = "OrphanedCluster"
+	// LeakedEndpointMappingDrift means that an endpoint is still mapped in the ExternalCIDR,
+	// although it is not reflected on any remote cluster.
+	LeakedEndpointMappingDrift DriftType = "LeakedEndpointMapping"
+	// MissingNatMappingDrift means that an endpoint mapping is missing from the NAT mappings
+	// of a remote cluster the endpoint is reflected on.
+	MissingNatMappingDrift DriftType = "MissingNatMapping"
+	// StaleNatMappingDrift means that a NAT mapping of a remote cluster is not backed by an endpoint mapping.
+	StaleNatMappingDrift DriftType = "StaleNatMapping"
+)
+
+// Drift describes an inconsistency detected in the IPAM configuration.
+type Drift struct {
+	Type DriftType
+	// ClusterID is the remote cluster the drift refers to, if any.
+	ClusterID string
+	// Message is a human readable description of the drift.
+	Message string
+	// Repaired is true if the drift has been fixed.
+	Repaired bool
+}
+
+// PeeringChecker returns whether a remote cluster is still peered with the local one, hence
+// whether its IPAM configuration has to be preserved.
+type PeeringChecker func(clusterID string) (bool, error)
+
+// CheckConsistency cross-checks the IPAM configuration against the remote clusters still peered and the NAT mappings,
+// and returns the detected drifts. If repair is true, the drifts are fixed as well:
+// - the configuration of clusters no longer peered is removed, freeing their networks and endpoint IPs;
+// - the endpoint IPs which are not reflected on any cluster are freed;
+// - the NAT mappings are aligned with the endpoint mappings.
+// The IPAM is locked during the check, hence isPeered can safely compare the IPAM configuration
+// with the state of the peerings.
+func (liqoIPAM *IPAM) CheckConsistency(isPeered PeeringChecker, repair bool) ([]Drift, error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	var drifts []Drift
+	orphans := make(map[string]bool)
+	for _, clusterID := range liqoIPAM.configuredClusters() {
+		peered, err := isPeered(clusterID)
+		if err != nil {
+			return drifts, fmt.Errorf("cannot check the peering with cluster %s: %w", clusterID, err)
+		}
+		if peered {
+			continue
+		}
+		orphans[clusterID] = true
+		drift := Drift{
+			Type:      OrphanedClusterDrift,
+			ClusterID: clusterID,
+			Message:   fmt.Sprintf("the IPAM configuration of cluster %s has not been removed, although it is no longer peered", clusterID),
+		}
+		if repair {
+			if err := liqoIPAM.removeOrphanedCluster(clusterID); err != nil {
+				return drifts, fmt.Errorf("cannot remove the IPAM configuration of cluster %s: %w", clusterID, err)
+			}
+			drift.Repaired = true
+		}
+		drifts = append(drifts, drift)
+	}
+
+	leakedDrifts, err := liqoIPAM.checkLeakedEndpointMappings(repair)
+	drifts = append(drifts, leakedDrifts...)
+	if err != nil {
+		return drifts, err
+	}
+
+	natMappingDrifts, err := liqoIPAM.checkNatMappings(orphans, repair)
+	drifts = append(drifts, natMappingDrifts...)
+	return drifts, err
+}
+
+// configuredClusters returns the sorted IDs of the remote clusters the IPAM holds any configuration for.
+func (liqoIPAM *IPAM) configuredClusters() []string {
+	clusters := make(map[string]struct{})
+	for clusterID := range liqoIPAM.ipamStorage.getClusterSubnets() {
+		clusters[clusterID] = struct{}{}
+	}
+	for clusterID := range liqoIPAM.ipamStorage.getNatMappingsConfigured() {
+		clusters[clusterID] = struct{}{}
+	}
+	for clusterID := range liqoIPAM.ipamStorage.getTransitSubnets() {
+		clusters[clusterID] = struct{}{}
+	}
+	for _, mapping := range liqoIPAM.ipamStorage.getEndpointMappings() {
+		for clusterID := range mapping.ClusterMappings {
+			clusters[clusterID] = struct{}{}
+		}
+	}
+	for _, clusterID := range liqoIPAM.natMappingInflater.GetClusterIDs() {
+		clusters[clusterID] = struct{}{}
+	}
+
+	clusterIDs := make([]string, 0, len(clusters))
+	for clusterID := range clusters {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+	return clusterIDs
+}
+
+// removeOrphanedCluster removes any configuration related to a remote cluster no longer peered.
+// Differently from removeClusterConfig, it does not rely on the cluster subnets, the endpoint mappings
+// and the NAT mappings being consistent with each other.
+func (liqoIPAM *IPAM) removeOrphanedCluster(clusterID string) error {
+	if err := liqoIPAM.removeClusterConfig(clusterID); err != nil {
+		return err
+	}
+
+	// Remove the cluster from the endpoint mappings not tracked by its NAT mappings.
+	endpointMappings := liqoIPAM.ipamStorage.getEndpointMappings()
+	for ip, mapping := range endpointMappings {
+		if _, exists := mapping.ClusterMappings[clusterID]; !exists {
+			continue
+		}
+		delete(mapping.ClusterMappings, clusterID)
+		if len(mapping.ClusterMappings) == 0 {
+			if err := liqoIPAM.releaseEndpointIP(ip, mapping); err != nil {
+				return err
+			}
+			delete(endpointMappings, ip)
+		}
+	}
+	if err := liqoIPAM.ipamStorage.updateEndpointMappings(endpointMappings); err != nil {
+		return fmt.Errorf("cannot update endpointMappings: %w", err)
+	}
+
+	natMappingsConfigured := liqoIPAM.ipamStorage.getNatMappingsConfigured()
+	if _, exists := natMappingsConfigured[clusterID]; exists {
+		delete(natMappingsConfigured, clusterID)
+		if err := liqoIPAM.ipamStorage.updateNatMappingsConfigured(natMappingsConfigured); err != nil {
+			return fmt.Errorf("unable to update NatMappingsConfigured: %w", err)
+		}
+	}
+
+	// Delete the NatMapping resource, in case it has not been tracked in the IPAM configuration.
+	if err := liqoIPAM.natMappingInflater.TerminateNatMappingsPerCluster(clusterID); err != nil {
+		return err
+	}
+	klog.Infof("IPAM configuration of cluster %s has been removed", clusterID)
+	return nil
+}
+
+// checkLeakedEndpointMappings looks for endpoints mapped in the ExternalCIDR which are not reflected
+// on any remote cluster, and frees their IPs if repair is true.
+func (liqoIPAM *IPAM) checkLeakedEndpointMappings(repair bool) ([]Drift, error) {
+	var drifts []Drift
+	endpointMappings := liqoIPAM.ipamStorage.getEndpointMappings()
+	for _, ip := range sortedEndpointIPs(endpointMappings) {
+		mapping := endpointMappings[ip]
+		if len(mapping.ClusterMappings) > 0 {
+			continue
+		}
+		drift := Drift{
+			Type:    LeakedEndpointMappingDrift,
+			Message: fmt.Sprintf("endpoint %s is still mapped on %s, although it is not reflected on any cluster", ip, mapping.IP),
+		}
+		if repair {
+			if err := liqoIPAM.releaseEndpointIP(ip, mapping); err != nil {
+				return drifts, err
+			}
+			delete(endpointMappings, ip)
+			drift.Repaired = true
+		}
+		drifts = append(drifts, drift)
+	}
+
+	if repair && len(drifts) > 0 {
+		if err := liqoIPAM.ipamStorage.updateEndpointMappings(endpointMappings); err != nil {
+			return drifts, fmt.Errorf("cannot update endpointMappings: %w", err)
+		}
+	}
+	return drifts, nil
+}
+
+// checkNatMappings compares the NAT mappings of the remote clusters not orphaned with the endpoint mappings,
+// and aligns the former with the latter if repair is true.
+func (liqoIPAM *IPAM) checkNatMappings(orphans map[string]bool, repair bool) ([]Drift, error) {
+	var drifts []Drift
+	endpointMappings := liqoIPAM.ipamStorage.getEndpointMappings()
+	clusterIDs := liqoIPAM.natMappingInflater.GetClusterIDs()
+	sort.Strings(clusterIDs)
+	for _, clusterID := range clusterIDs {
+		if orphans[clusterID] {
+			continue
+		}
+		natMappings, err := liqoIPAM.natMappingInflater.GetNatMappings(clusterID)
+		if err != nil {
+			return drifts, fmt.Errorf("cannot get NAT mappings for cluster %s: %w", clusterID, err)
+		}
+
+		for _, ip := range sortedNatMappingIPs(natMappings) {
+			if _, active := endpointMappings[ip].ClusterMappings[clusterID]; active {
+				continue
+			}
+			drift := Drift{
+				Type:      StaleNatMappingDrift,
+				ClusterID: clusterID,
+				Message:   fmt.Sprintf("NAT mapping of %s on %s for cluster %s is not backed by an endpoint mapping", ip, natMappings[ip], clusterID),
+			}
+			if repair {
+				if err := liqoIPAM.natMappingInflater.RemoveMapping(ip, clusterID); err != nil {
+					return drifts, err
+				}
+				drift.Repaired = true
+			}
+			drifts = append(drifts, drift)
+		}
+
+		for _, ip := range sortedEndpointIPs(endpointMappings) {
+			mapping := endpointMappings[ip]
+			if _, active := mapping.ClusterMappings[clusterID]; !active || natMappings[ip] == mapping.IP {
+				continue
+			}
+			drift := Drift{
+				Type:      MissingNatMappingDrift,
+				ClusterID: clusterID,
+				Message:   fmt.Sprintf("NAT mapping of %s on %s for cluster %s is missing", ip, mapping.IP, clusterID),
+			}
+			if repair {
+				if err := liqoIPAM.natMappingInflater.AddMapping(ip, mapping.IP, clusterID); err != nil {
+					return drifts, err
+				}
+				drift.Repaired = true
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
+
+// releaseEndpointIP frees the ExternalCIDR IP an endpoint has been mapped on.
+func (liqoIPAM *IPAM) releaseEndpointIP(ip string, mapping netv1alpha1.EndpointMapping) error {
+	localExternalCIDR := liqoIPAM.localExternalCIDR(ip)
+	if localExternalCIDR == emptyCIDR {
+		return fmt.Errorf("cannot get ExternalCIDR to free IP %s", mapping.IP)
+	}
+	// ErrNotFound is returned if the IP has not been allocated or has already been freed.
+	if err := liqoIPAM.ipam.ReleaseIPFromPrefix(localExternalCIDR, mapping.IP); err != nil && !errors.Is(err, goipam.ErrNotFound) {
+		return fmt.Errorf("cannot free IP: %w", err)
+	}
+	klog.Infof("IP %s (mapped from %s) has been freed", mapping.IP, ip)
+	return nil
+}
+
+// sortedNatMappingIPs returns the sorted endpoint IPs of a set of NAT mappings.
+func sortedNatMappingIPs(natMappings map[string]string) []string {
+	ips := make([]string, 0, len(natMappings))
+	for ip := range natMappings {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// sortedEndpointIPs returns the sorted endpoint IPs of a set of endpoint mappings.
+func sortedEndpointIPs(endpointMappings map[string]netv1alpha1.EndpointMapping) []string {
+	ips := make([]string, 0, len(endpointMappings))
+	for ip := range endpointMappings {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
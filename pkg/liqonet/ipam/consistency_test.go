@@ -0,0 +1,173 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	liqonetapi "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+)
+
+var _ = Describe("CheckConsistency", func() {
+	var (
+		mappedIP string
+		peered   map[string]bool
+		isPeered = func(clusterID string) (bool, error) { return peered[clusterID], nil }
+	)
+
+	BeforeEach(func() {
+		ipam = NewIPAM()
+		Expect(setDynClient()).To(Succeed())
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		Expect(err).To(BeNil())
+		Expect(ipam.Init(Pools, dynClient, 2000+int(n.Int64()))).To(Succeed())
+
+		Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+		_, err = ipam.GetExternalCIDR(24)
+		Expect(err).To(BeNil())
+		for _, clusterID := range []string{clusterID1, clusterID2} {
+			_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID)
+			Expect(err).To(BeNil())
+			Expect(ipam.AddLocalSubnetsPerCluster(consts.DefaultCIDRValue, consts.DefaultCIDRValue, clusterID)).To(Succeed())
+		}
+		response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: externalEndpointIP})
+		Expect(err).To(BeNil())
+		mappedIP = response.GetIp()
+
+		peered = map[string]bool{clusterID1: true, clusterID2: true}
+	})
+
+	AfterEach(func() {
+		ipam.Terminate()
+	})
+
+	Context("When the configuration is consistent", func() {
+		It("should not report any drift", func() {
+			drifts, err := ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(BeEmpty())
+		})
+	})
+
+	Context("When the peering checker fails", func() {
+		It("should return an error", func() {
+			_, err := ipam.CheckConsistency(func(clusterID string) (bool, error) {
+				return false, fmt.Errorf("fake error")
+			}, true)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("When a cluster is no longer peered", func() {
+		BeforeEach(func() { peered[clusterID1] = false })
+
+		It("should only report the drift if repair is false", func() {
+			drifts, err := ipam.CheckConsistency(isPeered, false)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type": Equal(OrphanedClusterDrift), "ClusterID": Equal(clusterID1), "Repaired": BeFalse(),
+			})))
+			Expect(ipam.ipamStorage.getClusterSubnets()).To(HaveKey(clusterID1))
+			Expect(ipam.ipamStorage.getEndpointMappings()).To(HaveKey(externalEndpointIP))
+		})
+
+		It("should remove its configuration if repair is true", func() {
+			drifts, err := ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type": Equal(OrphanedClusterDrift), "ClusterID": Equal(clusterID1), "Repaired": BeTrue(),
+			})))
+			Expect(ipam.ipamStorage.getClusterSubnets()).NotTo(HaveKey(clusterID1))
+			Expect(ipam.ipamStorage.getClusterSubnets()).To(HaveKey(clusterID2))
+			Expect(ipam.ipamStorage.getNatMappingsConfigured()).NotTo(HaveKey(clusterID1))
+			Expect(ipam.ipamStorage.getEndpointMappings()).NotTo(HaveKey(externalEndpointIP))
+			_, err = getNatMappingResourcePerCluster(clusterID1)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+
+			// A further check should not find any drift.
+			drifts, err = ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(BeEmpty())
+		})
+
+		It("should remove its endpoint mappings even if not tracked by its NAT mappings", func() {
+			Expect(ipam.natMappingInflater.RemoveMapping(externalEndpointIP, clusterID1)).To(Succeed())
+
+			_, err := ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			Expect(ipam.ipamStorage.getEndpointMappings()).NotTo(HaveKey(externalEndpointIP))
+		})
+	})
+
+	Context("When an endpoint mapping is not reflected on any cluster", func() {
+		BeforeEach(func() {
+			endpointMappings := ipam.ipamStorage.getEndpointMappings()
+			endpointMappings[externalEndpointIP] = liqonetapi.EndpointMapping{
+				IP:              mappedIP,
+				ClusterMappings: map[string]liqonetapi.ClusterMapping{},
+			}
+			Expect(ipam.ipamStorage.updateEndpointMappings(endpointMappings)).To(Succeed())
+			Expect(ipam.natMappingInflater.RemoveMapping(externalEndpointIP, clusterID1)).To(Succeed())
+		})
+
+		It("should free the mapped IP", func() {
+			drifts, err := ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type": Equal(LeakedEndpointMappingDrift), "Repaired": BeTrue(),
+			})))
+			Expect(ipam.ipamStorage.getEndpointMappings()).NotTo(HaveKey(externalEndpointIP))
+		})
+	})
+
+	Context("When the NAT mappings diverge from the endpoint mappings", func() {
+		BeforeEach(func() {
+			Expect(ipam.natMappingInflater.RemoveMapping(externalEndpointIP, clusterID1)).To(Succeed())
+			Expect(ipam.natMappingInflater.AddMapping("10.0.50.7", "192.168.30.7", clusterID2)).To(Succeed())
+		})
+
+		It("should report the drifts without modifying the NAT mappings if repair is false", func() {
+			drifts, err := ipam.CheckConsistency(isPeered, false)
+			Expect(err).To(BeNil())
+			Expect(drifts).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{"Type": Equal(MissingNatMappingDrift), "ClusterID": Equal(clusterID1)}),
+				MatchFields(IgnoreExtras, Fields{"Type": Equal(StaleNatMappingDrift), "ClusterID": Equal(clusterID2)}),
+			))
+			nm, err := getNatMappingResourcePerCluster(clusterID2)
+			Expect(err).To(BeNil())
+			Expect(nm.Spec.ClusterMappings).To(HaveKey("10.0.50.7"))
+		})
+
+		It("should align the NAT mappings if repair is true", func() {
+			_, err := ipam.CheckConsistency(isPeered, true)
+			Expect(err).To(BeNil())
+			nm, err := getNatMappingResourcePerCluster(clusterID1)
+			Expect(err).To(BeNil())
+			Expect(nm.Spec.ClusterMappings).To(HaveKeyWithValue(externalEndpointIP, mappedIP))
+			nm, err = getNatMappingResourcePerCluster(clusterID2)
+			Expect(err).To(BeNil())
+			Expect(nm.Spec.ClusterMappings).NotTo(HaveKey("10.0.50.7"))
+		})
+	})
+})
@@ -50,6 +50,9 @@ type Ipam interface {
 	- Both.
 	*/
 	GetSubnetsPerCluster(podCidr, externalCIDR, clusterID string) (string, string, error)
+	// GetSubnetsPerClusterV6 is the counterpart of GetSubnetsPerCluster for the IPv6 PodCIDR
+	// and ExternalCIDR of a remote dual-stack cluster.
+	GetSubnetsPerClusterV6(podCidr, externalCIDR, clusterID string) (string, string, error)
 	// RemoveClusterConfig deletes the IPAM configuration of a remote cluster,
 	// by freeing networks and removing data structures related to that cluster.
 	RemoveClusterConfig(clusterID string) error
@@ -57,6 +60,18 @@ type Ipam interface {
 	AcquireReservedSubnet(network string) error
 	// FreeReservedSubnet frees a network.
 	FreeReservedSubnet(network string) error
+	// SetPinnedSubnets pins the networks the PodCIDR and the ExternalCIDR of a remote cluster are remapped on,
+	// in case they conflict with the networks already in use. It does not affect the networks already
+	// assigned to the cluster, and empty values are left to the automatic selection from the pools.
+	// The networks of a cluster can be pinned by a single owner at a time.
+	SetPinnedSubnets(clusterID, owner string, pinned netv1alpha1.PinnedSubnets) error
+	// RemovePinnedSubnets removes the networks pinned for a remote cluster by the given owner.
+	RemovePinnedSubnets(clusterID, owner string) error
+	// GetTransitSubnets is the counterpart of GetSubnetsPerCluster for the IPv4 PodCIDR and ExternalCIDR
+	// of a cluster reached through a transit cluster, i.e. without a direct peering.
+	GetTransitSubnets(viaClusterID, clusterID, podCIDR, externalCIDR string) (string, string, error)
+	// RemoveTransitSubnets frees the networks assigned to a cluster reached through a transit cluster.
+	RemoveTransitSubnets(viaClusterID, clusterID string) error
 	// AddNetworkPool adds a network to the set of default network pools.
 	AddNetworkPool(network string) error
 	// RemoveNetworkPool removes a network from the set of network pools.
@@ -66,11 +81,19 @@ type Ipam interface {
 	this function must not reserve it. If the remote cluster has not remapped
 	a local subnet, then CIDR value should be equal to "None". */
 	AddLocalSubnetsPerCluster(podCIDR, externalCIDR, clusterID string) error
+	// AddLocalSubnetsPerClusterV6 is the counterpart of AddLocalSubnetsPerCluster for the IPv6 networks.
+	// It has to be called after AddLocalSubnetsPerCluster.
+	AddLocalSubnetsPerClusterV6(podCIDR, externalCIDR, clusterID string) error
 	GetExternalCIDR(mask uint8) (string, error)
-	// SetPodCIDR sets the cluster PodCIDR.
+	// GetExternalCIDRv6 chooses and returns the local cluster's IPv6 ExternalCIDR.
+	GetExternalCIDRv6(mask uint8) (string, error)
+	// SetPodCIDR sets the cluster PodCIDR. An IPv6 CIDR sets the IPv6 PodCIDR of a dual-stack cluster.
 	SetPodCIDR(podCIDR string) error
-	// SetServiceCIDR sets the cluster ServiceCIDR.
+	// SetServiceCIDR sets the cluster ServiceCIDR. An IPv6 CIDR sets the IPv6 ServiceCIDR of a dual-stack cluster.
 	SetServiceCIDR(serviceCIDR string) error
+	// CheckConsistency cross-checks the IPAM configuration against the remote clusters still peered
+	// and the NAT mappings, returning the detected drifts. Drifts are fixed if repair is true.
+	CheckConsistency(isPeered PeeringChecker, repair bool) ([]Drift, error)
 	// Terminate function enforces a graceful termination of the IPAM module.
 	Terminate()
 	IpamServer
@@ -98,6 +121,11 @@ var Pools = []string{
 	"172.16.0.0/12",
 }
 
+// PoolsV6 is a constant slice containing private IPv6 networks, used in case of dual-stack clusters.
+var PoolsV6 = []string{
+	"fd00::/8",
+}
+
 const emptyCIDR = ""
 
 // Init uses the Ipam resource to retrieve and allocate reserved networks.
@@ -126,6 +154,8 @@ func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listenin
 		if err != nil {
 			return fmt.Errorf("cannot set pools: %w", err)
 		}
+	} else if err := liqoIPAM.addMissingPoolsV6(ipamPools, pools); err != nil {
+		return err
 	}
 	if listeningPort > 0 {
 		err = liqoIPAM.initRPCServer(listeningPort)
@@ -138,6 +168,20 @@ func (liqoIPAM *IPAM) Init(pools []string, dynClient dynamic.Interface, listenin
 	return nil
 }
 
+// addMissingPoolsV6 adds the IPv6 network pools received from the caller that are not
+// part of the stored ones yet, to enable dual-stack on an already configured IPAM.
+func (liqoIPAM *IPAM) addMissingPoolsV6(ipamPools, pools []string) error {
+	for _, network := range pools {
+		if !utils.IsIPv6Network(network) || slice.ContainsString(ipamPools, network) {
+			continue
+		}
+		if err := liqoIPAM.AddNetworkPool(network); err != nil {
+			return fmt.Errorf("cannot add IPv6 network pool %s: %w", network, err)
+		}
+	}
+	return nil
+}
+
 // Terminate function stops the gRPC server.
 func (liqoIPAM *IPAM) Terminate() {
 	// Stop GRPC server
@@ -182,6 +226,13 @@ func (liqoIPAM *IPAM) reservePoolInHalves(pool string) error {
 
 // AcquireReservedSubnet marks as used the network received as parameter.
 func (liqoIPAM *IPAM) AcquireReservedSubnet(reservedNetwork string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.acquireReservedSubnet(reservedNetwork)
+}
+
+// Internal implementation of exported func AcquireReservedSubnet.
+func (liqoIPAM *IPAM) acquireReservedSubnet(reservedNetwork string) error {
 	klog.Infof("Request to reserve network %s has been received", reservedNetwork)
 	cluster, overlaps, err := liqoIPAM.overlapsWithCluster(reservedNetwork)
 	if err != nil {
@@ -245,6 +296,24 @@ func (liqoIPAM *IPAM) overlapsWithCluster(network string) (overlappingCluster st
 			return
 		}
 	}
+	// Check also the networks of the clusters reached through a transit cluster
+	for _, transitSubnets := range liqoIPAM.ipamStorage.getTransitSubnets() {
+		for cluster, subnets := range transitSubnets {
+			overlapsWithPodCIDR, err = liqoIPAM.overlapsWithNetwork(network, subnets.PodCIDR)
+			if err != nil {
+				return
+			}
+			overlapsWithExternalCIDR, err = liqoIPAM.overlapsWithNetwork(network, subnets.ExternalCIDR)
+			if err != nil {
+				return
+			}
+			if overlapsWithPodCIDR || overlapsWithExternalCIDR {
+				overlaps = true
+				overlappingCluster = cluster
+				return
+			}
+		}
+	}
 	return overlappingCluster, overlaps, err
 }
 
@@ -294,7 +363,7 @@ func (liqoIPAM *IPAM) getPoolFromNetwork(network string) (networkPool string, su
 
 func (liqoIPAM *IPAM) clusterSubnetEqualToPool(pool string) (string, error) {
 	klog.Infof("Network %s is equal to a pool, looking for a mapping..", pool)
-	mappedNetwork, err := liqoIPAM.getNetworkFromPool(utils.GetMask(pool))
+	mappedNetwork, err := liqoIPAM.getNetworkFromPool(utils.GetMask(pool), utils.IsIPv6Network(pool))
 	if err != nil {
 		klog.Infof("Mapping not found, acquiring the entire network pool..")
 		err = liqoIPAM.reservePoolInHalves(pool)
@@ -306,9 +375,21 @@ func (liqoIPAM *IPAM) clusterSubnetEqualToPool(pool string) (string, error) {
 	return mappedNetwork, nil
 }
 
+// acquirePinnedNetwork acquires the network pinned by the user to remap the received network.
+func (liqoIPAM *IPAM) acquirePinnedNetwork(network, pinned string) (string, error) {
+	if utils.GetMask(pinned) != utils.GetMask(network) {
+		return "", fmt.Errorf("pinned network %s has a mask length different from network %s", pinned, network)
+	}
+	if err := liqoIPAM.acquireReservedSubnet(pinned); err != nil {
+		return "", fmt.Errorf("cannot acquire pinned network %s:%w", pinned, err)
+	}
+	klog.Infof("Network %s successfully mapped to pinned network %s", network, pinned)
+	return pinned, nil
+}
+
 // getOrRemapNetwork first tries to acquire the received network.
-// If conflicts are found, a new mapped network is returned.
-func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
+// If conflicts are found, a new mapped network is returned: the pinned one, if not empty.
+func (liqoIPAM *IPAM) getOrRemapNetwork(network, pinned string) (string, error) {
 	var mappedNetwork string
 	klog.Infof("Allocating network %s", network)
 	// First try to get a new Prefix
@@ -332,6 +413,9 @@ func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
 		is better to look first for a mapping rather than acquire the entire network pool.
 		Consider the impact of having a network pool n completely filled and multiple clusters asking for
 		networks in n. This would create the necessity of nat-ting the traffic towards these clusters. */
+		if pinned != "" {
+			return liqoIPAM.acquirePinnedNetwork(network, pinned)
+		}
 		mappedNetwork, err = liqoIPAM.clusterSubnetEqualToPool(pool)
 		if err != nil {
 			return "", err
@@ -350,7 +434,10 @@ func (liqoIPAM *IPAM) getOrRemapNetwork(network string) (string, error) {
 		}
 	}
 	/* Network is already reserved, need a mapping */
-	mappedNetwork, err = liqoIPAM.getNetworkFromPool(utils.GetMask(network))
+	if pinned != "" {
+		return liqoIPAM.acquirePinnedNetwork(network, pinned)
+	}
+	mappedNetwork, err = liqoIPAM.getNetworkFromPool(utils.GetMask(network), utils.IsIPv6Network(network))
 	if err != nil {
 		return "", err
 	}
@@ -367,27 +454,84 @@ func (liqoIPAM *IPAM) GetSubnetsPerCluster(
 	podCidr,
 	externalCIDR,
 	clusterID string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
-	var exists bool
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.getSubnetsPerCluster(podCidr, externalCIDR, clusterID, false)
+}
+
+// GetSubnetsPerClusterV6 behaves as GetSubnetsPerCluster, but for the IPv6 PodCIDR and ExternalCIDR of a cluster.
+func (liqoIPAM *IPAM) GetSubnetsPerClusterV6(
+	podCidr,
+	externalCIDR,
+	clusterID string) (mappedPodCIDR, mappedExternalCIDR string, err error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.getSubnetsPerCluster(podCidr, externalCIDR, clusterID, true)
+}
+
+// remoteSubnets returns the fields of subnets holding the remote PodCIDR and ExternalCIDR of the given family.
+func remoteSubnets(subnets *netv1alpha1.Subnets, ipv6 bool) (podCIDR, externalCIDR *string) {
+	if ipv6 {
+		return &subnets.RemotePodCIDRv6, &subnets.RemoteExternalCIDRv6
+	}
+	return &subnets.RemotePodCIDR, &subnets.RemoteExternalCIDR
+}
 
+// localNATSubnets returns the fields of subnets holding the local NAT PodCIDR and ExternalCIDR of the given family.
+func localNATSubnets(subnets *netv1alpha1.Subnets, ipv6 bool) (podCIDR, externalCIDR *string) {
+	if ipv6 {
+		return &subnets.LocalNATPodCIDRv6, &subnets.LocalNATExternalCIDRv6
+	}
+	return &subnets.LocalNATPodCIDR, &subnets.LocalNATExternalCIDR
+}
+
+// pinnedNetworks returns the fields of pinned holding the pinned PodCIDR and ExternalCIDR of the given family.
+func pinnedNetworks(pinned *netv1alpha1.PinnedSubnets, ipv6 bool) (podCIDR, externalCIDR string) {
+	if ipv6 {
+		return pinned.PodCIDRv6, pinned.ExternalCIDRv6
+	}
+	return pinned.PodCIDR, pinned.ExternalCIDR
+}
+
+// validateCIDRFamily returns an error if the received CIDR is invalid or does not belong to the given family.
+func validateCIDRFamily(cidr string, ipv6 bool) error {
+	if err := utils.IsValidCIDR(cidr); err != nil {
+		return err
+	}
+	if utils.IsIPv6Network(cidr) != ipv6 {
+		return fmt.Errorf("network %s does not belong to the expected address family", cidr)
+	}
+	return nil
+}
+
+func (liqoIPAM *IPAM) getSubnetsPerCluster(
+	podCidr,
+	externalCIDR,
+	clusterID string, ipv6 bool) (mappedPodCIDR, mappedExternalCIDR string, err error) {
 	// Get subnets of clusters
 	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
 
 	// Check existence
 	subnets, exists := clusterSubnets[clusterID]
-	if exists && subnets.RemotePodCIDR != "" && subnets.RemoteExternalCIDR != "" {
-		return subnets.RemotePodCIDR, subnets.RemoteExternalCIDR, nil
+	remotePodCIDR, remoteExternalCIDR := remoteSubnets(&subnets, ipv6)
+	if exists && *remotePodCIDR != "" && *remoteExternalCIDR != "" {
+		return *remotePodCIDR, *remoteExternalCIDR, nil
 	}
 
 	// Check if podCidr is a valid CIDR
-	err = utils.IsValidCIDR(podCidr)
+	err = validateCIDRFamily(podCidr, ipv6)
 	if err != nil {
 		return "", "", fmt.Errorf("PodCidr is an invalid CIDR:%w", err)
 	}
 
 	klog.Infof("Cluster networks allocation request received: %s", clusterID)
 
+	// Get the networks pinned by the user, used in case a remapping is necessary
+	pinned := liqoIPAM.ipamStorage.getPinnedSubnets()[clusterID]
+	pinnedPodCIDR, pinnedExternalCIDR := pinnedNetworks(&pinned, ipv6)
+
 	// Get PodCidr
-	mappedPodCIDR, err = liqoIPAM.getOrRemapNetwork(podCidr)
+	mappedPodCIDR, err = liqoIPAM.getOrRemapNetwork(podCidr, pinnedPodCIDR)
 	if err != nil {
 		return "", "", fmt.Errorf("cannot get a PodCIDR for cluster %s:%w", clusterID, err)
 	}
@@ -395,58 +539,109 @@ func (liqoIPAM *IPAM) GetSubnetsPerCluster(
 	klog.Infof("PodCIDR %s has been assigned to cluster %s", mappedPodCIDR, clusterID)
 
 	// Check if externalCIDR is a valid CIDR
-	err = utils.IsValidCIDR(externalCIDR)
+	err = validateCIDRFamily(externalCIDR, ipv6)
 	if err != nil {
 		return "", "", fmt.Errorf("ExternalCIDR is an invalid CIDR:%w", err)
 	}
 
 	// Get ExternalCIDR
-	mappedExternalCIDR, err = liqoIPAM.getOrRemapNetwork(externalCIDR)
+	mappedExternalCIDR, err = liqoIPAM.getOrRemapNetwork(externalCIDR, pinnedExternalCIDR)
 	if err != nil {
-		_ = liqoIPAM.FreeReservedSubnet(mappedPodCIDR)
+		_ = liqoIPAM.freeReservedSubnet(mappedPodCIDR)
 		return "", "", fmt.Errorf("cannot get an ExternalCIDR for cluster %s:%w", clusterID, err)
 	}
 
 	klog.Infof("ExternalCIDR %s has been assigned to cluster %s", mappedExternalCIDR, clusterID)
 
-	if !exists {
-		// Create cluster network configuration
-		subnets = netv1alpha1.Subnets{
-			LocalNATPodCIDR:      "",
-			RemotePodCIDR:        mappedPodCIDR,
-			RemoteExternalCIDR:   mappedExternalCIDR,
-			LocalNATExternalCIDR: "",
-		}
-	} else {
-		// Update cluster network configuration
-		subnets.RemotePodCIDR = mappedPodCIDR
-		subnets.RemoteExternalCIDR = mappedExternalCIDR
-	}
+	// Create or update cluster network configuration
+	*remotePodCIDR = mappedPodCIDR
+	*remoteExternalCIDR = mappedExternalCIDR
 	clusterSubnets[clusterID] = subnets
 
 	// Push it in clusterSubnets
 	if err := liqoIPAM.ipamStorage.updateClusterSubnets(clusterSubnets); err != nil {
-		_ = liqoIPAM.FreeReservedSubnet(mappedPodCIDR)
-		_ = liqoIPAM.FreeReservedSubnet(mappedExternalCIDR)
+		_ = liqoIPAM.freeReservedSubnet(mappedPodCIDR)
+		_ = liqoIPAM.freeReservedSubnet(mappedExternalCIDR)
 		return "", "", fmt.Errorf("cannot update cluster subnets:%w", err)
 	}
 	return mappedPodCIDR, mappedExternalCIDR, nil
 }
 
-// getNetworkFromPool returns a network with mask length equal to mask taken by a network pool.
-func (liqoIPAM *IPAM) getNetworkFromPool(mask uint8) (string, error) {
+// getNetworkFromPool returns a network with mask length equal to mask taken by a network pool
+// of the requested address family. Networks overlapping with the ones pinned by the user are skipped:
+// a free candidate is computed in advance, to acquire only the network actually returned.
+func (liqoIPAM *IPAM) getNetworkFromPool(mask uint8, ipv6 bool) (string, error) {
+	pinned, err := liqoIPAM.pinnedPrefixes()
+	if err != nil {
+		return "", err
+	}
+
 	// Get network pools
 	pools := liqoIPAM.ipamStorage.getPools()
 	// For each pool, try to get a network with mask length mask
 	for _, pool := range pools {
-		if mappedNetwork, err := liqoIPAM.ipam.AcquireChildPrefix(pool, mask); err == nil {
-			klog.Infof("Acquired network %s", mappedNetwork)
-			return mappedNetwork.String(), nil
+		if utils.IsIPv6Network(pool) != ipv6 {
+			continue
+		}
+		candidate, found, err := liqoIPAM.freeNetworkInPool(pool, mask, pinned)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			continue
 		}
+		mappedNetwork, err := liqoIPAM.ipam.AcquireSpecificChildPrefix(pool, candidate)
+		if err != nil {
+			return "", fmt.Errorf("cannot acquire network %s from pool %s: %w", candidate, pool, err)
+		}
+		klog.Infof("Acquired network %s", mappedNetwork)
+		return mappedNetwork.String(), nil
 	}
 	return "", fmt.Errorf("no networks available")
 }
 
+// freeNetworkInPool returns the first network with mask length equal to mask which is available in the given pool
+// and does not overlap with the pinned networks, without acquiring it.
+func (liqoIPAM *IPAM) freeNetworkInPool(pool string, mask uint8, pinned []netaddr.IPPrefix) (string, bool, error) {
+	prefix := liqoIPAM.ipam.PrefixFrom(pool)
+	if prefix == nil {
+		return "", false, nil
+	}
+
+	var free netaddr.IPSetBuilder
+	for _, available := range prefix.Usage().AvailablePrefixes {
+		p, err := netaddr.ParseIPPrefix(available)
+		if err != nil {
+			return "", false, fmt.Errorf("cannot parse network %s available in pool %s: %w", available, pool, err)
+		}
+		free.AddPrefix(p)
+	}
+	for _, p := range pinned {
+		free.RemovePrefix(p)
+	}
+
+	candidate, _, found := free.IPSet().RemoveFreePrefix(mask)
+	if !found {
+		return "", false, nil
+	}
+	return candidate.String(), true, nil
+}
+
+// pinnedPrefixes returns the networks pinned by the user for all the remote clusters.
+func (liqoIPAM *IPAM) pinnedPrefixes() ([]netaddr.IPPrefix, error) {
+	var prefixes []netaddr.IPPrefix
+	for _, pinned := range liqoIPAM.ipamStorage.getPinnedSubnets() {
+		for _, network := range pinnedNetworkList(pinned) {
+			p, err := netaddr.ParseIPPrefix(network)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse pinned network %s: %w", network, err)
+			}
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes, nil
+}
+
 func (liqoIPAM *IPAM) freePoolInHalves(pool string) error {
 	// Get halves mask length
 	mask := utils.GetMask(pool)
@@ -480,6 +675,13 @@ func (liqoIPAM *IPAM) freePoolInHalves(pool string) error {
 
 // FreeReservedSubnet marks as free a reserved subnet.
 func (liqoIPAM *IPAM) FreeReservedSubnet(network string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.freeReservedSubnet(network)
+}
+
+// Internal implementation of exported func FreeReservedSubnet.
+func (liqoIPAM *IPAM) freeReservedSubnet(network string) error {
 	var p *goipam.Prefix
 
 	// Check existence
@@ -519,7 +721,11 @@ func (liqoIPAM *IPAM) eventuallyDeleteClusterSubnet(clusterID string,
 	if subnets.RemotePodCIDR == "" &&
 		subnets.LocalNATPodCIDR == "" &&
 		subnets.RemoteExternalCIDR == "" &&
-		subnets.LocalNATExternalCIDR == "" {
+		subnets.LocalNATExternalCIDR == "" &&
+		subnets.RemotePodCIDRv6 == "" &&
+		subnets.LocalNATPodCIDRv6 == "" &&
+		subnets.RemoteExternalCIDRv6 == "" &&
+		subnets.LocalNATExternalCIDRv6 == "" {
 		// Delete entry
 		delete(clusterSubnets, clusterID)
 	}
@@ -533,6 +739,13 @@ func (liqoIPAM *IPAM) eventuallyDeleteClusterSubnet(clusterID string,
 // RemoveClusterConfig frees remote PodCIDR and ExternalCIDR and
 // deletes local subnets for the remote cluster.
 func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.removeClusterConfig(clusterID)
+}
+
+// Internal implementation of exported func RemoveClusterConfig.
+func (liqoIPAM *IPAM) removeClusterConfig(clusterID string) error {
 	var subnets netv1alpha1.Subnets
 	var subnetsExist, natMappingsPerClusterConfigured bool
 
@@ -543,6 +756,11 @@ func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
 		}
 	}
 
+	// Free the networks of the clusters reached through the remote one
+	if err := liqoIPAM.removeTransitSubnetsVia(clusterID); err != nil {
+		return fmt.Errorf("cannot free the networks of the clusters reached through cluster %s:%w", clusterID, err)
+	}
+
 	// Get cluster subnets
 	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
 
@@ -560,14 +778,24 @@ func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
 	// re-executing the following block.
 	if subnetsExist {
 		// Free PodCidr
-		if err := liqoIPAM.FreeReservedSubnet(subnets.RemotePodCIDR); err != nil {
+		if err := liqoIPAM.freeReservedSubnet(subnets.RemotePodCIDR); err != nil {
 			return err
 		}
 
 		// Free ExternalCidr
-		if err := liqoIPAM.FreeReservedSubnet(subnets.RemoteExternalCIDR); err != nil {
+		if err := liqoIPAM.freeReservedSubnet(subnets.RemoteExternalCIDR); err != nil {
 			return err
 		}
+
+		// Free IPv6 PodCidr and ExternalCidr, if any
+		for _, network := range []string{subnets.RemotePodCIDRv6, subnets.RemoteExternalCIDRv6} {
+			if network == "" {
+				continue
+			}
+			if err := liqoIPAM.freeReservedSubnet(network); err != nil {
+				return err
+			}
+		}
 		klog.Infof("Networks assigned to cluster %s have just been freed", clusterID)
 
 		delete(clusterSubnets, clusterID)
@@ -583,7 +811,6 @@ func (liqoIPAM *IPAM) RemoveClusterConfig(clusterID string) error {
 
 	delete(natMappingsConfigured, clusterID)
 	// Update natMappingsConfigured
-	natMappingsConfigured[clusterID] = netv1alpha1.ConfiguredCluster{}
 	if err := liqoIPAM.ipamStorage.updateNatMappingsConfigured(natMappingsConfigured); err != nil {
 		return fmt.Errorf("unable to update NatMappingsConfigured: %w", err)
 	}
@@ -638,7 +865,7 @@ func (liqoIPAM *IPAM) terminateNatMappingsPerCluster(clusterID string) error {
 			// There are no more clusters using this endpoint IP
 
 			// Get local ExternalCIDR
-			localExternalCIDR := liqoIPAM.ipamStorage.getExternalCIDR()
+			localExternalCIDR := liqoIPAM.localExternalCIDR(ip)
 			if localExternalCIDR == emptyCIDR {
 				return fmt.Errorf("cannot get ExternalCIDR: %w", err)
 			}
@@ -724,7 +951,7 @@ func (liqoIPAM *IPAM) RemoveNetworkPool(network string) error {
 		return fmt.Errorf("network %s is not a network pool", network)
 	}
 	// Cannot remove a default one
-	if contains := slice.ContainsString(Pools, network); contains {
+	if slice.ContainsString(Pools, network) || slice.ContainsString(PoolsV6, network) {
 		return fmt.Errorf("cannot remove a default network pool")
 	}
 	// Check overlapping with cluster networks
@@ -766,6 +993,9 @@ func (liqoIPAM *IPAM) RemoveNetworkPool(network string) error {
 // AddLocalSubnetsPerCluster stores how the PodCIDR and the ExternalCIDR of local cluster
 // has been remapped in a remote cluster. If no remapping happened, then the CIDR value should be equal to "None".
 func (liqoIPAM *IPAM) AddLocalSubnetsPerCluster(podCIDR, externalCIDR, clusterID string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
 	var subnetsExist, natMappingsPerClusterConfigured bool
 	var subnets netv1alpha1.Subnets
 	if clusterID == "" {
@@ -826,6 +1056,48 @@ func (liqoIPAM *IPAM) AddLocalSubnetsPerCluster(podCIDR, externalCIDR, clusterID
 	return nil
 }
 
+// AddLocalSubnetsPerClusterV6 stores how the IPv6 PodCIDR and ExternalCIDR of local cluster
+// have been remapped in a remote dual-stack cluster. The NAT mappings of the cluster are shared
+// between the address families, hence AddLocalSubnetsPerCluster has to be called first.
+func (liqoIPAM *IPAM) AddLocalSubnetsPerClusterV6(podCIDR, externalCIDR, clusterID string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	if clusterID == "" {
+		return &liqoneterrors.WrongParameter{
+			Parameter: consts.ClusterIDLabelName,
+			Reason:    liqoneterrors.StringNotEmpty,
+		}
+	}
+
+	// Get cluster subnets
+	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
+	subnets, exists := clusterSubnets[clusterID]
+	if !exists || subnets.RemotePodCIDRv6 == "" {
+		return fmt.Errorf("remote IPv6 subnets for cluster %s do not exist yet. Call first GetSubnetsPerClusterV6",
+			clusterID)
+	}
+	if _, configured := liqoIPAM.ipamStorage.getNatMappingsConfigured()[clusterID]; !configured {
+		return fmt.Errorf("NAT mappings for cluster %s have not been initialized yet. Call first AddLocalSubnetsPerCluster",
+			clusterID)
+	}
+	if subnets.LocalNATPodCIDRv6 == podCIDR && subnets.LocalNATExternalCIDRv6 == externalCIDR {
+		return nil
+	}
+
+	subnets.LocalNATPodCIDRv6 = podCIDR
+	subnets.LocalNATExternalCIDRv6 = externalCIDR
+	clusterSubnets[clusterID] = subnets
+	klog.Infof("Local NAT IPv6 PodCIDR of cluster %s set to %s", clusterID, podCIDR)
+	klog.Infof("Local NAT IPv6 ExternalCIDR of cluster %s set to %s", clusterID, externalCIDR)
+
+	// Push it in clusterSubnets
+	if err := liqoIPAM.ipamStorage.updateClusterSubnets(clusterSubnets); err != nil {
+		return fmt.Errorf("cannot update cluster subnets:%w", err)
+	}
+	return nil
+}
+
 // RemoveLocalSubnetsPerCluster deletes networks related to a cluster.
 func (liqoIPAM *IPAM) RemoveLocalSubnetsPerCluster(clusterID string) error {
 	var exists bool
@@ -835,13 +1107,16 @@ func (liqoIPAM *IPAM) RemoveLocalSubnetsPerCluster(clusterID string) error {
 	clusterSubnets := liqoIPAM.ipamStorage.getClusterSubnets()
 	// Check existence
 	subnets, exists = clusterSubnets[clusterID]
-	if !exists || (subnets.LocalNATPodCIDR == "" && subnets.LocalNATExternalCIDR == "") {
+	if !exists || (subnets.LocalNATPodCIDR == "" && subnets.LocalNATExternalCIDR == "" &&
+		subnets.LocalNATPodCIDRv6 == "" && subnets.LocalNATExternalCIDRv6 == "") {
 		return nil
 	}
 
 	// Unset networks
 	subnets.LocalNATPodCIDR = ""
 	subnets.LocalNATExternalCIDR = ""
+	subnets.LocalNATPodCIDRv6 = ""
+	subnets.LocalNATExternalCIDRv6 = ""
 	clusterSubnets[clusterID] = subnets
 
 	klog.Infof("Local NAT networks of cluster %s deleted", clusterID)
@@ -861,16 +1136,44 @@ func (liqoIPAM *IPAM) GetExternalCIDR(mask uint8) (string, error) {
 	if externalCIDR != "" {
 		return externalCIDR, nil
 	}
-	if externalCIDR, err = liqoIPAM.getNetworkFromPool(mask); err != nil {
+	if externalCIDR, err = liqoIPAM.getNetworkFromPool(mask, false); err != nil {
 		return "", fmt.Errorf("cannot allocate an ExternalCIDR:%w", err)
 	}
 	if err := liqoIPAM.ipamStorage.updateExternalCIDR(externalCIDR); err != nil {
-		_ = liqoIPAM.FreeReservedSubnet(externalCIDR)
+		_ = liqoIPAM.freeReservedSubnet(externalCIDR)
 		return "", fmt.Errorf("cannot update ExternalCIDR:%w", err)
 	}
 	return externalCIDR, nil
 }
 
+// GetExternalCIDRv6 chooses and returns the local cluster's IPv6 ExternalCIDR.
+func (liqoIPAM *IPAM) GetExternalCIDRv6(mask uint8) (string, error) {
+	var externalCIDR string
+	var err error
+
+	// Get cluster IPv6 ExternalCIDR
+	externalCIDR = liqoIPAM.ipamStorage.getExternalCIDRv6()
+	if externalCIDR != "" {
+		return externalCIDR, nil
+	}
+	if externalCIDR, err = liqoIPAM.getNetworkFromPool(mask, true); err != nil {
+		return "", fmt.Errorf("cannot allocate an IPv6 ExternalCIDR:%w", err)
+	}
+	if err := liqoIPAM.ipamStorage.updateExternalCIDRv6(externalCIDR); err != nil {
+		_ = liqoIPAM.freeReservedSubnet(externalCIDR)
+		return "", fmt.Errorf("cannot update IPv6 ExternalCIDR:%w", err)
+	}
+	return externalCIDR, nil
+}
+
+// localExternalCIDR returns the local ExternalCIDR of the same address family of the given IP.
+func (liqoIPAM *IPAM) localExternalCIDR(ip string) string {
+	if utils.IsIPv6(ip) {
+		return liqoIPAM.ipamStorage.getExternalCIDRv6()
+	}
+	return liqoIPAM.ipamStorage.getExternalCIDR()
+}
+
 // Function that receives an IP and a network and returns true if
 // the IP address does belong to the network.
 func ipBelongsToNetwork(ip, network string) (bool, error) {
@@ -891,7 +1194,10 @@ func (liqoIPAM *IPAM) mapIPToExternalCIDR(clusterID, remoteExternalCIDR, ip stri
 	endpointMappings := liqoIPAM.ipamStorage.getEndpointMappings()
 
 	// Get local ExternalCIDR
-	localExternalCIDR := liqoIPAM.ipamStorage.getExternalCIDR()
+	localExternalCIDR := liqoIPAM.localExternalCIDR(ip)
+	if localExternalCIDR == emptyCIDR {
+		return "", fmt.Errorf("cannot get the local ExternalCIDR for endpoint %s", ip)
+	}
 
 	if remoteExternalCIDR == "None" {
 		externalCIDR = localExternalCIDR
@@ -963,11 +1269,15 @@ func (liqoIPAM *IPAM) mapEndpointIPInternal(clusterID, ip string) (string, error
 		return "", fmt.Errorf("cluster %s has not a network configuration", clusterID)
 	}
 
-	// Get PodCIDR
+	// Get PodCIDR and the local NAT networks of the same family of the IP
 	podCIDR := liqoIPAM.ipamStorage.getPodCIDR()
+	if utils.IsIPv6(ip) {
+		podCIDR = liqoIPAM.ipamStorage.getPodCIDRv6()
+	}
 	if podCIDR == emptyCIDR {
 		return "", fmt.Errorf("cannot get cluster PodCIDR: %w", err)
 	}
+	localNATPodCIDR, localNATExternalCIDR := localNATSubnets(&subnets, utils.IsIPv6(ip))
 
 	belongs, err := ipBelongsToNetwork(ip, podCIDR)
 	if err != nil {
@@ -977,7 +1287,7 @@ func (liqoIPAM *IPAM) mapEndpointIPInternal(clusterID, ip string) (string, error
 		/* IP belongs to local PodCIDR, this means the Pod is a local Pod and
 		the new IP should belong to the network used in the remote cluster
 		for local Pods: this can be either the cluster PodCIDR or a different network */
-		newIP, err := utils.MapIPToNetwork(subnets.LocalNATPodCIDR, ip)
+		newIP, err := utils.MapIPToNetwork(*localNATPodCIDR, ip)
 		if err != nil {
 			return "", fmt.Errorf("cannot map endpoint IP %s to PodCIDR of remote cluster %s:%w", ip, clusterID, err)
 		}
@@ -986,7 +1296,7 @@ func (liqoIPAM *IPAM) mapEndpointIPInternal(clusterID, ip string) (string, error
 	// IP does not belong to cluster PodCIDR: Pod is a reflected Pod
 
 	// Map IP to ExternalCIDR
-	newIP, err := liqoIPAM.mapIPToExternalCIDR(clusterID, subnets.LocalNATExternalCIDR, ip)
+	newIP, err := liqoIPAM.mapIPToExternalCIDR(clusterID, *localNATExternalCIDR, ip)
 	if err != nil {
 		return "", fmt.Errorf("cannot map endpoint IP %s to ExternalCIDR of cluster %s:%w", ip, clusterID, err)
 	}
@@ -1063,13 +1373,14 @@ func (liqoIPAM *IPAM) getHomePodIPInternal(clusterID, ip string) (string, error)
 		return "", fmt.Errorf("cluster %s subnets are not set", clusterID)
 	}
 
-	if subnets.RemotePodCIDR == "" {
+	remotePodCIDR, _ := remoteSubnets(&subnets, utils.IsIPv6(ip))
+	if *remotePodCIDR == "" {
 		return "", &liqoneterrors.WrongParameter{
 			Reason: liqoneterrors.StringNotEmpty,
 		}
 	}
 
-	return utils.MapIPToNetwork(subnets.RemotePodCIDR, ip)
+	return utils.MapIPToNetwork(*remotePodCIDR, ip)
 }
 
 // unmapEndpointIPInternal is the internal implementation of UnmapEndpointIP.
@@ -1089,7 +1400,7 @@ func (liqoIPAM *IPAM) unmapEndpointIPInternal(clusterID, endpointIP string) erro
 	endpointMappings := liqoIPAM.ipamStorage.getEndpointMappings()
 
 	// Get local ExternalCIDR
-	localExternalCIDR := liqoIPAM.ipamStorage.getExternalCIDR()
+	localExternalCIDR := liqoIPAM.localExternalCIDR(endpointIP)
 
 	endpointMapping, exists := endpointMappings[endpointIP]
 	if !exists {
@@ -1144,12 +1455,16 @@ func (liqoIPAM *IPAM) UnmapEndpointIP(ctx context.Context, unmapRequest *UnmapRe
 	return &UnmapResponse{}, nil
 }
 
-// SetPodCIDR sets the PodCIDR.
+// SetPodCIDR sets the PodCIDR, or the IPv6 PodCIDR if the received network is an IPv6 one.
 func (liqoIPAM *IPAM) SetPodCIDR(podCIDR string) error {
 	var oldPodCIDR string
+	getPodCIDR, updatePodCIDR := liqoIPAM.ipamStorage.getPodCIDR, liqoIPAM.ipamStorage.updatePodCIDR
+	if utils.IsIPv6Network(podCIDR) {
+		getPodCIDR, updatePodCIDR = liqoIPAM.ipamStorage.getPodCIDRv6, liqoIPAM.ipamStorage.updatePodCIDRv6
+	}
 
 	// Get PodCIDR
-	oldPodCIDR = liqoIPAM.ipamStorage.getPodCIDR()
+	oldPodCIDR = getPodCIDR()
 	if oldPodCIDR != "" && oldPodCIDR != podCIDR {
 		return fmt.Errorf("trying to change PodCIDR")
 	}
@@ -1157,22 +1472,26 @@ func (liqoIPAM *IPAM) SetPodCIDR(podCIDR string) error {
 		return nil
 	}
 	// Acquire PodCIDR
-	if err := liqoIPAM.AcquireReservedSubnet(podCIDR); err != nil {
+	if err := liqoIPAM.acquireReservedSubnet(podCIDR); err != nil {
 		return fmt.Errorf("cannot acquire PodCIDR:%w", err)
 	}
 	// Update PodCIDR
-	if err := liqoIPAM.ipamStorage.updatePodCIDR(podCIDR); err != nil {
+	if err := updatePodCIDR(podCIDR); err != nil {
 		return fmt.Errorf("cannot set PodCIDR:%w", err)
 	}
 	return nil
 }
 
-// SetServiceCIDR sets the ServiceCIDR.
+// SetServiceCIDR sets the ServiceCIDR, or the IPv6 ServiceCIDR if the received network is an IPv6 one.
 func (liqoIPAM *IPAM) SetServiceCIDR(serviceCIDR string) error {
 	var oldServiceCIDR string
+	getServiceCIDR, updateServiceCIDR := liqoIPAM.ipamStorage.getServiceCIDR, liqoIPAM.ipamStorage.updateServiceCIDR
+	if utils.IsIPv6Network(serviceCIDR) {
+		getServiceCIDR, updateServiceCIDR = liqoIPAM.ipamStorage.getServiceCIDRv6, liqoIPAM.ipamStorage.updateServiceCIDRv6
+	}
 
 	// Get ServiceCIDR
-	oldServiceCIDR = liqoIPAM.ipamStorage.getServiceCIDR()
+	oldServiceCIDR = getServiceCIDR()
 	if oldServiceCIDR != "" && oldServiceCIDR != serviceCIDR {
 		return fmt.Errorf("trying to change ServiceCIDR")
 	}
@@ -1180,11 +1499,11 @@ func (liqoIPAM *IPAM) SetServiceCIDR(serviceCIDR string) error {
 		return nil
 	}
 	// Acquire Service CIDR
-	if err := liqoIPAM.AcquireReservedSubnet(serviceCIDR); err != nil {
+	if err := liqoIPAM.acquireReservedSubnet(serviceCIDR); err != nil {
 		return fmt.Errorf("cannot acquire ServiceCIDR:%w", err)
 	}
 	// Update Service CIDR
-	if err := liqoIPAM.ipamStorage.updateServiceCIDR(serviceCIDR); err != nil {
+	if err := updateServiceCIDR(serviceCIDR); err != nil {
 		return fmt.Errorf("cannot set ServiceCIDR:%w", err)
 	}
 	return nil
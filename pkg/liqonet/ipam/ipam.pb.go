@@ -318,6 +318,558 @@ func (x *GetHomePodIPResponse) GetHomeIP() string {
 	return ""
 }
 
+// An empty clusterID selects all the remote clusters.
+type ListClusterSubnetsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClusterID string `protobuf:"bytes,1,opt,name=clusterID,proto3" json:"clusterID,omitempty"`
+}
+
+func (x *ListClusterSubnetsRequest) Reset() {
+	*x = ListClusterSubnetsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListClusterSubnetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClusterSubnetsRequest) ProtoMessage() {}
+
+func (x *ListClusterSubnetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClusterSubnetsRequest.ProtoReflect.Descriptor instead.
+func (*ListClusterSubnetsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListClusterSubnetsRequest) GetClusterID() string {
+	if x != nil {
+		return x.ClusterID
+	}
+	return ""
+}
+
+type ClusterSubnets struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClusterID              string `protobuf:"bytes,1,opt,name=clusterID,proto3" json:"clusterID,omitempty"`
+	RemotePodCIDR          string `protobuf:"bytes,2,opt,name=remotePodCIDR,proto3" json:"remotePodCIDR,omitempty"`
+	RemoteExternalCIDR     string `protobuf:"bytes,3,opt,name=remoteExternalCIDR,proto3" json:"remoteExternalCIDR,omitempty"`
+	LocalNATPodCIDR        string `protobuf:"bytes,4,opt,name=localNATPodCIDR,proto3" json:"localNATPodCIDR,omitempty"`
+	LocalNATExternalCIDR   string `protobuf:"bytes,5,opt,name=localNATExternalCIDR,proto3" json:"localNATExternalCIDR,omitempty"`
+	RemotePodCIDRv6        string `protobuf:"bytes,6,opt,name=remotePodCIDRv6,proto3" json:"remotePodCIDRv6,omitempty"`
+	RemoteExternalCIDRv6   string `protobuf:"bytes,7,opt,name=remoteExternalCIDRv6,proto3" json:"remoteExternalCIDRv6,omitempty"`
+	LocalNATPodCIDRv6      string `protobuf:"bytes,8,opt,name=localNATPodCIDRv6,proto3" json:"localNATPodCIDRv6,omitempty"`
+	LocalNATExternalCIDRv6 string `protobuf:"bytes,9,opt,name=localNATExternalCIDRv6,proto3" json:"localNATExternalCIDRv6,omitempty"`
+}
+
+func (x *ClusterSubnets) Reset() {
+	*x = ClusterSubnets{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClusterSubnets) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClusterSubnets) ProtoMessage() {}
+
+func (x *ClusterSubnets) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClusterSubnets.ProtoReflect.Descriptor instead.
+func (*ClusterSubnets) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ClusterSubnets) GetClusterID() string {
+	if x != nil {
+		return x.ClusterID
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetRemotePodCIDR() string {
+	if x != nil {
+		return x.RemotePodCIDR
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetRemoteExternalCIDR() string {
+	if x != nil {
+		return x.RemoteExternalCIDR
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetLocalNATPodCIDR() string {
+	if x != nil {
+		return x.LocalNATPodCIDR
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetLocalNATExternalCIDR() string {
+	if x != nil {
+		return x.LocalNATExternalCIDR
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetRemotePodCIDRv6() string {
+	if x != nil {
+		return x.RemotePodCIDRv6
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetRemoteExternalCIDRv6() string {
+	if x != nil {
+		return x.RemoteExternalCIDRv6
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetLocalNATPodCIDRv6() string {
+	if x != nil {
+		return x.LocalNATPodCIDRv6
+	}
+	return ""
+}
+
+func (x *ClusterSubnets) GetLocalNATExternalCIDRv6() string {
+	if x != nil {
+		return x.LocalNATExternalCIDRv6
+	}
+	return ""
+}
+
+type ListClusterSubnetsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClusterSubnets []*ClusterSubnets `protobuf:"bytes,1,rep,name=clusterSubnets,proto3" json:"clusterSubnets,omitempty"`
+}
+
+func (x *ListClusterSubnetsResponse) Reset() {
+	*x = ListClusterSubnetsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListClusterSubnetsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClusterSubnetsResponse) ProtoMessage() {}
+
+func (x *ListClusterSubnetsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClusterSubnetsResponse.ProtoReflect.Descriptor instead.
+func (*ListClusterSubnetsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListClusterSubnetsResponse) GetClusterSubnets() []*ClusterSubnets {
+	if x != nil {
+		return x.ClusterSubnets
+	}
+	return nil
+}
+
+type ListPoolsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListPoolsRequest) Reset() {
+	*x = ListPoolsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListPoolsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoolsRequest) ProtoMessage() {}
+
+func (x *ListPoolsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoolsRequest.ProtoReflect.Descriptor instead.
+func (*ListPoolsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{9}
+}
+
+type Pool struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Network           string   `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	AllocatedNetworks []string `protobuf:"bytes,2,rep,name=allocatedNetworks,proto3" json:"allocatedNetworks,omitempty"`
+}
+
+func (x *Pool) Reset() {
+	*x = Pool{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pool) ProtoMessage() {}
+
+func (x *Pool) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pool.ProtoReflect.Descriptor instead.
+func (*Pool) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Pool) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Pool) GetAllocatedNetworks() []string {
+	if x != nil {
+		return x.AllocatedNetworks
+	}
+	return nil
+}
+
+type ListPoolsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pools          []*Pool `protobuf:"bytes,1,rep,name=pools,proto3" json:"pools,omitempty"`
+	PodCIDR        string  `protobuf:"bytes,2,opt,name=podCIDR,proto3" json:"podCIDR,omitempty"`
+	ServiceCIDR    string  `protobuf:"bytes,3,opt,name=serviceCIDR,proto3" json:"serviceCIDR,omitempty"`
+	ExternalCIDR   string  `protobuf:"bytes,4,opt,name=externalCIDR,proto3" json:"externalCIDR,omitempty"`
+	PodCIDRv6      string  `protobuf:"bytes,5,opt,name=podCIDRv6,proto3" json:"podCIDRv6,omitempty"`
+	ServiceCIDRv6  string  `protobuf:"bytes,6,opt,name=serviceCIDRv6,proto3" json:"serviceCIDRv6,omitempty"`
+	ExternalCIDRv6 string  `protobuf:"bytes,7,opt,name=externalCIDRv6,proto3" json:"externalCIDRv6,omitempty"`
+}
+
+func (x *ListPoolsResponse) Reset() {
+	*x = ListPoolsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListPoolsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoolsResponse) ProtoMessage() {}
+
+func (x *ListPoolsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoolsResponse.ProtoReflect.Descriptor instead.
+func (*ListPoolsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListPoolsResponse) GetPools() []*Pool {
+	if x != nil {
+		return x.Pools
+	}
+	return nil
+}
+
+func (x *ListPoolsResponse) GetPodCIDR() string {
+	if x != nil {
+		return x.PodCIDR
+	}
+	return ""
+}
+
+func (x *ListPoolsResponse) GetServiceCIDR() string {
+	if x != nil {
+		return x.ServiceCIDR
+	}
+	return ""
+}
+
+func (x *ListPoolsResponse) GetExternalCIDR() string {
+	if x != nil {
+		return x.ExternalCIDR
+	}
+	return ""
+}
+
+func (x *ListPoolsResponse) GetPodCIDRv6() string {
+	if x != nil {
+		return x.PodCIDRv6
+	}
+	return ""
+}
+
+func (x *ListPoolsResponse) GetServiceCIDRv6() string {
+	if x != nil {
+		return x.ServiceCIDRv6
+	}
+	return ""
+}
+
+func (x *ListPoolsResponse) GetExternalCIDRv6() string {
+	if x != nil {
+		return x.ExternalCIDRv6
+	}
+	return ""
+}
+
+// An empty clusterID selects the mappings of all the remote clusters.
+type ListEndpointMappingsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ClusterID string `protobuf:"bytes,1,opt,name=clusterID,proto3" json:"clusterID,omitempty"`
+}
+
+func (x *ListEndpointMappingsRequest) Reset() {
+	*x = ListEndpointMappingsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListEndpointMappingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEndpointMappingsRequest) ProtoMessage() {}
+
+func (x *ListEndpointMappingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEndpointMappingsRequest.ProtoReflect.Descriptor instead.
+func (*ListEndpointMappingsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListEndpointMappingsRequest) GetClusterID() string {
+	if x != nil {
+		return x.ClusterID
+	}
+	return ""
+}
+
+type EndpointMapping struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ip             string   `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	ExternalCIDRIP string   `protobuf:"bytes,2,opt,name=externalCIDRIP,proto3" json:"externalCIDRIP,omitempty"`
+	ClusterIDs     []string `protobuf:"bytes,3,rep,name=clusterIDs,proto3" json:"clusterIDs,omitempty"`
+}
+
+func (x *EndpointMapping) Reset() {
+	*x = EndpointMapping{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EndpointMapping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointMapping) ProtoMessage() {}
+
+func (x *EndpointMapping) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointMapping.ProtoReflect.Descriptor instead.
+func (*EndpointMapping) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *EndpointMapping) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *EndpointMapping) GetExternalCIDRIP() string {
+	if x != nil {
+		return x.ExternalCIDRIP
+	}
+	return ""
+}
+
+func (x *EndpointMapping) GetClusterIDs() []string {
+	if x != nil {
+		return x.ClusterIDs
+	}
+	return nil
+}
+
+type ListEndpointMappingsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EndpointMappings []*EndpointMapping `protobuf:"bytes,1,rep,name=endpointMappings,proto3" json:"endpointMappings,omitempty"`
+}
+
+func (x *ListEndpointMappingsResponse) Reset() {
+	*x = ListEndpointMappingsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListEndpointMappingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEndpointMappingsResponse) ProtoMessage() {}
+
+func (x *ListEndpointMappingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_liqonet_ipam_ipam_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEndpointMappingsResponse.ProtoReflect.Descriptor instead.
+func (*ListEndpointMappingsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListEndpointMappingsResponse) GetEndpointMappings() []*EndpointMapping {
+	if x != nil {
+		return x.EndpointMappings
+	}
+	return nil
+}
+
 var File_pkg_liqonet_ipam_ipam_proto protoreflect.FileDescriptor
 
 var file_pkg_liqonet_ipam_ipam_proto_rawDesc = []byte{
@@ -340,19 +892,106 @@ var file_pkg_liqonet_ipam_ipam_proto_rawDesc = []byte{
 	0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x70, 0x22, 0x2e, 0x0a, 0x14,
 	0x47, 0x65, 0x74, 0x48, 0x6f, 0x6d, 0x65, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x52, 0x65, 0x73, 0x70,
 	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x6f, 0x6d, 0x65, 0x49, 0x50, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x68, 0x6f, 0x6d, 0x65, 0x49, 0x50, 0x32, 0xa1, 0x01, 0x0a,
-	0x04, 0x69, 0x70, 0x61, 0x6d, 0x12, 0x2a, 0x0a, 0x0d, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x64, 0x70,
-	0x6f, 0x69, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x0b, 0x2e, 0x4d, 0x61, 0x70, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x4d, 0x61, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x30, 0x0a, 0x0f, 0x55, 0x6e, 0x6d, 0x61, 0x70, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69,
-	0x6e, 0x74, 0x49, 0x50, 0x12, 0x0d, 0x2e, 0x55, 0x6e, 0x6d, 0x61, 0x70, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x55, 0x6e, 0x6d, 0x61, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x48, 0x6f, 0x6d, 0x65, 0x50, 0x6f,
-	0x64, 0x49, 0x50, 0x12, 0x14, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x6f, 0x6d, 0x65, 0x50, 0x6f, 0x64,
-	0x49, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x47, 0x65, 0x74, 0x48,
-	0x6f, 0x6d, 0x65, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x42, 0x08, 0x5a, 0x06, 0x2e, 0x2f, 0x69, 0x70, 0x61, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x68, 0x6f, 0x6d, 0x65, 0x49, 0x50, 0x22, 0x39, 0x0a, 0x19,
+	0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6c,
+	0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x22, 0xa6, 0x03, 0x0a, 0x0e, 0x43, 0x6c, 0x75, 0x73,
+	0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6c,
+	0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63,
+	0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x12, 0x24, 0x0a, 0x0d, 0x72, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x12, 0x2e,
+	0x0a, 0x12, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c,
+	0x43, 0x49, 0x44, 0x52, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x72, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x12, 0x28,
+	0x0a, 0x0f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x4e, 0x41, 0x54, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44,
+	0x52, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x4e, 0x41,
+	0x54, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x12, 0x32, 0x0a, 0x14, 0x6c, 0x6f, 0x63, 0x61,
+	0x6c, 0x4e, 0x41, 0x54, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x4e, 0x41, 0x54,
+	0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x12, 0x28, 0x0a, 0x0f,
+	0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x50, 0x6f, 0x64,
+	0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x12, 0x32, 0x0a, 0x14, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65,
+	0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x45, 0x78, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x12, 0x2c, 0x0a, 0x11, 0x6c, 0x6f,
+	0x63, 0x61, 0x6c, 0x4e, 0x41, 0x54, 0x50, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x4e, 0x41, 0x54, 0x50,
+	0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x12, 0x36, 0x0a, 0x16, 0x6c, 0x6f, 0x63, 0x61,
+	0x6c, 0x4e, 0x41, 0x54, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52,
+	0x76, 0x36, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x16, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x4e,
+	0x41, 0x54, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36,
+	0x22, 0x55, 0x0a, 0x1a, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x53,
+	0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37,
+	0x0a, 0x0e, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72,
+	0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x52, 0x0e, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72,
+	0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x22, 0x12, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x50,
+	0x6f, 0x6f, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x4e, 0x0a, 0x04, 0x50,
+	0x6f, 0x6f, 0x6c, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x2c, 0x0a,
+	0x11, 0x61, 0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x65, 0x64, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x61, 0x6c, 0x6c, 0x6f, 0x63, 0x61,
+	0x74, 0x65, 0x64, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x22, 0xfc, 0x01, 0x0a, 0x11,
+	0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1b, 0x0a, 0x05, 0x70, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x05, 0x2e, 0x50, 0x6f, 0x6f, 0x6c, 0x52, 0x05, 0x70, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x18,
+	0x0a, 0x07, 0x70, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x70, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x43, 0x49, 0x44, 0x52, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x43, 0x49, 0x44, 0x52, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x78,
+	0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x12, 0x1c,
+	0x0a, 0x09, 0x70, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x70, 0x6f, 0x64, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x12, 0x24, 0x0a, 0x0d,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x43, 0x49, 0x44, 0x52,
+	0x76, 0x36, 0x12, 0x26, 0x0a, 0x0e, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49,
+	0x44, 0x52, 0x76, 0x36, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x65, 0x78, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x76, 0x36, 0x22, 0x3b, 0x0a, 0x1b, 0x4c, 0x69,
+	0x73, 0x74, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6c,
+	0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x22, 0x69, 0x0a, 0x0f, 0x45, 0x6e, 0x64, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x70, 0x12, 0x26, 0x0a, 0x0e, 0x65, 0x78,
+	0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52, 0x49, 0x50, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0e, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x43, 0x49, 0x44, 0x52,
+	0x49, 0x50, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x44, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x49,
+	0x44, 0x73, 0x22, 0x5c, 0x0a, 0x1c, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3c, 0x0a, 0x10, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61,
+	0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x52, 0x10,
+	0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x73,
+	0x32, 0xf9, 0x02, 0x0a, 0x04, 0x69, 0x70, 0x61, 0x6d, 0x12, 0x2a, 0x0a, 0x0d, 0x4d, 0x61, 0x70,
+	0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x0b, 0x2e, 0x4d, 0x61, 0x70,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x4d, 0x61, 0x70, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a, 0x0f, 0x55, 0x6e, 0x6d, 0x61, 0x70, 0x45, 0x6e,
+	0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x50, 0x12, 0x0d, 0x2e, 0x55, 0x6e, 0x6d, 0x61, 0x70,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x55, 0x6e, 0x6d, 0x61, 0x70, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x48, 0x6f,
+	0x6d, 0x65, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x12, 0x14, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x6f, 0x6d,
+	0x65, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e,
+	0x47, 0x65, 0x74, 0x48, 0x6f, 0x6d, 0x65, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d, 0x0a, 0x12, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x75, 0x73,
+	0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x12, 0x1a, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x32, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x73,
+	0x12, 0x11, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x53, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x12,
+	0x1c, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61,
+	0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x4d, 0x61, 0x70, 0x70,
+	0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x08, 0x5a, 0x06,
+	0x2e, 0x2f, 0x69, 0x70, 0x61, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -367,27 +1006,45 @@ func file_pkg_liqonet_ipam_ipam_proto_rawDescGZIP() []byte {
 	return file_pkg_liqonet_ipam_ipam_proto_rawDescData
 }
 
-var file_pkg_liqonet_ipam_ipam_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pkg_liqonet_ipam_ipam_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_pkg_liqonet_ipam_ipam_proto_goTypes = []interface{}{
-	(*MapRequest)(nil),           // 0: MapRequest
-	(*MapResponse)(nil),          // 1: MapResponse
-	(*UnmapRequest)(nil),         // 2: UnmapRequest
-	(*UnmapResponse)(nil),        // 3: UnmapResponse
-	(*GetHomePodIPRequest)(nil),  // 4: GetHomePodIPRequest
-	(*GetHomePodIPResponse)(nil), // 5: GetHomePodIPResponse
+	(*MapRequest)(nil),                   // 0: MapRequest
+	(*MapResponse)(nil),                  // 1: MapResponse
+	(*UnmapRequest)(nil),                 // 2: UnmapRequest
+	(*UnmapResponse)(nil),                // 3: UnmapResponse
+	(*GetHomePodIPRequest)(nil),          // 4: GetHomePodIPRequest
+	(*GetHomePodIPResponse)(nil),         // 5: GetHomePodIPResponse
+	(*ListClusterSubnetsRequest)(nil),    // 6: ListClusterSubnetsRequest
+	(*ClusterSubnets)(nil),               // 7: ClusterSubnets
+	(*ListClusterSubnetsResponse)(nil),   // 8: ListClusterSubnetsResponse
+	(*ListPoolsRequest)(nil),             // 9: ListPoolsRequest
+	(*Pool)(nil),                         // 10: Pool
+	(*ListPoolsResponse)(nil),            // 11: ListPoolsResponse
+	(*ListEndpointMappingsRequest)(nil),  // 12: ListEndpointMappingsRequest
+	(*EndpointMapping)(nil),              // 13: EndpointMapping
+	(*ListEndpointMappingsResponse)(nil), // 14: ListEndpointMappingsResponse
 }
 var file_pkg_liqonet_ipam_ipam_proto_depIdxs = []int32{
-	0, // 0: ipam.MapEndpointIP:input_type -> MapRequest
-	2, // 1: ipam.UnmapEndpointIP:input_type -> UnmapRequest
-	4, // 2: ipam.GetHomePodIP:input_type -> GetHomePodIPRequest
-	1, // 3: ipam.MapEndpointIP:output_type -> MapResponse
-	3, // 4: ipam.UnmapEndpointIP:output_type -> UnmapResponse
-	5, // 5: ipam.GetHomePodIP:output_type -> GetHomePodIPResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: ListClusterSubnetsResponse.clusterSubnets:type_name -> ClusterSubnets
+	10, // 1: ListPoolsResponse.pools:type_name -> Pool
+	13, // 2: ListEndpointMappingsResponse.endpointMappings:type_name -> EndpointMapping
+	0,  // 3: ipam.MapEndpointIP:input_type -> MapRequest
+	2,  // 4: ipam.UnmapEndpointIP:input_type -> UnmapRequest
+	4,  // 5: ipam.GetHomePodIP:input_type -> GetHomePodIPRequest
+	6,  // 6: ipam.ListClusterSubnets:input_type -> ListClusterSubnetsRequest
+	9,  // 7: ipam.ListPools:input_type -> ListPoolsRequest
+	12, // 8: ipam.ListEndpointMappings:input_type -> ListEndpointMappingsRequest
+	1,  // 9: ipam.MapEndpointIP:output_type -> MapResponse
+	3,  // 10: ipam.UnmapEndpointIP:output_type -> UnmapResponse
+	5,  // 11: ipam.GetHomePodIP:output_type -> GetHomePodIPResponse
+	8,  // 12: ipam.ListClusterSubnets:output_type -> ListClusterSubnetsResponse
+	11, // 13: ipam.ListPools:output_type -> ListPoolsResponse
+	14, // 14: ipam.ListEndpointMappings:output_type -> ListEndpointMappingsResponse
+	9,  // [9:15] is the sub-list for method output_type
+	3,  // [3:9] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_pkg_liqonet_ipam_ipam_proto_init() }
@@ -468,6 +1125,114 @@ func file_pkg_liqonet_ipam_ipam_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListClusterSubnetsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClusterSubnets); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListClusterSubnetsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListPoolsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pool); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListPoolsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListEndpointMappingsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EndpointMapping); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_liqonet_ipam_ipam_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListEndpointMappingsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -475,7 +1240,7 @@ func file_pkg_liqonet_ipam_ipam_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_liqonet_ipam_ipam_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
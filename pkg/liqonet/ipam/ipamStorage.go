@@ -45,6 +45,12 @@ const (
 	podCIDRUpdate               = "podCIDR"
 	serviceCIDRUpdate           = "serviceCIDR"
 	natMappingsConfiguredUpdate = "natMappingsConfigured"
+	externalCIDRv6Update        = "externalCIDRv6"
+	podCIDRv6Update             = "podCIDRv6"
+	serviceCIDRv6Update         = "serviceCIDRv6"
+	pinnedSubnetsUpdate         = "pinnedSubnets"
+	pinnedSubnetsOwnersUpdate   = "pinnedSubnetsOwners"
+	transitSubnetsUpdate        = "transitSubnets"
 )
 
 // IpamStorage is the interface to be implemented to enforce persistency in IPAM.
@@ -56,6 +62,12 @@ type IpamStorage interface {
 	updatePodCIDR(podCIDR string) error
 	updateServiceCIDR(serviceCIDR string) error
 	updateNatMappingsConfigured(natMappingsConfigured map[string]netv1alpha1.ConfiguredCluster) error
+	updateExternalCIDRv6(externalCIDR string) error
+	updatePodCIDRv6(podCIDR string) error
+	updateServiceCIDRv6(serviceCIDR string) error
+	updatePinnedSubnets(pinnedSubnets map[string]netv1alpha1.PinnedSubnets) error
+	updatePinnedSubnetsOwners(owners map[string]string) error
+	updateTransitSubnets(transitSubnets map[string]map[string]netv1alpha1.TransitSubnets) error
 	getClusterSubnets() map[string]netv1alpha1.Subnets
 	getPools() []string
 	getExternalCIDR() string
@@ -63,6 +75,12 @@ type IpamStorage interface {
 	getPodCIDR() string
 	getServiceCIDR() string
 	getNatMappingsConfigured() map[string]netv1alpha1.ConfiguredCluster
+	getExternalCIDRv6() string
+	getPodCIDRv6() string
+	getServiceCIDRv6() string
+	getPinnedSubnets() map[string]netv1alpha1.PinnedSubnets
+	getPinnedSubnetsOwners() map[string]string
+	getTransitSubnets() map[string]map[string]netv1alpha1.TransitSubnets
 	goipam.Storage
 }
 
@@ -229,6 +247,28 @@ func (ipamStorage *IPAMStorage) updateNatMappingsConfigured(natMappingsConfigure
 	return ipamStorage.updateConfig(natMappingsConfiguredUpdate, natMappingsConfigured)
 }
 
+func (ipamStorage *IPAMStorage) updateExternalCIDRv6(externalCIDR string) error {
+	return ipamStorage.updateConfig(externalCIDRv6Update, externalCIDR)
+}
+func (ipamStorage *IPAMStorage) updatePodCIDRv6(podCIDR string) error {
+	return ipamStorage.updateConfig(podCIDRv6Update, podCIDR)
+}
+func (ipamStorage *IPAMStorage) updateServiceCIDRv6(serviceCIDR string) error {
+	return ipamStorage.updateConfig(serviceCIDRv6Update, serviceCIDR)
+}
+
+func (ipamStorage *IPAMStorage) updatePinnedSubnets(pinnedSubnets map[string]netv1alpha1.PinnedSubnets) error {
+	return ipamStorage.updateConfig(pinnedSubnetsUpdate, pinnedSubnets)
+}
+
+func (ipamStorage *IPAMStorage) updatePinnedSubnetsOwners(owners map[string]string) error {
+	return ipamStorage.updateConfig(pinnedSubnetsOwnersUpdate, owners)
+}
+
+func (ipamStorage *IPAMStorage) updateTransitSubnets(transitSubnets map[string]map[string]netv1alpha1.TransitSubnets) error {
+	return ipamStorage.updateConfig(transitSubnetsUpdate, transitSubnets)
+}
+
 func (ipamStorage *IPAMStorage) updateConfig(updateType string, data interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -237,8 +277,10 @@ func (ipamStorage *IPAMStorage) updateConfig(updateType string, data interface{}
 	}
 
 	var b bytes.Buffer
+	// The add operation replaces the value of members that already exist, and it is required to set
+	// the optional fields that are omitted from the resource until they are first configured.
 	patch := fmt.Sprintf(
-		`[{"op": "replace", "path": "/spec/%s", "value": `,
+		`[{"op": "add", "path": "/spec/%s", "value": `,
 		updateType)
 	b.WriteString(patch)
 	b.Write(jsonData)
@@ -287,6 +329,28 @@ func (ipamStorage *IPAMStorage) getNatMappingsConfigured() map[string]netv1alpha
 	return ipamStorage.getConfig().Spec.NatMappingsConfigured
 }
 
+func (ipamStorage *IPAMStorage) getExternalCIDRv6() string {
+	return ipamStorage.getConfig().Spec.ExternalCIDRv6
+}
+func (ipamStorage *IPAMStorage) getPodCIDRv6() string {
+	return ipamStorage.getConfig().Spec.PodCIDRv6
+}
+func (ipamStorage *IPAMStorage) getServiceCIDRv6() string {
+	return ipamStorage.getConfig().Spec.ServiceCIDRv6
+}
+
+func (ipamStorage *IPAMStorage) getPinnedSubnets() map[string]netv1alpha1.PinnedSubnets {
+	return ipamStorage.getConfig().Spec.PinnedSubnets
+}
+
+func (ipamStorage *IPAMStorage) getPinnedSubnetsOwners() map[string]string {
+	return ipamStorage.getConfig().Spec.PinnedSubnetsOwners
+}
+
+func (ipamStorage *IPAMStorage) getTransitSubnets() map[string]map[string]netv1alpha1.TransitSubnets {
+	return ipamStorage.getConfig().Spec.TransitSubnets
+}
+
 func (ipamStorage *IPAMStorage) getConfig() *netv1alpha1.IpamStorage {
 	ipamStorage.RLock()
 	defer ipamStorage.RUnlock()
@@ -22,6 +22,9 @@ type IpamClient interface {
 	MapEndpointIP(ctx context.Context, in *MapRequest, opts ...grpc.CallOption) (*MapResponse, error)
 	UnmapEndpointIP(ctx context.Context, in *UnmapRequest, opts ...grpc.CallOption) (*UnmapResponse, error)
 	GetHomePodIP(ctx context.Context, in *GetHomePodIPRequest, opts ...grpc.CallOption) (*GetHomePodIPResponse, error)
+	ListClusterSubnets(ctx context.Context, in *ListClusterSubnetsRequest, opts ...grpc.CallOption) (*ListClusterSubnetsResponse, error)
+	ListPools(ctx context.Context, in *ListPoolsRequest, opts ...grpc.CallOption) (*ListPoolsResponse, error)
+	ListEndpointMappings(ctx context.Context, in *ListEndpointMappingsRequest, opts ...grpc.CallOption) (*ListEndpointMappingsResponse, error)
 }
 
 type ipamClient struct {
@@ -59,6 +62,33 @@ func (c *ipamClient) GetHomePodIP(ctx context.Context, in *GetHomePodIPRequest,
 	return out, nil
 }
 
+func (c *ipamClient) ListClusterSubnets(ctx context.Context, in *ListClusterSubnetsRequest, opts ...grpc.CallOption) (*ListClusterSubnetsResponse, error) {
+	out := new(ListClusterSubnetsResponse)
+	err := c.cc.Invoke(ctx, "/ipam/ListClusterSubnets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) ListPools(ctx context.Context, in *ListPoolsRequest, opts ...grpc.CallOption) (*ListPoolsResponse, error) {
+	out := new(ListPoolsResponse)
+	err := c.cc.Invoke(ctx, "/ipam/ListPools", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) ListEndpointMappings(ctx context.Context, in *ListEndpointMappingsRequest, opts ...grpc.CallOption) (*ListEndpointMappingsResponse, error) {
+	out := new(ListEndpointMappingsResponse)
+	err := c.cc.Invoke(ctx, "/ipam/ListEndpointMappings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IpamServer is the server API for Ipam service.
 // All implementations must embed UnimplementedIpamServer
 // for forward compatibility
@@ -66,6 +96,9 @@ type IpamServer interface {
 	MapEndpointIP(context.Context, *MapRequest) (*MapResponse, error)
 	UnmapEndpointIP(context.Context, *UnmapRequest) (*UnmapResponse, error)
 	GetHomePodIP(context.Context, *GetHomePodIPRequest) (*GetHomePodIPResponse, error)
+	ListClusterSubnets(context.Context, *ListClusterSubnetsRequest) (*ListClusterSubnetsResponse, error)
+	ListPools(context.Context, *ListPoolsRequest) (*ListPoolsResponse, error)
+	ListEndpointMappings(context.Context, *ListEndpointMappingsRequest) (*ListEndpointMappingsResponse, error)
 	mustEmbedUnimplementedIpamServer()
 }
 
@@ -82,6 +115,15 @@ func (UnimplementedIpamServer) UnmapEndpointIP(context.Context, *UnmapRequest) (
 func (UnimplementedIpamServer) GetHomePodIP(context.Context, *GetHomePodIPRequest) (*GetHomePodIPResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetHomePodIP not implemented")
 }
+func (UnimplementedIpamServer) ListClusterSubnets(context.Context, *ListClusterSubnetsRequest) (*ListClusterSubnetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListClusterSubnets not implemented")
+}
+func (UnimplementedIpamServer) ListPools(context.Context, *ListPoolsRequest) (*ListPoolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPools not implemented")
+}
+func (UnimplementedIpamServer) ListEndpointMappings(context.Context, *ListEndpointMappingsRequest) (*ListEndpointMappingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEndpointMappings not implemented")
+}
 func (UnimplementedIpamServer) mustEmbedUnimplementedIpamServer() {}
 
 // UnsafeIpamServer may be embedded to opt out of forward compatibility for this service.
@@ -149,6 +191,60 @@ func _Ipam_GetHomePodIP_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ipam_ListClusterSubnets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListClusterSubnetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).ListClusterSubnets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ipam/ListClusterSubnets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).ListClusterSubnets(ctx, req.(*ListClusterSubnetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_ListPools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPoolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).ListPools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ipam/ListPools",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).ListPools(ctx, req.(*ListPoolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_ListEndpointMappings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEndpointMappingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).ListEndpointMappings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ipam/ListEndpointMappings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).ListEndpointMappings(ctx, req.(*ListEndpointMappingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Ipam_ServiceDesc is the grpc.ServiceDesc for Ipam service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -168,6 +264,18 @@ var Ipam_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetHomePodIP",
 			Handler:    _Ipam_GetHomePodIP_Handler,
 		},
+		{
+			MethodName: "ListClusterSubnets",
+			Handler:    _Ipam_ListClusterSubnets_Handler,
+		},
+		{
+			MethodName: "ListPools",
+			Handler:    _Ipam_ListPools_Handler,
+		},
+		{
+			MethodName: "ListEndpointMappings",
+			Handler:    _Ipam_ListEndpointMappings_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/liqonet/ipam/ipam.proto",
@@ -51,6 +51,7 @@ const (
 	externalEndpointIP   = "10.0.50.6"
 	internalEndpointIP   = "10.0.0.6"
 	invalidValue         = "invalid value"
+	pinOwner             = "reservation"
 )
 
 var (
@@ -1559,6 +1560,461 @@ var _ = Describe("Ipam", func() {
 			})
 		})
 	})
+	Describe("SetPinnedSubnets", func() {
+		Context("Passing an invalid network", func() {
+			It("should return an error", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: invalidValue})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("Passing a network of the wrong address family", func() {
+			It("should return an error", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "fd00:1::/64"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("Passing an empty owner", func() {
+			It("should return an error", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, "", liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the networks of the cluster are already pinned by another owner", func() {
+			It("should return an error and keep the current pin", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				err = ipam.SetPinnedSubnets(clusterID1, "other-owner", liqonetapi.PinnedSubnets{PodCIDR: "10.200.0.0/16"})
+				Expect(err).To(HaveOccurred())
+				Expect(ipam.ipamStorage.getPinnedSubnets()).To(HaveKeyWithValue(clusterID1, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16"}))
+				Expect(ipam.ipamStorage.getPinnedSubnetsOwners()).To(HaveKeyWithValue(clusterID1, pinOwner))
+			})
+		})
+		Context("Passing a network overlapping with the one pinned for another cluster", func() {
+			It("should return an error", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				err = ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{ExternalCIDR: "10.100.1.0/24"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the networks of the remote cluster do not need a remapping", func() {
+			It("should ignore the pinned networks", func() {
+				err := ipam.SetPinnedSubnets(clusterID1, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("11.0.0.0/16"))
+				Expect(e).To(Equal("11.1.0.0/16"))
+			})
+		})
+		Context("When the networks of the remote cluster need a remapping", func() {
+			BeforeEach(func() {
+				_, _, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should remap them on the pinned networks", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("10.100.0.0/16"))
+				Expect(e).To(Equal("10.101.0.0/16"))
+			})
+			It("should remap only the networks with a pin on the pinned ones", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(Equal("10.0.0.0/16"))
+				Expect(p).To(HaveSuffix("/16"))
+				Expect(e).To(Equal("10.101.0.0/16"))
+			})
+			It("should fail if the pinned network has a different mask length", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/24"})
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).To(HaveOccurred())
+			})
+			It("should not assign the networks pinned for other clusters", func() {
+				err := ipam.SetPinnedSubnets(clusterID3, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.2.0.0/16", ExternalCIDR: "10.3.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]string{p, e}).ToNot(ContainElements("10.2.0.0/16", "10.3.0.0/16"))
+
+				// The networks pinned for other clusters are still available to them
+				p, e, err = ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID3)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("10.2.0.0/16"))
+				Expect(e).To(Equal("10.3.0.0/16"))
+			})
+			It("should free the pinned networks when the cluster configuration is removed", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.RemoveClusterConfig(clusterID2)).To(Succeed())
+				Expect(ipam.AcquireReservedSubnet("10.100.0.0/16")).To(Succeed())
+				Expect(ipam.AcquireReservedSubnet("10.101.0.0/16")).To(Succeed())
+			})
+		})
+	})
+	Describe("RemovePinnedSubnets", func() {
+		Context("Removing the networks pinned for a cluster", func() {
+			It("should fall back to the automatic remapping", func() {
+				_, _, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+				err = ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.RemovePinnedSubnets(clusterID2, pinOwner)).To(Succeed())
+				Expect(ipam.ipamStorage.getPinnedSubnets()).ToNot(HaveKey(clusterID2))
+				p, e, err := ipam.GetSubnetsPerCluster("10.0.0.0/16", "10.1.0.0/16", clusterID2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(Equal("10.100.0.0/16"))
+				Expect(e).ToNot(Equal("10.101.0.0/16"))
+			})
+		})
+		Context("Removing the networks pinned by another owner", func() {
+			It("should keep them", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipam.RemovePinnedSubnets(clusterID2, "other-owner")).To(Succeed())
+				Expect(ipam.ipamStorage.getPinnedSubnets()).To(HaveKey(clusterID2))
+				Expect(ipam.RemovePinnedSubnets(clusterID2, pinOwner)).To(Succeed())
+				Expect(ipam.ipamStorage.getPinnedSubnets()).ToNot(HaveKey(clusterID2))
+				Expect(ipam.ipamStorage.getPinnedSubnetsOwners()).ToNot(HaveKey(clusterID2))
+			})
+		})
+		Context("Removing the networks of a cluster without pins", func() {
+			It("should succeed", func() {
+				Expect(ipam.RemovePinnedSubnets(clusterID2, pinOwner)).To(Succeed())
+			})
+		})
+	})
+	Describe("GetTransitSubnets", func() {
+		Context("Passing an empty cluster ID", func() {
+			It("should return an error", func() {
+				_, _, err := ipam.GetTransitSubnets("", clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).To(HaveOccurred())
+				_, _, err = ipam.GetTransitSubnets(clusterID1, "", "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("Passing an IPv6 network", func() {
+			It("should return an error", func() {
+				_, _, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "fd00:1::/64", "11.1.0.0/16")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the networks do not need a remapping", func() {
+			It("should reserve and return them", func() {
+				p, e, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("11.0.0.0/16"))
+				Expect(e).To(Equal("11.1.0.0/16"))
+				Expect(ipam.AcquireReservedSubnet("11.0.0.0/16")).ToNot(Succeed())
+			})
+		})
+		Context("When the networks conflict with the ones of a peered cluster", func() {
+			BeforeEach(func() {
+				_, _, err := ipam.GetSubnetsPerCluster("11.0.0.0/16", "11.1.0.0/16", clusterID1)
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should remap them", func() {
+				p, e, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]string{p, e}).ToNot(ContainElements("11.0.0.0/16", "11.1.0.0/16"))
+				Expect(p).To(HaveSuffix("/16"))
+				Expect(e).To(HaveSuffix("/16"))
+			})
+			It("should remap them on the pinned networks", func() {
+				err := ipam.SetPinnedSubnets(clusterID2, pinOwner, liqonetapi.PinnedSubnets{PodCIDR: "10.100.0.0/16", ExternalCIDR: "10.101.0.0/16"})
+				Expect(err).ToNot(HaveOccurred())
+				p, e, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(Equal("10.100.0.0/16"))
+				Expect(e).To(Equal("10.101.0.0/16"))
+			})
+			It("should return the same networks when called again", func() {
+				p, e, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).ToNot(HaveOccurred())
+				p2, e2, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p2).To(Equal(p))
+				Expect(e2).To(Equal(e))
+			})
+		})
+	})
+
+	Describe("RemoveTransitSubnets", func() {
+		BeforeEach(func() {
+			_, _, err := ipam.GetTransitSubnets(clusterID1, clusterID2, "11.0.0.0/16", "11.1.0.0/16")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should free the networks", func() {
+			Expect(ipam.RemoveTransitSubnets(clusterID1, clusterID2)).To(Succeed())
+			Expect(ipam.AcquireReservedSubnet("11.0.0.0/16")).To(Succeed())
+			Expect(ipam.AcquireReservedSubnet("11.1.0.0/16")).To(Succeed())
+		})
+		It("should succeed if the networks have already been freed", func() {
+			Expect(ipam.RemoveTransitSubnets(clusterID1, clusterID2)).To(Succeed())
+			Expect(ipam.RemoveTransitSubnets(clusterID1, clusterID2)).To(Succeed())
+		})
+		It("should free the networks when the configuration of the transit cluster is removed", func() {
+			Expect(ipam.RemoveClusterConfig(clusterID1)).To(Succeed())
+			Expect(ipam.AcquireReservedSubnet("11.0.0.0/16")).To(Succeed())
+			Expect(ipam.AcquireReservedSubnet("11.1.0.0/16")).To(Succeed())
+		})
+	})
+
+	Describe("ListClusterSubnets", func() {
+		BeforeEach(func() {
+			_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+			Expect(err).To(BeNil())
+			err = ipam.AddLocalSubnetsPerCluster(localNATPodCIDR, localNATExternalCIDR, clusterID1)
+			Expect(err).To(BeNil())
+			_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID2)
+			Expect(err).To(BeNil())
+		})
+		Context("Without a cluster ID", func() {
+			It("should return the subnets of all the clusters", func() {
+				response, err := ipam.ListClusterSubnets(context.Background(), &ListClusterSubnetsRequest{})
+				Expect(err).To(BeNil())
+				Expect(response.GetClusterSubnets()).To(HaveLen(2))
+				Expect(response.GetClusterSubnets()[0].GetClusterID()).To(Equal(clusterID1))
+				Expect(response.GetClusterSubnets()[1].GetClusterID()).To(Equal(clusterID2))
+			})
+		})
+		Context("With a cluster ID", func() {
+			It("should return only the subnets of that cluster", func() {
+				response, err := ipam.ListClusterSubnets(context.Background(), &ListClusterSubnetsRequest{ClusterID: clusterID1})
+				Expect(err).To(BeNil())
+				Expect(response.GetClusterSubnets()).To(HaveLen(1))
+				subnets := response.GetClusterSubnets()[0]
+				Expect(subnets.GetRemotePodCIDR()).To(Equal(remotePodCIDR))
+				Expect(subnets.GetRemoteExternalCIDR()).To(Equal(remoteExternalCIDR))
+				Expect(subnets.GetLocalNATPodCIDR()).To(Equal(localNATPodCIDR))
+				Expect(subnets.GetLocalNATExternalCIDR()).To(Equal(localNATExternalCIDR))
+			})
+		})
+		Context("With the ID of a non-configured cluster", func() {
+			It("should return an empty list", func() {
+				response, err := ipam.ListClusterSubnets(context.Background(), &ListClusterSubnetsRequest{ClusterID: clusterID3})
+				Expect(err).To(BeNil())
+				Expect(response.GetClusterSubnets()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("ListPools", func() {
+		It("should return the pools along with the networks allocated from them", func() {
+			err := ipam.SetPodCIDR(homePodCIDR)
+			Expect(err).To(BeNil())
+			err = ipam.SetServiceCIDR("192.168.100.0/24")
+			Expect(err).To(BeNil())
+			externalCIDR, err := ipam.GetExternalCIDR(24)
+			Expect(err).To(BeNil())
+
+			response, err := ipam.ListPools(context.Background(), &ListPoolsRequest{})
+			Expect(err).To(BeNil())
+			Expect(response.GetPodCIDR()).To(Equal(homePodCIDR))
+			Expect(response.GetServiceCIDR()).To(Equal("192.168.100.0/24"))
+			Expect(response.GetExternalCIDR()).To(Equal(externalCIDR))
+			Expect(response.GetPools()).To(HaveLen(len(Pools)))
+
+			allocated := make(map[string][]string)
+			for _, pool := range response.GetPools() {
+				allocated[pool.GetNetwork()] = pool.GetAllocatedNetworks()
+			}
+			Expect(allocated).To(HaveKey(Pools[0]))
+			Expect(allocated[Pools[0]]).To(ContainElement(homePodCIDR))
+			Expect(allocated[Pools[1]]).To(ContainElement("192.168.100.0/24"))
+		})
+	})
+
+	Describe("ListEndpointMappings", func() {
+		var mappedIP string
+		BeforeEach(func() {
+			err := ipam.SetPodCIDR(homePodCIDR)
+			Expect(err).To(BeNil())
+			_, err = ipam.GetExternalCIDR(24)
+			Expect(err).To(BeNil())
+			for _, clusterID := range []string{clusterID1, clusterID2} {
+				_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID)
+				Expect(err).To(BeNil())
+				err = ipam.AddLocalSubnetsPerCluster(consts.DefaultCIDRValue, consts.DefaultCIDRValue, clusterID)
+				Expect(err).To(BeNil())
+			}
+			response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: externalEndpointIP})
+			Expect(err).To(BeNil())
+			mappedIP = response.GetIp()
+		})
+		Context("Without a cluster ID", func() {
+			It("should return all the mappings", func() {
+				response, err := ipam.ListEndpointMappings(context.Background(), &ListEndpointMappingsRequest{})
+				Expect(err).To(BeNil())
+				Expect(response.GetEndpointMappings()).To(HaveLen(1))
+				mapping := response.GetEndpointMappings()[0]
+				Expect(mapping.GetIp()).To(Equal(externalEndpointIP))
+				Expect(mapping.GetExternalCIDRIP()).To(Equal(mappedIP))
+				Expect(mapping.GetClusterIDs()).To(ConsistOf(clusterID1))
+			})
+		})
+		Context("With a cluster ID", func() {
+			It("should return only the mappings reflected to that cluster", func() {
+				response, err := ipam.ListEndpointMappings(context.Background(), &ListEndpointMappingsRequest{ClusterID: clusterID1})
+				Expect(err).To(BeNil())
+				Expect(response.GetEndpointMappings()).To(HaveLen(1))
+
+				response, err = ipam.ListEndpointMappings(context.Background(), &ListEndpointMappingsRequest{ClusterID: clusterID2})
+				Expect(err).To(BeNil())
+				Expect(response.GetEndpointMappings()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Dual-stack", func() {
+		const (
+			homePodCIDRv6        = "fd10::/64"
+			remotePodCIDRv6      = "fd50::/64"
+			remoteExternalCIDRv6 = "fd60::/64"
+			localNATPodCIDRv6    = "fd70::/64"
+		)
+		BeforeEach(func() {
+			// Enable dual-stack on an already configured IPAM
+			ipam.Terminate()
+			ipam = NewIPAM()
+			n, err := rand.Int(rand.Reader, big.NewInt(2000))
+			Expect(err).To(BeNil())
+			err = ipam.Init(append(Pools, PoolsV6...), dynClient, 2000+int(n.Int64()))
+			Expect(err).To(BeNil())
+
+			Expect(ipam.SetPodCIDR(homePodCIDR)).To(Succeed())
+			Expect(ipam.SetPodCIDR(homePodCIDRv6)).To(Succeed())
+			_, err = ipam.GetExternalCIDR(24)
+			Expect(err).To(BeNil())
+		})
+		Context("Init on an existing configuration", func() {
+			It("should add the IPv6 network pools", func() {
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.Pools).To(ContainElements(append(Pools, PoolsV6...)))
+				Expect(ipamStorage.Spec.PodCIDR).To(Equal(homePodCIDR))
+				Expect(ipamStorage.Spec.PodCIDRv6).To(Equal(homePodCIDRv6))
+			})
+		})
+		Context("GetExternalCIDRv6", func() {
+			It("should allocate an IPv6 network from the IPv6 pools", func() {
+				externalCIDR, err := ipam.GetExternalCIDRv6(64)
+				Expect(err).To(BeNil())
+				Expect(utils.IsIPv6Network(externalCIDR)).To(BeTrue())
+				Expect(externalCIDR).To(HaveSuffix("/64"))
+				externalCIDRv4, err := ipam.GetExternalCIDR(24)
+				Expect(err).To(BeNil())
+				Expect(utils.IsIPv6Network(externalCIDRv4)).To(BeFalse())
+			})
+		})
+		Context("GetSubnetsPerClusterV6", func() {
+			It("should reserve the IPv6 networks beside the IPv4 ones", func() {
+				p, e, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal(remotePodCIDR))
+				Expect(e).To(Equal(remoteExternalCIDR))
+				p, e, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal(remotePodCIDRv6))
+				Expect(e).To(Equal(remoteExternalCIDRv6))
+
+				// A second cluster asking for the same IPv6 networks is remapped within the IPv6 pools
+				p, e, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).ToNot(Equal(remotePodCIDRv6))
+				Expect(e).ToNot(Equal(remoteExternalCIDRv6))
+				Expect(utils.IsIPv6Network(p)).To(BeTrue())
+				Expect(utils.IsIPv6Network(e)).To(BeTrue())
+			})
+			It("should refuse networks of the wrong address family", func() {
+				_, _, err := ipam.GetSubnetsPerClusterV6(remotePodCIDR, remoteExternalCIDRv6, clusterID1)
+				Expect(err).ToNot(BeNil())
+				_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDRv6, remoteExternalCIDR, clusterID1)
+				Expect(err).ToNot(BeNil())
+			})
+			It("should free the IPv6 networks when the cluster configuration is removed", func() {
+				_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(ipam.RemoveClusterConfig(clusterID1)).To(Succeed())
+
+				p, _, err := ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID2)
+				Expect(err).To(BeNil())
+				Expect(p).To(Equal(remotePodCIDRv6))
+			})
+		})
+		Context("AddLocalSubnetsPerClusterV6", func() {
+			It("should require the IPv4 configuration first", func() {
+				_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID1)
+				Expect(err).To(BeNil())
+				err = ipam.AddLocalSubnetsPerClusterV6(localNATPodCIDRv6, consts.DefaultCIDRValue, clusterID1)
+				Expect(err).ToNot(BeNil())
+			})
+			It("should store the IPv6 local NAT networks", func() {
+				_, _, err := ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(ipam.AddLocalSubnetsPerCluster(localNATPodCIDR, localNATExternalCIDR, clusterID1)).To(Succeed())
+				Expect(ipam.AddLocalSubnetsPerClusterV6(localNATPodCIDRv6, consts.DefaultCIDRValue, clusterID1)).To(Succeed())
+
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				subnets := ipamStorage.Spec.ClusterSubnets[clusterID1]
+				Expect(subnets.LocalNATPodCIDR).To(Equal(localNATPodCIDR))
+				Expect(subnets.LocalNATPodCIDRv6).To(Equal(localNATPodCIDRv6))
+				Expect(subnets.LocalNATExternalCIDRv6).To(Equal(consts.DefaultCIDRValue))
+			})
+		})
+		Context("Mapping IPv6 endpoints", func() {
+			BeforeEach(func() {
+				_, err := ipam.GetExternalCIDRv6(64)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerCluster(remotePodCIDR, remoteExternalCIDR, clusterID1)
+				Expect(err).To(BeNil())
+				_, _, err = ipam.GetSubnetsPerClusterV6(remotePodCIDRv6, remoteExternalCIDRv6, clusterID1)
+				Expect(err).To(BeNil())
+				Expect(ipam.AddLocalSubnetsPerCluster(localNATPodCIDR, localNATExternalCIDR, clusterID1)).To(Succeed())
+				Expect(ipam.AddLocalSubnetsPerClusterV6(localNATPodCIDRv6, consts.DefaultCIDRValue, clusterID1)).To(Succeed())
+			})
+			It("should map local pod IPs using the IPv6 local NAT PodCIDR", func() {
+				response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: "fd10::20"})
+				Expect(err).To(BeNil())
+				Expect(response.GetIp()).To(Equal("fd70::20"))
+			})
+			It("should map external IPs into the IPv6 ExternalCIDR and release them", func() {
+				response, err := ipam.MapEndpointIP(context.Background(), &MapRequest{ClusterID: clusterID1, Ip: "fd99::6"})
+				Expect(err).To(BeNil())
+				ipamStorage, err := getIpamStorageResource()
+				Expect(err).To(BeNil())
+				belongs, err := ipBelongsToNetwork(response.GetIp(), ipamStorage.Spec.ExternalCIDRv6)
+				Expect(err).To(BeNil())
+				Expect(belongs).To(BeTrue())
+				Expect(ipamStorage.Spec.EndpointMappings).To(HaveKey("fd99::6"))
+
+				_, err = ipam.UnmapEndpointIP(context.Background(), &UnmapRequest{ClusterID: clusterID1, Ip: "fd99::6"})
+				Expect(err).To(BeNil())
+				ipamStorage, err = getIpamStorageResource()
+				Expect(err).To(BeNil())
+				Expect(ipamStorage.Spec.EndpointMappings).ToNot(HaveKey("fd99::6"))
+			})
+			It("should return the home IPv6 pod IPs", func() {
+				response, err := ipam.GetHomePodIP(context.Background(), &GetHomePodIPRequest{ClusterID: clusterID1, Ip: "fd50::30"})
+				Expect(err).To(BeNil())
+				Expect(response.GetHomeIP()).To(Equal("fd50::30"))
+			})
+		})
+	})
 })
 
 func getNatMappingResourcePerCluster(clusterID string) (*liqonetapi.NatMapping, error) {
@@ -0,0 +1,112 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ListClusterSubnets returns the networks assigned to the remote clusters. If the request carries
+// a cluster ID, only the networks of that cluster are returned.
+func (liqoIPAM *IPAM) ListClusterSubnets(ctx context.Context, request *ListClusterSubnetsRequest) (*ListClusterSubnetsResponse, error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	response := &ListClusterSubnetsResponse{}
+	for clusterID, subnets := range liqoIPAM.ipamStorage.getClusterSubnets() {
+		if request.GetClusterID() != "" && request.GetClusterID() != clusterID {
+			continue
+		}
+		response.ClusterSubnets = append(response.ClusterSubnets, &ClusterSubnets{
+			ClusterID:              clusterID,
+			RemotePodCIDR:          subnets.RemotePodCIDR,
+			RemoteExternalCIDR:     subnets.RemoteExternalCIDR,
+			LocalNATPodCIDR:        subnets.LocalNATPodCIDR,
+			LocalNATExternalCIDR:   subnets.LocalNATExternalCIDR,
+			RemotePodCIDRv6:        subnets.RemotePodCIDRv6,
+			RemoteExternalCIDRv6:   subnets.RemoteExternalCIDRv6,
+			LocalNATPodCIDRv6:      subnets.LocalNATPodCIDRv6,
+			LocalNATExternalCIDRv6: subnets.LocalNATExternalCIDRv6,
+		})
+	}
+	sort.Slice(response.ClusterSubnets, func(i, j int) bool {
+		return response.ClusterSubnets[i].ClusterID < response.ClusterSubnets[j].ClusterID
+	})
+	return response, nil
+}
+
+// ListPools returns the network pools along with the networks allocated from each of them,
+// and the networks used by the local cluster.
+func (liqoIPAM *IPAM) ListPools(ctx context.Context, request *ListPoolsRequest) (*ListPoolsResponse, error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	prefixes, err := liqoIPAM.ipamStorage.ReadAllPrefixes()
+	if err != nil {
+		return &ListPoolsResponse{}, fmt.Errorf("cannot read the allocated networks: %w", err)
+	}
+	allocated := make(map[string][]string)
+	for i := range prefixes {
+		if prefixes[i].ParentCidr != "" {
+			allocated[prefixes[i].ParentCidr] = append(allocated[prefixes[i].ParentCidr], prefixes[i].Cidr)
+		}
+	}
+
+	response := &ListPoolsResponse{
+		PodCIDR:        liqoIPAM.ipamStorage.getPodCIDR(),
+		ServiceCIDR:    liqoIPAM.ipamStorage.getServiceCIDR(),
+		ExternalCIDR:   liqoIPAM.ipamStorage.getExternalCIDR(),
+		PodCIDRv6:      liqoIPAM.ipamStorage.getPodCIDRv6(),
+		ServiceCIDRv6:  liqoIPAM.ipamStorage.getServiceCIDRv6(),
+		ExternalCIDRv6: liqoIPAM.ipamStorage.getExternalCIDRv6(),
+	}
+	for _, pool := range liqoIPAM.ipamStorage.getPools() {
+		networks := allocated[pool]
+		sort.Strings(networks)
+		response.Pools = append(response.Pools, &Pool{Network: pool, AllocatedNetworks: networks})
+	}
+	return response, nil
+}
+
+// ListEndpointMappings returns the local endpoints mapped in the ExternalCIDR. If the request carries
+// a cluster ID, only the endpoints reflected to that cluster are returned.
+func (liqoIPAM *IPAM) ListEndpointMappings(ctx context.Context,
+	request *ListEndpointMappingsRequest) (*ListEndpointMappingsResponse, error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	response := &ListEndpointMappingsResponse{}
+	for ip, mapping := range liqoIPAM.ipamStorage.getEndpointMappings() {
+		if _, exists := mapping.ClusterMappings[request.GetClusterID()]; request.GetClusterID() != "" && !exists {
+			continue
+		}
+		clusterIDs := make([]string, 0, len(mapping.ClusterMappings))
+		for clusterID := range mapping.ClusterMappings {
+			clusterIDs = append(clusterIDs, clusterID)
+		}
+		sort.Strings(clusterIDs)
+		response.EndpointMappings = append(response.EndpointMappings, &EndpointMapping{
+			Ip:             ip,
+			ExternalCIDRIP: mapping.IP,
+			ClusterIDs:     clusterIDs,
+		})
+	}
+	sort.Slice(response.EndpointMappings, func(i, j int) bool {
+		return response.EndpointMappings[i].Ip < response.EndpointMappings[j].Ip
+	})
+	return response, nil
+}
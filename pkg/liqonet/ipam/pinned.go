@@ -0,0 +1,164 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// SetPinnedSubnets stores the networks the PodCIDR and the ExternalCIDR of a remote cluster
+// are remapped on, in case of conflicts. The networks already assigned to the cluster are not affected.
+// It fails if the networks of the cluster are already pinned by a different owner.
+func (liqoIPAM *IPAM) SetPinnedSubnets(clusterID, owner string, pinned netv1alpha1.PinnedSubnets) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	if clusterID == "" {
+		return fmt.Errorf("ClusterID must not be empty")
+	}
+	if owner == "" {
+		return fmt.Errorf("owner must not be empty")
+	}
+	owners := liqoIPAM.ipamStorage.getPinnedSubnetsOwners()
+	if current, exists := owners[clusterID]; exists && current != owner {
+		return fmt.Errorf("networks of cluster %s are already pinned by %s", clusterID, current)
+	}
+	if err := liqoIPAM.validatePinnedSubnets(clusterID, &pinned); err != nil {
+		return fmt.Errorf("cannot pin networks for cluster %s:%w", clusterID, err)
+	}
+
+	pinnedSubnets := liqoIPAM.ipamStorage.getPinnedSubnets()
+	if current, exists := pinnedSubnets[clusterID]; !exists || current != pinned {
+		if pinnedSubnets == nil {
+			pinnedSubnets = make(map[string]netv1alpha1.PinnedSubnets)
+		}
+		pinnedSubnets[clusterID] = pinned
+		if err := liqoIPAM.ipamStorage.updatePinnedSubnets(pinnedSubnets); err != nil {
+			return fmt.Errorf("cannot update pinned subnets:%w", err)
+		}
+		klog.Infof("Networks pinned for cluster %s by %s: %+v", clusterID, owner, pinned)
+	}
+
+	// The owner is recorded also for the networks pinned before the owners were tracked.
+	if _, exists := owners[clusterID]; !exists {
+		if owners == nil {
+			owners = make(map[string]string)
+		}
+		owners[clusterID] = owner
+		if err := liqoIPAM.ipamStorage.updatePinnedSubnetsOwners(owners); err != nil {
+			return fmt.Errorf("cannot update pinned subnets owners:%w", err)
+		}
+	}
+	return nil
+}
+
+// RemovePinnedSubnets removes the networks pinned for a remote cluster, in case they are owned by the given owner.
+// The networks already assigned to the cluster are not affected.
+func (liqoIPAM *IPAM) RemovePinnedSubnets(clusterID, owner string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	owners := liqoIPAM.ipamStorage.getPinnedSubnetsOwners()
+	if current, exists := owners[clusterID]; exists && current != owner {
+		klog.V(4).Infof("Networks of cluster %s are pinned by %s, not removing them on behalf of %s", clusterID, current, owner)
+		return nil
+	}
+
+	pinnedSubnets := liqoIPAM.ipamStorage.getPinnedSubnets()
+	if _, exists := pinnedSubnets[clusterID]; exists {
+		delete(pinnedSubnets, clusterID)
+		if err := liqoIPAM.ipamStorage.updatePinnedSubnets(pinnedSubnets); err != nil {
+			return fmt.Errorf("cannot update pinned subnets:%w", err)
+		}
+		klog.Infof("Networks pinned for cluster %s have been removed", clusterID)
+	}
+
+	if _, exists := owners[clusterID]; exists {
+		delete(owners, clusterID)
+		if err := liqoIPAM.ipamStorage.updatePinnedSubnetsOwners(owners); err != nil {
+			return fmt.Errorf("cannot update pinned subnets owners:%w", err)
+		}
+	}
+	return nil
+}
+
+// pinnedNetworkList returns the non-empty networks of pinned.
+func pinnedNetworkList(pinned netv1alpha1.PinnedSubnets) []string {
+	var networks []string
+	for _, network := range []string{pinned.PodCIDR, pinned.ExternalCIDR, pinned.PodCIDRv6, pinned.ExternalCIDRv6} {
+		if network != "" {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// validatePinnedSubnets checks that the pinned networks are valid CIDRs of the expected address family,
+// and that they do not overlap with each other nor with the networks pinned for other clusters.
+func (liqoIPAM *IPAM) validatePinnedSubnets(clusterID string, pinned *netv1alpha1.PinnedSubnets) error {
+	for _, network := range []string{pinned.PodCIDR, pinned.ExternalCIDR} {
+		if network != "" {
+			if err := validateCIDRFamily(network, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, network := range []string{pinned.PodCIDRv6, pinned.ExternalCIDRv6} {
+		if network != "" {
+			if err := validateCIDRFamily(network, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	networks := pinnedNetworkList(*pinned)
+	for i, network := range networks {
+		for _, other := range networks[i+1:] {
+			if err := liqoIPAM.checkPinnedOverlap(network, other); err != nil {
+				return err
+			}
+		}
+	}
+
+	for cluster, other := range liqoIPAM.ipamStorage.getPinnedSubnets() {
+		if cluster == clusterID {
+			continue
+		}
+		for _, network := range networks {
+			for _, otherNetwork := range pinnedNetworkList(other) {
+				if err := liqoIPAM.checkPinnedOverlap(network, otherNetwork); err != nil {
+					return fmt.Errorf("%w, pinned for cluster %s", err, cluster)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkPinnedOverlap returns an error if the two pinned networks overlap.
+func (liqoIPAM *IPAM) checkPinnedOverlap(network, other string) error {
+	overlaps, err := liqoIPAM.overlapsWithNetwork(network, other)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return fmt.Errorf("network %s overlaps with network %s", network, other)
+	}
+	return nil
+}
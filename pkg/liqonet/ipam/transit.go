@@ -0,0 +1,123 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+/*
+GetTransitSubnets receives the IPv4 PodCIDR and ExternalCIDR of a cluster reached through a transit cluster,
+as seen by the latter, and returns the networks assigned to them. As for the directly peered clusters,
+the received networks are reserved if they do not generate conflicts, and remapped otherwise.
+The networks already assigned to the cluster reached through the given transit cluster are returned as they are.
+*/
+func (liqoIPAM *IPAM) GetTransitSubnets(viaClusterID, clusterID, podCIDR, externalCIDR string) (
+	mappedPodCIDR, mappedExternalCIDR string, err error) {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+
+	if viaClusterID == "" || clusterID == "" {
+		return "", "", fmt.Errorf("ClusterID must not be empty")
+	}
+
+	transitSubnets := liqoIPAM.ipamStorage.getTransitSubnets()
+	if subnets, exists := transitSubnets[viaClusterID][clusterID]; exists {
+		return subnets.PodCIDR, subnets.ExternalCIDR, nil
+	}
+
+	if err = validateCIDRFamily(podCIDR, false); err != nil {
+		return "", "", fmt.Errorf("PodCidr is an invalid CIDR:%w", err)
+	}
+	if err = validateCIDRFamily(externalCIDR, false); err != nil {
+		return "", "", fmt.Errorf("ExternalCIDR is an invalid CIDR:%w", err)
+	}
+
+	klog.Infof("Networks allocation request received for cluster %s reached through cluster %s", clusterID, viaClusterID)
+
+	// Get the networks pinned by the user, used in case a remapping is necessary
+	pinned := liqoIPAM.ipamStorage.getPinnedSubnets()[clusterID]
+
+	mappedPodCIDR, err = liqoIPAM.getOrRemapNetwork(podCIDR, pinned.PodCIDR)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot get a PodCIDR for cluster %s:%w", clusterID, err)
+	}
+	mappedExternalCIDR, err = liqoIPAM.getOrRemapNetwork(externalCIDR, pinned.ExternalCIDR)
+	if err != nil {
+		_ = liqoIPAM.freeReservedSubnet(mappedPodCIDR)
+		return "", "", fmt.Errorf("cannot get an ExternalCIDR for cluster %s:%w", clusterID, err)
+	}
+
+	if transitSubnets == nil {
+		transitSubnets = make(map[string]map[string]netv1alpha1.TransitSubnets)
+	}
+	if transitSubnets[viaClusterID] == nil {
+		transitSubnets[viaClusterID] = make(map[string]netv1alpha1.TransitSubnets)
+	}
+	transitSubnets[viaClusterID][clusterID] = netv1alpha1.TransitSubnets{PodCIDR: mappedPodCIDR, ExternalCIDR: mappedExternalCIDR}
+	if err := liqoIPAM.ipamStorage.updateTransitSubnets(transitSubnets); err != nil {
+		_ = liqoIPAM.freeReservedSubnet(mappedPodCIDR)
+		_ = liqoIPAM.freeReservedSubnet(mappedExternalCIDR)
+		return "", "", fmt.Errorf("cannot update transit subnets:%w", err)
+	}
+	klog.Infof("Networks %s and %s have been assigned to cluster %s reached through cluster %s",
+		mappedPodCIDR, mappedExternalCIDR, clusterID, viaClusterID)
+	return mappedPodCIDR, mappedExternalCIDR, nil
+}
+
+// RemoveTransitSubnets frees the networks assigned to a cluster reached through a transit cluster.
+func (liqoIPAM *IPAM) RemoveTransitSubnets(viaClusterID, clusterID string) error {
+	liqoIPAM.mutex.Lock()
+	defer liqoIPAM.mutex.Unlock()
+	return liqoIPAM.removeTransitSubnets(viaClusterID, clusterID)
+}
+
+// Internal implementation of exported func RemoveTransitSubnets.
+func (liqoIPAM *IPAM) removeTransitSubnets(viaClusterID, clusterID string) error {
+	transitSubnets := liqoIPAM.ipamStorage.getTransitSubnets()
+	subnets, exists := transitSubnets[viaClusterID][clusterID]
+	if !exists {
+		return nil
+	}
+	if err := liqoIPAM.freeReservedSubnet(subnets.PodCIDR); err != nil {
+		return err
+	}
+	if err := liqoIPAM.freeReservedSubnet(subnets.ExternalCIDR); err != nil {
+		return err
+	}
+	delete(transitSubnets[viaClusterID], clusterID)
+	if len(transitSubnets[viaClusterID]) == 0 {
+		delete(transitSubnets, viaClusterID)
+	}
+	if err := liqoIPAM.ipamStorage.updateTransitSubnets(transitSubnets); err != nil {
+		return fmt.Errorf("cannot update transit subnets:%w", err)
+	}
+	klog.Infof("Networks assigned to cluster %s reached through cluster %s have just been freed", clusterID, viaClusterID)
+	return nil
+}
+
+// removeTransitSubnetsVia frees the networks assigned to all the clusters reached through a transit cluster.
+func (liqoIPAM *IPAM) removeTransitSubnetsVia(viaClusterID string) error {
+	for clusterID := range liqoIPAM.ipamStorage.getTransitSubnets()[viaClusterID] {
+		if err := liqoIPAM.removeTransitSubnets(viaClusterID, clusterID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+import (
+	"fmt"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/nftables"
+)
+
+// Handler is the interface implemented by the backends configuring the NAT and filtering rules
+// needed to interconnect the local cluster with the remote ones.
+type Handler interface {
+	// Init configures the rules shared by all the remote clusters.
+	Init() error
+	// Terminate removes all the rules configured by liqo.
+	Terminate() error
+	// EnsureChainsPerCluster makes sure that the chains for the given remote cluster exist.
+	EnsureChainsPerCluster(clusterID string) error
+	// EnsureChainRulesPerCluster makes sure that the traffic towards and from the given remote cluster
+	// is steered into the chains of the cluster.
+	EnsureChainRulesPerCluster(tep *netv1alpha1.TunnelEndpoint) error
+	// EnsurePostroutingRules makes sure that the postrouting rules for the given remote cluster are in place and updated.
+	EnsurePostroutingRules(tep *netv1alpha1.TunnelEndpoint) error
+	// EnsurePreroutingRulesPerTunnelEndpoint makes sure that the prerouting rules extracted from
+	// the TunnelEndpoint are in place and updated.
+	EnsurePreroutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) error
+	// EnsurePreroutingRulesPerNatMapping makes sure that the prerouting rules extracted from
+	// the NatMapping are in place and updated.
+	EnsurePreroutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) error
+	// RemoveIPTablesConfigurationPerCluster removes all the chains and rules configured for the given remote cluster.
+	RemoveIPTablesConfigurationPerCluster(tep *netv1alpha1.TunnelEndpoint) error
+}
+
+var _ Handler = IPTHandler{}
+var _ Handler = &nftables.NFTHandler{}
+
+// Backend is the backend used to configure the NAT and filtering rules.
+type Backend string
+
+const (
+	// BackendIPTables configures the rules through iptables.
+	BackendIPTables Backend = "iptables"
+	// BackendNFTables configures the rules through nftables, programming a table per remote cluster.
+	BackendNFTables Backend = "nftables"
+)
+
+// backend is the backend used by the handlers returned by NewHandler.
+var backend = BackendIPTables
+
+// SetBackend configures the backend used by the handlers returned by NewHandler.
+// It must be called before the creation of the handlers.
+func SetBackend(b Backend) error {
+	switch b {
+	case BackendIPTables, BackendNFTables:
+		backend = b
+		return nil
+	default:
+		return fmt.Errorf("unknown netfilter backend %q, the accepted values are: %q, %q", b, BackendIPTables, BackendNFTables)
+	}
+}
+
+// NewHandler returns the handler used to configure the NAT and filtering rules, according to the configured backend.
+func NewHandler() (Handler, error) {
+	if backend == BackendNFTables {
+		h, err := nftables.NewNFTHandler()
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+	return NewIPTHandler()
+}
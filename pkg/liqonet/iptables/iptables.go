@@ -77,6 +77,9 @@ type IPTableRule []string
 // IPTHandler a handler that exposes all the functions needed to configure the iptables chains and rules.
 type IPTHandler struct {
 	ipt iptables.IPTables
+	// ip6 is the handler used to configure the ip6tables chains and rules for the IPv6 networks
+	// of dual-stack clusters. It is nil if ip6tables is not available on the host.
+	ip6 *IPTHandler
 }
 
 // NewIPTHandler return the iptables handler used to configure the iptables rules.
@@ -85,9 +88,34 @@ func NewIPTHandler() (IPTHandler, error) {
 	if err != nil {
 		return IPTHandler{}, err
 	}
-	return IPTHandler{
+	h := IPTHandler{
 		ipt: *ipt,
-	}, err
+	}
+	ip6t, err := iptables.NewWithProtocol(iptables.ProtocolIPv6)
+	if err != nil {
+		klog.Warningf("ip6tables is not available, IPv6 networks will not be configured: %v", err)
+		return h, nil
+	}
+	h.ip6 = &IPTHandler{ipt: *ip6t}
+	return h, nil
+}
+
+// isIPv6 returns whether the handler configures the ip6tables rules.
+func (h IPTHandler) isIPv6() bool {
+	return h.ipt.Proto() == iptables.ProtocolIPv6
+}
+
+// ipv6View returns the handler for the ip6tables rules and the IPv6 view of the TunnelEndpoint,
+// if both the host and the TunnelEndpoint support IPv6.
+func (h IPTHandler) ipv6View(tep *netv1alpha1.TunnelEndpoint) (*IPTHandler, *netv1alpha1.TunnelEndpoint) {
+	if h.ip6 == nil {
+		return nil, nil
+	}
+	tepv6 := utils.IPv6TunnelEndpoint(tep)
+	if tepv6 == nil {
+		return nil, nil
+	}
+	return h.ip6, tepv6
 }
 
 // Init function is called at startup of the operator.
@@ -112,6 +140,10 @@ func (h IPTHandler) Init() error {
 	if err := h.ensureLiqoRules(liqoRules); err != nil {
 		return err
 	}
+
+	if h.ip6 != nil {
+		return h.ip6.Init()
+	}
 	return nil
 }
 
@@ -157,6 +189,11 @@ func (h IPTHandler) Terminate() error {
 	if err := h.deleteLiqoChains(); err != nil {
 		return fmt.Errorf("cannot delete Liqo default chains: %w", err)
 	}
+	if h.ip6 != nil {
+		if err := h.ip6.Terminate(); err != nil {
+			return fmt.Errorf("cannot remove ip6tables configuration: %w", err)
+		}
+	}
 	klog.Infof("IPTables Liqo configuration has been successfully removed.")
 	return nil
 }
@@ -271,6 +308,9 @@ func (h IPTHandler) EnsureChainRulesPerCluster(tep *netv1alpha1.TunnelEndpoint)
 			return fmt.Errorf("cannot update rule for chain %s (table %s): %w", chain, getTableFromChain(chain), err)
 		}
 	}
+	if ip6, tepv6 := h.ipv6View(tep); ip6 != nil {
+		return ip6.EnsureChainRulesPerCluster(tepv6)
+	}
 	return nil
 }
 
@@ -327,6 +367,9 @@ func (h IPTHandler) EnsureChainsPerCluster(clusterID string) error {
 			return err
 		}
 	}
+	if h.ip6 != nil {
+		return h.ip6.EnsureChainsPerCluster(clusterID)
+	}
 	return nil
 }
 
@@ -367,6 +410,11 @@ func (h IPTHandler) RemoveIPTablesConfigurationPerCluster(tep *netv1alpha1.Tunne
 	if err != nil {
 		return fmt.Errorf("cannot remove chains per cluster: %w", err)
 	}
+	if ip6, tepv6 := h.ipv6View(tep); ip6 != nil {
+		if err := ip6.RemoveIPTablesConfigurationPerCluster(tepv6); err != nil {
+			return fmt.Errorf("cannot remove ip6tables configuration per cluster: %w", err)
+		}
+	}
 	klog.Infof("IPTables config per cluster %s has been deleted", tep.Spec.ClusterID)
 	return nil
 }
@@ -460,7 +508,21 @@ func (h IPTHandler) EnsurePostroutingRules(tep *netv1alpha1.TunnelEndpoint) erro
 	if err != nil {
 		return err
 	}
-	return h.updateRulesPerChain(getClusterPostRoutingChain(clusterID), rules)
+	// The traffic towards the clusters reached through the remote one is handled as the one towards the remote cluster.
+	for _, transit := range utils.TransitTunnelEndpoints(tep) {
+		transitRules, err := getPostroutingRules(transit)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, transitRules...)
+	}
+	if err := h.updateRulesPerChain(getClusterPostRoutingChain(clusterID), rules); err != nil {
+		return err
+	}
+	if ip6, tepv6 := h.ipv6View(tep); ip6 != nil {
+		return ip6.EnsurePostroutingRules(tepv6)
+	}
+	return nil
 }
 
 // EnsurePreroutingRulesPerTunnelEndpoint makes sure that the prerouting rules extracted from a
@@ -471,18 +533,30 @@ func (h IPTHandler) EnsurePreroutingRulesPerTunnelEndpoint(tep *netv1alpha1.Tunn
 	if err != nil {
 		return err
 	}
-	return h.updateRulesPerChain(getClusterPreRoutingChain(clusterID), rules)
+	if err := h.updateRulesPerChain(getClusterPreRoutingChain(clusterID), rules); err != nil {
+		return err
+	}
+	if ip6, tepv6 := h.ipv6View(tep); ip6 != nil {
+		return ip6.EnsurePreroutingRulesPerTunnelEndpoint(tepv6)
+	}
+	return nil
 }
 
 // EnsurePreroutingRulesPerNatMapping makes sure that the prerouting rules extracted from a
-// NatMapping resource are place and updated.
+// NatMapping resource are place and updated. The IPv6 mappings are configured through ip6tables.
 func (h IPTHandler) EnsurePreroutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) error {
 	clusterID := nm.Spec.ClusterID
-	rules, err := getPreRoutingRulesPerNatMapping(nm)
+	rules, err := getPreRoutingRulesPerNatMapping(nm, h.isIPv6())
 	if err != nil {
 		return err
 	}
-	return h.updateRulesPerChain(getClusterPreRoutingMappingChain(clusterID), rules)
+	if err := h.updateRulesPerChain(getClusterPreRoutingMappingChain(clusterID), rules); err != nil {
+		return err
+	}
+	if h.ip6 != nil {
+		return h.ip6.EnsurePreroutingRulesPerNatMapping(nm)
+	}
+	return nil
 }
 
 func getPreRoutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) ([]IPTableRule, error) {
@@ -494,19 +568,24 @@ func getPreRoutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) ([]IPT
 	localRemappedPodCIDR, remotePodCIDR := utils.GetPodCIDRS(tep)
 
 	rules := make([]IPTableRule, 0)
-	if localRemappedPodCIDR == consts.DefaultCIDRValue {
-		// Remote cluster has not remapped home PodCIDR,
-		// this means there is no need to NAT
-		return rules, nil
-	}
-	// Remote cluster has remapped home PodCIDR
-	rules = append(rules,
-		IPTableRule{"-s", remotePodCIDR, "-d", localRemappedPodCIDR, "-j", NETMAP, "--to", localPodCIDR},
-	)
+	// If the remote cluster has not remapped home PodCIDR, there is no need to NAT
+	if localRemappedPodCIDR != consts.DefaultCIDRValue {
+		// Remote cluster has remapped home PodCIDR
+		rules = append(rules,
+			IPTableRule{"-s", remotePodCIDR, "-d", localRemappedPodCIDR, "-j", NETMAP, "--to", localPodCIDR},
+		)
+	}
+	// Remote cluster has remapped the networks of the clusters it reaches through the local one
+	for _, transit := range utils.GetRemappedLocalTransitNetworks(tep) {
+		rules = append(rules,
+			IPTableRule{"-s", remotePodCIDR, "-d", transit.Remapped, "-j", NETMAP, "--to", transit.Original},
+		)
+	}
 	return rules, nil
 }
 
-func getPreRoutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) ([]IPTableRule, error) {
+// getPreRoutingRulesPerNatMapping returns the rules for the mappings of the given address family.
+func getPreRoutingRulesPerNatMapping(nm *netv1alpha1.NatMapping, ipv6 bool) ([]IPTableRule, error) {
 	// Check tep fields
 	if nm.Spec.ClusterID == "" {
 		return nil, &errors.WrongParameter{
@@ -518,6 +597,9 @@ func getPreRoutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) ([]IPTableRule,
 	rules := make([]IPTableRule, 0, len(nm.Spec.ClusterMappings))
 
 	for oldIP, newIP := range nm.Spec.ClusterMappings {
+		if utils.IsIPv6(oldIP) != ipv6 {
+			continue
+		}
 		rules = append(rules,
 			IPTableRule{"-d", newIP, "-j", DNAT, "--to-destination", oldIP},
 		)
@@ -544,9 +626,13 @@ func (h IPTHandler) ListRulesInChain(chain string) ([]string, error) {
 	}
 	rules := make([]string, 0)
 	ruleToRemove := strings.Join([]string{"-N", chain}, " ")
+	hostMask := "/32"
+	if h.isIPv6() {
+		hostMask = "/128"
+	}
 	for _, rule := range existingRules {
 		if rule != ruleToRemove {
-			rule = strings.ReplaceAll(rule, "/32", "")
+			rule = strings.ReplaceAll(rule, hostMask, "")
 			tmp := strings.Split(rule, " ")
 			rules = append(rules, strings.Join(tmp[2:], " "))
 		}
@@ -749,6 +835,23 @@ func getChainRulesPerCluster(tep *netv1alpha1.TunnelEndpoint) (map[string][]IPTa
 		chainRules[liqonetPreroutingChain] = append(chainRules[liqonetPreroutingChain],
 			IPTableRule{"-s", remotePodCIDR, "-d", localRemappedPodCIDR, "-j", getClusterPreRoutingChain(clusterID)})
 	}
+	// The traffic towards the clusters reached through the remote one is steered into the chains of the remote cluster.
+	for _, transit := range utils.TransitTunnelEndpoints(tep) {
+		_, transitPodCIDR := utils.GetPodCIDRS(transit)
+		_, transitExternalCIDR := utils.GetExternalCIDRS(transit)
+		chainRules[liqonetPostroutingChain] = append(chainRules[liqonetPostroutingChain],
+			IPTableRule{"-d", transitPodCIDR, "-j", getClusterPostRoutingChain(clusterID)},
+			IPTableRule{"-d", transitExternalCIDR, "-j", getClusterPostRoutingChain(clusterID)})
+		chainRules[liqonetInputChain] = append(chainRules[liqonetInputChain],
+			IPTableRule{"-d", transitPodCIDR, "-j", getClusterInputChain(clusterID)})
+		chainRules[liqonetForwardingChain] = append(chainRules[liqonetForwardingChain],
+			IPTableRule{"-d", transitPodCIDR, "-j", getClusterForwardChain(clusterID)})
+	}
+	// The same holds for the traffic the remote cluster sends through the local one, towards the networks it remapped.
+	for _, transit := range utils.GetRemappedLocalTransitNetworks(tep) {
+		chainRules[liqonetPreroutingChain] = append(chainRules[liqonetPreroutingChain],
+			IPTableRule{"-s", remotePodCIDR, "-d", transit.Remapped, "-j", getClusterPreRoutingChain(clusterID)})
+	}
 	return chainRules, nil
 }
 
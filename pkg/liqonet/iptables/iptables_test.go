@@ -927,6 +927,23 @@ var _ = Describe("iptables", func() {
 				func() { tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue },
 				func() []string { return []string{} },
 			),
+			Entry(
+				"LocalTransitNetworks remapped by the remote cluster",
+				func() {
+					tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+					tep.Spec.LocalTransitNetworks = []v1alpha1.TransitEndpoint{{
+						ClusterID:       "transit-cluster",
+						PodCIDR:         "10.200.0.0/16",
+						NATPodCIDR:      "10.201.0.0/16",
+						ExternalCIDR:    "10.202.0.0/16",
+						NATExternalCIDR: consts.DefaultCIDRValue,
+					}}
+				},
+				func() []string {
+					return []string{fmt.Sprintf("-s %s -d %s -j %s --to %s",
+						tep.Spec.RemoteNATPodCIDR, "10.201.0.0/16", NETMAP, "10.200.0.0/16")}
+				},
+			),
 		)
 	})
 	Describe("EnsurePreroutingRulesPerNatMapping", func() {
@@ -0,0 +1,175 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtu
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultPathMTU is the MTU assumed for the underlay network, in case it cannot be discovered.
+	DefaultPathMTU = 1500
+	// Minimum is the lowest MTU configured on the tunnel and overlay interfaces, i.e. the minimum IPv6 MTU.
+	Minimum = 1280
+	// VxlanOverhead is the number of bytes added to each packet by the VXLAN encapsulation over IPv4.
+	VxlanOverhead = 50
+
+	// discardPort is the port the probes are sent to. Replies are not expected, since only the
+	// ICMP "fragmentation needed" messages generated along the path are relevant.
+	discardPort = 9
+	// maxRounds is the maximum number of probes sent, each one possibly discovering a lower MTU.
+	maxRounds = 5
+
+	// maxPacketSize is the maximum size of an IP packet, possibly lower than the MTU of the route (e.g. loopback).
+	maxPacketSize = 65535
+
+	ipv4Headers = 28
+	ipv6Headers = 48
+)
+
+// DefaultWait is the time waited after each probe for the ICMP messages to be received.
+var DefaultWait = 200 * time.Millisecond
+
+// Discover returns the MTU of the network path towards the given address (or hostname). It starts from the MTU
+// of the route towards the destination, and sends probes with the Don't Fragment bit set, to let the kernel learn
+// the lower MTUs advertised through the ICMP "fragmentation needed" messages by the routers along the path.
+// Paths dropping those messages cannot be detected, hence in that case the MTU has to be configured explicitly.
+func Discover(address string, wait time.Duration) (int, error) {
+	ip, err := net.ResolveIPAddr("ip", address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve address %q: %w", address, err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: ip.IP, Port: discardPort})
+	if err != nil {
+		return 0, fmt.Errorf("failed to open the probing socket towards %s: %w", ip, err)
+	}
+	defer conn.Close()
+
+	ipv4 := ip.IP.To4() != nil
+	headers := ipv6Headers
+	if ipv4 {
+		headers = ipv4Headers
+	}
+	if err := setSockoptInt(conn, ipv4, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_DO, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_DO); err != nil {
+		return 0, fmt.Errorf("failed to enable path MTU discovery: %w", err)
+	}
+
+	current, err := getSockoptInt(conn, ipv4, unix.IP_MTU, unix.IPV6_MTU)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve the path MTU: %w", err)
+	}
+	if current > maxPacketSize {
+		current = maxPacketSize
+	}
+	for round := 0; round < maxRounds; round++ {
+		_, err := conn.Write(make([]byte, current-headers))
+		// EMSGSIZE means the path MTU decreased since it has been read, while ECONNREFUSED is caused by the
+		// ICMP "port unreachable" messages possibly received in response to the previous probes.
+		if err != nil && !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ECONNREFUSED) {
+			return 0, fmt.Errorf("failed to send the probe towards %s: %w", ip, err)
+		}
+		time.Sleep(wait)
+
+		discovered, err := getSockoptInt(conn, ipv4, unix.IP_MTU, unix.IPV6_MTU)
+		if err != nil {
+			return 0, fmt.Errorf("failed to retrieve the path MTU: %w", err)
+		}
+		if discovered == current {
+			break
+		}
+		klog.V(4).Infof("Path MTU towards %s lowered from %d to %d", ip, current, discovered)
+		current = discovered
+	}
+
+	klog.V(4).Infof("Path MTU towards %s is %d", ip, current)
+	return current, nil
+}
+
+// LinkMTU returns the MTU of the interface the given IP address is assigned to.
+func LinkMTU(ip net.IP) (int, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the interfaces: %w", err)
+	}
+	for _, link := range links {
+		addresses, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the addresses of interface %s: %w", link.Attrs().Name, err)
+		}
+		for i := range addresses {
+			if addresses[i].IP.Equal(ip) {
+				return link.Attrs().MTU, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no interface with address %s found", ip)
+}
+
+// Encapsulated returns the MTU of an interface encapsulating the traffic with the given overhead, on top of
+// a path with the given MTU. The result is never lower than the minimum MTU.
+func Encapsulated(pathMTU, overhead int) int {
+	if pathMTU-overhead < Minimum {
+		return Minimum
+	}
+	return pathMTU - overhead
+}
+
+func setSockoptInt(conn *net.UDPConn, ipv4 bool, optv4, valuev4, optv6, valuev6 int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		if ipv4 {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, optv4, valuev4)
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, optv6, valuev6)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+func getSockoptInt(conn *net.UDPConn, ipv4 bool, optv4, optv6 int) (int, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var value int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		if ipv4 {
+			value, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_IP, optv4)
+			return
+		}
+		value, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_IPV6, optv6)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, sockErr
+}
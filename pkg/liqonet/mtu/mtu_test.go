@@ -0,0 +1,66 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtu
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+)
+
+var _ = Describe("MTU functions", func() {
+	var loopbackMTU int
+
+	BeforeEach(func() {
+		loopback, err := netlink.LinkByName("lo")
+		Expect(err).ToNot(HaveOccurred())
+		loopbackMTU = loopback.Attrs().MTU
+	})
+
+	Describe("The Discover function", func() {
+		It("should return the MTU of the route towards the destination, capped to the maximum packet size", func() {
+			expected := loopbackMTU
+			if expected > maxPacketSize {
+				expected = maxPacketSize
+			}
+			Expect(Discover("127.0.0.1", 10*time.Millisecond)).To(Equal(expected))
+		})
+		It("should fail if the address cannot be resolved", func() {
+			_, err := Discover("invalid..address", 10*time.Millisecond)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The LinkMTU function", func() {
+		It("should return the MTU of the interface the address is assigned to", func() {
+			Expect(LinkMTU(net.ParseIP("127.0.0.1"))).To(Equal(loopbackMTU))
+		})
+		It("should fail if the address is not assigned to any interface", func() {
+			_, err := LinkMTU(net.ParseIP("192.0.2.1"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	DescribeTable("The Encapsulated function",
+		func(pathMTU, overhead, expected int) { Expect(Encapsulated(pathMTU, overhead)).To(Equal(expected)) },
+		Entry("standard underlay", 1500, 85, 1415),
+		Entry("jumbo frames underlay", 9000, 85, 8915),
+		Entry("underlay below the minimum", 1300, 85, Minimum),
+	)
+})
@@ -49,6 +49,8 @@ type Interface interface {
 	AddMapping(oldIP, newIP, clusterID string) error
 	// RemoveMapping removes a NAT mapping.
 	RemoveMapping(oldIP, clusterID string) error
+	// GetClusterIDs returns the IDs of the remote clusters NAT mappings have been initialized for.
+	GetClusterIDs() []string
 }
 
 // NatMappingInflater is an implementation of the NatMappingInflaterInterface
@@ -383,6 +385,15 @@ func (inflater *NatMappingInflater) GetNatMappings(clusterID string) (map[string
 	return mappings, nil
 }
 
+// GetClusterIDs returns the IDs of the remote clusters NAT mappings have been initialized for.
+func (inflater *NatMappingInflater) GetClusterIDs() []string {
+	clusterIDs := make([]string, 0, len(inflater.natMappingsPerCluster))
+	for clusterID := range inflater.natMappingsPerCluster {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	return clusterIDs
+}
+
 // Function that keeps a resource and removes remaining ones in case multiple resources exist.
 // Return value is the survived resource.
 func (inflater *NatMappingInflater) deleteMultipleNatMappingResources(resources []unstructured.Unstructured) (unstructured.Unstructured, error) {
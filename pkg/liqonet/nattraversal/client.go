@@ -0,0 +1,164 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default time waited for the responses of the reflector.
+	DefaultTimeout = 2 * time.Second
+	// udpHeaderLength is the length of the UDP header.
+	udpHeaderLength = 8
+)
+
+// Discover returns the public mapping of the given local UDP port, as observed by the given reflector.
+// The binding request is sent through a raw socket, with the given port as source, since the port is expected
+// to be already bound by the tunnel: this way, the request traverses the NAT with the same mapping of the
+// tunnel traffic. Only IPv4 is supported, and the CAP_NET_RAW capability is required.
+func Discover(localPort int, reflector string, timeout time.Duration) (*net.UDPAddr, error) {
+	server, err := net.ResolveUDPAddr("udp4", reflector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reflector address %s: %w", reflector, err)
+	}
+	conn, err := net.ListenPacket("ip4:udp", "0.0.0.0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %w", err)
+	}
+	defer conn.Close()
+
+	request, err := newMessage(bindingRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(udpDatagram(localPort, server.Port, request.marshal()), &net.IPAddr{IP: server.IP}); err != nil {
+		return nil, fmt.Errorf("failed to send binding request to %s: %w", server, err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+	buffer := make([]byte, 65535)
+	for {
+		n, source, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("no binding response received from %s: %w", server, err)
+		}
+		// The raw socket receives all the UDP packets, hence the ones not concerning the request are skipped.
+		srcPort, dstPort, payload, ok := parseUDPDatagram(buffer[:n])
+		if !ok || !source.(*net.IPAddr).IP.Equal(server.IP) || srcPort != server.Port || dstPort != localPort {
+			continue
+		}
+		response, err := unmarshal(payload)
+		if err != nil || response.txid != request.txid || response.kind != bindingResponse {
+			continue
+		}
+		mapping, err := net.ResolveUDPAddr("udp4", string(response.payload))
+		if err != nil {
+			return nil, fmt.Errorf("malformed binding response from %s: %w", server, err)
+		}
+		return mapping, nil
+	}
+}
+
+// Allocate allocates the given side of the given session on the relay, and returns the endpoint
+// the traffic of the side is expected to be sent to. The request is authenticated through the secret
+// configured on the relay, and the relay accepts the traffic of the side only from the source address
+// of the request: hence, it has to be sent from the same network namespace of the tunnel.
+func Allocate(relay, session string, side Side, secret []byte, timeout time.Duration) (*net.UDPAddr, error) {
+	server, err := net.ResolveUDPAddr("udp", relay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relay address %s: %w", relay, err)
+	}
+	conn, err := net.DialUDP("udp", nil, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to relay %s: %w", server, err)
+	}
+	defer conn.Close()
+
+	request, err := newMessage(allocateRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.payload = allocatePayload(request.txid, session, side, time.Now(), secret)
+	if _, err := conn.Write(request.marshal()); err != nil {
+		return nil, fmt.Errorf("failed to send allocation request to %s: %w", server, err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+	buffer := make([]byte, maxMessageLength)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("no allocation response received from %s: %w", server, err)
+		}
+		response, err := unmarshal(buffer[:n])
+		if err != nil || response.txid != request.txid {
+			continue
+		}
+		switch response.kind {
+		case allocateResponse:
+			if len(response.payload) != 2 {
+				return nil, fmt.Errorf("malformed allocation response from %s", server)
+			}
+			return &net.UDPAddr{IP: server.IP, Port: int(binary.BigEndian.Uint16(response.payload))}, nil
+		case errorResponse:
+			return nil, fmt.Errorf("allocation refused by %s: %s", server, response.payload)
+		default:
+			return nil, fmt.Errorf("unexpected response of type %d from %s", response.kind, server)
+		}
+	}
+}
+
+// Session returns the identifier of the relay session shared by the gateways with the given identifiers
+// (e.g. the public keys of the tunnel), together with the side of the local one.
+// Both gateways obtain the same session, and complementary sides.
+func Session(local, remote string) (string, Side) {
+	first, second, side := local, remote, SideA
+	if remote < local {
+		first, second, side = remote, local, SideB
+	}
+	hash := sha256.Sum256([]byte(first + "/" + second))
+	return hex.EncodeToString(hash[:]), side
+}
+
+// udpDatagram returns a UDP datagram carrying the given payload. The checksum is left unset, as allowed by IPv4.
+func udpDatagram(srcPort, dstPort int, payload []byte) []byte {
+	datagram := make([]byte, udpHeaderLength, udpHeaderLength+len(payload))
+	binary.BigEndian.PutUint16(datagram[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(datagram[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(datagram[4:6], uint16(udpHeaderLength+len(payload)))
+	return append(datagram, payload...)
+}
+
+// parseUDPDatagram returns the ports and the payload of the given UDP datagram.
+func parseUDPDatagram(datagram []byte) (srcPort, dstPort int, payload []byte, ok bool) {
+	if len(datagram) < udpHeaderLength {
+		return 0, 0, nil, false
+	}
+	length := int(binary.BigEndian.Uint16(datagram[4:6]))
+	if length < udpHeaderLength || length > len(datagram) {
+		return 0, 0, nil, false
+	}
+	return int(binary.BigEndian.Uint16(datagram[0:2])), int(binary.BigEndian.Uint16(datagram[2:4])),
+		datagram[udpHeaderLength:length], true
+}
@@ -0,0 +1,19 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nattraversal implements the traversal of the NATs the gateways may be located behind.
+// It provides a reflector, answering each binding request with the address the request has been received from,
+// and optionally relaying the traffic between two gateways unable to reach each other directly, together with the
+// client functions to discover the public mapping of the tunnel port and to allocate the relay endpoints.
+package nattraversal
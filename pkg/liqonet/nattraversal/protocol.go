@@ -0,0 +1,162 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Mode identifies the way the tunnel towards a remote cluster traverses the NATs.
+type Mode string
+
+const (
+	// ModeNone is the mode of the tunnels established directly with the advertised endpoint.
+	ModeNone Mode = ""
+	// ModeDirect is the mode of the tunnels established with the public mapping of a gateway behind a NAT,
+	// relying on the keepalives sent by both sides to punch the holes in the respective NATs.
+	ModeDirect Mode = "direct"
+	// ModeRelay is the mode of the tunnels whose traffic is relayed through a reflector.
+	ModeRelay Mode = "relay"
+)
+
+type messageType byte
+
+const (
+	bindingRequest messageType = iota + 1
+	bindingResponse
+	allocateRequest
+	allocateResponse
+	errorResponse
+)
+
+const (
+	// magic identifies the messages of the protocol, distinguishing them from the ones of the tunnels.
+	magic = "LQNT"
+	// headerLength is the length of the header of the messages: magic, type and transaction ID.
+	headerLength = len(magic) + 1 + 8
+	// maxMessageLength is the maximum length of the messages of the protocol.
+	maxMessageLength = 512
+	// allocateHeaderLength is the length of the fields preceding the session in the allocation requests: side and timestamp.
+	allocateHeaderLength = 1 + 8
+	// macLength is the length of the MAC authenticating the allocation requests.
+	macLength = sha256.Size
+	// AllocationValidity is the maximum difference between the timestamp of an allocation request and the time
+	// it is received by the relay, which bounds the window in which a captured request could be replayed.
+	AllocationValidity = time.Minute
+)
+
+// Side identifies one of the two gateways sharing a relay session.
+type Side byte
+
+const (
+	// SideA is the side of the gateway with the lower identifier.
+	SideA Side = iota
+	// SideB is the side of the gateway with the higher identifier.
+	SideB
+)
+
+// message is a message of the protocol spoken by the reflector.
+type message struct {
+	kind    messageType
+	txid    [8]byte
+	payload []byte
+}
+
+// newMessage returns a new message of the given type, with a random transaction ID.
+func newMessage(kind messageType, payload []byte) (*message, error) {
+	msg := &message{kind: kind, payload: payload}
+	if _, err := rand.Read(msg.txid[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate the transaction ID: %w", err)
+	}
+	return msg, nil
+}
+
+// reply returns a new message of the given type, with the same transaction ID of the receiver.
+func (msg *message) reply(kind messageType, payload []byte) *message {
+	return &message{kind: kind, txid: msg.txid, payload: payload}
+}
+
+// marshal returns the wire representation of the message.
+func (msg *message) marshal() []byte {
+	buffer := make([]byte, 0, headerLength+len(msg.payload))
+	buffer = append(buffer, magic...)
+	buffer = append(buffer, byte(msg.kind))
+	buffer = append(buffer, msg.txid[:]...)
+	return append(buffer, msg.payload...)
+}
+
+// unmarshal parses the wire representation of a message.
+func unmarshal(data []byte) (*message, error) {
+	if len(data) < headerLength || !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("not a NAT traversal message")
+	}
+	msg := &message{kind: messageType(data[len(magic)])}
+	copy(msg.txid[:], data[len(magic)+1:headerLength])
+	msg.payload = append([]byte(nil), data[headerLength:]...)
+	return msg, nil
+}
+
+// allocatePayload returns the payload of an allocation request for the given session and side, authenticated
+// through the secret shared with the relay. The MAC covers also the transaction ID and the timestamp of the request.
+func allocatePayload(txid [8]byte, session string, side Side, timestamp time.Time, secret []byte) []byte {
+	payload := make([]byte, allocateHeaderLength, allocateHeaderLength+len(session)+macLength)
+	payload[0] = byte(side)
+	binary.BigEndian.PutUint64(payload[1:allocateHeaderLength], uint64(timestamp.Unix()))
+	payload = append(payload, session...)
+	return append(payload, allocateMAC(txid, payload, secret)...)
+}
+
+// parseAllocatePayload parses the payload of an allocation request, verifying that it has been authenticated
+// through the given secret and that its timestamp is not farther than AllocationValidity from the given time.
+func parseAllocatePayload(txid [8]byte, payload, secret []byte, now time.Time) (session string, side Side, err error) {
+	if len(payload) < allocateHeaderLength+1+macLength {
+		return "", 0, errors.New("malformed allocation request")
+	}
+	data, mac := payload[:len(payload)-macLength], payload[len(payload)-macLength:]
+	if !hmac.Equal(mac, allocateMAC(txid, data, secret)) {
+		return "", 0, errors.New("allocation request not authenticated")
+	}
+	side = Side(data[0])
+	if side != SideA && side != SideB {
+		return "", 0, fmt.Errorf("invalid side %d", side)
+	}
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(data[1:allocateHeaderLength])), 0)
+	if skew := now.Sub(timestamp); skew > AllocationValidity || skew < -AllocationValidity {
+		return "", 0, fmt.Errorf("allocation request expired (timestamp %s)", timestamp.UTC().Format(time.RFC3339))
+	}
+	return string(data[allocateHeaderLength:]), side, nil
+}
+
+// allocateMAC returns the MAC of the given allocation request data, keyed with the given secret.
+func allocateMAC(txid [8]byte, data, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(txid[:])
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// portPayload returns the payload of an allocation response for the given port.
+func portPayload(port int) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(port))
+	return payload
+}
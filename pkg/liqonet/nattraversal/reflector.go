@@ -0,0 +1,341 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultReflectorPort is the port the reflector listens on by default.
+	DefaultReflectorPort = 3478
+	// RelayIdleTimeout is the time after which the relay sessions not carrying any traffic are released.
+	RelayIdleTimeout = 5 * time.Minute
+	// DefaultRelayMaxSessions is the default maximum number of sessions handled by the relay.
+	DefaultRelayMaxSessions = 1024
+	// DefaultRelayMaxSessionsPerSource is the default maximum number of sessions created from the same source address.
+	DefaultRelayMaxSessionsPerSource = 64
+)
+
+// RelayConfig is the configuration of the relay.
+type RelayConfig struct {
+	// Secret is the secret shared with the gateways, authenticating the allocation requests.
+	Secret []byte
+	// MaxSessions is the maximum number of sessions handled by the relay.
+	MaxSessions int
+	// MaxSessionsPerSource is the maximum number of sessions created from the same source address.
+	MaxSessionsPerSource int
+}
+
+// Reflector answers the binding requests with the address they have been received from,
+// and optionally relays the traffic between the two sides of the allocated sessions.
+type Reflector struct {
+	conn  *net.UDPConn
+	relay *relay
+}
+
+// NewReflector returns a new reflector listening on the given address.
+// The relaying of the traffic is enabled only if the relay configuration is not nil.
+func NewReflector(address string, relayConfig *RelayConfig) (*Reflector, error) {
+	if relayConfig != nil && len(relayConfig.Secret) == 0 {
+		return nil, errors.New("the relay requires a secret to authenticate the allocation requests")
+	}
+	if relayConfig != nil && (relayConfig.MaxSessions <= 0 || relayConfig.MaxSessionsPerSource <= 0) {
+		return nil, errors.New("the maximum numbers of relay sessions must be positive")
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve address %s: %w", address, err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	r := &Reflector{conn: conn}
+	if relayConfig != nil {
+		r.relay = newRelay(udpAddr.IP, relayConfig)
+	}
+	return r, nil
+}
+
+// Addr returns the address the reflector is listening on.
+func (r *Reflector) Addr() *net.UDPAddr {
+	return r.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// Start serves the requests until the given context is canceled.
+func (r *Reflector) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		r.conn.Close()
+		if r.relay != nil {
+			r.relay.close()
+		}
+	}()
+	if r.relay != nil {
+		go r.relay.expire(ctx, RelayIdleTimeout)
+	}
+
+	buffer := make([]byte, maxMessageLength)
+	for {
+		n, source, err := r.conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to receive message: %w", err)
+		}
+		msg, err := unmarshal(buffer[:n])
+		if err != nil {
+			klog.V(4).Infof("discarding message from %s: %v", source, err)
+			continue
+		}
+		response := r.handle(msg, source)
+		if response == nil {
+			continue
+		}
+		if _, err := r.conn.WriteToUDP(response.marshal(), source); err != nil {
+			klog.Warningf("failed to answer to %s: %v", source, err)
+		}
+	}
+}
+
+// handle processes a message received from the given source, returning the corresponding response.
+func (r *Reflector) handle(msg *message, source *net.UDPAddr) *message {
+	switch msg.kind {
+	case bindingRequest:
+		klog.V(4).Infof("binding request from %s", source)
+		return msg.reply(bindingResponse, []byte(source.String()))
+	case allocateRequest:
+		if r.relay == nil {
+			return msg.reply(errorResponse, []byte("relay not enabled"))
+		}
+		session, side, err := parseAllocatePayload(msg.txid, msg.payload, r.relay.config.Secret, time.Now())
+		if err != nil {
+			klog.Warningf("refusing allocation request from %s: %v", source, err)
+			return msg.reply(errorResponse, []byte(err.Error()))
+		}
+		port, err := r.relay.allocate(msg.txid, session, side, source)
+		if err != nil {
+			klog.Errorf("failed to allocate side %d of relay session %q for %s: %v", side, session, source, err)
+			return msg.reply(errorResponse, []byte(err.Error()))
+		}
+		klog.V(4).Infof("allocated side %d of relay session %q for %s on port %d", side, session, source, port)
+		return msg.reply(allocateResponse, portPayload(port))
+	default:
+		klog.V(4).Infof("discarding message of unexpected type %d from %s", msg.kind, source)
+		return nil
+	}
+}
+
+// relay forwards the traffic between the two sides of each session. Each side is assigned a dedicated port,
+// and the packets received on it are forwarded, through the port of the other side, to the address the other
+// side has been seen from. Only the packets coming from the IP address the side has been allocated from are
+// accepted, and the address of the side is locked at the first packet, until the side is allocated again.
+// Hence, the gateways are not required to register the port of the tunnel explicitly, since it is learned
+// from the tunnel traffic itself (including the keepalives), while it cannot be hijacked by third parties.
+type relay struct {
+	mutex    sync.Mutex
+	ip       net.IP
+	config   *RelayConfig
+	sessions map[string]*session
+	// requests contains the transaction IDs of the allocation requests received recently, to refuse the replayed ones.
+	requests map[[8]byte]time.Time
+}
+
+// session is a relay session, connecting two sides.
+type session struct {
+	mutex sync.Mutex
+	conns [2]*net.UDPConn
+	// creator is the IP address the session has been created from, accounted for the limit of sessions per source.
+	creator string
+	// sources are the IP addresses each side has been allocated from, the only ones its packets are accepted from.
+	sources [2]net.IP
+	// peers are the addresses each side has been seen from, the packets of the other side are relayed to.
+	peers [2]*net.UDPAddr
+	// rebind is true for the sides allocated again, whose address is learned anew from the next packet.
+	rebind       [2]bool
+	lastActivity time.Time
+}
+
+func newRelay(ip net.IP, config *RelayConfig) *relay {
+	return &relay{ip: ip, config: config, sessions: make(map[string]*session), requests: make(map[[8]byte]time.Time)}
+}
+
+// allocate returns the port assigned to the given side of the given session, creating the session if necessary,
+// and binds the side to the IP address of the given source.
+func (rl *relay) allocate(txid [8]byte, id string, side Side, source *net.UDPAddr) (int, error) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if _, found := rl.requests[txid]; found {
+		return 0, errors.New("replayed allocation request")
+	}
+	rl.requests[txid] = time.Now()
+
+	s, found := rl.sessions[id]
+	if !found {
+		if len(rl.sessions) >= rl.config.MaxSessions {
+			return 0, errors.New("maximum number of relay sessions reached")
+		}
+		if rl.sessionsCreatedBy(source.IP.String()) >= rl.config.MaxSessionsPerSource {
+			return 0, fmt.Errorf("maximum number of relay sessions reached for %s", source.IP)
+		}
+		s = &session{creator: source.IP.String(), lastActivity: time.Now()}
+		for i := range s.conns {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: rl.ip})
+			if err != nil {
+				s.close()
+				return 0, fmt.Errorf("failed to allocate the relay port: %w", err)
+			}
+			s.conns[i] = conn
+		}
+		for i := range s.conns {
+			go s.forward(Side(i))
+		}
+		rl.sessions[id] = s
+		klog.Infof("relay session %q created", id)
+	}
+	s.bind(side, source.IP)
+	return s.conns[side].LocalAddr().(*net.UDPAddr).Port, nil
+}
+
+// sessionsCreatedBy returns the number of sessions created from the given IP address.
+func (rl *relay) sessionsCreatedBy(ip string) int {
+	var count int
+	for _, s := range rl.sessions {
+		if s.creator == ip {
+			count++
+		}
+	}
+	return count
+}
+
+// expire periodically releases the sessions idle for longer than the given timeout.
+func (rl *relay) expire(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.mutex.Lock()
+			for id, s := range rl.sessions {
+				if s.idle(timeout) {
+					s.close()
+					delete(rl.sessions, id)
+					klog.Infof("relay session %q released, as idle", id)
+				}
+			}
+			// The requests older than the validity would be refused anyway, hence they are no longer tracked.
+			for txid, received := range rl.requests {
+				if time.Since(received) > 2*AllocationValidity {
+					delete(rl.requests, txid)
+				}
+			}
+			rl.mutex.Unlock()
+		}
+	}
+}
+
+// close releases all the sessions.
+func (rl *relay) close() {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	for id, s := range rl.sessions {
+		s.close()
+		delete(rl.sessions, id)
+	}
+}
+
+// forward relays the packets received by the given side to the other one.
+func (s *session) forward(side Side) {
+	other := 1 - side
+	buffer := make([]byte, 65535)
+	for {
+		n, source, err := s.conns[side].ReadFromUDP(buffer)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				klog.Warningf("relay session stopped: %v", err)
+			}
+			return
+		}
+		s.mutex.Lock()
+		accepted := s.accept(side, source)
+		destination := s.peers[other]
+		s.mutex.Unlock()
+		// The packets are dropped if not coming from the side, or until the other side is seen for the first time.
+		if !accepted {
+			klog.V(4).Infof("discarding packet from %s, not matching the relay session side", source)
+			continue
+		}
+		if destination == nil {
+			continue
+		}
+		if _, err := s.conns[other].WriteToUDP(buffer[:n], destination); err != nil {
+			klog.V(4).Infof("failed to relay packet to %s: %v", destination, err)
+		}
+	}
+}
+
+// bind binds the given side to the given IP address, letting its address be learned anew from the next packet.
+func (s *session) bind(side Side, ip net.IP) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources[side] = ip
+	s.rebind[side] = true
+}
+
+// accept returns whether the packet received by the given side from the given source has to be relayed,
+// learning the address of the side from the first packet coming from the IP address it has been allocated from.
+// It must be called with the mutex held.
+func (s *session) accept(side Side, source *net.UDPAddr) bool {
+	if !source.IP.Equal(s.sources[side]) {
+		return false
+	}
+	if s.peers[side] == nil || s.rebind[side] {
+		s.peers[side] = source
+		s.rebind[side] = false
+	}
+	if s.peers[side].String() != source.String() {
+		return false
+	}
+	s.lastActivity = time.Now()
+	return true
+}
+
+// idle returns whether the session has not carried any traffic for longer than the given timeout.
+func (s *session) idle(timeout time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Since(s.lastActivity) > timeout
+}
+
+// close releases the ports of the session.
+func (s *session) close() {
+	for _, conn := range s.conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reflector", func() {
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		reflector *Reflector
+		relay     *RelayConfig
+	)
+
+	const sessionID = "session"
+	secret := []byte("secret")
+
+	BeforeEach(func() { relay = nil })
+
+	JustBeforeEach(func() {
+		var err error
+		ctx, cancel = context.WithCancel(context.Background())
+		reflector, err = NewReflector("127.0.0.1:0", relay)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			Expect(reflector.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() { cancel() })
+
+	Describe("the Discover function", func() {
+		It("should return the address the binding request has been received from", func() {
+			mapping, err := Discover(45871, reflector.Addr().String(), DefaultTimeout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mapping.String()).To(Equal("127.0.0.1:45871"))
+		})
+	})
+
+	Describe("the Allocate function", func() {
+		When("the relay is disabled", func() {
+			It("should return an error", func() {
+				_, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+				Expect(err).To(MatchError(ContainSubstring("relay not enabled")))
+			})
+		})
+
+		When("the relay is enabled", func() {
+			var first, second *net.UDPConn
+
+			BeforeEach(func() {
+				relay = &RelayConfig{Secret: secret, MaxSessions: DefaultRelayMaxSessions, MaxSessionsPerSource: DefaultRelayMaxSessionsPerSource}
+			})
+
+			JustBeforeEach(func() {
+				var err error
+				first, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).ToNot(HaveOccurred())
+				second, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				first.Close()
+				second.Close()
+			})
+
+			It("should return the same endpoints for repeated requests", func() {
+				endpoint, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				again, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(again).To(Equal(endpoint))
+			})
+
+			It("should refuse the requests not authenticated with the secret of the relay", func() {
+				_, err := Allocate(reflector.Addr().String(), sessionID, SideA, []byte("wrong"), DefaultTimeout)
+				Expect(err).To(MatchError(ContainSubstring("not authenticated")))
+			})
+
+			It("should refuse the replayed requests", func() {
+				conn, err := net.DialUDP("udp", nil, reflector.Addr())
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+
+				request, err := newMessage(allocateRequest, nil)
+				Expect(err).ToNot(HaveOccurred())
+				request.payload = allocatePayload(request.txid, sessionID, SideA, time.Now(), secret)
+				kinds := []messageType{}
+				for i := 0; i < 2; i++ {
+					_, err = conn.Write(request.marshal())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(conn.SetReadDeadline(time.Now().Add(DefaultTimeout))).To(Succeed())
+					buffer := make([]byte, maxMessageLength)
+					n, err := conn.Read(buffer)
+					Expect(err).ToNot(HaveOccurred())
+					response, err := unmarshal(buffer[:n])
+					Expect(err).ToNot(HaveOccurred())
+					kinds = append(kinds, response.kind)
+				}
+				Expect(kinds).To(Equal([]messageType{allocateResponse, errorResponse}))
+			})
+
+			When("the maximum number of sessions per source is reached", func() {
+				BeforeEach(func() { relay.MaxSessionsPerSource = 1 })
+
+				It("should refuse the creation of further sessions", func() {
+					_, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+					Expect(err).ToNot(HaveOccurred())
+					// The allocation of the other side of an existing session is not limited.
+					_, err = Allocate(reflector.Addr().String(), sessionID, SideB, secret, DefaultTimeout)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = Allocate(reflector.Addr().String(), "other", SideA, secret, DefaultTimeout)
+					Expect(err).To(MatchError(ContainSubstring("maximum number of relay sessions reached")))
+				})
+			})
+
+			It("should relay the traffic between the two sides of the session", func() {
+				endpointA, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				endpointB, err := Allocate(reflector.Addr().String(), sessionID, SideB, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(endpointA).ToNot(Equal(endpointB))
+
+				// The first packet of each side lets the relay learn its address.
+				_, err = second.WriteToUDP([]byte("hello"), endpointB)
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(func() string {
+					_, err = first.WriteToUDP([]byte("ping"), endpointA)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(second.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+					buffer := make([]byte, 16)
+					n, _, _ := second.ReadFromUDP(buffer)
+					return string(buffer[:n])
+				}).Should(Equal("ping"))
+
+				_, err = second.WriteToUDP([]byte("pong"), endpointB)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(first.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+				buffer := make([]byte, 16)
+				// The initial packet of the second side may have been relayed as well, hence it is skipped.
+				for {
+					n, source, err := first.ReadFromUDP(buffer)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(source.String()).To(Equal(endpointA.String()))
+					if string(buffer[:n]) != "hello" {
+						Expect(string(buffer[:n])).To(Equal("pong"))
+						break
+					}
+				}
+			})
+
+			It("should not relay the traffic to sources other than the allocating ones", func() {
+				endpointA, err := Allocate(reflector.Addr().String(), sessionID, SideA, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				endpointB, err := Allocate(reflector.Addr().String(), sessionID, SideB, secret, DefaultTimeout)
+				Expect(err).ToNot(HaveOccurred())
+
+				// The intruder attempts to take the place of the first side, from a different address.
+				intruder, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2)})
+				Expect(err).ToNot(HaveOccurred())
+				defer intruder.Close()
+				_, err = intruder.WriteToUDP([]byte("hijack"), endpointA)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(func() string {
+					_, err = second.WriteToUDP([]byte("pong"), endpointB)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = first.WriteToUDP([]byte("ping"), endpointA)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(first.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+					buffer := make([]byte, 16)
+					n, _, _ := first.ReadFromUDP(buffer)
+					return string(buffer[:n])
+				}).Should(Equal("pong"))
+
+				Expect(intruder.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+				_, _, err = intruder.ReadFromUDP(make([]byte, 16))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("the Session function", func() {
+		It("should return the same session and complementary sides to the two gateways", func() {
+			sessionA, sideA := Session("first", "second")
+			sessionB, sideB := Session("second", "first")
+			Expect(sessionA).To(Equal(sessionB))
+			Expect(sideA).To(Equal(SideA))
+			Expect(sideB).To(Equal(SideB))
+		})
+	})
+})
@@ -0,0 +1,39 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// RelaySecretEnvVar is the environment variable holding the secret authenticating the allocation requests sent to the relays.
+const RelaySecretEnvVar = "RELAY_SECRET"
+
+// LoadRelaySecret returns the secret authenticating the allocation requests sent to the relays, reading it from the
+// given file if not empty, and from the RelaySecretEnvVar environment variable otherwise. This way, the secret does not
+// appear among the command line arguments, which are visible to the other processes. The trailing newlines are ignored,
+// and an empty secret is returned if it is not configured.
+func LoadRelaySecret(file string) ([]byte, error) {
+	if file == "" {
+		return []byte(os.Getenv(RelaySecretEnvVar)), nil
+	}
+	secret, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the relay secret from %s: %w", file, err)
+	}
+	return bytes.TrimRight(secret, "\r\n"), nil
+}
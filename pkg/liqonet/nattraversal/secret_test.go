@@ -0,0 +1,58 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nattraversal
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadRelaySecret", func() {
+	var (
+		previous string
+		found    bool
+	)
+
+	BeforeEach(func() {
+		previous, found = os.LookupEnv(RelaySecretEnvVar)
+		Expect(os.Setenv(RelaySecretEnvVar, "env-secret")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if found {
+			Expect(os.Setenv(RelaySecretEnvVar, previous)).To(Succeed())
+		} else {
+			Expect(os.Unsetenv(RelaySecretEnvVar)).To(Succeed())
+		}
+	})
+
+	It("should read the secret from the environment if no file is given", func() {
+		Expect(LoadRelaySecret("")).To(Equal([]byte("env-secret")))
+	})
+
+	It("should read the secret from the given file, ignoring the trailing newline", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "secret")
+		Expect(os.WriteFile(file, []byte("file-secret\n"), 0o600)).To(Succeed())
+		Expect(LoadRelaySecret(file)).To(Equal([]byte("file-secret")))
+	})
+
+	It("should fail if the given file does not exist", func() {
+		_, err := LoadRelaySecret(filepath.Join(GinkgoT().TempDir(), "missing"))
+		Expect(err).To(HaveOccurred())
+	})
+})
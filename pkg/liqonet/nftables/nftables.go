@@ -0,0 +1,374 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nftables
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/liqonet/errors"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+const (
+	// tablePrefix is the prefix used to name the table configured for a specific cluster.
+	tablePrefix = "liqo_"
+	// preroutingChain is the base chain steering the traffic coming from the remote cluster in the prerouting chains.
+	preroutingChain = "prerouting"
+	// postroutingChain is the base chain steering the traffic directed to the remote cluster in the postrouting chain.
+	postroutingChain = "postrouting"
+	// preroutingClusterChain is the chain translating the local PodCIDR remapped by the remote cluster.
+	preroutingClusterChain = "prerouting_cluster"
+	// preroutingMappingChain is the chain translating the addresses of the ExternalCIDR, according to the NatMapping.
+	preroutingMappingChain = "prerouting_mapping"
+	// postroutingClusterChain is the chain translating the source addresses of the traffic directed to the remote cluster.
+	postroutingClusterChain = "postrouting_cluster"
+	// dstnatPriority is the priority of the prerouting chain, which is evaluated before the ones configured through iptables.
+	dstnatPriority = -101
+	// srcnatPriority is the priority of the postrouting chain, which is evaluated before the ones configured through iptables.
+	srcnatPriority = 99
+)
+
+// family is the address family matched by a rule.
+type family string
+
+const (
+	familyIPv4 family = "ip"
+	familyIPv6 family = "ip6"
+)
+
+// runner executes the nft binary with the given arguments, feeding it the given input.
+type runner func(input string, args ...string) (string, error)
+
+// NFTHandler is the handler configuring the NAT rules through nftables. Each remote cluster is assigned
+// a dedicated table, whose chains are programmed atomically in a single transaction, hence avoiding the
+// rule-by-rule updates performed by the iptables handler. The IPv4 and IPv6 rules of dual-stack clusters
+// are configured in the same table, which belongs to the inet family.
+type NFTHandler struct {
+	nft runner
+}
+
+// NewNFTHandler returns the handler used to configure the nftables rules.
+// It requires the nft binary and the support for NAT prefix mappings (Linux 5.8 or later).
+func NewNFTHandler() (*NFTHandler, error) {
+	path, err := exec.LookPath("nft")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the nft binary: %w", err)
+	}
+	return &NFTHandler{nft: execRunner(path)}, nil
+}
+
+func execRunner(path string) runner {
+	return func(input string, args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(path, args...)
+		cmd.Stdin = strings.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return stdout.String(), nil
+	}
+}
+
+// Init checks that nftables can be configured. No rules shared by the remote clusters are needed,
+// since the tables of the clusters are hooked directly in the netfilter hooks.
+func (h *NFTHandler) Init() error {
+	if _, err := h.nft("", "list", "tables"); err != nil {
+		return fmt.Errorf("unable to list nftables tables: %w", err)
+	}
+	return nil
+}
+
+// Terminate removes the tables of all the remote clusters.
+func (h *NFTHandler) Terminate() error {
+	out, err := h.nft("", "list", "tables")
+	if err != nil {
+		return fmt.Errorf("unable to list nftables tables: %w", err)
+	}
+	var script strings.Builder
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "table" && fields[1] == "inet" && strings.HasPrefix(fields[2], tablePrefix) {
+			fmt.Fprintf(&script, "delete table inet %s\n", fields[2])
+		}
+	}
+	if script.Len() > 0 {
+		if err := h.apply(script.String()); err != nil {
+			return fmt.Errorf("unable to delete liqo nftables tables: %w", err)
+		}
+	}
+	klog.Infof("NFTables Liqo configuration has been successfully removed.")
+	return nil
+}
+
+// EnsureChainsPerCluster makes sure that the table of the given cluster, along with its chains, exists.
+func (h *NFTHandler) EnsureChainsPerCluster(clusterID string) error {
+	if clusterID == "" {
+		return &errors.WrongParameter{
+			Parameter: consts.ClusterIDLabelName,
+			Reason:    errors.StringNotEmpty,
+		}
+	}
+	return h.program(clusterID, nil)
+}
+
+// EnsureChainRulesPerCluster makes sure that the base chains of the cluster table steer the traffic
+// towards and from the remote cluster in the chains performing the NAT.
+func (h *NFTHandler) EnsureChainRulesPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	if err := utils.CheckTep(tep); err != nil {
+		return fmt.Errorf("invalid TunnelEndpoint resource: %w", err)
+	}
+	chainRules := map[string][]string{preroutingChain: nil, postroutingChain: nil}
+	for _, v := range views(tep) {
+		fam, t := v.family, v.tep
+		localRemappedPodCIDR, remotePodCIDR := utils.GetPodCIDRS(t)
+		localRemappedExternalCIDR, remoteExternalCIDR := utils.GetExternalCIDRS(t)
+		chainRules[postroutingChain] = append(chainRules[postroutingChain],
+			fmt.Sprintf("%s daddr %s jump %s", fam, remotePodCIDR, postroutingClusterChain),
+			fmt.Sprintf("%s daddr %s jump %s", fam, remoteExternalCIDR, postroutingClusterChain))
+		if v.transit {
+			// The traffic from the clusters reached through the remote one is masqueraded by the latter.
+			continue
+		}
+		chainRules[preroutingChain] = append(chainRules[preroutingChain],
+			fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s jump %[4]s", fam, remotePodCIDR, localRemappedExternalCIDR, preroutingMappingChain))
+		if localRemappedPodCIDR != consts.DefaultCIDRValue {
+			chainRules[preroutingChain] = append(chainRules[preroutingChain],
+				fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s jump %[4]s", fam, remotePodCIDR, localRemappedPodCIDR, preroutingClusterChain))
+		}
+	}
+	_, remotePodCIDR := utils.GetPodCIDRS(tep)
+	for _, network := range utils.GetRemappedLocalTransitNetworks(tep) {
+		chainRules[preroutingChain] = append(chainRules[preroutingChain],
+			fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s jump %[4]s", familyIPv4, remotePodCIDR, network.Remapped, preroutingClusterChain))
+	}
+	return h.program(tep.Spec.ClusterID, chainRules)
+}
+
+// EnsurePostroutingRules makes sure that the postrouting rules for a given cluster are in place and updated.
+func (h *NFTHandler) EnsurePostroutingRules(tep *netv1alpha1.TunnelEndpoint) error {
+	if err := utils.CheckTep(tep); err != nil {
+		return fmt.Errorf("invalid TunnelEndpoint resource: %w", err)
+	}
+	var rules []string
+	for _, v := range views(tep) {
+		fam, t := v.family, v.tep
+		r, err := getPostroutingRules(fam, t)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, r...)
+	}
+	return h.program(tep.Spec.ClusterID, map[string][]string{postroutingClusterChain: rules})
+}
+
+// EnsurePreroutingRulesPerTunnelEndpoint makes sure that the prerouting rules extracted from a
+// TunnelEndpoint resource are in place and updated.
+func (h *NFTHandler) EnsurePreroutingRulesPerTunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) error {
+	if err := utils.CheckTep(tep); err != nil {
+		return fmt.Errorf("invalid TunnelEndpoint resource: %w", err)
+	}
+	var rules []string
+	for _, v := range views(tep) {
+		fam, t := v.family, v.tep
+		localRemappedPodCIDR, remotePodCIDR := utils.GetPodCIDRS(t)
+		if v.transit || localRemappedPodCIDR == consts.DefaultCIDRValue {
+			// Remote cluster has not remapped home PodCIDR, this means there is no need to NAT.
+			continue
+		}
+		rules = append(rules, fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s dnat %[1]s prefix to %[1]s daddr map { %[3]s : %[4]s }",
+			fam, remotePodCIDR, localRemappedPodCIDR, t.Spec.LocalPodCIDR))
+	}
+	// Remote cluster has remapped the networks of the clusters it reaches through the local one.
+	_, remotePodCIDR := utils.GetPodCIDRS(tep)
+	for _, network := range utils.GetRemappedLocalTransitNetworks(tep) {
+		rules = append(rules, fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s dnat %[1]s prefix to %[1]s daddr map { %[3]s : %[4]s }",
+			familyIPv4, remotePodCIDR, network.Remapped, network.Original))
+	}
+	return h.program(tep.Spec.ClusterID, map[string][]string{preroutingClusterChain: rules})
+}
+
+// EnsurePreroutingRulesPerNatMapping makes sure that the prerouting rules extracted from a
+// NatMapping resource are in place and updated. The mappings of each address family are
+// configured through a single rule, looking up the destination address in a map.
+func (h *NFTHandler) EnsurePreroutingRulesPerNatMapping(nm *netv1alpha1.NatMapping) error {
+	if nm.Spec.ClusterID == "" {
+		return &errors.WrongParameter{
+			Parameter: consts.ClusterIDLabelName,
+			Reason:    errors.StringNotEmpty,
+		}
+	}
+	mappings := make(map[family][]string)
+	for oldIP, newIP := range nm.Spec.ClusterMappings {
+		fam := familyIPv4
+		if utils.IsIPv6(oldIP) {
+			fam = familyIPv6
+		}
+		mappings[fam] = append(mappings[fam], fmt.Sprintf("%s : %s", newIP, oldIP))
+	}
+	var rules []string
+	for _, fam := range []family{familyIPv4, familyIPv6} {
+		if len(mappings[fam]) == 0 {
+			continue
+		}
+		sort.Strings(mappings[fam])
+		rules = append(rules, fmt.Sprintf("dnat %[1]s to %[1]s daddr map { %[2]s }", fam, strings.Join(mappings[fam], ", ")))
+	}
+	return h.program(nm.Spec.ClusterID, map[string][]string{preroutingMappingChain: rules})
+}
+
+// RemoveIPTablesConfigurationPerCluster deletes the table of the given cluster, along with all its chains and rules.
+func (h *NFTHandler) RemoveIPTablesConfigurationPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	if tep.Spec.ClusterID == "" {
+		return &errors.WrongParameter{
+			Parameter: consts.ClusterIDLabelName,
+			Reason:    errors.StringNotEmpty,
+		}
+	}
+	table := getClusterTable(tep.Spec.ClusterID)
+	// The table is declared before being deleted, so that the deletion does not fail if it does not exist.
+	if err := h.apply(fmt.Sprintf("table inet %[1]s {\n}\ndelete table inet %[1]s\n", table)); err != nil {
+		return fmt.Errorf("cannot remove nftables table %s: %w", table, err)
+	}
+	klog.Infof("NFTables config per cluster %s has been deleted", tep.Spec.ClusterID)
+	return nil
+}
+
+// program makes sure that the table of the given cluster exists, and replaces the rules of the given
+// chains with the given ones. The whole configuration is applied in a single transaction.
+func (h *NFTHandler) program(clusterID string, chainRules map[string][]string) error {
+	table := getClusterTable(clusterID)
+	script := getTableDeclaration(table)
+	chains := make([]string, 0, len(chainRules))
+	for chain := range chainRules {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	for _, chain := range chains {
+		script += fmt.Sprintf("flush chain inet %s %s\n", table, chain)
+		for _, rule := range chainRules[chain] {
+			script += fmt.Sprintf("add rule inet %s %s %s\n", table, chain, rule)
+		}
+	}
+	if err := h.apply(script); err != nil {
+		return fmt.Errorf("cannot update chains %v of table %s: %w", chains, table, err)
+	}
+	klog.V(4).Infof("Updated chains %v of table %s", chains, table)
+	return nil
+}
+
+// apply applies the given script in a single nftables transaction.
+func (h *NFTHandler) apply(script string) error {
+	klog.V(5).Infof("applying nftables script:\n%s", script)
+	_, err := h.nft(script, "-f", "-")
+	return err
+}
+
+// getTableDeclaration returns the declaration of the table of a cluster along with its chains,
+// which creates them if they do not exist and leaves their rules untouched otherwise.
+func getTableDeclaration(table string) string {
+	return fmt.Sprintf(`table inet %s {
+	chain %s {
+		type nat hook prerouting priority %d; policy accept;
+	}
+	chain %s {
+		type nat hook postrouting priority %d; policy accept;
+	}
+	chain %s {
+	}
+	chain %s {
+	}
+	chain %s {
+	}
+}
+`, table, preroutingChain, dstnatPriority, postroutingChain, srcnatPriority,
+		preroutingClusterChain, preroutingMappingChain, postroutingClusterChain)
+}
+
+func getPostroutingRules(fam family, tep *netv1alpha1.TunnelEndpoint) ([]string, error) {
+	clusterID := tep.Spec.ClusterID
+	localPodCIDR := tep.Spec.LocalPodCIDR
+	localRemappedPodCIDR, remotePodCIDR := utils.GetPodCIDRS(tep)
+	_, remoteExternalCIDR := utils.GetExternalCIDRS(tep)
+	if localRemappedPodCIDR != consts.DefaultCIDRValue {
+		// Get the first IP address from the podCIDR to which the local podCIDR has been remapped by the remote cluster.
+		natIP, err := utils.GetFirstIP(localRemappedPodCIDR)
+		if err != nil {
+			klog.Errorf("Unable to get the IP from localPodCidr %s for remote cluster %s used to NAT the traffic from localhosts to remote hosts",
+				localRemappedPodCIDR, clusterID)
+			return nil, err
+		}
+		return []string{
+			fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s snat %[1]s prefix to %[1]s saddr map { %[2]s : %[4]s }",
+				fam, localPodCIDR, remotePodCIDR, localRemappedPodCIDR),
+			fmt.Sprintf("%[1]s saddr %[2]s %[1]s daddr %[3]s snat %[1]s prefix to %[1]s saddr map { %[2]s : %[4]s }",
+				fam, localPodCIDR, remoteExternalCIDR, localRemappedPodCIDR),
+			fmt.Sprintf("%[1]s saddr != %[2]s %[1]s daddr %[3]s snat %[1]s to %[4]s", fam, localPodCIDR, remotePodCIDR, natIP),
+			fmt.Sprintf("%[1]s saddr != %[2]s %[1]s daddr %[3]s snat %[1]s to %[4]s", fam, localPodCIDR, remoteExternalCIDR, natIP),
+		}, nil
+	}
+	// Get the first IP address from the podCIDR of the local cluster.
+	natIP, err := utils.GetFirstIP(localPodCIDR)
+	if err != nil {
+		klog.Errorf("Unable to get the IP from localPodCidr %s for cluster %s used to NAT the traffic from localhosts to remote hosts",
+			localPodCIDR, clusterID)
+		return nil, err
+	}
+	return []string{
+		fmt.Sprintf("%[1]s saddr != %[2]s %[1]s daddr %[3]s snat %[1]s to %[4]s", fam, localPodCIDR, remotePodCIDR, natIP),
+		fmt.Sprintf("%[1]s saddr != %[2]s %[1]s daddr %[3]s snat %[1]s to %[4]s", fam, localPodCIDR, remoteExternalCIDR, natIP),
+	}, nil
+}
+
+// view is the TunnelEndpoint to be configured for a given address family.
+type view struct {
+	family family
+	tep    *netv1alpha1.TunnelEndpoint
+	// transit is whether the view describes a cluster reached through the remote one.
+	transit bool
+}
+
+// views returns the TunnelEndpoint along with, in case of dual-stack clusters, its IPv6 view,
+// and the views of the clusters reached through the remote one.
+func views(tep *netv1alpha1.TunnelEndpoint) []view {
+	v := []view{{family: familyIPv4, tep: tep}}
+	if tepv6 := utils.IPv6TunnelEndpoint(tep); tepv6 != nil {
+		v = append(v, view{family: familyIPv6, tep: tepv6})
+	}
+	for _, transit := range utils.TransitTunnelEndpoints(tep) {
+		v = append(v, view{family: familyIPv4, tep: transit, transit: true})
+	}
+	return v
+}
+
+// getClusterTable returns the name of the table of the given cluster, replacing the characters
+// not allowed in the nftables identifiers.
+func getClusterTable(clusterID string) string {
+	return tablePrefix + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, clusterID)
+}
@@ -0,0 +1,283 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nftables
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/consts"
+	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
+)
+
+const (
+	clusterID1   = "cluster-1"
+	clusterTable = "liqo_cluster_1"
+)
+
+var (
+	h *NFTHandler
+	// scripts holds the scripts applied through the fake nft binary.
+	scripts []string
+	// tables is the output returned by the fake nft binary when listing the tables.
+	tables string
+	// nftErr is the error returned by the fake nft binary.
+	nftErr error
+	tep    *v1alpha1.TunnelEndpoint
+
+	validTep = &v1alpha1.TunnelEndpoint{
+		Spec: v1alpha1.TunnelEndpointSpec{
+			ClusterID:             clusterID1,
+			LocalPodCIDR:          "192.168.0.0/24",
+			LocalNATPodCIDR:       "192.168.1.0/24",
+			LocalExternalCIDR:     "192.168.3.0/24",
+			LocalNATExternalCIDR:  "192.168.4.0/24",
+			RemotePodCIDR:         "10.0.0.0/24",
+			RemoteNATPodCIDR:      "10.60.0.0/24",
+			RemoteExternalCIDR:    "10.0.1.0/24",
+			RemoteNATExternalCIDR: "192.168.5.0/24",
+		},
+	}
+)
+
+func fakeRunner(input string, args ...string) (string, error) {
+	if nftErr != nil {
+		return "", nftErr
+	}
+	if strings.Join(args, " ") == "list tables" {
+		return tables, nil
+	}
+	scripts = append(scripts, input)
+	return "", nil
+}
+
+// lastScript returns the lines of the last applied script, following the declaration of the table.
+func lastScript() []string {
+	ExpectWithOffset(1, scripts).NotTo(BeEmpty())
+	script := scripts[len(scripts)-1]
+	declaration := getTableDeclaration(clusterTable)
+	ExpectWithOffset(1, script).To(HavePrefix(declaration))
+	return strings.Split(strings.TrimSuffix(strings.TrimPrefix(script, declaration), "\n"), "\n")
+}
+
+var _ = Describe("nftables", func() {
+	BeforeEach(func() {
+		h = &NFTHandler{nft: fakeRunner}
+		scripts, tables, nftErr = nil, "", nil
+		tep = validTep.DeepCopy()
+	})
+
+	Describe("EnsureChainsPerCluster", func() {
+		It("should declare the table of the cluster without touching the rules", func() {
+			Expect(h.EnsureChainsPerCluster(clusterID1)).To(Succeed())
+			Expect(scripts).To(ConsistOf(getTableDeclaration(clusterTable)))
+		})
+
+		It("should fail if the cluster ID is empty", func() {
+			err := h.EnsureChainsPerCluster("")
+			Expect(err).To(MatchError(&liqoneterrors.WrongParameter{
+				Parameter: consts.ClusterIDLabelName,
+				Reason:    liqoneterrors.StringNotEmpty,
+			}))
+			Expect(scripts).To(BeEmpty())
+		})
+
+		It("should return the errors of nft", func() {
+			nftErr = errors.New("nft failure")
+			Expect(h.EnsureChainsPerCluster(clusterID1)).To(MatchError(ContainSubstring("nft failure")))
+		})
+	})
+
+	Describe("EnsureChainRulesPerCluster", func() {
+		It("should atomically replace the rules of the base chains", func() {
+			Expect(h.EnsureChainRulesPerCluster(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 postrouting",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 10.60.0.0/24 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 192.168.5.0/24 jump postrouting_cluster",
+				"flush chain inet liqo_cluster_1 prerouting",
+				"add rule inet liqo_cluster_1 prerouting ip saddr 10.60.0.0/24 ip daddr 192.168.4.0/24 jump prerouting_mapping",
+				"add rule inet liqo_cluster_1 prerouting ip saddr 10.60.0.0/24 ip daddr 192.168.1.0/24 jump prerouting_cluster",
+			}))
+		})
+
+		It("should not steer the traffic in the prerouting_cluster chain if the local PodCIDR has not been remapped", func() {
+			tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+			Expect(h.EnsureChainRulesPerCluster(tep)).To(Succeed())
+			Expect(lastScript()).NotTo(ContainElement(ContainSubstring("jump prerouting_cluster")))
+		})
+
+		It("should configure the IPv6 rules in dual-stack clusters", func() {
+			tep.Spec.LocalPodCIDRv6 = "fd00:0:0:1::/64"
+			tep.Spec.LocalExternalCIDRv6 = "fd00:0:0:2::/64"
+			tep.Spec.RemotePodCIDRv6 = "fd00:0:0:3::/64"
+			tep.Spec.RemoteExternalCIDRv6 = "fd00:0:0:4::/64"
+			Expect(h.EnsureChainRulesPerCluster(tep)).To(Succeed())
+			Expect(lastScript()).To(ContainElements(
+				"add rule inet liqo_cluster_1 postrouting ip6 daddr fd00:0:0:3::/64 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting ip6 daddr fd00:0:0:4::/64 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 prerouting ip6 saddr fd00:0:0:3::/64 ip6 daddr fd00:0:0:2::/64 jump prerouting_mapping",
+			))
+		})
+
+		It("should steer the traffic towards and through the transit clusters", func() {
+			tep.Spec.RemoteTransitNetworks = []v1alpha1.TransitEndpoint{{
+				ClusterID: "cluster-2", PodCIDR: "10.70.0.0/24", NATPodCIDR: consts.DefaultCIDRValue,
+				ExternalCIDR: "10.71.0.0/24", NATExternalCIDR: "10.72.0.0/24",
+			}}
+			tep.Spec.LocalTransitNetworks = []v1alpha1.TransitEndpoint{{
+				ClusterID: "cluster-3", PodCIDR: "10.80.0.0/24", NATPodCIDR: "10.81.0.0/24",
+				ExternalCIDR: "10.82.0.0/24", NATExternalCIDR: consts.DefaultCIDRValue,
+			}}
+			Expect(h.EnsureChainRulesPerCluster(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 postrouting",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 10.60.0.0/24 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 192.168.5.0/24 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 10.70.0.0/24 jump postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting ip daddr 10.72.0.0/24 jump postrouting_cluster",
+				"flush chain inet liqo_cluster_1 prerouting",
+				"add rule inet liqo_cluster_1 prerouting ip saddr 10.60.0.0/24 ip daddr 192.168.4.0/24 jump prerouting_mapping",
+				"add rule inet liqo_cluster_1 prerouting ip saddr 10.60.0.0/24 ip daddr 192.168.1.0/24 jump prerouting_cluster",
+				"add rule inet liqo_cluster_1 prerouting ip saddr 10.60.0.0/24 ip daddr 10.81.0.0/24 jump prerouting_cluster",
+			}))
+		})
+
+		It("should fail if the TunnelEndpoint is not valid", func() {
+			tep.Spec.RemotePodCIDR = "an invalid value"
+			Expect(h.EnsureChainRulesPerCluster(tep)).NotTo(Succeed())
+			Expect(scripts).To(BeEmpty())
+		})
+	})
+
+	Describe("EnsurePostroutingRules", func() {
+		It("should remap the local PodCIDR if the remote cluster has remapped it", func() {
+			Expect(h.EnsurePostroutingRules(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr 192.168.0.0/24 ip daddr 10.60.0.0/24 " +
+					"snat ip prefix to ip saddr map { 192.168.0.0/24 : 192.168.1.0/24 }",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr 192.168.0.0/24 ip daddr 192.168.5.0/24 " +
+					"snat ip prefix to ip saddr map { 192.168.0.0/24 : 192.168.1.0/24 }",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 10.60.0.0/24 snat ip to 192.168.1.0",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 192.168.5.0/24 snat ip to 192.168.1.0",
+			}))
+		})
+
+		It("should only masquerade the traffic not coming from the local PodCIDR if it has not been remapped", func() {
+			tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+			Expect(h.EnsurePostroutingRules(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 postrouting_cluster",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 10.60.0.0/24 snat ip to 192.168.0.0",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 192.168.5.0/24 snat ip to 192.168.0.0",
+			}))
+		})
+
+		It("should handle the traffic towards the transit clusters as the one towards the remote cluster", func() {
+			tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+			tep.Spec.RemoteTransitNetworks = []v1alpha1.TransitEndpoint{{
+				ClusterID: "cluster-2", PodCIDR: "10.70.0.0/24", NATPodCIDR: consts.DefaultCIDRValue,
+				ExternalCIDR: "10.71.0.0/24", NATExternalCIDR: consts.DefaultCIDRValue,
+			}}
+			Expect(h.EnsurePostroutingRules(tep)).To(Succeed())
+			Expect(lastScript()).To(ContainElements(
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 10.70.0.0/24 snat ip to 192.168.0.0",
+				"add rule inet liqo_cluster_1 postrouting_cluster ip saddr != 192.168.0.0/24 ip daddr 10.71.0.0/24 snat ip to 192.168.0.0",
+			))
+		})
+	})
+
+	Describe("EnsurePreroutingRulesPerTunnelEndpoint", func() {
+		It("should translate the remapped local PodCIDR", func() {
+			Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 prerouting_cluster",
+				"add rule inet liqo_cluster_1 prerouting_cluster ip saddr 10.60.0.0/24 ip daddr 192.168.1.0/24 " +
+					"dnat ip prefix to ip daddr map { 192.168.1.0/24 : 192.168.0.0/24 }",
+			}))
+		})
+
+		It("should translate the transit networks remapped by the remote cluster", func() {
+			tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+			tep.Spec.LocalTransitNetworks = []v1alpha1.TransitEndpoint{{
+				ClusterID: "cluster-3", PodCIDR: "10.80.0.0/24", NATPodCIDR: "10.81.0.0/24",
+				ExternalCIDR: "10.82.0.0/24", NATExternalCIDR: "10.83.0.0/24",
+			}}
+			Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 prerouting_cluster",
+				"add rule inet liqo_cluster_1 prerouting_cluster ip saddr 10.60.0.0/24 ip daddr 10.81.0.0/24 " +
+					"dnat ip prefix to ip daddr map { 10.81.0.0/24 : 10.80.0.0/24 }",
+				"add rule inet liqo_cluster_1 prerouting_cluster ip saddr 10.60.0.0/24 ip daddr 10.83.0.0/24 " +
+					"dnat ip prefix to ip daddr map { 10.83.0.0/24 : 10.82.0.0/24 }",
+			}))
+		})
+
+		It("should flush the chain if the local PodCIDR has not been remapped", func() {
+			tep.Spec.LocalNATPodCIDR = consts.DefaultCIDRValue
+			Expect(h.EnsurePreroutingRulesPerTunnelEndpoint(tep)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{"flush chain inet liqo_cluster_1 prerouting_cluster"}))
+		})
+	})
+
+	Describe("EnsurePreroutingRulesPerNatMapping", func() {
+		It("should configure a single rule per address family", func() {
+			nm := &v1alpha1.NatMapping{Spec: v1alpha1.NatMappingSpec{
+				ClusterID: clusterID1,
+				ClusterMappings: v1alpha1.Mappings{
+					"10.0.0.2":  "192.168.4.2",
+					"12.0.0.4":  "192.168.4.1",
+					"fd00::1:2": "fd00::2:1",
+				},
+			}}
+			Expect(h.EnsurePreroutingRulesPerNatMapping(nm)).To(Succeed())
+			Expect(lastScript()).To(Equal([]string{
+				"flush chain inet liqo_cluster_1 prerouting_mapping",
+				"add rule inet liqo_cluster_1 prerouting_mapping dnat ip to ip daddr map { 192.168.4.1 : 12.0.0.4, 192.168.4.2 : 10.0.0.2 }",
+				"add rule inet liqo_cluster_1 prerouting_mapping dnat ip6 to ip6 daddr map { fd00::2:1 : fd00::1:2 }",
+			}))
+		})
+
+		It("should fail if the cluster ID is empty", func() {
+			Expect(h.EnsurePreroutingRulesPerNatMapping(&v1alpha1.NatMapping{})).NotTo(Succeed())
+		})
+	})
+
+	Describe("RemoveIPTablesConfigurationPerCluster", func() {
+		It("should delete the table of the cluster, even if it does not exist", func() {
+			Expect(h.RemoveIPTablesConfigurationPerCluster(tep)).To(Succeed())
+			Expect(scripts).To(ConsistOf("table inet liqo_cluster_1 {\n}\ndelete table inet liqo_cluster_1\n"))
+		})
+	})
+
+	Describe("Terminate", func() {
+		It("should delete only the tables configured by liqo", func() {
+			tables = "table inet liqo_cluster_1\ntable ip filter\ntable inet liqo_cluster_2\ntable ip liqo_other\n"
+			Expect(h.Terminate()).To(Succeed())
+			Expect(scripts).To(ConsistOf("delete table inet liqo_cluster_1\ndelete table inet liqo_cluster_2\n"))
+		})
+
+		It("should not apply any script if no table has been configured", func() {
+			tables = "table ip filter\n"
+			Expect(h.Terminate()).To(Succeed())
+			Expect(scripts).To(BeEmpty())
+		})
+	})
+})
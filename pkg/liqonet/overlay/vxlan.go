@@ -84,6 +84,13 @@ func createVxLanLink(link *netlink.Vxlan) (*netlink.Vxlan, error) {
 		}
 		if isVxlanConfigTheSame(link, existing) {
 			klog.V(4).Infof("vxlan device with the same configuration already exists")
+			// The MTU can be updated in place, without recreating the device.
+			if link.MTU != 0 && link.MTU != existing.Attrs().MTU {
+				if err = netlink.LinkSetMTU(existing, link.MTU); err != nil {
+					return nil, fmt.Errorf("failed to set the MTU of the vxlan device with name %s: %w", link.Name, err)
+				}
+				existing.Attrs().MTU = link.MTU
+			}
 			link = existing.(*netlink.Vxlan)
 			return link, nil
 		}
@@ -29,12 +29,19 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
 	liqoneterrors "github.com/liqotech/liqo/pkg/liqonet/errors"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
 )
 
 // AddRoute adds a new route on the given interface.
 func AddRoute(dstNet, gwIP string, iFaceIndex, tableID int) (bool, error) {
+	return AddRouteWithMTU(dstNet, gwIP, iFaceIndex, tableID, 0)
+}
+
+// AddRouteWithMTU adds a new route on the given interface, limiting the size of the packets to the given MTU.
+// A zero MTU means that the MTU of the interface applies.
+func AddRouteWithMTU(dstNet, gwIP string, iFaceIndex, tableID, mtu int) (bool, error) {
 	var route *netlink.Route
 	var gatewayIP net.IP
 	// Convert destination in *net.IPNet.
@@ -54,9 +61,10 @@ func AddRoute(dstNet, gwIP string, iFaceIndex, tableID int) (bool, error) {
 		Dst:       destinationNet,
 		Gw:        gatewayIP,
 		LinkIndex: iFaceIndex,
+		MTU:       mtu,
 	}
 	// Check if already exists a route for the given destination.
-	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, route, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_DST)
+	routes, err := netlink.RouteListFiltered(ipFamily(destinationNet.IP), route, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_DST)
 	if err != nil {
 		return false, err
 	}
@@ -65,7 +73,7 @@ func AddRoute(dstNet, gwIP string, iFaceIndex, tableID int) (bool, error) {
 	if len(routes) == 1 {
 		r := routes[0]
 		// Check if the existing rule is equal to the one that we want to configure.
-		if reflect.DeepEqual(r.Gw, gatewayIP.To4()) && r.LinkIndex == iFaceIndex {
+		if reflect.DeepEqual(r.Gw, normalizeIP(gatewayIP)) && r.LinkIndex == iFaceIndex && r.MTU == mtu {
 			klog.V(5).Infof("route {%s} already exists", route.String())
 			return false, nil
 		}
@@ -121,7 +129,7 @@ func flushRoutesForRoutingTable(tableID int) error {
 	route := &netlink.Route{
 		Table: tableID,
 	}
-	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, route, netlink.RT_FILTER_TABLE)
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, route, netlink.RT_FILTER_TABLE)
 	if err != nil {
 		return err
 	}
@@ -145,7 +153,7 @@ func AddPolicyRoutingRule(fromSubnet, toSubnet string, tableID int) (bool, error
 		return false, err
 	}
 	// Get existing rules.
-	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	rules, err := netlink.RuleList(policyRuleFamily(sourceNet, destinationNet))
 	if err != nil {
 		klog.Errorf("an error occurred while listing the policy routing rules: %v", err)
 		return false, err
@@ -186,7 +194,7 @@ func DelPolicyRoutingRule(fromSubnet, toSubnet string, tableID int) (bool, error
 		return false, err
 	}
 	// Get existing rules.
-	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	rules, err := netlink.RuleList(policyRuleFamily(sourceNet, destinationNet))
 	if err != nil {
 		klog.Errorf("an error occurred while listing the policy routing rules: %v", err)
 		return false, err
@@ -206,7 +214,7 @@ func DelPolicyRoutingRule(fromSubnet, toSubnet string, tableID int) (bool, error
 
 func flushRulesForRoutingTable(routingTableID int) error {
 	// First we list all the policy routing rules.
-	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
 	if err != nil {
 		return err
 	}
@@ -227,10 +235,14 @@ func flushRulesForRoutingTable(routingTableID int) error {
 func getRouteConfig(tep *v1alpha1.TunnelEndpoint, podIP string) (dstPodCIDRNet, dstExternalCIDRNet, gatewayIP string, iFaceIndex int, err error) {
 	_, dstPodCIDRNet = utils.GetPodCIDRS(tep)
 	_, dstExternalCIDRNet = utils.GetExternalCIDRS(tep)
+	ipv6 := utils.IsIPv6Network(dstPodCIDRNet)
 	// Check if we are running on the same host as the gateway pod.
 	if tep.Status.GatewayIP != podIP {
 		// If the pod is not running on the same host then set the IP address of the Gateway as next hop.
 		gatewayIP = tep.Status.GatewayIP
+		if ipv6 {
+			gatewayIP = tep.Status.GatewayIPv6
+		}
 		// Get the iFace index for the IP address of the Gateway pod.
 		iFaceIndex, err = getIFaceIndexForIP(gatewayIP)
 		if err != nil {
@@ -239,10 +251,55 @@ func getRouteConfig(tep *v1alpha1.TunnelEndpoint, podIP string) (dstPodCIDRNet,
 	} else {
 		// Running on the same host as the Gateway then set the index of the veth device living on the same network namespace.
 		iFaceIndex = tep.Status.VethIFaceIndex
+		if ipv6 {
+			// Proxy arp is not available for IPv6, hence the link local address of the gateway veth is used as next hop.
+			gatewayIP = gatewayVethIPv6()
+		}
 	}
 	return dstPodCIDRNet, dstExternalCIDRNet, gatewayIP, iFaceIndex, err
 }
 
+// withIPv6View invokes the given function on the IPv6 view of the TunnelEndpoint, if the remote
+// cluster is a dual-stack one, and merges the outcome with the one of the IPv4 configuration.
+func withIPv6View(tep *v1alpha1.TunnelEndpoint, configured bool,
+	configure func(*v1alpha1.TunnelEndpoint) (bool, error)) (bool, error) {
+	tepv6 := utils.IPv6TunnelEndpoint(tep)
+	if tepv6 == nil {
+		return configured, nil
+	}
+	configuredV6, err := configure(tepv6)
+	return configured || configuredV6, err
+}
+
+// gatewayVethIPv6 returns the link local IPv6 address of the veth device living in the gateway network namespace.
+func gatewayVethIPv6() string {
+	return strings.Split(liqoconst.GatewayVethIPv6Addr, "/")[0]
+}
+
+// ipFamily returns the netlink family of the given IP address.
+func ipFamily(ip net.IP) int {
+	if ip.To4() == nil {
+		return netlink.FAMILY_V6
+	}
+	return netlink.FAMILY_V4
+}
+
+// policyRuleFamily returns the netlink family of the policy routing rule with the given networks, at least one is not nil.
+func policyRuleFamily(sourceNet, destinationNet *net.IPNet) int {
+	if destinationNet != nil {
+		return ipFamily(destinationNet.IP)
+	}
+	return ipFamily(sourceNet.IP)
+}
+
+// normalizeIP returns the IPv4 addresses in their 4 bytes representation, as returned by netlink.
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
 func getIFaceIndexForIP(ipAddress string) (int, error) {
 	// Convert the given IP address from string to net.IP format
 	ip := net.ParseIP(ipAddress)
@@ -251,7 +308,7 @@ func getIFaceIndexForIP(ipAddress string) (int, error) {
 			IPToBeParsed: ipAddress,
 		}
 	}
-	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	routes, err := netlink.RouteList(nil, ipFamily(ip))
 	if err != nil {
 		return 0, err
 	}
@@ -310,6 +367,11 @@ func EnableIPForwarding() error {
 	return ioutil.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0600)
 }
 
+// EnableIPv6Forwarding enables ipv6 forwarding in the current network namespace.
+func EnableIPv6Forwarding() error {
+	return ioutil.WriteFile("/proc/sys/net/ipv6/conf/all/forwarding", []byte("1"), 0600)
+}
+
 // EnableProxyArp enables proxy arp for the given network interface.
 func EnableProxyArp(iFaceName string) error {
 	proxyArpFilePath := strings.Join([]string{"/proc/sys/net/ipv4/conf/", iFaceName, "/proxy_arp"}, "")
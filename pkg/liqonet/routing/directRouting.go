@@ -81,20 +81,20 @@ func (drm *DirectRoutingManager) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelE
 	// Add routes for the given cluster.
 	klog.Infof("%s -> adding route for destination {%s} with gateway {%s} in routing table with ID {%d}",
 		clusterID, dstPodCIDR, gatewayIP, drm.routingTableID)
-	routePodCIDRAdd, err = AddRoute(dstPodCIDR, gatewayIP, iFaceIndex, drm.routingTableID)
+	routePodCIDRAdd, err = AddRouteWithMTU(dstPodCIDR, gatewayIP, iFaceIndex, drm.routingTableID, tep.Status.MTU)
 	if err != nil {
 		return routePodCIDRAdd, err
 	}
 	klog.Infof("%s -> adding route for destination {%s} with gateway {%s} in routing table with ID {%d}",
 		clusterID, dstExternalCIDR, gatewayIP, drm.routingTableID)
-	routeExternalCIDRAdd, err = AddRoute(dstExternalCIDR, gatewayIP, iFaceIndex, drm.routingTableID)
+	routeExternalCIDRAdd, err = AddRouteWithMTU(dstExternalCIDR, gatewayIP, iFaceIndex, drm.routingTableID, tep.Status.MTU)
 	if err != nil {
 		return routeExternalCIDRAdd, err
 	}
 	if routePodCIDRAdd || routeExternalCIDRAdd || policyRulePodCIDRAdd || policyRuleExternalCIDRAdd {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, drm.EnsureRoutesPerCluster)
 }
 
 // RemoveRoutesPerCluster accepts as input a netv1alpha.tunnelendpoint.
@@ -136,7 +136,7 @@ func (drm *DirectRoutingManager) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelE
 	if routePodCIDRDel || routeExternalCIDRDel || policyRulePodCIDRDel || policyRuleExternalCIDRDel {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, drm.RemoveRoutesPerCluster)
 }
 
 // CleanRoutingTable removes all the routes from the custom routing table used by the route manager.
@@ -64,18 +64,18 @@ func (grm *GatewayRoutingManager) EnsureRoutesPerCluster(tep *netv1alpha1.Tunnel
 	_, dstPodCIDRNet := utils.GetPodCIDRS(tep)
 	_, dstExternalCIDRNet := utils.GetExternalCIDRS(tep)
 	// Add routes for the given cluster.
-	routePodCIDRAdd, err = AddRoute(dstPodCIDRNet, "", grm.tunnelDevice.Attrs().Index, grm.routingTableID)
+	routePodCIDRAdd, err = AddRouteWithMTU(dstPodCIDRNet, "", grm.tunnelDevice.Attrs().Index, grm.routingTableID, tep.Status.MTU)
 	if err != nil {
 		return routePodCIDRAdd, err
 	}
-	routeExternalCIDRAdd, err = AddRoute(dstExternalCIDRNet, "", grm.tunnelDevice.Attrs().Index, grm.routingTableID)
+	routeExternalCIDRAdd, err = AddRouteWithMTU(dstExternalCIDRNet, "", grm.tunnelDevice.Attrs().Index, grm.routingTableID, tep.Status.MTU)
 	if err != nil {
 		return routeExternalCIDRAdd, err
 	}
 	if routePodCIDRAdd || routeExternalCIDRAdd {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, grm.EnsureRoutesPerCluster)
 }
 
 // RemoveRoutesPerCluster accepts as input a netv1alpha.tunnelendpoint.
@@ -100,7 +100,7 @@ func (grm *GatewayRoutingManager) RemoveRoutesPerCluster(tep *netv1alpha1.Tunnel
 	if routePodCIDRDel || routeExternalCIDRDel {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, grm.RemoveRoutesPerCluster)
 }
 
 // CleanRoutingTable stub function, as the gateway only operates in custom network namespace.
@@ -148,6 +148,20 @@ var _ = Describe("GatewayRouting", func() {
 				Expect(routes[0].Gw).Should(BeNil())
 			})
 
+			It("route configuration should be inserted with the MTU of the tunnel", func() {
+				tepMTU := tep.DeepCopy()
+				tepMTU.Status.MTU = 1380
+				added, err := grm.EnsureRoutesPerCluster(tepMTU)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(added).Should(BeTrue())
+				_, dstPodCIDRNet, err := net.ParseCIDR(tepMTU.Spec.RemoteNATPodCIDR)
+				Expect(err).ShouldNot(HaveOccurred())
+				routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{Dst: dstPodCIDRNet,
+					Table: routingTableIDGRM}, netlink.RT_FILTER_DST|netlink.RT_FILTER_TABLE)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routes[0].MTU).Should(Equal(1380))
+			})
+
 			It("route already exists, should return false and nil", func() {
 				tepGRM.Spec.RemoteNATPodCIDR = existingRoutesGRM[0].Dst.String()
 				tepGRM.Spec.RemoteNATExternalCIDR = existingRoutesGRM[1].Dst.String()
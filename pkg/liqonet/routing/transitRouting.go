@@ -0,0 +1,121 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// TransitRoutingManager wraps a routing manager, additionally configuring through it the routes towards the clusters
+// reached through the remote ones. The transit networks configured for each remote cluster are tracked, so that the
+// routes towards the ones no longer advertised are removed.
+type TransitRoutingManager struct {
+	Routing
+	mutex sync.Mutex
+	// configured contains, for each remote cluster, the views of the transit clusters whose routes have been configured.
+	configured map[string][]*netv1alpha1.TunnelEndpoint
+}
+
+// NewTransitRoutingManager returns a TransitRoutingManager wrapping the given routing manager.
+func NewTransitRoutingManager(routing Routing) Routing {
+	return &TransitRoutingManager{
+		Routing:    routing,
+		configured: make(map[string][]*netv1alpha1.TunnelEndpoint),
+	}
+}
+
+// EnsureRoutesPerCluster configures the routes towards the remote cluster and the clusters reached through it,
+// removing the ones towards the transit clusters no longer advertised.
+// Returns true if any route has been configured or removed.
+func (trm *TransitRoutingManager) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	configured, err := trm.Routing.EnsureRoutesPerCluster(tep)
+	if err != nil {
+		return configured, err
+	}
+
+	trm.mutex.Lock()
+	defer trm.mutex.Unlock()
+	clusterID := tep.Spec.ClusterID
+	transits := utils.TransitTunnelEndpoints(tep)
+	for _, stale := range trm.configured[clusterID] {
+		if containsTransitView(transits, stale) {
+			continue
+		}
+		_, remotePodCIDR := utils.GetPodCIDRS(stale)
+		klog.Infof("%s -> removing the routes towards the transit network {%s} no longer advertised", clusterID, remotePodCIDR)
+		removed, err := trm.Routing.RemoveRoutesPerCluster(stale)
+		if err != nil {
+			return configured, err
+		}
+		configured = configured || removed
+	}
+	// The transit networks are tracked before configuring them, so that they are removed in any case when no longer needed.
+	trm.configured[clusterID] = transits
+	for _, transit := range transits {
+		added, err := trm.Routing.EnsureRoutesPerCluster(transit)
+		if err != nil {
+			return configured, err
+		}
+		configured = configured || added
+	}
+	return configured, nil
+}
+
+// RemoveRoutesPerCluster removes the routes towards the remote cluster and the clusters reached through it.
+// Returns true if any route has been removed.
+func (trm *TransitRoutingManager) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	removed, err := trm.Routing.RemoveRoutesPerCluster(tep)
+	if err != nil {
+		return removed, err
+	}
+
+	trm.mutex.Lock()
+	defer trm.mutex.Unlock()
+	clusterID := tep.Spec.ClusterID
+	transits := trm.configured[clusterID]
+	for _, transit := range utils.TransitTunnelEndpoints(tep) {
+		if !containsTransitView(transits, transit) {
+			transits = append(transits, transit)
+		}
+	}
+	for _, transit := range transits {
+		transitRemoved, err := trm.Routing.RemoveRoutesPerCluster(transit)
+		if err != nil {
+			return removed, err
+		}
+		removed = removed || transitRemoved
+	}
+	delete(trm.configured, clusterID)
+	return removed, nil
+}
+
+// containsTransitView returns whether the given views contain one with the same remote networks of view.
+func containsTransitView(views []*netv1alpha1.TunnelEndpoint, view *netv1alpha1.TunnelEndpoint) bool {
+	_, podCIDR := utils.GetPodCIDRS(view)
+	_, externalCIDR := utils.GetExternalCIDRS(view)
+	for _, v := range views {
+		_, vPodCIDR := utils.GetPodCIDRS(v)
+		_, vExternalCIDR := utils.GetExternalCIDRS(v)
+		if vPodCIDR == podCIDR && vExternalCIDR == externalCIDR {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+// fakeRouting records the remote PodCIDRs whose routes are configured.
+type fakeRouting struct {
+	Routing
+	routes map[string]struct{}
+}
+
+func (fr *fakeRouting) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	_, remotePodCIDR := utils.GetPodCIDRS(tep)
+	_, exists := fr.routes[remotePodCIDR]
+	fr.routes[remotePodCIDR] = struct{}{}
+	return !exists, nil
+}
+
+func (fr *fakeRouting) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelEndpoint) (bool, error) {
+	_, remotePodCIDR := utils.GetPodCIDRS(tep)
+	_, exists := fr.routes[remotePodCIDR]
+	delete(fr.routes, remotePodCIDR)
+	return exists, nil
+}
+
+var _ = Describe("TransitRoutingManager", func() {
+	var (
+		fr         *fakeRouting
+		trm        Routing
+		transitTep *netv1alpha1.TunnelEndpoint
+	)
+
+	BeforeEach(func() {
+		fr = &fakeRouting{routes: make(map[string]struct{})}
+		trm = NewTransitRoutingManager(fr)
+		transitTep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterID:             "cluster-1",
+			RemotePodCIDR:         "10.0.0.0/16",
+			RemoteNATPodCIDR:      "None",
+			RemoteExternalCIDR:    "10.1.0.0/16",
+			RemoteNATExternalCIDR: "None",
+			RemoteTransitNetworks: []netv1alpha1.TransitEndpoint{
+				{ClusterID: "cluster-2", PodCIDR: "10.2.0.0/16", NATPodCIDR: "None", ExternalCIDR: "10.3.0.0/16", NATExternalCIDR: "None"},
+				{ClusterID: "cluster-3", PodCIDR: "10.4.0.0/16", NATPodCIDR: "10.6.0.0/16", ExternalCIDR: "10.5.0.0/16", NATExternalCIDR: "None"},
+			},
+		}}
+	})
+
+	It("should configure the routes towards the remote cluster and the transit ones", func() {
+		configured, err := trm.EnsureRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configured).To(BeTrue())
+		Expect(fr.routes).To(HaveLen(3))
+		Expect(fr.routes).To(HaveKey("10.0.0.0/16"))
+		Expect(fr.routes).To(HaveKey("10.2.0.0/16"))
+		Expect(fr.routes).To(HaveKey("10.6.0.0/16"))
+
+		configured, err = trm.EnsureRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configured).To(BeFalse())
+	})
+
+	It("should remove the routes towards the transit networks no longer advertised", func() {
+		_, err := trm.EnsureRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		transitTep.Spec.RemoteTransitNetworks = transitTep.Spec.RemoteTransitNetworks[:1]
+		configured, err := trm.EnsureRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configured).To(BeTrue())
+		Expect(fr.routes).To(HaveLen(2))
+		Expect(fr.routes).ToNot(HaveKey("10.6.0.0/16"))
+	})
+
+	It("should remove all the routes when the remote cluster is removed", func() {
+		_, err := trm.EnsureRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		transitTep.Spec.RemoteTransitNetworks = nil
+		removed, err := trm.RemoveRoutesPerCluster(transitTep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(BeTrue())
+		Expect(fr.routes).To(BeEmpty())
+	})
+})
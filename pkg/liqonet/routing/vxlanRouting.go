@@ -88,12 +88,14 @@ func (vrm *VxlanRoutingManager) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelEn
 	// Extract and save route information from the given tep.
 	_, dstPodCIDR := utils.GetPodCIDRS(tep)
 	_, dstExternalCIDR := utils.GetExternalCIDRS(tep)
-	if tep.Status.GatewayIP != vrm.podIP {
-		gatewayIP = utils.GetOverlayIP(tep.Status.GatewayIP)
-		iFaceIndex = vrm.vxlanDevice.Link.Index
-	} else {
-		iFaceIndex = tep.Status.VethIFaceIndex
+	if utils.IsIPv6Network(dstPodCIDR) {
+		// The IPv6 overlay address is configured only once a dual-stack remote cluster is found.
+		if err = vrm.vxlanDevice.ConfigureIPAddress(utils.GetOverlayIPv6(vrm.podIP) + liqoconst.OverlayNetworkMaskV6); err != nil {
+			return false, err
+		}
 	}
+	gatewayIP, iFaceIndex = vrm.nextHop(tep, dstPodCIDR)
+	mtu := vrm.routeMTU(tep, iFaceIndex)
 	// Add policy routing rule for the given cluster.
 	klog.V(4).Infof("%s -> adding policy routing rule for destination {%s} to lookup routing table with ID {%d}",
 		clusterID, dstPodCIDR, vrm.routingTableID)
@@ -108,20 +110,20 @@ func (vrm *VxlanRoutingManager) EnsureRoutesPerCluster(tep *netv1alpha1.TunnelEn
 	// Add route for the given cluster.
 	klog.V(4).Infof("%s -> adding route for destination {%s} with gateway {%s} in routing table with ID {%d} on device {%s}",
 		clusterID, dstPodCIDR, gatewayIP, vrm.routingTableID, vrm.vxlanDevice.Link.Name)
-	routePodCIDRAdd, err = AddRoute(dstPodCIDR, gatewayIP, iFaceIndex, vrm.routingTableID)
+	routePodCIDRAdd, err = AddRouteWithMTU(dstPodCIDR, gatewayIP, iFaceIndex, vrm.routingTableID, mtu)
 	if err != nil {
 		return routePodCIDRAdd, err
 	}
 	klog.V(4).Infof("%s -> adding route for destination {%s} with gateway {%s} in routing table with ID {%d} on device {%s}",
 		clusterID, dstExternalCIDR, gatewayIP, vrm.routingTableID, vrm.vxlanDevice.Link.Name)
-	routeExternalCIDRAdd, err = AddRoute(dstExternalCIDR, gatewayIP, iFaceIndex, vrm.routingTableID)
+	routeExternalCIDRAdd, err = AddRouteWithMTU(dstExternalCIDR, gatewayIP, iFaceIndex, vrm.routingTableID, mtu)
 	if err != nil {
 		return routeExternalCIDRAdd, err
 	}
 	if routePodCIDRAdd || routeExternalCIDRAdd || policyRulePodCIDRAdd || policyRuleExternalCIDRAdd {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, vrm.EnsureRoutesPerCluster)
 }
 
 // RemoveRoutesPerCluster accepts as input a netv1alpha.tunnelendpoint.
@@ -137,12 +139,7 @@ func (vrm *VxlanRoutingManager) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelEn
 	// Extract and save route information from the given tep.
 	_, dstPodCIDRNet := utils.GetPodCIDRS(tep)
 	_, dstExternalCIDRNet := utils.GetExternalCIDRS(tep)
-	if tep.Status.GatewayIP != vrm.podIP {
-		gatewayIP = utils.GetOverlayIP(tep.Status.GatewayIP)
-		iFaceIndex = vrm.vxlanDevice.Link.Index
-	} else {
-		iFaceIndex = tep.Status.VethIFaceIndex
-	}
+	gatewayIP, iFaceIndex = vrm.nextHop(tep, dstPodCIDRNet)
 	// Delete policy routing rule for the given cluster.
 	klog.V(4).Infof("%s -> deleting policy routing rule for destination {%s} to lookup routing table with ID {%d}",
 		clusterID, dstPodCIDRNet, vrm.routingTableID)
@@ -170,7 +167,32 @@ func (vrm *VxlanRoutingManager) RemoveRoutesPerCluster(tep *netv1alpha1.TunnelEn
 	if policyRulePodCIDRDel || policyRuleExternalCIDRDel || routePodCIDRDel || routeExternalCIDRDel {
 		configured = true
 	}
-	return configured, nil
+	return withIPv6View(tep, configured, vrm.RemoveRoutesPerCluster)
+}
+
+// nextHop returns the next hop and the index of the interface used to reach the given remote network.
+func (vrm *VxlanRoutingManager) nextHop(tep *netv1alpha1.TunnelEndpoint, dstNet string) (gatewayIP string, iFaceIndex int) {
+	ipv6 := utils.IsIPv6Network(dstNet)
+	switch {
+	case tep.Status.GatewayIP != vrm.podIP && ipv6:
+		return utils.GetOverlayIPv6(tep.Status.GatewayIP), vrm.vxlanDevice.Link.Index
+	case tep.Status.GatewayIP != vrm.podIP:
+		return utils.GetOverlayIP(tep.Status.GatewayIP), vrm.vxlanDevice.Link.Index
+	case ipv6:
+		return gatewayVethIPv6(), tep.Status.VethIFaceIndex
+	default:
+		return "", tep.Status.VethIFaceIndex
+	}
+}
+
+// routeMTU returns the MTU of the routes towards the remote networks, i.e. the one of the tunnel, further limited
+// by the one of the vxlan device in case the traffic is forwarded to the gateway through the overlay.
+func (vrm *VxlanRoutingManager) routeMTU(tep *netv1alpha1.TunnelEndpoint, iFaceIndex int) int {
+	mtu := tep.Status.MTU
+	if iFaceIndex == vrm.vxlanDevice.Link.Index && mtu > vrm.vxlanDevice.Link.MTU {
+		return vrm.vxlanDevice.Link.MTU
+	}
+	return mtu
 }
 
 // CleanRoutingTable removes all the routes from the custom routing table used by the route manager.
@@ -0,0 +1,65 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"hash/fnv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	podutils "github.com/liqotech/liqo/pkg/utils/pod"
+)
+
+// IsEligible returns whether the given gateway replica can be assigned tunnels, i.e. it is
+// running, ready and not being terminated, and it has already been assigned an IP address.
+func IsEligible(pod *corev1.Pod) bool {
+	return pod.GetDeletionTimestamp().IsZero() && pod.Status.Phase == corev1.PodRunning &&
+		pod.Status.PodIP != "" && podutils.IsPodReady(pod)
+}
+
+// EligibleReplicas returns the subset of the given gateway replicas which can be assigned tunnels.
+func EligibleReplicas(pods []corev1.Pod) []corev1.Pod {
+	eligible := make([]corev1.Pod, 0, len(pods))
+	for i := range pods {
+		if IsEligible(&pods[i]) {
+			eligible = append(eligible, pods[i])
+		}
+	}
+	return eligible
+}
+
+// Owner returns the replica the tunnel towards the given cluster is assigned to, or nil if no replica is given.
+// The assignment is performed through rendezvous hashing, hence it is consistent across the different components
+// and, when a replica is lost, only the tunnels it was handling are reassigned, evenly among the remaining ones.
+func Owner(clusterID string, replicas []corev1.Pod) *corev1.Pod {
+	var owner *corev1.Pod
+	var highest uint64
+	for i := range replicas {
+		score := weight(clusterID, replicas[i].GetName())
+		// Ties are broken by name, to guarantee the result does not depend on the order of the replicas.
+		if owner == nil || score > highest || (score == highest && replicas[i].GetName() < owner.GetName()) {
+			owner, highest = &replicas[i], score
+		}
+	}
+	return owner
+}
+
+// weight returns the score of the given replica for the given cluster.
+func weight(clusterID, replica string) uint64 {
+	hash := fnv.New64a()
+	// The separator prevents different pairs with the same concatenation from colliding.
+	_, _ = hash.Write([]byte(clusterID + "/" + replica))
+	return hash.Sum64()
+}
@@ -0,0 +1,114 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func replica(name string, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			PodIP:      "10.0.0.1",
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func clusterIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("cluster-%d", i)
+	}
+	return ids
+}
+
+var _ = Describe("Sharding", func() {
+	DescribeTable("the IsEligible function",
+		func(mutate func(pod *corev1.Pod), expected bool) {
+			pod := replica("gateway-1", true)
+			mutate(&pod)
+			Expect(IsEligible(&pod)).To(Equal(expected))
+		},
+		Entry("running and ready replica", func(pod *corev1.Pod) {}, true),
+		Entry("replica not ready", func(pod *corev1.Pod) { pod.Status.Conditions[0].Status = corev1.ConditionFalse }, false),
+		Entry("replica not running", func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodPending }, false),
+		Entry("replica without IP", func(pod *corev1.Pod) { pod.Status.PodIP = "" }, false),
+		Entry("replica being terminated", func(pod *corev1.Pod) { pod.DeletionTimestamp = &metav1.Time{Time: time.Now()} }, false),
+	)
+
+	It("should filter out the replicas which are not eligible", func() {
+		replicas := EligibleReplicas([]corev1.Pod{replica("gateway-1", true), replica("gateway-2", false), replica("gateway-3", true)})
+		Expect(replicas).To(HaveLen(2))
+		Expect(replicas[0].Name).To(Equal("gateway-1"))
+		Expect(replicas[1].Name).To(Equal("gateway-3"))
+	})
+
+	Describe("the Owner function", func() {
+		var replicas []corev1.Pod
+
+		BeforeEach(func() {
+			replicas = []corev1.Pod{replica("gateway-1", true), replica("gateway-2", true), replica("gateway-3", true)}
+		})
+
+		It("should return nil if no replica is available", func() {
+			Expect(Owner("cluster-0", nil)).To(BeNil())
+		})
+
+		It("should not depend on the order of the replicas", func() {
+			reversed := []corev1.Pod{replicas[2], replicas[1], replicas[0]}
+			for _, id := range clusterIDs(50) {
+				Expect(Owner(id, reversed).Name).To(Equal(Owner(id, replicas).Name))
+			}
+		})
+
+		It("should spread the tunnels among the replicas", func() {
+			assigned := map[string]int{}
+			for _, id := range clusterIDs(300) {
+				assigned[Owner(id, replicas).Name]++
+			}
+			Expect(assigned).To(HaveLen(3))
+			for _, count := range assigned {
+				Expect(count).To(BeNumerically(">", 50))
+			}
+		})
+
+		It("should reassign only the tunnels of a lost replica", func() {
+			remaining := []corev1.Pod{replicas[0], replicas[2]}
+			for _, id := range clusterIDs(100) {
+				before := Owner(id, replicas).Name
+				after := Owner(id, remaining).Name
+				if before != "gateway-2" {
+					Expect(after).To(Equal(before))
+				} else {
+					Expect(after).ToNot(Equal("gateway-2"))
+				}
+			}
+		})
+	})
+})
@@ -27,6 +27,7 @@ import (
 type MockIpam struct {
 	LocalRemappedPodCIDR  string
 	RemoteRemappedPodCIDR string
+	PodCIDR               string
 }
 
 // MapEndpointIP mocks the corresponding func in IPAM.
@@ -61,3 +62,32 @@ func (mock *MockIpam) GetHomePodIP(
 	}
 	return &liqonetIpam.GetHomePodIPResponse{HomeIP: homeIP}, nil
 }
+
+// ListClusterSubnets mocks the corresponding func in IPAM.
+func (mock *MockIpam) ListClusterSubnets(
+	ctx context.Context,
+	in *liqonetIpam.ListClusterSubnetsRequest,
+	opts ...grpc.CallOption) (*liqonetIpam.ListClusterSubnetsResponse, error) {
+	if mock.LocalRemappedPodCIDR == "" {
+		return &liqonetIpam.ListClusterSubnetsResponse{}, nil
+	}
+	return &liqonetIpam.ListClusterSubnetsResponse{ClusterSubnets: []*liqonetIpam.ClusterSubnets{
+		{ClusterID: in.GetClusterID(), LocalNATPodCIDR: mock.LocalRemappedPodCIDR},
+	}}, nil
+}
+
+// ListPools mocks the corresponding func in IPAM.
+func (mock *MockIpam) ListPools(
+	ctx context.Context,
+	in *liqonetIpam.ListPoolsRequest,
+	opts ...grpc.CallOption) (*liqonetIpam.ListPoolsResponse, error) {
+	return &liqonetIpam.ListPoolsResponse{PodCIDR: mock.PodCIDR}, nil
+}
+
+// ListEndpointMappings mocks the corresponding func in IPAM.
+func (mock *MockIpam) ListEndpointMappings(
+	ctx context.Context,
+	in *liqonetIpam.ListEndpointMappingsRequest,
+	opts ...grpc.CallOption) (*liqonetIpam.ListEndpointMappingsResponse, error) {
+	return &liqonetIpam.ListEndpointMappingsResponse{}, nil
+}
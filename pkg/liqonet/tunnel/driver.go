@@ -15,6 +15,8 @@
 package tunnel
 
 import (
+	"time"
+
 	"github.com/vishvananda/netlink"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
@@ -49,3 +51,30 @@ type Driver interface {
 
 	Close() error
 }
+
+// KeyRotator is the interface optionally implemented by the drivers supporting the periodic rotation of their keys.
+type KeyRotator interface {
+	// KeyRotationStatus returns the current status of the key rotation.
+	KeyRotationStatus() *netv1alpha1.KeyRotationStatus
+	// KeyRotated returns a channel which is notified every time the local key is rotated.
+	KeyRotated() <-chan struct{}
+}
+
+// PeerStatistics holds the traffic statistics concerning the peer corresponding to a remote cluster.
+type PeerStatistics struct {
+	LastHandshakeTime time.Time
+	ReceivedBytes     int64
+	TransmittedBytes  int64
+}
+
+// StatisticsReporter is the interface optionally implemented by the drivers able to report peer statistics.
+type StatisticsReporter interface {
+	// PeerStatistics returns the traffic statistics concerning the peer corresponding to the given remote cluster.
+	PeerStatistics(clusterID string) (*PeerStatistics, error)
+}
+
+// OverheadReporter is the interface optionally implemented by the drivers able to report their encapsulation overhead.
+type OverheadReporter interface {
+	// Overhead returns the number of bytes added by the driver to each packet traversing the tunnel.
+	Overhead() int
+}
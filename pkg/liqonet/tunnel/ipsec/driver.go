@@ -0,0 +1,696 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
+	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
+	"github.com/liqotech/liqo/pkg/liqonet/utils"
+)
+
+const (
+	// PublicKey is the key of the IPsec publicKey entry in back-end map and also for the secret containing the IPsec keys.
+	PublicKey = "ipsecPublicKey"
+	// PrivateKey is the key of the private key for the secret containing the IPsec keys.
+	PrivateKey = "ipsecPrivateKey"
+	// ListeningPort is the key of the IPsec listeningPort entry in the back-end map,
+	// i.e. the port where the ESP packets encapsulated in UDP are received.
+	ListeningPort = "ipsecPort"
+	// EndpointIP is the key of the endpointIP entry in the peer configuration.
+	EndpointIP = "endpointIP"
+	// AllowedIPs is the key of the allowedIPs entry in the peer configuration.
+	AllowedIPs = "allowedIPs"
+	// DeviceName name of the xfrm interface created on the custom network namespace.
+	// This interface is used to interconnect the local cluster with the remote ones.
+	DeviceName = "liqo.ipsec"
+	// DriverName name of the driver which is also used as the type of the backend in tunnelendpoint CRD.
+	DriverName = "ipsec"
+	// name of the secret that contains the keys used by IPsec.
+	keysName = "ipsec-keys"
+	// KeysLabel label for the secret that contains the public key.
+	KeysLabel = "net.liqo.io/key"
+	// DefaultPort is the port used to receive the ESP packets encapsulated in UDP (i.e. the standard NAT-T port).
+	DefaultPort = 4500
+	// MTU size of mtu for the xfrm interface, accounting for the ESP over UDP overhead.
+	MTU = 1400
+
+	// ifID identifies the xfrm interface, and the states and policies associated with it.
+	ifID = 0x4c49
+	// aeadAlgorithm is the algorithm used to encrypt and authenticate the ESP packets.
+	aeadAlgorithm = "rfc4106(gcm(aes))"
+	// aeadICVLen is the length in bits of the integrity check value.
+	aeadICVLen = 128
+	// replayWindow is the size of the anti-replay window.
+	replayWindow = 32
+
+	// Socket options to enable the ESP over UDP encapsulation (from linux/udp.h).
+	udpEncap         = 100
+	udpEncapESPInUDP = 2
+
+	// Ports 1-65535 are available.
+	udpMinPort = 1
+	udpMaxPort = 65535
+)
+
+// Registering the driver as available.
+func init() {
+	tunnel.AddDriver(DriverName, NewDriver)
+}
+
+// ResolverFunc type of function that knows how to resolve an ip address.
+type ResolverFunc func(network string, address string) (*net.IPAddr, error)
+
+// xfrmHandle is the subset of the operations of the netlink handle used to configure the xfrm states and policies.
+type xfrmHandle interface {
+	XfrmStateAdd(state *netlink.XfrmState) error
+	XfrmStateDel(state *netlink.XfrmState) error
+	XfrmStateList(family int) ([]netlink.XfrmState, error)
+	XfrmPolicyAdd(policy *netlink.XfrmPolicy) error
+	XfrmPolicyDel(policy *netlink.XfrmPolicy) error
+	XfrmPolicyList(family int) ([]netlink.XfrmPolicy, error)
+	Delete()
+}
+
+// connection stores the xfrm configuration established towards a remote cluster.
+type connection struct {
+	status *netv1alpha1.Connection
+	// outbound and inbound are the installed security associations the traffic is sent and received with.
+	outbound *netlink.XfrmState
+	inbound  *netlink.XfrmState
+	policies []*netlink.XfrmPolicy
+	// nextOutbound is the outbound security association derived from the current nonces, not yet installed,
+	// which replaces the current one at switchTime (see rekeyConnection).
+	nextOutbound *netlink.XfrmState
+	switchTime   time.Time
+	// retired are the installed security associations replaced by a rekey, removed once expired.
+	retired []retiredState
+	// localNonce and remoteNonce are the nonces the security associations have been derived from.
+	localNonce  string
+	remoteNonce string
+}
+
+// retiredState is a security association replaced by a rekey, which is kept until the expiration time.
+type retiredState struct {
+	state      *netlink.XfrmState
+	expiration time.Time
+}
+
+// states returns the security associations of the connection currently installed.
+func (con *connection) states() []*netlink.XfrmState {
+	var states []*netlink.XfrmState
+	for _, state := range []*netlink.XfrmState{con.outbound, con.inbound} {
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+	for _, retired := range con.retired {
+		states = append(states, retired.state)
+	}
+	return states
+}
+
+// IPsec a wrapper for the xfrm interface and the configuration of the IPsec tunnels.
+type IPsec struct {
+	// mutex protects the connections and the rekey state, accessed also by the periodic rekey.
+	mutex       sync.Mutex
+	connections map[string]*connection
+	rekey       rekeyState
+	k8sClient   k8s.Interface
+	namespace   string
+	// handle is bound to the network namespace the driver is created in, which is the one the ESP packets flow through.
+	handle  xfrmHandle
+	link    netlink.Link
+	socket  int
+	localIP net.IP
+	port    int
+	priKey  Key
+	pubKey  Key
+}
+
+// NewDriver creates a new IPsec driver.
+func NewDriver(k8sClient k8s.Interface, namespace string) (tunnel.Driver, error) {
+	var err error
+	i := IPsec{
+		connections: make(map[string]*connection),
+		rekey:       rekeyState{interval: rekeyInterval, rekeyed: make(chan struct{}, 1)},
+		k8sClient:   k8sClient,
+		namespace:   namespace,
+		socket:      -1,
+		port:        DefaultPort,
+	}
+
+	defer func() {
+		if err != nil {
+			if e := i.Close(); e != nil {
+				klog.Errorf("failed to clean up the %s driver: %v", DriverName, e)
+			}
+		}
+	}()
+
+	if i.localIP, err = utils.GetPodIP(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve the local IP address: %w", err)
+	}
+	var handle *netlink.Handle
+	if handle, err = netlink.NewHandle(unix.NETLINK_XFRM); err != nil {
+		return nil, fmt.Errorf("failed to open xfrm netlink handle: %w", err)
+	}
+	i.handle = handle
+	// Remove the configuration possibly left over by a previous instance.
+	if err = i.flushXfrmConfiguration(); err != nil {
+		return nil, err
+	}
+	if err = i.setXfrmLink(); err != nil {
+		return nil, fmt.Errorf("failed to setup %s link: %w", DriverName, err)
+	}
+	if i.socket, err = openEncapSocket(i.port); err != nil {
+		return nil, err
+	}
+	// The keys are configured last, so that they are advertised only if the driver is correctly set up.
+	if err = i.setKeys(k8sClient, namespace); err != nil {
+		return nil, err
+	}
+
+	klog.Infof("created %s interface named %s with publicKey %s", DriverName, DeviceName, i.pubKey.String())
+	return &i, nil
+}
+
+// Init initializes the xfrm interface.
+func (i *IPsec) Init() error {
+	if err := netlink.LinkSetUp(i.link); err != nil {
+		return fmt.Errorf("failed to bring up %s device: %w", DriverName, err)
+	}
+
+	if err := netlink.LinkSetMTU(i.link, MTU); err != nil {
+		return fmt.Errorf("failed to set mtu for interface %s: %w", DeviceName, err)
+	}
+
+	klog.Infof("%s interface named %s, is up on i/f number %d, listening on port :%d, with key %s", DriverName,
+		i.link.Attrs().Name, i.link.Attrs().Index, i.port, i.pubKey)
+
+	if i.rekey.stop == nil {
+		i.rekey.stop = make(chan struct{})
+		go i.runRekey(i.rekey.stop)
+	}
+	return nil
+}
+
+// ConnectToEndpoint connects to a remote cluster described by the given tep.
+func (i *IPsec) ConnectToEndpoint(tep *netv1alpha1.TunnelEndpoint) (*netv1alpha1.Connection, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	// the nonce is regenerated as soon as the replica starts handling the tunnels, to let the remote clusters
+	// retrieve it, regardless of whether they already advertised their own one.
+	if i.rekey.nonce == "" {
+		if err := i.regenerateNonce(time.Now()); err != nil {
+			return newConnectionOnError(err.Error()), err
+		}
+	}
+
+	// parse the remote CIDRs.
+	remoteCIDRs, stringRemoteCIDRs, err := getRemoteCIDRs(tep)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	// parse remote public key.
+	remoteKey, err := getKey(tep)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	// parse remote endpoint.
+	endpoint, err := getEndpoint(tep, net.ResolveIPAddr)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	// parse remote nonce.
+	remoteNonce, err := getNonce(tep)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	peerConfiguration := map[string]string{ListeningPort: strconv.Itoa(endpoint.Port), EndpointIP: endpoint.IP.String(),
+		AllowedIPs: stringRemoteCIDRs, PublicKey: remoteKey.String(), Nonce: remoteNonce}
+
+	// check if the peer configuration and the local nonce are updated.
+	if oldCon, found := i.connections[tep.Spec.ClusterID]; found && oldCon.localNonce == i.rekey.nonce &&
+		reflect.DeepEqual(oldCon.status.PeerConfiguration, peerConfiguration) {
+		return oldCon.status, nil
+	}
+	// the security associations must not be installed again with the same keys.
+	if i.mustRegenerateNonce(tep.Spec.ClusterID, remoteNonce) {
+		if err = i.regenerateNonce(time.Now()); err != nil {
+			return newConnectionOnError(err.Error()), err
+		}
+	}
+
+	localNonceBytes, err := parseNonce(i.rekey.nonce)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+	remoteNonceBytes, err := parseNonce(remoteNonce)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+	outbound, inbound, err := deriveSecurityAssociations(i.priKey, remoteKey, localNonceBytes, remoteNonceBytes)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	con := &connection{localNonce: i.rekey.nonce, remoteNonce: remoteNonce}
+	local := &net.UDPAddr{IP: i.localIP, Port: i.port}
+	states, policies := xfrmConfiguration(local, endpoint, outbound, inbound, remoteCIDRs)
+	con.outbound, con.inbound, con.policies = states[0], states[1], policies
+
+	oldCon, found := i.connections[tep.Spec.ClusterID]
+	switch {
+	case found && sameTunnel(oldCon.status.PeerConfiguration, peerConfiguration):
+		// only the keys changed, hence the security associations are replaced without interrupting the traffic.
+		klog.V(4).Infof("rekeying the connection with cluster %s", tep.Spec.ClusterID)
+		if err = i.rekeyConnection(oldCon, con, time.Now()); err != nil {
+			return newConnectionOnError(err.Error()), fmt.Errorf("failed to rekey the connection with clusterid %s: %w",
+				tep.Spec.ClusterID, err)
+		}
+	default:
+		// delete the old configuration for ClusterID, if any.
+		if found {
+			klog.V(4).Infof("updating peer configuration for cluster %s", tep.Spec.ClusterID)
+			if err = i.removeConnection(oldCon); err != nil {
+				return newConnectionOnError(err.Error()), fmt.Errorf("failed to remove outdated configuration for clusterid %s: %w",
+					tep.Spec.ClusterID, err)
+			}
+			delete(i.connections, tep.Spec.ClusterID)
+		} else {
+			klog.V(4).Infof("Connecting cluster %s endpoint %s with publicKey %s", tep.Spec.ClusterID, endpoint.String(), remoteKey)
+		}
+		if err = i.addConnection(con); err != nil {
+			if e := i.removeConnection(con); e != nil {
+				klog.Errorf("failed to roll back the configuration for clusterid %s: %v", tep.Spec.ClusterID, e)
+			}
+			return newConnectionOnError(err.Error()), fmt.Errorf("failed to configure peer with clusterid %s: %w", tep.Spec.ClusterID, err)
+		}
+	}
+
+	con.status = &netv1alpha1.Connection{
+		Status:            netv1alpha1.Connected,
+		StatusMessage:     "Cluster peer connected",
+		PeerConfiguration: peerConfiguration,
+	}
+	i.connections[tep.Spec.ClusterID] = con
+	i.rekey.installed[tep.Spec.ClusterID] = struct{}{}
+	klog.V(4).Infof("Done connecting cluster peer %s@%s", tep.Spec.ClusterID, endpoint.String())
+	return con.status, nil
+}
+
+// DisconnectFromEndpoint disconnects a remote cluster described by the given tep.
+func (i *IPsec) DisconnectFromEndpoint(tep *netv1alpha1.TunnelEndpoint) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	klog.V(4).Infof("Removing connection with cluster %s", tep.Spec.ClusterID)
+
+	con, found := i.connections[tep.Spec.ClusterID]
+	if !found {
+		klog.V(4).Infof("no tunnel configured for cluster %s, nothing to be removed", tep.Spec.ClusterID)
+		return nil
+	}
+
+	if err := i.removeConnection(con); err != nil {
+		return fmt.Errorf("failed to remove %s configuration for clusterid %s: %w", DriverName, tep.Spec.ClusterID, err)
+	}
+
+	klog.V(4).Infof("Done removing %s configuration for clusterid %s", DriverName, tep.Spec.ClusterID)
+	delete(i.connections, tep.Spec.ClusterID)
+	return nil
+}
+
+// GetLink returns the netlink.Link referred to the xfrm interface.
+func (i *IPsec) GetLink() netlink.Link {
+	return i.link
+}
+
+// Overhead returns the number of bytes added by the ESP over UDP encapsulation, as accounted for by the default MTU.
+func (i *IPsec) Overhead() int {
+	return mtu.DefaultPathMTU - MTU
+}
+
+// Close removes the xfrm interface and the IPsec configuration from the host.
+func (i *IPsec) Close() error {
+	if i.rekey.stop != nil {
+		close(i.rekey.stop)
+		i.rekey.stop = nil
+	}
+	if err := utils.DeleteIFaceByName(DeviceName); err != nil {
+		return fmt.Errorf("failed to delete existing %s device: %w", DriverName, err)
+	}
+	if i.socket >= 0 {
+		if err := unix.Close(i.socket); err != nil {
+			return fmt.Errorf("failed to close the encapsulation socket: %w", err)
+		}
+		i.socket = -1
+	}
+	if i.handle != nil {
+		if err := i.flushXfrmConfiguration(); err != nil {
+			return err
+		}
+		i.handle.Delete()
+		i.handle = nil
+	}
+	return nil
+}
+
+// setXfrmLink creates a new xfrm interface, bound to the one the local IP address is assigned to.
+func (i *IPsec) setXfrmLink() error {
+	// delete existing xfrm device if needed.
+	if err := utils.DeleteIFaceByName(DeviceName); err != nil {
+		return err
+	}
+
+	parent, err := linkByIP(i.localIP)
+	if err != nil {
+		return err
+	}
+
+	la := netlink.NewLinkAttrs()
+	la.Name = DeviceName
+	la.MTU = MTU
+	la.ParentIndex = parent.Attrs().Index
+	link := &netlink.Xfrmi{LinkAttrs: la, Ifid: ifID}
+
+	// create the xfrm device (ip link add $DeviceName type xfrm dev $parent if_id $ifID).
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("failed to add xfrm device '%s': %w", DeviceName, err)
+	}
+	i.link = link
+	return nil
+}
+
+// addConnection configures the xfrm states and policies of the given connection.
+func (i *IPsec) addConnection(con *connection) error {
+	for _, state := range con.states() {
+		if err := i.handle.XfrmStateAdd(state); err != nil {
+			return fmt.Errorf("failed to add xfrm state with spi %#x: %w", state.Spi, err)
+		}
+	}
+	for _, policy := range con.policies {
+		if err := i.handle.XfrmPolicyAdd(policy); err != nil {
+			return fmt.Errorf("failed to add xfrm %s policy for %s->%s: %w", policy.Dir, policy.Src, policy.Dst, err)
+		}
+	}
+	return nil
+}
+
+// removeConnection removes the xfrm states and policies of the given connection, ignoring the missing ones.
+func (i *IPsec) removeConnection(con *connection) error {
+	return i.removeXfrmConfiguration(con.policies, con.states())
+}
+
+// removeXfrmConfiguration removes the given xfrm states and policies, ignoring the missing ones.
+func (i *IPsec) removeXfrmConfiguration(policies []*netlink.XfrmPolicy, states []*netlink.XfrmState) error {
+	for _, policy := range policies {
+		if err := i.handle.XfrmPolicyDel(policy); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to remove xfrm %s policy for %s->%s: %w", policy.Dir, policy.Src, policy.Dst, err)
+		}
+	}
+	for _, state := range states {
+		if err := i.handle.XfrmStateDel(state); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to remove xfrm state with spi %#x: %w", state.Spi, err)
+		}
+	}
+	return nil
+}
+
+// flushXfrmConfiguration removes all the xfrm states and policies associated with the xfrm interface.
+func (i *IPsec) flushXfrmConfiguration() error {
+	var policies []*netlink.XfrmPolicy
+	allPolicies, err := i.handle.XfrmPolicyList(netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list xfrm policies: %w", err)
+	}
+	for j := range allPolicies {
+		if allPolicies[j].Ifid == ifID {
+			policies = append(policies, &allPolicies[j])
+		}
+	}
+
+	var states []*netlink.XfrmState
+	allStates, err := i.handle.XfrmStateList(netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list xfrm states: %w", err)
+	}
+	for j := range allStates {
+		if allStates[j].Ifid == ifID {
+			states = append(states, &allStates[j])
+		}
+	}
+	return i.removeXfrmConfiguration(policies, states)
+}
+
+// sameTunnel returns whether the given peer configurations refer to the same endpoint and remote CIDRs,
+// hence they differ at most in the keys of the security associations.
+func sameTunnel(current, desired map[string]string) bool {
+	for _, key := range []string{EndpointIP, ListeningPort, AllowedIPs} {
+		if current[key] != desired[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// xfrmConfiguration returns the xfrm states and policies to establish the tunnel between the local and the remote endpoint,
+// to carry the traffic directed to (and coming from) the given remote CIDRs.
+func xfrmConfiguration(local, remote *net.UDPAddr, outbound, inbound *securityAssociation,
+	remoteCIDRs []*net.IPNet) ([]*netlink.XfrmState, []*netlink.XfrmPolicy) {
+	newState := func(src, dst *net.UDPAddr, sa *securityAssociation) *netlink.XfrmState {
+		return &netlink.XfrmState{
+			Src:          src.IP,
+			Dst:          dst.IP,
+			Proto:        netlink.XFRM_PROTO_ESP,
+			Mode:         netlink.XFRM_MODE_TUNNEL,
+			Spi:          sa.spi,
+			Ifid:         ifID,
+			ReplayWindow: replayWindow,
+			Aead:         &netlink.XfrmStateAlgo{Name: aeadAlgorithm, Key: sa.key, ICVLen: aeadICVLen},
+			Encap: &netlink.XfrmStateEncap{
+				Type:            netlink.XFRM_ENCAP_ESPINUDP,
+				SrcPort:         src.Port,
+				DstPort:         dst.Port,
+				OriginalAddress: net.IPv4zero,
+			},
+		}
+	}
+
+	newPolicy := func(src, dst *net.IPNet, dir netlink.Dir, tmplSrc, tmplDst net.IP) *netlink.XfrmPolicy {
+		return &netlink.XfrmPolicy{
+			Src:  src,
+			Dst:  dst,
+			Dir:  dir,
+			Ifid: ifID,
+			Tmpls: []netlink.XfrmPolicyTmpl{{
+				Src:   tmplSrc,
+				Dst:   tmplDst,
+				Proto: netlink.XFRM_PROTO_ESP,
+				Mode:  netlink.XFRM_MODE_TUNNEL,
+			}},
+		}
+	}
+
+	states := []*netlink.XfrmState{newState(local, remote, outbound), newState(remote, local, inbound)}
+
+	policies := make([]*netlink.XfrmPolicy, 0, 2*len(remoteCIDRs))
+	for _, cidr := range remoteCIDRs {
+		anyNetwork := &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, net.IPv4len*8)}
+		if cidr.IP.To4() == nil {
+			anyNetwork = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, net.IPv6len*8)}
+		}
+		policies = append(policies,
+			newPolicy(anyNetwork, cidr, netlink.XFRM_DIR_OUT, local.IP, remote.IP),
+			newPolicy(cidr, anyNetwork, netlink.XFRM_DIR_IN, remote.IP, local.IP))
+	}
+	return states, policies
+}
+
+// openEncapSocket opens the UDP socket used to receive the ESP packets encapsulated in UDP.
+// The kernel decapsulates the packets received on the socket and processes them as ESP ones.
+func openEncapSocket(port int) (int, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.IPPROTO_UDP)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open the encapsulation socket: %w", err)
+	}
+	if err = unix.SetsockoptInt(fd, unix.IPPROTO_UDP, udpEncap, udpEncapESPInUDP); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to enable the ESP over UDP encapsulation: %w", err)
+	}
+	if err = unix.Bind(fd, &unix.SockaddrInet4{Port: port}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to bind the encapsulation socket to port %d: %w", port, err)
+	}
+	return fd, nil
+}
+
+// linkByIP returns the link the given IP address is assigned to.
+func linkByIP(ip net.IP) (netlink.Link, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the network interfaces: %w", err)
+	}
+	for _, link := range links {
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the addresses of interface %s: %w", link.Attrs().Name, err)
+		}
+		for j := range addrs {
+			if addrs[j].IP.Equal(ip) {
+				return link, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no network interface found with address %s", ip)
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ESRCH)
+}
+
+// Function that receives a TunnelEndpoint resource and extracts the remote CIDRs
+// to be routed through the tunnel. They are returned as []*net.IPNet and
+// as a string (to accommodate comparison/storing on TEP resource).
+func getRemoteCIDRs(tep *netv1alpha1.TunnelEndpoint) ([]*net.IPNet, string, error) {
+	remoteCIDRs := utils.GetRemoteCIDRs(tep)
+	cidrs := make([]*net.IPNet, 0, len(remoteCIDRs))
+	for _, remoteCIDR := range remoteCIDRs {
+		_, cidr, err := net.ParseCIDR(remoteCIDR)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to parse CIDR %s for cluster %s: %w", remoteCIDR, tep.Spec.ClusterID, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, strings.Join(remoteCIDRs, ","), nil
+}
+
+func getKey(tep *netv1alpha1.TunnelEndpoint) (Key, error) {
+	s, found := tep.Spec.BackendConfig[PublicKey]
+	if !found {
+		return Key{}, fmt.Errorf("endpoint is missing %s public key", DriverName)
+	}
+
+	key, err := ParseKey(s)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to parse public key %s: %w", s, err)
+	}
+	return key, nil
+}
+
+func getNonce(tep *netv1alpha1.TunnelEndpoint) (string, error) {
+	nonce, found := tep.Spec.BackendConfig[Nonce]
+	if !found {
+		return "", fmt.Errorf("endpoint is missing %s nonce", DriverName)
+	}
+	if _, err := parseNonce(nonce); err != nil {
+		return "", fmt.Errorf("failed to parse nonce %s: %w", nonce, err)
+	}
+	return nonce, nil
+}
+
+func getEndpoint(tep *netv1alpha1.TunnelEndpoint, addrResolver ResolverFunc) (*net.UDPAddr, error) {
+	// Get port.
+	port, found := tep.Spec.BackendConfig[ListeningPort]
+	if !found {
+		return nil, fmt.Errorf("port not found in BackendConfig map using key {%s}", ListeningPort)
+	}
+	// Convert port from string to int.
+	tunnelPort, err := strconv.ParseInt(port, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse port {%s} to int: %w", port, err)
+	}
+	// If port is not in the correct range, then return an error.
+	if tunnelPort < udpMinPort || tunnelPort > udpMaxPort {
+		return nil, fmt.Errorf("port {%s} should be greater than {%d} and minor than {%d}", port, udpMinPort, udpMaxPort)
+	}
+
+	// Get the endpoint address, which is currently supported only as an ipv4 address.
+	address, err := addrResolver("ip4", tep.Spec.EndpointIP)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve the endpoint address {%s} as an ipv4 address: %w", tep.Spec.EndpointIP, err)
+	}
+	return &net.UDPAddr{IP: address.IP, Port: int(tunnelPort)}, nil
+}
+
+func newConnectionOnError(msg string) *netv1alpha1.Connection {
+	return &netv1alpha1.Connection{
+		Status:            netv1alpha1.ConnectionError,
+		StatusMessage:     msg,
+		PeerConfiguration: nil,
+	}
+}
+
+func (i *IPsec) setKeys(c k8s.Interface, namespace string) error {
+	// first we check if a secret containing valid keys already exists.
+	s, err := c.CoreV1().Secrets(namespace).Get(context.Background(), keysName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	// if the secret does not exist then keys are generated and saved into a secret.
+	if apierrors.IsNotFound(err) {
+		if i.priKey, err = GeneratePrivateKey(); err != nil {
+			return fmt.Errorf("error generating private key for %s backend: %w", DriverName, err)
+		}
+		i.pubKey = i.priKey.PublicKey()
+		keys := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      keysName,
+				Namespace: namespace,
+				Labels:    map[string]string{KeysLabel: DriverName},
+			},
+			StringData: map[string]string{PublicKey: i.pubKey.String(), PrivateKey: i.priKey.String()},
+		}
+		if _, err = c.CoreV1().Secrets(namespace).Create(context.Background(), &keys, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create the secret with name %s: %w", keysName, err)
+		}
+		return nil
+	}
+	// get the keys from the existing secret and set them.
+	privKey, found := s.Data[PrivateKey]
+	if !found {
+		return fmt.Errorf("no data with key '%s' found in secret %s", PrivateKey, keysName)
+	}
+	if i.priKey, err = ParseKey(string(privKey)); err != nil {
+		return fmt.Errorf("an error occurred while parsing the private key for the %s driver: %w", DriverName, err)
+	}
+	// the public key is derived from the private one, to prevent inconsistencies.
+	i.pubKey = i.priKey.PublicKey()
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+var _ = Describe("Driver", func() {
+	var tep *netv1alpha1.TunnelEndpoint
+
+	addressResolverMock := func(network, address string) (*net.IPAddr, error) {
+		if address == "gateway.liqo.io" {
+			return &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, nil
+		}
+		return nil, errors.New("address not found")
+	}
+
+	BeforeEach(func() {
+		tep = &netv1alpha1.TunnelEndpoint{
+			Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterID:     "remote-cluster",
+				EndpointIP:    "gateway.liqo.io",
+				BackendConfig: map[string]string{ListeningPort: "31000"},
+
+				RemotePodCIDR:         "10.200.0.0/16",
+				RemoteNATPodCIDR:      "None",
+				RemoteExternalCIDR:    "10.201.0.0/16",
+				RemoteNATExternalCIDR: "None",
+			},
+		}
+	})
+
+	Describe("the key management functions", func() {
+		It("should derive matching security associations on both sides", func() {
+			local, err := GeneratePrivateKey()
+			Expect(err).ToNot(HaveOccurred())
+			remote, err := GeneratePrivateKey()
+			Expect(err).ToNot(HaveOccurred())
+
+			localNonce, remoteNonce := []byte("local-nonce-0123"), []byte("remote-nonce-012")
+
+			localOut, localIn, err := deriveSecurityAssociations(local, remote.PublicKey(), localNonce, remoteNonce)
+			Expect(err).ToNot(HaveOccurred())
+			remoteOut, remoteIn, err := deriveSecurityAssociations(remote, local.PublicKey(), remoteNonce, localNonce)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(localOut).To(Equal(remoteIn))
+			Expect(localIn).To(Equal(remoteOut))
+			Expect(localOut.spi).ToNot(Equal(localIn.spi))
+			Expect(localOut.key).ToNot(Equal(localIn.key))
+			Expect(localOut.key).To(HaveLen(aeadKeyLen))
+			Expect(localOut.spi).To(BeNumerically(">=", minSPI))
+
+			// A different nonce of either cluster yields different security associations in both directions.
+			otherOut, otherIn, err := deriveSecurityAssociations(local, remote.PublicKey(), []byte("other-nonce-0123"), remoteNonce)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherOut.key).ToNot(Equal(localOut.key))
+			Expect(otherIn.key).ToNot(Equal(localIn.key))
+		})
+
+		It("should parse back a generated nonce", func() {
+			nonce, err := generateNonce()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parseNonce(nonce)).To(HaveLen(nonceLen))
+			_, err = parseNonce("Zm9v")
+			Expect(err).To(MatchError("incorrect nonce size: 3"))
+		})
+
+		It("should parse back the string representation of a key", func() {
+			key, err := GeneratePrivateKey()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ParseKey(key.String())).To(Equal(key))
+		})
+
+		It("should fail to parse keys of the wrong length", func() {
+			_, err := ParseKey("Zm9v")
+			Expect(err).To(MatchError("incorrect key size: 3"))
+		})
+	})
+
+	Describe("testing getEndpoint", func() {
+		It("should resolve the endpoint address and port", func() {
+			endpoint, err := getEndpoint(tep, addressResolverMock)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoint.String()).To(Equal("10.0.0.1:31000"))
+		})
+
+		It("should fail if the port is out of range", func() {
+			tep.Spec.BackendConfig[ListeningPort] = "65536"
+			_, err := getEndpoint(tep, addressResolverMock)
+			Expect(err).To(MatchError(fmt.Sprintf("port {65536} should be greater than {%d} and minor than {%d}", udpMinPort, udpMaxPort)))
+		})
+
+		It("should fail if the port is not set", func() {
+			delete(tep.Spec.BackendConfig, ListeningPort)
+			_, err := getEndpoint(tep, addressResolverMock)
+			Expect(err).To(MatchError(fmt.Sprintf("port not found in BackendConfig map using key {%s}", ListeningPort)))
+		})
+
+		It("should fail if the address can not be resolved", func() {
+			tep.Spec.EndpointIP = "unexisting"
+			_, err := getEndpoint(tep, addressResolverMock)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("testing getRemoteCIDRs", func() {
+		It("should return the remote CIDRs", func() {
+			cidrs, stringCIDRs, err := getRemoteCIDRs(tep)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cidrs).To(HaveLen(2))
+			Expect(cidrs[0].String()).To(Equal("10.200.0.0/16"))
+			Expect(cidrs[1].String()).To(Equal("10.201.0.0/16"))
+			Expect(stringCIDRs).To(Equal("10.200.0.0/16,10.201.0.0/16"))
+		})
+
+		It("should return the remapped CIDRs, if any", func() {
+			tep.Spec.RemoteNATPodCIDR = "10.210.0.0/16"
+			_, stringCIDRs, err := getRemoteCIDRs(tep)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stringCIDRs).To(Equal("10.210.0.0/16,10.201.0.0/16"))
+		})
+	})
+
+	Describe("testing xfrmConfiguration", func() {
+		It("should return the states and policies of the tunnel", func() {
+			local := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: DefaultPort}
+			remote := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 31000}
+			outbound := &securityAssociation{spi: 1000, key: []byte("outbound")}
+			inbound := &securityAssociation{spi: 2000, key: []byte("inbound")}
+			cidrs, _, err := getRemoteCIDRs(tep)
+			Expect(err).ToNot(HaveOccurred())
+
+			states, policies := xfrmConfiguration(local, remote, outbound, inbound, cidrs)
+
+			Expect(states).To(HaveLen(2))
+			Expect(states[0].Src).To(Equal(local.IP))
+			Expect(states[0].Dst).To(Equal(remote.IP))
+			Expect(states[0].Spi).To(Equal(1000))
+			Expect(states[0].Encap.SrcPort).To(Equal(DefaultPort))
+			Expect(states[0].Encap.DstPort).To(Equal(31000))
+			Expect(states[1].Src).To(Equal(remote.IP))
+			Expect(states[1].Dst).To(Equal(local.IP))
+			Expect(states[1].Spi).To(Equal(2000))
+			Expect(states[1].Encap.SrcPort).To(Equal(31000))
+			for _, state := range states {
+				Expect(state.Ifid).To(Equal(ifID))
+				Expect(state.Mode).To(Equal(netlink.XFRM_MODE_TUNNEL))
+			}
+
+			Expect(policies).To(HaveLen(4))
+			Expect(policies[0].Dir).To(Equal(netlink.XFRM_DIR_OUT))
+			Expect(policies[0].Dst).To(Equal(cidrs[0]))
+			Expect(policies[0].Tmpls[0].Dst).To(Equal(remote.IP))
+			Expect(policies[1].Dir).To(Equal(netlink.XFRM_DIR_IN))
+			Expect(policies[1].Src).To(Equal(cidrs[0]))
+			Expect(policies[1].Tmpls[0].Src).To(Equal(remote.IP))
+			Expect(policies[2].Dst).To(Equal(cidrs[1]))
+			Expect(policies[3].Src).To(Equal(cidrs[1]))
+			for _, policy := range policies {
+				Expect(policy.Ifid).To(Equal(ifID))
+			}
+		})
+	})
+})
@@ -0,0 +1,155 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// keyLen is the length of the curve25519 keys.
+	keyLen = curve25519.ScalarSize
+	// aeadKeyLen is the length of the keys used by the AEAD algorithm (128 bits key plus 32 bits salt).
+	aeadKeyLen = 20
+	// minSPI is the minimum value of the SPIs, as the lower ones are reserved.
+	minSPI = 256
+	// hkdfInfo is the context information used to derive the security associations.
+	hkdfInfo = "liqo.io ipsec security association"
+	// nonceLen is the length of the nonces mixed into the derivation of the security associations.
+	nonceLen = 16
+)
+
+// Key is a curve25519 key, used to agree on the secrets of the security associations with the remote clusters.
+type Key [keyLen]byte
+
+// GeneratePrivateKey generates a new random private key.
+func GeneratePrivateKey() (Key, error) {
+	var key Key
+	if _, err := rand.Read(key[:]); err != nil {
+		return Key{}, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	// Clamp the key, as described in RFC 7748.
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+	return key, nil
+}
+
+// ParseKey parses a base64 encoded key.
+func ParseKey(s string) (Key, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to parse base64-encoded key: %w", err)
+	}
+	if len(b) != keyLen {
+		return Key{}, fmt.Errorf("incorrect key size: %d", len(b))
+	}
+
+	var key Key
+	copy(key[:], b)
+	return key, nil
+}
+
+// PublicKey returns the public key corresponding to the private one.
+func (k Key) PublicKey() Key {
+	var public Key
+	pub, err := curve25519.X25519(k[:], curve25519.Basepoint)
+	if err != nil {
+		// This may happen only if the private key is of the wrong length, which is prevented by the type.
+		panic(err)
+	}
+	copy(public[:], pub)
+	return public
+}
+
+// String returns the base64 encoding of the key.
+func (k Key) String() string {
+	return base64.StdEncoding.EncodeToString(k[:])
+}
+
+// securityAssociation contains the parameters identifying a unidirectional security association.
+type securityAssociation struct {
+	spi int
+	key []byte
+}
+
+// generateNonce generates a new random nonce, base64 encoded.
+func generateNonce() (string, error) {
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(nonce), nil
+}
+
+// parseNonce parses a base64 encoded nonce.
+func parseNonce(s string) ([]byte, error) {
+	nonce, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base64-encoded nonce: %w", err)
+	}
+	if len(nonce) != nonceLen {
+		return nil, fmt.Errorf("incorrect nonce size: %d", len(nonce))
+	}
+	return nonce, nil
+}
+
+// deriveSecurityAssociations derives the parameters of the outbound and inbound security associations
+// towards a remote cluster, starting from the secret agreed through the local private key and the remote public one,
+// mixed with the nonces of the two clusters. Both clusters derive the same values, as each direction is identified
+// by the public keys and the nonces of the source and destination. Different nonces yield different parameters,
+// which prevents the reuse of the keys (hence of the GCM nonces) when the security associations are installed again.
+func deriveSecurityAssociations(private, remote Key, localNonce, remoteNonce []byte) (outbound, inbound *securityAssociation, err error) {
+	secret, err := curve25519.X25519(private[:], remote[:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute the shared secret: %w", err)
+	}
+
+	local := private.PublicKey()
+	if outbound, err = deriveSecurityAssociation(secret, local, remote, localNonce, remoteNonce); err != nil {
+		return nil, nil, err
+	}
+	if inbound, err = deriveSecurityAssociation(secret, remote, local, remoteNonce, localNonce); err != nil {
+		return nil, nil, err
+	}
+	return outbound, inbound, nil
+}
+
+// deriveSecurityAssociation derives the parameters of the security association from the source to the destination.
+func deriveSecurityAssociation(secret []byte, source, destination Key, sourceNonce, destinationNonce []byte) (*securityAssociation, error) {
+	info := make([]byte, 0, len(hkdfInfo)+2*keyLen)
+	info = append(append(append(info, hkdfInfo...), source[:]...), destination[:]...)
+	salt := make([]byte, 0, len(sourceNonce)+len(destinationNonce))
+	salt = append(append(salt, sourceNonce...), destinationNonce...)
+
+	material := make([]byte, 4+aeadKeyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), material); err != nil {
+		return nil, fmt.Errorf("failed to derive the security association parameters: %w", err)
+	}
+
+	// The SPI is kept positive, to be safely stored in an int also on 32 bits architectures.
+	spi := binary.BigEndian.Uint32(material[:4]) & 0x7fffffff
+	if spi < minSPI {
+		spi += minSPI
+	}
+	return &securityAssociation{spi: int(spi), key: material[4:]}, nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// The sequence numbers of the security associations, from which the GCM nonces are built, restart from zero every
+// time they are installed: hence, the same keys must never be installed twice, including by a restarted or a different
+// gateway replica. To this end, the keys are derived mixing a random nonce of each cluster, which:
+//  - is regenerated when the gateway replica starts handling the tunnels (i.e., at the first connection after a restart
+//    or a failover), before the security associations towards a remote cluster are installed again with unchanged
+//    nonces (e.g., because its endpoint changed), and periodically;
+//  - is published through the secret containing the keys, and then the NetworkConfig. The remote clusters install
+//    again the security associations as soon as they observe a different nonce, which also resets the anti-replay
+//    windows, while the other gateway replicas adopt the one stored in the secret.
+// When only the keys of an established connection change, the new security associations are installed make-before-break
+// (see rekeyConnection), hence the traffic is not interrupted provided that the remote cluster observes the new nonce
+// within outboundSwitchDelay. Otherwise (e.g., after a restart or a failover, or if the endpoint changed), the traffic
+// is interrupted until the remote cluster configures the security associations matching the new nonce.
+// The keys are not negotiated through IKE: they are derived from the long-term keys of the clusters and the nonces,
+// hence the compromise of the private key of a cluster discloses the keys derived from the nonces it observed.
+
+const (
+	// Nonce is the key of the IPsec nonce entry in back-end map and also for the secret containing the IPsec keys.
+	Nonce = "ipsecNonce"
+	// LastRekeyAnnotation is the annotation of the keys secret storing the time the nonce has been last regenerated.
+	LastRekeyAnnotation = "net.liqo.io/last-ipsec-rekey"
+	// DefaultRekeyInterval is the default interval between two subsequent regenerations of the nonce.
+	DefaultRekeyInterval = 24 * time.Hour
+	// MinRekeyInterval is the minimum interval between two subsequent regenerations of the nonce.
+	MinRekeyInterval = 10 * time.Minute
+
+	// interval between two subsequent checks of whether the nonce has to be regenerated or adopted,
+	// and of whether the rekeys of the connections have to be completed.
+	rekeyCheckInterval = 10 * time.Second
+	// outboundSwitchDelay is the time waited, after the local nonce changed, before sending the traffic with the new
+	// keys, to let the remote cluster observe the new nonce and install the matching inbound security association.
+	outboundSwitchDelay = 30 * time.Second
+	// inboundRetention is the time the inbound security associations replaced by a rekey are kept, to accept the
+	// traffic the remote cluster sends with the previous keys until it switches to the new ones in turn.
+	inboundRetention = 2 * time.Minute
+)
+
+// rekeyInterval is the interval between two subsequent regenerations of the nonce.
+var rekeyInterval = DefaultRekeyInterval
+
+// SetRekeyInterval sets the interval between two subsequent regenerations of the nonce mixed into the derivation
+// of the security associations. It has to be called before creating the driver.
+func SetRekeyInterval(interval time.Duration) error {
+	if interval < MinRekeyInterval {
+		return fmt.Errorf("the rekey interval must be at least %s, got %s", MinRekeyInterval, interval)
+	}
+	rekeyInterval = interval
+	return nil
+}
+
+// rekeyState holds the state of the nonce mixed into the derivation of the security associations.
+type rekeyState struct {
+	// nonce is the current nonce, empty until the replica starts handling the tunnels.
+	nonce string
+	// generated is true if the current nonce has been generated by the local replica, rather than adopted.
+	generated bool
+	// installed contains the remote clusters the security associations have been installed for with the current nonce.
+	installed map[string]struct{}
+	lastRekey time.Time
+	interval  time.Duration
+	rekeyed   chan struct{}
+	stop      chan struct{}
+}
+
+// KeyRotationStatus returns the current status of the periodic regeneration of the nonce.
+func (i *IPsec) KeyRotationStatus() *netv1alpha1.KeyRotationStatus {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	status := &netv1alpha1.KeyRotationStatus{Interval: metav1.Duration{Duration: i.rekey.interval}}
+	if i.rekey.lastRekey.IsZero() {
+		return status
+	}
+	last := metav1.NewTime(i.rekey.lastRekey.Truncate(time.Second))
+	next := metav1.NewTime(i.rekey.lastRekey.Add(i.rekey.interval).Truncate(time.Second))
+	status.LastRotationTime, status.NextRotationTime = &last, &next
+	return status
+}
+
+// KeyRotated returns a channel which is notified every time the nonce changes.
+func (i *IPsec) KeyRotated() <-chan struct{} {
+	return i.rekey.rekeyed
+}
+
+// runRekey periodically regenerates the nonce, or adopts the one generated by another replica, until the stop channel is closed.
+func (i *IPsec) runRekey(stop <-chan struct{}) {
+	ticker := time.NewTicker(rekeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if err := i.syncNonce(now); err != nil {
+				klog.Errorf("an error occurred while checking the %s nonce: %v", DriverName, err)
+			}
+			i.completeRekeys(now)
+		}
+	}
+}
+
+// syncNonce adopts the nonce stored in the secret, if generated by another replica, or regenerates it if the
+// rekey interval elapsed. Nothing is done until the replica starts handling the tunnels.
+func (i *IPsec) syncNonce(now time.Time) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.rekey.nonce == "" {
+		return nil
+	}
+	s, err := i.k8sClient.CoreV1().Secrets(i.namespace).Get(context.Background(), keysName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the secret with name %s: %w", keysName, err)
+	}
+	if nonce := string(s.Data[Nonce]); nonce != "" && nonce != i.rekey.nonce {
+		lastRekey, err := time.Parse(time.RFC3339, s.Annotations[LastRekeyAnnotation])
+		if err != nil {
+			return fmt.Errorf("failed to parse annotation %s of secret %s: %w", LastRekeyAnnotation, keysName, err)
+		}
+		i.setNonce(nonce, false, lastRekey)
+		klog.Infof("adopted the %s nonce regenerated by another replica", DriverName)
+		return nil
+	}
+	if !now.Before(i.rekey.lastRekey.Add(i.rekey.interval)) {
+		return i.regenerateNonce(now)
+	}
+	return nil
+}
+
+// regenerateNonce generates a new nonce and publishes it through the secret containing the keys.
+// It must be called with the mutex held.
+func (i *IPsec) regenerateNonce(now time.Time) error {
+	nonce, err := generateNonce()
+	if err != nil {
+		return err
+	}
+	secrets := i.k8sClient.CoreV1().Secrets(i.namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		s, err := secrets.Get(context.Background(), keysName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		setNonceData(s, nonce, now)
+		_, err = secrets.Update(context.Background(), s, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update the secret with name %s: %w", keysName, err)
+	}
+	i.setNonce(nonce, true, now)
+	klog.Infof("regenerated the %s nonce", DriverName)
+	return nil
+}
+
+// setNonce configures the given nonce as the current one, and notifies the change,
+// to let the security associations be installed again. It must be called with the mutex held.
+func (i *IPsec) setNonce(nonce string, generated bool, lastRekey time.Time) {
+	i.rekey.nonce, i.rekey.generated, i.rekey.lastRekey = nonce, generated, lastRekey
+	i.rekey.installed = make(map[string]struct{})
+	// notify the change, without blocking if a notification is already pending.
+	select {
+	case i.rekey.rekeyed <- struct{}{}:
+	default:
+	}
+}
+
+// mustRegenerateNonce returns whether the nonce has to be regenerated before installing the security associations
+// towards the given remote cluster with the given remote nonce, since the resulting keys might have already been used.
+// It must be called with the mutex held.
+func (i *IPsec) mustRegenerateNonce(clusterID, remoteNonce string) bool {
+	if i.rekey.nonce == "" {
+		// the replica is starting to handle the tunnels.
+		return true
+	}
+	if con, found := i.connections[clusterID]; found {
+		// the security associations are installed again with the same nonces.
+		return con.localNonce == i.rekey.nonce && con.remoteNonce == remoteNonce
+	}
+	// the nonce adopted from another replica might have been used by it for the same remote cluster,
+	// as well as the local one if the security associations have been removed and are now installed again.
+	_, installed := i.rekey.installed[clusterID]
+	return !i.rekey.generated || installed
+}
+
+// rekeyConnection replaces the security associations of the old connection with the ones of the new connection,
+// which refers to the same endpoint and remote CIDRs, without interrupting the traffic. The new inbound security
+// association is installed alongside the current one, while the old one is kept for inboundRetention, until the
+// remote cluster switches to the new keys. The new outbound security association replaces the current one immediately
+// if only the remote nonce changed, since the remote cluster installs the matching inbound one before advertising the
+// nonce to the local cluster, and after outboundSwitchDelay otherwise. It must be called with the mutex held.
+func (i *IPsec) rekeyConnection(oldCon, con *connection, now time.Time) error {
+	if err := i.handle.XfrmStateAdd(con.inbound); err != nil {
+		return fmt.Errorf("failed to add xfrm state with spi %#x: %w", con.inbound.Spi, err)
+	}
+	con.policies = oldCon.policies
+	con.retired = append(oldCon.retired, retiredState{state: oldCon.inbound, expiration: now.Add(inboundRetention)})
+	// the outbound security association pending from a previous rekey, if any, has never been installed,
+	// hence it is simply superseded by the new one.
+	con.outbound, con.nextOutbound = oldCon.outbound, con.outbound
+	switch {
+	case con.localNonce != oldCon.localNonce:
+		con.switchTime = now.Add(outboundSwitchDelay)
+	case oldCon.nextOutbound != nil:
+		// the remote cluster might not have observed yet the local nonce of the pending rekey.
+		con.switchTime = oldCon.switchTime
+	default:
+		con.switchTime = now
+	}
+	// the failures are retried by the periodic completion of the rekeys, since the connection is already updated.
+	if err := i.completeRekey(con, now); err != nil {
+		klog.Errorf("an error occurred while completing the rekey of the %s connection: %v", DriverName, err)
+	}
+	return nil
+}
+
+// completeRekeys completes the rekeys of the connections, according to the given time.
+func (i *IPsec) completeRekeys(now time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for clusterID, con := range i.connections {
+		if err := i.completeRekey(con, now); err != nil {
+			klog.Errorf("an error occurred while completing the rekey of the connection with cluster %s: %v", clusterID, err)
+		}
+	}
+}
+
+// completeRekey switches the traffic of the given connection to the new outbound security association, once the
+// switch time elapsed, and removes the expired retired ones. It must be called with the mutex held.
+func (i *IPsec) completeRekey(con *connection, now time.Time) error {
+	if con.nextOutbound != nil && !now.Before(con.switchTime) {
+		// the kernel selects the most recently added among the matching outbound security associations,
+		// hence the traffic moves to the new one as soon as it is installed.
+		if err := i.handle.XfrmStateAdd(con.nextOutbound); err != nil {
+			return fmt.Errorf("failed to add xfrm state with spi %#x: %w", con.nextOutbound.Spi, err)
+		}
+		con.retired = append(con.retired, retiredState{state: con.outbound, expiration: now})
+		con.outbound, con.nextOutbound = con.nextOutbound, nil
+	}
+
+	var err error
+	retained := con.retired[:0]
+	for _, retired := range con.retired {
+		if now.Before(retired.expiration) {
+			retained = append(retained, retired)
+			continue
+		}
+		if e := i.handle.XfrmStateDel(retired.state); e != nil && !isNotFound(e) {
+			err = fmt.Errorf("failed to remove xfrm state with spi %#x: %w", retired.state.Spi, e)
+			retained = append(retained, retired)
+		}
+	}
+	con.retired = retained
+	return err
+}
+
+// setNonceData sets the given nonce and rekey time in the secret containing the IPsec keys.
+func setNonceData(s *corev1.Secret, nonce string, lastRekey time.Time) {
+	if s.Data == nil {
+		s.Data = make(map[string][]byte)
+	}
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string)
+	}
+	s.Data[Nonce] = []byte(nonce)
+	s.Annotations[LastRekeyAnnotation] = lastRekey.Format(time.RFC3339)
+}
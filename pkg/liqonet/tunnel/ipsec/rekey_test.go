@@ -0,0 +1,306 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeXfrmHandle records the xfrm states installed by the driver, identified by their SPIs.
+type fakeXfrmHandle struct {
+	states map[int]*netlink.XfrmState
+}
+
+func (f *fakeXfrmHandle) XfrmStateAdd(state *netlink.XfrmState) error {
+	if _, found := f.states[state.Spi]; found {
+		return unix.EEXIST
+	}
+	f.states[state.Spi] = state
+	return nil
+}
+
+func (f *fakeXfrmHandle) XfrmStateDel(state *netlink.XfrmState) error {
+	if _, found := f.states[state.Spi]; !found {
+		return unix.ESRCH
+	}
+	delete(f.states, state.Spi)
+	return nil
+}
+
+func (f *fakeXfrmHandle) XfrmStateList(family int) ([]netlink.XfrmState, error)   { return nil, nil }
+func (f *fakeXfrmHandle) XfrmPolicyAdd(policy *netlink.XfrmPolicy) error          { return nil }
+func (f *fakeXfrmHandle) XfrmPolicyDel(policy *netlink.XfrmPolicy) error          { return nil }
+func (f *fakeXfrmHandle) XfrmPolicyList(family int) ([]netlink.XfrmPolicy, error) { return nil, nil }
+func (f *fakeXfrmHandle) Delete()                                                 {}
+
+// spis returns the SPIs of the installed states.
+func (f *fakeXfrmHandle) spis() []int {
+	spis := make([]int, 0, len(f.states))
+	for spi := range f.states {
+		spis = append(spis, spi)
+	}
+	return spis
+}
+
+var _ = Describe("Rekey", func() {
+	const (
+		namespace       = "liqo"
+		remoteClusterID = "remote-cluster"
+	)
+
+	var (
+		clientset *fake.Clientset
+		i         *IPsec
+		now       time.Time
+	)
+
+	getSecret := func() *corev1.Secret {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), keysName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return secret
+	}
+
+	newNonce := func() string {
+		nonce, err := generateNonce()
+		Expect(err).ToNot(HaveOccurred())
+		return nonce
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: keysName, Namespace: namespace}})
+		now = time.Now()
+		i = &IPsec{
+			connections: make(map[string]*connection),
+			rekey:       rekeyState{interval: time.Hour, rekeyed: make(chan struct{}, 1)},
+			k8sClient:   clientset,
+			namespace:   namespace,
+		}
+	})
+
+	Describe("the SetRekeyInterval function", func() {
+		AfterEach(func() { rekeyInterval = DefaultRekeyInterval })
+
+		It("should accept a valid interval", func() {
+			Expect(SetRekeyInterval(time.Hour)).To(Succeed())
+			Expect(rekeyInterval).To(Equal(time.Hour))
+		})
+
+		It("should refuse an interval shorter than the minimum", func() {
+			Expect(SetRekeyInterval(time.Minute)).ToNot(Succeed())
+		})
+	})
+
+	Describe("the regeneration of the nonce", func() {
+		It("should publish the nonce through the secret and notify the change", func() {
+			Expect(i.regenerateNonce(now)).To(Succeed())
+			Expect(i.rekey.nonce).ToNot(BeEmpty())
+			Expect(i.rekey.generated).To(BeTrue())
+			Expect(getSecret().Data).To(HaveKeyWithValue(Nonce, []byte(i.rekey.nonce)))
+			Expect(getSecret().Annotations).To(HaveKeyWithValue(LastRekeyAnnotation, now.Format(time.RFC3339)))
+			Expect(i.KeyRotated()).To(Receive())
+		})
+	})
+
+	Describe("the synchronization of the nonce", func() {
+		When("the replica is not yet handling the tunnels", func() {
+			It("should do nothing", func() {
+				Expect(i.syncNonce(now.Add(2 * time.Hour))).To(Succeed())
+				Expect(i.rekey.nonce).To(BeEmpty())
+				Expect(getSecret().Data).ToNot(HaveKey(Nonce))
+			})
+		})
+
+		When("the replica is handling the tunnels", func() {
+			var nonce string
+
+			BeforeEach(func() {
+				Expect(i.regenerateNonce(now)).To(Succeed())
+				nonce = i.rekey.nonce
+				Expect(i.KeyRotated()).To(Receive())
+			})
+
+			It("should keep the nonce until the rekey interval elapses", func() {
+				Expect(i.syncNonce(now.Add(30 * time.Minute))).To(Succeed())
+				Expect(i.rekey.nonce).To(Equal(nonce))
+				Expect(i.KeyRotated()).ToNot(Receive())
+			})
+
+			It("should regenerate the nonce once the rekey interval elapsed", func() {
+				Expect(i.syncNonce(now.Add(time.Hour))).To(Succeed())
+				Expect(i.rekey.nonce).ToNot(Equal(nonce))
+				Expect(getSecret().Data).To(HaveKeyWithValue(Nonce, []byte(i.rekey.nonce)))
+				Expect(i.KeyRotated()).To(Receive())
+			})
+
+			It("should adopt the nonce regenerated by another replica", func() {
+				secret := getSecret()
+				other := newNonce()
+				setNonceData(secret, other, now.Add(time.Minute))
+				_, err := clientset.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(i.syncNonce(now.Add(2 * time.Minute))).To(Succeed())
+				Expect(i.rekey.nonce).To(Equal(other))
+				Expect(i.rekey.generated).To(BeFalse())
+				Expect(i.rekey.lastRekey).To(BeTemporally("~", now.Add(time.Minute), time.Second))
+				Expect(i.KeyRotated()).To(Receive())
+			})
+		})
+	})
+
+	Describe("the mustRegenerateNonce function", func() {
+		var remoteNonce string
+
+		BeforeEach(func() { remoteNonce = newNonce() })
+
+		It("should require a new nonce when the replica starts handling the tunnels", func() {
+			Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeTrue())
+		})
+
+		When("the nonce has been generated by the local replica", func() {
+			BeforeEach(func() { Expect(i.regenerateNonce(now)).To(Succeed()) })
+
+			It("should not require a new nonce for the first connection", func() {
+				Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeFalse())
+			})
+
+			It("should require a new nonce if the connection is installed again with the same nonces", func() {
+				i.connections[remoteClusterID] = &connection{localNonce: i.rekey.nonce, remoteNonce: remoteNonce}
+				Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeTrue())
+			})
+
+			It("should not require a new nonce if the remote nonce changed", func() {
+				i.connections[remoteClusterID] = &connection{localNonce: i.rekey.nonce, remoteNonce: remoteNonce}
+				Expect(i.mustRegenerateNonce(remoteClusterID, newNonce())).To(BeFalse())
+			})
+
+			It("should require a new nonce if the connection had already been installed with the current one", func() {
+				i.rekey.installed[remoteClusterID] = struct{}{}
+				Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeTrue())
+			})
+		})
+
+		When("the nonce has been adopted from another replica", func() {
+			BeforeEach(func() { i.setNonce(newNonce(), false, now) })
+
+			It("should require a new nonce for a new connection", func() {
+				Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeTrue())
+			})
+
+			It("should not require a new nonce for the connections already handled by the replica", func() {
+				i.connections[remoteClusterID] = &connection{localNonce: newNonce(), remoteNonce: remoteNonce}
+				Expect(i.mustRegenerateNonce(remoteClusterID, remoteNonce)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("the rekey of the connections", func() {
+		var (
+			handle        *fakeXfrmHandle
+			oldCon        *connection
+			localNonce    string
+			newConnection func(localNonce string, outboundSPI, inboundSPI int) *connection
+			local, remote *net.UDPAddr
+			remoteCIDRs   []*net.IPNet
+			remoteNonce   string
+		)
+
+		BeforeEach(func() {
+			handle = &fakeXfrmHandle{states: make(map[int]*netlink.XfrmState)}
+			i.handle = handle
+			local = &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: DefaultPort}
+			remote = &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 31000}
+			_, cidr, err := net.ParseCIDR("10.200.0.0/16")
+			Expect(err).ToNot(HaveOccurred())
+			remoteCIDRs = []*net.IPNet{cidr}
+			localNonce, remoteNonce = newNonce(), newNonce()
+
+			newConnection = func(localNonce string, outboundSPI, inboundSPI int) *connection {
+				states, policies := xfrmConfiguration(local, remote, &securityAssociation{spi: outboundSPI, key: []byte("outbound")},
+					&securityAssociation{spi: inboundSPI, key: []byte("inbound")}, remoteCIDRs)
+				return &connection{outbound: states[0], inbound: states[1], policies: policies,
+					localNonce: localNonce, remoteNonce: remoteNonce}
+			}
+			oldCon = newConnection(localNonce, 1000, 2000)
+			Expect(i.addConnection(oldCon)).To(Succeed())
+			Expect(handle.spis()).To(ConsistOf(1000, 2000))
+		})
+
+		When("the local nonce changed", func() {
+			var con *connection
+
+			BeforeEach(func() {
+				con = newConnection(newNonce(), 1001, 2001)
+				Expect(i.rekeyConnection(oldCon, con, now)).To(Succeed())
+			})
+
+			It("should install the new inbound security association, keeping the current ones", func() {
+				Expect(handle.spis()).To(ConsistOf(1000, 2000, 2001))
+				Expect(con.outbound.Spi).To(Equal(1000))
+				Expect(con.nextOutbound.Spi).To(Equal(1001))
+			})
+
+			It("should switch to the new outbound security association once the switch delay elapsed", func() {
+				Expect(i.completeRekey(con, now.Add(outboundSwitchDelay/2))).To(Succeed())
+				Expect(handle.spis()).To(ConsistOf(1000, 2000, 2001))
+
+				Expect(i.completeRekey(con, now.Add(outboundSwitchDelay))).To(Succeed())
+				Expect(handle.spis()).To(ConsistOf(1001, 2000, 2001))
+				Expect(con.outbound.Spi).To(Equal(1001))
+				Expect(con.nextOutbound).To(BeNil())
+			})
+
+			It("should remove the old inbound security association once the retention elapsed", func() {
+				Expect(i.completeRekey(con, now.Add(inboundRetention))).To(Succeed())
+				Expect(handle.spis()).To(ConsistOf(1001, 2001))
+				Expect(con.retired).To(BeEmpty())
+			})
+
+			It("should remove all the security associations when the connection is removed", func() {
+				Expect(i.removeConnection(con)).To(Succeed())
+				Expect(handle.spis()).To(BeEmpty())
+			})
+
+			It("should keep the switch time if the remote nonce changes before the switch", func() {
+				next := newConnection(con.localNonce, 1002, 2002)
+				next.remoteNonce = newNonce()
+				Expect(i.rekeyConnection(con, next, now.Add(time.Second))).To(Succeed())
+				Expect(handle.spis()).To(ConsistOf(1000, 2000, 2001, 2002))
+				Expect(next.nextOutbound.Spi).To(Equal(1002))
+				Expect(next.switchTime).To(Equal(now.Add(outboundSwitchDelay)))
+			})
+		})
+
+		When("only the remote nonce changed", func() {
+			It("should switch to the new outbound security association immediately", func() {
+				con := newConnection(localNonce, 1001, 2001)
+				con.remoteNonce = newNonce()
+				Expect(i.rekeyConnection(oldCon, con, now)).To(Succeed())
+				Expect(handle.spis()).To(ConsistOf(1001, 2000, 2001))
+				Expect(con.outbound.Spi).To(Equal(1001))
+			})
+		})
+	})
+})
@@ -0,0 +1,364 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultInterval is the default interval between two subsequent probes towards the same remote gateway.
+	DefaultInterval = 5 * time.Second
+	// DefaultFailureThreshold is the default number of consecutive lost probes
+	// after which a remote gateway is considered unreachable.
+	DefaultFailureThreshold = 6
+	// Window is the number of probes the statistics are computed on. The statistics are also
+	// reported once every Window probes, unless the reachability of the remote gateway changes.
+	Window = 12
+	// ResponderKey is the key of the back-end map entry advertising that the gateway answers the probes of the remote
+	// gateways. Peers not advertising it (e.g., running a previous version) are not flagged as unreachable.
+	ResponderKey = "probeResponder"
+
+	// protocolICMP is the IANA number of the ICMP protocol.
+	protocolICMP = 1
+	// maxPacketSize is the size of the buffer used to read the replies.
+	maxPacketSize = 1500
+)
+
+// PacketConn is the subset of the icmp.PacketConn methods used by the prober.
+type PacketConn interface {
+	ReadFrom(b []byte) (int, net.Addr, error)
+	WriteTo(b []byte, dst net.Addr) (int, error)
+	Close() error
+}
+
+// Listen opens the raw socket used to send the ICMP probes. The socket is bound to the
+// network namespace of the calling thread, hence it has to be invoked in the gateway one.
+func Listen() (*icmp.PacketConn, error) {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the ICMP socket: %w", err)
+	}
+	return conn, nil
+}
+
+// Options are the parameters of the prober.
+type Options struct {
+	// Interval is the interval between two subsequent probes towards the same remote gateway.
+	// It is also the time after which a probe without reply is considered lost.
+	Interval time.Duration
+	// FailureThreshold is the number of consecutive lost probes after which a remote gateway is considered unreachable.
+	FailureThreshold int
+}
+
+// Statistics summarizes the outcome of the last probes towards a remote gateway.
+type Statistics struct {
+	// RTT is the average round trip time of the probes which got a reply, zero if none.
+	RTT time.Duration
+	// PacketLoss is the percentage of the probes which did not get a reply.
+	PacketLoss int
+	// Probes is the number of probes the statistics are computed on.
+	Probes int
+	// ConsecutiveFailures is the number of probes lost since the last reply.
+	ConsecutiveFailures int
+	// Reachable is false if the number of consecutive failures reached the threshold.
+	Reachable bool
+	// LastProbeTime is the time the outcome of the last probe has been recorded.
+	LastProbeTime time.Time
+}
+
+// NotifyFunc is the function invoked when the statistics concerning a remote cluster are reported.
+type NotifyFunc func(clusterID string, statistics Statistics)
+
+// target represents a remote gateway to be probed.
+type target struct {
+	ip net.IP
+	// results is a ring buffer of the last probe outcomes, where a zero rtt denotes a lost probe.
+	results             []time.Duration
+	next                int
+	consecutiveFailures int
+	reachable           bool
+	lastProbe           time.Time
+	// sinceReport is the number of probe outcomes recorded since the statistics have been last reported.
+	sinceReport int
+}
+
+// notification holds the statistics to be notified concerning a remote cluster.
+type notification struct {
+	clusterID  string
+	statistics Statistics
+}
+
+// pendingProbe represents a probe waiting for the corresponding reply.
+type pendingProbe struct {
+	clusterID string
+	ip        net.IP
+	sent      time.Time
+}
+
+// Prober periodically probes the remote gateways and keeps track of the corresponding statistics.
+type Prober struct {
+	mutex   sync.Mutex
+	conn    PacketConn
+	opts    Options
+	notify  NotifyFunc
+	id      int
+	seq     uint16
+	targets map[string]*target
+	pending map[uint16]*pendingProbe
+}
+
+// New returns a new prober, sending the probes through the given connection.
+// The notify function is invoked every Window probes towards a given gateway and when its reachability changes.
+func New(conn PacketConn, opts Options, notify NotifyFunc) *Prober {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	return &Prober{
+		conn:    conn,
+		opts:    opts,
+		notify:  notify,
+		id:      os.Getpid() & 0xffff,
+		targets: make(map[string]*target),
+		pending: make(map[uint16]*pendingProbe),
+	}
+}
+
+// SetTarget configures the address of the remote gateway of the given cluster. The statistics are reset if it changes.
+func (p *Prober) SetTarget(clusterID string, ip net.IP) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if t, found := p.targets[clusterID]; found && t.ip.Equal(ip) {
+		return
+	}
+	klog.V(4).Infof("%s -> probing the remote gateway at address %s", clusterID, ip)
+	p.targets[clusterID] = &target{ip: ip, reachable: true}
+}
+
+// RemoveTarget stops probing the remote gateway of the given cluster.
+func (p *Prober) RemoveTarget(clusterID string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.targets, clusterID)
+}
+
+// Statistics returns the current statistics concerning the remote gateway of the given cluster.
+func (p *Prober) Statistics(clusterID string) (Statistics, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	t, found := p.targets[clusterID]
+	if !found {
+		return Statistics{}, false
+	}
+	return t.statistics(), true
+}
+
+// Start probes the remote gateways until the context is canceled, and then closes the connection.
+func (p *Prober) Start(ctx context.Context) error {
+	go p.receive()
+	defer func() {
+		if err := p.conn.Close(); err != nil {
+			klog.Errorf("failed to close the ICMP socket: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			p.probe(now)
+		}
+	}
+}
+
+// probe records the probes without a reply as lost, and sends a new probe towards each remote gateway.
+func (p *Prober) probe(now time.Time) {
+	var notifications []notification
+	defer func() { p.notifyAll(notifications) }()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// the probes sent in the previous rounds are considered lost if no reply has been received yet.
+	for seq, pending := range p.pending {
+		delete(p.pending, seq)
+		if n := p.record(pending, 0, now); n != nil {
+			notifications = append(notifications, *n)
+		}
+	}
+
+	for clusterID, t := range p.targets {
+		p.seq++
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: p.id, Seq: int(p.seq), Data: []byte(clusterID)},
+		}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			klog.Errorf("%s -> failed to marshal the ICMP probe: %v", clusterID, err)
+			continue
+		}
+		// the probe is recorded as pending also in case of error, to account for it as lost.
+		p.pending[p.seq] = &pendingProbe{clusterID: clusterID, ip: t.ip, sent: now}
+		if _, err := p.conn.WriteTo(b, &net.IPAddr{IP: t.ip}); err != nil {
+			klog.V(4).Infof("%s -> failed to send the ICMP probe to %s: %v", clusterID, t.ip, err)
+		}
+	}
+}
+
+// receive processes the replies to the probes, until the connection is closed.
+func (p *Prober) receive() {
+	buffer := make([]byte, maxPacketSize)
+	for {
+		n, peer, err := p.conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			klog.Errorf("failed to read from the ICMP socket: %v", err)
+			continue
+		}
+		p.handleReply(buffer[:n], peer, time.Now())
+	}
+}
+
+// handleReply records the outcome of the probe the given message is a reply to, if any.
+func (p *Prober) handleReply(b []byte, peer net.Addr, now time.Time) {
+	msg, err := icmp.ParseMessage(protocolICMP, b)
+	if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+		return
+	}
+	echo, ok := msg.Body.(*icmp.Echo)
+	if !ok || echo.ID != p.id {
+		return
+	}
+
+	var n *notification
+	defer func() {
+		if n != nil {
+			p.notifyAll([]notification{*n})
+		}
+	}()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	seq := uint16(echo.Seq)
+	pending, found := p.pending[seq]
+	if !found {
+		return
+	}
+	if addr, ok := peer.(*net.IPAddr); ok && !addr.IP.Equal(pending.ip) {
+		return
+	}
+	delete(p.pending, seq)
+	rtt := now.Sub(pending.sent)
+	if rtt <= 0 {
+		// a zero rtt denotes a lost probe, hence make sure a reply is always accounted as such.
+		rtt = time.Nanosecond
+	}
+	n = p.record(pending, rtt, now)
+}
+
+// record stores the outcome of a probe, and returns the statistics to be notified, if any. A zero rtt denotes a lost probe.
+func (p *Prober) record(pending *pendingProbe, rtt time.Duration, now time.Time) *notification {
+	t, found := p.targets[pending.clusterID]
+	if !found || !t.ip.Equal(pending.ip) {
+		// the target has been removed or changed in the meanwhile.
+		return nil
+	}
+
+	if len(t.results) < Window {
+		t.results = append(t.results, rtt)
+	} else {
+		t.results[t.next] = rtt
+	}
+	t.next = (t.next + 1) % Window
+	t.lastProbe = now
+	t.sinceReport++
+
+	if rtt == 0 {
+		t.consecutiveFailures++
+	} else {
+		t.consecutiveFailures = 0
+	}
+
+	reachable := t.consecutiveFailures < p.opts.FailureThreshold
+	if reachable != t.reachable {
+		t.reachable = reachable
+		if !reachable {
+			klog.Warningf("%s -> the remote gateway at address %s is unreachable: %d consecutive probes lost",
+				pending.clusterID, t.ip, t.consecutiveFailures)
+		} else {
+			klog.Infof("%s -> the remote gateway at address %s is reachable again", pending.clusterID, t.ip)
+		}
+	} else if t.sinceReport < Window {
+		return nil
+	}
+
+	t.sinceReport = 0
+	return &notification{clusterID: pending.clusterID, statistics: t.statistics()}
+}
+
+// notifyAll invokes the notify function for the given notifications. It must be called without holding the mutex.
+func (p *Prober) notifyAll(notifications []notification) {
+	if p.notify == nil {
+		return
+	}
+	for i := range notifications {
+		p.notify(notifications[i].clusterID, notifications[i].statistics)
+	}
+}
+
+// statistics computes the statistics from the outcomes of the last probes.
+func (t *target) statistics() Statistics {
+	stats := Statistics{
+		Probes:              len(t.results),
+		ConsecutiveFailures: t.consecutiveFailures,
+		Reachable:           t.reachable,
+		LastProbeTime:       t.lastProbe,
+	}
+	var total time.Duration
+	var replies int
+	for _, rtt := range t.results {
+		if rtt > 0 {
+			total += rtt
+			replies++
+		}
+	}
+	if replies > 0 {
+		stats.RTT = total / time.Duration(replies)
+	}
+	if stats.Probes > 0 {
+		stats.PacketLoss = (stats.Probes - replies) * 100 / stats.Probes
+	}
+	return stats
+}
@@ -0,0 +1,177 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probe
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// connMock records the packets written, without actually sending them.
+type connMock struct {
+	written []*icmp.Echo
+	dsts    []net.Addr
+}
+
+func (c *connMock) ReadFrom(b []byte) (int, net.Addr, error) { return 0, nil, net.ErrClosed }
+
+func (c *connMock) WriteTo(b []byte, dst net.Addr) (int, error) {
+	msg, err := icmp.ParseMessage(protocolICMP, b)
+	Expect(err).ToNot(HaveOccurred())
+	c.written = append(c.written, msg.Body.(*icmp.Echo))
+	c.dsts = append(c.dsts, dst)
+	return len(b), nil
+}
+
+func (c *connMock) Close() error { return nil }
+
+var _ = Describe("Prober", func() {
+	const clusterID = "remote-cluster-id"
+
+	var (
+		conn          *connMock
+		prober        *Prober
+		target        net.IP
+		now           time.Time
+		notifications []Statistics
+	)
+
+	reply := func(echo *icmp.Echo, from net.IP, at time.Time) {
+		msg := icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: echo}
+		b, err := msg.Marshal(nil)
+		Expect(err).ToNot(HaveOccurred())
+		prober.handleReply(b, &net.IPAddr{IP: from}, at)
+	}
+
+	// round sends a new probe, and replies to it after the given rtt (a zero value means no reply).
+	round := func(rtt time.Duration) {
+		now = now.Add(DefaultInterval)
+		prober.probe(now)
+		if rtt > 0 {
+			reply(conn.written[len(conn.written)-1], target, now.Add(rtt))
+		}
+	}
+
+	BeforeEach(func() {
+		conn = &connMock{}
+		notifications = nil
+		prober = New(conn, Options{Interval: DefaultInterval, FailureThreshold: 3}, func(id string, stats Statistics) {
+			Expect(id).To(Equal(clusterID))
+			notifications = append(notifications, stats)
+		})
+		target = net.ParseIP("10.201.0.0")
+		now = time.Now()
+		prober.SetTarget(clusterID, target)
+	})
+
+	It("should send the probes to the remote gateway", func() {
+		round(0)
+		round(0)
+		Expect(conn.written).To(HaveLen(2))
+		Expect(conn.dsts[0].String()).To(Equal("10.201.0.0"))
+		Expect(conn.written[0].ID).To(Equal(prober.id))
+		Expect(conn.written[1].Seq).To(Equal(conn.written[0].Seq + 1))
+	})
+
+	It("should compute the average rtt of the replies and the packet loss", func() {
+		round(10 * time.Millisecond)
+		round(20 * time.Millisecond)
+		round(0)
+		round(30 * time.Millisecond)
+
+		stats, found := prober.Statistics(clusterID)
+		Expect(found).To(BeTrue())
+		Expect(stats.Probes).To(Equal(4))
+		Expect(stats.RTT).To(Equal(20 * time.Millisecond))
+		Expect(stats.PacketLoss).To(Equal(25))
+		Expect(stats.Reachable).To(BeTrue())
+	})
+
+	It("should account for the probes without reply as lost", func() {
+		for i := 0; i < 3; i++ {
+			round(10 * time.Millisecond)
+			round(0)
+		}
+		round(0)
+
+		stats, _ := prober.Statistics(clusterID)
+		Expect(stats.Probes).To(Equal(6))
+		Expect(stats.PacketLoss).To(Equal(50))
+		Expect(stats.ConsecutiveFailures).To(Equal(1))
+		Expect(stats.Reachable).To(BeTrue())
+	})
+
+	It("should ignore replies coming from unexpected sources", func() {
+		round(0)
+		reply(conn.written[0], net.ParseIP("10.202.0.1"), now)
+		round(0)
+
+		stats, _ := prober.Statistics(clusterID)
+		Expect(stats.PacketLoss).To(Equal(100))
+	})
+
+	It("should report the statistics once every window", func() {
+		for i := 0; i <= Window; i++ {
+			round(10 * time.Millisecond)
+		}
+		Expect(notifications).To(HaveLen(1))
+		Expect(notifications[0].Probes).To(Equal(Window))
+	})
+
+	When("the probes are lost for longer than the threshold", func() {
+		BeforeEach(func() {
+			round(10 * time.Millisecond)
+			for i := 0; i < 4; i++ {
+				round(0)
+			}
+		})
+
+		It("should report the remote gateway as unreachable", func() {
+			Expect(notifications).To(HaveLen(1))
+			Expect(notifications[0].Reachable).To(BeFalse())
+			Expect(notifications[0].ConsecutiveFailures).To(Equal(3))
+		})
+
+		It("should report the remote gateway as reachable when it replies again", func() {
+			reply(conn.written[len(conn.written)-1], target, now)
+			Expect(notifications).To(HaveLen(2))
+			Expect(notifications[1].Reachable).To(BeTrue())
+		})
+	})
+
+	It("should reset the statistics if the target changes", func() {
+		round(0)
+		round(0)
+		prober.SetTarget(clusterID, net.ParseIP("10.203.0.0"))
+
+		stats, _ := prober.Statistics(clusterID)
+		Expect(stats.Probes).To(BeZero())
+	})
+
+	It("should stop probing the removed targets", func() {
+		round(0)
+		prober.RemoveTarget(clusterID)
+		round(0)
+
+		Expect(conn.written).To(HaveLen(1))
+		_, found := prober.Statistics(clusterID)
+		Expect(found).To(BeFalse())
+	})
+})
@@ -15,14 +15,15 @@
 package wireguard
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"k8s.io/klog/v2"
 
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/liqotech/liqo/pkg/liqonet/mtu"
 	"github.com/liqotech/liqo/pkg/liqonet/tunnel"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
 )
@@ -52,6 +54,11 @@ const (
 	ListeningPort = "port"
 	// AllowedIPs is the key of the allowedIPs entry in the back-end map.
 	AllowedIPs = "allowedIPs"
+	// NATTraversal is the key of the natTraversal entry in the back-end map, set to the way the endpoint traverses the NATs.
+	NATTraversal = "natTraversal"
+	// RelayAddress is the key of the relayAddress entry in the back-end map, set to the address of the relay
+	// through which the cluster is willing to exchange the tunnel traffic.
+	RelayAddress = "relayAddress"
 	// DeviceName name of wireguard tunnel created on the custom network namespace.
 	// This tunnel is used to interconnect the local cluster with the remote ones.
 	DeviceName = "liqo.tunnel"
@@ -60,10 +67,14 @@ const (
 	// name of the secret that contains the public key used by wireguard.
 	keysName = "wireguard-pubkey"
 	// KeysLabel label for the secret that contains the public key.
-	KeysLabel   = "net.liqo.io/key"
-	defaultPort = 5871
+	KeysLabel = "net.liqo.io/key"
+	// DefaultPort is the port the wireguard device listens on.
+	DefaultPort = 5871
 	// KeepAliveInterval interval used to send keepalive checks for the wireguard tunnels.
 	KeepAliveInterval = 10 * time.Second
+	// HolePunchingKeepAliveInterval interval used to send keepalive checks for the wireguard tunnels traversing a NAT.
+	// It is shorter than the default one, to open the mappings in the NATs quickly and keep them from expiring.
+	HolePunchingKeepAliveInterval = 5 * time.Second
 	// MTU size of mtu for wireguard interface.
 	MTU = 1415
 	// Ports 1-65535 are available.
@@ -89,12 +100,28 @@ type wgConfig struct {
 // ipv4 or ipv6 family.
 type ResolverFunc func(network string, address string) (*net.IPAddr, error)
 
+// wgClient is the subset of the wgctrl.Client methods used to interact with the wireguard device.
+type wgClient interface {
+	ConfigureDevice(name string, cfg wgtypes.Config) error
+	Device(name string) (*wgtypes.Device, error)
+	Close() error
+}
+
 // Wireguard a wrapper for the wireguard device and its configuration.
 type Wireguard struct {
+	// mutex protects the following fields from the concurrent accesses of the key rotation routine.
+	mutex       sync.Mutex
 	connections map[string]*netv1alpha1.Connection
-	client      *wgctrl.Client
+	peers       map[string]*peerState
+	client      wgClient
 	link        netlink.Link
 	conf        wgConfig
+	rotation    keyRotation
+	// userspace is the userspace device, set only if the kernel implementation is not in use.
+	userspace *userspaceDevice
+
+	k8sClient k8s.Interface
+	namespace string
 }
 
 // NewDriver creates a new WireGuard driver.
@@ -102,24 +129,43 @@ func NewDriver(k8sClient k8s.Interface, namespace string) (tunnel.Driver, error)
 	var err error
 	w := Wireguard{
 		connections: make(map[string]*netv1alpha1.Connection),
+		peers:       make(map[string]*peerState),
 		conf: wgConfig{
-			port: defaultPort,
+			port: DefaultPort,
+		},
+		rotation: keyRotation{
+			interval: keyRotationInterval,
+			rotated:  make(chan struct{}, 1),
 		},
+		k8sClient: k8sClient,
+		namespace: namespace,
 	}
-	err = w.setKeys(k8sClient, namespace)
+	err = w.setKeys()
 	if err != nil {
 		return nil, err
 	}
 	if err = w.setWGLink(); err != nil {
 		return nil, fmt.Errorf("failed to setup %s link: %w", DriverName, err)
 	}
+
+	defer func() {
+		if err != nil && w.userspace != nil {
+			if e := w.userspace.Close(); e != nil {
+				klog.Errorf("Failed to close userspace device: %v", e)
+			}
+			w.userspace = nil
+		}
+	}()
+
 	// create controller.
-	if w.client, err = wgctrl.New(); err != nil {
+	client, err := wgctrl.New()
+	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("wgctrl is not available on this system")
 		}
 		return nil, fmt.Errorf("failed to open wgctl client: %w", err)
 	}
+	w.client = client
 
 	defer func() {
 		if err != nil {
@@ -130,7 +176,7 @@ func NewDriver(k8sClient k8s.Interface, namespace string) (tunnel.Driver, error)
 		}
 	}()
 
-	port := defaultPort
+	port := DefaultPort
 	// configure the device. the device is still down.
 	peerConfigs := make([]wgtypes.PeerConfig, 0)
 	cfg := wgtypes.Config{
@@ -160,6 +206,11 @@ func (w *Wireguard) Init() error {
 
 	klog.Infof("%s interface named %s, is up on i/f number %d, listening on port :%d, with key %s", DriverName,
 		w.link.Attrs().Name, w.link.Attrs().Index, w.conf.port, w.conf.pubKey)
+
+	if w.rotation.stop == nil {
+		w.rotation.stop = make(chan struct{})
+		go w.runKeyRotation(w.rotation.stop)
+	}
 	return nil
 }
 
@@ -183,54 +234,64 @@ func (w *Wireguard) ConnectToEndpoint(tep *netv1alpha1.TunnelEndpoint) (*netv1al
 		return newConnectionOnError(err.Error()), err
 	}
 
-	// delete or update old peers for ClusterID.
+	// parse the key the remote cluster is rotating to, if any.
+	nextKey, err := getNextKey(tep, NextPublicKey)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	// parse the local next key the remote cluster confirmed to have configured, if any.
+	installedNextKey, err := getNextKey(tep, InstalledNextPublicKey)
+	if err != nil {
+		return newConnectionOnError(err.Error()), err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	peerConfiguration := map[string]string{ListeningPort: strconv.Itoa(endpoint.Port), EndpointIP: endpoint.IP.String(),
+		AllowedIPs: stringAllowedIPs, PublicKey: remoteKey.String()}
+	if nextKey != nil {
+		peerConfiguration[NextPublicKey] = nextKey.String()
+	}
+	if installedNextKey != nil {
+		peerConfiguration[InstalledNextPublicKey] = installedNextKey.String()
+	}
+	natTraversal := tep.Spec.BackendConfig[NATTraversal]
+	if natTraversal != "" {
+		peerConfiguration[NATTraversal] = natTraversal
+	}
+
+	// check if the peer configuration is updated.
 	oldCon, found := w.connections[tep.Spec.ClusterID]
+	if found && reflect.DeepEqual(oldCon.PeerConfiguration, peerConfiguration) {
+		return oldCon, nil
+	}
 	if found {
-		// check if the peer configuration is updated.
-		if stringAllowedIPs == oldCon.PeerConfiguration[AllowedIPs] && remoteKey.String() == oldCon.PeerConfiguration[PublicKey] &&
-			endpoint.IP.String() == oldCon.PeerConfiguration[EndpointIP] && strconv.Itoa(endpoint.Port) == oldCon.PeerConfiguration[ListeningPort] {
-			return oldCon, nil
-		}
 		klog.V(4).Infof("updating peer configuration for cluster %s", tep.Spec.ClusterID)
-		err = w.client.ConfigureDevice(DeviceName, wgtypes.Config{
-			ReplacePeers: false,
-			Peers: []wgtypes.PeerConfig{{PublicKey: *remoteKey,
-				Remove: true,
-			}},
-		})
-		if err != nil {
-			return newConnectionOnError(err.Error()), fmt.Errorf("failed to configure peer with clusterid %s: %w", tep.Spec.ClusterID, err)
-		}
 	} else {
 		klog.V(4).Infof("Connecting cluster %s endpoint %s with publicKey %s",
 			tep.Spec.ClusterID, endpoint.IP.String(), remoteKey)
 	}
 
-	ka := KeepAliveInterval
-	// configure peer.
-	peerCfg := []wgtypes.PeerConfig{{
-		PublicKey:                   *remoteKey,
-		Remove:                      false,
-		UpdateOnly:                  false,
-		Endpoint:                    endpoint,
-		PersistentKeepaliveInterval: &ka,
-		ReplaceAllowedIPs:           true,
-		AllowedIPs:                  allowedIPs,
-	}}
-
+	// configure the peers, removing the ones corresponding to keys no longer in use by the remote cluster.
+	oldState := w.peers[tep.Spec.ClusterID]
+	state := &peerState{current: *remoteKey, next: nextKey, natTraversal: natTraversal != "", installedNext: installedNextKey}
+	// the traffic keeps flowing through the next key, if it has already been promoted and it is still in use.
+	state.promoted = oldState != nil && oldState.promoted && nextKey != nil && *nextKey == *oldState.next
 	err = w.client.ConfigureDevice(DeviceName, wgtypes.Config{
 		ReplacePeers: false,
-		Peers:        peerCfg,
+		Peers:        peerConfigs(oldState, state, endpoint, allowedIPs),
 	})
 	if err != nil {
 		return newConnectionOnError(err.Error()), fmt.Errorf("failed to configure peer with clusterid %s: %w", tep.Spec.ClusterID, err)
 	}
-	//
+	w.peers[tep.Spec.ClusterID] = state
+
 	c := &netv1alpha1.Connection{
-		Status:        netv1alpha1.Connected,
-		StatusMessage: "Cluster peer connected",
-		PeerConfiguration: map[string]string{ListeningPort: strconv.Itoa(endpoint.Port), EndpointIP: endpoint.IP.String(),
-			AllowedIPs: stringAllowedIPs, PublicKey: remoteKey.String()},
+		Status:            netv1alpha1.Connected,
+		StatusMessage:     "Cluster peer connected",
+		PeerConfiguration: peerConfiguration,
 	}
 	w.connections[tep.Spec.ClusterID] = c
 	klog.V(4).Infof("Done connecting cluster peer %s@%s", tep.Spec.ClusterID, endpoint.String())
@@ -241,22 +302,24 @@ func (w *Wireguard) ConnectToEndpoint(tep *netv1alpha1.TunnelEndpoint) (*netv1al
 func (w *Wireguard) DisconnectFromEndpoint(tep *netv1alpha1.TunnelEndpoint) error {
 	klog.V(4).Infof("Removing connection with cluster %s", tep.Spec.ClusterID)
 
-	s, found := tep.Status.Connection.PeerConfiguration[PublicKey]
-	if !found {
-		klog.V(4).Infof("no tunnel configured for cluster %s, nothing to be removed", tep.Spec.ClusterID)
-		return nil
-	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 
-	key, err := wgtypes.ParseKey(s)
+	keys, err := w.configuredKeys(tep)
 	if err != nil {
-		return fmt.Errorf("failed to parse public key %s: %w", s, err)
+		return err
+	}
+	if len(keys) == 0 {
+		klog.V(4).Infof("no tunnel configured for cluster %s, nothing to be removed", tep.Spec.ClusterID)
+		return nil
 	}
 
-	peerCfg := []wgtypes.PeerConfig{
-		{
+	peerCfg := make([]wgtypes.PeerConfig, 0, len(keys))
+	for _, key := range keys {
+		peerCfg = append(peerCfg, wgtypes.PeerConfig{
 			PublicKey: key,
 			Remove:    true,
-		},
+		})
 	}
 	err = w.client.ConfigureDevice(DeviceName, wgtypes.Config{
 		ReplacePeers: false,
@@ -268,17 +331,72 @@ func (w *Wireguard) DisconnectFromEndpoint(tep *netv1alpha1.TunnelEndpoint) erro
 
 	klog.V(4).Infof("Done removing WireGuard peer with clusterid %s", tep.Spec.ClusterID)
 	delete(w.connections, tep.Spec.ClusterID)
+	delete(w.peers, tep.Spec.ClusterID)
 
 	return nil
 }
 
+// configuredKeys returns the keys of the peers configured for the remote cluster described by the given tep.
+// In case the driver has no record of the cluster, they are retrieved from the status of the tep.
+func (w *Wireguard) configuredKeys(tep *netv1alpha1.TunnelEndpoint) ([]wgtypes.Key, error) {
+	if state, found := w.peers[tep.Spec.ClusterID]; found {
+		return state.keys(), nil
+	}
+
+	var keys []wgtypes.Key
+	for _, entry := range []string{PublicKey, NextPublicKey} {
+		s, found := tep.Status.Connection.PeerConfiguration[entry]
+		if !found {
+			continue
+		}
+		key, err := wgtypes.ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", s, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LocalPublicKey returns the public key currently in use by the local wireguard device.
+func (w *Wireguard) LocalPublicKey() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.conf.pubKey.String()
+}
+
+// ListeningPort returns the port the local wireguard device listens on.
+func (w *Wireguard) ListeningPort() int {
+	return w.conf.port
+}
+
 // GetLink returns the netlink.Link referred to the wireguard device.
 func (w *Wireguard) GetLink() netlink.Link {
 	return w.link
 }
 
+// Overhead returns the number of bytes added by the wireguard encapsulation, as accounted for by the default MTU.
+func (w *Wireguard) Overhead() int {
+	return mtu.DefaultPathMTU - MTU
+}
+
 // Close remove the wireguard device from the host.
 func (w *Wireguard) Close() error {
+	w.mutex.Lock()
+	if w.rotation.stop != nil {
+		close(w.rotation.stop)
+		w.rotation.stop = nil
+	}
+	w.mutex.Unlock()
+
+	if w.userspace != nil {
+		// closing the userspace device also removes the corresponding interface.
+		if err := w.userspace.Close(); err != nil {
+			return fmt.Errorf("failed to close the userspace WireGuard device: %w", err)
+		}
+		w.userspace = nil
+		return nil
+	}
 	// it removes the wireguard interface.
 	var err error
 	if link, err := netlink.LinkByName(DeviceName); err == nil {
@@ -304,6 +422,28 @@ func (w *Wireguard) setWGLink() error {
 			return fmt.Errorf("failed to delete existing WireGuard device: %w", err)
 		}
 	}
+	if implementation != ImplementationUserspace {
+		if err = w.addKernelLink(); err == nil {
+			return nil
+		}
+		if implementation == ImplementationKernel || !errors.Is(err, unix.EOPNOTSUPP) {
+			return fmt.Errorf("failed to add wireguard device '%s': %w", DeviceName, err)
+		}
+		klog.Warningf("wireguard kernel module not present, falling back to the userspace implementation")
+	}
+
+	// create the wg device in userspace, running wireguard-go in-process.
+	if w.userspace, err = newUserspaceDevice(DeviceName, MTU); err != nil {
+		return err
+	}
+	if w.link, err = netlink.LinkByName(DeviceName); err != nil {
+		return fmt.Errorf("failed to get wireguard device '%s': %w", DeviceName, err)
+	}
+	return nil
+}
+
+// addKernelLink creates a new wg link, backed by the kernel implementation.
+func (w *Wireguard) addKernelLink() error {
 	// create the wg device (ip link add dev $DefaultDeviceName type wireguard).
 	la := netlink.NewLinkAttrs()
 	la.Name = DeviceName
@@ -313,24 +453,8 @@ func (w *Wireguard) setWGLink() error {
 		LinkType:  "wireguard",
 	}
 
-	if err = netlink.LinkAdd(link); err != nil && !errors.Is(err, unix.EOPNOTSUPP) {
-		return fmt.Errorf("failed to add wireguard device '%s': %w", DeviceName, err)
-	}
-	if errors.Is(err, unix.EOPNOTSUPP) {
-		klog.Warningf("wireguard kernel module not present, falling back to the userspace implementation")
-		cmd := exec.Command("/usr/bin/boringtun", DeviceName, "--disable-drop-privileges", "true")
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		err = cmd.Run()
-		if err != nil {
-			outStr, errStr := stdout.String(), stderr.String()
-			fmt.Printf("out:\n%s\nerr:\n%s\n", outStr, errStr)
-			return fmt.Errorf("failed to add wireguard devices '%s': %w", DeviceName, err)
-		}
-		if w.link, err = netlink.LinkByName(DeviceName); err != nil {
-			return fmt.Errorf("failed to get wireguard device '%s': %w", DeviceName, err)
-		}
+	if err := netlink.LinkAdd(link); err != nil {
+		return err
 	}
 	w.link = link
 	return nil
@@ -340,18 +464,16 @@ func (w *Wireguard) setWGLink() error {
 // wireguard allowedIPs. They are returned as []net.IPNet and
 // as a string (to accommodate comparison/storing on TEP resource).
 func getAllowedIPs(tep *netv1alpha1.TunnelEndpoint) ([]net.IPNet, string, error) {
-	_, remotePodCIDR := utils.GetPodCIDRS(tep)
-	_, remoteExternalCIDR := utils.GetExternalCIDRS(tep)
-
-	_, podCIDR, err := net.ParseCIDR(remotePodCIDR)
-	if err != nil {
-		return nil, "", fmt.Errorf("unable to parse podCIDR %s for cluster %s: %w", remotePodCIDR, tep.Spec.ClusterID, err)
-	}
-	_, externalCIDR, err := net.ParseCIDR(remoteExternalCIDR)
-	if err != nil {
-		return nil, "", fmt.Errorf("unable to parse externalCIDR %s for cluster %s: %w", remoteExternalCIDR, tep.Spec.ClusterID, err)
+	remoteCIDRs := utils.GetRemoteCIDRs(tep)
+	allowedIPs := make([]net.IPNet, 0, len(remoteCIDRs))
+	for _, remoteCIDR := range remoteCIDRs {
+		_, cidr, err := net.ParseCIDR(remoteCIDR)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to parse CIDR %s for cluster %s: %w", remoteCIDR, tep.Spec.ClusterID, err)
+		}
+		allowedIPs = append(allowedIPs, *cidr)
 	}
-	return []net.IPNet{*podCIDR, *externalCIDR}, fmt.Sprintf("%s,%s", remotePodCIDR, remoteExternalCIDR), nil
+	return allowedIPs, strings.Join(remoteCIDRs, ","), nil
 }
 
 func getKey(tep *netv1alpha1.TunnelEndpoint) (*wgtypes.Key, error) {
@@ -368,6 +490,21 @@ func getKey(tep *netv1alpha1.TunnelEndpoint) (*wgtypes.Key, error) {
 	return &key, nil
 }
 
+// getNextKey returns the next key stored in the given entry of the back-end map, or nil if no rotation is in progress.
+func getNextKey(tep *netv1alpha1.TunnelEndpoint, entry string) (*wgtypes.Key, error) {
+	s, found := tep.Spec.BackendConfig[entry]
+	if !found {
+		return nil, nil
+	}
+
+	key, err := wgtypes.ParseKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse next public key %s: %w", s, err)
+	}
+
+	return &key, nil
+}
+
 func getEndpoint(tep *netv1alpha1.TunnelEndpoint, addrResolver ResolverFunc) (*net.UDPAddr, error) {
 	// Get tunnel port.
 	tunnelPort, err := getTunnelPortFromTep(tep)
@@ -436,8 +573,9 @@ func newConnectionOnError(msg string) *netv1alpha1.Connection {
 	}
 }
 
-func (w *Wireguard) setKeys(c k8s.Interface, namespace string) error {
+func (w *Wireguard) setKeys() error {
 	var priv, pub wgtypes.Key
+	c, namespace := w.k8sClient, w.namespace
 	// first we check if a secret containing valid keys already exists.
 	s, err := c.CoreV1().Secrets(namespace).Get(context.Background(), keysName, metav1.GetOptions{})
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -452,11 +590,13 @@ func (w *Wireguard) setKeys(c k8s.Interface, namespace string) error {
 		pub = priv.PublicKey()
 		w.conf.pubKey = pub
 		w.conf.priKey = priv
+		w.rotation.lastRotation = time.Now()
 		pKey := corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      keysName,
-				Namespace: namespace,
-				Labels:    map[string]string{KeysLabel: DriverName},
+				Name:        keysName,
+				Namespace:   namespace,
+				Labels:      map[string]string{KeysLabel: DriverName},
+				Annotations: map[string]string{LastRotationAnnotation: w.rotation.lastRotation.Format(time.RFC3339)},
 			},
 			StringData: map[string]string{PublicKey: pub.String(), PrivateKey: priv.String()},
 		}
@@ -485,7 +625,7 @@ func (w *Wireguard) setKeys(c k8s.Interface, namespace string) error {
 	}
 	w.conf.pubKey = pub
 	w.conf.priKey = priv
-	return nil
+	return w.loadRotationState(s)
 }
 
 // SetNewClient set a new client used to interact with the wireguard device.
@@ -497,6 +637,34 @@ func (w *Wireguard) SetNewClient() error {
 		}
 		return fmt.Errorf("failed to open wgctl client: %w", err)
 	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	w.client = c
 	return nil
 }
+
+// PeerStatistics returns the traffic statistics concerning the peer corresponding to the given remote cluster.
+// In case of a key rotation in progress, the statistics refer to the peer the traffic is currently routed through.
+func (w *Wireguard) PeerStatistics(clusterID string) (*tunnel.PeerStatistics, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	state, found := w.peers[clusterID]
+	if !found {
+		return nil, fmt.Errorf("no peer configured for cluster %s", clusterID)
+	}
+	device, err := w.client.Device(DeviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve WireGuard device: %w", err)
+	}
+	for i := range device.Peers {
+		if peer := &device.Peers[i]; peer.PublicKey == state.active() {
+			return &tunnel.PeerStatistics{
+				LastHandshakeTime: peer.LastHandshakeTime,
+				ReceivedBytes:     peer.ReceiveBytes,
+				TransmittedBytes:  peer.TransmitBytes,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("peer with public key %s not found for cluster %s", state.active(), clusterID)
+}
@@ -0,0 +1,405 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+)
+
+// The rotation of the wireguard keys is performed in two phases, to avoid breaking the tunnels with the remote clusters:
+//  1. a new key pair is generated, and the public key is published (through the secret containing the keys,
+//     and then the NetworkConfig) as the next one. The remote clusters add it as a second peer entry, which
+//     is not yet associated with any allowed IP, and confirm it through the status of the NetworkConfig (which is
+//     propagated back to the TunnelEndpoint);
+//  2. once the grace period is elapsed and all the remote clusters confirmed the next key, the new private key is
+//     configured on the local device. As soon as the
+//     remote clusters detect a handshake completed with the new key, they move the allowed IPs to the new peer
+//     entry and remove the old one. The new key is finally published as the current one.
+
+const (
+	// NextPublicKey is the key of the nextPublicKey entry in back-end map and also for the secret containing the wireguard keys.
+	// It is set only while a key rotation is in progress, and holds the public key which is going to replace the current one.
+	NextPublicKey = "nextPublicKey"
+	// NextPrivateKey is the key of the next private key for the secret containing the wireguard keys.
+	NextPrivateKey = "nextPrivateKey"
+	// InstalledNextPublicKey is the key of the back-end map entry holding the local next public key, once the remote
+	// cluster confirmed it configured it as an additional peer.
+	InstalledNextPublicKey = "installedNextPublicKey"
+	// LastRotationAnnotation is the annotation of the keys secret storing the time the keys have been last rotated.
+	LastRotationAnnotation = "net.liqo.io/last-key-rotation"
+	// NextKeyCreationAnnotation is the annotation of the keys secret storing the time the next key has been generated.
+	NextKeyCreationAnnotation = "net.liqo.io/next-key-creation"
+	// KeyRotationGracePeriod is the minimum time elapsed between the publication of the next key and its actual usage.
+	// The key is anyhow switched only once all the remote clusters confirmed they configured it as an additional peer.
+	KeyRotationGracePeriod = 1 * time.Minute
+
+	// interval between two subsequent checks of whether a rotation phase has to be performed.
+	rotationCheckInterval = 10 * time.Second
+	// interval between two subsequent checks of whether remote clusters started using their next keys.
+	promotionCheckInterval = 1 * time.Second
+)
+
+// keyRotationInterval is the interval between two subsequent rotations of the wireguard keys.
+var keyRotationInterval time.Duration
+
+// SetKeyRotationInterval sets the interval between two subsequent rotations of the wireguard keys.
+// A zero value disables the key rotation, which is the default. It has to be called before creating the driver.
+func SetKeyRotationInterval(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("the key rotation interval cannot be negative, got %s", interval)
+	}
+	if interval > 0 && interval < 2*KeyRotationGracePeriod {
+		return fmt.Errorf("the key rotation interval must be at least %s, got %s", 2*KeyRotationGracePeriod, interval)
+	}
+	keyRotationInterval = interval
+	return nil
+}
+
+// keyRotation holds the state of the rotation of the local keys.
+type keyRotation struct {
+	// interval between two subsequent rotations, zero if disabled.
+	interval time.Duration
+	// lastRotation is the time the keys have been last rotated (or generated).
+	lastRotation time.Time
+	// next is the private key which is going to replace the current one, if a rotation is in progress.
+	next *wgtypes.Key
+	// nextCreation is the time the next key has been generated.
+	nextCreation time.Time
+	// deviceOutdated is set if the private key has been rotated, but the device has not been successfully configured yet.
+	deviceOutdated bool
+	// rotated is notified every time the local key is rotated.
+	rotated chan struct{}
+	// stop is closed to terminate the key rotation loop.
+	stop chan struct{}
+}
+
+// peerState keeps track of the wireguard peers configured for a remote cluster.
+type peerState struct {
+	// current is the public key currently advertised by the remote cluster.
+	current wgtypes.Key
+	// next is the public key the remote cluster is rotating to, if any.
+	next *wgtypes.Key
+	// promoted is set once the remote cluster started using the next key, and the traffic has been moved to it.
+	promoted bool
+	// natTraversal is set if the tunnel traverses a NAT, hence requiring more frequent keepalives.
+	natTraversal bool
+	// installedNext is the local next public key the remote cluster confirmed to have configured as a peer, if any.
+	installedNext *wgtypes.Key
+}
+
+// active returns the key of the peer the traffic towards the remote cluster is routed through.
+func (p *peerState) active() wgtypes.Key {
+	if p.promoted {
+		return *p.next
+	}
+	return p.current
+}
+
+// keys returns the keys of all the peers configured for the remote cluster.
+func (p *peerState) keys() []wgtypes.Key {
+	if p.next == nil {
+		return []wgtypes.Key{p.current}
+	}
+	if p.promoted {
+		return []wgtypes.Key{*p.next}
+	}
+	return []wgtypes.Key{p.current, *p.next}
+}
+
+// peerConfigs returns the configurations needed to move the peers of a remote cluster from the old state (possibly nil)
+// to the new one. The active peer is associated with the allowed IPs, while the one corresponding to the next key of the
+// remote cluster is configured with the endpoint only, to accept the handshakes initiated with the next key.
+func peerConfigs(old, desired *peerState, endpoint *net.UDPAddr, allowedIPs []net.IPNet) []wgtypes.PeerConfig {
+	ka := KeepAliveInterval
+	if desired.natTraversal {
+		ka = HolePunchingKeepAliveInterval
+	}
+	configs := []wgtypes.PeerConfig{{
+		PublicKey:                   desired.active(),
+		Endpoint:                    endpoint,
+		PersistentKeepaliveInterval: &ka,
+		ReplaceAllowedIPs:           true,
+		AllowedIPs:                  allowedIPs,
+	}}
+	if desired.next != nil && !desired.promoted {
+		configs = append(configs, wgtypes.PeerConfig{
+			PublicKey:         *desired.next,
+			Endpoint:          endpoint,
+			ReplaceAllowedIPs: true,
+		})
+	}
+	if old == nil {
+		return configs
+	}
+	for _, oldKey := range old.keys() {
+		stale := true
+		for _, key := range desired.keys() {
+			if key == oldKey {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			configs = append(configs, wgtypes.PeerConfig{PublicKey: oldKey, Remove: true})
+		}
+	}
+	return configs
+}
+
+// KeyRotationStatus returns the current status of the rotation of the local keys.
+func (w *Wireguard) KeyRotationStatus() *netv1alpha1.KeyRotationStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	status := &netv1alpha1.KeyRotationStatus{Interval: metav1.Duration{Duration: w.rotation.interval}}
+	if w.rotation.lastRotation.IsZero() {
+		return status
+	}
+	last := metav1.NewTime(w.rotation.lastRotation.Truncate(time.Second))
+	status.LastRotationTime = &last
+	if w.rotation.interval > 0 {
+		next := metav1.NewTime(w.rotation.lastRotation.Add(w.rotation.interval).Truncate(time.Second))
+		status.NextRotationTime = &next
+	}
+	return status
+}
+
+// KeyRotated returns a channel which is notified every time the local key is rotated.
+func (w *Wireguard) KeyRotated() <-chan struct{} {
+	return w.rotation.rotated
+}
+
+// runKeyRotation periodically rotates the local keys and promotes the next keys of the remote clusters,
+// until the stop channel is closed.
+func (w *Wireguard) runKeyRotation(stop <-chan struct{}) {
+	rotationTicker := time.NewTicker(rotationCheckInterval)
+	defer rotationTicker.Stop()
+	promotionTicker := time.NewTicker(promotionCheckInterval)
+	defer promotionTicker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-rotationTicker.C:
+			if err := w.rotateKeys(now); err != nil {
+				klog.Errorf("an error occurred while rotating the %s keys: %v", DriverName, err)
+			}
+		case <-promotionTicker.C:
+			if err := w.promotePeers(); err != nil {
+				klog.Errorf("an error occurred while promoting the next keys of the %s peers: %v", DriverName, err)
+			}
+		}
+	}
+}
+
+// rotateKeys performs, if necessary, the next phase of the rotation of the local keys.
+func (w *Wireguard) rotateKeys(now time.Time) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	r := &w.rotation
+	switch {
+	case r.deviceOutdated:
+		return w.configurePrivateKey()
+
+	case r.next != nil && !now.Before(r.nextCreation.Add(KeyRotationGracePeriod)) && w.nextKeyInstalled():
+		next := *r.next
+		if err := w.storeKeys(next, nil, now, time.Time{}); err != nil {
+			return err
+		}
+		w.conf.priKey, w.conf.pubKey = next, next.PublicKey()
+		r.next, r.nextCreation, r.lastRotation = nil, time.Time{}, now
+		r.deviceOutdated = true
+		klog.Infof("rotated the %s key, the current public key is %s", DriverName, w.conf.pubKey)
+		// notify the rotation, without blocking if a notification is already pending.
+		select {
+		case r.rotated <- struct{}{}:
+		default:
+		}
+		return w.configurePrivateKey()
+
+	case r.next != nil, r.interval == 0:
+		// either a rotation is in progress, or the rotation is disabled.
+		return nil
+
+	case !now.Before(r.lastRotation.Add(r.interval)):
+		next, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate the next private key: %w", err)
+		}
+		if err := w.storeKeys(w.conf.priKey, &next, r.lastRotation, now); err != nil {
+			return err
+		}
+		r.next, r.nextCreation = &next, now
+		klog.Infof("generated the next %s key %s, which will replace the current one %s starting from %s",
+			DriverName, next.PublicKey(), w.conf.pubKey, now.Add(KeyRotationGracePeriod).Format(time.RFC3339))
+		return nil
+	}
+	return nil
+}
+
+// nextKeyInstalled returns whether all the remote clusters confirmed they configured the next local key as a peer,
+// hence it can be used without breaking the tunnels.
+func (w *Wireguard) nextKeyInstalled() bool {
+	next := w.rotation.next.PublicKey()
+	for clusterID, state := range w.peers {
+		if state.installedNext == nil || *state.installedNext != next {
+			klog.V(4).Infof("%s -> waiting for the remote cluster to configure the next key %s", clusterID, next)
+			return false
+		}
+	}
+	return true
+}
+
+// configurePrivateKey configures the current private key on the wireguard device.
+func (w *Wireguard) configurePrivateKey() error {
+	if err := w.client.ConfigureDevice(DeviceName, wgtypes.Config{PrivateKey: &w.conf.priKey}); err != nil {
+		return fmt.Errorf("failed to configure the private key on WireGuard device: %w", err)
+	}
+	w.rotation.deviceOutdated = false
+	return nil
+}
+
+// promotePeers moves the traffic to the next key of the remote clusters which completed a handshake with it,
+// and removes the peer corresponding to the old key.
+func (w *Wireguard) promotePeers() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	pending := false
+	for _, state := range w.peers {
+		if state.next != nil && !state.promoted {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	device, err := w.client.Device(DeviceName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve WireGuard device: %w", err)
+	}
+	peers := make(map[wgtypes.Key]wgtypes.Peer, len(device.Peers))
+	for i := range device.Peers {
+		peers[device.Peers[i].PublicKey] = device.Peers[i]
+	}
+
+	for clusterID, state := range w.peers {
+		if state.next == nil || state.promoted {
+			continue
+		}
+		current, found := peers[state.current]
+		if !found {
+			continue
+		}
+		// the remote cluster switched to the next key as soon as a handshake is completed with it.
+		next, found := peers[*state.next]
+		if !found || !next.LastHandshakeTime.After(current.LastHandshakeTime) {
+			continue
+		}
+
+		promoted := *state
+		promoted.promoted = true
+		cfg := wgtypes.Config{Peers: peerConfigs(state, &promoted, current.Endpoint, current.AllowedIPs)}
+		if err := w.client.ConfigureDevice(DeviceName, cfg); err != nil {
+			return fmt.Errorf("failed to promote the next key of the peer with clusterid %s: %w", clusterID, err)
+		}
+		w.peers[clusterID] = &promoted
+		klog.Infof("%s -> the remote cluster rotated its key from %s to %s", clusterID, state.current, state.next)
+	}
+	return nil
+}
+
+// storeKeys persists the given keys and timestamps in the secret containing the wireguard keys.
+func (w *Wireguard) storeKeys(current wgtypes.Key, next *wgtypes.Key, lastRotation, nextCreation time.Time) error {
+	secrets := w.k8sClient.CoreV1().Secrets(w.namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		s, err := secrets.Get(context.Background(), keysName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		setKeysData(s, current, next, lastRotation, nextCreation)
+		_, err = secrets.Update(context.Background(), s, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update the secret with name %s: %w", keysName, err)
+	}
+	return nil
+}
+
+// setKeysData sets the given keys and timestamps in the secret containing the wireguard keys.
+func setKeysData(s *corev1.Secret, current wgtypes.Key, next *wgtypes.Key, lastRotation, nextCreation time.Time) {
+	if s.Data == nil {
+		s.Data = make(map[string][]byte)
+	}
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string)
+	}
+	s.Data[PrivateKey] = []byte(current.String())
+	s.Data[PublicKey] = []byte(current.PublicKey().String())
+	s.Annotations[LastRotationAnnotation] = lastRotation.Format(time.RFC3339)
+
+	if next == nil {
+		delete(s.Data, NextPrivateKey)
+		delete(s.Data, NextPublicKey)
+		delete(s.Annotations, NextKeyCreationAnnotation)
+		return
+	}
+	s.Data[NextPrivateKey] = []byte(next.String())
+	s.Data[NextPublicKey] = []byte(next.PublicKey().String())
+	s.Annotations[NextKeyCreationAnnotation] = nextCreation.Format(time.RFC3339)
+}
+
+// loadRotationState loads the state of the key rotation from the secret containing the wireguard keys.
+func (w *Wireguard) loadRotationState(s *corev1.Secret) error {
+	w.rotation.lastRotation = s.CreationTimestamp.Time
+	if value, found := s.Annotations[LastRotationAnnotation]; found {
+		lastRotation, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse annotation %s of secret %s: %w", LastRotationAnnotation, keysName, err)
+		}
+		w.rotation.lastRotation = lastRotation
+	}
+
+	nextPrivKey, found := s.Data[NextPrivateKey]
+	if !found {
+		return nil
+	}
+	next, err := wgtypes.ParseKey(string(nextPrivKey))
+	if err != nil {
+		return fmt.Errorf("an error occurred while parsing the next private key for the wireguard driver: %w", err)
+	}
+	nextCreation, err := time.Parse(time.RFC3339, s.Annotations[NextKeyCreationAnnotation])
+	if err != nil {
+		return fmt.Errorf("failed to parse annotation %s of secret %s: %w", NextKeyCreationAnnotation, keysName, err)
+	}
+	w.rotation.next, w.rotation.nextCreation = &next, nextCreation
+	return nil
+}
@@ -0,0 +1,386 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+)
+
+const (
+	namespace       = "liqo"
+	remoteClusterID = "remote-cluster-id"
+)
+
+var _ = Describe("Key rotation", func() {
+	var (
+		clientset *fake.Clientset
+		client    *clientMock
+		w         *Wireguard
+		now       time.Time
+	)
+
+	newKey := func() wgtypes.Key {
+		key, err := wgtypes.GeneratePrivateKey()
+		Expect(err).ToNot(HaveOccurred())
+		return key
+	}
+
+	getSecret := func() *corev1.Secret {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), keysName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return secret
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		client = newClientMock()
+		now = time.Now()
+		w = &Wireguard{
+			connections: make(map[string]*netv1alpha1.Connection),
+			peers:       make(map[string]*peerState),
+			client:      client,
+			rotation:    keyRotation{interval: time.Hour, rotated: make(chan struct{}, 1)},
+			k8sClient:   clientset,
+			namespace:   namespace,
+		}
+	})
+
+	Describe("the SetKeyRotationInterval function", func() {
+		AfterEach(func() { keyRotationInterval = 0 })
+
+		DescribeTable("should validate the interval",
+			func(interval time.Duration, expectErr bool) {
+				err := SetKeyRotationInterval(interval)
+				if expectErr {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(keyRotationInterval).To(Equal(interval))
+				}
+			},
+			Entry("zero disables rotation", time.Duration(0), false),
+			Entry("a valid interval", 24*time.Hour, false),
+			Entry("a negative interval", -time.Hour, true),
+			Entry("an interval shorter than the grace period", time.Minute, true),
+		)
+	})
+
+	Describe("the rotation of the local keys", func() {
+		var initialKey wgtypes.Key
+
+		BeforeEach(func() {
+			Expect(w.setKeys()).To(Succeed())
+			initialKey = w.conf.priKey
+			Expect(client.ConfigureDevice(DeviceName, wgtypes.Config{PrivateKey: &initialKey})).To(Succeed())
+		})
+
+		It("should record the time the keys have been generated", func() {
+			Expect(w.rotation.lastRotation).To(BeTemporally("~", now, time.Second))
+			Expect(getSecret().Annotations).To(HaveKey(LastRotationAnnotation))
+		})
+
+		When("the rotation interval is not yet elapsed", func() {
+			It("should do nothing", func() {
+				Expect(w.rotateKeys(now.Add(30 * time.Minute))).To(Succeed())
+				Expect(w.rotation.next).To(BeNil())
+				Expect(getSecret().Data).ToNot(HaveKey(NextPublicKey))
+			})
+		})
+
+		When("the rotation is disabled", func() {
+			BeforeEach(func() { w.rotation.interval = 0 })
+
+			It("should do nothing", func() {
+				Expect(w.rotateKeys(now.Add(365 * 24 * time.Hour))).To(Succeed())
+				Expect(w.rotation.next).To(BeNil())
+			})
+		})
+
+		When("the rotation interval is elapsed", func() {
+			var rotationStart time.Time
+
+			BeforeEach(func() {
+				rotationStart = now.Add(time.Hour + time.Second)
+				Expect(w.rotateKeys(rotationStart)).To(Succeed())
+			})
+
+			It("should generate and publish the next key, keeping the current one in use", func() {
+				Expect(w.rotation.next).ToNot(BeNil())
+				Expect(w.conf.priKey).To(Equal(initialKey))
+				Expect(client.privateKey).To(Equal(initialKey))
+
+				secret := getSecret()
+				Expect(secret.Data).To(HaveKeyWithValue(PrivateKey, []byte(initialKey.String())))
+				Expect(secret.Data).To(HaveKeyWithValue(NextPublicKey, []byte(w.rotation.next.PublicKey().String())))
+				Expect(secret.Data).To(HaveKeyWithValue(NextPrivateKey, []byte(w.rotation.next.String())))
+				Expect(secret.Annotations).To(HaveKey(NextKeyCreationAnnotation))
+			})
+
+			It("should not switch to the next key before the grace period", func() {
+				Expect(w.rotateKeys(rotationStart.Add(KeyRotationGracePeriod / 2))).To(Succeed())
+				Expect(w.conf.priKey).To(Equal(initialKey))
+				Expect(w.rotation.next).ToNot(BeNil())
+			})
+
+			When("a remote cluster is connected", func() {
+				var tep *netv1alpha1.TunnelEndpoint
+
+				connect := func(installedNext string) {
+					tep.Spec.BackendConfig[InstalledNextPublicKey] = installedNext
+					_, err := w.ConnectToEndpoint(tep)
+					Expect(err).ToNot(HaveOccurred())
+				}
+
+				BeforeEach(func() {
+					tep = &netv1alpha1.TunnelEndpoint{
+						Spec: netv1alpha1.TunnelEndpointSpec{
+							ClusterID:             remoteClusterID,
+							EndpointIP:            "10.0.0.1",
+							RemotePodCIDR:         "10.200.0.0/16",
+							RemoteNATPodCIDR:      liqoconst.DefaultCIDRValue,
+							RemoteExternalCIDR:    "10.201.0.0/16",
+							RemoteNATExternalCIDR: liqoconst.DefaultCIDRValue,
+							BackendConfig:         map[string]string{PublicKey: newKey().PublicKey().String(), ListeningPort: "5871"},
+						},
+					}
+					_, err := w.ConnectToEndpoint(tep)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("should not switch to the next key before the remote cluster confirmed it", func() {
+					Expect(w.rotateKeys(rotationStart.Add(2 * KeyRotationGracePeriod))).To(Succeed())
+					Expect(w.conf.priKey).To(Equal(initialKey))
+
+					By("confirming a key different from the next one")
+					connect(newKey().PublicKey().String())
+					Expect(w.rotateKeys(rotationStart.Add(2 * KeyRotationGracePeriod))).To(Succeed())
+					Expect(w.conf.priKey).To(Equal(initialKey))
+				})
+
+				It("should switch to the next key once the remote cluster confirmed it", func() {
+					next := *w.rotation.next
+					connect(next.PublicKey().String())
+					Expect(w.rotateKeys(rotationStart.Add(2 * KeyRotationGracePeriod))).To(Succeed())
+					Expect(w.conf.priKey).To(Equal(next))
+					Expect(client.privateKey).To(Equal(next))
+				})
+			})
+
+			When("the grace period is elapsed", func() {
+				var next wgtypes.Key
+
+				BeforeEach(func() {
+					next = *w.rotation.next
+					Expect(w.rotateKeys(rotationStart.Add(KeyRotationGracePeriod))).To(Succeed())
+				})
+
+				It("should configure the next key on the device", func() {
+					Expect(w.conf.priKey).To(Equal(next))
+					Expect(w.conf.pubKey).To(Equal(next.PublicKey()))
+					Expect(client.privateKey).To(Equal(next))
+				})
+
+				It("should publish the next key as the current one", func() {
+					secret := getSecret()
+					Expect(secret.Data).To(HaveKeyWithValue(PublicKey, []byte(next.PublicKey().String())))
+					Expect(secret.Data).To(HaveKeyWithValue(PrivateKey, []byte(next.String())))
+					Expect(secret.Data).ToNot(HaveKey(NextPublicKey))
+					Expect(secret.Data).ToNot(HaveKey(NextPrivateKey))
+				})
+
+				It("should notify the rotation", func() { Expect(w.KeyRotated()).To(Receive()) })
+
+				It("should report the rotation in the status", func() {
+					status := w.KeyRotationStatus()
+					Expect(status.Interval.Duration).To(Equal(time.Hour))
+					Expect(status.LastRotationTime.Time).To(BeTemporally("~", rotationStart.Add(KeyRotationGracePeriod), time.Second))
+					Expect(status.NextRotationTime.Time).To(BeTemporally("~", rotationStart.Add(KeyRotationGracePeriod+time.Hour), time.Second))
+				})
+
+				It("should load the rotated keys upon restart", func() {
+					restarted := &Wireguard{k8sClient: clientset, namespace: namespace}
+					Expect(restarted.setKeys()).To(Succeed())
+					Expect(restarted.conf.priKey).To(Equal(next))
+					Expect(restarted.rotation.next).To(BeNil())
+					Expect(restarted.rotation.lastRotation).To(BeTemporally("~", rotationStart.Add(KeyRotationGracePeriod), time.Second))
+				})
+			})
+
+			It("should complete the pending rotation upon restart", func() {
+				restarted := &Wireguard{k8sClient: clientset, namespace: namespace, client: client}
+				Expect(restarted.setKeys()).To(Succeed())
+				Expect(restarted.rotation.next).To(Equal(w.rotation.next))
+				Expect(restarted.rotateKeys(rotationStart.Add(KeyRotationGracePeriod))).To(Succeed())
+				Expect(restarted.conf.priKey).To(Equal(*w.rotation.next))
+			})
+		})
+	})
+
+	Describe("the peers of a remote cluster rotating its keys", func() {
+		var (
+			tep           *netv1alpha1.TunnelEndpoint
+			current, next wgtypes.Key
+		)
+
+		BeforeEach(func() {
+			current, next = newKey().PublicKey(), newKey().PublicKey()
+			tep = &netv1alpha1.TunnelEndpoint{
+				Spec: netv1alpha1.TunnelEndpointSpec{
+					ClusterID:             remoteClusterID,
+					EndpointIP:            "10.0.0.1",
+					RemotePodCIDR:         "10.200.0.0/16",
+					RemoteNATPodCIDR:      liqoconst.DefaultCIDRValue,
+					RemoteExternalCIDR:    "10.201.0.0/16",
+					RemoteNATExternalCIDR: liqoconst.DefaultCIDRValue,
+					BackendConfig:         map[string]string{PublicKey: current.String(), ListeningPort: "5871"},
+				},
+			}
+			_, err := w.ConnectToEndpoint(tep)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should route the traffic through the current key", func() {
+			Expect(client.peers).To(HaveLen(1))
+			Expect(client.peers[current].AllowedIPs).To(HaveLen(2))
+		})
+
+		It("should report the statistics of the peer in use", func() {
+			client.peers[current].LastHandshakeTime = now
+			client.peers[current].ReceiveBytes = 1024
+			client.peers[current].TransmitBytes = 2048
+			stats, err := w.PeerStatistics(remoteClusterID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.LastHandshakeTime).To(Equal(now))
+			Expect(stats.ReceivedBytes).To(BeNumerically("==", 1024))
+			Expect(stats.TransmittedBytes).To(BeNumerically("==", 2048))
+		})
+
+		It("should fail to report the statistics of unknown peers", func() {
+			_, err := w.PeerStatistics("unknown-cluster-id")
+			Expect(err).To(HaveOccurred())
+		})
+
+		When("the tunnel traverses a NAT", func() {
+			var con *netv1alpha1.Connection
+
+			BeforeEach(func() {
+				tep.Spec.BackendConfig[NATTraversal] = "direct"
+				var err error
+				con, err = w.ConnectToEndpoint(tep)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should send the keepalives more frequently", func() {
+				Expect(client.peers[current].PersistentKeepaliveInterval).To(Equal(HolePunchingKeepAliveInterval))
+			})
+
+			It("should report the NAT traversal mode in the peer configuration", func() {
+				Expect(con.PeerConfiguration).To(HaveKeyWithValue(NATTraversal, "direct"))
+			})
+		})
+
+		When("the next key is published", func() {
+			var con *netv1alpha1.Connection
+
+			BeforeEach(func() {
+				tep.Spec.BackendConfig[NextPublicKey] = next.String()
+				var err error
+				con, err = w.ConnectToEndpoint(tep)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should add a second peer without allowed IPs", func() {
+				Expect(client.peers).To(HaveLen(2))
+				Expect(client.peers[current].AllowedIPs).To(HaveLen(2))
+				Expect(client.peers[next].AllowedIPs).To(BeEmpty())
+				Expect(client.peers[next].Endpoint.String()).To(Equal("10.0.0.1:5871"))
+			})
+
+			It("should report the next key in the peer configuration", func() {
+				Expect(con.PeerConfiguration).To(HaveKeyWithValue(NextPublicKey, next.String()))
+			})
+
+			It("should not promote the next key before a handshake is completed", func() {
+				client.peers[current].LastHandshakeTime = now
+				Expect(w.promotePeers()).To(Succeed())
+				Expect(client.peers).To(HaveLen(2))
+				Expect(client.peers[current].AllowedIPs).To(HaveLen(2))
+			})
+
+			When("a handshake is completed with the next key", func() {
+				BeforeEach(func() {
+					client.peers[current].LastHandshakeTime = now
+					client.peers[next].LastHandshakeTime = now.Add(time.Second)
+					Expect(w.promotePeers()).To(Succeed())
+				})
+
+				It("should move the traffic to the next key and remove the old peer", func() {
+					Expect(client.peers).To(HaveLen(1))
+					Expect(client.peers[next].AllowedIPs).To(HaveLen(2))
+					Expect(client.peers[next].PersistentKeepaliveInterval).To(Equal(KeepAliveInterval))
+				})
+
+				It("should keep the promoted peer if the tunnelendpoint is reconciled again", func() {
+					tep.Spec.BackendConfig[ListeningPort] = "5872"
+					_, err := w.ConnectToEndpoint(tep)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.peers).To(HaveLen(1))
+					Expect(client.peers[next].AllowedIPs).To(HaveLen(2))
+				})
+
+				It("should keep the traffic on the new key once the rotation is completed", func() {
+					tep.Spec.BackendConfig[PublicKey] = next.String()
+					delete(tep.Spec.BackendConfig, NextPublicKey)
+					_, err := w.ConnectToEndpoint(tep)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.peers).To(HaveLen(1))
+					Expect(client.peers[next].AllowedIPs).To(HaveLen(2))
+				})
+			})
+
+			When("the rotation is completed without the promotion being detected", func() {
+				BeforeEach(func() {
+					tep.Spec.BackendConfig[PublicKey] = next.String()
+					delete(tep.Spec.BackendConfig, NextPublicKey)
+					_, err := w.ConnectToEndpoint(tep)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("should move the traffic to the new key and remove the old peer", func() {
+					Expect(client.peers).To(HaveLen(1))
+					Expect(client.peers[next].AllowedIPs).To(HaveLen(2))
+				})
+			})
+
+			When("the remote cluster is disconnected", func() {
+				BeforeEach(func() { Expect(w.DisconnectFromEndpoint(tep)).To(Succeed()) })
+
+				It("should remove all the peers", func() { Expect(client.peers).To(BeEmpty()) })
+			})
+		})
+	})
+})
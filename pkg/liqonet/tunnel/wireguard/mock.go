@@ -17,6 +17,8 @@ package wireguard
 import (
 	"fmt"
 	"net"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 const (
@@ -60,3 +62,81 @@ func addressResolverMock(network, address string) (*net.IPAddr, error) {
 		return nil, fmt.Errorf("ip not found")
 	}
 }
+
+// clientMock is a fake wgClient, which keeps the device configuration in memory.
+type clientMock struct {
+	privateKey wgtypes.Key
+	peers      map[wgtypes.Key]*wgtypes.Peer
+}
+
+func newClientMock() *clientMock {
+	return &clientMock{peers: make(map[wgtypes.Key]*wgtypes.Peer)}
+}
+
+func (c *clientMock) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	if cfg.PrivateKey != nil {
+		c.privateKey = *cfg.PrivateKey
+	}
+	if cfg.ReplacePeers {
+		c.peers = make(map[wgtypes.Key]*wgtypes.Peer)
+	}
+	for i := range cfg.Peers {
+		peerCfg := &cfg.Peers[i]
+		if peerCfg.Remove {
+			delete(c.peers, peerCfg.PublicKey)
+			continue
+		}
+		peer, found := c.peers[peerCfg.PublicKey]
+		if !found {
+			peer = &wgtypes.Peer{PublicKey: peerCfg.PublicKey}
+			c.peers[peerCfg.PublicKey] = peer
+		}
+		if peerCfg.Endpoint != nil {
+			peer.Endpoint = peerCfg.Endpoint
+		}
+		if peerCfg.PersistentKeepaliveInterval != nil {
+			peer.PersistentKeepaliveInterval = *peerCfg.PersistentKeepaliveInterval
+		}
+		if peerCfg.ReplaceAllowedIPs {
+			peer.AllowedIPs = nil
+		}
+		// allowed IPs are unique across peers, hence they are removed from the other ones.
+		for _, other := range c.peers {
+			if other == peer {
+				continue
+			}
+			other.AllowedIPs = removeAllowedIPs(other.AllowedIPs, peerCfg.AllowedIPs)
+		}
+		peer.AllowedIPs = append(peer.AllowedIPs, peerCfg.AllowedIPs...)
+	}
+	return nil
+}
+
+func (c *clientMock) Device(name string) (*wgtypes.Device, error) {
+	device := &wgtypes.Device{Name: name, PrivateKey: c.privateKey, PublicKey: c.privateKey.PublicKey()}
+	for _, peer := range c.peers {
+		device.Peers = append(device.Peers, *peer)
+	}
+	return device, nil
+}
+
+func (c *clientMock) Close() error {
+	return nil
+}
+
+func removeAllowedIPs(allowedIPs, toBeRemoved []net.IPNet) []net.IPNet {
+	var result []net.IPNet
+	for _, allowedIP := range allowedIPs {
+		found := false
+		for _, r := range toBeRemoved {
+			if allowedIP.String() == r.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, allowedIP)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/tun"
+	"k8s.io/klog/v2"
+)
+
+// Implementation is the implementation of wireguard used by the driver.
+type Implementation string
+
+const (
+	// ImplementationAuto selects the kernel implementation if available, and the userspace one otherwise.
+	ImplementationAuto Implementation = "auto"
+	// ImplementationKernel selects the kernel implementation of wireguard.
+	ImplementationKernel Implementation = "kernel"
+	// ImplementationUserspace selects the userspace implementation of wireguard, running in-process.
+	ImplementationUserspace Implementation = "userspace"
+)
+
+// implementation is the implementation of wireguard used by the driver.
+var implementation = ImplementationAuto
+
+// SetImplementation configures the implementation of wireguard used by the driver.
+// It must be called before the creation of the driver.
+func SetImplementation(impl Implementation) error {
+	switch impl {
+	case ImplementationAuto, ImplementationKernel, ImplementationUserspace:
+		implementation = impl
+		return nil
+	default:
+		return fmt.Errorf("unknown wireguard implementation %q, the accepted values are: %q, %q, %q",
+			impl, ImplementationAuto, ImplementationKernel, ImplementationUserspace)
+	}
+}
+
+// userspaceDevice is a wireguard device implemented in userspace by wireguard-go, on top of a TUN interface.
+// The device is configured through the UAPI socket, which is transparently used by the wgctrl client.
+type userspaceDevice struct {
+	device *device.Device
+	uapi   net.Listener
+}
+
+// newUserspaceDevice creates a new userspace wireguard device, along with the corresponding TUN interface.
+func newUserspaceDevice(name string, mtu int) (*userspaceDevice, error) {
+	tunDevice, err := tun.CreateTUN(name, mtu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN device '%s': %w", name, err)
+	}
+
+	fileUAPI, err := ipc.UAPIOpen(name)
+	if err != nil {
+		if e := tunDevice.Close(); e != nil {
+			klog.Errorf("failed to close TUN device '%s': %v", name, e)
+		}
+		return nil, fmt.Errorf("failed to open the UAPI socket for device '%s': %w", name, err)
+	}
+
+	// The device takes ownership of the TUN interface, and closes it when closed.
+	dev := device.NewDevice(tunDevice, &device.Logger{
+		Debug: log.New(ioutil.Discard, "", 0),
+		Info:  log.New(logWriter(klog.V(4).Info), "", 0),
+		Error: log.New(logWriter(klog.Error), "", 0),
+	})
+
+	uapi, err := ipc.UAPIListen(name, fileUAPI)
+	if err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("failed to listen on the UAPI socket for device '%s': %w", name, err)
+	}
+
+	go func() {
+		for {
+			conn, err := uapi.Accept()
+			if err != nil {
+				klog.V(4).Infof("stopped accepting UAPI connections for device '%s': %v", name, err)
+				return
+			}
+			go dev.IpcHandle(conn)
+		}
+	}()
+
+	klog.Infof("started userspace %s device named %s", DriverName, name)
+	return &userspaceDevice{device: dev, uapi: uapi}, nil
+}
+
+// Close stops the userspace device, removing also the corresponding TUN interface.
+func (d *userspaceDevice) Close() error {
+	err := d.uapi.Close()
+	d.device.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close the UAPI socket: %w", err)
+	}
+	return nil
+}
+
+// logWriter adapts a klog function to the io.Writer interface, to be used by the wireguard-go loggers.
+type logWriter func(args ...interface{})
+
+// Write implements the io.Writer interface.
+func (w logWriter) Write(p []byte) (int, error) {
+	w(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
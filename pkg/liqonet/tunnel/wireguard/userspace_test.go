@@ -0,0 +1,55 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Userspace", func() {
+	AfterEach(func() {
+		implementation = ImplementationAuto
+	})
+
+	DescribeTable("setting the wireguard implementation",
+		func(impl Implementation, valid bool) {
+			err := SetImplementation(impl)
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(implementation).To(Equal(impl))
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(implementation).To(Equal(ImplementationAuto))
+			}
+		},
+		Entry("auto", ImplementationAuto, true),
+		Entry("kernel", ImplementationKernel, true),
+		Entry("userspace", ImplementationUserspace, true),
+		Entry("unknown", Implementation("boringtun"), false),
+	)
+
+	Describe("the log writer", func() {
+		It("should forward the messages without the trailing newline", func() {
+			var messages []interface{}
+			writer := logWriter(func(args ...interface{}) { messages = append(messages, args...) })
+			n, err := writer.Write([]byte("message\n"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(len("message\n")))
+			Expect(messages).To(ConsistOf("message"))
+		})
+	})
+})
@@ -39,6 +39,7 @@ var (
 )
 
 // MapIPToNetwork creates a new IP address obtained by means of the old IP address and the new network.
+// Both IPv4 and IPv6 addresses are supported, as long as the address and the network belong to the same family.
 func MapIPToNetwork(newNetwork, oldIP string) (newIP string, err error) {
 	if newNetwork == consts.DefaultCIDRValue {
 		return oldIP, nil
@@ -50,16 +51,21 @@ func MapIPToNetwork(newNetwork, oldIP string) (newIP string, err error) {
 	}
 	// Get mask
 	mask := network.Mask
-	// Get slice of bytes for newNetwork
-	// Type net.IP has underlying type []byte
-	parsedNewIP := ip.To4()
 	// Get oldIP as slice of bytes
 	parsedOldIP := net.ParseIP(oldIP)
 	if parsedOldIP == nil {
 		return "", fmt.Errorf("cannot parse oldIP")
 	}
-	parsedOldIP = parsedOldIP.To4()
-	// Substitute the last 32-mask bits of newNetwork with bits taken by the old ip
+	if IsIPv6(oldIP) != IsIPv6Network(newNetwork) {
+		return "", fmt.Errorf("oldIP %s and network %s belong to different address families", oldIP, newNetwork)
+	}
+	// Get slice of bytes for newNetwork and oldIP, with the length of the mask
+	// Type net.IP has underlying type []byte
+	parsedNewIP := ip.To16()
+	if len(mask) == net.IPv4len {
+		parsedNewIP, parsedOldIP = ip.To4(), parsedOldIP.To4()
+	}
+	// Substitute the last bits of newNetwork (i.e. the host ones) with bits taken by the old ip
 	for i := 0; i < len(mask); i++ {
 		// Step 1: NOT(mask[i]) = mask[i] ^ 0xff. They are the 'host' bits
 		// Step 2: BITWISE AND between the host bits and parsedOldIP[i] zeroes the network bits in parsedOldIP[i]
@@ -70,6 +76,18 @@ func MapIPToNetwork(newNetwork, oldIP string) (newIP string, err error) {
 	return
 }
 
+// IsIPv6 returns whether the given IP address is an IPv6 address.
+func IsIPv6(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && addr.To4() == nil
+}
+
+// IsIPv6Network returns whether the given network, in CIDR notation, is an IPv6 network.
+func IsIPv6Network(network string) bool {
+	_, n, err := net.ParseCIDR(network)
+	return err == nil && len(n.IP) == net.IPv6len
+}
+
 func GetPodIP() (net.IP, error) {
 	ipAddress, isSet := os.LookupEnv("POD_IP")
 	if !isSet {
@@ -81,6 +99,26 @@ func GetPodIP() (net.IP, error) {
 	return net.ParseIP(ipAddress), nil
 }
 
+// GetPodIPv6 returns the IPv6 address of the pod, taken from the POD_IPS environment variable
+// holding the comma separated list of the pod addresses. It returns nil if the pod has no IPv6 address.
+func GetPodIPv6() net.IP {
+	for _, address := range strings.Split(os.Getenv("POD_IPS"), ",") {
+		if ip := net.ParseIP(strings.TrimSpace(address)); ip != nil && ip.To4() == nil {
+			return ip
+		}
+	}
+	return nil
+}
+
+// GetPodName gets the name of the pod passed as an environment variable.
+func GetPodName() (string, error) {
+	name, isSet := os.LookupEnv("POD_NAME")
+	if !isSet {
+		return "", errdefs.NotFound("the POD_NAME environment variable is not set as an environment variable")
+	}
+	return name, nil
+}
+
 // GetPodNamespace gets the namespace of the pod passed as an environment variable.
 func GetPodNamespace() (string, error) {
 	namespace, isSet := os.LookupEnv("POD_NAMESPACE")
@@ -112,7 +150,7 @@ func SetMask(network string, mask uint8) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	newMask := net.CIDRMask(int(mask), 32)
+	newMask := net.CIDRMask(int(mask), len(n.IP)*8)
 	n.Mask = newMask
 	return n.String(), nil
 }
@@ -157,6 +195,107 @@ func GetExternalCIDRS(tep *netv1alpha1.TunnelEndpoint) (localExternalCIDR, remot
 	return
 }
 
+// GetRemoteCIDRs returns the networks to be reached through the tunnel, i.e. the remote PodCIDR and ExternalCIDR,
+// followed by the networks of the clusters reached through the remote one and, in case of dual-stack clusters,
+// by the IPv6 counterparts of the remote networks.
+func GetRemoteCIDRs(tep *netv1alpha1.TunnelEndpoint) []string {
+	_, remotePodCIDR := GetPodCIDRS(tep)
+	_, remoteExternalCIDR := GetExternalCIDRS(tep)
+	cidrs := []string{remotePodCIDR, remoteExternalCIDR}
+	cidrs = append(cidrs, GetTransitCIDRs(tep)...)
+	if tepv6 := IPv6TunnelEndpoint(tep); tepv6 != nil {
+		cidrs = append(cidrs, GetRemoteCIDRs(tepv6)...)
+	}
+	return cidrs
+}
+
+// IPv6TunnelEndpoint returns a copy of the given TunnelEndpoint whose CIDR fields are replaced by their IPv6
+// counterparts, so that the IPv6 configuration can be handled by the same functions managing the IPv4 one.
+// It returns nil if IPv6 has not been configured for the remote cluster.
+func IPv6TunnelEndpoint(tep *netv1alpha1.TunnelEndpoint) *netv1alpha1.TunnelEndpoint {
+	if tep.Spec.RemotePodCIDRv6 == "" {
+		return nil
+	}
+	tepv6 := tep.DeepCopy()
+	tepv6.Spec.LocalPodCIDR = tep.Spec.LocalPodCIDRv6
+	tepv6.Spec.LocalNATPodCIDR = defaultIfEmpty(tep.Spec.LocalNATPodCIDRv6)
+	tepv6.Spec.LocalExternalCIDR = tep.Spec.LocalExternalCIDRv6
+	tepv6.Spec.LocalNATExternalCIDR = defaultIfEmpty(tep.Spec.LocalNATExternalCIDRv6)
+	tepv6.Spec.RemotePodCIDR = tep.Spec.RemotePodCIDRv6
+	tepv6.Spec.RemoteNATPodCIDR = defaultIfEmpty(tep.Spec.RemoteNATPodCIDRv6)
+	tepv6.Spec.RemoteExternalCIDR = tep.Spec.RemoteExternalCIDRv6
+	tepv6.Spec.RemoteNATExternalCIDR = defaultIfEmpty(tep.Spec.RemoteNATExternalCIDRv6)
+	tepv6.Spec.LocalPodCIDRv6, tepv6.Spec.LocalNATPodCIDRv6 = "", ""
+	tepv6.Spec.LocalExternalCIDRv6, tepv6.Spec.LocalNATExternalCIDRv6 = "", ""
+	tepv6.Spec.RemotePodCIDRv6, tepv6.Spec.RemoteNATPodCIDRv6 = "", ""
+	tepv6.Spec.RemoteExternalCIDRv6, tepv6.Spec.RemoteNATExternalCIDRv6 = "", ""
+	tepv6.Spec.RemoteTransitNetworks, tepv6.Spec.LocalTransitNetworks = nil, nil
+	return tepv6
+}
+
+// TransitNetworkMapping associates a network of a cluster reached through the local one with the network
+// it has been remapped on by the remote cluster.
+type TransitNetworkMapping struct {
+	Original string
+	Remapped string
+}
+
+// GetRemappedLocalTransitNetworks returns the networks of the clusters reached by the remote cluster through
+// the local one, which have been remapped by the remote cluster.
+func GetRemappedLocalTransitNetworks(tep *netv1alpha1.TunnelEndpoint) []TransitNetworkMapping {
+	var mappings []TransitNetworkMapping
+	for i := range tep.Spec.LocalTransitNetworks {
+		transit := &tep.Spec.LocalTransitNetworks[i]
+		if defaultIfEmpty(transit.NATPodCIDR) != consts.DefaultCIDRValue {
+			mappings = append(mappings, TransitNetworkMapping{Original: transit.PodCIDR, Remapped: transit.NATPodCIDR})
+		}
+		if defaultIfEmpty(transit.NATExternalCIDR) != consts.DefaultCIDRValue {
+			mappings = append(mappings, TransitNetworkMapping{Original: transit.ExternalCIDR, Remapped: transit.NATExternalCIDR})
+		}
+	}
+	return mappings
+}
+
+// GetTransitCIDRs returns the networks of the clusters reached through the remote one, as seen by the local cluster.
+func GetTransitCIDRs(tep *netv1alpha1.TunnelEndpoint) []string {
+	var cidrs []string
+	for _, transit := range TransitTunnelEndpoints(tep) {
+		_, remotePodCIDR := GetPodCIDRS(transit)
+		_, remoteExternalCIDR := GetExternalCIDRS(transit)
+		cidrs = append(cidrs, remotePodCIDR, remoteExternalCIDR)
+	}
+	return cidrs
+}
+
+// TransitTunnelEndpoints returns, for each cluster reached through the remote one, a copy of the given TunnelEndpoint
+// whose remote CIDR fields are replaced by the networks of that cluster, so that the traffic towards it can be handled
+// by the same functions managing the one towards the remote cluster. Transit networks are IPv4 only.
+func TransitTunnelEndpoints(tep *netv1alpha1.TunnelEndpoint) []*netv1alpha1.TunnelEndpoint {
+	teps := make([]*netv1alpha1.TunnelEndpoint, 0, len(tep.Spec.RemoteTransitNetworks))
+	for i := range tep.Spec.RemoteTransitNetworks {
+		transit := &tep.Spec.RemoteTransitNetworks[i]
+		transitTep := tep.DeepCopy()
+		transitTep.Spec.RemotePodCIDR = transit.PodCIDR
+		transitTep.Spec.RemoteNATPodCIDR = defaultIfEmpty(transit.NATPodCIDR)
+		transitTep.Spec.RemoteExternalCIDR = transit.ExternalCIDR
+		transitTep.Spec.RemoteNATExternalCIDR = defaultIfEmpty(transit.NATExternalCIDR)
+		transitTep.Spec.LocalPodCIDRv6, transitTep.Spec.LocalNATPodCIDRv6 = "", ""
+		transitTep.Spec.LocalExternalCIDRv6, transitTep.Spec.LocalNATExternalCIDRv6 = "", ""
+		transitTep.Spec.RemotePodCIDRv6, transitTep.Spec.RemoteNATPodCIDRv6 = "", ""
+		transitTep.Spec.RemoteExternalCIDRv6, transitTep.Spec.RemoteNATExternalCIDRv6 = "", ""
+		transitTep.Spec.RemoteTransitNetworks, transitTep.Spec.LocalTransitNetworks = nil, nil
+		teps = append(teps, transitTep)
+	}
+	return teps
+}
+
+func defaultIfEmpty(cidr string) string {
+	if cidr == "" {
+		return consts.DefaultCIDRValue
+	}
+	return cidr
+}
+
 // IsValidCIDR returns an error if the received CIDR is invalid.
 func IsValidCIDR(cidr string) error {
 	_, _, err := net.ParseCIDR(cidr)
@@ -233,6 +372,61 @@ func CheckTep(tep *netv1alpha1.TunnelEndpoint) error {
 		}
 	}
 
+	// The networks of the clusters reached through a transit hop, if any.
+	for _, transits := range [][]netv1alpha1.TransitEndpoint{tep.Spec.RemoteTransitNetworks, tep.Spec.LocalTransitNetworks} {
+		for i := range transits {
+			if err := checkTransitEndpoint(&transits[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The IPv6 configuration, if any, is validated through the same checks, once moved to the IPv4 fields.
+	if tepv6 := IPv6TunnelEndpoint(tep); tepv6 != nil {
+		if !IsIPv6Network(tepv6.Spec.RemotePodCIDR) || !IsIPv6Network(tepv6.Spec.LocalPodCIDR) {
+			return &liqoneterrors.WrongParameter{
+				Parameter: consts.PodCIDRv6,
+				Reason:    liqoneterrors.ValidCIDR,
+			}
+		}
+		if !IsIPv6Network(tepv6.Spec.RemoteExternalCIDR) || !IsIPv6Network(tepv6.Spec.LocalExternalCIDR) {
+			return &liqoneterrors.WrongParameter{
+				Parameter: consts.ExternalCIDRv6,
+				Reason:    liqoneterrors.ValidCIDR,
+			}
+		}
+		return CheckTep(tepv6)
+	}
+
+	return nil
+}
+
+// checkTransitEndpoint validates the networks of a cluster reached through a transit hop.
+func checkTransitEndpoint(transit *netv1alpha1.TransitEndpoint) error {
+	if err := IsValidCIDR(transit.PodCIDR); err != nil || IsIPv6Network(transit.PodCIDR) {
+		return &liqoneterrors.WrongParameter{
+			Parameter: consts.PodCIDR,
+			Reason:    liqoneterrors.ValidCIDR,
+		}
+	}
+	if err := IsValidCIDR(transit.ExternalCIDR); err != nil || IsIPv6Network(transit.ExternalCIDR) {
+		return &liqoneterrors.WrongParameter{
+			Parameter: consts.ExternalCIDR,
+			Reason:    liqoneterrors.ValidCIDR,
+		}
+	}
+	if err := IsValidCIDR(transit.NATPodCIDR); defaultIfEmpty(transit.NATPodCIDR) != consts.DefaultCIDRValue && err != nil {
+		return &liqoneterrors.WrongParameter{
+			Parameter: consts.RemoteNATPodCIDR,
+			Reason:    liqoneterrors.ValidCIDR,
+		}
+	}
+	if err := IsValidCIDR(transit.NATExternalCIDR); defaultIfEmpty(transit.NATExternalCIDR) != consts.DefaultCIDRValue && err != nil {
+		return &liqoneterrors.WrongParameter{
+			Parameter: consts.RemoteNATExternalCIDR,
+			Reason:    liqoneterrors.ValidCIDR,
+		}
+	}
 	return nil
 }
 
@@ -243,13 +437,26 @@ func GetOverlayIP(ip string) string {
 	addr := net.ParseIP(ip)
 	// If the ip is malformed we prevent a panic, the subsequent calls
 	// that use the returned value will return an error.
-	if addr == nil {
+	if addr == nil || addr.To4() == nil {
 		return ""
 	}
 	tokens := strings.Split(ip, ".")
 	return strings.Join([]string{consts.OverlayNetworkPrefix, tokens[1], tokens[2], tokens[3]}, ".")
 }
 
+// GetOverlayIPv6 given an IPv4 address it is mapped in to the IPv6 overlay network,
+// described by consts.OverlayNetworkPrefixV6. It uses the overlay prefix and the
+// IPv4 overlay address as the last four bytes.
+func GetOverlayIPv6(ip string) string {
+	overlayIP := net.ParseIP(GetOverlayIP(ip)).To4()
+	if overlayIP == nil {
+		return ""
+	}
+	addr := net.ParseIP(consts.OverlayNetworkPrefixV6)
+	copy(addr[net.IPv6len-net.IPv4len:], overlayIP)
+	return addr.String()
+}
+
 // AddAnnotationToObj for a given object it adds the annotation with the given key and value.
 // It return a bool which is true when the annotations has been added or false if the
 // annotation is already present.
@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/pkg/liqonet/utils"
 )
 
@@ -73,6 +74,20 @@ var _ = Describe("Liqonet", func() {
 		Entry("Mapping 10.2.128.128 to 10.0.126.0/25", "10.0.126.0/25", "10.2.128.128", "10.0.126.0", ""),
 		Entry("Using an invalid newPodCidr", "10.0..0/25", "10.2.128.128", "", "invalid CIDR address: 10.0..0/25"),
 		Entry("Using an invalid oldIp", "10.0.0.0/25", "10.2...128", "", "cannot parse oldIP"),
+		Entry("Mapping fd00:1::5 to fd00:2::/64", "fd00:2::/64", "fd00:1::5", "fd00:2::5", ""),
+		Entry("Mapping fd00:1::1:5 to fd00:2:0:1::/112", "fd00:2:0:1::/112", "fd00:1::1:5", "fd00:2:0:1::5", ""),
+		Entry("Mapping an IPv6 address to an IPv4 network", "10.0.0.0/24", "fd00:1::5", "",
+			"oldIP fd00:1::5 and network 10.0.0.0/24 belong to different address families"),
+		Entry("Mapping an IPv4 address to an IPv6 network", "fd00:2::/64", "10.2.1.3", "",
+			"oldIP 10.2.1.3 and network fd00:2::/64 belong to different address families"),
+	)
+
+	DescribeTable("SetMask",
+		func(network string, mask uint8, expected string) {
+			Expect(utils.SetMask(network, mask)).To(Equal(expected))
+		},
+		Entry("Setting the mask of an IPv4 network", "10.0.0.0/8", uint8(9), "10.0.0.0/9"),
+		Entry("Setting the mask of an IPv6 network", "fd00::/8", uint8(9), "fd00::/9"),
 	)
 
 	DescribeTable("GetFirstIP",
@@ -102,7 +117,133 @@ var _ = Describe("Liqonet", func() {
 		Context("when input parameter is not correct", func() {
 			It("should return an empty string", func() {
 				Expect(utils.GetOverlayIP("10.200.")).Should(Equal(""))
+				Expect(utils.GetOverlayIP("fd00::1")).Should(Equal(""))
+			})
+		})
+	})
+
+	Describe("testing getOverlayIPv6 function", func() {
+		Context("when input parameter is correct", func() {
+			It("should return a valid ip", func() {
+				Expect(utils.GetOverlayIPv6("10.200.1.1")).Should(Equal("fd24::f0c8:101"))
+			})
+		})
+
+		Context("when input parameter is not correct", func() {
+			It("should return an empty string", func() {
+				Expect(utils.GetOverlayIPv6("10.200.")).Should(Equal(""))
+			})
+		})
+	})
+
+	Describe("testing IPv6TunnelEndpoint function", func() {
+		var tep *netv1alpha1.TunnelEndpoint
+
+		BeforeEach(func() {
+			tep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterID:             "cluster-id",
+				LocalPodCIDR:          "10.0.0.0/16",
+				LocalNATPodCIDR:       "None",
+				LocalExternalCIDR:     "10.1.0.0/16",
+				LocalNATExternalCIDR:  "None",
+				RemotePodCIDR:         "10.2.0.0/16",
+				RemoteNATPodCIDR:      "None",
+				RemoteExternalCIDR:    "10.3.0.0/16",
+				RemoteNATExternalCIDR: "None",
+			}}
+		})
+
+		Context("when IPv6 is not configured", func() {
+			It("should return nil", func() {
+				Expect(utils.IPv6TunnelEndpoint(tep)).To(BeNil())
+				Expect(utils.CheckTep(tep)).To(Succeed())
+			})
+		})
+
+		Context("when IPv6 is configured", func() {
+			BeforeEach(func() {
+				tep.Spec.LocalPodCIDRv6 = "fd00:0::/64"
+				tep.Spec.LocalExternalCIDRv6 = "fd00:1::/64"
+				tep.Spec.RemotePodCIDRv6 = "fd00:2::/64"
+				tep.Spec.RemoteNATPodCIDRv6 = "fd00:4::/64"
+				tep.Spec.RemoteExternalCIDRv6 = "fd00:3::/64"
 			})
+
+			It("should return a copy with the IPv6 CIDRs", func() {
+				tepv6 := utils.IPv6TunnelEndpoint(tep)
+				Expect(tepv6).ToNot(BeNil())
+				Expect(tepv6.Spec.LocalPodCIDR).To(Equal("fd00:0::/64"))
+				Expect(tepv6.Spec.LocalNATPodCIDR).To(Equal("None"))
+				Expect(tepv6.Spec.LocalExternalCIDR).To(Equal("fd00:1::/64"))
+				Expect(tepv6.Spec.LocalNATExternalCIDR).To(Equal("None"))
+				Expect(tepv6.Spec.RemotePodCIDR).To(Equal("fd00:2::/64"))
+				Expect(tepv6.Spec.RemoteNATPodCIDR).To(Equal("fd00:4::/64"))
+				Expect(tepv6.Spec.RemoteExternalCIDR).To(Equal("fd00:3::/64"))
+				Expect(tepv6.Spec.RemoteNATExternalCIDR).To(Equal("None"))
+				Expect(utils.IPv6TunnelEndpoint(tepv6)).To(BeNil())
+				Expect(tep.Spec.LocalPodCIDR).To(Equal("10.0.0.0/16"))
+			})
+
+			It("should validate the IPv6 CIDRs", func() {
+				Expect(utils.CheckTep(tep)).To(Succeed())
+				tep.Spec.RemoteExternalCIDRv6 = "10.4.0.0/16"
+				Expect(utils.CheckTep(tep)).ToNot(Succeed())
+			})
+		})
+	})
+
+	Describe("testing TransitTunnelEndpoints function", func() {
+		var tep *netv1alpha1.TunnelEndpoint
+
+		BeforeEach(func() {
+			tep = &netv1alpha1.TunnelEndpoint{Spec: netv1alpha1.TunnelEndpointSpec{
+				ClusterID:             "cluster-id",
+				LocalPodCIDR:          "10.0.0.0/16",
+				LocalNATPodCIDR:       "None",
+				LocalExternalCIDR:     "10.1.0.0/16",
+				LocalNATExternalCIDR:  "None",
+				RemotePodCIDR:         "10.2.0.0/16",
+				RemoteNATPodCIDR:      "None",
+				RemoteExternalCIDR:    "10.3.0.0/16",
+				RemoteNATExternalCIDR: "None",
+				RemotePodCIDRv6:       "fd00:2::/64",
+				RemoteExternalCIDRv6:  "fd00:3::/64",
+				LocalPodCIDRv6:        "fd00:0::/64",
+				LocalExternalCIDRv6:   "fd00:1::/64",
+				RemoteTransitNetworks: []netv1alpha1.TransitEndpoint{{
+					ClusterID:       "transit-id",
+					PodCIDR:         "10.4.0.0/16",
+					NATPodCIDR:      "10.6.0.0/16",
+					ExternalCIDR:    "10.5.0.0/16",
+					NATExternalCIDR: "None",
+				}},
+			}}
+		})
+
+		It("should return a copy with the transit CIDRs", func() {
+			transits := utils.TransitTunnelEndpoints(tep)
+			Expect(transits).To(HaveLen(1))
+			Expect(transits[0].Spec.ClusterID).To(Equal("cluster-id"))
+			Expect(transits[0].Spec.LocalPodCIDR).To(Equal("10.0.0.0/16"))
+			Expect(transits[0].Spec.RemotePodCIDR).To(Equal("10.4.0.0/16"))
+			Expect(transits[0].Spec.RemoteNATPodCIDR).To(Equal("10.6.0.0/16"))
+			Expect(transits[0].Spec.RemoteExternalCIDR).To(Equal("10.5.0.0/16"))
+			Expect(transits[0].Spec.RemoteNATExternalCIDR).To(Equal("None"))
+			Expect(utils.IPv6TunnelEndpoint(transits[0])).To(BeNil())
+			Expect(utils.TransitTunnelEndpoints(transits[0])).To(BeEmpty())
+			Expect(tep.Spec.RemotePodCIDR).To(Equal("10.2.0.0/16"))
+		})
+
+		It("should include the transit CIDRs in the remote ones", func() {
+			Expect(utils.GetTransitCIDRs(tep)).To(Equal([]string{"10.6.0.0/16", "10.5.0.0/16"}))
+			Expect(utils.GetRemoteCIDRs(tep)).To(Equal([]string{
+				"10.2.0.0/16", "10.3.0.0/16", "10.6.0.0/16", "10.5.0.0/16", "fd00:2::/64", "fd00:3::/64"}))
+		})
+
+		It("should validate the transit CIDRs", func() {
+			Expect(utils.CheckTep(tep)).To(Succeed())
+			tep.Spec.RemoteTransitNetworks[0].PodCIDR = "fd00:4::/64"
+			Expect(utils.CheckTep(tep)).ToNot(Succeed())
 		})
 	})
 
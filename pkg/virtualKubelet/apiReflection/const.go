@@ -17,21 +17,36 @@ package apiReflection
 const (
 	Configmaps = iota
 	EndpointSlices
+	Events
+	Ingresses
+	Jobs
+	NetworkPolicies
+	PersistentVolumeClaims
 	Pods
 	ReplicaSets
 	Services
 	Secrets
+	ServiceAccounts
 )
 
 type ApiType int
 
+// NotReflectAnnotation is the annotation preventing a resource from being reflected to the remote cluster, when set to "true".
+const NotReflectAnnotation = "liqo.io/not-reflect"
+
 var ApiNames = map[ApiType]string{
-	Configmaps:     "configmaps",
-	EndpointSlices: "endpointslices",
-	Pods:           "pods",
-	ReplicaSets:    "replicasets",
-	Services:       "services",
-	Secrets:        "secrets",
+	Configmaps:             "configmaps",
+	EndpointSlices:         "endpointslices",
+	Events:                 "events",
+	Ingresses:              "ingresses",
+	Jobs:                   "jobs",
+	NetworkPolicies:        "networkpolicies",
+	PersistentVolumeClaims: "persistentvolumeclaims",
+	Pods:                   "pods",
+	ReplicaSets:            "replicasets",
+	Services:               "services",
+	Secrets:                "secrets",
+	ServiceAccounts:        "serviceaccounts",
 }
 
 type ApiEvent struct {
@@ -18,13 +18,30 @@ import (
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
 )
 
 var ReflectorBuilder = map[apimgmt.ApiType]func(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.IncomingAPIReflector{
+	apimgmt.Events:      eventsReflectorBuilder,
+	apimgmt.Jobs:        jobsReflectorBuilder,
 	apimgmt.Pods:        podsReflectorBuilder,
 	apimgmt.ReplicaSets: replicaSetsReflectorBuilder,
 }
 
+func eventsReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.IncomingAPIReflector {
+	return &EventsIncomingReflector{
+		APIReflector:    reflector,
+		VirtualNodeName: opts[types.VirtualNodeName],
+		HomePodGetter:   GetHomePodFunc,
+	}
+}
+
+func jobsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.IncomingAPIReflector {
+	return &JobsIncomingReflector{
+		APIReflector: reflector,
+	}
+}
+
 func podsReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.IncomingAPIReflector {
 	return &PodsIncomingReflector{
 		APIReflector:  reflector,
@@ -0,0 +1,197 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package incoming
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+)
+
+// EventsIncomingReflector is in charge of reflecting the events involving the foreign pods in the home cluster,
+// re-emitting them against the corresponding home pods, so that they can be inspected (e.g. through kubectl describe)
+// without accessing the foreign cluster.
+type EventsIncomingReflector struct {
+	ri.APIReflector
+
+	VirtualNodeName options.ReadOnlyOption
+	HomePodGetter   HomePodGetter
+}
+
+// SetSpecializedPreProcessingHandlers allows to set the pre-routine handlers for the EventsIncomingReflector.
+func (r *EventsIncomingReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete,
+	})
+}
+
+// HandleEvent creates (or updates, in case it already exists) the event in the home cluster.
+func (r *EventsIncomingReflector) HandleEvent(obj interface{}) {
+	event, ok := obj.(watch.Event)
+	if !ok {
+		klog.Error("cannot cast object to event")
+		return
+	}
+
+	homeEvent, ok := event.Object.(*corev1.Event)
+	if !ok {
+		klog.Error("INCOMING REFLECTION: wrong type, cannot cast object to event")
+		return
+	}
+
+	klog.V(3).Infof("INCOMING REFLECTION: received %v for event %v/%v", event.Type, homeEvent.Namespace, homeEvent.Name)
+
+	client := r.GetHomeClient().CoreV1().Events(homeEvent.Namespace)
+	_, err := client.Create(context.TODO(), homeEvent, metav1.CreateOptions{})
+	if kerrors.IsAlreadyExists(err) {
+		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			existing, newErr := client.Get(context.TODO(), homeEvent.Name, metav1.GetOptions{})
+			if newErr != nil {
+				return newErr
+			}
+			mergeEvent(homeEvent, existing)
+			_, newErr = client.Update(context.TODO(), existing, metav1.UpdateOptions{})
+			return newErr
+		})
+	}
+
+	if err != nil {
+		klog.Errorf("INCOMING REFLECTION: error while reflecting event %v/%v - ERR: %v", homeEvent.Namespace, homeEvent.Name, err)
+		return
+	}
+	klog.V(3).Infof("INCOMING REFLECTION: event %v/%v correctly reflected", homeEvent.Namespace, homeEvent.Name)
+}
+
+// PreAdd is the pre-routine called in case of event creation in the foreign cluster. It returns the corresponding
+// home event, or nil in case the event does not involve a reflected pod.
+func (r *EventsIncomingReflector) PreAdd(obj interface{}) (interface{}, watch.EventType) {
+	homeEvent := r.forgeHomeEvent(obj.(*corev1.Event))
+	if homeEvent == nil {
+		return nil, watch.Added
+	}
+
+	return homeEvent, watch.Added
+}
+
+// PreUpdate is the pre-routine called in case of event update in the foreign cluster (e.g. when the same event is
+// emitted again). It returns the corresponding home event, unless the home one is already up-to-date.
+func (r *EventsIncomingReflector) PreUpdate(newObj, _ interface{}) (interface{}, watch.EventType) {
+	homeEvent := r.forgeHomeEvent(newObj.(*corev1.Event))
+	if homeEvent == nil {
+		return nil, watch.Modified
+	}
+
+	existingObj, err := r.GetCacheManager().GetHomeNamespacedObject(apimgmt.Events, homeEvent.Namespace, homeEvent.Name)
+	if err == nil {
+		existing := existingObj.(*corev1.Event)
+		if existing.Count == homeEvent.Count && existing.Message == homeEvent.Message && existing.LastTimestamp.Equal(&homeEvent.LastTimestamp) {
+			klog.V(4).Infof("INCOMING REFLECTION: event %v/%v already up-to-date", homeEvent.Namespace, homeEvent.Name)
+			return nil, watch.Modified
+		}
+	}
+
+	return homeEvent, watch.Modified
+}
+
+// PreDelete returns always nil, since the reflected events are garbage collected by the home cluster when expired.
+func (r *EventsIncomingReflector) PreDelete(_ interface{}) (interface{}, watch.EventType) {
+	return nil, watch.Deleted
+}
+
+// CleanupNamespace does nothing, since the reflected events are garbage collected by the home cluster when expired.
+func (r *EventsIncomingReflector) CleanupNamespace(_ string) {}
+
+// forgeHomeEvent returns the home event corresponding to the given foreign one, with the home pod as involved object
+// and the virtual node as source. Nil is returned if the event does not involve a pod reflected from the home cluster.
+func (r *EventsIncomingReflector) forgeHomeEvent(foreignEvent *corev1.Event) *corev1.Event {
+	if foreignEvent.InvolvedObject.Kind != "Pod" || foreignEvent.InvolvedObject.Name == "" {
+		return nil
+	}
+
+	foreignPodObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.Pods, foreignEvent.Namespace, foreignEvent.InvolvedObject.Name)
+	if err != nil {
+		klog.V(4).Infof("INCOMING REFLECTION: event %v/%v ignored, since the involved pod has not been found: %v",
+			foreignEvent.Namespace, foreignEvent.Name, err)
+		return nil
+	}
+	foreignPod := foreignPodObj.(*corev1.Pod)
+
+	homePod, err := r.HomePodGetter(r, foreignPod)
+	if err != nil {
+		klog.V(4).Infof("INCOMING REFLECTION: event %v/%v ignored, since the involved pod is not reflected: %v",
+			foreignEvent.Namespace, foreignEvent.Name, err)
+		return nil
+	}
+
+	nodeName := r.VirtualNodeName.Value().ToString()
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      homeEventName(foreignEvent, foreignPod.Name, homePod.Name),
+			Namespace: homePod.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:            "Pod",
+			APIVersion:      "v1",
+			Namespace:       homePod.Namespace,
+			Name:            homePod.Name,
+			UID:             homePod.UID,
+			ResourceVersion: homePod.ResourceVersion,
+			FieldPath:       foreignEvent.InvolvedObject.FieldPath,
+		},
+		Reason:              foreignEvent.Reason,
+		Message:             foreignEvent.Message,
+		Source:              corev1.EventSource{Component: foreignEvent.Source.Component, Host: nodeName},
+		FirstTimestamp:      foreignEvent.FirstTimestamp,
+		LastTimestamp:       foreignEvent.LastTimestamp,
+		Count:               foreignEvent.Count,
+		Type:                foreignEvent.Type,
+		EventTime:           foreignEvent.EventTime,
+		Series:              foreignEvent.Series.DeepCopy(),
+		Action:              foreignEvent.Action,
+		ReportingController: foreignEvent.ReportingController,
+		ReportingInstance:   nodeName,
+	}
+}
+
+// homeEventName returns the name of the home event, obtained replacing the name of the foreign pod with the one of
+// the home pod, to preserve the usual naming convention (i.e. <pod-name>.<unique-suffix>).
+func homeEventName(foreignEvent *corev1.Event, foreignPodName, homePodName string) string {
+	if suffix := strings.TrimPrefix(foreignEvent.Name, foreignPodName); suffix != foreignEvent.Name {
+		return homePodName + suffix
+	}
+	return fmt.Sprintf("%s.%s", homePodName, foreignEvent.UID)
+}
+
+// mergeEvent updates the mutable fields of the existing event with the ones of the desired event.
+func mergeEvent(desired, existing *corev1.Event) {
+	existing.Message = desired.Message
+	existing.Count = desired.Count
+	existing.LastTimestamp = desired.LastTimestamp
+	existing.Series = desired.Series
+	existing.InvolvedObject = desired.InvolvedObject
+}
@@ -0,0 +1,174 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package incoming_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/incoming"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+var _ = Describe("Events incoming reflector", func() {
+	const (
+		homeNamespace    = "homeNamespace"
+		foreignNamespace = "homeNamespace-natted"
+		homePodName      = "home-pod"
+		foreignPodName   = "home-pod-abcde"
+		virtualNodeName  = "liqo-foreign-cluster"
+	)
+
+	var (
+		cacheManager          *storageTest.MockManager
+		namespaceNattingTable *test.MockNamespaceMapper
+		homeClient            *fake.Clientset
+		reflector             *incoming.EventsIncomingReflector
+
+		homePod      *corev1.Pod
+		foreignEvent *corev1.Event
+	)
+
+	BeforeEach(func() {
+		cacheManager = &storageTest.MockManager{
+			HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+			ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		}
+		namespaceNattingTable = &test.MockNamespaceMapper{Cache: map[string]string{}}
+		namespaceNattingTable.NewNamespace(homeNamespace)
+		homeClient = fake.NewSimpleClientset()
+
+		reflector = &incoming.EventsIncomingReflector{
+			APIReflector: &reflectors.GenericAPIReflector{
+				HomeClient:       homeClient,
+				NamespaceNatting: namespaceNattingTable,
+				CacheManager:     cacheManager,
+			},
+			VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, virtualNodeName),
+			HomePodGetter:   incoming.GetHomePodFunc,
+		}
+		reflector.SetSpecializedPreProcessingHandlers()
+
+		homePod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: homePodName, Namespace: homeNamespace, UID: "home-uid"}}
+		cacheManager.AddHomeEntry(homeNamespace, apimgmt.Pods, homePod)
+		cacheManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      foreignPodName,
+				Namespace: foreignNamespace,
+				UID:       "foreign-uid",
+				Labels:    map[string]string{virtualKubelet.ReflectedpodKey: homePodName},
+			},
+		})
+
+		foreignEvent = &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: foreignPodName + ".16a3f2b1c0d9e8f7", Namespace: foreignNamespace},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Pod", APIVersion: "v1", Namespace: foreignNamespace, Name: foreignPodName,
+				UID: "foreign-uid", FieldPath: "spec.containers{nginx}",
+			},
+			Reason:  "Failed",
+			Message: "Failed to pull image \"nginx:notexisting\"",
+			Type:    corev1.EventTypeWarning,
+			Count:   3,
+			Source:  corev1.EventSource{Component: "kubelet", Host: "foreign-worker"},
+		}
+	})
+
+	Describe("pre add", func() {
+		It("should forge the event against the home pod, with the virtual node as source", func() {
+			ret, _ := reflector.PreProcessAdd(foreignEvent)
+			Expect(ret).ToNot(BeNil())
+
+			homeEvent := ret.(*corev1.Event)
+			Expect(homeEvent.Name).To(Equal(homePodName + ".16a3f2b1c0d9e8f7"))
+			Expect(homeEvent.Namespace).To(Equal(homeNamespace))
+			Expect(homeEvent.InvolvedObject).To(Equal(corev1.ObjectReference{
+				Kind: "Pod", APIVersion: "v1", Namespace: homeNamespace, Name: homePodName,
+				UID: "home-uid", FieldPath: "spec.containers{nginx}",
+			}))
+			Expect(homeEvent.Reason).To(Equal(foreignEvent.Reason))
+			Expect(homeEvent.Message).To(Equal(foreignEvent.Message))
+			Expect(homeEvent.Type).To(Equal(corev1.EventTypeWarning))
+			Expect(homeEvent.Count).To(BeNumerically("==", 3))
+			Expect(homeEvent.Source).To(Equal(corev1.EventSource{Component: "kubelet", Host: virtualNodeName}))
+		})
+
+		It("should ignore the events not involving pods", func() {
+			foreignEvent.InvolvedObject.Kind = "ReplicaSet"
+			ret, _ := reflector.PreProcessAdd(foreignEvent)
+			Expect(ret).To(BeNil())
+		})
+
+		It("should ignore the events involving pods not reflected from the home cluster", func() {
+			foreignEvent.InvolvedObject.Name = "not-reflected"
+			ret, _ := reflector.PreProcessAdd(foreignEvent)
+			Expect(ret).To(BeNil())
+		})
+	})
+
+	Describe("pre update", func() {
+		It("should skip the event if the home one is already up-to-date", func() {
+			ret, _ := reflector.PreProcessAdd(foreignEvent)
+			cacheManager.AddHomeEntry(homeNamespace, apimgmt.Events, ret.(*corev1.Event))
+
+			ret, _ = reflector.PreProcessUpdate(foreignEvent, foreignEvent)
+			Expect(ret).To(BeNil())
+
+			updated := foreignEvent.DeepCopy()
+			updated.Count++
+			ret, _ = reflector.PreProcessUpdate(updated, foreignEvent)
+			Expect(ret).ToNot(BeNil())
+			Expect(ret.(*corev1.Event).Count).To(BeNumerically("==", 4))
+		})
+	})
+
+	Describe("pre delete", func() {
+		It("should never propagate the deletion", func() {
+			ret, _ := reflector.PreProcessDelete(foreignEvent)
+			Expect(ret).To(BeNil())
+		})
+	})
+
+	Describe("handle event", func() {
+		It("should create the event and then update it when already existing", func() {
+			ret, _ := reflector.PreProcessAdd(foreignEvent)
+			reflector.HandleEvent(watch.Event{Type: watch.Added, Object: ret.(*corev1.Event)})
+
+			homeEvent, err := homeClient.CoreV1().Events(homeNamespace).Get(context.TODO(), homePodName+".16a3f2b1c0d9e8f7", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(homeEvent.Count).To(BeNumerically("==", 3))
+
+			updated := foreignEvent.DeepCopy()
+			updated.Count = 5
+			ret, _ = reflector.PreProcessUpdate(updated, foreignEvent)
+			reflector.HandleEvent(watch.Event{Type: watch.Modified, Object: ret.(*corev1.Event)})
+
+			homeEvent, err = homeClient.CoreV1().Events(homeNamespace).Get(context.TODO(), homePodName+".16a3f2b1c0d9e8f7", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(homeEvent.Count).To(BeNumerically("==", 5))
+		})
+	})
+})
@@ -0,0 +1,130 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package incoming
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// JobsIncomingReflector is in charge of reflecting the deletion of the remote jobs (i.e. the ones executing the
+// offloaded batch pods) in the home cluster.
+type JobsIncomingReflector struct {
+	ri.APIReflector
+}
+
+// SetSpecializedPreProcessingHandlers allows to set the pre-routine handlers for the JobsIncomingReflector.
+func (r *JobsIncomingReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.preAdd,
+		UpdateFunc: r.preUpdate,
+		DeleteFunc: r.preDelete,
+	})
+}
+
+// HandleEvent takes the job event and performs an operation in the home cluster.
+// The only event to be handled by this reflector is the deletion of a job: in that case, the object embedded
+// in the received struct is the home pod to be collected.
+func (r *JobsIncomingReflector) HandleEvent(obj interface{}) {
+	event, ok := obj.(watch.Event)
+	if !ok {
+		klog.Error("cannot cast object to event")
+		return
+	}
+
+	pod, ok := event.Object.(*corev1.Pod)
+	if !ok {
+		klog.Error("INCOMING REFLECTION: wrong type, cannot cast object to pod")
+		return
+	}
+
+	klog.V(3).Infof("INCOMING REFLECTION: received %v for pod %v/%v", event.Type, pod.Namespace, pod.Name)
+
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		klog.V(4).Infof("INCOMING REFLECTION: event %v for object %v/%v ignored", event.Type, pod.Namespace, pod.Name)
+	case watch.Deleted:
+		r.PushToInforming(pod)
+		klog.V(3).Infof("INCOMING REFLECTION: delete for job related to home pod %v/%v processed", pod.Namespace, pod.Name)
+	}
+}
+
+// preAdd returns always nil because the add events have to be ignored.
+func (r *JobsIncomingReflector) preAdd(_ interface{}) (interface{}, watch.EventType) {
+	return nil, watch.Added
+}
+
+// preUpdate returns always nil because the update events have to be ignored (the status of the job pods is
+// reflected by the pods incoming reflector).
+func (r *JobsIncomingReflector) preUpdate(_, _ interface{}) (interface{}, watch.EventType) {
+	return nil, watch.Modified
+}
+
+// preDelete receives a job, then deletes and returns the home pod named according to a job label.
+func (r *JobsIncomingReflector) preDelete(obj interface{}) (interface{}, watch.EventType) {
+	foreignJob := obj.(*batchv1.Job).DeepCopy()
+	return homePodForDeletedOwner(r, foreignJob, "job")
+}
+
+// CleanupNamespace deletes all the remote jobs created by the virtual kubelet in the namespace corresponding
+// to the given home one, together with the pods they own.
+func (r *JobsIncomingReflector) CleanupNamespace(namespace string) {
+	foreignNamespace, err := r.NattingTable().NatNamespace(namespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.Jobs, foreignNamespace)
+	if err != nil {
+		klog.Errorf("error while listing remote objects in namespace %v", namespace)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting job because of ERR; %v", err)
+			return true
+		}
+	}
+
+	propagationPolicy := metav1.DeletePropagationBackground
+	for _, obj := range objects {
+		job := obj.(*batchv1.Job)
+		if _, ok := job.Labels[forge.LiqoOutgoingKey]; !ok {
+			continue
+		}
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().BatchV1().Jobs(foreignNamespace).Delete(context.TODO(), job.Name,
+				metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote job %v/%v - ERR: %v", namespace, job.Name, err)
+		}
+	}
+}
@@ -90,16 +90,22 @@ func (r *ReplicaSetsIncomingReflector) preUpdate(_, _ interface{}) (interface{},
 // finally the pod is returned.
 func (r *ReplicaSetsIncomingReflector) preDelete(obj interface{}) (interface{}, watch.EventType) {
 	foreignReplicaSet := obj.(*appsv1.ReplicaSet).DeepCopy()
+	return homePodForDeletedOwner(r, foreignReplicaSet, "replicaset")
+}
 
-	homeNamespace, err := r.NattingTable().DeNatNamespace(foreignReplicaSet.Namespace)
+// homePodForDeletedOwner is in charge of deleting the home pod corresponding to the given foreign owner (i.e., a
+// replicaset or a job), which has been deleted. The home pod is returned with all the containers in terminated
+// status, for allowing the home controllers to collect it, or nil if it has not to be further processed.
+func homePodForDeletedOwner(r ri.APIReflector, foreignOwner metav1.Object, kind string) (interface{}, watch.EventType) {
+	homeNamespace, err := r.NattingTable().DeNatNamespace(foreignOwner.GetNamespace())
 	if err != nil {
 		klog.Error(err)
 		return nil, watch.Deleted
 	}
 
-	podName := foreignReplicaSet.Labels[virtualKubelet.ReflectedpodKey]
+	podName := foreignOwner.GetLabels()[virtualKubelet.ReflectedpodKey]
 	if podName == "" {
-		klog.V(4).Infof("INCOMING REFLECTION: label missing for replicaset %v/%v", foreignReplicaSet.Namespace, foreignReplicaSet.Name)
+		klog.V(4).Infof("INCOMING REFLECTION: label missing for %v %v/%v", kind, foreignOwner.GetNamespace(), foreignOwner.GetName())
 		return nil, watch.Deleted
 	}
 
@@ -126,13 +132,13 @@ func (r *ReplicaSetsIncomingReflector) preDelete(obj interface{}) (interface{},
 		return nil, watch.Deleted
 	}
 
-	// if the DeletionTimestamp is already set, the replicaset deletion has been triggered by a homePod delete event,
+	// if the DeletionTimestamp is already set, the owner deletion has been triggered by a homePod delete event,
 	// hence we have not to delete it
 	if homePod.DeletionTimestamp != nil {
 		return nil, watch.Deleted
 	}
 
-	// if a foreign replicaset has been deleted, first we trigger a delete event for the home pod
+	// if a foreign owner has been deleted, first we trigger a delete event for the home pod
 	if err := r.GetHomeClient().CoreV1().Pods(homeNamespace).Delete(context.TODO(), podName, metav1.DeleteOptions{}); err != nil {
 		klog.Errorf("INCOMING REFLECTION: error while deleting home pod %s/%s", homeNamespace, podName)
 		return nil, watch.Deleted
@@ -22,6 +22,7 @@ import (
 
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
 	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+	argsutils "github.com/liqotech/liqo/pkg/utils/args"
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
@@ -29,10 +30,14 @@ import (
 )
 
 var ReflectorBuilders = map[apimgmt.ApiType]func(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector{
-	apimgmt.Configmaps:     configmapsReflectorBuilder,
-	apimgmt.EndpointSlices: endpointslicesReflectorBuilder,
-	apimgmt.Secrets:        secretsReflectorBuilder,
-	apimgmt.Services:       servicesReflectorBuilder,
+	apimgmt.Configmaps:             configmapsReflectorBuilder,
+	apimgmt.EndpointSlices:         endpointslicesReflectorBuilder,
+	apimgmt.Ingresses:              ingressesReflectorBuilder,
+	apimgmt.NetworkPolicies:        networkpoliciesReflectorBuilder,
+	apimgmt.PersistentVolumeClaims: persistentvolumeclaimsReflectorBuilder,
+	apimgmt.Secrets:                secretsReflectorBuilder,
+	apimgmt.Services:               servicesReflectorBuilder,
+	apimgmt.ServiceAccounts:        serviceaccountsReflectorBuilder,
 }
 
 func configmapsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
@@ -40,18 +45,51 @@ func configmapsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionK
 }
 
 func endpointslicesReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", opts[options.OptionKey(types.LiqoIpamServer)].Value(), liqoconst.NetworkManagerIpamPort),
-		grpc.WithInsecure(),
-		grpc.WithBlock())
-	if err != nil {
-		klog.Error(err)
+	return &EndpointSlicesReflector{
+		APIReflector:    reflector,
+		VirtualNodeName: opts[types.VirtualNodeName],
+		IpamClient:      ipamClientBuilder(opts),
 	}
-	ipamClient := liqonetIpam.NewIpamClient(conn)
+}
 
-	return &EndpointSlicesReflector{
+func ingressesReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
+	ingressClassMapping := argsutils.StringMap{}
+	if opt, ok := opts[types.IngressClassMapping]; ok {
+		if err := ingressClassMapping.Set(opt.Value().ToString()); err != nil {
+			klog.Errorf("invalid ingress class mapping %q - ERR: %v", opt.Value(), err)
+		}
+	}
+
+	var remoteClusterID string
+	if opt, ok := opts[types.RemoteClusterID]; ok {
+		remoteClusterID = opt.Value().ToString()
+	}
+
+	return &IngressesReflector{
+		APIReflector:        reflector,
+		IngressClassMapping: ingressClassMappingForCluster(ingressClassMapping.StringMap, remoteClusterID),
+	}
+}
+
+func networkpoliciesReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
+	return &NetworkPoliciesReflector{
 		APIReflector:    reflector,
 		VirtualNodeName: opts[types.VirtualNodeName],
-		IpamClient:      ipamClient,
+		IpamClient:      ipamClientBuilder(opts),
+	}
+}
+
+func persistentvolumeclaimsReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
+	storageClassMapping := argsutils.StringMap{}
+	if opt, ok := opts[types.StorageClassMapping]; ok {
+		if err := storageClassMapping.Set(opt.Value().ToString()); err != nil {
+			klog.Errorf("invalid storage class mapping %q - ERR: %v", opt.Value(), err)
+		}
+	}
+
+	return &PersistentVolumeClaimsReflector{
+		APIReflector:        reflector,
+		StorageClassMapping: storageClassMapping.StringMap,
 	}
 }
 
@@ -62,3 +100,17 @@ func secretsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]
 func servicesReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
 	return &ServicesReflector{APIReflector: reflector}
 }
+
+func serviceaccountsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
+	return &ServiceAccountsReflector{APIReflector: reflector}
+}
+
+func ipamClientBuilder(opts map[options.OptionKey]options.Option) liqonetIpam.IpamClient {
+	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", opts[options.OptionKey(types.LiqoIpamServer)].Value(), liqoconst.NetworkManagerIpamPort),
+		grpc.WithInsecure(),
+		grpc.WithBlock())
+	if err != nil {
+		klog.Error(err)
+	}
+	return liqonetIpam.NewIpamClient(conn)
+}
@@ -0,0 +1,251 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// ingressClassAnnotation is the legacy annotation specifying the class of an ingress,
+// still honored by most ingress controllers in place of the ingressClassName field.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressesReflector reflects the Ingresses of the offloaded namespaces, so that the workloads can be exposed
+// also through the ingress controllers of the remote cluster. The backends refer to the reflected services,
+// which have the same name in the remote namespace, and the TLS secrets are reflected by the secrets reflector.
+type IngressesReflector struct {
+	ri.APIReflector
+
+	// IngressClassMapping maps the home ingress classes to the foreign ones, while the unmapped classes are preserved.
+	IngressClassMapping map[string]string
+}
+
+func (r *IngressesReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete})
+}
+
+func (r *IngressesReflector) HandleEvent(e interface{}) {
+	event := e.(watch.Event)
+	ingress, ok := event.Object.(*networkingv1.Ingress)
+	if !ok {
+		klog.Error("REFLECTION: cannot cast object to Ingress")
+		return
+	}
+	klog.V(3).Infof("REFLECTION: received %v for Ingress %v/%v", event.Type, ingress.Namespace, ingress.Name)
+
+	switch event.Type {
+	case watch.Added:
+		_, err := r.GetForeignClient().NetworkingV1().Ingresses(ingress.Namespace).Create(context.TODO(), ingress, metav1.CreateOptions{})
+		if kerrors.IsAlreadyExists(err) {
+			klog.V(4).Infof("REFLECTION: The remote Ingress %v/%v has not been created because already existing", ingress.Namespace, ingress.Name)
+			break
+		}
+		if err != nil {
+			klog.Errorf("REFLECTION: Error while creating the remote Ingress %v/%v - ERR: %v", ingress.Namespace, ingress.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote Ingress %v/%v correctly created", ingress.Namespace, ingress.Name)
+		}
+
+	case watch.Modified:
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, newErr := r.GetForeignClient().NetworkingV1().Ingresses(ingress.Namespace).Update(context.TODO(), ingress, metav1.UpdateOptions{})
+			return newErr
+		}); err != nil {
+			klog.Errorf("REFLECTION: Error while updating the remote Ingress %v/%v - ERR: %v", ingress.Namespace, ingress.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote Ingress %v/%v correctly updated", ingress.Namespace, ingress.Name)
+		}
+
+	case watch.Deleted:
+		err := r.GetForeignClient().NetworkingV1().Ingresses(ingress.Namespace).Delete(context.TODO(), ingress.Name, metav1.DeleteOptions{})
+		if err != nil {
+			klog.Errorf("REFLECTION: Error while deleting the remote Ingress %v/%v - ERR: %v", ingress.Namespace, ingress.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote Ingress %v/%v correctly deleted", ingress.Namespace, ingress.Name)
+		}
+	}
+}
+
+func (r *IngressesReflector) CleanupNamespace(localNamespace string) {
+	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.Ingresses, foreignNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting ingress because of- ERR; %v", err)
+			return true
+		}
+	}
+	for _, obj := range objects {
+		ingress := obj.(*networkingv1.Ingress)
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().NetworkingV1().Ingresses(foreignNamespace).Delete(context.TODO(), ingress.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote ingress %v/%v", ingress.Namespace, ingress.Name)
+		}
+	}
+}
+
+func (r *IngressesReflector) PreAdd(obj interface{}) (interface{}, watch.EventType) {
+	ingressLocal := obj.(*networkingv1.Ingress)
+	klog.V(3).Infof("PreAdd routine started for ingress %v/%v", ingressLocal.Namespace, ingressLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(ingressLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Added
+	}
+
+	ingressRemote := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ingressLocal.Name,
+			Namespace: nattedNs,
+		},
+	}
+	r.forgeForeignIngress(ingressLocal, ingressRemote)
+
+	klog.V(3).Infof("PreAdd routine completed for ingress %v/%v", ingressLocal.Namespace, ingressLocal.Name)
+	return ingressRemote, watch.Added
+}
+
+func (r *IngressesReflector) PreUpdate(newObj, _ interface{}) (interface{}, watch.EventType) {
+	newHomeIngress := newObj.(*networkingv1.Ingress)
+
+	klog.V(3).Infof("PreUpdate routine started for ingress %v/%v", newHomeIngress.Namespace, newHomeIngress.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(newHomeIngress.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+
+	oldForeignObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.Ingresses, nattedNs, newHomeIngress.Name)
+	if err != nil {
+		err = errors.Wrapf(err, "ingress %v/%v", nattedNs, newHomeIngress.Name)
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+	oldForeignIngress := oldForeignObj.(*networkingv1.Ingress)
+
+	foreignIngress := oldForeignIngress.DeepCopy()
+	r.forgeForeignIngress(newHomeIngress, foreignIngress)
+
+	if isUpToDate(foreignIngress, oldForeignIngress, foreignIngress.Spec, oldForeignIngress.Spec) {
+		klog.V(4).Infof("PreUpdate routine completed for ingress %v/%v: remote ingress already up-to-date", newHomeIngress.Namespace, newHomeIngress.Name)
+		return nil, watch.Modified
+	}
+
+	klog.V(3).Infof("PreUpdate routine completed for ingress %v/%v", newHomeIngress.Namespace, newHomeIngress.Name)
+	return foreignIngress, watch.Modified
+}
+
+func (r *IngressesReflector) PreDelete(obj interface{}) (interface{}, watch.EventType) {
+	ingressLocal := obj.(*networkingv1.Ingress).DeepCopy()
+	klog.V(3).Infof("PreDelete routine started for ingress %v/%v", ingressLocal.Namespace, ingressLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(ingressLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Deleted
+	}
+	ingressLocal.Namespace = nattedNs
+
+	klog.V(3).Infof("PreDelete routine completed for ingress %v/%v", ingressLocal.Namespace, ingressLocal.Name)
+	return ingressLocal, watch.Deleted
+}
+
+// forgeForeignIngress configures the foreign ingress starting from the home one, remapping the ingress class.
+// The labels and annotations are rebuilt from the home ones, so that the ones removed at home are removed also remotely.
+func (r *IngressesReflector) forgeForeignIngress(homeIngress, foreignIngress *networkingv1.Ingress) {
+	foreignIngress.Labels = make(map[string]string, len(homeIngress.Labels)+1)
+	for k, v := range homeIngress.Labels {
+		foreignIngress.Labels[k] = v
+	}
+	foreignIngress.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	foreignIngress.Annotations = make(map[string]string, len(homeIngress.Annotations))
+	for k, v := range homeIngress.Annotations {
+		foreignIngress.Annotations[k] = v
+	}
+	if homeClass, ok := homeIngress.Annotations[ingressClassAnnotation]; ok {
+		foreignIngress.Annotations[ingressClassAnnotation] = *r.mapIngressClass(&homeClass)
+	}
+
+	foreignIngress.Spec = *homeIngress.Spec.DeepCopy()
+	foreignIngress.Spec.IngressClassName = r.mapIngressClass(homeIngress.Spec.IngressClassName)
+}
+
+// mapIngressClass returns the foreign ingress class corresponding to the home one.
+// The class is preserved when no mapping is configured, rather than falling back to the foreign default ingress class,
+// which might expose the ingress differently from what requested (e.g., publicly rather than internally).
+func (r *IngressesReflector) mapIngressClass(homeClass *string) *string {
+	if homeClass == nil {
+		return nil
+	}
+	foreignClass, ok := r.IngressClassMapping[*homeClass]
+	if !ok {
+		return pointer.StringPtr(*homeClass)
+	}
+	return &foreignClass
+}
+
+// ingressClassMappingForCluster returns the ingress class mapping applying to the given remote cluster, given the
+// configured one, whose keys are either <home-class> or <remote-cluster-id>/<home-class>. The entries restricted
+// to the given cluster take precedence over the generic ones, while the ones restricted to other clusters are discarded.
+func ingressClassMappingForCluster(mapping map[string]string, clusterID string) map[string]string {
+	output := make(map[string]string)
+	for key, value := range mapping {
+		if !strings.Contains(key, "/") {
+			if _, found := output[key]; !found {
+				output[key] = value
+			}
+			continue
+		}
+
+		if chunks := strings.SplitN(key, "/", 2); chunks[0] == clusterID {
+			output[chunks[1]] = value
+		}
+	}
+	return output
+}
@@ -0,0 +1,224 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+func TestIngressAdd(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &IngressesReflector{
+		APIReflector:        Greflector,
+		IngressClassMapping: map[string]string{"nginx": "traefik"},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	ingress := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "name",
+			Namespace:   "homeNamespace",
+			Labels:      map[string]string{"app": "nginx"},
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/rewrite-target": "/"},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: pointer.StringPtr("nginx"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{"foo.bar"}, SecretName: "tls"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "foo.bar",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path: "/",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "service", Port: networkingv1.ServiceBackendPort{Number: 80},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&ingress)
+	postadd := pa.(*networkingv1.Ingress)
+
+	assert.Equal(t, postadd.Namespace, "homeNamespace-natted", "Asserting namespace natting")
+	assert.Equal(t, *postadd.Spec.IngressClassName, "traefik", "Asserting ingress class mapping")
+	assert.Equal(t, postadd.Labels[forge.LiqoOutgoingKey], forge.LiqoNodeName())
+	assert.Equal(t, postadd.Annotations["nginx.ingress.kubernetes.io/rewrite-target"], "/")
+	assert.DeepEqual(t, postadd.Spec.Rules, ingress.Spec.Rules)
+	assert.DeepEqual(t, postadd.Spec.TLS, ingress.Spec.TLS)
+
+	ingress.Spec.IngressClassName = pointer.StringPtr("internal")
+	pa, _ = reflector.PreProcessAdd(&ingress)
+	postadd = pa.(*networkingv1.Ingress)
+	assert.Equal(t, *postadd.Spec.IngressClassName, "internal", "Asserting unmapped classes are preserved")
+}
+
+func TestIngressAddLegacyAnnotation(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &IngressesReflector{
+		APIReflector:        Greflector,
+		IngressClassMapping: map[string]string{"nginx": "traefik"},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	ingress := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "name",
+			Namespace:   "homeNamespace",
+			Annotations: map[string]string{ingressClassAnnotation: "nginx"},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&ingress)
+	postadd := pa.(*networkingv1.Ingress)
+	assert.Equal(t, postadd.Annotations[ingressClassAnnotation], "traefik", "Asserting ingress class mapping")
+	assert.Assert(t, postadd.Spec.IngressClassName == nil)
+
+	ingress.Annotations[ingressClassAnnotation] = "internal"
+	pa, _ = reflector.PreProcessAdd(&ingress)
+	postadd = pa.(*networkingv1.Ingress)
+	assert.Equal(t, postadd.Annotations[ingressClassAnnotation], "internal", "Asserting unmapped classes are preserved")
+}
+
+func TestIngressUpdate(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &IngressesReflector{
+		APIReflector:        Greflector,
+		IngressClassMapping: map[string]string{"nginx": "traefik"},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	ingress := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "name",
+			Namespace:   "homeNamespace",
+			Labels:      map[string]string{"app": "nginx"},
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/rewrite-target": "/"},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: pointer.StringPtr("nginx"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{"foo.bar"}, SecretName: "tls"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "foo.bar",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path: "/",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "service", Port: networkingv1.ServiceBackendPort{Number: 80},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&ingress)
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.Ingresses, pa.(*networkingv1.Ingress))
+
+	updated := ingress.DeepCopy()
+	updated.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.1.1.1"}}
+	pu, _ := reflector.PreProcessUpdate(updated, &ingress)
+	assert.Assert(t, pu == nil, "Asserting the up-to-date remote ingress is not updated")
+
+	updated.Spec.Rules[0].Host = "bar.foo"
+	pu, _ = reflector.PreProcessUpdate(updated, &ingress)
+	postupdate := pu.(*networkingv1.Ingress)
+	assert.Equal(t, postupdate.Spec.Rules[0].Host, "bar.foo")
+	assert.Equal(t, *postupdate.Spec.IngressClassName, "traefik")
+
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.Ingresses, postupdate)
+	pruned := updated.DeepCopy()
+	pruned.Annotations = map[string]string{ingressClassAnnotation: "nginx"}
+	pu, _ = reflector.PreProcessUpdate(pruned, updated)
+	postupdate = pu.(*networkingv1.Ingress)
+	assert.DeepEqual(t, postupdate.Annotations, map[string]string{ingressClassAnnotation: "traefik"})
+
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.Ingresses, postupdate)
+	pruned = pruned.DeepCopy()
+	pruned.Annotations = nil
+	pu, _ = reflector.PreProcessUpdate(pruned, updated)
+	postupdate = pu.(*networkingv1.Ingress)
+	assert.Assert(t, len(postupdate.Annotations) == 0, "Asserting the annotations removed at home are pruned")
+}
+
+func TestIngressClassMappingForCluster(t *testing.T) {
+	mapping := map[string]string{
+		"nginx":            "traefik",
+		"internal":         "nginx-internal",
+		"cluster-1/nginx":  "haproxy",
+		"cluster-2/public": "contour",
+	}
+
+	assert.DeepEqual(t, ingressClassMappingForCluster(mapping, "cluster-1"),
+		map[string]string{"nginx": "haproxy", "internal": "nginx-internal"})
+	assert.DeepEqual(t, ingressClassMappingForCluster(mapping, "cluster-2"),
+		map[string]string{"nginx": "traefik", "internal": "nginx-internal", "public": "contour"})
+}
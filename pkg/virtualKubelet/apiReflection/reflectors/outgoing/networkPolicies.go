@@ -0,0 +1,727 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetIpam "github.com/liqotech/liqo/pkg/liqonet/ipam"
+	liqonetUtils "github.com/liqotech/liqo/pkg/liqonet/utils"
+	"github.com/liqotech/liqo/pkg/utils"
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+)
+
+// unmatchableLabel is the key of a label which is never set on any pod, used to forge a peer matching no pods
+// when none of the ones selected by a home peer exists, since an empty list of peers would allow all traffic instead.
+const unmatchableLabel = "networkpolicy.liqo.io/unmatchable"
+
+// NetworkPoliciesReflector reflects the NetworkPolicies of the offloaded namespaces, so that the isolation rules apply
+// also to the offloaded pods. The peers are translated, since the selectors configured in the home cluster do not
+// match the pods running there when enforced by the remote cluster: the pods offloaded to the same remote cluster
+// are still selected by labels (in the corresponding remote namespaces), while all the others are converted into
+// ipBlocks, containing the addresses of the pods translated into the LocalNATPodCIDR, i.e. the network through which
+// the remote cluster reaches them and from which the traffic they originate appears to come.
+// Since the ipBlocks depend on the pods currently existing in the home cluster, the affected policies are requeued
+// whenever a home pod or namespace they may select is created, deleted or changes its labels or IP address.
+type NetworkPoliciesReflector struct {
+	ri.APIReflector
+
+	VirtualNodeName options.ReadOnlyOption
+	IpamClient      liqonetIpam.IpamClient
+
+	peersMutex      sync.Mutex
+	peersNamespaces map[string]struct{}
+	peers           *peersInformers
+}
+
+// peersInformers are the cluster-wide informers watching the home pods and namespaces the peers of the policies may select.
+type peersInformers struct {
+	pods       corev1listers.PodLister
+	namespaces corev1listers.NamespaceLister
+	synced     []cache.InformerSynced
+	stop       chan struct{}
+}
+
+// SetupHandlers configures the handlers for the given namespace and, the first time, starts the informers
+// watching the home pods and namespaces, to requeue the policies whose peers they may match.
+func (r *NetworkPoliciesReflector) SetupHandlers(api apimgmt.ApiType, reflectionType ri.ReflectionType, namespace, nattedNs string) {
+	r.APIReflector.SetupHandlers(api, reflectionType, namespace, nattedNs)
+	r.watchPeers(namespace)
+}
+
+// watchPeers records that the given namespace is reflected and, if it is the first one, starts the peers informers,
+// waiting for their caches to be synced.
+func (r *NetworkPoliciesReflector) watchPeers(namespace string) {
+	r.peersMutex.Lock()
+	if r.peersNamespaces == nil {
+		r.peersNamespaces = make(map[string]struct{})
+	}
+	r.peersNamespaces[namespace] = struct{}{}
+	if r.peers == nil {
+		r.peers = r.startPeersInformers()
+	}
+	peers := r.peers
+	r.peersMutex.Unlock()
+
+	// the caches are awaited without holding the lock, since the event handlers retrieve the listers.
+	if !cache.WaitForCacheSync(peers.stop, peers.synced...) {
+		klog.Warning("failed to wait for the caches of the home pods and namespaces to be synced")
+	}
+}
+
+// unwatchPeers records that the given namespace is no longer reflected and, if it was the last one, stops the peers
+// informers. This happens also when the reflection is stopped, since all the namespaces are cleaned up.
+func (r *NetworkPoliciesReflector) unwatchPeers(namespace string) {
+	r.peersMutex.Lock()
+	defer r.peersMutex.Unlock()
+
+	delete(r.peersNamespaces, namespace)
+	if len(r.peersNamespaces) == 0 && r.peers != nil {
+		klog.V(4).Info("stopping the informers watching the home pods and namespaces")
+		close(r.peers.stop)
+		r.peers = nil
+	}
+}
+
+// peerListers returns the informers watching the home pods and namespaces, if started.
+func (r *NetworkPoliciesReflector) peerListers() *peersInformers {
+	r.peersMutex.Lock()
+	defer r.peersMutex.Unlock()
+	return r.peers
+}
+
+// startPeersInformers starts the cluster-wide informers watching the home pods and namespaces. They are cluster-wide,
+// since the peers of the policies may select pods in any namespace, regardless of whether it is offloaded.
+func (r *NetworkPoliciesReflector) startPeersInformers() *peersInformers {
+	factory := informers.NewSharedInformerFactory(r.GetHomeClient(), 0)
+
+	pods := factory.Core().V1().Pods()
+	pods.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { r.onHomePodEvent(nil, obj.(*corev1.Pod)) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			r.onHomePodEvent(oldObj.(*corev1.Pod), newObj.(*corev1.Pod))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pod, ok := tombstoneObject(obj).(*corev1.Pod); ok {
+				r.onHomePodEvent(pod, nil)
+			}
+		},
+	})
+
+	namespaces := factory.Core().V1().Namespaces()
+	namespaces.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { r.onHomeNamespaceEvent(nil, obj.(*corev1.Namespace)) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			r.onHomeNamespaceEvent(oldObj.(*corev1.Namespace), newObj.(*corev1.Namespace))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if namespace, ok := tombstoneObject(obj).(*corev1.Namespace); ok {
+				r.onHomeNamespaceEvent(namespace, nil)
+			}
+		},
+	})
+
+	peers := &peersInformers{
+		pods:       pods.Lister(),
+		namespaces: namespaces.Lister(),
+		synced:     []cache.InformerSynced{pods.Informer().HasSynced, namespaces.Informer().HasSynced},
+		stop:       make(chan struct{}),
+	}
+	factory.Start(peers.stop)
+	return peers
+}
+
+// tombstoneObject returns the object wrapped by a tombstone, if any.
+func tombstoneObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+func (r *NetworkPoliciesReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete})
+}
+
+func (r *NetworkPoliciesReflector) HandleEvent(e interface{}) {
+	event := e.(watch.Event)
+	np, ok := event.Object.(*networkingv1.NetworkPolicy)
+	if !ok {
+		klog.Error("REFLECTION: cannot cast object to NetworkPolicy")
+		return
+	}
+	klog.V(3).Infof("REFLECTION: received %v for NetworkPolicy %v/%v", event.Type, np.Namespace, np.Name)
+
+	client := r.GetForeignClient().NetworkingV1().NetworkPolicies(np.Namespace)
+	switch event.Type {
+	case watch.Added:
+		_, err := client.Create(context.TODO(), np, metav1.CreateOptions{})
+		if kerrors.IsAlreadyExists(err) {
+			klog.V(4).Infof("REFLECTION: The remote NetworkPolicy %v/%v has not been created because already existing", np.Namespace, np.Name)
+			break
+		}
+		if err != nil {
+			klog.Errorf("REFLECTION: Error while creating the remote NetworkPolicy %v/%v - ERR: %v", np.Namespace, np.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote NetworkPolicy %v/%v correctly created", np.Namespace, np.Name)
+		}
+
+	case watch.Modified:
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, newErr := client.Update(context.TODO(), np, metav1.UpdateOptions{})
+			return newErr
+		}); err != nil {
+			klog.Errorf("REFLECTION: Error while updating the remote NetworkPolicy %v/%v - ERR: %v", np.Namespace, np.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote NetworkPolicy %v/%v correctly updated", np.Namespace, np.Name)
+		}
+
+	case watch.Deleted:
+		if err := client.Delete(context.TODO(), np.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("REFLECTION: Error while deleting the remote NetworkPolicy %v/%v - ERR: %v", np.Namespace, np.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote NetworkPolicy %v/%v correctly deleted", np.Namespace, np.Name)
+		}
+	}
+}
+
+func (r *NetworkPoliciesReflector) CleanupNamespace(localNamespace string) {
+	defer r.unwatchPeers(localNamespace)
+
+	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.NetworkPolicies, foreignNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting networkpolicy because of- ERR; %v", err)
+			return true
+		}
+	}
+	for _, obj := range objects {
+		np := obj.(*networkingv1.NetworkPolicy)
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().NetworkingV1().NetworkPolicies(foreignNamespace).Delete(context.TODO(), np.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote networkpolicy %v/%v", np.Namespace, np.Name)
+		}
+	}
+}
+
+func (r *NetworkPoliciesReflector) PreAdd(obj interface{}) (interface{}, watch.EventType) {
+	npLocal := obj.(*networkingv1.NetworkPolicy)
+	klog.V(3).Infof("PreAdd routine started for networkpolicy %v/%v", npLocal.Namespace, npLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(npLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Added
+	}
+
+	npRemote := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      npLocal.Name,
+			Namespace: nattedNs,
+		},
+	}
+	if err = r.forgeForeignNetworkPolicy(npLocal, npRemote); err != nil {
+		klog.Errorf("error while forging the remote networkpolicy %v/%v - ERR: %v", nattedNs, npLocal.Name, err)
+		return nil, watch.Added
+	}
+
+	klog.V(3).Infof("PreAdd routine completed for networkpolicy %v/%v", npLocal.Namespace, npLocal.Name)
+	return npRemote, watch.Added
+}
+
+func (r *NetworkPoliciesReflector) PreUpdate(newObj, _ interface{}) (interface{}, watch.EventType) {
+	newHomeNp := newObj.(*networkingv1.NetworkPolicy)
+
+	klog.V(3).Infof("PreUpdate routine started for networkpolicy %v/%v", newHomeNp.Namespace, newHomeNp.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(newHomeNp.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+
+	oldForeignObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.NetworkPolicies, nattedNs, newHomeNp.Name)
+	if err != nil {
+		err = errors.Wrapf(err, "networkpolicy %v/%v", nattedNs, newHomeNp.Name)
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+	oldForeignNp := oldForeignObj.(*networkingv1.NetworkPolicy)
+
+	foreignNp := oldForeignNp.DeepCopy()
+	if err = r.forgeForeignNetworkPolicy(newHomeNp, foreignNp); err != nil {
+		klog.Errorf("error while forging the remote networkpolicy %v/%v - ERR: %v", nattedNs, newHomeNp.Name, err)
+		return nil, watch.Modified
+	}
+
+	if isUpToDate(foreignNp, oldForeignNp, foreignNp.Spec, oldForeignNp.Spec) {
+		klog.V(4).Infof("PreUpdate routine completed for networkpolicy %v/%v: remote networkpolicy already up-to-date",
+			newHomeNp.Namespace, newHomeNp.Name)
+		return nil, watch.Modified
+	}
+
+	klog.V(3).Infof("PreUpdate routine completed for networkpolicy %v/%v", newHomeNp.Namespace, newHomeNp.Name)
+	return foreignNp, watch.Modified
+}
+
+func (r *NetworkPoliciesReflector) PreDelete(obj interface{}) (interface{}, watch.EventType) {
+	npLocal := obj.(*networkingv1.NetworkPolicy).DeepCopy()
+	klog.V(3).Infof("PreDelete routine started for networkpolicy %v/%v", npLocal.Namespace, npLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(npLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Deleted
+	}
+	npLocal.Namespace = nattedNs
+
+	klog.V(3).Infof("PreDelete routine completed for networkpolicy %v/%v", npLocal.Namespace, npLocal.Name)
+	return npLocal, watch.Deleted
+}
+
+// onHomePodEvent requeues the policies whose peers may select the given pod, either before or after the change.
+// Either of the two pods is nil in case of creation and deletion, respectively.
+func (r *NetworkPoliciesReflector) onHomePodEvent(oldPod, newPod *corev1.Pod) {
+	oldSelectable, newSelectable := oldPod != nil && r.isSelectablePod(oldPod), newPod != nil && r.isSelectablePod(newPod)
+	if !oldSelectable && !newSelectable {
+		return
+	}
+	if oldSelectable && newSelectable && oldPod.Status.PodIP == newPod.Status.PodIP &&
+		reflect.DeepEqual(oldPod.Labels, newPod.Labels) {
+		return
+	}
+
+	var pods []*corev1.Pod
+	if oldSelectable {
+		pods = append(pods, oldPod)
+	}
+	if newSelectable {
+		pods = append(pods, newPod)
+	}
+
+	r.requeuePolicies(func(np *networkingv1.NetworkPolicy, peer *networkingv1.NetworkPolicyPeer) bool {
+		for _, pod := range pods {
+			if r.peerMayMatchPod(np, peer, pod) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// onHomeNamespaceEvent requeues the policies whose peers may select the given namespace, either before or after the change.
+// Either of the two namespaces is nil in case of creation and deletion, respectively.
+func (r *NetworkPoliciesReflector) onHomeNamespaceEvent(oldNamespace, newNamespace *corev1.Namespace) {
+	if oldNamespace != nil && newNamespace != nil && reflect.DeepEqual(oldNamespace.Labels, newNamespace.Labels) {
+		return
+	}
+
+	var namespaceLabels []labels.Set
+	for _, namespace := range []*corev1.Namespace{oldNamespace, newNamespace} {
+		if namespace != nil {
+			namespaceLabels = append(namespaceLabels, namespace.Labels)
+		}
+	}
+
+	r.requeuePolicies(func(_ *networkingv1.NetworkPolicy, peer *networkingv1.NetworkPolicyPeer) bool {
+		for _, set := range namespaceLabels {
+			if peer.NamespaceSelector != nil && selectorMatches(peer.NamespaceSelector, set) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// requeuePolicies reconciles again the home policies of the offloaded namespaces having at least one peer for which
+// the given function returns true, informing about the ones whose remote counterpart is no longer up-to-date.
+func (r *NetworkPoliciesReflector) requeuePolicies(affected func(np *networkingv1.NetworkPolicy, peer *networkingv1.NetworkPolicyPeer) bool) {
+	for namespace := range r.NattingTable().MappedNamespaces() {
+		objects, err := r.GetCacheManager().ListHomeNamespacedObject(apimgmt.NetworkPolicies, namespace)
+		if err != nil {
+			klog.Errorf("error while listing the networkpolicies in namespace %v - ERR: %v", namespace, err)
+			continue
+		}
+
+		for _, obj := range objects {
+			np := obj.(*networkingv1.NetworkPolicy)
+			if !policyHasPeer(np, affected) {
+				continue
+			}
+
+			klog.V(4).Infof("requeuing networkpolicy %v/%v because of a change of its peers", np.Namespace, np.Name)
+			o, event := r.PreProcessUpdate(np, np)
+			if o == nil {
+				continue
+			}
+			r.Inform(apimgmt.ApiEvent{
+				Event: watch.Event{Type: event, Object: o.(runtime.Object)},
+				Api:   apimgmt.NetworkPolicies,
+			})
+		}
+	}
+}
+
+// policyHasPeer returns whether at least one of the selector-based peers of the policy satisfies the given function.
+func policyHasPeer(np *networkingv1.NetworkPolicy, affected func(*networkingv1.NetworkPolicy, *networkingv1.NetworkPolicyPeer) bool) bool {
+	var peers []networkingv1.NetworkPolicyPeer
+	for i := range np.Spec.Ingress {
+		peers = append(peers, np.Spec.Ingress[i].From...)
+	}
+	for i := range np.Spec.Egress {
+		peers = append(peers, np.Spec.Egress[i].To...)
+	}
+
+	for i := range peers {
+		if peers[i].IPBlock == nil && affected(np, &peers[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerMayMatchPod returns whether the given peer of the policy may select the pod. It errs on the side of caution
+// in case the labels of the namespace of the pod cannot be retrieved.
+func (r *NetworkPoliciesReflector) peerMayMatchPod(np *networkingv1.NetworkPolicy,
+	peer *networkingv1.NetworkPolicyPeer, pod *corev1.Pod) bool {
+	if peer.PodSelector != nil && !selectorMatches(peer.PodSelector, pod.Labels) {
+		return false
+	}
+
+	if peer.NamespaceSelector == nil {
+		return pod.Namespace == np.Namespace
+	}
+	peers := r.peerListers()
+	if peers == nil {
+		return true
+	}
+	namespace, err := peers.namespaces.Get(pod.Namespace)
+	if err != nil {
+		return true
+	}
+	return selectorMatches(peer.NamespaceSelector, namespace.Labels)
+}
+
+// selectorMatches returns whether the given selector matches the labels, considering an invalid selector as matching.
+func selectorMatches(selector *metav1.LabelSelector, set labels.Set) bool {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return true
+	}
+	return labelSelector.Matches(set)
+}
+
+// isSelectablePod returns whether the given pod is converted into an ipBlock, i.e. whether it is a home pod, reachable
+// through its own IP address and not offloaded to any remote cluster. Offloaded pods are either selected by labels
+// (if running in the remote cluster targeted by the policy), or not reachable through their home IP address at all.
+func (r *NetworkPoliciesReflector) isSelectablePod(pod *corev1.Pod) bool {
+	if pod.Spec.HostNetwork || pod.Status.PodIP == "" {
+		return false
+	}
+	if pod.Labels[liqoconst.LocalPodLabelKey] == liqoconst.LocalPodLabelValue {
+		return false
+	}
+	return pod.Spec.NodeName != r.VirtualNodeName.Value().ToString() &&
+		!strings.HasPrefix(pod.Spec.NodeName, virtualKubelet.VirtualNodePrefix)
+}
+
+// forgeForeignNetworkPolicy configures the foreign network policy starting from the home one, translating the peers.
+func (r *NetworkPoliciesReflector) forgeForeignNetworkPolicy(homeNp, foreignNp *networkingv1.NetworkPolicy) error {
+	if foreignNp.Labels == nil {
+		foreignNp.Labels = make(map[string]string)
+	}
+	for k, v := range homeNp.Labels {
+		foreignNp.Labels[k] = v
+	}
+	foreignNp.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	if foreignNp.Annotations == nil {
+		foreignNp.Annotations = make(map[string]string)
+	}
+	for k, v := range homeNp.Annotations {
+		foreignNp.Annotations[k] = v
+	}
+
+	translator, err := r.podAddressTranslator()
+	if err != nil {
+		return err
+	}
+
+	spec := homeNp.Spec.DeepCopy()
+	for i := range spec.Ingress {
+		peers, err := r.translatePeers(homeNp.Namespace, spec.Ingress[i].From, translator)
+		if err != nil {
+			return err
+		}
+		spec.Ingress[i].From = peers
+	}
+	for i := range spec.Egress {
+		peers, err := r.translatePeers(homeNp.Namespace, spec.Egress[i].To, translator)
+		if err != nil {
+			return err
+		}
+		spec.Egress[i].To = peers
+	}
+	foreignNp.Spec = *spec
+
+	return nil
+}
+
+// translatePeers translates the given home peers into the equivalent ones from the point of view of the remote cluster.
+func (r *NetworkPoliciesReflector) translatePeers(namespace string, homePeers []networkingv1.NetworkPolicyPeer,
+	translator *podAddressTranslator) ([]networkingv1.NetworkPolicyPeer, error) {
+	// an empty list of peers matches all sources/destinations, hence it is preserved as is.
+	if len(homePeers) == 0 {
+		return homePeers, nil
+	}
+
+	var peers []networkingv1.NetworkPolicyPeer
+	for i := range homePeers {
+		translated, err := r.translatePeer(namespace, &homePeers[i], translator)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, translated...)
+	}
+
+	if len(peers) == 0 {
+		// none of the selected pods currently exists, but the rule must still not match any other one.
+		peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: unmatchableLabel, Operator: metav1.LabelSelectorOpExists}},
+		}})
+	}
+
+	return peers, nil
+}
+
+// translatePeer translates a single home peer into the equivalent ones from the point of view of the remote cluster.
+// IP blocks are preserved as is, while the selectors are converted into the combination of (i) the selectors matching
+// the pods offloaded to the remote cluster, and (ii) the ipBlocks matching all the other pods.
+func (r *NetworkPoliciesReflector) translatePeer(namespace string, homePeer *networkingv1.NetworkPolicyPeer,
+	translator *podAddressTranslator) ([]networkingv1.NetworkPolicyPeer, error) {
+	if homePeer.IPBlock != nil {
+		return []networkingv1.NetworkPolicyPeer{*homePeer.DeepCopy()}, nil
+	}
+
+	namespaces := []string{namespace}
+	if homePeer.NamespaceSelector != nil {
+		var err error
+		if namespaces, err = r.selectHomeNamespaces(homePeer.NamespaceSelector); err != nil {
+			return nil, err
+		}
+	}
+
+	podSelector := homePeer.PodSelector
+	if podSelector == nil {
+		podSelector = &metav1.LabelSelector{}
+	}
+
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, ns := range namespaces {
+		// the pods offloaded to the remote cluster are selected by labels in the corresponding remote namespace.
+		if nattedNs, err := r.NattingTable().NatNamespace(ns); err == nil {
+			peer := networkingv1.NetworkPolicyPeer{PodSelector: podSelector.DeepCopy()}
+			if homePeer.NamespaceSelector != nil {
+				peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: nattedNs}}
+			}
+			peers = append(peers, peer)
+		}
+
+		blocks, err := r.homePodsIPBlocks(ns, podSelector, translator)
+		if err != nil {
+			return nil, err
+		}
+		for i := range blocks {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &blocks[i]})
+		}
+	}
+
+	return peers, nil
+}
+
+// selectHomeNamespaces returns the names of the home namespaces matching the given selector.
+func (r *NetworkPoliciesReflector) selectHomeNamespaces(selector *metav1.LabelSelector) ([]string, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := r.peerListers()
+	if peers == nil {
+		return nil, errors.New("the informers watching the home namespaces are not running")
+	}
+	namespaces, err := peers.namespaces.List(labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the home namespaces")
+	}
+
+	names := make([]string, len(namespaces))
+	for i := range namespaces {
+		names[i] = namespaces[i].Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// homePodsIPBlocks returns the ipBlocks matching the addresses through which the remote cluster sees the pods
+// selected in the given home namespace, excluding the ones offloaded to any remote cluster.
+func (r *NetworkPoliciesReflector) homePodsIPBlocks(namespace string, selector *metav1.LabelSelector,
+	translator *podAddressTranslator) ([]networkingv1.IPBlock, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := r.peerListers()
+	if peers == nil {
+		return nil, errors.New("the informers watching the home pods are not running")
+	}
+	pods, err := peers.pods.Pods(namespace).List(labelSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the home pods in namespace %q", namespace)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	var blocks []networkingv1.IPBlock
+	for _, pod := range pods {
+		if !r.isSelectablePod(pod) {
+			continue
+		}
+
+		block, ok, err := translator.ipBlock(pod.Status.PodIP)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to translate the IP address of pod %q", pod.Name)
+		}
+		if !ok {
+			klog.V(4).Infof("pod %v/%v with IP address %v outside of the pod CIDR cannot be selected by the remote networkpolicies",
+				pod.Namespace, pod.Name, pod.Status.PodIP)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// podAddressTranslator translates the addresses of the home pods into the ones the remote cluster sees them with, that
+// is their NETMAP translation into the LocalNATPodCIDR (unless not remapped). The pods outside the home PodCIDR are not
+// translated, since the traffic they originate is masqueraded with the first address of the LocalNATPodCIDR instead.
+type podAddressTranslator struct {
+	podCIDRs    []*net.IPNet
+	natPodCIDRs []string
+}
+
+// podAddressTranslator retrieves from the IPAM the home PodCIDRs and the corresponding LocalNATPodCIDRs of the remote cluster.
+func (r *NetworkPoliciesReflector) podAddressTranslator() (*podAddressTranslator, error) {
+	clusterID := utils.GetClusterIDFromNodeName(r.VirtualNodeName.Value().ToString())
+
+	pools, err := r.IpamClient.ListPools(context.TODO(), &liqonetIpam.ListPoolsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve the home pod CIDR")
+	}
+	response, err := r.IpamClient.ListClusterSubnets(context.TODO(), &liqonetIpam.ListClusterSubnetsRequest{ClusterID: clusterID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve the networks of cluster %v", clusterID)
+	}
+	if len(response.GetClusterSubnets()) == 0 {
+		return nil, errors.Errorf("the networks of cluster %v are not yet configured", clusterID)
+	}
+	subnets := response.GetClusterSubnets()[0]
+
+	translator := &podAddressTranslator{}
+	for _, family := range [][2]string{
+		{pools.GetPodCIDR(), subnets.GetLocalNATPodCIDR()},
+		{pools.GetPodCIDRv6(), subnets.GetLocalNATPodCIDRv6()},
+	} {
+		if family[0] == "" || family[1] == "" {
+			continue
+		}
+		_, podCIDR, err := net.ParseCIDR(family[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pod CIDR %q", family[0])
+		}
+		translator.podCIDRs = append(translator.podCIDRs, podCIDR)
+		translator.natPodCIDRs = append(translator.natPodCIDRs, family[1])
+	}
+	return translator, nil
+}
+
+// ipBlock returns the ipBlock matching the given home pod address as seen by the remote cluster, and whether the
+// address can be translated at all.
+func (t *podAddressTranslator) ipBlock(podIP string) (networkingv1.IPBlock, bool, error) {
+	ip := net.ParseIP(podIP)
+	if ip == nil {
+		return networkingv1.IPBlock{}, false, errors.Errorf("invalid IP address %q", podIP)
+	}
+
+	for i := range t.podCIDRs {
+		if !t.podCIDRs[i].Contains(ip) {
+			continue
+		}
+		translated, err := liqonetUtils.MapIPToNetwork(t.natPodCIDRs[i], podIP)
+		if err != nil {
+			return networkingv1.IPBlock{}, false, err
+		}
+		mask := 8 * net.IPv4len
+		if ip.To4() == nil {
+			mask = 8 * net.IPv6len
+		}
+		return networkingv1.IPBlock{CIDR: fmt.Sprintf("%s/%d", translated, mask)}, true, nil
+	}
+	return networkingv1.IPBlock{}, false, nil
+}
@@ -0,0 +1,404 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	liqonetTest "github.com/liqotech/liqo/pkg/liqonet/test"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+// newTestPeers returns the (not started) informers watching the given home pods and namespaces,
+// along with the indexers backing them.
+func newTestPeers(objects ...runtime.Object) (peers *peersInformers, pods, namespaces cache.Indexer) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	podsInformer, namespacesInformer := factory.Core().V1().Pods(), factory.Core().V1().Namespaces()
+	pods, namespaces = podsInformer.Informer().GetIndexer(), namespacesInformer.Informer().GetIndexer()
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *v1.Pod:
+			_ = pods.Add(o)
+		case *v1.Namespace:
+			_ = namespaces.Add(o)
+		}
+	}
+	return &peersInformers{pods: podsInformer.Lister(), namespaces: namespacesInformer.Lister(), stop: make(chan struct{})}, pods, namespaces
+}
+
+func TestNetworkPolicyAdd(t *testing.T) {
+	peers, _, _ := newTestPeers(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "homeNamespace", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "otherNamespace", Labels: map[string]string{"team": "a"}}},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-local", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+			Spec:       v1.PodSpec{NodeName: "worker-1"},
+			Status:     v1.PodStatus{PodIP: "10.0.0.15"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-offloaded", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+			Spec:       v1.PodSpec{NodeName: "vk-node"},
+			Status:     v1.PodStatus{PodIP: "10.200.0.5"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-other-virtual-node", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+			Spec:       v1.PodSpec{NodeName: "liqo-other-cluster"},
+			Status:     v1.PodStatus{PodIP: "10.201.0.5"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-shadow", Namespace: "homeNamespace", Labels: map[string]string{
+				"app": "db", liqoconst.LocalPodLabelKey: liqoconst.LocalPodLabelValue}},
+			Spec:   v1.PodSpec{NodeName: "virtual-node"},
+			Status: v1.PodStatus{PodIP: "10.202.0.5"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-pending", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-outside-pod-cidr", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+			Spec:       v1.PodSpec{NodeName: "worker-1"},
+			Status:     v1.PodStatus{PodIP: "172.16.0.5"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "homeNamespace", Labels: map[string]string{"app": "web"}},
+			Spec:       v1.PodSpec{NodeName: "worker-1"},
+			Status:     v1.PodStatus{PodIP: "10.0.0.16"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "otherNamespace", Labels: map[string]string{"app": "db"}},
+			Spec:       v1.PodSpec{NodeName: "worker-2"},
+			Status:     v1.PodStatus{PodIP: "10.0.1.1"},
+		},
+	)
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		HomeClient:       fake.NewSimpleClientset(),
+		ForeignClient:    fake.NewSimpleClientset(),
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &NetworkPoliciesReflector{
+		APIReflector:    Greflector,
+		VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, "vk-node"),
+		IpamClient:      &liqonetTest.MockIpam{LocalRemappedPodCIDR: "10.100.0.0/16", PodCIDR: "10.0.0.0/16"},
+		peers:           peers,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	home := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}}},
+				{From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}}}},
+				{From: nil},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				},
+				{IPBlock: &networkingv1.IPBlock{CIDR: "8.8.8.8/32"}},
+			}}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&home)
+	postadd := pa.(*networkingv1.NetworkPolicy)
+
+	assert.Equal(t, postadd.Namespace, "homeNamespace-natted", "Asserting namespace natting")
+	assert.DeepEqual(t, postadd.Spec.PodSelector, home.Spec.PodSelector)
+	assert.DeepEqual(t, postadd.Spec.PolicyTypes, home.Spec.PolicyTypes)
+
+	// the pods offloaded to this cluster are still selected by labels, while the home ones are converted into ipBlocks.
+	// The pods offloaded to other clusters are neither mapped nor converted.
+	assert.DeepEqual(t, postadd.Spec.Ingress[0].From, []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.0.15/32"}},
+	})
+
+	// a rule selecting no pods must not be converted into a rule allowing all traffic.
+	assert.Equal(t, len(postadd.Spec.Ingress[1].From), 1)
+	assert.Equal(t, postadd.Spec.Ingress[1].From[0].PodSelector.MatchExpressions[0].Key, unmatchableLabel)
+	assert.Assert(t, postadd.Spec.Ingress[2].From == nil)
+
+	assert.DeepEqual(t, postadd.Spec.Egress[0].To, []networkingv1.NetworkPolicyPeer{
+		{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelMetadataName: "homeNamespace-natted"}},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.0.15/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.1.1/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "8.8.8.8/32"}},
+	})
+}
+
+func TestNetworkPolicyUpdate(t *testing.T) {
+	peers, _, _ := newTestPeers(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "worker-1"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.15"},
+	})
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		HomeClient:       fake.NewSimpleClientset(),
+		ForeignClient:    fake.NewSimpleClientset(),
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &NetworkPoliciesReflector{
+		APIReflector:    Greflector,
+		VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, "vk-node"),
+		IpamClient:      &liqonetTest.MockIpam{LocalRemappedPodCIDR: "10.100.0.0/16", PodCIDR: "10.0.0.0/16"},
+		peers:           peers,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	home := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}}},
+				{From: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "8.8.8.8/32"}}}},
+			},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&home)
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.NetworkPolicies, pa.(*networkingv1.NetworkPolicy))
+
+	pu, _ := reflector.PreProcessUpdate(home.DeepCopy(), &home)
+	assert.Assert(t, pu == nil, "Asserting the up-to-date remote networkpolicy is not updated")
+
+	updated := home.DeepCopy()
+	updated.Spec.Ingress = updated.Spec.Ingress[:1]
+	pu, _ = reflector.PreProcessUpdate(updated, &home)
+	assert.Equal(t, len(pu.(*networkingv1.NetworkPolicy).Spec.Ingress), 1)
+}
+
+func TestNetworkPolicyPodChurn(t *testing.T) {
+	peers, pods, _ := newTestPeers(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "worker-1"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.15"},
+	})
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	outputChan := make(chan apimgmt.ApiEvent, 10)
+
+	Greflector := &api.GenericAPIReflector{
+		OutputChan:       outputChan,
+		HomeClient:       fake.NewSimpleClientset(),
+		ForeignClient:    fake.NewSimpleClientset(),
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &NetworkPoliciesReflector{
+		APIReflector:    Greflector,
+		VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, "vk-node"),
+		IpamClient:      &liqonetTest.MockIpam{LocalRemappedPodCIDR: "10.100.0.0/16", PodCIDR: "10.0.0.0/16"},
+		peers:           peers,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	home := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}}},
+			},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.NetworkPolicies, home)
+	pa, _ := reflector.PreProcessAdd(home)
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.NetworkPolicies, pa.(*networkingv1.NetworkPolicy))
+
+	requeued := func() []networkingv1.NetworkPolicyPeer {
+		select {
+		case event := <-outputChan:
+			np := event.Event.(watch.Event).Object.(*networkingv1.NetworkPolicy)
+			cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.NetworkPolicies, np)
+			return np.Spec.Ingress[0].From
+		default:
+			return nil
+		}
+	}
+
+	// a new pod matching the selector is added to the ipBlocks.
+	created := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-2", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "worker-2"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.16"},
+	}
+	assert.NilError(t, pods.Add(created))
+	reflector.onHomePodEvent(nil, created)
+	assert.DeepEqual(t, requeued(), []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.0.15/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.0.16/32"}},
+	})
+
+	// pods not matching the selector, or running on virtual nodes, do not trigger any update.
+	web := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "homeNamespace", Labels: map[string]string{"app": "web"}},
+		Spec:       v1.PodSpec{NodeName: "worker-2"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.17"},
+	}
+	reflector.onHomePodEvent(nil, web)
+	offloaded := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-3", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "liqo-other-cluster"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.18"},
+	}
+	reflector.onHomePodEvent(nil, offloaded)
+	assert.Assert(t, requeued() == nil)
+
+	// a deleted pod is removed from the ipBlocks.
+	deleted := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "homeNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "worker-1"},
+		Status:     v1.PodStatus{PodIP: "10.0.0.15"},
+	}
+	assert.NilError(t, pods.Delete(deleted))
+	reflector.onHomePodEvent(deleted, nil)
+	assert.DeepEqual(t, requeued(), []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.0.16/32"}},
+	})
+}
+
+func TestNetworkPolicyNamespaceChurn(t *testing.T) {
+	otherNamespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "otherNamespace", Labels: map[string]string{"team": "a"}}}
+	peers, _, namespaces := newTestPeers(otherNamespace, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "otherNamespace", Labels: map[string]string{"app": "db"}},
+		Spec:       v1.PodSpec{NodeName: "worker-1"},
+		Status:     v1.PodStatus{PodIP: "10.0.1.1"},
+	})
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	outputChan := make(chan apimgmt.ApiEvent, 10)
+
+	Greflector := &api.GenericAPIReflector{
+		OutputChan:       outputChan,
+		HomeClient:       fake.NewSimpleClientset(),
+		ForeignClient:    fake.NewSimpleClientset(),
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &NetworkPoliciesReflector{
+		APIReflector:    Greflector,
+		VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, "vk-node"),
+		IpamClient:      &liqonetTest.MockIpam{LocalRemappedPodCIDR: "10.100.0.0/16", PodCIDR: "10.0.0.0/16"},
+		peers:           peers,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	home := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}}}},
+			},
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.NetworkPolicies, home)
+	pa, _ := reflector.PreProcessAdd(home)
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.NetworkPolicies, pa.(*networkingv1.NetworkPolicy))
+
+	// the pods of a namespace starting to match the selector are added to the ipBlocks.
+	relabeled := otherNamespace.DeepCopy()
+	relabeled.Labels["team"] = "b"
+	assert.NilError(t, namespaces.Update(relabeled))
+	reflector.onHomeNamespaceEvent(otherNamespace, relabeled)
+
+	assert.Equal(t, len(outputChan), 1)
+	event := <-outputChan
+	assert.DeepEqual(t, event.Event.(watch.Event).Object.(*networkingv1.NetworkPolicy).Spec.Ingress[0].From, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.100.1.1/32"}},
+	})
+}
+
+func TestNetworkPolicyPeersInformers(t *testing.T) {
+	reflector := &NetworkPoliciesReflector{
+		APIReflector: &api.GenericAPIReflector{
+			HomeClient:       fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "homeNamespace"}}),
+			NamespaceNatting: &test.MockNamespaceMapper{Cache: map[string]string{}},
+		},
+		VirtualNodeName: types.NewNetworkingOption(types.VirtualNodeName, "vk-node"),
+	}
+
+	// the informers are started along with the reflection of the first namespace, and their caches are synced.
+	reflector.watchPeers("homeNamespace")
+	peers := reflector.peerListers()
+	assert.Assert(t, peers != nil)
+	_, err := peers.namespaces.Get("homeNamespace")
+	assert.NilError(t, err)
+
+	reflector.watchPeers("otherNamespace")
+	assert.Equal(t, reflector.peerListers(), peers, "Asserting the informers are shared by the reflected namespaces")
+
+	// the informers are stopped once no namespace is reflected anymore.
+	reflector.unwatchPeers("homeNamespace")
+	assert.Equal(t, reflector.peerListers(), peers)
+	reflector.unwatchPeers("otherNamespace")
+	assert.Assert(t, reflector.peerListers() == nil)
+	_, open := <-peers.stop
+	assert.Assert(t, !open, "Asserting the informers are stopped")
+}
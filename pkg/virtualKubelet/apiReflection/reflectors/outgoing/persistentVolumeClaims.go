@@ -0,0 +1,251 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// pvcHomeAnnotationPrefixes contains the prefixes of the annotations set by the home control plane
+// when binding and provisioning a claim, which are meaningless in the foreign cluster.
+var pvcHomeAnnotationPrefixes = []string{
+	"pv.kubernetes.io/",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// PersistentVolumeClaimsReflector reflects the PersistentVolumeClaims of the offloaded namespaces,
+// so that the pods scheduled on the virtual node can mount a remote-backed volume with the same claim name.
+type PersistentVolumeClaimsReflector struct {
+	ri.APIReflector
+
+	// StorageClassMapping maps the home storage classes to the corresponding foreign ones.
+	// Claims requesting a storage class not included in the mapping are bound to the foreign default one.
+	StorageClassMapping map[string]string
+}
+
+func (r *PersistentVolumeClaimsReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete})
+}
+
+func (r *PersistentVolumeClaimsReflector) HandleEvent(e interface{}) {
+	event := e.(watch.Event)
+	pvc, ok := event.Object.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		klog.Error("REFLECTION: cannot cast object to PersistentVolumeClaim")
+		return
+	}
+	klog.V(3).Infof("REFLECTION: received %v for PersistentVolumeClaim %v/%v", event.Type, pvc.Namespace, pvc.Name)
+
+	switch event.Type {
+	case watch.Added:
+		_, err := r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(context.TODO(), pvc, metav1.CreateOptions{})
+		if kerrors.IsAlreadyExists(err) {
+			klog.V(4).Infof("REFLECTION: The remote PersistentVolumeClaim %v/%v has not been created because already existing", pvc.Namespace, pvc.Name)
+			break
+		}
+		if err != nil {
+			klog.Errorf("REFLECTION: Error while creating the remote PersistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote PersistentVolumeClaim %v/%v correctly created", pvc.Namespace, pvc.Name)
+		}
+
+	case watch.Modified:
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, newErr := r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, metav1.UpdateOptions{})
+			return newErr
+		}); err != nil {
+			klog.Errorf("REFLECTION: Error while updating the remote PersistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote PersistentVolumeClaim %v/%v correctly updated", pvc.Namespace, pvc.Name)
+		}
+
+	case watch.Deleted:
+		if err := r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("REFLECTION: Error while deleting the remote PersistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote PersistentVolumeClaim %v/%v correctly deleted", pvc.Namespace, pvc.Name)
+		}
+	}
+}
+
+func (r *PersistentVolumeClaimsReflector) CleanupNamespace(localNamespace string) {
+	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.PersistentVolumeClaims, foreignNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting persistentvolumeclaim because of- ERR; %v", err)
+			return true
+		}
+	}
+	for _, obj := range objects {
+		pvc := obj.(*corev1.PersistentVolumeClaim)
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().CoreV1().PersistentVolumeClaims(foreignNamespace).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote persistentvolumeclaim %v/%v", pvc.Namespace, pvc.Name)
+		}
+	}
+}
+
+func (r *PersistentVolumeClaimsReflector) PreAdd(obj interface{}) (interface{}, watch.EventType) {
+	pvcLocal := obj.(*corev1.PersistentVolumeClaim)
+	klog.V(3).Infof("PreAdd routine started for persistentvolumeclaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(pvcLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Added
+	}
+
+	pvcRemote := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pvcLocal.Name,
+			Namespace:   nattedNs,
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      pvcLocal.Spec.AccessModes,
+			Resources:        pvcLocal.Spec.Resources,
+			VolumeMode:       pvcLocal.Spec.VolumeMode,
+			StorageClassName: r.mapStorageClass(pvcLocal.Spec.StorageClassName),
+		},
+	}
+
+	for k, v := range pvcLocal.Labels {
+		pvcRemote.Labels[k] = v
+	}
+	pvcRemote.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	for k, v := range pvcLocal.Annotations {
+		if !isHomePVCAnnotation(k) {
+			pvcRemote.Annotations[k] = v
+		}
+	}
+
+	klog.V(3).Infof("PreAdd routine completed for persistentvolumeclaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+	return pvcRemote, watch.Added
+}
+
+func (r *PersistentVolumeClaimsReflector) PreUpdate(newObj, _ interface{}) (interface{}, watch.EventType) {
+	newHomePvc := newObj.(*corev1.PersistentVolumeClaim).DeepCopy()
+
+	klog.V(3).Infof("PreUpdate routine started for persistentvolumeclaim %v/%v", newHomePvc.Namespace, newHomePvc.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(newHomePvc.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+
+	oldForeignObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.PersistentVolumeClaims, nattedNs, newHomePvc.Name)
+	if err != nil {
+		err = errors.Wrapf(err, "persistentvolumeclaim %v/%v", nattedNs, newHomePvc.Name)
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+	foreignPvc := oldForeignObj.(*corev1.PersistentVolumeClaim).DeepCopy()
+
+	if foreignPvc.Labels == nil {
+		foreignPvc.Labels = make(map[string]string)
+	}
+	for k, v := range newHomePvc.Labels {
+		foreignPvc.Labels[k] = v
+	}
+	foreignPvc.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	if foreignPvc.Annotations == nil {
+		foreignPvc.Annotations = make(map[string]string)
+	}
+	for k, v := range newHomePvc.Annotations {
+		if !isHomePVCAnnotation(k) {
+			foreignPvc.Annotations[k] = v
+		}
+	}
+
+	// the only mutable field of the spec of a bound claim is the amount of requested resources (i.e. volume expansion).
+	foreignPvc.Spec.Resources = newHomePvc.Spec.Resources
+
+	klog.V(3).Infof("PreUpdate routine completed for persistentvolumeclaim %v/%v", newHomePvc.Namespace, newHomePvc.Name)
+	return foreignPvc, watch.Modified
+}
+
+func (r *PersistentVolumeClaimsReflector) PreDelete(obj interface{}) (interface{}, watch.EventType) {
+	pvcLocal := obj.(*corev1.PersistentVolumeClaim).DeepCopy()
+	klog.V(3).Infof("PreDelete routine started for persistentvolumeclaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(pvcLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Deleted
+	}
+	pvcLocal.Namespace = nattedNs
+
+	klog.V(3).Infof("PreDelete routine completed for persistentvolumeclaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+	return pvcLocal, watch.Deleted
+}
+
+// mapStorageClass returns the foreign storage class corresponding to the home one.
+// A nil value is returned when no mapping is configured, to select the foreign default storage class.
+func (r *PersistentVolumeClaimsReflector) mapStorageClass(homeClass *string) *string {
+	if homeClass == nil {
+		return nil
+	}
+	foreignClass, ok := r.StorageClassMapping[*homeClass]
+	if !ok {
+		return nil
+	}
+	return &foreignClass
+}
+
+func isHomePVCAnnotation(key string) bool {
+	for _, prefix := range pvcHomeAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
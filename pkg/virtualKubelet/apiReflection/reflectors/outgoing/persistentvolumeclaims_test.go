@@ -0,0 +1,184 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+func TestPersistentVolumeClaimAdd(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &PersistentVolumeClaimsReflector{
+		APIReflector:        Greflector,
+		StorageClassMapping: map[string]string{"standard": "gp2"},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	storageClass := "standard"
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+			Annotations: map[string]string{
+				"pv.kubernetes.io/bind-completed":               "yes",
+				"volume.beta.kubernetes.io/storage-provisioner": "rancher.io/local-path",
+				"app.liqo.io/custom":                            "value",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+			StorageClassName: &storageClass,
+			VolumeName:       "pvc-home-volume",
+		},
+	}
+
+	pa, _ := reflector.PreProcessAdd(&pvc)
+	postadd := pa.(*corev1.PersistentVolumeClaim)
+
+	assert.Equal(t, postadd.Namespace, "homeNamespace-natted")
+	assert.Equal(t, *postadd.Spec.StorageClassName, "gp2")
+	assert.Equal(t, postadd.Spec.VolumeName, "", "the home volume name should not be reflected")
+	assert.Equal(t, postadd.Spec.Resources.Requests.Storage().String(), "1Gi")
+	assert.Equal(t, len(postadd.Annotations), 1, "home provisioning annotations are not removed")
+	assert.Equal(t, postadd.Labels[forge.LiqoOutgoingKey], forge.LiqoNodeName())
+}
+
+func TestPersistentVolumeClaimAddUnmappedStorageClass(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &PersistentVolumeClaimsReflector{
+		APIReflector:        Greflector,
+		StorageClassMapping: map[string]string{},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	storageClass := "standard"
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+		},
+	}
+
+	pa, _ := reflector.PreProcessAdd(&pvc)
+	postadd := pa.(*corev1.PersistentVolumeClaim)
+
+	assert.Assert(t, postadd.Spec.StorageClassName == nil, "unmapped storage classes should select the foreign default one")
+}
+
+func TestPersistentVolumeClaimUpdate(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &PersistentVolumeClaimsReflector{
+		APIReflector:        Greflector,
+		StorageClassMapping: map[string]string{},
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	foreignStorageClass := "gp2"
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.PersistentVolumeClaims, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "name",
+			Namespace:       "homeNamespace-natted",
+			ResourceVersion: "42",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+			StorageClassName: &foreignStorageClass,
+			VolumeName:       "pvc-foreign-volume",
+		},
+	})
+
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+			},
+			VolumeName: "pvc-home-volume",
+		},
+	}
+
+	pu, _ := reflector.PreProcessUpdate(&pvc, nil)
+	postupdate := pu.(*corev1.PersistentVolumeClaim)
+
+	assert.Equal(t, postupdate.ResourceVersion, "42")
+	assert.Equal(t, postupdate.Spec.Resources.Requests.Storage().String(), "2Gi")
+	assert.Equal(t, postupdate.Spec.VolumeName, "pvc-foreign-volume")
+	assert.Equal(t, *postupdate.Spec.StorageClassName, "gp2")
+}
@@ -233,6 +233,6 @@ func (r *SecretsReflector) isAllowed(_ context.Context, obj interface{}) bool {
 		return false
 	}
 	// if this annotation is set, this secret will not be reflected to the remote cluster
-	val, ok := sec.Annotations["liqo.io/not-reflect"]
+	val, ok := sec.Annotations[apimgmt.NotReflectAnnotation]
 	return !ok || val != "true"
 }
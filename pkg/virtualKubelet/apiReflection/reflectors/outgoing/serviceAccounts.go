@@ -0,0 +1,226 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// defaultServiceAccountName is the name of the ServiceAccount automatically created in every namespace.
+const defaultServiceAccountName = "default"
+
+// ServiceAccountsReflector reflects the ServiceAccounts of the offloaded namespaces, so that the offloaded pods
+// can run with the same identity and the foreign kubelet can mount the corresponding projected tokens.
+type ServiceAccountsReflector struct {
+	ri.APIReflector
+}
+
+func (r *ServiceAccountsReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		IsAllowed:  r.isAllowed,
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete})
+}
+
+func (r *ServiceAccountsReflector) HandleEvent(e interface{}) {
+	event := e.(watch.Event)
+	sa, ok := event.Object.(*corev1.ServiceAccount)
+	if !ok {
+		klog.Error("REFLECTION: cannot cast object to ServiceAccount")
+		return
+	}
+	klog.V(3).Infof("REFLECTION: received %v for ServiceAccount %v/%v", event.Type, sa.Namespace, sa.Name)
+
+	switch event.Type {
+	case watch.Added:
+		_, err := r.GetForeignClient().CoreV1().ServiceAccounts(sa.Namespace).Create(context.TODO(), sa, metav1.CreateOptions{})
+		if kerrors.IsAlreadyExists(err) {
+			klog.V(4).Infof("REFLECTION: The remote ServiceAccount %v/%v has not been created because already existing", sa.Namespace, sa.Name)
+			break
+		}
+		if err != nil {
+			klog.Errorf("REFLECTION: Error while creating the remote ServiceAccount %v/%v - ERR: %v", sa.Namespace, sa.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote ServiceAccount %v/%v correctly created", sa.Namespace, sa.Name)
+		}
+
+	case watch.Modified:
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, newErr := r.GetForeignClient().CoreV1().ServiceAccounts(sa.Namespace).Update(context.TODO(), sa, metav1.UpdateOptions{})
+			return newErr
+		}); err != nil {
+			klog.Errorf("REFLECTION: Error while updating the remote ServiceAccount %v/%v - ERR: %v", sa.Namespace, sa.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote ServiceAccount %v/%v correctly updated", sa.Namespace, sa.Name)
+		}
+
+	case watch.Deleted:
+		if err := r.GetForeignClient().CoreV1().ServiceAccounts(sa.Namespace).Delete(context.TODO(), sa.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("REFLECTION: Error while deleting the remote ServiceAccount %v/%v - ERR: %v", sa.Namespace, sa.Name, err)
+		} else {
+			klog.V(3).Infof("REFLECTION: remote ServiceAccount %v/%v correctly deleted", sa.Namespace, sa.Name)
+		}
+	}
+}
+
+func (r *ServiceAccountsReflector) CleanupNamespace(localNamespace string) {
+	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.ServiceAccounts, foreignNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting serviceaccount because of- ERR; %v", err)
+			return true
+		}
+	}
+	for _, obj := range objects {
+		sa := obj.(*corev1.ServiceAccount)
+		// the default ServiceAccount is managed by the foreign control plane.
+		if sa.Name == defaultServiceAccountName {
+			continue
+		}
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().CoreV1().ServiceAccounts(foreignNamespace).Delete(context.TODO(), sa.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote serviceaccount %v/%v", sa.Namespace, sa.Name)
+		}
+	}
+}
+
+func (r *ServiceAccountsReflector) PreAdd(obj interface{}) (interface{}, watch.EventType) {
+	saLocal := obj.(*corev1.ServiceAccount)
+	klog.V(3).Infof("PreAdd routine started for serviceaccount %v/%v", saLocal.Namespace, saLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(saLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Added
+	}
+
+	// the token secrets are not reflected in the ServiceAccount, since they are generated by the foreign control plane.
+	saRemote := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        saLocal.Name,
+			Namespace:   nattedNs,
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		AutomountServiceAccountToken: saLocal.AutomountServiceAccountToken,
+		// the image pull secrets are reflected with the same name, hence they can be referenced as they are.
+		ImagePullSecrets: saLocal.ImagePullSecrets,
+	}
+
+	for k, v := range saLocal.Annotations {
+		saRemote.Annotations[k] = v
+	}
+	for k, v := range saLocal.Labels {
+		saRemote.Labels[k] = v
+	}
+	saRemote.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	klog.V(3).Infof("PreAdd routine completed for serviceaccount %v/%v", saLocal.Namespace, saLocal.Name)
+	return saRemote, watch.Added
+}
+
+func (r *ServiceAccountsReflector) PreUpdate(newObj, _ interface{}) (interface{}, watch.EventType) {
+	newHomeSa := newObj.(*corev1.ServiceAccount).DeepCopy()
+
+	klog.V(3).Infof("PreUpdate routine started for serviceaccount %v/%v", newHomeSa.Namespace, newHomeSa.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(newHomeSa.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+
+	oldForeignObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.ServiceAccounts, nattedNs, newHomeSa.Name)
+	if err != nil {
+		err = errors.Wrapf(err, "serviceaccount %v/%v", nattedNs, newHomeSa.Name)
+		klog.Error(err)
+		return nil, watch.Modified
+	}
+	foreignSa := oldForeignObj.(*corev1.ServiceAccount).DeepCopy()
+
+	if foreignSa.Labels == nil {
+		foreignSa.Labels = make(map[string]string)
+	}
+	for k, v := range newHomeSa.Labels {
+		foreignSa.Labels[k] = v
+	}
+	foreignSa.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+
+	if foreignSa.Annotations == nil {
+		foreignSa.Annotations = make(map[string]string)
+	}
+	for k, v := range newHomeSa.Annotations {
+		foreignSa.Annotations[k] = v
+	}
+
+	foreignSa.AutomountServiceAccountToken = newHomeSa.AutomountServiceAccountToken
+	foreignSa.ImagePullSecrets = newHomeSa.ImagePullSecrets
+
+	klog.V(3).Infof("PreUpdate routine completed for serviceaccount %v/%v", newHomeSa.Namespace, newHomeSa.Name)
+	return foreignSa, watch.Modified
+}
+
+func (r *ServiceAccountsReflector) PreDelete(obj interface{}) (interface{}, watch.EventType) {
+	saLocal := obj.(*corev1.ServiceAccount).DeepCopy()
+	klog.V(3).Infof("PreDelete routine started for serviceaccount %v/%v", saLocal.Namespace, saLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(saLocal.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Deleted
+	}
+	saLocal.Namespace = nattedNs
+
+	klog.V(3).Infof("PreDelete routine completed for serviceaccount %v/%v", saLocal.Namespace, saLocal.Name)
+	return saLocal, watch.Deleted
+}
+
+func (r *ServiceAccountsReflector) isAllowed(_ context.Context, obj interface{}) bool {
+	sa, ok := obj.(*corev1.ServiceAccount)
+	if !ok {
+		klog.Error("cannot convert obj to serviceaccount")
+		return false
+	}
+	// the default ServiceAccount already exists in the foreign namespace, hence it is not reflected.
+	return sa.Name != defaultServiceAccountName
+}
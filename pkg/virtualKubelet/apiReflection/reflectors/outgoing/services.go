@@ -66,10 +66,17 @@ func (r *ServicesReflector) HandleEvent(e interface{}) {
 		}
 
 	case watch.Modified:
-		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 			_, newErr := r.GetForeignClient().CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
 			return newErr
-		}); err != nil {
+		})
+		if kerrors.IsInvalid(err) {
+			// some fields (e.g. the cluster IP when switching from/to a headless service) are immutable,
+			// hence the remote service needs to be recreated to match the home one.
+			klog.V(3).Infof("REFLECTION: the remote service %v/%v cannot be updated, recreating it - ERR: %v", svc.Namespace, svc.Name, err)
+			err = r.recreateRemoteService(svc)
+		}
+		if err != nil {
 			klog.Errorf("REFLECTION: Error while updating the remote service %v/%v - ERR: %v", svc.Namespace, svc.Name, err)
 		} else {
 			klog.V(3).Infof("REFLECTION: remote service %v/%v correctly updated", svc.Namespace, svc.Name)
@@ -84,6 +91,30 @@ func (r *ServicesReflector) HandleEvent(e interface{}) {
 	}
 }
 
+// recreateRemoteService deletes the remote service and creates it again, resetting the fields allocated by the
+// foreign cluster, which are not compatible with the new specification.
+func (r *ServicesReflector) recreateRemoteService(svc *corev1.Service) error {
+	client := r.GetForeignClient().CoreV1().Services(svc.Namespace)
+	if err := client.Delete(context.TODO(), svc.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	svc = svc.DeepCopy()
+	svc.ResourceVersion = ""
+	svc.UID = ""
+	svc.Spec.HealthCheckNodePort = 0
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		svc.Spec.ClusterIP = ""
+		svc.Spec.ClusterIPs = nil
+	}
+	for i := range svc.Spec.Ports {
+		svc.Spec.Ports[i].NodePort = 0
+	}
+
+	_, err := client.Create(context.TODO(), svc, metav1.CreateOptions{})
+	return err
+}
+
 func (r *ServicesReflector) CleanupNamespace(localNamespace string) {
 	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace)
 	if err != nil {
@@ -120,35 +151,18 @@ func (r *ServicesReflector) PreAdd(obj interface{}) (interface{}, watch.EventTyp
 	svcLocal := obj.(*corev1.Service)
 	klog.V(3).Infof("PreAdd routine started for service %v/%v", svcLocal.Namespace, svcLocal.Name)
 
-	nattedNs, err := r.NattingTable().NatNamespace(svcLocal.Namespace)
+	svcRemote, err := forge.HomeToForeign(svcLocal, nil, forge.LiqoOutgoingKey)
 	if err != nil {
 		klog.Error(err)
 		return nil, watch.Added
 	}
 
-	svcRemote := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      svcLocal.Name,
-			Namespace: nattedNs,
-			Labels:    make(map[string]string),
-		},
-		Spec: corev1.ServiceSpec{
-			Ports:    svcLocal.Spec.Ports,
-			Selector: svcLocal.Spec.Selector,
-			Type:     svcLocal.Spec.Type,
-		},
-	}
-	for k, v := range svcLocal.Labels {
-		svcRemote.Labels[k] = v
-	}
-	svcRemote.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
-
 	klog.V(3).Infof("PreAdd routine completed for service %v/%v", svcLocal.Namespace, svcLocal.Name)
 	return svcRemote, watch.Added
 }
 
 func (r *ServicesReflector) PreUpdate(newObj interface{}, _ interface{}) (interface{}, watch.EventType) {
-	newSvc := newObj.(*corev1.Service).DeepCopy()
+	newSvc := newObj.(*corev1.Service)
 	newSvcName := newSvc.Name
 
 	nattedNs, err := r.NattingTable().NatNamespace(newSvc.Namespace)
@@ -163,27 +177,20 @@ func (r *ServicesReflector) PreUpdate(newObj interface{}, _ interface{}) (interf
 		klog.Error(err)
 		return nil, watch.Modified
 	}
-	foreignSvc := oldRemoteObj.(*corev1.Service).DeepCopy()
+	oldRemoteSvc := oldRemoteObj.(*corev1.Service)
 
-	if foreignSvc.Labels == nil {
-		foreignSvc.Labels = make(map[string]string)
-	}
-	for k, v := range newSvc.Labels {
-		foreignSvc.Labels[k] = v
+	foreignObj, err := forge.HomeToForeign(newSvc, oldRemoteSvc, forge.LiqoOutgoingKey)
+	if err != nil {
+		klog.Error(err)
+		return nil, watch.Modified
 	}
-	foreignSvc.Labels[forge.LiqoOutgoingKey] = forge.LiqoNodeName()
+	foreignSvc := foreignObj.(*corev1.Service)
 
-	if foreignSvc.Annotations == nil {
-		foreignSvc.Annotations = make(map[string]string)
-	}
-	for k, v := range newSvc.Annotations {
-		foreignSvc.Annotations[k] = v
+	if isUpToDate(foreignSvc, oldRemoteSvc, foreignSvc.Spec, oldRemoteSvc.Spec) {
+		klog.V(4).Infof("PreUpdate routine completed for service %v/%v: remote service already up-to-date", newSvc.Namespace, newSvcName)
+		return nil, watch.Modified
 	}
 
-	foreignSvc.Spec.Ports = newSvc.Spec.Ports
-	foreignSvc.Spec.Selector = newSvc.Spec.Selector
-	foreignSvc.Spec.Type = newSvc.Spec.Type
-
 	return foreignSvc, watch.Modified
 }
 
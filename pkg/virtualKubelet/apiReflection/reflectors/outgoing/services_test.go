@@ -0,0 +1,196 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+func TestServiceAdd(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	forge.InitForger(nattingTable, types.NewNetworkingOption(types.RemoteClusterID, "foreign-id"))
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &ServicesReflector{
+		APIReflector: Greflector,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	svc := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:            v1.ServiceTypeClusterIP,
+			ClusterIP:       v1.ClusterIPNone,
+			ClusterIPs:      []string{v1.ClusterIPNone},
+			Selector:        map[string]string{"app": "nginx"},
+			Ports:           []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+			SessionAffinity: v1.ServiceAffinityNone,
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, event := reflector.PreProcessAdd(&svc)
+	postadd := pa.(*v1.Service)
+
+	assert.Equal(t, event, watch.Added)
+	assert.Equal(t, postadd.Namespace, "homeNamespace-natted", "Asserting namespace natting")
+	assert.Equal(t, postadd.Spec.ClusterIP, v1.ClusterIPNone, "Asserting the service is still headless")
+	assert.Equal(t, postadd.Labels["app"], "nginx")
+}
+
+func TestServiceUpdate(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	forge.InitForger(nattingTable, types.NewNetworkingOption(types.RemoteClusterID, "foreign-id"))
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &ServicesReflector{
+		APIReflector: Greflector,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	svc := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:            v1.ServiceTypeClusterIP,
+			ClusterIP:       v1.ClusterIPNone,
+			ClusterIPs:      []string{v1.ClusterIPNone},
+			Selector:        map[string]string{"app": "nginx"},
+			Ports:           []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+			SessionAffinity: v1.ServiceAffinityNone,
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&svc)
+	remote := pa.(*v1.Service)
+	remote.ResourceVersion = "1"
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.Services, remote)
+
+	pu, _ := reflector.PreProcessUpdate(svc.DeepCopy(), &svc)
+	assert.Assert(t, pu == nil, "Asserting the up-to-date remote service is not updated")
+
+	updated := svc.DeepCopy()
+	updated.Spec.PublishNotReadyAddresses = true
+	pu, event := reflector.PreProcessUpdate(updated, &svc)
+	assert.Equal(t, event, watch.Modified)
+	postupdate := pu.(*v1.Service)
+	assert.Assert(t, postupdate.Spec.PublishNotReadyAddresses)
+	assert.Equal(t, postupdate.ResourceVersion, "1")
+}
+
+func TestServiceUpdateImmutableField(t *testing.T) {
+	existing := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace-natted"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeClusterIP, ClusterIP: "10.0.0.1", ClusterIPs: []string{"10.0.0.1"}},
+	}
+	foreignClient := fake.NewSimpleClientset(existing)
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	forge.InitForger(nattingTable, types.NewNetworkingOption(types.RemoteClusterID, "foreign-id"))
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &ServicesReflector{
+		APIReflector: Greflector,
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	foreignClient.PrependReactor("update", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerrors.NewInvalid(schema.GroupKind{Kind: "Service"}, "name",
+			field.ErrorList{field.Invalid(field.NewPath("spec", "clusterIP"), v1.ClusterIPNone, "field is immutable")})
+	})
+
+	svc := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "homeNamespace",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:            v1.ServiceTypeClusterIP,
+			ClusterIP:       v1.ClusterIPNone,
+			ClusterIPs:      []string{v1.ClusterIPNone},
+			Selector:        map[string]string{"app": "nginx"},
+			Ports:           []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+			SessionAffinity: v1.ServiceAffinityNone,
+		},
+	}
+
+	nattingTable.NewNamespace("homeNamespace")
+
+	pa, _ := reflector.PreProcessAdd(&svc)
+	desired := pa.(*v1.Service)
+	reflector.HandleEvent(watch.Event{Type: watch.Modified, Object: desired})
+
+	remote, err := foreignClient.CoreV1().Services("homeNamespace-natted").Get(context.TODO(), "name", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, remote.Spec.ClusterIP, v1.ClusterIPNone, "Asserting the remote service has been recreated")
+}
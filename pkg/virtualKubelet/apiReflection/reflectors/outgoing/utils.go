@@ -0,0 +1,29 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outgoing
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isUpToDate returns whether the current foreign object already matches the desired one, in terms of labels,
+// annotations and spec. It allows to update the foreign objects only when they actually drifted from the desired
+// state, rather than at every modification of the home objects (e.g. due to status changes) and at every resync.
+func isUpToDate(desired, current metav1.Object, desiredSpec, currentSpec interface{}) bool {
+	return equality.Semantic.DeepEqual(desired.GetLabels(), current.GetLabels()) &&
+		equality.Semantic.DeepEqual(desired.GetAnnotations(), current.GetAnnotations()) &&
+		equality.Semantic.DeepEqual(desiredSpec, currentSpec)
+}
@@ -17,10 +17,12 @@ package forge
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -60,7 +62,8 @@ func HomeToForeign(homeObj, foreignObj runtime.Object, reflectionType string) (r
 	case *corev1.Pod:
 		return forger.podHomeToForeign(homeObj, foreignObj, reflectionType)
 	case *corev1.Service:
-		return forger.serviceHomeToForeign(homeObj.(*corev1.Service), foreignObj.(*corev1.Service))
+		foreignService, _ := foreignObj.(*corev1.Service)
+		return forger.serviceHomeToForeign(homeObj.(*corev1.Service), foreignService)
 	}
 
 	return nil, errors.Errorf("error while creating foreign object from home: api %s unhandled", reflect.TypeOf(homeObj).String())
@@ -70,6 +73,11 @@ func ReplicasetFromPod(pod *corev1.Pod) *appsv1.ReplicaSet {
 	return forger.replicasetFromPod(pod)
 }
 
+// JobFromPod returns the remote job in charge of executing the given (batch) pod to completion.
+func JobFromPod(pod *corev1.Pod) *batchv1.Job {
+	return forger.jobFromPod(pod)
+}
+
 func ForeignReplicasetDeleted(pod *corev1.Pod) *corev1.Pod {
 	return forger.setPodToBeDeleted(pod)
 }
@@ -81,6 +89,11 @@ type apiForger struct {
 	virtualNodeName  options.ReadOnlyOption
 	liqoIpamServer   options.ReadOnlyOption
 	offloadClusterID options.ReadOnlyOption
+
+	// deniedPodSpecFields contains the pod spec fields which are not reflected (see ValidateDeniedPodSpecFields).
+	deniedPodSpecFields []string
+	// reflectedVolumeTypes contains the volume types which are reflected (see ValidateReflectedVolumeTypes).
+	reflectedVolumeTypes []string
 }
 
 var forger apiForger
@@ -97,6 +110,16 @@ func InitForger(nattingTable namespacesmapping.NamespaceNatter, opts ...options.
 		case types.LiqoIpamServer:
 			forger.liqoIpamServer = opt
 			initIpamClient()
+		case types.DeniedPodSpecFields:
+			forger.deniedPodSpecFields = nil
+			if value := opt.Value().ToString(); value != "" {
+				forger.deniedPodSpecFields = strings.Split(value, ",")
+			}
+		case types.ReflectedVolumeTypes:
+			forger.reflectedVolumeTypes = []string{}
+			if value := opt.Value().ToString(); value != "" {
+				forger.reflectedVolumeTypes = strings.Split(value, ",")
+			}
 		}
 	}
 }
@@ -0,0 +1,105 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"math"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+)
+
+var (
+	defaultCompletions int32 = 1
+	defaultParallelism int32 = 1
+)
+
+// jobControllerLabels are the labels set by the job controller on the pods of a job, which refer to the home job:
+// they are not propagated to the remote job, whose selector is generated from the labels its own controller sets.
+var jobControllerLabels = []string{"controller-uid", "job-name"}
+
+// jobControllerLabelsPrefix is the prefix of the labels set by the job controller in the recent kubernetes versions.
+const jobControllerLabelsPrefix = "batch.kubernetes.io/"
+
+// IsBatchPod returns whether the given pod is expected to run to completion (i.e. it is owned by a job, or it is not
+// always restarted), hence it has to be offloaded through a remote job (see JobFromPod) rather than a replicaset,
+// which would restart it upon completion.
+func IsBatchPod(pod *corev1.Pod) bool {
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Job" {
+		return true
+	}
+	return pod.Spec.RestartPolicy == corev1.RestartPolicyNever || pod.Spec.RestartPolicy == corev1.RestartPolicyOnFailure
+}
+
+func (f *apiForger) jobFromPod(pod *corev1.Pod) *batchv1.Job {
+	labels := jobLabels(pod.Labels)
+	labels[virtualKubelet.ReflectedpodKey] = pod.Name
+
+	podSpec := *pod.Spec.DeepCopy()
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure {
+		podSpec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	forgeStableHostname(&podSpec, pod.Name)
+
+	// a failed pod is never retried by the remote job, since this is up to the home owner (if any), which observes the
+	// reflected status. Conversely, the container restarts of OnFailure pods shall not cause the remote job to fail.
+	var backoffLimit int32
+	if podSpec.RestartPolicy == corev1.RestartPolicyOnFailure {
+		backoffLimit = math.MaxInt32
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			Labels:      labels,
+			Annotations: pod.Annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  &defaultCompletions,
+			Parallelism:  &defaultParallelism,
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: pod.Annotations,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+
+	return job
+}
+
+// jobLabels returns the labels of the remote job and of its pod template, i.e. the ones of the home pod except
+// for the ones set by the home job controller, which would conflict with the selector generated for the remote job.
+func jobLabels(podLabels map[string]string) map[string]string {
+	labels := make(map[string]string, len(podLabels)+1)
+	for key, value := range podLabels {
+		if strings.HasPrefix(key, jobControllerLabelsPrefix) {
+			continue
+		}
+		labels[key] = value
+	}
+	for _, key := range jobControllerLabels {
+		delete(labels, key)
+	}
+	return labels
+}
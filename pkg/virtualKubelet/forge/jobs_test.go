@@ -0,0 +1,116 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+)
+
+func newTestBatchPod(name string, restartPolicy corev1.RestartPolicy, ownerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "homeNamespace-natted"},
+		Spec:       corev1.PodSpec{RestartPolicy: restartPolicy},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+func TestIsBatchPod(t *testing.T) {
+	assert.Assert(t, !IsBatchPod(newTestBatchPod("pod", "", "")))
+	assert.Assert(t, !IsBatchPod(newTestBatchPod("pod", corev1.RestartPolicyAlways, "ReplicaSet")))
+	assert.Assert(t, !IsBatchPod(newTestBatchPod("pod", corev1.RestartPolicyAlways, "StatefulSet")))
+	assert.Assert(t, IsBatchPod(newTestBatchPod("pod", corev1.RestartPolicyNever, "")))
+	assert.Assert(t, IsBatchPod(newTestBatchPod("pod", corev1.RestartPolicyOnFailure, "")))
+	assert.Assert(t, IsBatchPod(newTestBatchPod("pod", "", "Job")))
+}
+
+func TestJobFromPodNever(t *testing.T) {
+	initTestForger()
+
+	job := JobFromPod(newTestBatchPod("job-abcde", corev1.RestartPolicyNever, "Job"))
+	assert.Equal(t, job.Name, "job-abcde")
+	assert.Equal(t, job.Namespace, "homeNamespace-natted")
+	assert.Equal(t, job.Labels[virtualKubelet.ReflectedpodKey], "job-abcde")
+	assert.Equal(t, job.Spec.Template.Labels[virtualKubelet.ReflectedpodKey], "job-abcde")
+	assert.Assert(t, job.Spec.Selector == nil)
+	assert.Equal(t, *job.Spec.Completions, int32(1))
+	assert.Equal(t, *job.Spec.Parallelism, int32(1))
+	assert.Equal(t, *job.Spec.BackoffLimit, int32(0))
+	assert.Equal(t, job.Spec.Template.Spec.RestartPolicy, corev1.RestartPolicyNever)
+	assert.Equal(t, job.Spec.Template.Spec.Hostname, "job-abcde")
+}
+
+func TestJobFromPodJobOwned(t *testing.T) {
+	initTestForger()
+
+	pod := newTestBatchPod("job-abcde", corev1.RestartPolicyNever, "Job")
+	pod.Labels = map[string]string{
+		"app":                                "batch",
+		"controller-uid":                     "home-uid",
+		"job-name":                           "job",
+		"batch.kubernetes.io/controller-uid": "home-uid",
+		"batch.kubernetes.io/job-name":       "job",
+		"batch.kubernetes.io/job-completion-index": "0",
+	}
+
+	job := JobFromPod(pod)
+	expected := map[string]string{"app": "batch", virtualKubelet.ReflectedpodKey: "job-abcde"}
+	assert.DeepEqual(t, job.Labels, expected)
+	assert.DeepEqual(t, job.Spec.Template.Labels, expected)
+	assert.Assert(t, job.Spec.Selector == nil)
+	assert.Assert(t, job.Spec.ManualSelector == nil)
+	assert.Equal(t, pod.Labels["controller-uid"], "home-uid", "Asserting the home pod is not modified")
+}
+
+func TestJobFromPodOnFailure(t *testing.T) {
+	initTestForger()
+
+	job := JobFromPod(newTestBatchPod("job-abcde", corev1.RestartPolicyOnFailure, ""))
+	assert.Equal(t, job.Spec.Template.Spec.RestartPolicy, corev1.RestartPolicyOnFailure)
+	assert.Equal(t, *job.Spec.BackoffLimit, int32(math.MaxInt32))
+}
+
+func TestForgeStableHostname(t *testing.T) {
+	podSpec := corev1.PodSpec{}
+	forgeStableHostname(&podSpec, "web-0")
+	assert.Equal(t, podSpec.Hostname, "web-0")
+
+	podSpec = corev1.PodSpec{Hostname: "custom", Subdomain: "web"}
+	forgeStableHostname(&podSpec, "web-0")
+	assert.Equal(t, podSpec.Hostname, "custom")
+	assert.Equal(t, podSpec.Subdomain, "web")
+
+	podSpec = corev1.PodSpec{}
+	forgeStableHostname(&podSpec, strings.Repeat("a", 62)+"-b")
+	assert.Equal(t, podSpec.Hostname, strings.Repeat("a", 62))
+
+	podSpec = corev1.PodSpec{}
+	forgeStableHostname(&podSpec, "pod.with.dots")
+	assert.Equal(t, podSpec.Hostname, "")
+
+	rs := ReplicasetFromPod(newTestBatchPod("web-0", corev1.RestartPolicyAlways, "StatefulSet"))
+	assert.Equal(t, rs.Spec.Template.Spec.Hostname, "web-0")
+}
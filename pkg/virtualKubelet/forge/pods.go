@@ -22,6 +22,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 
 	liqoconst "github.com/liqotech/liqo/pkg/consts"
@@ -31,7 +32,16 @@ import (
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
 )
 
-const affinitySelector = liqoconst.TypeNode
+const (
+	affinitySelector = liqoconst.TypeNode
+
+	// liqoLabelsPrefix is the prefix of the labels set by liqo on the nodes, which identify the virtual nodes.
+	liqoLabelsPrefix = "liqo.io/"
+
+	// kubeAPIAccessVolumePrefix is the prefix of the projected volume automatically added by the ServiceAccount
+	// admission controller, which is added again by the foreign control plane.
+	kubeAPIAccessVolumePrefix = "kube-api-access-"
+)
 
 func (f *apiForger) podForeignToHome(foreignObj, homeObj runtime.Object, reflectionType string) (*corev1.Pod, error) {
 	var isNewObject bool
@@ -130,22 +140,58 @@ func (f *apiForger) podHomeToForeign(homeObj, foreignObj runtime.Object, reflect
 	f.forgeForeignMeta(&homePod.ObjectMeta, &foreignPod.ObjectMeta, foreignNamespace, reflectionType)
 
 	if isNewObject {
+		if err := f.checkVolumes(homePod.Spec.Volumes); err != nil {
+			return nil, err
+		}
 		foreignPod.Spec = f.forgePodSpec(homePod.Spec)
-		foreignPod.Spec.Affinity = forgeAffinity()
+		foreignPod.Spec.Affinity = f.forgeAffinity(homePod.Spec.Affinity)
 	}
 
 	return foreignPod, nil
 }
 
-func (f *apiForger) forgePodSpec(inputPodSpec corev1.PodSpec) corev1.PodSpec {
-	outputPodSpec := corev1.PodSpec{}
+// forgeStableHostname sets the hostname of the given pod spec to the name of the home pod (i.e. the hostname the pod
+// would have had if executed locally), unless already specified. Indeed, the remote pods are created by a controller,
+// and they would otherwise get the generated name as hostname. Names which are not valid hostnames are truncated
+// as the kubelet does, and ignored if still invalid.
+func forgeStableHostname(podSpec *corev1.PodSpec, homePodName string) {
+	if podSpec.Hostname != "" {
+		return
+	}
 
-	outputPodSpec.TerminationGracePeriodSeconds = inputPodSpec.TerminationGracePeriodSeconds
-	outputPodSpec.Volumes = forgeVolumes(inputPodSpec.Volumes)
-	outputPodSpec.InitContainers = forgeContainers(inputPodSpec.InitContainers, outputPodSpec.Volumes)
-	outputPodSpec.Containers = forgeContainers(inputPodSpec.Containers, outputPodSpec.Volumes)
+	hostname := homePodName
+	if len(hostname) > validation.DNS1123LabelMaxLength {
+		hostname = strings.TrimRight(hostname[:validation.DNS1123LabelMaxLength], "-.")
+	}
+	if len(validation.IsDNS1123Label(hostname)) == 0 {
+		podSpec.Hostname = hostname
+	}
+}
+
+// forgePodSpec returns a copy of the given pod spec, which preserves all the fields except for the ones that are
+// meaningless (or rejected) in the other cluster, and for the ones configured as denied.
+func (f *apiForger) forgePodSpec(inputPodSpec corev1.PodSpec) corev1.PodSpec {
+	outputPodSpec := *inputPodSpec.DeepCopy()
+
+	// the pod is scheduled by the scheduler of the other cluster, on nodes different from the original ones.
+	// The affinity is forged separately, since it has to be merged with the anti-affinity for the virtual nodes.
+	outputPodSpec.NodeName = ""
+	outputPodSpec.Affinity = nil
+	// the priority, the preemption policy and the overhead are computed by the admission controllers starting from
+	// the priority and runtime classes, and they are rejected if not matching the values computed in the other cluster.
+	outputPodSpec.Priority = nil
+	outputPodSpec.PreemptionPolicy = nil
+	outputPodSpec.Overhead = nil
+	// ephemeral containers cannot be specified at creation time.
+	outputPodSpec.EphemeralContainers = nil
+
+	outputPodSpec.Volumes = f.forgeVolumes(inputPodSpec.Volumes, inputPodSpec.ServiceAccountName)
+	outputPodSpec.InitContainers = forgeContainers(outputPodSpec.InitContainers, outputPodSpec.Volumes)
+	outputPodSpec.Containers = forgeContainers(outputPodSpec.Containers, outputPodSpec.Volumes)
 	outputPodSpec.Tolerations = forgeTolerations(inputPodSpec.Tolerations)
 
+	f.denyPodSpecFields(&outputPodSpec)
+
 	return outputPodSpec
 }
 
@@ -167,45 +213,60 @@ func forgeContainers(inputContainers []corev1.Container, inputVolumes []corev1.V
 	containers := make([]corev1.Container, 0)
 
 	for _, container := range inputContainers {
-		volumeMounts := filterVolumeMounts(inputVolumes, container.VolumeMounts)
-		containers = append(containers, translateContainer(container, volumeMounts))
+		containers = append(containers, translateContainer(container, filterVolumeMounts(inputVolumes, container.VolumeMounts)))
 	}
 
 	return containers
 }
 
+// translateContainer returns a copy of the given container, mounting only the given volumes.
 func translateContainer(container corev1.Container, volumes []corev1.VolumeMount) corev1.Container {
-	return corev1.Container{
-		Name:            container.Name,
-		Image:           container.Image,
-		Command:         container.Command,
-		Args:            container.Args,
-		WorkingDir:      container.WorkingDir,
-		Ports:           container.Ports,
-		Env:             container.Env,
-		Resources:       container.Resources,
-		LivenessProbe:   container.LivenessProbe,
-		ReadinessProbe:  container.ReadinessProbe,
-		StartupProbe:    container.StartupProbe,
-		SecurityContext: container.SecurityContext,
-		VolumeMounts:    volumes,
-	}
-}
-
-func forgeVolumes(volumesIn []corev1.Volume) []corev1.Volume {
+	output := *container.DeepCopy()
+	output.VolumeMounts = volumes
+	return output
+}
+
+// forgeVolumes returns the volumes of the types configured as reflected, which are copied as they are, except for the
+// ones mounting the ServiceAccount token, since the foreign control plane mounts the one of the reflected ServiceAccount.
+// Claims, config maps and secrets are reflected with the same name in the foreign namespace, while the ServiceAccount
+// token sources of projected volumes are served by the foreign kubelet for the reflected ServiceAccount.
+func (f *apiForger) forgeVolumes(volumesIn []corev1.Volume, serviceAccountName string) []corev1.Volume {
 	volumesOut := make([]corev1.Volume, 0)
-	for _, v := range volumesIn {
-		if v.ConfigMap != nil || v.EmptyDir != nil || v.DownwardAPI != nil {
-			volumesOut = append(volumesOut, v)
+	for i := range volumesIn {
+		v := &volumesIn[i]
+		if !f.isReflectedVolumeType(volumeType(v)) {
+			continue
 		}
-		// copy all volumes of type Secret except for the default token
-		if v.Secret != nil && !strings.Contains(v.Secret.SecretName, "default-token") {
-			volumesOut = append(volumesOut, v)
+		if v.Secret != nil && isServiceAccountTokenSecret(v.Secret.SecretName, serviceAccountName) {
+			continue
 		}
+		if v.Projected != nil && strings.HasPrefix(v.Name, kubeAPIAccessVolumePrefix) {
+			continue
+		}
+		volumesOut = append(volumesOut, *v)
 	}
 	return volumesOut
 }
 
+// checkVolumes returns an error if any of the given volumes is of a type not configured as reflected, since the pod
+// would otherwise be started in the foreign cluster without it. The error is surfaced as an event of the home pod.
+func (f *apiForger) checkVolumes(volumes []corev1.Volume) error {
+	for i := range volumes {
+		if t := volumeType(&volumes[i]); !f.isReflectedVolumeType(t) {
+			return fmt.Errorf("volume %q of type %q cannot be reflected to the foreign cluster", volumes[i].Name, t)
+		}
+	}
+	return nil
+}
+
+// isServiceAccountTokenSecret returns whether the given secret is a token generated for the given ServiceAccount.
+func isServiceAccountTokenSecret(secretName, serviceAccountName string) bool {
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+	return strings.HasPrefix(secretName, serviceAccountName+"-token-")
+}
+
 // remove from volumeMountsIn all the volumeMounts with name not contained in volumes.
 func filterVolumeMounts(volumes []corev1.Volume, volumeMountsIn []corev1.VolumeMount) []corev1.VolumeMount {
 	volumeMounts := make([]corev1.VolumeMount, 0)
@@ -219,22 +280,87 @@ func filterVolumeMounts(volumes []corev1.Volume, volumeMountsIn []corev1.VolumeM
 	return volumeMounts
 }
 
-func forgeAffinity() *corev1.Affinity {
-	return &corev1.Affinity{
-		NodeAffinity: &corev1.NodeAffinity{
-			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-				NodeSelectorTerms: []corev1.NodeSelectorTerm{
-					{
-						MatchExpressions: []corev1.NodeSelectorRequirement{
-							{
-								Key:      liqoconst.TypeLabel,
-								Operator: corev1.NodeSelectorOpNotIn,
-								Values:   []string{affinitySelector},
-							},
-						},
-					},
-				},
-			},
-		},
+// forgeAffinity returns the affinity of the foreign pod, merging the one of the home pod (unless denied) with the
+// anti-affinity for the virtual nodes, which prevents the "recursive" scheduling on virtual nodes in the foreign cluster.
+// The node requirements referring to the virtual node or to the home nodes (i.e. the liqo labels, the hostname and the
+// node fields) are removed, since meaningless in the foreign cluster, while the namespaces of the pod (anti-)affinity
+// terms are translated into the corresponding foreign ones.
+func (f *apiForger) forgeAffinity(homeAffinity *corev1.Affinity) *corev1.Affinity {
+	affinity := &corev1.Affinity{}
+	if homeAffinity != nil && !f.isDeniedPodSpecField("affinity") {
+		affinity = homeAffinity.DeepCopy()
+	}
+
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		required = &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{}}}
+	}
+	// the terms are ORed, hence the anti-affinity for the virtual nodes is added to each of them.
+	for i := range required.NodeSelectorTerms {
+		term := &required.NodeSelectorTerms[i]
+		term.MatchExpressions = append(forgeNodeSelectorRequirements(term.MatchExpressions), corev1.NodeSelectorRequirement{
+			Key:      liqoconst.TypeLabel,
+			Operator: corev1.NodeSelectorOpNotIn,
+			Values:   []string{affinitySelector},
+		})
+		term.MatchFields = nil
+	}
+	affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = required
+
+	var preferred []corev1.PreferredSchedulingTerm
+	for _, term := range affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		term.Preference.MatchExpressions = forgeNodeSelectorRequirements(term.Preference.MatchExpressions)
+		term.Preference.MatchFields = nil
+		if len(term.Preference.MatchExpressions) > 0 {
+			preferred = append(preferred, term)
+		}
+	}
+	affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = preferred
+
+	if affinity.PodAffinity != nil {
+		f.forgePodAffinityTerms(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+	if affinity.PodAntiAffinity != nil {
+		f.forgePodAffinityTerms(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+
+	return affinity
+}
+
+// forgeNodeSelectorRequirements returns the given node requirements, except for the ones referring to the virtual node
+// or to the home nodes.
+func forgeNodeSelectorRequirements(requirements []corev1.NodeSelectorRequirement) []corev1.NodeSelectorRequirement {
+	var output []corev1.NodeSelectorRequirement
+	for _, requirement := range requirements {
+		if strings.HasPrefix(requirement.Key, liqoLabelsPrefix) || requirement.Key == corev1.LabelHostname {
+			continue
+		}
+		output = append(output, requirement)
+	}
+	return output
+}
+
+// forgePodAffinityTerms translates in place the namespaces of the given pod (anti-)affinity terms into the foreign ones.
+// The namespaces which are not offloaded are preserved as they are.
+func (f *apiForger) forgePodAffinityTerms(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) {
+	terms := make([]*corev1.PodAffinityTerm, 0, len(required)+len(preferred))
+	for i := range required {
+		terms = append(terms, &required[i])
+	}
+	for i := range preferred {
+		terms = append(terms, &preferred[i].PodAffinityTerm)
+	}
+
+	for _, term := range terms {
+		for i, namespace := range term.Namespaces {
+			if natted, err := f.nattingTable.NatNamespace(namespace); err == nil {
+				term.Namespaces[i] = natted
+			}
+		}
 	}
 }
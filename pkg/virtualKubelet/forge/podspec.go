@@ -0,0 +1,164 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerFieldPrefix is the prefix identifying the fields of the containers in the list of the denied pod spec fields.
+const ContainerFieldPrefix = "containers."
+
+// DefaultDeniedPodSpecFields contains the pod spec fields which are not reflected by default, since they refer to
+// the nodes of the originating cluster, or they grant access to the namespaces of the hosting node.
+var DefaultDeniedPodSpecFields = []string{
+	"nodeSelector",
+	"schedulerName",
+	"readinessGates",
+	"hostNetwork",
+	"hostPID",
+	"hostIPC",
+}
+
+// DefaultReflectedVolumeTypes contains the volume types (i.e. the JSON names of the volume sources) reflected by default,
+// since they refer to no external resource, or to resources which are reflected to the foreign cluster.
+var DefaultReflectedVolumeTypes = []string{
+	"configMap",
+	"secret",
+	"emptyDir",
+	"downwardAPI",
+	"projected",
+	"persistentVolumeClaim",
+}
+
+// requiredPodSpecFields contains the pod spec fields which cannot be denied, since required to create a valid pod.
+var requiredPodSpecFields = []string{
+	"containers",
+	ContainerFieldPrefix + "name",
+	ContainerFieldPrefix + "image",
+}
+
+// ValidateDeniedPodSpecFields checks that each of the given fields identifies, through its JSON name, either a field of the
+// pod spec (e.g. "hostAliases") or a field of its containers prefixed by ContainerFieldPrefix (e.g. "containers.lifecycle").
+func ValidateDeniedPodSpecFields(fields []string) error {
+	for _, field := range fields {
+		for _, required := range requiredPodSpecFields {
+			if field == required {
+				return errors.Errorf("pod spec field %q cannot be denied", field)
+			}
+		}
+
+		obj, name := splitDeniedPodSpecField(field)
+		if _, found := jsonFieldIndex(obj.Type(), name); !found {
+			return errors.Errorf("unknown pod spec field %q", field)
+		}
+	}
+	return nil
+}
+
+// ValidateReflectedVolumeTypes checks that each of the given types identifies, through its JSON name, a volume source
+// (e.g. "configMap").
+func ValidateReflectedVolumeTypes(volumeTypes []string) error {
+	for _, volumeType := range volumeTypes {
+		if _, found := jsonFieldIndex(reflect.TypeOf(corev1.VolumeSource{}), volumeType); !found {
+			return errors.Errorf("unknown volume type %q", volumeType)
+		}
+	}
+	return nil
+}
+
+// isDeniedPodSpecField returns whether the given pod spec field is configured as denied.
+func (f *apiForger) isDeniedPodSpecField(field string) bool {
+	for _, denied := range f.deniedPodSpecFields {
+		if denied == field {
+			return true
+		}
+	}
+	return false
+}
+
+// isReflectedVolumeType returns whether the volumes of the given type are reflected, according to the configuration,
+// or to DefaultReflectedVolumeTypes if not configured.
+func (f *apiForger) isReflectedVolumeType(volumeType string) bool {
+	reflected := f.reflectedVolumeTypes
+	if reflected == nil {
+		reflected = DefaultReflectedVolumeTypes
+	}
+	for _, t := range reflected {
+		if t == volumeType {
+			return true
+		}
+	}
+	return false
+}
+
+// volumeType returns the type of the given volume, i.e. the JSON name of its source.
+func volumeType(volume *corev1.Volume) string {
+	source := reflect.ValueOf(volume.VolumeSource)
+	for i := 0; i < source.NumField(); i++ {
+		if !source.Field(i).IsNil() {
+			return strings.Split(source.Type().Field(i).Tag.Get("json"), ",")[0]
+		}
+	}
+	return ""
+}
+
+// denyPodSpecFields resets to their zero value the fields of the given pod spec (and of its containers) configured as denied.
+func (f *apiForger) denyPodSpecFields(podSpec *corev1.PodSpec) {
+	for _, field := range f.deniedPodSpecFields {
+		if !strings.HasPrefix(field, ContainerFieldPrefix) {
+			clearJSONField(reflect.ValueOf(podSpec).Elem(), field)
+			continue
+		}
+
+		name := strings.TrimPrefix(field, ContainerFieldPrefix)
+		for i := range podSpec.InitContainers {
+			clearJSONField(reflect.ValueOf(&podSpec.InitContainers[i]).Elem(), name)
+		}
+		for i := range podSpec.Containers {
+			clearJSONField(reflect.ValueOf(&podSpec.Containers[i]).Elem(), name)
+		}
+	}
+}
+
+// splitDeniedPodSpecField returns the object the given denied field refers to, and the JSON name of the field.
+func splitDeniedPodSpecField(field string) (obj reflect.Value, name string) {
+	if strings.HasPrefix(field, ContainerFieldPrefix) {
+		return reflect.ValueOf(corev1.Container{}), strings.TrimPrefix(field, ContainerFieldPrefix)
+	}
+	return reflect.ValueOf(corev1.PodSpec{}), field
+}
+
+// clearJSONField resets to its zero value the field of the given struct with the given JSON name, if any.
+func clearJSONField(obj reflect.Value, name string) {
+	if index, found := jsonFieldIndex(obj.Type(), name); found {
+		field := obj.Field(index)
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
+
+// jsonFieldIndex returns the index of the field of the given struct type with the given JSON name.
+func jsonFieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,252 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	liqoconst "github.com/liqotech/liqo/pkg/consts"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+)
+
+func initTestForger(deniedFields ...string) {
+	initTestForgerWithVolumeTypes(DefaultReflectedVolumeTypes, deniedFields...)
+}
+
+func initTestForgerWithVolumeTypes(volumeTypes []string, deniedFields ...string) {
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{"homeNamespace": "homeNamespace-natted"}}
+	InitForger(nattingTable,
+		types.NewNetworkingOption(types.RemoteClusterID, "foreign-id"),
+		types.NewReflectionOption(types.DeniedPodSpecFields, types.ReflectionValue(strings.Join(deniedFields, ","))),
+		types.NewReflectionOption(types.ReflectedVolumeTypes, types.ReflectionValue(strings.Join(volumeTypes, ","))))
+}
+
+func newTestPodSpec() corev1.PodSpec {
+	container := corev1.Container{
+		Name:            "container",
+		Image:           "nginx:latest",
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		EnvFrom: []corev1.EnvFromSource{{
+			ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "cm"}},
+		}},
+		Lifecycle:                &corev1.Lifecycle{PreStop: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"sleep", "5"}}}},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		TerminationMessagePath:   "/dev/termination-log",
+		Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("100m"),
+		}},
+		VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		Stdin:        true,
+		TTY:          true,
+	}
+
+	return corev1.PodSpec{
+		Volumes: []corev1.Volume{{
+			Name:         "data",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}},
+		InitContainers:                []corev1.Container{*container.DeepCopy()},
+		Containers:                    []corev1.Container{container},
+		RestartPolicy:                 corev1.RestartPolicyAlways,
+		TerminationGracePeriodSeconds: pointer.Int64Ptr(30),
+		ActiveDeadlineSeconds:         pointer.Int64Ptr(300),
+		DNSPolicy:                     corev1.DNSClusterFirst,
+		DNSConfig:                     &corev1.PodDNSConfig{Nameservers: []string{"1.1.1.1"}},
+		ServiceAccountName:            "sa",
+		AutomountServiceAccountToken:  pointer.BoolPtr(true),
+		SecurityContext:               &corev1.PodSecurityContext{RunAsUser: pointer.Int64Ptr(1000)},
+		ImagePullSecrets:              []corev1.LocalObjectReference{{Name: "pull-secret"}},
+		Hostname:                      "hostname",
+		Subdomain:                     "subdomain",
+		HostAliases:                   []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"foo.bar"}}},
+		PriorityClassName:             "high-priority",
+		EnableServiceLinks:            pointer.BoolPtr(false),
+		ShareProcessNamespace:         pointer.BoolPtr(true),
+		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		}},
+		Tolerations: []corev1.Toleration{{Key: "key", Operator: corev1.TolerationOpExists}},
+	}
+}
+
+func TestForgePodSpecRoundTrip(t *testing.T) {
+	initTestForger()
+
+	homePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "homeNamespace"},
+		Spec:       newTestPodSpec(),
+	}
+
+	foreignObj, err := HomeToForeign(homePod, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignPod := foreignObj.(*corev1.Pod)
+	assert.Equal(t, foreignPod.Namespace, "homeNamespace-natted")
+	assert.DeepEqual(t, foreignPod.Spec.Affinity, forger.forgeAffinity(nil))
+
+	homeObj, err := ForeignToHome(foreignPod, nil, LiqoIncomingKey)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, homeObj.(*corev1.Pod).Spec, homePod.Spec)
+}
+
+func TestForgePodSpecTransformedFields(t *testing.T) {
+	initTestForger()
+
+	input := newTestPodSpec()
+	input.NodeName = "virtual-node"
+	input.Priority = pointer.Int32Ptr(1000)
+	input.EphemeralContainers = []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug"}}}
+	input.Tolerations = append(input.Tolerations, corev1.Toleration{Key: liqoconst.VirtualNodeTolerationKey, Operator: corev1.TolerationOpExists})
+	input.Volumes = append(input.Volumes, corev1.Volume{
+		Name:         "default-token-abcde",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "sa-token-abcde"}},
+	})
+	input.Containers[0].VolumeMounts = append(input.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "default-token-abcde"})
+
+	output := forger.forgePodSpec(input)
+
+	expected := newTestPodSpec()
+	assert.DeepEqual(t, output, expected)
+}
+
+func TestForgePodSpecDeniedFields(t *testing.T) {
+	initTestForger("hostAliases", "priorityClassName", "containers.lifecycle")
+
+	output := forger.forgePodSpec(newTestPodSpec())
+
+	expected := newTestPodSpec()
+	expected.HostAliases = nil
+	expected.PriorityClassName = ""
+	expected.InitContainers[0].Lifecycle = nil
+	expected.Containers[0].Lifecycle = nil
+	assert.DeepEqual(t, output, expected)
+}
+
+func TestValidateDeniedPodSpecFields(t *testing.T) {
+	assert.NilError(t, ValidateDeniedPodSpecFields(DefaultDeniedPodSpecFields))
+	assert.NilError(t, ValidateDeniedPodSpecFields([]string{"hostAliases", "containers.lifecycle"}))
+	assert.ErrorContains(t, ValidateDeniedPodSpecFields([]string{"HostAliases"}), "unknown")
+	assert.ErrorContains(t, ValidateDeniedPodSpecFields([]string{"containers.foo"}), "unknown")
+	assert.ErrorContains(t, ValidateDeniedPodSpecFields([]string{"containers.image"}), "cannot be denied")
+}
+
+func TestValidateReflectedVolumeTypes(t *testing.T) {
+	assert.NilError(t, ValidateReflectedVolumeTypes(DefaultReflectedVolumeTypes))
+	assert.NilError(t, ValidateReflectedVolumeTypes([]string{"hostPath", "csi"}))
+	assert.ErrorContains(t, ValidateReflectedVolumeTypes([]string{"HostPath"}), "unknown")
+}
+
+func TestForgePodSpecVolumeTypes(t *testing.T) {
+	hostPath := corev1.Volume{
+		Name:         "host",
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log"}},
+	}
+	homePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "homeNamespace"},
+		Spec:       newTestPodSpec(),
+	}
+	homePod.Spec.Volumes = append(homePod.Spec.Volumes, hostPath)
+	homePod.Spec.Containers[0].VolumeMounts = append(homePod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "host"})
+
+	// the pods mounting volumes of types not reflected are rejected, rather than started without them.
+	initTestForger()
+	_, err := HomeToForeign(homePod, nil, LiqoOutgoingKey)
+	assert.ErrorContains(t, err, `volume "host" of type "hostPath" cannot be reflected`)
+
+	initTestForgerWithVolumeTypes(append([]string{"hostPath"}, DefaultReflectedVolumeTypes...))
+	foreignObj, err := HomeToForeign(homePod, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignPod := foreignObj.(*corev1.Pod)
+	assert.DeepEqual(t, foreignPod.Spec.Volumes, homePod.Spec.Volumes)
+	assert.DeepEqual(t, foreignPod.Spec.Containers[0].VolumeMounts, homePod.Spec.Containers[0].VolumeMounts)
+}
+
+func TestForgeAffinity(t *testing.T) {
+	initTestForger()
+
+	virtualNodeRequirement := corev1.NodeSelectorRequirement{
+		Key: liqoconst.TypeLabel, Operator: corev1.NodeSelectorOpNotIn, Values: []string{liqoconst.TypeNode}}
+	zoneRequirement := corev1.NodeSelectorRequirement{
+		Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"zone-a"}}
+	podAffinityTerm := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		Namespaces:    []string{"homeNamespace", "otherNamespace"},
+		TopologyKey:   corev1.LabelHostname,
+	}
+
+	home := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement, {
+					Key: liqoconst.TypeLabel, Operator: corev1.NodeSelectorOpIn, Values: []string{liqoconst.TypeNode}}}},
+				{MatchFields: []corev1.NodeSelectorRequirement{{
+					Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"worker-1"}}}},
+			}},
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement}}},
+				{Weight: 20, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key: corev1.LabelHostname, Operator: corev1.NodeSelectorOpIn, Values: []string{"liqo-foreign-id"}}}}},
+			},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{podAffinityTerm},
+		},
+	}
+
+	// the user affinity is merged with the anti-affinity for the virtual nodes, dropping the requirements on home nodes.
+	forged := forger.forgeAffinity(home)
+	assert.DeepEqual(t, forged.NodeAffinity, &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement, virtualNodeRequirement}},
+			{MatchExpressions: []corev1.NodeSelectorRequirement{virtualNodeRequirement}},
+		}},
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+			{Weight: 10, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement}}},
+		},
+	})
+	assert.DeepEqual(t, forged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].Namespaces,
+		[]string{"homeNamespace-natted", "otherNamespace"})
+	assert.DeepEqual(t, home.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0], podAffinityTerm)
+
+	// without a user affinity, or if it is denied, only the anti-affinity for the virtual nodes is set.
+	expected := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{virtualNodeRequirement}}},
+	}}}
+	assert.DeepEqual(t, forger.forgeAffinity(nil), expected)
+	initTestForger("affinity")
+	assert.DeepEqual(t, forger.forgeAffinity(home), expected)
+}
+
+func TestReplicasetFromPod(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "homeNamespace-natted"},
+		Spec:       newTestPodSpec(),
+	}
+	pod.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+
+	replicaset := ReplicasetFromPod(pod)
+	assert.Equal(t, replicaset.Spec.Template.Spec.RestartPolicy, corev1.RestartPolicyAlways)
+	assert.Assert(t, replicaset.Spec.Template.Spec.ActiveDeadlineSeconds == nil)
+	assert.Equal(t, pod.Spec.RestartPolicy, corev1.RestartPolicyOnFailure)
+}
@@ -30,6 +30,12 @@ func (f *apiForger) replicasetFromPod(pod *corev1.Pod) *appsv1.ReplicaSet {
 	}
 	pod.Labels[virtualKubelet.ReflectedpodKey] = pod.Name
 
+	// the pods managed by a replicaset must be always restarted, and cannot be subject to a deadline.
+	podSpec := *pod.Spec.DeepCopy()
+	podSpec.RestartPolicy = corev1.RestartPolicyAlways
+	podSpec.ActiveDeadlineSeconds = nil
+	forgeStableHostname(&podSpec, pod.Name)
+
 	replicaset := &appsv1.ReplicaSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        pod.Name,
@@ -44,7 +50,7 @@ func (f *apiForger) replicasetFromPod(pod *corev1.Pod) *appsv1.ReplicaSet {
 					Labels:      pod.Labels,
 					Annotations: pod.Annotations,
 				},
-				Spec: pod.Spec,
+				Spec: podSpec,
 			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: pod.Labels,
@@ -16,6 +16,111 @@ package forge
 
 import corev1 "k8s.io/api/core/v1"
 
+// serviceHomeToForeign forges the foreign service starting from the home one. In case foreignService is not nil,
+// the fields allocated by the foreign cluster (e.g. the cluster IPs and the node ports) are preserved, so that the
+// resulting object can be compared with the existing one and used to update it.
 func (f *apiForger) serviceHomeToForeign(homeService, foreignService *corev1.Service) (*corev1.Service, error) {
-	panic("to implement")
+	if foreignService == nil {
+		foreignService = &corev1.Service{}
+	} else {
+		foreignService = foreignService.DeepCopy()
+	}
+
+	foreignNamespace, err := f.nattingTable.NatNamespace(homeService.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	f.forgeForeignMeta(&homeService.ObjectMeta, &foreignService.ObjectMeta, foreignNamespace, LiqoOutgoingKey)
+	foreignService.Spec = forgeServiceSpec(&homeService.Spec, &foreignService.Spec)
+
+	return foreignService, nil
+}
+
+// forgeServiceSpec returns the foreign service spec, given the home and the current foreign ones. The fields which
+// describe how the service behaves are reflected, while the ones which are allocated by (or are specific to) the
+// cluster hosting the service are either preserved from the current foreign spec or left for the foreign cluster
+// to be defaulted.
+func forgeServiceSpec(homeSpec, foreignSpec *corev1.ServiceSpec) corev1.ServiceSpec {
+	spec := corev1.ServiceSpec{
+		// the behavioural fields are reflected as they are.
+		Type:                     homeSpec.Type,
+		Selector:                 homeSpec.Selector,
+		SessionAffinity:          homeSpec.SessionAffinity,
+		SessionAffinityConfig:    homeSpec.SessionAffinityConfig.DeepCopy(),
+		PublishNotReadyAddresses: homeSpec.PublishNotReadyAddresses,
+		InternalTrafficPolicy:    homeSpec.InternalTrafficPolicy,
+
+		// the IP families depend on the configuration of the foreign cluster.
+		IPFamilies:     foreignSpec.IPFamilies,
+		IPFamilyPolicy: foreignSpec.IPFamilyPolicy,
+
+		// the external IPs and the load balancer IP are specific to the home cluster, hence they are not reflected.
+	}
+
+	// the internal traffic policy is defaulted by the API server only if the corresponding feature is enabled.
+	if spec.InternalTrafficPolicy == nil {
+		spec.InternalTrafficPolicy = foreignSpec.InternalTrafficPolicy
+	}
+
+	switch {
+	case homeSpec.Type == corev1.ServiceTypeExternalName:
+		// ExternalName services are plain DNS aliases, and they do not get any cluster IP.
+		spec.ExternalName = homeSpec.ExternalName
+	case homeSpec.ClusterIP == corev1.ClusterIPNone:
+		// headless services must be kept headless, since clients rely on the DNS records of the single endpoints.
+		spec.ClusterIP = corev1.ClusterIPNone
+		spec.ClusterIPs = []string{corev1.ClusterIPNone}
+	case foreignSpec.ClusterIP != corev1.ClusterIPNone:
+		// the cluster IPs are allocated by the foreign cluster.
+		spec.ClusterIP = foreignSpec.ClusterIP
+		spec.ClusterIPs = foreignSpec.ClusterIPs
+	}
+
+	if homeSpec.Type == corev1.ServiceTypeNodePort || homeSpec.Type == corev1.ServiceTypeLoadBalancer {
+		spec.ExternalTrafficPolicy = homeSpec.ExternalTrafficPolicy
+		if spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyTypeLocal {
+			spec.HealthCheckNodePort = foreignSpec.HealthCheckNodePort
+		}
+	}
+
+	if homeSpec.Type == corev1.ServiceTypeLoadBalancer {
+		spec.LoadBalancerSourceRanges = homeSpec.LoadBalancerSourceRanges
+		spec.LoadBalancerClass = homeSpec.LoadBalancerClass
+		spec.AllocateLoadBalancerNodePorts = homeSpec.AllocateLoadBalancerNodePorts
+		if spec.AllocateLoadBalancerNodePorts == nil {
+			spec.AllocateLoadBalancerNodePorts = foreignSpec.AllocateLoadBalancerNodePorts
+		}
+	}
+
+	if homeSpec.Type != corev1.ServiceTypeExternalName {
+		exposesNodePorts := homeSpec.Type == corev1.ServiceTypeNodePort || homeSpec.Type == corev1.ServiceTypeLoadBalancer
+		spec.Ports = forgeServicePorts(homeSpec.Ports, foreignSpec.Ports, exposesNodePorts)
+	}
+
+	return spec
+}
+
+// forgeServicePorts returns a copy of the home service ports. The node ports are allocated by the foreign cluster,
+// hence the ones already assigned to the matching foreign ports are preserved (if still meaningful), while the others
+// are left empty.
+func forgeServicePorts(homePorts, foreignPorts []corev1.ServicePort, keepNodePorts bool) []corev1.ServicePort {
+	if homePorts == nil {
+		return nil
+	}
+
+	ports := make([]corev1.ServicePort, len(homePorts))
+	for i := range homePorts {
+		ports[i] = *homePorts[i].DeepCopy()
+		ports[i].NodePort = 0
+
+		for j := range foreignPorts {
+			if keepNodePorts && foreignPorts[j].Port == homePorts[i].Port && foreignPorts[j].Protocol == homePorts[i].Protocol {
+				ports[i].NodePort = foreignPorts[j].NodePort
+				break
+			}
+		}
+	}
+
+	return ports
 }
@@ -0,0 +1,168 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func newTestService(serviceType corev1.ServiceType) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "service",
+			Namespace:   "homeNamespace",
+			Labels:      map[string]string{"app": "nginx"},
+			Annotations: map[string]string{"service.kubernetes.io/topology-aware-hints": "auto"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     serviceType,
+			Selector:                 map[string]string{"app": "nginx"},
+			Ports:                    []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(8080)}},
+			ClusterIP:                "10.96.0.10",
+			ClusterIPs:               []string{"10.96.0.10"},
+			SessionAffinity:          corev1.ServiceAffinityClientIP,
+			SessionAffinityConfig:    &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: pointer.Int32Ptr(60)}},
+			PublishNotReadyAddresses: true,
+			ExternalIPs:              []string{"1.1.1.1"},
+		},
+	}
+}
+
+func TestForgeServiceClusterIP(t *testing.T) {
+	initTestForger()
+
+	homeService := newTestService(corev1.ServiceTypeClusterIP)
+	foreignObj, err := HomeToForeign(homeService, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService := foreignObj.(*corev1.Service)
+
+	assert.Equal(t, foreignService.Name, "service")
+	assert.Equal(t, foreignService.Namespace, "homeNamespace-natted")
+	assert.Equal(t, foreignService.Labels["app"], "nginx")
+	assert.Equal(t, foreignService.Labels[LiqoOutgoingKey], LiqoNodeName())
+	assert.Equal(t, foreignService.Labels[LiqoOriginClusterID], "foreign-id")
+	assert.Equal(t, foreignService.Annotations["service.kubernetes.io/topology-aware-hints"], "auto")
+
+	assert.Equal(t, foreignService.Spec.Type, corev1.ServiceTypeClusterIP)
+	assert.DeepEqual(t, foreignService.Spec.Selector, homeService.Spec.Selector)
+	assert.DeepEqual(t, foreignService.Spec.Ports, homeService.Spec.Ports)
+	assert.Equal(t, foreignService.Spec.SessionAffinity, corev1.ServiceAffinityClientIP)
+	assert.DeepEqual(t, foreignService.Spec.SessionAffinityConfig, homeService.Spec.SessionAffinityConfig)
+	assert.Assert(t, foreignService.Spec.PublishNotReadyAddresses)
+
+	// the cluster-specific fields must not be reflected.
+	assert.Equal(t, foreignService.Spec.ClusterIP, "")
+	assert.Assert(t, foreignService.Spec.ClusterIPs == nil)
+	assert.Assert(t, foreignService.Spec.ExternalIPs == nil)
+}
+
+func TestForgeServiceHeadless(t *testing.T) {
+	initTestForger()
+
+	homeService := newTestService(corev1.ServiceTypeClusterIP)
+	homeService.Spec.ClusterIP = corev1.ClusterIPNone
+	homeService.Spec.ClusterIPs = []string{corev1.ClusterIPNone}
+
+	foreignObj, err := HomeToForeign(homeService, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService := foreignObj.(*corev1.Service)
+	assert.Equal(t, foreignService.Spec.ClusterIP, corev1.ClusterIPNone)
+	assert.DeepEqual(t, foreignService.Spec.ClusterIPs, []string{corev1.ClusterIPNone})
+}
+
+func TestForgeServiceExternalName(t *testing.T) {
+	initTestForger()
+
+	homeService := newTestService(corev1.ServiceTypeExternalName)
+	homeService.Spec.ExternalName = "example.com"
+	homeService.Spec.ClusterIP = ""
+	homeService.Spec.ClusterIPs = nil
+	homeService.Spec.Selector = nil
+
+	foreignObj, err := HomeToForeign(homeService, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService := foreignObj.(*corev1.Service)
+	assert.Equal(t, foreignService.Spec.Type, corev1.ServiceTypeExternalName)
+	assert.Equal(t, foreignService.Spec.ExternalName, "example.com")
+	assert.Equal(t, foreignService.Spec.ClusterIP, "")
+	assert.Assert(t, foreignService.Spec.Ports == nil)
+}
+
+func TestForgeServiceLoadBalancer(t *testing.T) {
+	initTestForger()
+
+	homeService := newTestService(corev1.ServiceTypeLoadBalancer)
+	homeService.Spec.Ports[0].NodePort = 30080
+	homeService.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+	homeService.Spec.HealthCheckNodePort = 30000
+	homeService.Spec.LoadBalancerIP = "1.2.3.4"
+	homeService.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+
+	foreignObj, err := HomeToForeign(homeService, nil, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService := foreignObj.(*corev1.Service)
+	assert.Equal(t, foreignService.Spec.ExternalTrafficPolicy, corev1.ServiceExternalTrafficPolicyTypeLocal)
+	assert.DeepEqual(t, foreignService.Spec.LoadBalancerSourceRanges, []string{"10.0.0.0/8"})
+	assert.Equal(t, foreignService.Spec.Ports[0].NodePort, int32(0))
+	assert.Equal(t, foreignService.Spec.HealthCheckNodePort, int32(0))
+	assert.Equal(t, foreignService.Spec.LoadBalancerIP, "")
+}
+
+func TestForgeServicePreservesForeignAllocations(t *testing.T) {
+	initTestForger()
+
+	homeService := newTestService(corev1.ServiceTypeNodePort)
+	homeService.Spec.Ports[0].NodePort = 30080
+	homeService.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+
+	existing := newTestService(corev1.ServiceTypeNodePort)
+	existing.Namespace = "homeNamespace-natted"
+	existing.Labels = map[string]string{"remote": "true"}
+	existing.Annotations = nil
+	existing.Spec.ClusterIP = "10.200.0.20"
+	existing.Spec.ClusterIPs = []string{"10.200.0.20"}
+	existing.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv4Protocol}
+	existing.Spec.Ports[0].NodePort = 31080
+	existing.Spec.Selector = map[string]string{"app": "outdated"}
+
+	foreignObj, err := HomeToForeign(homeService, existing, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService := foreignObj.(*corev1.Service)
+
+	assert.Equal(t, foreignService.Labels["remote"], "true")
+	assert.Equal(t, foreignService.Labels["app"], "nginx")
+	assert.DeepEqual(t, foreignService.Spec.Selector, homeService.Spec.Selector)
+	assert.Equal(t, foreignService.Spec.ClusterIP, "10.200.0.20")
+	assert.DeepEqual(t, foreignService.Spec.ClusterIPs, []string{"10.200.0.20"})
+	assert.DeepEqual(t, foreignService.Spec.IPFamilies, []corev1.IPFamily{corev1.IPv4Protocol})
+	assert.Equal(t, foreignService.Spec.Ports[0].NodePort, int32(31080))
+
+	// the existing object must not be modified.
+	assert.DeepEqual(t, existing.Spec.Selector, map[string]string{"app": "outdated"})
+
+	// the node ports are dropped when the service is no longer exposed through them.
+	homeService.Spec.Type = corev1.ServiceTypeClusterIP
+	foreignObj, err = HomeToForeign(homeService, existing, LiqoOutgoingKey)
+	assert.NilError(t, err)
+	foreignService = foreignObj.(*corev1.Service)
+	assert.Equal(t, foreignService.Spec.Ports[0].NodePort, int32(0))
+	assert.Equal(t, foreignService.Spec.ExternalTrafficPolicy, corev1.ServiceExternalTrafficPolicyType(""))
+}
@@ -0,0 +1,136 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes the resource metrics of the virtual node in the Prometheus format,
+// mimicking the /metrics/resource endpoint of the kubelet (consumed, e.g., by the metrics-server).
+// The metrics missing from the stats summary are omitted: in particular, the cumulative CPU usage is currently
+// not exposed for the offloaded pods, since it is not available from the foreign metrics API.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	stats "github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// ResourceMetricsPath is the path the resource metrics are served at.
+const ResourceMetricsPath = "/metrics/resource"
+
+// collectTimeout is the maximum time allowed to retrieve the stats summary.
+const collectTimeout = 30 * time.Second
+
+// StatsSummaryFunc is the function returning the stats summary of the virtual node.
+type StatsSummaryFunc func(context.Context) (*stats.Summary, error)
+
+var (
+	nodeCPUUsageDesc = prometheus.NewDesc("node_cpu_usage_seconds_total",
+		"Cumulative cpu time consumed by the node in core-seconds", nil, nil)
+	nodeMemoryUsageDesc = prometheus.NewDesc("node_memory_working_set_bytes",
+		"Current working set of the node in bytes", nil, nil)
+
+	podCPUUsageDesc = prometheus.NewDesc("pod_cpu_usage_seconds_total",
+		"Cumulative cpu time consumed by the pod in core-seconds", []string{"pod", "namespace"}, nil)
+	podMemoryUsageDesc = prometheus.NewDesc("pod_memory_working_set_bytes",
+		"Current working set of the pod in bytes", []string{"pod", "namespace"}, nil)
+
+	containerCPUUsageDesc = prometheus.NewDesc("container_cpu_usage_seconds_total",
+		"Cumulative cpu time consumed by the container in core-seconds", []string{"container", "pod", "namespace"}, nil)
+	containerMemoryUsageDesc = prometheus.NewDesc("container_memory_working_set_bytes",
+		"Current working set of the container in bytes", []string{"container", "pod", "namespace"}, nil)
+	containerStartTimeDesc = prometheus.NewDesc("container_start_time_seconds",
+		"Start time of the container since unix epoch in seconds", []string{"container", "pod", "namespace"}, nil)
+
+	scrapeErrorDesc = prometheus.NewDesc("scrape_error",
+		"1 if there was an error while getting container metrics, 0 otherwise", nil, nil)
+)
+
+// NewResourceMetricsHandler returns an http handler serving the resource metrics derived from the given stats summary.
+func NewResourceMetricsHandler(summary StatsSummaryFunc) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&resourceMetricsCollector{summary: summary})
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// resourceMetricsCollector is a prometheus.Collector exposing the resource metrics derived from the stats summary.
+type resourceMetricsCollector struct {
+	summary StatsSummaryFunc
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *resourceMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeCPUUsageDesc
+	ch <- nodeMemoryUsageDesc
+	ch <- podCPUUsageDesc
+	ch <- podMemoryUsageDesc
+	ch <- containerCPUUsageDesc
+	ch <- containerMemoryUsageDesc
+	ch <- containerStartTimeDesc
+	ch <- scrapeErrorDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *resourceMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	summary, err := c.summary(ctx)
+	if err != nil {
+		klog.Errorf("error while retrieving the stats summary: %v", err)
+		ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, 1)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, 0)
+
+	collectCPU(ch, nodeCPUUsageDesc, summary.Node.CPU)
+	collectMemory(ch, nodeMemoryUsageDesc, summary.Node.Memory)
+
+	for i := range summary.Pods {
+		pod := &summary.Pods[i]
+		collectCPU(ch, podCPUUsageDesc, pod.CPU, pod.PodRef.Name, pod.PodRef.Namespace)
+		collectMemory(ch, podMemoryUsageDesc, pod.Memory, pod.PodRef.Name, pod.PodRef.Namespace)
+
+		for j := range pod.Containers {
+			container := &pod.Containers[j]
+			labels := []string{container.Name, pod.PodRef.Name, pod.PodRef.Namespace}
+			collectCPU(ch, containerCPUUsageDesc, container.CPU, labels...)
+			collectMemory(ch, containerMemoryUsageDesc, container.Memory, labels...)
+			if !container.StartTime.IsZero() {
+				ch <- prometheus.NewMetricWithTimestamp(container.StartTime.Time,
+					prometheus.MustNewConstMetric(containerStartTimeDesc, prometheus.GaugeValue,
+						float64(container.StartTime.UnixNano())/float64(time.Second), labels...))
+			}
+		}
+	}
+}
+
+func collectCPU(ch chan<- prometheus.Metric, desc *prometheus.Desc, cpu *stats.CPUStats, labels ...string) {
+	if cpu == nil || cpu.UsageCoreNanoSeconds == nil {
+		return
+	}
+	ch <- prometheus.NewMetricWithTimestamp(cpu.Time.Time, prometheus.MustNewConstMetric(desc,
+		prometheus.CounterValue, float64(*cpu.UsageCoreNanoSeconds)/float64(time.Second), labels...))
+}
+
+func collectMemory(ch chan<- prometheus.Metric, desc *prometheus.Desc, memory *stats.MemoryStats, labels ...string) {
+	if memory == nil || memory.WorkingSetBytes == nil {
+		return
+	}
+	ch <- prometheus.NewMetricWithTimestamp(memory.Time.Time, prometheus.MustNewConstMetric(desc,
+		prometheus.GaugeValue, float64(*memory.WorkingSetBytes), labels...))
+}
@@ -0,0 +1,89 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	stats "github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func uint64Ptr(value uint64) *uint64 {
+	return &value
+}
+
+func scrape(t *testing.T, summary StatsSummaryFunc) string {
+	recorder := httptest.NewRecorder()
+	NewResourceMetricsHandler(summary).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ResourceMetricsPath, nil))
+	assert.Equal(t, recorder.Code, http.StatusOK)
+
+	body, err := io.ReadAll(recorder.Body)
+	assert.NilError(t, err)
+	return string(body)
+}
+
+func TestResourceMetrics(t *testing.T) {
+	now := metav1.NewTime(time.Unix(1600000000, 0))
+	ms := now.UnixNano() / int64(time.Millisecond)
+
+	summary := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName: "liqo-node",
+			CPU:      &stats.CPUStats{Time: now, UsageCoreNanoSeconds: uint64Ptr(3 * 1000 * 1000 * 1000)},
+			Memory:   &stats.MemoryStats{Time: now, WorkingSetBytes: uint64Ptr(2048)},
+		},
+		Pods: []stats.PodStats{{
+			PodRef: stats.PodReference{Name: "pod", Namespace: "namespace"},
+			CPU:    &stats.CPUStats{Time: now, UsageCoreNanoSeconds: uint64Ptr(1500 * 1000 * 1000)},
+			Memory: &stats.MemoryStats{Time: now, WorkingSetBytes: uint64Ptr(1024)},
+			Containers: []stats.ContainerStats{{
+				Name:      "container",
+				StartTime: now,
+				CPU:       &stats.CPUStats{Time: now, UsageCoreNanoSeconds: uint64Ptr(1500 * 1000 * 1000)},
+				Memory:    &stats.MemoryStats{Time: now, WorkingSetBytes: uint64Ptr(1024)},
+			}},
+		}},
+	}
+
+	body := scrape(t, func(context.Context) (*stats.Summary, error) { return summary, nil })
+
+	for _, line := range []string{
+		fmt.Sprintf("node_cpu_usage_seconds_total 3 %d", ms),
+		fmt.Sprintf("node_memory_working_set_bytes 2048 %d", ms),
+		fmt.Sprintf(`pod_cpu_usage_seconds_total{namespace="namespace",pod="pod"} 1.5 %d`, ms),
+		fmt.Sprintf(`pod_memory_working_set_bytes{namespace="namespace",pod="pod"} 1024 %d`, ms),
+		fmt.Sprintf(`container_cpu_usage_seconds_total{container="container",namespace="namespace",pod="pod"} 1.5 %d`, ms),
+		fmt.Sprintf(`container_memory_working_set_bytes{container="container",namespace="namespace",pod="pod"} 1024 %d`, ms),
+		fmt.Sprintf(`container_start_time_seconds{container="container",namespace="namespace",pod="pod"} 1.6e+09 %d`, ms),
+		"scrape_error 0",
+	} {
+		assert.Assert(t, is.Contains(body, line))
+	}
+}
+
+func TestResourceMetricsError(t *testing.T) {
+	body := scrape(t, func(context.Context) (*stats.Summary, error) { return nil, errors.New("failure") })
+	assert.Assert(t, is.Contains(body, "scrape_error 1"))
+}
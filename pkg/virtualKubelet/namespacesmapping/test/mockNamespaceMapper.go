@@ -44,7 +44,11 @@ func (m *MockNamespaceMapper) DeNatNamespace(namespace string) (string, error) {
 
 // MappedNamespaces returns the entire namespace mapping map.
 func (m *MockNamespaceMapper) MappedNamespaces() map[string]string {
-	panic("implement me")
+	namespaces := make(map[string]string, len(m.Cache))
+	for k, v := range m.Cache {
+		namespaces[k] = v
+	}
+	return namespaces
 }
 
 // NewNamespace creates a new namespace in the local cache.
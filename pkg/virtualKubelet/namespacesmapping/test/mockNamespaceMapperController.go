@@ -83,7 +83,7 @@ func (c *MockNamespaceMapperController) DeNatNamespace(namespace string) (string
 
 // MappedNamespaces returns the entire namespace mapping map.
 func (c *MockNamespaceMapperController) MappedNamespaces() map[string]string {
-	panic("implement me")
+	return c.Mapper.MappedNamespaces()
 }
 
 // WaitForSync waits until internal caches are synchronized.
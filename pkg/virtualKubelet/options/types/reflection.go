@@ -0,0 +1,90 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"sync"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+)
+
+// ReflectionKey is the type of the keys of the options tuning the reflection of the resources.
+type ReflectionKey string
+
+// ReflectionValue is the type of the values of the options tuning the reflection of the resources.
+type ReflectionValue string
+
+const (
+	// StorageClassMapping is the key for the option containing the mapping between home and foreign storage classes,
+	// formatted as a comma separated list of <home-class>=<foreign-class> pairs.
+	StorageClassMapping = "storageClassMapping"
+	// IngressClassMapping is the key for the option containing the mapping between home and foreign ingress classes,
+	// formatted as a comma separated list of [<remote-cluster-id>/]<home-class>=<foreign-class> pairs.
+	IngressClassMapping = "ingressClassMapping"
+	// DeniedPodSpecFields is the key for the option containing the pod spec fields which are not reflected,
+	// formatted as a comma separated list of JSON field names.
+	DeniedPodSpecFields = "deniedPodSpecFields"
+	// ReflectedVolumeTypes is the key for the option containing the volume types which are reflected,
+	// formatted as a comma separated list of JSON names of the volume sources.
+	ReflectedVolumeTypes = "reflectedVolumeTypes"
+)
+
+// NewReflectionOption returns a new ReflectionOption with the given key and value.
+func NewReflectionOption(key ReflectionKey, value ReflectionValue) *ReflectionOption {
+	return &ReflectionOption{
+		key:   key,
+		value: value,
+		lock:  sync.RWMutex{},
+	}
+}
+
+// ReflectionOption is an option tuning the reflection of the resources towards the foreign cluster.
+type ReflectionOption struct {
+	key   ReflectionKey
+	value ReflectionValue
+
+	isSet bool
+	lock  sync.RWMutex
+}
+
+// Key returns the key of the option.
+func (o *ReflectionOption) Key() options.OptionKey {
+	return options.OptionKey(o.key)
+}
+
+// Value returns the value of the option.
+func (o *ReflectionOption) Value() options.OptionValue {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return options.OptionValue(o.value)
+}
+
+// SetValue sets the value of the option.
+func (o *ReflectionOption) SetValue(v options.OptionValue) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.value = ReflectionValue(v)
+	o.isSet = true
+}
+
+// IsSet returns whether the value of the option has been explicitly set.
+func (o *ReflectionOption) IsSet() bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return o.isSet
+}
@@ -18,15 +18,14 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"time"
 
 	"github.com/modern-go/reflect2"
 	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
-	stats "github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -38,6 +37,8 @@ import (
 	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 	vkContext "github.com/liqotech/liqo/pkg/virtualKubelet/context"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/translation/imagePullSecrets"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/translation/serviceAccount"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/translation/serviceEnv"
 )
 
@@ -68,9 +69,21 @@ func (p *LiqoProvider) CreatePod(ctx context.Context, homePod *corev1.Pod) error
 		return kerror.NewServiceUnavailable(err.Error())
 	}
 
-	foreignReplicaset := forge.ReplicasetFromPod(foreignPod)
+	if err = imagePullSecrets.CheckImagePullSecrets(homePod, foreignPod.Namespace, p.apiController.CacheManager()); err != nil {
+		klog.V(4).Info(err)
+		return kerror.NewServiceUnavailable(err.Error())
+	}
 
-	// Add a finalizer to allow the pod to be garbage collected by the incoming replicaset reflector.
+	if p.homeAPIServerHost != "" {
+		foreignPod, err = serviceAccount.TranslateServiceAccountTokens(foreignPod, homePod, p.apiController.CacheManager(), p.homeClient,
+			p.homeAPIServerHost, p.homeAPIServerPort)
+		if err != nil {
+			klog.V(4).Info(err)
+			return kerror.NewServiceUnavailable(err.Error())
+		}
+	}
+
+	// Add a finalizer to allow the pod to be garbage collected by the incoming replicaset (or job) reflector.
 	// Add label to distinct the offloaded pods from the local ones.
 	// The merge strategy is types.StrategicMergePatchType in order to merger the previous state
 	// with the new configuration.
@@ -88,7 +101,16 @@ func (p *LiqoProvider) CreatePod(ctx context.Context, homePod *corev1.Pod) error
 		return kerror.NewServiceUnavailable(err.Error())
 	}
 
-	_, err = p.foreignClient.AppsV1().ReplicaSets(foreignReplicaset.Namespace).Create(context.TODO(), foreignReplicaset, metav1.CreateOptions{})
+	// The pods expected to run to completion are offloaded through a job, to prevent them from being restarted
+	// once completed, while all the others through a replicaset, to survive the deletions in the foreign cluster.
+	if forge.IsBatchPod(homePod) {
+		return p.createForeignJob(forge.JobFromPod(foreignPod))
+	}
+	return p.createForeignReplicaset(forge.ReplicasetFromPod(foreignPod))
+}
+
+func (p *LiqoProvider) createForeignReplicaset(foreignReplicaset *appsv1.ReplicaSet) error {
+	_, err := p.foreignClient.AppsV1().ReplicaSets(foreignReplicaset.Namespace).Create(context.TODO(), foreignReplicaset, metav1.CreateOptions{})
 	if kerror.IsAlreadyExists(err) {
 		klog.V(4).Infof("PROVIDER: creation of foreign replicaset %s/%s aborted, already existing", foreignReplicaset.Namespace, foreignReplicaset.Name)
 		return nil
@@ -103,6 +125,22 @@ func (p *LiqoProvider) CreatePod(ctx context.Context, homePod *corev1.Pod) error
 	return nil
 }
 
+func (p *LiqoProvider) createForeignJob(foreignJob *batchv1.Job) error {
+	_, err := p.foreignClient.BatchV1().Jobs(foreignJob.Namespace).Create(context.TODO(), foreignJob, metav1.CreateOptions{})
+	if kerror.IsAlreadyExists(err) {
+		klog.V(4).Infof("PROVIDER: creation of foreign job %s/%s aborted, already existing", foreignJob.Namespace, foreignJob.Name)
+		return nil
+	}
+	if err != nil {
+		klog.Error(err)
+		return kerror.NewServiceUnavailable(err.Error())
+	}
+
+	klog.V(3).Infof("PROVIDER: job %v/%v successfully created on remote cluster", foreignJob.Namespace, foreignJob.Name)
+
+	return nil
+}
+
 // UpdatePod accepts a Pod definition and updates its reference.
 func (p *LiqoProvider) UpdatePod(ctx context.Context, pod *corev1.Pod) error {
 	if reflect2.IsNil(pod) {
@@ -121,7 +159,8 @@ func (p *LiqoProvider) DeletePod(ctx context.Context, pod *corev1.Pod) (err erro
 		return errors.New("received nil pod to delete")
 	}
 
-	var foreignNamespace, replicasetName string
+	// the name of the foreign replicaset (or job) matches the one of the home pod.
+	var foreignNamespace, ownerName string
 
 	klog.V(3).Infof("PROVIDER: pod %s/%s asked to be deleted in the provider", pod.Namespace, pod.Name)
 
@@ -130,21 +169,32 @@ func (p *LiqoProvider) DeletePod(ctx context.Context, pod *corev1.Pod) (err erro
 	if value, ok := vkContext.CallingFunction(ctx); ok && value == vkContext.DeleteDanglingPods {
 		foreignNamespace = pod.Namespace
 		if pod.Labels != nil {
-			replicasetName = pod.Labels[virtualKubelet.ReflectedpodKey]
+			ownerName = pod.Labels[virtualKubelet.ReflectedpodKey]
 		}
-		if replicasetName == "" {
+		if ownerName == "" {
 			klog.V(3).Infof("PROVIDER: home pod %s/%s foreign replica not deleted because unlabeled", pod.Namespace, pod.Name)
 			return nil
 		}
 	} else {
-		replicasetName = pod.Name
+		ownerName = pod.Name
 		foreignNamespace, err = p.namespaceMapper.NatNamespace(pod.Namespace)
 		if err != nil {
 			return nil
 		}
 	}
 
-	err = p.foreignClient.AppsV1().ReplicaSets(foreignNamespace).Delete(context.TODO(), replicasetName, metav1.DeleteOptions{})
+	if forge.IsBatchPod(pod) {
+		deleted, err := p.deleteForeignJob(foreignNamespace, ownerName)
+		if err != nil || deleted {
+			return err
+		}
+		// the pods offloaded by previous versions are always owned by a replicaset, hence fall back to it.
+	}
+	return p.deleteForeignReplicaset(foreignNamespace, ownerName)
+}
+
+func (p *LiqoProvider) deleteForeignReplicaset(foreignNamespace, replicasetName string) error {
+	err := p.foreignClient.AppsV1().ReplicaSets(foreignNamespace).Delete(context.TODO(), replicasetName, metav1.DeleteOptions{})
 	if kerror.IsNotFound(err) {
 		klog.V(5).Infof("PROVIDER: replicaset %v/%v not deleted because not existing", foreignNamespace, replicasetName)
 		return nil
@@ -153,11 +203,30 @@ func (p *LiqoProvider) DeletePod(ctx context.Context, pod *corev1.Pod) (err erro
 		return errors.Wrap(err, "Unable to delete foreign replicaset")
 	}
 
-	klog.V(3).Infof("PROVIDER: replicaset %v/%v successfully deleted on remote cluster", foreignNamespace, pod.Name)
+	klog.V(3).Infof("PROVIDER: replicaset %v/%v successfully deleted on remote cluster", foreignNamespace, replicasetName)
 
 	return nil
 }
 
+// deleteForeignJob deletes the given foreign job, and returns whether it existed.
+func (p *LiqoProvider) deleteForeignJob(foreignNamespace, jobName string) (bool, error) {
+	// the jobs orphan their pods by default, hence the propagation policy has to be explicitly set.
+	propagationPolicy := metav1.DeletePropagationBackground
+	err := p.foreignClient.BatchV1().Jobs(foreignNamespace).Delete(context.TODO(), jobName,
+		metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+	if kerror.IsNotFound(err) {
+		klog.V(5).Infof("PROVIDER: job %v/%v not deleted because not existing", foreignNamespace, jobName)
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Unable to delete foreign job")
+	}
+
+	klog.V(3).Infof("PROVIDER: job %v/%v successfully deleted on remote cluster", foreignNamespace, jobName)
+
+	return true, nil
+}
+
 // GetPod returns a pod by name that is stored in memory.
 func (p *LiqoProvider) GetPod(ctx context.Context, namespace, name string) (pod *corev1.Pod, err error) {
 	if reflect2.IsNil(pod) {
@@ -236,6 +305,21 @@ func (p *LiqoProvider) RunInContainer(ctx context.Context, homeNamespace, homePo
 	}
 	foreignPod := foreignObj.(*corev1.Pod)
 
+	// The streams requested by the client are honored, as the stderr one is merged with stdout in case of a TTY.
+	opts := remotecommandclient.StreamOptions{
+		Stdin:  attach.Stdin(),
+		Stdout: attach.Stdout(),
+		Tty:    attach.TTY(),
+	}
+	if !opts.Tty {
+		opts.Stderr = attach.Stderr()
+	} else if resize := attach.Resize(); resize != nil {
+		// The context is canceled on return, to stop waiting for further resize events.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		opts.TerminalSizeQueue = &terminalSizeQueue{ctx: ctx, resize: resize}
+	}
+
 	req := p.foreignClient.CoreV1().RESTClient().
 		Post().
 		Namespace(foreignNamespace).
@@ -245,10 +329,10 @@ func (p *LiqoProvider) RunInContainer(ctx context.Context, homeNamespace, homePo
 		VersionedParams(&corev1.PodExecOptions{
 			Container: containerName,
 			Command:   cmd,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       true,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil,
+			TTY:       opts.Tty,
 		}, scheme.ParameterCodec)
 
 	exec, err := remotecommandclient.NewSPDYExecutor(p.foreignRestConfig, "POST", req.URL())
@@ -256,12 +340,7 @@ func (p *LiqoProvider) RunInContainer(ctx context.Context, homeNamespace, homePo
 		return fmt.Errorf("could not make remote command: %v", err)
 	}
 
-	err = exec.Stream(remotecommandclient.StreamOptions{
-		Stdin:  attach.Stdin(),
-		Stdout: attach.Stdout(),
-		Stderr: attach.Stderr(),
-		Tty:    attach.TTY(),
-	})
+	err = exec.Stream(opts)
 	if err != nil {
 		return fmt.Errorf("streaming error: %v", err)
 	}
@@ -315,138 +394,10 @@ func (p *LiqoProvider) GetContainerLogs(ctx context.Context, homeNamespace, home
 	return stream, nil
 }
 
-// GetStatsSummary returns dummy stats for all pods known by this provider.
-func (p *LiqoProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
-	// Grab the current timestamp so we can report it as the time the stats were generated.
-	t := metav1.NewTime(time.Now())
-
-	// Create the Summary object that will later be populated with node and pod stats.
-	res := &stats.Summary{}
-
-	// Populate the Summary object with basic node stats.
-	res.Node = stats.NodeStats{
-		NodeName:  p.nodeName.Value().ToString(),
-		StartTime: metav1.NewTime(p.startTime),
-	}
-
-	var (
-		// nodeTotalNanoCore will be populated with the sum of the values of UsageNanoCores computes across all
-		// containers in all the pods running in the remote cluster on behalf of this virtual node.
-		nodeTotalNanoCore uint64
-		// nodeTotalNanoBytes will be populated with the sum of the values of UsageBytes computed across all containers
-		// in all the pods running in the remote cluster on behalf of this virtual node.
-		nodeTotalNanoBytes uint64
-	)
-
-	// iterates over all the mapped namespaces
-	for home, foreign := range p.namespaceMapper.MappedNamespaces() {
-		// get the metrics of the foreign pods in each namespace by filtering with the liqoOutgoingKey
-		podMetrics, err := p.foreignMetricsClient.MetricsV1beta1().PodMetricses(foreign).List(ctx, metav1.ListOptions{
-			LabelSelector: fmt.Sprintf("%s=%s", forge.LiqoOutgoingKey, forge.LiqoNodeName()),
-		})
-		if err != nil {
-			return nil, errors.Wrapf(err, "error while listing foreign pod metricses in namespace %s", foreign)
-		}
-
-		for index := range podMetrics.Items {
-			// fetch foreign pod from cache
-			foreignObj, err := p.apiController.CacheManager().GetForeignNamespacedObject(apimgmgt.Pods, foreign, podMetrics.Items[index].Name)
-			if err != nil {
-				return nil, errors.Errorf("error while retrieving foreign pod %s/%s from cache", foreign, podMetrics.Items[index].Name)
-			}
-			// retrieve foreign pod name by the foreign pod label
-			foreignPod := foreignObj.(*corev1.Pod)
-			if foreignPod.Labels == nil {
-				return nil, errors.Errorf("error in foreign pod, empty %s label set in pod %s/%s",
-					virtualKubelet.ReflectedpodKey, foreign, podMetrics.Items[index].Name)
-			}
-			homePodName, ok := foreignPod.Labels[virtualKubelet.ReflectedpodKey]
-			if !ok {
-				return nil, errors.Errorf("error in foreign pod, missing %s label in pod %s/%s",
-					virtualKubelet.ReflectedpodKey, foreign, podMetrics.Items[index].Name)
-			}
-
-			// fetch home pod from cache
-			homeObj, err := p.apiController.CacheManager().GetHomeNamespacedObject(apimgmgt.Pods, home, homePodName)
-			if err != nil {
-				return nil, errors.Errorf("error while retrieving home pod %s/%s from cache", home, homePodName)
-			}
-			homePod := homeObj.(*corev1.Pod)
-
-			// Create a PodStats object to populate with pod stats.
-			podStats := stats.PodStats{
-				PodRef: stats.PodReference{
-					Name:      homePodName,
-					Namespace: home,
-					UID:       string(homePod.UID),
-				},
-				StartTime: homePod.CreationTimestamp,
-			}
-
-			var (
-				// totalUsageNanoCores will be populated with the sum of the values of UsageNanoCores computes across all containers in the pod.
-				totalUsageNanoCores uint64
-				// totalUsageBytes will be populated with the sum of the values of UsageBytes computed across all containers in the pod.
-				totalUsageBytes uint64
-			)
-
-			// Iterate over all containers in the current pod to get stats
-			for _, container := range podMetrics.Items[index].Containers {
-				nanoCpuUsage := uint64(container.Usage.Cpu().ScaledValue(resource.Nano))
-				totalUsageNanoCores += nanoCpuUsage
-
-				nanoMemoryUsage := uint64(container.Usage.Memory().Value())
-				totalUsageBytes += nanoMemoryUsage
-
-				// Append a ContainerStats object containing the dummy stats to the PodStats object.
-				podStats.Containers = append(podStats.Containers, stats.ContainerStats{
-					Name:      container.Name,
-					StartTime: homePod.CreationTimestamp,
-					CPU: &stats.CPUStats{
-						Time:           t,
-						UsageNanoCores: &nanoCpuUsage,
-					},
-					Memory: &stats.MemoryStats{
-						Time:            t,
-						UsageBytes:      &nanoMemoryUsage,
-						WorkingSetBytes: &nanoMemoryUsage,
-					},
-				})
-			}
-
-			nodeTotalNanoCore += totalUsageNanoCores
-			nodeTotalNanoBytes += totalUsageBytes
-
-			// Populate the CPU and RAM stats for the pod and append the PodsStats object to the Summary object to be returned.
-			podStats.CPU = &stats.CPUStats{
-				Time:           t,
-				UsageNanoCores: &totalUsageNanoCores,
-			}
-			podStats.Memory = &stats.MemoryStats{
-				Time:            t,
-				UsageBytes:      &totalUsageBytes,
-				WorkingSetBytes: &totalUsageBytes,
-			}
-			res.Pods = append(res.Pods, podStats)
-		}
-	}
-
-	res.Node.CPU = &stats.CPUStats{
-		Time:           t,
-		UsageNanoCores: &nodeTotalNanoCore,
-	}
-	res.Node.Memory = &stats.MemoryStats{
-		Time:            t,
-		UsageBytes:      &nodeTotalNanoBytes,
-		WorkingSetBytes: &nodeTotalNanoBytes,
-	}
-
-	return res, nil
-}
-
 // NotifyPods is called to set a pod informing callback function. This should be called before any operations are ready
 // within the provider.
 func (p *LiqoProvider) NotifyPods(ctx context.Context, notifier func(*corev1.Pod)) {
 	p.apiController.SetInformingFunc(apimgmgt.Pods, notifier)
 	p.apiController.SetInformingFunc(apimgmgt.ReplicaSets, notifier)
+	p.apiController.SetInformingFunc(apimgmgt.Jobs, notifier)
 }
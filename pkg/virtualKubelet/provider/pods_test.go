@@ -23,7 +23,9 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
@@ -130,6 +132,33 @@ var _ = Describe("Pods", func() {
 					klog.Flush()
 					Expect(strings.Contains(buffer.String(), "replicaset homeNamespace-natted/testObject not deleted because not existing")).To(BeTrue())
 				})
+
+				It("with batch pod and corresponding job existing", func() {
+					pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+					job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "testObject", Namespace: "homeNamespace-natted"}}
+					_, _ = foreignClient.BatchV1().Jobs("homeNamespace-natted").Create(context.TODO(), job, metav1.CreateOptions{})
+					err := provider.DeletePod(context.TODO(), pod)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = foreignClient.BatchV1().Jobs("homeNamespace-natted").Get(context.TODO(), "testObject", metav1.GetOptions{})
+					Expect(kerror.IsNotFound(err)).To(BeTrue())
+				})
+
+				It("with batch pod and without corresponding job existing", func() {
+					pod.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+					err := provider.DeletePod(context.TODO(), pod)
+					Expect(err).NotTo(HaveOccurred())
+					klog.Flush()
+					Expect(strings.Contains(buffer.String(), "job homeNamespace-natted/testObject not deleted because not existing")).To(BeTrue())
+				})
+
+				It("with batch pod and corresponding replicaset existing", func() {
+					pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+					_, _ = foreignClient.AppsV1().ReplicaSets("homeNamespace-natted").Create(context.TODO(), replicaset, metav1.CreateOptions{})
+					err := provider.DeletePod(context.TODO(), pod)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = foreignClient.AppsV1().ReplicaSets("homeNamespace-natted").Get(context.TODO(), "testObject", metav1.GetOptions{})
+					Expect(kerror.IsNotFound(err)).To(BeTrue())
+				})
 			})
 		})
 	})
@@ -16,6 +16,7 @@ package provider
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -28,6 +29,7 @@ import (
 	identitymanager "github.com/liqotech/liqo/pkg/identityManager"
 	tenantnamespace "github.com/liqotech/liqo/pkg/tenantNamespace"
 	"github.com/liqotech/liqo/pkg/utils"
+	argsutils "github.com/liqotech/liqo/pkg/utils/args"
 	"github.com/liqotech/liqo/pkg/virtualKubelet"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/controller"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
@@ -54,6 +56,10 @@ type LiqoProvider struct {
 	foreignClusterID   string
 	foreignRestConfig  *rest.Config
 
+	// homeAPIServerHost and homeAPIServerPort identify the home API server, as reachable from the offloaded pods.
+	homeAPIServerHost string
+	homeAPIServerPort string
+
 	nodeName options.Option
 
 	foreignPodWatcherStop chan struct{}
@@ -61,7 +67,9 @@ type LiqoProvider struct {
 
 // NewLiqoProvider creates a new NewLiqoProvider instance.
 func NewLiqoProvider(ctx context.Context, nodeName, foreignClusterID, homeClusterID, internalIP string, daemonEndpointPort int32,
-	kubeconfig string, informerResyncPeriod time.Duration, ipamGRPCServer string) (*LiqoProvider, error) {
+	kubeconfig string, informerResyncPeriod time.Duration, ipamGRPCServer string, storageClassMapping map[string]string,
+	ingressClassMapping map[string]string,
+	homeAPIServerHost, homeAPIServerPort string, deniedPodSpecFields, reflectedVolumeTypes []string) (*LiqoProvider, error) {
 	var err error
 
 	if err = vkalpha1.AddToScheme(scheme.Scheme); err != nil {
@@ -117,11 +125,32 @@ func NewLiqoProvider(ctx context.Context, nodeName, foreignClusterID, homeCluste
 	grpcServerNameOpt := optTypes.NewNetworkingOption(optTypes.LiqoIpamServer, optTypes.NetworkingValue(ipamGRPCServer))
 	remoteClusterIDOpt := optTypes.NewNetworkingOption(optTypes.RemoteClusterID, optTypes.NetworkingValue(foreignClusterID))
 
-	forge.InitForger(mapper, virtualNodeNameOpt, grpcServerNameOpt, remoteClusterIDOpt)
+	if err = forge.ValidateDeniedPodSpecFields(deniedPodSpecFields); err != nil {
+		return nil, err
+	}
+	deniedPodSpecFieldsOpt := optTypes.NewReflectionOption(optTypes.DeniedPodSpecFields,
+		optTypes.ReflectionValue(strings.Join(deniedPodSpecFields, ",")))
+
+	if err = forge.ValidateReflectedVolumeTypes(reflectedVolumeTypes); err != nil {
+		return nil, err
+	}
+	reflectedVolumeTypesOpt := optTypes.NewReflectionOption(optTypes.ReflectedVolumeTypes,
+		optTypes.ReflectionValue(strings.Join(reflectedVolumeTypes, ",")))
+
+	forge.InitForger(mapper, virtualNodeNameOpt, grpcServerNameOpt, remoteClusterIDOpt, deniedPodSpecFieldsOpt, reflectedVolumeTypesOpt)
+
+	storageClassMappingOpt := optTypes.NewReflectionOption(optTypes.StorageClassMapping,
+		optTypes.ReflectionValue(argsutils.StringMap{StringMap: storageClassMapping}.String()))
+
+	ingressClassMappingOpt := optTypes.NewReflectionOption(optTypes.IngressClassMapping,
+		optTypes.ReflectionValue(argsutils.StringMap{StringMap: ingressClassMapping}.String()))
 
 	opts := forgeOptionsMap(
 		virtualNodeNameOpt,
-		grpcServerNameOpt)
+		grpcServerNameOpt,
+		remoteClusterIDOpt,
+		storageClassMappingOpt,
+		ingressClassMappingOpt)
 
 	tepReady := make(chan struct{})
 
@@ -136,6 +165,8 @@ func NewLiqoProvider(ctx context.Context, nodeName, foreignClusterID, homeCluste
 		homeClusterID:         homeClusterID,
 		foreignPodWatcherStop: make(chan struct{}, 1),
 		foreignRestConfig:     remoteRestConfig,
+		homeAPIServerHost:     homeAPIServerHost,
+		homeAPIServerPort:     homeAPIServerPort,
 		homeClient:            homeClient,
 		foreignClient:         foreignClient,
 		foreignMetricsClient:  foreignMetricsClient,
@@ -0,0 +1,218 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	stats "github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+)
+
+// GetStatsSummary returns the stats of the pods offloaded by this virtual node. The stats are retrieved from the foreign
+// metrics API, which requires no access to the foreign nodes, but exposes only the CPU usage averaged over the last
+// window and the memory working set. Hence, the cumulative CPU usage, the memory usage other than the working set,
+// and the network, filesystem, ephemeral-storage and volume stats are not reported, since they would require the
+// nodes/proxy permission on the foreign nodes, which is not granted to the peering identity.
+// The pods whose stats cannot be retrieved are skipped, to return partial results rather than failing.
+func (p *LiqoProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
+	res := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName:  p.nodeName.Value().ToString(),
+			StartTime: metav1.NewTime(p.startTime),
+		},
+	}
+
+	for home, foreign := range p.namespaceMapper.MappedNamespaces() {
+		objects, err := p.apiController.CacheManager().ListForeignNamespacedObject(apimgmgt.Pods, foreign)
+		if err != nil {
+			klog.Warningf("error while listing foreign pods in namespace %s: %v", foreign, err)
+			continue
+		}
+
+		// the metrics of the foreign pods in the namespace, retrieved only if required.
+		var podMetrics map[string]*metricsv1beta1.PodMetrics
+
+		for _, obj := range objects {
+			foreignPod := obj.(*corev1.Pod)
+			if foreignPod.Labels[forge.LiqoOutgoingKey] != forge.LiqoNodeName() {
+				continue
+			}
+
+			homePod, err := p.getHomePodFromForeign(home, foreignPod)
+			if err != nil {
+				klog.V(4).Info(err)
+				continue
+			}
+
+			if podMetrics == nil {
+				podMetrics = p.listForeignPodMetrics(ctx, foreign)
+			}
+			podStats := podStatsFromMetrics(foreignPod, homePod, podMetrics[foreignPod.Name])
+			if podStats == nil {
+				klog.V(4).Infof("stats not available for pod %s/%s", homePod.Namespace, homePod.Name)
+				continue
+			}
+
+			translatePodStats(podStats, homePod)
+			res.Pods = append(res.Pods, *podStats)
+		}
+	}
+
+	res.Node.CPU, res.Node.Memory = aggregateNodeStats(res.Pods)
+	return res, nil
+}
+
+// getHomePodFromForeign returns the home pod corresponding to the given foreign one.
+func (p *LiqoProvider) getHomePodFromForeign(homeNamespace string, foreignPod *corev1.Pod) (*corev1.Pod, error) {
+	homePodName, ok := foreignPod.Labels[virtualKubelet.ReflectedpodKey]
+	if !ok {
+		return nil, errors.Errorf("missing %s label in foreign pod %s/%s", virtualKubelet.ReflectedpodKey, foreignPod.Namespace, foreignPod.Name)
+	}
+
+	homeObj, err := p.apiController.CacheManager().GetHomeNamespacedObject(apimgmgt.Pods, homeNamespace, homePodName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while retrieving home pod %s/%s from cache", homeNamespace, homePodName)
+	}
+	return homeObj.(*corev1.Pod), nil
+}
+
+// listForeignPodMetrics returns the metrics of the offloaded pods in the given foreign namespace, indexed by pod name.
+func (p *LiqoProvider) listForeignPodMetrics(ctx context.Context, foreignNamespace string) map[string]*metricsv1beta1.PodMetrics {
+	podMetrics := make(map[string]*metricsv1beta1.PodMetrics)
+
+	list, err := p.foreignMetricsClient.MetricsV1beta1().PodMetricses(foreignNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", forge.LiqoOutgoingKey, forge.LiqoNodeName()),
+	})
+	if err != nil {
+		klog.Warningf("error while listing foreign pod metricses in namespace %s: %v", foreignNamespace, err)
+		return podMetrics
+	}
+
+	for i := range list.Items {
+		podMetrics[list.Items[i].Name] = &list.Items[i]
+	}
+	return podMetrics
+}
+
+// podStatsFromMetrics returns the stats of the given foreign pod, starting from the corresponding metrics.
+func podStatsFromMetrics(foreignPod, homePod *corev1.Pod, podMetrics *metricsv1beta1.PodMetrics) *stats.PodStats {
+	if podMetrics == nil {
+		return nil
+	}
+
+	podStats := &stats.PodStats{
+		PodRef: stats.PodReference{
+			Name:      foreignPod.Name,
+			Namespace: foreignPod.Namespace,
+		},
+		StartTime: podStartTime(foreignPod, homePod),
+	}
+
+	var totalUsageNanoCores, totalWorkingSetBytes uint64
+	for i := range podMetrics.Containers {
+		container := &podMetrics.Containers[i]
+
+		usageNanoCores := uint64(container.Usage.Cpu().ScaledValue(resource.Nano))
+		// the memory usage reported by the metrics API corresponds to the working set.
+		workingSetBytes := uint64(container.Usage.Memory().Value())
+
+		totalUsageNanoCores += usageNanoCores
+		totalWorkingSetBytes += workingSetBytes
+
+		podStats.Containers = append(podStats.Containers, stats.ContainerStats{
+			Name:      container.Name,
+			StartTime: containerStartTime(foreignPod, container.Name, podStats.StartTime),
+			CPU:       cpuStats(podMetrics.Timestamp, usageNanoCores),
+			Memory:    memoryStats(podMetrics.Timestamp, workingSetBytes),
+		})
+	}
+
+	podStats.CPU = cpuStats(podMetrics.Timestamp, totalUsageNanoCores)
+	podStats.Memory = memoryStats(podMetrics.Timestamp, totalWorkingSetBytes)
+	return podStats
+}
+
+// translatePodStats replaces the references to the foreign pod with the ones to the corresponding home pod.
+func translatePodStats(podStats *stats.PodStats, homePod *corev1.Pod) {
+	podStats.PodRef = stats.PodReference{
+		Name:      homePod.Name,
+		Namespace: homePod.Namespace,
+		UID:       string(homePod.UID),
+	}
+}
+
+// aggregateNodeStats returns the CPU and memory stats of the virtual node, summing the ones of the offloaded pods.
+func aggregateNodeStats(pods []stats.PodStats) (*stats.CPUStats, *stats.MemoryStats) {
+	t := metav1.NewTime(time.Now())
+
+	var usageNanoCores, workingSetBytes uint64
+	for i := range pods {
+		if cpu := pods[i].CPU; cpu != nil {
+			usageNanoCores += valueOrZero(cpu.UsageNanoCores)
+		}
+		if memory := pods[i].Memory; memory != nil {
+			workingSetBytes += valueOrZero(memory.WorkingSetBytes)
+		}
+	}
+
+	return cpuStats(t, usageNanoCores), memoryStats(t, workingSetBytes)
+}
+
+func podStartTime(foreignPod, homePod *corev1.Pod) metav1.Time {
+	if foreignPod.Status.StartTime != nil {
+		return *foreignPod.Status.StartTime
+	}
+	return homePod.CreationTimestamp
+}
+
+// containerStartTime returns the time the given container started running, as reported by the foreign pod status.
+func containerStartTime(foreignPod *corev1.Pod, containerName string, defaultTime metav1.Time) metav1.Time {
+	for i := range foreignPod.Status.ContainerStatuses {
+		status := &foreignPod.Status.ContainerStatuses[i]
+		if status.Name == containerName && status.State.Running != nil {
+			return status.State.Running.StartedAt
+		}
+	}
+	return defaultTime
+}
+
+// cpuStats returns the CPU stats with the given usage. The cumulative usage is not set, since the metrics API does not
+// expose it, and synthesizing it from the average usage would not produce a monotonic counter.
+func cpuStats(t metav1.Time, usageNanoCores uint64) *stats.CPUStats {
+	return &stats.CPUStats{Time: t, UsageNanoCores: &usageNanoCores}
+}
+
+func memoryStats(t metav1.Time, workingSetBytes uint64) *stats.MemoryStats {
+	return &stats.MemoryStats{Time: t, WorkingSetBytes: &workingSetBytes}
+}
+
+func valueOrZero(value *uint64) uint64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,161 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	test2 "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/controller/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/forge"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	optTypes "github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	test3 "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+var _ = Describe("Stats", func() {
+	const (
+		homeNamespace    = "homeNamespace"
+		foreignNamespace = "homeNamespace-natted"
+		nodeName         = "liqo-node"
+	)
+
+	var (
+		provider      *LiqoProvider
+		metricsClient *metricsfake.Clientset
+		timestamp     time.Time
+		startedAt     metav1.Time
+
+		podMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	)
+
+	newForeignPod := func(name, homeName, virtualNode string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: foreignNamespace,
+				Labels:    map[string]string{forge.LiqoOutgoingKey: virtualNode, virtualKubelet.ReflectedpodKey: homeName},
+			},
+			Status: corev1.PodStatus{
+				StartTime: &startedAt,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "container",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: startedAt}},
+				}},
+			},
+		}
+	}
+
+	newPodMetrics := func(name string, cpu, memory string, t time.Time) *metricsv1beta1.PodMetrics {
+		return &metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: foreignNamespace,
+				Labels:    map[string]string{forge.LiqoOutgoingKey: nodeName},
+			},
+			Timestamp: metav1.NewTime(t),
+			Containers: []metricsv1beta1.ContainerMetrics{{
+				Name: "container",
+				Usage: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		timestamp = time.Now().Truncate(time.Second)
+		startedAt = metav1.NewTime(timestamp.Add(-time.Hour))
+
+		namespaceNattingTable := &test.MockNamespaceMapper{Cache: map[string]string{homeNamespace: foreignNamespace}}
+		mockManager := &test3.MockManager{
+			HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+			ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		}
+		nodeNameOpt := optTypes.NewNetworkingOption(optTypes.VirtualNodeName, nodeName)
+		forge.InitForger(namespaceNattingTable, nodeNameOpt)
+
+		mockManager.AddHomeEntry(homeNamespace, apimgmt.Pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "home-pod", Namespace: homeNamespace, UID: "home-uid"},
+		})
+		mockManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, newForeignPod("foreign-pod", "home-pod", nodeName))
+		// the home counterpart of this pod is missing, hence it is skipped.
+		mockManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, newForeignPod("orphan-pod", "missing-pod", nodeName))
+		// this pod has been offloaded by a different virtual node, hence it is skipped.
+		mockManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, newForeignPod("other-pod", "home-pod", "other-node"))
+
+		metricsClient = metricsfake.NewSimpleClientset()
+		Expect(metricsClient.Tracker().Create(podMetricsGVR, newPodMetrics("foreign-pod", "100m", "10Mi", timestamp), foreignNamespace)).To(Succeed())
+		Expect(metricsClient.Tracker().Create(podMetricsGVR, newPodMetrics("orphan-pod", "100m", "10Mi", timestamp), foreignNamespace)).To(Succeed())
+
+		provider = &LiqoProvider{
+			namespaceMapper:      test.NewMockNamespaceMapperController(namespaceNattingTable),
+			apiController:        &test2.MockController{Manager: mockManager},
+			foreignMetricsClient: metricsClient,
+			nodeName:             nodeNameOpt,
+		}
+	})
+
+	It("returns the stats of the offloaded pods from the metrics API", func() {
+		summary, err := provider.GetStatsSummary(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Node.NodeName).To(Equal(nodeName))
+		Expect(summary.Pods).To(HaveLen(1))
+
+		podStats := summary.Pods[0]
+		Expect(podStats.PodRef.Name).To(Equal("home-pod"))
+		Expect(podStats.PodRef.Namespace).To(Equal(homeNamespace))
+		Expect(podStats.PodRef.UID).To(BeEquivalentTo("home-uid"))
+		Expect(podStats.StartTime).To(Equal(startedAt))
+		Expect(*podStats.CPU.UsageNanoCores).To(BeNumerically("==", 100*1000*1000))
+		Expect(*podStats.Memory.WorkingSetBytes).To(BeNumerically("==", 10*1024*1024))
+
+		Expect(podStats.Containers).To(HaveLen(1))
+		Expect(podStats.Containers[0].Name).To(Equal("container"))
+		Expect(podStats.Containers[0].StartTime).To(Equal(startedAt))
+
+		Expect(*summary.Node.CPU.UsageNanoCores).To(BeNumerically("==", 100*1000*1000))
+		Expect(*summary.Node.Memory.WorkingSetBytes).To(BeNumerically("==", 10*1024*1024))
+	})
+
+	It("does not report the stats unavailable from the metrics API", func() {
+		summary, err := provider.GetStatsSummary(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Pods).To(HaveLen(1))
+
+		podStats := summary.Pods[0]
+		Expect(podStats.CPU.UsageCoreNanoSeconds).To(BeNil())
+		Expect(podStats.Memory.UsageBytes).To(BeNil())
+		Expect(podStats.Network).To(BeNil())
+		Expect(podStats.EphemeralStorage).To(BeNil())
+		Expect(podStats.VolumeStats).To(BeEmpty())
+		Expect(podStats.Containers[0].CPU.UsageCoreNanoSeconds).To(BeNil())
+		Expect(podStats.Containers[0].Rootfs).To(BeNil())
+		Expect(podStats.Containers[0].Logs).To(BeNil())
+		Expect(summary.Node.CPU.UsageCoreNanoSeconds).To(BeNil())
+	})
+})
@@ -0,0 +1,191 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/proxy"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	remotecommandclient "k8s.io/client-go/tools/remotecommand"
+	"k8s.io/klog/v2"
+
+	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+)
+
+// The query parameters set by the API server when contacting the kubelet streaming endpoints.
+const (
+	streamStdinParam  = "input"
+	streamStdoutParam = "output"
+	streamStderrParam = "error"
+	streamTTYParam    = "tty"
+	portForwardParam  = "port"
+)
+
+// AttachToContainer attaches to the given container of the pod, proxying the streams of the
+// upgrade request to the foreign counterpart of the pod.
+func (p *LiqoProvider) AttachToContainer(w http.ResponseWriter, req *http.Request, homeNamespace, homePodName, containerName string) {
+	opts := attachOptions(req.URL.Query(), containerName)
+	p.proxyStream(w, req, homeNamespace, homePodName, "attach", opts)
+}
+
+// PortForward forwards the ports requested by the upgrade request to the foreign counterpart of the pod.
+func (p *LiqoProvider) PortForward(w http.ResponseWriter, req *http.Request, homeNamespace, homePodName string) {
+	opts, err := portForwardOptions(req.URL.Query())
+	if err != nil {
+		streamErrorResponder{}.Error(w, req, kerror.NewBadRequest(err.Error()))
+		return
+	}
+	p.proxyStream(w, req, homeNamespace, homePodName, "portforward", opts)
+}
+
+// proxyStream proxies the given upgrade request to the subresource of the foreign pod corresponding to the home one.
+func (p *LiqoProvider) proxyStream(w http.ResponseWriter, req *http.Request, homeNamespace, homePodName, subresource string,
+	opts runtime.Object) {
+	location, err := p.foreignPodSubresourceLocation(homeNamespace, homePodName, subresource, opts)
+	if err != nil {
+		streamErrorResponder{}.Error(w, req, err)
+		return
+	}
+
+	handler, err := newUpgradeProxyHandler(p.foreignRestConfig, location)
+	if err != nil {
+		streamErrorResponder{}.Error(w, req, err)
+		return
+	}
+
+	klog.V(4).Infof("PROVIDER: proxying %v request for pod %v/%v to %v", subresource, homeNamespace, homePodName, location)
+	handler.ServeHTTP(w, req)
+}
+
+// foreignPodSubresourceLocation returns the URL of the subresource of the foreign pod corresponding to the home one.
+func (p *LiqoProvider) foreignPodSubresourceLocation(homeNamespace, homePodName, subresource string,
+	opts runtime.Object) (*url.URL, error) {
+	foreignNamespace, err := p.namespaceMapper.NatNamespace(homeNamespace)
+	if err != nil {
+		return nil, kerror.NewNotFound(corev1.Resource("namespaces"), homeNamespace)
+	}
+
+	foreignObj, err := p.apiController.CacheManager().GetForeignAPIByIndex(apimgmgt.Pods, foreignNamespace, homePodName)
+	if err != nil {
+		return nil, kerror.NewNotFound(corev1.Resource("pods"), homePodName)
+	}
+	foreignPod := foreignObj.(*corev1.Pod)
+
+	return p.foreignClient.CoreV1().RESTClient().
+		Post().
+		Namespace(foreignNamespace).
+		Resource("pods").
+		Name(foreignPod.Name).
+		SubResource(subresource).
+		VersionedParams(opts, scheme.ParameterCodec).
+		URL(), nil
+}
+
+// newUpgradeProxyHandler returns a handler proxying the upgrade requests to the given location,
+// authenticating them according to the given rest config.
+func newUpgradeProxyHandler(config *rest.Config, location *url.URL) (*proxy.UpgradeAwareHandler, error) {
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve the TLS configuration")
+	}
+
+	// The spdy round tripper carries the TLS configuration to dial the foreign API server,
+	// while the wrappers add the authentication headers to the upgrade request.
+	upgrader := spdy.NewRoundTripper(tlsConfig, true, false)
+	wrapper, err := rest.HTTPWrappersForConfig(config, proxy.MirrorRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure the authentication wrappers")
+	}
+
+	handler := proxy.NewUpgradeAwareHandler(location, upgrader, false, true, streamErrorResponder{})
+	handler.UpgradeTransport = proxy.NewUpgradeRequestRoundTripper(upgrader, wrapper)
+	handler.UseLocationHost = true
+	return handler, nil
+}
+
+// attachOptions returns the attach options corresponding to the query parameters set by the API server.
+func attachOptions(query url.Values, containerName string) *corev1.PodAttachOptions {
+	return &corev1.PodAttachOptions{
+		Container: containerName,
+		Stdin:     boolParam(query, streamStdinParam),
+		Stdout:    boolParam(query, streamStdoutParam),
+		Stderr:    boolParam(query, streamStderrParam),
+		TTY:       boolParam(query, streamTTYParam),
+	}
+}
+
+// portForwardOptions returns the port-forward options corresponding to the query parameters set by the API server.
+func portForwardOptions(query url.Values) (*corev1.PodPortForwardOptions, error) {
+	opts := &corev1.PodPortForwardOptions{}
+	for _, value := range query[portForwardParam] {
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		opts.Ports = append(opts.Ports, int32(port))
+	}
+	return opts, nil
+}
+
+func boolParam(query url.Values, key string) bool {
+	value, err := strconv.ParseBool(query.Get(key))
+	return err == nil && value
+}
+
+// streamErrorResponder implements the proxy.ErrorResponder interface, returning the errors to the API server.
+type streamErrorResponder struct{}
+
+// Error implements the proxy.ErrorResponder interface.
+func (streamErrorResponder) Error(w http.ResponseWriter, req *http.Request, err error) {
+	klog.Errorf("PROVIDER: error while proxying %v: %v", req.URL.Path, err)
+
+	code := http.StatusInternalServerError
+	var status kerror.APIStatus
+	if errors.As(err, &status) {
+		code = int(status.Status().Code)
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// terminalSizeQueue adapts the channel of resize events to the remotecommand.TerminalSizeQueue interface.
+type terminalSizeQueue struct {
+	ctx    context.Context
+	resize <-chan api.TermSize
+}
+
+// Next implements the remotecommand.TerminalSizeQueue interface.
+func (q *terminalSizeQueue) Next() *remotecommandclient.TerminalSize {
+	select {
+	case size, ok := <-q.resize:
+		if !ok {
+			return nil
+		}
+		return &remotecommandclient.TerminalSize{Width: size.Width, Height: size.Height}
+	case <-q.ctx.Done():
+		return nil
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	test2 "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/controller/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesmapping/test"
+	test3 "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+var _ = Describe("Streaming", func() {
+	const (
+		homeNamespace    = "homeNamespace"
+		foreignNamespace = "homeNamespace-natted"
+	)
+
+	var (
+		provider *LiqoProvider
+		foreign  *httptest.Server
+		home     *httptest.Server
+		requests chan *http.Request
+	)
+
+	// upgrade sends an upgrade request to the home server, and returns the resulting connection.
+	upgrade := func(path string) (net.Conn, *http.Response) {
+		conn, err := net.Dial("tcp", home.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: virtual-kubelet\r\nConnection: Upgrade\r\nUpgrade: SPDY/3.1\r\n\r\n", path)
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		Expect(err).NotTo(HaveOccurred())
+		return conn, resp
+	}
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 1)
+		// The foreign API server upgrades the connection and echoes the received data.
+		foreign = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests <- req
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			fmt.Fprint(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: SPDY/3.1\r\n\r\n")
+			_, _ = io.Copy(conn, conn)
+		}))
+
+		namespaceNattingTable := &test.MockNamespaceMapper{Cache: map[string]string{homeNamespace: foreignNamespace}}
+		mockManager := &test3.MockManager{
+			HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+			ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		}
+		mockManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "foreign-pod", Namespace: foreignNamespace, Labels: map[string]string{virtualKubelet.ReflectedpodKey: "home-pod"},
+		}})
+
+		restConfig := &rest.Config{Host: foreign.URL, BearerToken: "token"}
+		provider = &LiqoProvider{
+			namespaceMapper:   test.NewMockNamespaceMapperController(namespaceNattingTable),
+			apiController:     &test2.MockController{Manager: mockManager},
+			foreignClient:     kubernetes.NewForConfigOrDie(restConfig),
+			foreignRestConfig: restConfig,
+		}
+
+		home = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/attach" {
+				provider.AttachToContainer(w, req, homeNamespace, "home-pod", "container")
+				return
+			}
+			provider.PortForward(w, req, homeNamespace, "home-pod")
+		}))
+	})
+
+	AfterEach(func() {
+		home.Close()
+		foreign.Close()
+	})
+
+	It("proxies the attach streams honoring the requested flags", func() {
+		conn, resp := upgrade("/attach?input=1&output=1&tty=1")
+		defer conn.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+
+		var req *http.Request
+		Eventually(requests).Should(Receive(&req))
+		Expect(req.URL.Path).To(Equal("/api/v1/namespaces/homeNamespace-natted/pods/foreign-pod/attach"))
+		Expect(req.Header.Get("Authorization")).To(Equal("Bearer token"))
+		Expect(req.URL.Query()).To(Equal(url.Values{
+			"container": []string{"container"}, "stdin": []string{"true"}, "stdout": []string{"true"}, "tty": []string{"true"}}))
+
+		_, err := conn.Write([]byte("ping"))
+		Expect(err).NotTo(HaveOccurred())
+		buffer := make([]byte, 4)
+		_, err = io.ReadFull(conn, buffer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(buffer)).To(Equal("ping"))
+	})
+
+	It("proxies the port-forward streams to the requested ports", func() {
+		conn, resp := upgrade("/portForward?port=8080&port=9090")
+		defer conn.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+
+		var req *http.Request
+		Eventually(requests).Should(Receive(&req))
+		Expect(req.URL.Path).To(Equal("/api/v1/namespaces/homeNamespace-natted/pods/foreign-pod/portforward"))
+		Expect(req.URL.Query()["ports"]).To(ConsistOf("8080", "9090"))
+	})
+
+	It("rejects invalid ports", func() {
+		conn, resp := upgrade("/portForward?port=foo")
+		defer conn.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails if the foreign pod does not exist", func() {
+		provider.apiController = &test2.MockController{Manager: &test3.MockManager{}}
+		conn, resp := upgrade("/portForward?port=8080")
+		defer conn.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
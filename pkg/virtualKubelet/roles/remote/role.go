@@ -15,9 +15,14 @@
 // Package remote defines the ClusterRole containing the permissions required by the virtual kubelet in the remote cluster.
 package remote
 
-// +kubebuilder:rbac:groups="",resources=configmaps;services;secrets;pods,verbs=get;list;watch;update;patch;delete;create
+// +kubebuilder:rbac:groups="",resources=configmaps;services;secrets;pods;persistentvolumeclaims,verbs=get;list;watch;update;patch;delete;create
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;update;patch;delete;create
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods/status;services/status,verbs=get;update;patch;list;watch;delete;create
 // +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/attach;pods/portforward,verbs=get;create
 
 // +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;update;create;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
@@ -19,8 +19,10 @@ import (
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/liqotech/liqo/pkg/virtualKubelet"
@@ -28,12 +30,18 @@ import (
 )
 
 var InformerIndexers = map[apimgmt.ApiType]func() cache.Indexers{
-	apimgmt.Configmaps:     configmapsIndexers,
-	apimgmt.EndpointSlices: endpointSlicesIndexers,
-	apimgmt.Pods:           podsIndexers,
-	apimgmt.ReplicaSets:    replicasetsIndexers,
-	apimgmt.Secrets:        secretsIndexers,
-	apimgmt.Services:       servicesIndexers,
+	apimgmt.Configmaps:             configmapsIndexers,
+	apimgmt.EndpointSlices:         endpointSlicesIndexers,
+	apimgmt.Events:                 eventsIndexers,
+	apimgmt.Ingresses:              ingressesIndexers,
+	apimgmt.Jobs:                   jobsIndexers,
+	apimgmt.NetworkPolicies:        networkPoliciesIndexers,
+	apimgmt.PersistentVolumeClaims: persistentVolumeClaimsIndexers,
+	apimgmt.Pods:                   podsIndexers,
+	apimgmt.ReplicaSets:            replicasetsIndexers,
+	apimgmt.Secrets:                secretsIndexers,
+	apimgmt.Services:               servicesIndexers,
+	apimgmt.ServiceAccounts:        serviceAccountsIndexers,
 }
 
 func configmapsIndexers() cache.Indexers {
@@ -64,6 +72,77 @@ func endpointSlicesIndexers() cache.Indexers {
 	return i
 }
 
+func eventsIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["events"] = func(obj interface{}) ([]string, error) {
+		event, ok := obj.(*corev1.Event)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to event")
+		}
+		return []string{
+			strings.Join([]string{event.Namespace, event.Name}, "/"),
+		}, nil
+	}
+	return i
+}
+
+func ingressesIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["ingresses"] = func(obj interface{}) ([]string, error) {
+		ingress, ok := obj.(*networkingv1.Ingress)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to ingress")
+		}
+		return []string{
+			strings.Join([]string{ingress.Namespace, ingress.Name}, "/"),
+		}, nil
+	}
+	return i
+}
+
+func jobsIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["jobs"] = func(obj interface{}) ([]string, error) {
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to job")
+		}
+		return []string{
+			strings.Join([]string{job.Namespace, job.Name}, "/"),
+			job.Name,
+		}, nil
+	}
+	return i
+}
+
+func networkPoliciesIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["networkpolicies"] = func(obj interface{}) ([]string, error) {
+		networkPolicy, ok := obj.(*networkingv1.NetworkPolicy)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to networkpolicy")
+		}
+		return []string{
+			strings.Join([]string{networkPolicy.Namespace, networkPolicy.Name}, "/"),
+		}, nil
+	}
+	return i
+}
+
+func persistentVolumeClaimsIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["persistentvolumeclaims"] = func(obj interface{}) ([]string, error) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to persistentvolumeclaim")
+		}
+		return []string{
+			strings.Join([]string{pvc.Namespace, pvc.Name}, "/"),
+		}, nil
+	}
+	return i
+}
+
 func podsIndexers() cache.Indexers {
 	i := cache.Indexers{}
 	i["pods"] = func(obj interface{}) ([]string, error) {
@@ -130,3 +209,17 @@ func servicesIndexers() cache.Indexers {
 	}
 	return i
 }
+
+func serviceAccountsIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["serviceaccounts"] = func(obj interface{}) ([]string, error) {
+		sa, ok := obj.(*corev1.ServiceAccount)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to serviceaccount")
+		}
+		return []string{
+			strings.Join([]string{sa.Namespace, sa.Name}, "/"),
+		}, nil
+	}
+	return i
+}
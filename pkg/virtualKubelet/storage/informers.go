@@ -22,12 +22,18 @@ import (
 )
 
 var InformerBuilders = map[apimgmt.ApiType]func(informers.SharedInformerFactory) cache.SharedIndexInformer{
-	apimgmt.Configmaps:     configmapsInformerBuilder,
-	apimgmt.EndpointSlices: endpointSlicesInformerBuilder,
-	apimgmt.Pods:           podsInformerBuilder,
-	apimgmt.ReplicaSets:    replicaSetsInformerBuilder,
-	apimgmt.Services:       servicesInformerBuilder,
-	apimgmt.Secrets:        secretsInformerBuilder,
+	apimgmt.Configmaps:             configmapsInformerBuilder,
+	apimgmt.EndpointSlices:         endpointSlicesInformerBuilder,
+	apimgmt.Events:                 eventsInformerBuilder,
+	apimgmt.Ingresses:              ingressesInformerBuilder,
+	apimgmt.Jobs:                   jobsInformerBuilder,
+	apimgmt.NetworkPolicies:        networkPoliciesInformerBuilder,
+	apimgmt.PersistentVolumeClaims: persistentVolumeClaimsInformerBuilder,
+	apimgmt.Pods:                   podsInformerBuilder,
+	apimgmt.ReplicaSets:            replicaSetsInformerBuilder,
+	apimgmt.Services:               servicesInformerBuilder,
+	apimgmt.Secrets:                secretsInformerBuilder,
+	apimgmt.ServiceAccounts:        serviceAccountsInformerBuilder,
 }
 
 func configmapsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
@@ -38,6 +44,26 @@ func endpointSlicesInformerBuilder(factory informers.SharedInformerFactory) cach
 	return factory.Discovery().V1beta1().EndpointSlices().Informer()
 }
 
+func eventsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Core().V1().Events().Informer()
+}
+
+func ingressesInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Networking().V1().Ingresses().Informer()
+}
+
+func jobsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Batch().V1().Jobs().Informer()
+}
+
+func networkPoliciesInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Networking().V1().NetworkPolicies().Informer()
+}
+
+func persistentVolumeClaimsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Core().V1().PersistentVolumeClaims().Informer()
+}
+
 func podsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
 	return factory.Core().V1().Pods().Informer()
 }
@@ -53,3 +79,7 @@ func servicesInformerBuilder(factory informers.SharedInformerFactory) cache.Shar
 func secretsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
 	return factory.Core().V1().Secrets().Informer()
 }
+
+func serviceAccountsInformerBuilder(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Core().V1().ServiceAccounts().Informer()
+}
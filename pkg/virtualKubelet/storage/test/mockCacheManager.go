@@ -20,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 )
 
@@ -135,9 +136,15 @@ func (m *MockManager) GetHomeAPIByIndex(apiType apimgmt.ApiType, s, s2 string) (
 	panic("implement me")
 }
 
-// GetForeignAPIByIndex is a mock implementation of the corresponding function (unimplemented).
+// GetForeignAPIByIndex is a mock implementation of the corresponding function,
+// matching the objects either by name or by the reflected pod label.
 func (m *MockManager) GetForeignAPIByIndex(apiType apimgmt.ApiType, s, s2 string) (interface{}, error) {
-	panic("implement me")
+	for _, obj := range m.ForeignCache[s][apiType] {
+		if obj.GetName() == s2 || obj.GetLabels()[virtualKubelet.ReflectedpodKey] == s2 {
+			return obj, nil
+		}
+	}
+	return nil, errors.New("object not found")
 }
 
 // Clear is a function used in tests only to clear the mock's state.
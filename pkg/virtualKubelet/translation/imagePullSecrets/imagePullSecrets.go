@@ -0,0 +1,48 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagePullSecrets contains the logic to ensure the image pull secrets of the offloaded pods
+// are available in the foreign cluster before the pods are created.
+package imagePullSecrets
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/storage"
+)
+
+// CheckImagePullSecrets returns an error if any of the image pull secrets referenced by the home pod (including
+// the ones injected from its ServiceAccount) has not been reflected yet in the foreign namespace, to prevent the
+// foreign pod from failing to pull private images. The secrets missing in the home cluster, or which are not
+// reflected by configuration, are ignored, since they would not be available in the foreign cluster anyway.
+func CheckImagePullSecrets(homePod *v1.Pod, foreignNamespace string, cacheManager storage.CacheManagerReader) error {
+	for _, ref := range homePod.Spec.ImagePullSecrets {
+		obj, err := cacheManager.GetHomeNamespacedObject(apimgmgt.Secrets, homePod.Namespace, ref.Name)
+		if err != nil {
+			klog.V(4).Infof("image pull secret %v/%v not found in the home cluster", homePod.Namespace, ref.Name)
+			continue
+		}
+		if obj.(*v1.Secret).Annotations[apimgmgt.NotReflectAnnotation] == "true" {
+			continue
+		}
+
+		if _, err = cacheManager.GetForeignNamespacedObject(apimgmgt.Secrets, foreignNamespace, ref.Name); err != nil {
+			return errors.Wrapf(err, "image pull secret %v/%v not yet reflected", foreignNamespace, ref.Name)
+		}
+	}
+	return nil
+}
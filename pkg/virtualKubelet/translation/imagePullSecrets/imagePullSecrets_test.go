@@ -0,0 +1,62 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagePullSecrets
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+const (
+	homeNamespace    = "namespace"
+	foreignNamespace = "namespace-natted"
+)
+
+func newSecret(namespace, name string, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}
+}
+
+func newPod(secrets ...string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: homeNamespace, Name: "pod"}}
+	for _, secret := range secrets {
+		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	return pod
+}
+
+func TestCheckImagePullSecrets(t *testing.T) {
+	cacheReader := &test.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	cacheReader.AddHomeEntry(homeNamespace, apimgmt.Secrets, newSecret(homeNamespace, "reflected", nil))
+	cacheReader.AddForeignEntry(foreignNamespace, apimgmt.Secrets, newSecret(foreignNamespace, "reflected", nil))
+	cacheReader.AddHomeEntry(homeNamespace, apimgmt.Secrets, newSecret(homeNamespace, "pending", nil))
+	cacheReader.AddHomeEntry(homeNamespace, apimgmt.Secrets, newSecret(homeNamespace, "not-reflected",
+		map[string]string{apimgmt.NotReflectAnnotation: "true"}))
+
+	assert.NilError(t, CheckImagePullSecrets(newPod(), foreignNamespace, cacheReader))
+	assert.NilError(t, CheckImagePullSecrets(newPod("reflected", "not-reflected", "missing"), foreignNamespace, cacheReader))
+	assert.ErrorContains(t, CheckImagePullSecrets(newPod("reflected", "pending"), foreignNamespace, cacheReader), "pending")
+}
@@ -0,0 +1,286 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package serviceAccount contains the logic to let the offloaded pods authenticate against the home API server,
+// with the tokens of the home ServiceAccounts.
+package serviceAccount
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/storage"
+)
+
+const (
+	// rootCAConfigMapName is the name of the ConfigMap containing the CA of the API server, published in every namespace.
+	rootCAConfigMapName = "kube-root-ca.crt"
+
+	// KubernetesServiceHostEnv is the environment variable containing the address of the API server.
+	KubernetesServiceHostEnv = "KUBERNETES_SERVICE_HOST"
+	// KubernetesServicePortEnv is the environment variable containing the port of the API server.
+	KubernetesServicePortEnv = "KUBERNETES_SERVICE_PORT"
+
+	// tokenSecretSuffix is the suffix of the name of the token secrets created for the ServiceAccounts lacking one.
+	tokenSecretSuffix = "-token-liqo"
+)
+
+// TranslateServiceAccountTokens configures the foreign pod to authenticate against the home API server (reachable at
+// apiServerHost:apiServerPort) using the token of the home ServiceAccount, which is reflected as a secret in the
+// foreign namespace. The projected ServiceAccount token sources are replaced by the corresponding secret keys, and the
+// token generated by the foreign control plane is no longer mounted. If the ServiceAccount lacks a token secret,
+// one is created through the home client.
+func TranslateServiceAccountTokens(foreignPod, homePod *v1.Pod, cacheManager storage.CacheManagerReader,
+	homeClient kubernetes.Interface, apiServerHost, apiServerPort string) (*v1.Pod, error) {
+	if !requiresToken(homePod) {
+		return foreignPod, nil
+	}
+
+	tokenSecret, err := getTokenSecretName(homePod, cacheManager, homeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignPod.Spec.AutomountServiceAccountToken = pointer.BoolPtr(false)
+
+	translated := make(map[string]bool)
+	for i := range homePod.Spec.Volumes {
+		volume := translateVolume(&homePod.Spec.Volumes[i], serviceAccountName(homePod), tokenSecret)
+		if volume == nil {
+			continue
+		}
+		translated[volume.Name] = true
+		setVolume(&foreignPod.Spec, volume)
+	}
+
+	envs := map[string]string{
+		KubernetesServiceHostEnv: apiServerHost,
+		KubernetesServicePortEnv: apiServerPort,
+	}
+	for i := range foreignPod.Spec.Containers {
+		setInContainer(&foreignPod.Spec.Containers[i], homePod.Spec.Containers, translated, envs)
+	}
+	for i := range foreignPod.Spec.InitContainers {
+		setInContainer(&foreignPod.Spec.InitContainers[i], homePod.Spec.InitContainers, translated, envs)
+	}
+
+	return foreignPod, nil
+}
+
+// requiresToken returns whether the given pod mounts a ServiceAccount token.
+func requiresToken(pod *v1.Pod) bool {
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Projected != nil && hasTokenSource(pod.Spec.Volumes[i].Projected) {
+			return true
+		}
+		if pod.Spec.Volumes[i].Secret != nil && isTokenSecret(pod.Spec.Volumes[i].Secret.SecretName, serviceAccountName(pod)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getTokenSecretName returns the name of the secret containing the token of the ServiceAccount of the given pod.
+func getTokenSecretName(pod *v1.Pod, cacheManager storage.CacheManagerReader, homeClient kubernetes.Interface) (string, error) {
+	saName := serviceAccountName(pod)
+	obj, err := cacheManager.GetHomeNamespacedObject(apimgmgt.ServiceAccounts, pod.Namespace, saName)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot retrieve serviceaccount %v/%v", pod.Namespace, saName)
+	}
+
+	sa := obj.(*v1.ServiceAccount)
+	for _, secret := range sa.Secrets {
+		if isTokenSecret(secret.Name, saName) {
+			return secret.Name, nil
+		}
+	}
+	return ensureTokenSecret(sa, cacheManager, homeClient)
+}
+
+// ensureTokenSecret returns the name of the token secret created for the given ServiceAccount, creating it if it does
+// not exist yet. Starting from Kubernetes 1.24, the token secrets are no longer generated for the ServiceAccounts,
+// while the ones explicitly created are still populated by the token controller. An error is returned until the token
+// is available, to let the creation of the pod be retried.
+func ensureTokenSecret(sa *v1.ServiceAccount, cacheManager storage.CacheManagerReader, homeClient kubernetes.Interface) (string, error) {
+	name := sa.Name + tokenSecretSuffix
+	obj, err := cacheManager.GetHomeNamespacedObject(apimgmgt.Secrets, sa.Namespace, name)
+	if err == nil {
+		if len(obj.(*v1.Secret).Data[v1.ServiceAccountTokenKey]) == 0 {
+			return "", errors.Errorf("token secret %v/%v not yet populated", sa.Namespace, name)
+		}
+		return name, nil
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   sa.Namespace,
+			Annotations: map[string]string{v1.ServiceAccountNameKey: sa.Name},
+			// The secret is garbage collected together with the ServiceAccount.
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "ServiceAccount", Name: sa.Name, UID: sa.UID}},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+	if _, err = homeClient.CoreV1().Secrets(sa.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		if !kerrors.IsAlreadyExists(err) {
+			return "", errors.Wrapf(err, "cannot create token secret %v/%v", sa.Namespace, name)
+		}
+	} else {
+		klog.Infof("created token secret %v/%v for serviceaccount %v", sa.Namespace, name, sa.Name)
+	}
+	return "", errors.Errorf("token secret not yet available for serviceaccount %v/%v", sa.Namespace, sa.Name)
+}
+
+// translateVolume returns the foreign counterpart of the given volume, if it mounts a ServiceAccount token.
+func translateVolume(volume *v1.Volume, saName, tokenSecret string) *v1.Volume {
+	switch {
+	case volume.Secret != nil && isTokenSecret(volume.Secret.SecretName, saName):
+		// the token secret is reflected with the same name, hence it can be mounted as it is.
+		output := volume.DeepCopy()
+		output.Secret.SecretName = tokenSecret
+		return output
+	case volume.Projected != nil && hasTokenSource(volume.Projected):
+		output := volume.DeepCopy()
+		output.Projected.Sources = nil
+		for i := range volume.Projected.Sources {
+			output.Projected.Sources = append(output.Projected.Sources, translateProjection(&volume.Projected.Sources[i], tokenSecret)...)
+		}
+		return output
+	default:
+		return nil
+	}
+}
+
+// translateProjection replaces the projections of the ServiceAccount token, of the API server CA and of the pod
+// namespace with the corresponding keys of the token secret, since they would otherwise refer to the foreign cluster.
+func translateProjection(projection *v1.VolumeProjection, tokenSecret string) []v1.VolumeProjection {
+	switch {
+	case projection.ServiceAccountToken != nil:
+		return []v1.VolumeProjection{secretProjection(tokenSecret, v1.KeyToPath{
+			Key: v1.ServiceAccountTokenKey, Path: projection.ServiceAccountToken.Path})}
+	case projection.ConfigMap != nil && projection.ConfigMap.Name == rootCAConfigMapName:
+		items := make([]v1.KeyToPath, len(projection.ConfigMap.Items))
+		for i := range projection.ConfigMap.Items {
+			items[i] = v1.KeyToPath{Key: v1.ServiceAccountRootCAKey, Path: projection.ConfigMap.Items[i].Path}
+		}
+		return []v1.VolumeProjection{secretProjection(tokenSecret, items...)}
+	case projection.DownwardAPI != nil:
+		output := projection.DeepCopy()
+		output.DownwardAPI.Items = nil
+		var items []v1.KeyToPath
+		for i := range projection.DownwardAPI.Items {
+			item := &projection.DownwardAPI.Items[i]
+			if item.FieldRef != nil && item.FieldRef.FieldPath == "metadata.namespace" {
+				items = append(items, v1.KeyToPath{Key: v1.ServiceAccountNamespaceKey, Path: item.Path, Mode: item.Mode})
+				continue
+			}
+			output.DownwardAPI.Items = append(output.DownwardAPI.Items, *item)
+		}
+		projections := []v1.VolumeProjection{}
+		if len(output.DownwardAPI.Items) > 0 {
+			projections = append(projections, *output)
+		}
+		if len(items) > 0 {
+			projections = append(projections, secretProjection(tokenSecret, items...))
+		}
+		return projections
+	default:
+		return []v1.VolumeProjection{*projection}
+	}
+}
+
+// secretProjection returns a projection of the given keys of a secret.
+func secretProjection(secretName string, items ...v1.KeyToPath) v1.VolumeProjection {
+	return v1.VolumeProjection{Secret: &v1.SecretProjection{
+		LocalObjectReference: v1.LocalObjectReference{Name: secretName},
+		Items:                items,
+	}}
+}
+
+// setVolume adds the given volume to the pod spec, replacing the one with the same name, if any.
+func setVolume(spec *v1.PodSpec, volume *v1.Volume) {
+	for i := range spec.Volumes {
+		if spec.Volumes[i].Name == volume.Name {
+			spec.Volumes[i] = *volume
+			return
+		}
+	}
+	spec.Volumes = append(spec.Volumes, *volume)
+}
+
+// setInContainer adds to the container the mounts of the translated volumes, as configured in the home container,
+// and the environment variables pointing to the home API server.
+func setInContainer(container *v1.Container, homeContainers []v1.Container, volumes map[string]bool, envs map[string]string) {
+	for i := range homeContainers {
+		if homeContainers[i].Name != container.Name {
+			continue
+		}
+		for _, mount := range homeContainers[i].VolumeMounts {
+			if volumes[mount.Name] && !hasVolumeMount(container, mount.Name) {
+				container.VolumeMounts = append(container.VolumeMounts, mount)
+			}
+		}
+	}
+
+	for name, value := range envs {
+		found := false
+		for i := range container.Env {
+			if container.Env[i].Name == name {
+				container.Env[i] = v1.EnvVar{Name: name, Value: value}
+				found = true
+			}
+		}
+		if !found {
+			container.Env = append(container.Env, v1.EnvVar{Name: name, Value: value})
+		}
+	}
+}
+
+func hasVolumeMount(container *v1.Container, name string) bool {
+	for i := range container.VolumeMounts {
+		if container.VolumeMounts[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTokenSource(projected *v1.ProjectedVolumeSource) bool {
+	for i := range projected.Sources {
+		if projected.Sources[i].ServiceAccountToken != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func serviceAccountName(pod *v1.Pod) string {
+	if pod.Spec.ServiceAccountName == "" {
+		return "default"
+	}
+	return pod.Spec.ServiceAccountName
+}
+
+func isTokenSecret(secretName, serviceAccountName string) bool {
+	return strings.HasPrefix(secretName, serviceAccountName+"-token-")
+}
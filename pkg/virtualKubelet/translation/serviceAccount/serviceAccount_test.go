@@ -0,0 +1,167 @@
+// Copyright 2019-2021 The Liqo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceAccount
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+)
+
+const (
+	namespace   = "namespace"
+	saName      = "test-sa"
+	tokenSecret = "test-sa-token-abcde"
+)
+
+func newCacheReader() *test.MockManager {
+	cacheReader := &test.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	cacheReader.AddHomeEntry(namespace, apimgmt.ServiceAccounts, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: saName},
+		Secrets:    []corev1.ObjectReference{{Name: "test-sa-dockercfg-12345"}, {Name: tokenSecret}},
+	})
+	return cacheReader
+}
+
+func newHomePod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: saName,
+			Containers: []corev1.Container{{
+				Name: "container",
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "kube-api-access-xyz", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "kube-api-access-xyz",
+				VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+						{ConfigMap: &corev1.ConfigMapProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: rootCAConfigMapName},
+							Items:                []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+						}},
+						{DownwardAPI: &corev1.DownwardAPIProjection{Items: []corev1.DownwardAPIVolumeFile{{
+							Path:     "namespace",
+							FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.namespace"},
+						}}}},
+					},
+				}},
+			}},
+		},
+	}
+}
+
+func TestTranslateServiceAccountTokens(t *testing.T) {
+	homePod := newHomePod()
+	foreignPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "namespace-natted", Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: saName,
+			Containers:         []corev1.Container{{Name: "container"}},
+		},
+	}
+
+	foreignPod, err := TranslateServiceAccountTokens(foreignPod, homePod, newCacheReader(), fake.NewSimpleClientset(), "1.2.3.4", "6443")
+	assert.NilError(t, err)
+
+	assert.Assert(t, foreignPod.Spec.AutomountServiceAccountToken != nil)
+	assert.Assert(t, !*foreignPod.Spec.AutomountServiceAccountToken)
+
+	assert.Assert(t, is.Len(foreignPod.Spec.Volumes, 1))
+	volume := foreignPod.Spec.Volumes[0]
+	assert.Equal(t, volume.Name, "kube-api-access-xyz")
+	assert.Assert(t, volume.Projected != nil)
+	assert.DeepEqual(t, volume.Projected.Sources, []corev1.VolumeProjection{
+		{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: tokenSecret},
+			Items:                []corev1.KeyToPath{{Key: corev1.ServiceAccountTokenKey, Path: "token"}},
+		}},
+		{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: tokenSecret},
+			Items:                []corev1.KeyToPath{{Key: corev1.ServiceAccountRootCAKey, Path: "ca.crt"}},
+		}},
+		{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: tokenSecret},
+			Items:                []corev1.KeyToPath{{Key: corev1.ServiceAccountNamespaceKey, Path: "namespace"}},
+		}},
+	})
+
+	container := foreignPod.Spec.Containers[0]
+	assert.DeepEqual(t, container.VolumeMounts, homePod.Spec.Containers[0].VolumeMounts)
+	assert.Assert(t, is.Contains(container.Env, corev1.EnvVar{Name: KubernetesServiceHostEnv, Value: "1.2.3.4"}))
+	assert.Assert(t, is.Contains(container.Env, corev1.EnvVar{Name: KubernetesServicePortEnv, Value: "6443"}))
+}
+
+func TestTranslateServiceAccountTokensNoToken(t *testing.T) {
+	homePod := newHomePod()
+	homePod.Spec.Volumes = nil
+	foreignPod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "container"}}}}
+
+	translated, err := TranslateServiceAccountTokens(foreignPod.DeepCopy(), homePod, newCacheReader(), fake.NewSimpleClientset(), "1.2.3.4", "6443")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, translated, foreignPod)
+}
+
+func TestTranslateServiceAccountTokensMissingSecret(t *testing.T) {
+	homePod := newHomePod()
+	homePod.Spec.ServiceAccountName = "other-sa"
+
+	_, err := TranslateServiceAccountTokens(&corev1.Pod{}, homePod, newCacheReader(), fake.NewSimpleClientset(), "1.2.3.4", "6443")
+	assert.Assert(t, err != nil)
+}
+
+func TestTranslateServiceAccountTokensCreateSecret(t *testing.T) {
+	homePod := newHomePod()
+	cacheReader := newCacheReader()
+	cacheReader.AddHomeEntry(namespace, apimgmt.ServiceAccounts, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: saName, UID: "uid"},
+	})
+	client := fake.NewSimpleClientset()
+	name := saName + tokenSecretSuffix
+
+	// The token secret is created if the ServiceAccount lacks one, and the translation is retried until it is populated.
+	_, err := TranslateServiceAccountTokens(&corev1.Pod{}, homePod, cacheReader, client, "1.2.3.4", "6443")
+	assert.Assert(t, err != nil)
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, secret.Type, corev1.SecretTypeServiceAccountToken)
+	assert.Equal(t, secret.Annotations[corev1.ServiceAccountNameKey], saName)
+	assert.Assert(t, is.Len(secret.OwnerReferences, 1))
+	assert.Equal(t, secret.OwnerReferences[0].UID, cacheReader.HomeCache[namespace][apimgmt.ServiceAccounts][saName].GetUID())
+
+	cacheReader.AddHomeEntry(namespace, apimgmt.Secrets, secret)
+	_, err = TranslateServiceAccountTokens(&corev1.Pod{}, homePod, cacheReader, client, "1.2.3.4", "6443")
+	assert.Assert(t, err != nil)
+
+	secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("token")}
+	foreignPod, err := TranslateServiceAccountTokens(&corev1.Pod{}, homePod, cacheReader, client, "1.2.3.4", "6443")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(foreignPod.Spec.Volumes, 1))
+	assert.Equal(t, foreignPod.Spec.Volumes[0].Projected.Sources[0].Secret.Name, name)
+}